@@ -0,0 +1,121 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package acme
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	vu modules.VU
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return &Module{vu: vu} })
+}
+
+var ErrMissingNonce = errors.New("missing nonce")
+
+type RequestOptions struct {
+	KeyID string `js:"kid"`
+}
+
+// Request creates ACME request JWS (RFC 8555 6.2) in flattened JSON serialization.
+func (m *Module) Request(
+	key *jose.JSONWebKey,
+	url string,
+	nonce string,
+	payload interface{},
+	options *RequestOptions,
+) (string, error) {
+	if options == nil {
+		options = &RequestOptions{}
+	}
+
+	return request(key, url, nonce, payload, options.KeyID)
+}
+
+// request signs the payload with the account key, the public key is embedded if there is no kid (account URL).
+func request(key *jose.JSONWebKey, url string, nonce string, payloadIn interface{}, kid string) (string, error) {
+	if key == nil {
+		return "", jwk.ErrUnsupportedKey
+	}
+
+	if nonce == "" {
+		return "", ErrMissingNonce
+	}
+
+	payload, err := encode(payloadIn)
+	if err != nil {
+		return "", err
+	}
+
+	signing := *key
+	signing.KeyID = kid
+
+	opts := &jose.SignerOptions{EmbedJWK: kid == ""}
+
+	opts.WithHeader(jose.HeaderKey("nonce"), nonce)
+	opts.WithHeader(jose.HeaderKey("url"), url)
+
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(key.Algorithm), Key: &signing}, opts)
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := sig.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return obj.FullSerialize(), nil
+}
+
+// encode returns the payload bytes, missing payload is the empty POST-as-GET payload.
+func encode(in interface{}) ([]byte, error) {
+	switch val := in.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return val, nil
+	case sobek.ArrayBuffer:
+		return val.Bytes(), nil
+	}
+
+	val, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,126 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package acme
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+)
+
+const (
+	nonceHeader = "Replay-Nonce"
+	nonceError  = "urn:ietf:params:acme:error:badNonce"
+)
+
+// Session creates ACME requests with the latest server provided nonce and the account URL.
+type Session struct {
+	rt    *sobek.Runtime
+	key   *jose.JSONWebKey
+	kid   string
+	nonce string
+}
+
+func (m *Module) CreateSession(key *jose.JSONWebKey, options *RequestOptions) *sobek.Object {
+	s := &Session{rt: m.vu.Runtime(), key: key}
+
+	if options != nil {
+		s.kid = options.KeyID
+	}
+
+	return jsmodule.Exports(s.rt, s)
+}
+
+// Request creates ACME request JWS, with jwk header until the account URL is known.
+func (s *Session) Request(url string, payload interface{}) (string, error) {
+	return request(s.key, url, s.nonce, payload, s.kid)
+}
+
+func (s *Session) Nonce() string {
+	return s.nonce
+}
+
+func (s *Session) SetNonce(nonce string) {
+	s.nonce = nonce
+}
+
+func (s *Session) Kid() string {
+	return s.kid
+}
+
+func (s *Session) SetKid(kid string) {
+	s.kid = kid
+}
+
+// Update stores the nonce (and the account URL of newAccount response), returns true if the request has to be retried.
+func (s *Session) Update(response sobek.Value) bool {
+	if !defined(response) {
+		return false
+	}
+
+	res := response.ToObject(s.rt)
+
+	var nonce, location string
+
+	if headers := res.Get("headers"); defined(headers) {
+		obj := headers.ToObject(s.rt)
+
+		for _, k := range obj.Keys() {
+			switch http.CanonicalHeaderKey(k) {
+			case nonceHeader:
+				nonce = obj.Get(k).String()
+			case "Location":
+				location = obj.Get(k).String()
+			}
+		}
+	}
+
+	if nonce != "" {
+		s.nonce = nonce
+	}
+
+	var code int64
+
+	if status := res.Get("status"); defined(status) {
+		code = status.ToInteger()
+	}
+
+	if s.kid == "" && location != "" && (code == http.StatusOK || code == http.StatusCreated) {
+		s.kid = location
+	}
+
+	if code != http.StatusBadRequest || nonce == "" {
+		return false
+	}
+
+	body := res.Get("body")
+
+	return defined(body) && strings.Contains(body.String(), nonceError)
+}
+
+func defined(v sobek.Value) bool {
+	return v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v)
+}
@@ -0,0 +1,473 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cose
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Minimal CBOR (RFC 8949) codec for COSE structures: definite length items only, encoded deterministically.
+
+var ErrMalformed = errors.New("malformed CBOR")
+
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+
+	simpleFalse   = 20
+	simpleTrue    = 21
+	simpleNull    = 22
+	simpleFloat16 = 25
+	simpleFloat32 = 26
+	simpleFloat64 = 27
+
+	maxDepth = 64
+)
+
+type tag struct {
+	number  uint64
+	content interface{}
+}
+
+type encoder struct {
+	strings.Builder
+}
+
+func encode(v interface{}) ([]byte, error) {
+	e := &encoder{}
+
+	if err := e.encode(v); err != nil {
+		return nil, err
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *encoder) head(major byte, n uint64) {
+	var buff [9]byte
+
+	switch {
+	case n < 24:
+		e.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		e.WriteByte(major<<5 | 24)
+		e.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buff[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(buff[1:], uint16(n))
+		e.Write(buff[:3])
+	case n <= math.MaxUint32:
+		buff[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(buff[1:], uint32(n))
+		e.Write(buff[:5])
+	default:
+		buff[0] = major<<5 | 27
+		binary.BigEndian.PutUint64(buff[1:], n)
+		e.Write(buff[:9])
+	}
+}
+
+func (e *encoder) integer(n int64) {
+	if n < 0 {
+		e.head(majorNegative, uint64(-(n + 1)))
+	} else {
+		e.head(majorUnsigned, uint64(n))
+	}
+}
+
+// float writes the shortest of the half, single and double precision forms which keeps the value.
+func (e *encoder) float(v float64) {
+	var buff [9]byte
+
+	if h, ok := halfBits(v); ok {
+		buff[0] = majorSimple<<5 | simpleFloat16
+		binary.BigEndian.PutUint16(buff[1:], h)
+		e.Write(buff[:3])
+
+		return
+	}
+
+	if f := float32(v); float64(f) == v {
+		buff[0] = majorSimple<<5 | simpleFloat32
+		binary.BigEndian.PutUint32(buff[1:], math.Float32bits(f))
+		e.Write(buff[:5])
+
+		return
+	}
+
+	buff[0] = majorSimple<<5 | simpleFloat64
+	binary.BigEndian.PutUint64(buff[1:], math.Float64bits(v))
+	e.Write(buff[:])
+}
+
+func (e *encoder) encode(in interface{}) error {
+	switch v := in.(type) {
+	case nil:
+		e.WriteByte(majorSimple<<5 | simpleNull)
+	case bool:
+		if v {
+			e.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			e.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+	case int:
+		e.integer(int64(v))
+	case int64:
+		e.integer(v)
+	case uint64:
+		e.head(majorUnsigned, v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			e.integer(int64(v))
+
+			return nil
+		}
+
+		e.float(v)
+	case string:
+		e.head(majorText, uint64(len(v)))
+		e.WriteString(v)
+	case []byte:
+		e.head(majorBytes, uint64(len(v)))
+		e.Write(v)
+	case []interface{}:
+		e.head(majorArray, uint64(len(v)))
+
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case []string:
+		e.head(majorArray, uint64(len(v)))
+
+		for _, item := range v {
+			e.head(majorText, uint64(len(item)))
+			e.WriteString(item)
+		}
+	case map[interface{}]interface{}:
+		return e.encodeMap(v)
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, item := range v {
+			m[k] = item
+		}
+
+		return e.encodeMap(m)
+	case *tag:
+		e.head(majorTag, v.number)
+
+		return e.encode(v.content)
+	default:
+		return fmt.Errorf("%w: unsupported type %T", ErrMalformed, in)
+	}
+
+	return nil
+}
+
+// encodeMap sorts the entries by the bytewise order of the encoded keys (RFC 8949 core deterministic encoding).
+func (e *encoder) encodeMap(m map[interface{}]interface{}) error {
+	type entry struct {
+		key   string
+		value interface{}
+	}
+
+	entries := make([]entry, 0, len(m))
+
+	for k, v := range m {
+		key, err := encode(k)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entry{key: string(key), value: v})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	e.head(majorMap, uint64(len(entries)))
+
+	for _, item := range entries {
+		e.WriteString(item.key)
+
+		if err := e.encode(item.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func decode(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrMalformed, len(data)-d.pos)
+	}
+
+	return v, nil
+}
+
+func (d *decoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+
+	return b, nil
+}
+
+func (d *decoder) head() (byte, byte, uint64, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	major, info := b[0]>>5, b[0]&0x1f
+
+	var size uint64
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		size = 1 << (info - 24)
+	default:
+		return 0, 0, 0, fmt.Errorf("%w: indefinite length or reserved info %d", ErrMalformed, info)
+	}
+
+	arg, err := d.next(size)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var n uint64
+
+	for _, c := range arg {
+		n = n<<8 | uint64(c)
+	}
+
+	return major, info, n, nil
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrMalformed)
+	}
+
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUnsigned:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+
+		return int64(n), nil
+	case majorNegative:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: negative integer overflow", ErrMalformed)
+		}
+
+		return -int64(n) - 1, nil
+	case majorBytes:
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte{}, b...), nil
+	case majorText:
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	case majorArray:
+		return d.array(n, depth)
+	case majorMap:
+		return d.mapping(n, depth)
+	case majorTag:
+		content, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tag{number: n, content: content}, nil
+	default:
+		return simple(info, n)
+	}
+}
+
+func (d *decoder) array(n uint64, depth int) (interface{}, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: array too long", ErrMalformed)
+	}
+
+	arr := make([]interface{}, 0, n)
+
+	for i := uint64(0); i < n; i++ {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		arr = append(arr, item)
+	}
+
+	return arr, nil
+}
+
+func (d *decoder) mapping(n uint64, depth int) (interface{}, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: map too long", ErrMalformed)
+	}
+
+	m := make(map[interface{}]interface{}, n)
+
+	for i := uint64(0); i < n; i++ {
+		k, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		switch k.(type) {
+		case int64, string:
+		default:
+			return nil, fmt.Errorf("%w: unsupported map key type %T", ErrMalformed, k)
+		}
+
+		if _, ok := m[k]; ok {
+			return nil, fmt.Errorf("%w: duplicate map key %v", ErrMalformed, k)
+		}
+
+		if m[k], err = d.decode(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func simple(info byte, n uint64) (interface{}, error) {
+	switch info {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull:
+		return nil, nil
+	case simpleFloat16:
+		return halfFloat(uint16(n)), nil
+	case simpleFloat32:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case simpleFloat64:
+		return math.Float64frombits(n), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported simple value %d", ErrMalformed, info)
+	}
+}
+
+// halfBits returns the half precision form of v if it is exact, NaN is encoded as 0x7e00.
+func halfBits(v float64) (uint16, bool) {
+	if math.IsNaN(v) {
+		return 0x7e00, true
+	}
+
+	var sign uint16
+	if math.Signbit(v) {
+		sign = 0x8000
+	}
+
+	abs := math.Abs(v)
+
+	var h uint16
+
+	switch frac, exp := math.Frexp(abs); {
+	case abs == 0:
+		h = sign
+	case math.IsInf(abs, 0):
+		h = sign | 0x7c00
+	case exp > 16:
+		return 0, false
+	case exp > -14:
+		h = sign | uint16(exp+14)<<10 | uint16(math.Ldexp(2*frac-1, 10))
+	default:
+		h = sign | uint16(math.Ldexp(abs, 24))
+	}
+
+	return h, halfFloat(h) == v
+}
+
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var val float64
+
+	switch exp {
+	case 0:
+		val = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			val = math.Inf(1)
+		} else {
+			val = math.NaN()
+		}
+	default:
+		val = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		val = -val
+	}
+
+	return val
+}
@@ -0,0 +1,132 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cose
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func cborSamples() []interface{} {
+	return []interface{}{
+		0, 23, 24, 255, 256, 65535, 65536, int64(math.MaxUint32) + 1, int64(math.MaxInt64), uint64(math.MaxUint64),
+		-1, -24, -25, -256, -257, int64(math.MinInt64),
+		0.5, -1.5, 65504.5, 1e-7, 5.960464477539063e-08, 3.4028234663852886e+38, 1.1, math.Inf(1), math.Inf(-1),
+		true, false, nil,
+		"", "a", "ü水", string(make([]byte, 300)),
+		[]byte{}, []byte{1, 2, 3}, make([]byte, 70000),
+		[]interface{}{}, []interface{}{1, "two", []interface{}{3.5}},
+		[]string{"a", "bb"},
+		map[interface{}]interface{}{int64(1): -7, int64(-1): "x", "kid": []byte("k"), int64(100): 1, "a": 2, "aa": 3},
+		map[string]interface{}{"b": 1, "a": map[string]interface{}{"z": nil, "y": []interface{}{}}},
+		&tag{number: tagSign1, content: []interface{}{[]byte{0xa0}, map[interface{}]interface{}{}, nil, []byte{}}},
+		&tag{number: 1 << 40, content: "big tag"},
+	}
+}
+
+// TestEncodeDeterministic checks the encoding against core deterministic encoding of fxamacker/cbor.
+func TestEncodeDeterministic(t *testing.T) {
+	t.Parallel()
+
+	mode, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sample := range cborSamples() {
+		ours, err := encode(sample)
+		if err != nil {
+			t.Fatalf("%v: %v", sample, err)
+		}
+
+		var decoded interface{}
+
+		if err := cbor.Unmarshal(ours, &decoded); err != nil {
+			t.Fatalf("%x: %v", ours, err)
+		}
+
+		theirs, err := mode.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(ours, theirs) {
+			t.Errorf("%v: encoded %x, deterministic %x", sample, ours, theirs)
+		}
+	}
+}
+
+// TestDecode decodes the output of fxamacker/cbor and encodes it again.
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	mode, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sample := range cborSamples() {
+		if tagged, ok := sample.(*tag); ok {
+			sample = cbor.Tag{Number: tagged.number, Content: tagged.content}
+		}
+
+		theirs, err := mode.Marshal(sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := decode(theirs)
+		if err != nil {
+			t.Fatalf("%x: %v", theirs, err)
+		}
+
+		ours, err := encode(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(ours, theirs) {
+			t.Errorf("%x: decoded %v, encoded again %x", theirs, decoded, ours)
+		}
+	}
+}
+
+// TestDecodeMalformed checks items fxamacker/cbor rejects too, and the unsupported indefinite length items.
+func TestDecodeMalformed(t *testing.T) {
+	t.Parallel()
+
+	for _, item := range []string{"", "18", "1b00", "62ff", "8201", "a101", "a201010102", "5f4101ff", "9fff", "fc"} {
+		data, err := hex.DecodeString(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := decode(data); err == nil {
+			t.Errorf("%s: decoded", item)
+		}
+	}
+}
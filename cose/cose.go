@@ -0,0 +1,427 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cose
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	vu modules.VU
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return &Module{vu: vu} })
+}
+
+var ErrInvalidMessage = errors.New("invalid COSE message")
+
+const (
+	tagSign1      = 18
+	sign1Context  = "Signature1"
+	sign1Elements = 4
+	headerAlg     = 1
+	headerKeyID   = 4
+)
+
+// header labels of the common parameters (RFC 9052 section 3.1)
+var headerLabels = map[string]int64{
+	"alg":        headerAlg,
+	"crit":       2,
+	"cty":        3,
+	"kid":        headerKeyID,
+	"iv":         5,
+	"partial_iv": 6,
+}
+
+type Sign1Options struct {
+	Protected   map[string]interface{} `js:"protected"`
+	Unprotected map[string]interface{} `js:"unprotected"`
+	AAD         interface{}            `js:"aad"`
+	Detached    bool                   `js:"detached"`
+	Untagged    bool                   `js:"untagged"`
+}
+
+type VerifyOptions struct {
+	AAD     interface{} `js:"aad"`
+	Payload interface{} `js:"payload"`
+}
+
+type Verified struct {
+	Payload     sobek.ArrayBuffer      `js:"payload"`
+	Protected   map[string]interface{} `js:"protected"`
+	Unprotected map[string]interface{} `js:"unprotected"`
+	KeyID       string                 `js:"kid"`
+	Algorithm   string                 `js:"alg"`
+	Key         *jose.JSONWebKey       `js:"key"`
+}
+
+func (m *Module) Sign1(
+	key *jose.JSONWebKey,
+	payloadIn interface{},
+	options *Sign1Options,
+) (sobek.ArrayBuffer, error) {
+	payload, err := buffer.Bytes(payloadIn)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	if options == nil {
+		options = &Sign1Options{}
+	}
+
+	msg, err := sign1(key, payload, options)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(msg), nil
+}
+
+// sign1 creates COSE_Sign1 message, kid of the key goes to the unprotected header unless given.
+// Keys without alg use the default algorithm of the key type.
+func sign1(key *jose.JSONWebKey, payload []byte, options *Sign1Options) ([]byte, error) {
+	name := key.Algorithm
+	if name == "" {
+		name = keyAlgorithm(key)
+	}
+
+	alg, ok := algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, name)
+	}
+
+	protected := headerMap(options.Protected)
+	protected[int64(headerAlg)] = alg
+
+	unprotected := headerMap(options.Unprotected)
+
+	_, inProtected := protected[int64(headerKeyID)]
+	_, inUnprotected := unprotected[int64(headerKeyID)]
+
+	if key.KeyID != "" && !inProtected && !inUnprotected {
+		unprotected[int64(headerKeyID)] = []byte(key.KeyID)
+	}
+
+	encodedProtected, err := encode(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	aad, err := buffer.Bytes(options.AAD)
+	if err != nil {
+		return nil, err
+	}
+
+	toBeSigned, err := sigStructure(encodedProtected, aad, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := sign(key, name, toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+
+	var content interface{} = payload
+	if options.Detached {
+		content = nil
+	}
+
+	var msg interface{} = []interface{}{encodedProtected, unprotected, content, sig}
+	if !options.Untagged {
+		msg = &tag{number: tagSign1, content: msg}
+	}
+
+	return encode(msg)
+}
+
+func sigStructure(protected, aad, payload []byte) ([]byte, error) {
+	if aad == nil {
+		aad = []byte{}
+	}
+
+	return encode([]interface{}{sign1Context, protected, aad, payload})
+}
+
+// headerMap converts header from JS: known parameter names and numeric strings are converted to labels,
+// kid given as string is converted to bytes.
+func headerMap(in map[string]interface{}) map[interface{}]interface{} {
+	header := make(map[interface{}]interface{}, len(in)+1)
+
+	for k, v := range in {
+		var label interface{} = k
+
+		if l, ok := headerLabels[k]; ok {
+			label = l
+		} else if l, err := strconv.ParseInt(k, 10, 64); err == nil {
+			label = l
+		}
+
+		if s, ok := v.(string); ok && label == int64(headerKeyID) {
+			v = []byte(s)
+		}
+
+		if b, ok := v.(sobek.ArrayBuffer); ok {
+			v = b.Bytes()
+		}
+
+		header[label] = v
+	}
+
+	return header
+}
+
+func (m *Module) Verify1(
+	messageIn interface{},
+	keys interface{},
+	options *VerifyOptions,
+) (*Verified, error) {
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+
+	msg, err := buffer.Bytes(messageIn)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := verify1(msg, keys, options)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := m.vu.Runtime()
+
+	return &Verified{
+		Payload:     rt.NewArrayBuffer(v.payload),
+		Protected:   headerObject(rt, v.protected),
+		Unprotected: headerObject(rt, v.unprotected),
+		KeyID:       v.kid,
+		Algorithm:   v.alg,
+		Key:         v.key,
+	}, nil
+}
+
+type verified struct {
+	payload     []byte
+	protected   map[interface{}]interface{}
+	unprotected map[interface{}]interface{}
+	kid         string
+	alg         string
+	key         *jose.JSONWebKey
+}
+
+func verify1(msg []byte, keysIn interface{}, options *VerifyOptions) (*verified, error) {
+	parts, err := parseSign1(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedProtected, _ := parts[0].([]byte)
+
+	protected := map[interface{}]interface{}{}
+
+	if len(encodedProtected) != 0 {
+		decoded, err := decode(encodedProtected)
+		if err != nil {
+			return nil, err
+		}
+
+		if protected, _ = decoded.(map[interface{}]interface{}); protected == nil {
+			return nil, fmt.Errorf("%w: protected header is not a map", ErrInvalidMessage)
+		}
+	}
+
+	unprotected, ok := parts[1].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: unprotected header is not a map", ErrInvalidMessage)
+	}
+
+	payload, err := messagePayload(parts[2], options)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, ok := parts[3].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: signature is not a byte string", ErrInvalidMessage)
+	}
+
+	id, ok := protected[int64(headerAlg)].(int64)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing protected alg", ErrInvalidMessage)
+	}
+
+	alg, err := algorithmName(id)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := keyID(protected, unprotected)
+
+	aad, err := buffer.Bytes(options.AAD)
+	if err != nil {
+		return nil, err
+	}
+
+	toBeSigned, err := sigStructure(encodedProtected, aad, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := verifyWithKeys(keysIn, kid, alg, toBeSigned, sig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verified{
+		payload:     payload,
+		protected:   protected,
+		unprotected: unprotected,
+		kid:         kid,
+		alg:         alg,
+		key:         key,
+	}, nil
+}
+
+// parseSign1 returns the elements of COSE_Sign1 message, the tag is optional.
+func parseSign1(msg []byte) ([]interface{}, error) {
+	decoded, err := decode(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// CWT tag is optional around the COSE_Sign1 tag
+	if t, ok := decoded.(*tag); ok && t.number == tagCWT {
+		decoded = t.content
+	}
+
+	if t, ok := decoded.(*tag); ok {
+		if t.number != tagSign1 {
+			return nil, fmt.Errorf("%w: unexpected tag %d", ErrInvalidMessage, t.number)
+		}
+
+		decoded = t.content
+	}
+
+	parts, ok := decoded.([]interface{})
+	if !ok || len(parts) != sign1Elements {
+		return nil, fmt.Errorf("%w: COSE_Sign1 must be array of %d elements", ErrInvalidMessage, sign1Elements)
+	}
+
+	return parts, nil
+}
+
+func messagePayload(content interface{}, options *VerifyOptions) ([]byte, error) {
+	if content == nil {
+		payload, err := buffer.Bytes(options.Payload)
+		if err != nil {
+			return nil, err
+		}
+
+		if payload == nil {
+			return nil, fmt.Errorf("%w: detached payload required", ErrInvalidMessage)
+		}
+
+		return payload, nil
+	}
+
+	payload, ok := content.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: payload is not a byte string", ErrInvalidMessage)
+	}
+
+	return payload, nil
+}
+
+func keyID(headers ...map[interface{}]interface{}) string {
+	for _, h := range headers {
+		if kid, ok := h[int64(headerKeyID)].([]byte); ok {
+			return string(kid)
+		}
+	}
+
+	return ""
+}
+
+// verifyWithKeys tries the keys with matching kid first, then every key.
+func verifyWithKeys(keysIn interface{}, kid string, alg string, data []byte, sig []byte) (*jose.JSONWebKey, error) {
+	set, err := jwk.KeySet(keysIn)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := jwk.Candidates(set, kid)
+
+	for i := range candidates {
+		if err := verify(&candidates[i], alg, data, sig); err == nil {
+			return &candidates[i], nil
+		}
+	}
+
+	return nil, ErrVerification
+}
+
+// headerObject converts header for JS: known labels to parameter names, alg to JOSE name, byte strings to ArrayBuffer.
+func headerObject(rt *sobek.Runtime, header map[interface{}]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(header))
+
+	for k, v := range header {
+		name := fmt.Sprint(k)
+
+		for n, label := range headerLabels {
+			if k == label {
+				name = n
+			}
+		}
+
+		switch value := v.(type) {
+		case []byte:
+			if k == int64(headerKeyID) {
+				v = string(value)
+			} else {
+				v = rt.NewArrayBuffer(value)
+			}
+		case int64:
+			if k == int64(headerAlg) {
+				if alg, err := algorithmName(value); err == nil {
+					v = alg
+				}
+			}
+		}
+
+		out[name] = v
+	}
+
+	return out
+}
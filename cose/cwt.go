@@ -0,0 +1,238 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cose
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+)
+
+var ErrInvalidClaims = errors.New("invalid CWT claims")
+
+const (
+	tagCWT   = 61
+	claimCTI = 7
+)
+
+// CWT claim keys (RFC 8392 section 4, RFC 8747)
+var claimLabels = map[string]int64{
+	"iss":   1,
+	"sub":   2,
+	"aud":   3,
+	"exp":   4,
+	"nbf":   5,
+	"iat":   6,
+	"cti":   claimCTI,
+	"cnf":   8,
+	"scope": 9,
+}
+
+type CWTOptions struct {
+	AAD    interface{} `js:"aad"`
+	Leeway int64       `js:"leeway"`
+}
+
+// SignCWT creates CBOR Web Token as COSE_Sign1 message, claim names are converted to claim keys.
+func (m *Module) SignCWT(
+	key *jose.JSONWebKey,
+	claims map[string]interface{},
+	options *Sign1Options,
+) (sobek.ArrayBuffer, error) {
+	if options == nil {
+		options = &Sign1Options{}
+	}
+
+	payload, err := encode(cwtClaims(claims))
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	msg, err := sign1(key, payload, options)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(msg), nil
+}
+
+func cwtClaims(claims map[string]interface{}) map[interface{}]interface{} {
+	out := make(map[interface{}]interface{}, len(claims))
+
+	for k, v := range claims {
+		label, ok := claimLabels[k]
+		if !ok {
+			out[mapKey(k)] = cborValue(v)
+
+			continue
+		}
+
+		if s, isString := v.(string); isString && label == claimCTI {
+			v = []byte(s)
+		}
+
+		out[label] = cborValue(v)
+	}
+
+	return out
+}
+
+// cborValue converts JS values: ArrayBuffer to byte string, numeric keys of nested objects to integers.
+func cborValue(in interface{}) interface{} {
+	switch v := in.(type) {
+	case sobek.ArrayBuffer:
+		return v.Bytes()
+	case map[string]interface{}:
+		out := make(map[interface{}]interface{}, len(v))
+		for k, item := range v {
+			out[mapKey(k)] = cborValue(item)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = cborValue(item)
+		}
+
+		return out
+	default:
+		return in
+	}
+}
+
+func mapKey(k string) interface{} {
+	if n, err := strconv.ParseInt(k, 10, 64); err == nil {
+		return n
+	}
+
+	return k
+}
+
+// VerifyCWT verifies CBOR Web Token and returns its claims, exp and nbf are checked.
+func (m *Module) VerifyCWT(
+	messageIn interface{},
+	keys interface{},
+	options *CWTOptions,
+) (map[string]interface{}, error) {
+	if options == nil {
+		options = &CWTOptions{}
+	}
+
+	msg, err := buffer.Bytes(messageIn)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := verify1(msg, keys, &VerifyOptions{AAD: options.AAD})
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decode(v.payload)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: claims are not a map", ErrInvalidClaims)
+	}
+
+	if err := checkTime(claims, options.Leeway); err != nil {
+		return nil, err
+	}
+
+	rt := m.vu.Runtime()
+	out := make(map[string]interface{}, len(claims))
+
+	for k, item := range claims {
+		name := fmt.Sprint(k)
+
+		for n, label := range claimLabels {
+			if k == label {
+				name = n
+			}
+		}
+
+		out[name] = jsValue(rt, item)
+	}
+
+	return out, nil
+}
+
+func checkTime(claims map[interface{}]interface{}, leeway int64) error {
+	now := time.Now().Unix()
+
+	if exp, ok := numeric(claims[claimLabels["exp"]]); ok && now > exp+leeway {
+		return fmt.Errorf("%w: expired", ErrInvalidClaims)
+	}
+
+	if nbf, ok := numeric(claims[claimLabels["nbf"]]); ok && now < nbf-leeway {
+		return fmt.Errorf("%w: not yet valid", ErrInvalidClaims)
+	}
+
+	return nil
+}
+
+func numeric(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// jsValue converts decoded CBOR for JS: byte strings to ArrayBuffer, map keys to strings, tags to their content.
+func jsValue(rt *sobek.Runtime, in interface{}) interface{} {
+	switch v := in.(type) {
+	case []byte:
+		return rt.NewArrayBuffer(v)
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[fmt.Sprint(k)] = jsValue(rt, item)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = jsValue(rt, item)
+		}
+
+		return out
+	case *tag:
+		return jsValue(rt, v.content)
+	default:
+		return in
+	}
+}
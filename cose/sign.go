@@ -0,0 +1,175 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrVerification         = errors.New("signature verification failed")
+)
+
+// COSE algorithm identifiers of the JOSE signature algorithms (RFC 9053, RFC 8230).
+var algorithms = map[string]int64{
+	string(jose.ES256): -7,
+	string(jose.ES384): -35,
+	string(jose.ES512): -36,
+	string(jose.EdDSA): -8,
+	string(jose.PS256): -37,
+	string(jose.PS384): -38,
+	string(jose.PS512): -39,
+	string(jose.RS256): -257,
+	string(jose.RS384): -258,
+	string(jose.RS512): -259,
+}
+
+// ES* algorithms of the ECDSA curves, the algorithm must match the curve of the key.
+var curveAlgorithms = map[string]string{
+	"P-256": string(jose.ES256),
+	"P-384": string(jose.ES384),
+	"P-521": string(jose.ES512),
+}
+
+// keyAlgorithm returns the default algorithm of a key without alg: ES* by curve, EdDSA or RS256.
+func keyAlgorithm(key *jose.JSONWebKey) string {
+	switch pub := key.Public().Key.(type) {
+	case ed25519.PublicKey:
+		return string(jose.EdDSA)
+	case *ecdsa.PublicKey:
+		return curveAlgorithms[pub.Curve.Params().Name]
+	case *rsa.PublicKey:
+		return string(jose.RS256)
+	default:
+		return ""
+	}
+}
+
+func algorithmName(id int64) (string, error) {
+	for name, value := range algorithms {
+		if value == id {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %d", ErrUnsupportedAlgorithm, id)
+}
+
+func hashOf(alg string) crypto.Hash {
+	switch alg[len(alg)-3:] {
+	case "384":
+		return crypto.SHA384
+	case "512":
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func digest(alg string, data []byte) []byte {
+	h := hashOf(alg).New()
+	h.Write(data)
+
+	return h.Sum(nil)
+}
+
+func sign(key *jose.JSONWebKey, alg string, data []byte) ([]byte, error) {
+	switch priv := key.Key.(type) {
+	case ed25519.PrivateKey:
+		if alg != string(jose.EdDSA) {
+			break
+		}
+
+		return ed25519.Sign(priv, data), nil
+	case *ecdsa.PrivateKey:
+		if curveAlgorithms[priv.Curve.Params().Name] != alg {
+			break
+		}
+
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest(alg, data))
+		if err != nil {
+			return nil, err
+		}
+
+		size := (priv.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+
+		return sig, nil
+	case *rsa.PrivateKey:
+		switch alg[:2] {
+		case "PS":
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+
+			return rsa.SignPSS(rand.Reader, priv, hashOf(alg), digest(alg, data), opts)
+		case "RS":
+			return rsa.SignPKCS1v15(rand.Reader, priv, hashOf(alg), digest(alg, data))
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s with %T", ErrUnsupportedAlgorithm, alg, key.Key)
+}
+
+func verify(key *jose.JSONWebKey, alg string, data []byte, sig []byte) error {
+	public := key.Public()
+
+	ok := false
+
+	switch pub := public.Key.(type) {
+	case ed25519.PublicKey:
+		ok = alg == string(jose.EdDSA) && ed25519.Verify(pub, data, sig)
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if curveAlgorithms[pub.Curve.Params().Name] == alg && len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(pub, digest(alg, data), r, s)
+		}
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "PS":
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+			ok = rsa.VerifyPSS(pub, hashOf(alg), digest(alg, data), sig, opts) == nil
+		case "RS":
+			ok = rsa.VerifyPKCS1v15(pub, hashOf(alg), digest(alg, data), sig) == nil
+		}
+	}
+
+	if !ok {
+		return ErrVerification
+	}
+
+	return nil
+}
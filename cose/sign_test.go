@@ -0,0 +1,73 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cose
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+func TestVerifyCurve(t *testing.T) {
+	t.Parallel()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("data")
+	sum := sha512.Sum512(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, priv, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := make([]byte, 64)
+
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	key := &jose.JSONWebKey{Key: priv}
+
+	if err := verify(key, string(jose.ES512), data, sig); err == nil {
+		t.Error("ES512 verified with P-256 key")
+	}
+
+	if _, err := sign(key, string(jose.ES512), data); err == nil {
+		t.Error("ES512 signed with P-256 key")
+	}
+
+	if sig, err = sign(key, string(jose.ES256), data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verify(key, string(jose.ES256), data, sig); err != nil {
+		t.Error(err)
+	}
+}
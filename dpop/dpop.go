@@ -0,0 +1,117 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package dpop
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	vu modules.VU
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return &Module{vu: vu} })
+}
+
+var ErrInvalidURL = errors.New("invalid url")
+
+const proofType = "dpop+jwt"
+
+type ProofOptions struct {
+	Method      string                 `js:"method"`
+	URL         string                 `js:"url"`
+	AccessToken string                 `js:"accessToken"`
+	Nonce       string                 `js:"nonce"`
+	Claims      map[string]interface{} `js:"claims"`
+}
+
+func (m *Module) Proof(key *jose.JSONWebKey, options *ProofOptions) (string, error) {
+	return proof(key, options)
+}
+
+// proof creates DPoP proof JWT (RFC 9449) with the public key in the jwk header.
+func proof(key *jose.JSONWebKey, options *ProofOptions) (string, error) {
+	if options == nil {
+		options = &ProofOptions{}
+	}
+
+	htu, err := targetURI(options.URL)
+	if err != nil {
+		return "", err
+	}
+
+	method := options.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	claims := map[string]interface{}{
+		"jti": jwt.RandomID(),
+		"htm": strings.ToUpper(method),
+		"htu": htu,
+		"iat": time.Now().Unix(),
+	}
+
+	if options.AccessToken != "" {
+		claims["ath"] = accessTokenHash(options.AccessToken)
+	}
+
+	if options.Nonce != "" {
+		claims["nonce"] = options.Nonce
+	}
+
+	header := map[string]interface{}{"typ": proofType, "jwk": key.Public()}
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+}
+
+// accessTokenHash returns the ath claim value of the access token.
+func accessTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// targetURI returns the URL without query and fragment.
+func targetURI(src string) (string, error) {
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidURL, src)
+	}
+
+	u.RawQuery, u.Fragment, u.RawFragment = "", "", ""
+
+	return u.String(), nil
+}
@@ -0,0 +1,122 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package dpop
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+)
+
+const (
+	nonceHeader = "DPoP-Nonce"
+	nonceError  = "use_dpop_nonce"
+)
+
+// Session creates DPoP proofs with the latest server provided nonce.
+type Session struct {
+	rt    *sobek.Runtime
+	key   *jose.JSONWebKey
+	nonce string
+}
+
+func (m *Module) CreateSession(key *jose.JSONWebKey) *sobek.Object {
+	s := &Session{rt: m.vu.Runtime(), key: key}
+
+	return jsmodule.Exports(s.rt, s)
+}
+
+// Proof creates DPoP proof, with the stored nonce unless the options contain one.
+func (s *Session) Proof(options *ProofOptions) (string, error) {
+	opts := ProofOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	if opts.Nonce == "" {
+		opts.Nonce = s.nonce
+	}
+
+	return proof(s.key, &opts)
+}
+
+func (s *Session) Nonce() string {
+	return s.nonce
+}
+
+func (s *Session) SetNonce(nonce string) {
+	s.nonce = nonce
+}
+
+// Update stores the nonce of the response (if any), returns true if the request has to be retried with the new nonce.
+// Header names are matched case-insensitively like in HTTP, the nonce is an opaque value compared exactly.
+func (s *Session) Update(response sobek.Value) bool {
+	if !defined(response) {
+		return false
+	}
+
+	res := response.ToObject(s.rt)
+
+	var nonce, challenge string
+
+	if headers := res.Get("headers"); defined(headers) {
+		obj := headers.ToObject(s.rt)
+
+		for _, k := range obj.Keys() {
+			switch http.CanonicalHeaderKey(k) {
+			case http.CanonicalHeaderKey(nonceHeader):
+				nonce = obj.Get(k).String()
+			case "Www-Authenticate":
+				challenge = obj.Get(k).String()
+			}
+		}
+	}
+
+	// nonces differing only in case are different nonces
+	if nonce == "" || nonce == s.nonce {
+		return false
+	}
+
+	s.nonce = nonce
+
+	status := res.Get("status")
+	if !defined(status) || (status.ToInteger() != http.StatusBadRequest && status.ToInteger() != http.StatusUnauthorized) {
+		return false
+	}
+
+	// resource servers use WWW-Authenticate challenge, authorization servers use error response
+	if strings.Contains(challenge, nonceError) {
+		return true
+	}
+
+	body := res.Get("body")
+
+	return defined(body) && strings.Contains(body.String(), nonceError)
+}
+
+func defined(v sobek.Value) bool {
+	return v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v)
+}
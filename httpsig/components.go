@@ -0,0 +1,259 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package httpsig
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// component is a component identifier: derived component (e.g. @method) or field name, with parameters.
+type component struct {
+	name   string
+	params params
+}
+
+func (c component) String() string {
+	return quote(c.name) + c.params.String()
+}
+
+// parseComponent parses the component identifier, serialized (`"@query-param";name="id"`) or plain (`@query-param;name=id`).
+func parseComponent(src string) (component, error) {
+	src = strings.TrimSpace(src)
+
+	if strings.HasPrefix(src, `"`) {
+		p := &parser{src: src}
+
+		name, err := p.string()
+		if err != nil {
+			return component{}, err
+		}
+
+		c := component{name: name}
+
+		if c.params, err = p.params(); err != nil {
+			return component{}, err
+		}
+
+		if p.pos != len(src) {
+			return component{}, p.errorf("unexpected %q", src[p.pos:])
+		}
+
+		return c, nil
+	}
+
+	parts := strings.Split(src, ";")
+	c := component{name: strings.ToLower(strings.TrimSpace(parts[0]))}
+
+	for _, part := range parts[1:] {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			c.params = append(c.params, param{key: key, value: true})
+
+			continue
+		}
+
+		c.params = append(c.params, param{key: key, value: strings.Trim(value, `"`)})
+	}
+
+	return c, nil
+}
+
+// Message is the HTTP request or response whose components are signed or verified.
+type Message struct {
+	Method  string                 `js:"method"`
+	URL     string                 `js:"url"`
+	Status  int                    `js:"status"`
+	Headers map[string]interface{} `js:"headers"`
+	Body    interface{}            `js:"body"`
+}
+
+// header returns the field value: values of repeated fields (given as array) are joined with comma.
+func (msg *Message) header(name string) (string, bool) {
+	for key, value := range msg.Headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			return strings.TrimSpace(v), true
+		case []interface{}:
+			values := make([]string, len(v))
+
+			for i := range v {
+				values[i] = strings.TrimSpace(fmt.Sprint(v[i]))
+			}
+
+			return strings.Join(values, ", "), true
+		default:
+			return strings.TrimSpace(fmt.Sprint(v)), true
+		}
+	}
+
+	return "", false
+}
+
+func (msg *Message) target() (*url.URL, error) {
+	u, err := url.Parse(msg.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: url %q", ErrInvalidComponent, msg.URL)
+	}
+
+	return u, nil
+}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// value returns the component value (RFC 9421 section 2).
+func (msg *Message) value(c component) (string, error) {
+	for _, p := range c.params {
+		if p.key != "name" || c.name != "@query-param" {
+			return "", fmt.Errorf("%w: %s parameter of %s is not supported", ErrInvalidComponent, p.key, c.name)
+		}
+	}
+
+	if !strings.HasPrefix(c.name, "@") {
+		value, ok := msg.header(c.name)
+		if !ok {
+			return "", fmt.Errorf("%w: missing %s header", ErrInvalidComponent, c.name)
+		}
+
+		return value, nil
+	}
+
+	if c.name == "@method" {
+		if msg.Method == "" {
+			return "GET", nil
+		}
+
+		return strings.ToUpper(msg.Method), nil
+	}
+
+	if c.name == "@status" {
+		if msg.Status == 0 {
+			return "", fmt.Errorf("%w: missing status", ErrInvalidComponent)
+		}
+
+		return strconv.Itoa(msg.Status), nil
+	}
+
+	u, err := msg.target()
+	if err != nil {
+		return "", err
+	}
+
+	switch c.name {
+	case "@target-uri":
+		return msg.URL, nil
+	case "@authority":
+		host, port := strings.ToLower(u.Hostname()), u.Port()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		if port != "" && port != defaultPorts[strings.ToLower(u.Scheme)] {
+			host += ":" + port
+		}
+
+		return host, nil
+	case "@scheme":
+		return strings.ToLower(u.Scheme), nil
+	case "@request-target":
+		return u.RequestURI(), nil
+	case "@path":
+		if path := u.EscapedPath(); path != "" {
+			return path, nil
+		}
+
+		return "/", nil
+	case "@query":
+		return "?" + u.RawQuery, nil
+	case "@query-param":
+		return queryParam(u, c)
+	default:
+		return "", fmt.Errorf("%w: unknown derived component %s", ErrInvalidComponent, c.name)
+	}
+}
+
+// queryParam returns the value of the query parameter, name and value are re-encoded (RFC 9421 section 2.2.8).
+func queryParam(u *url.URL, c component) (string, error) {
+	value, _ := c.params.get("name")
+
+	name, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: @query-param without name", ErrInvalidComponent)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return "", fmt.Errorf("%w: query %s", ErrInvalidComponent, err.Error())
+	}
+
+	decoded, err := url.QueryUnescape(name)
+	if err != nil {
+		decoded = name
+	}
+
+	values := query[decoded]
+	if len(values) != 1 {
+		return "", fmt.Errorf("%w: query parameter %s occurs %d times", ErrInvalidComponent, name, len(values))
+	}
+
+	return strings.ReplaceAll(url.QueryEscape(values[0]), "+", "%20"), nil
+}
+
+// signatureBase returns the signature base (RFC 9421 section 2.5) of the covered components,
+// with the serialized signature parameters.
+func signatureBase(msg *Message, components []component, signatureParams string) (string, error) {
+	var b strings.Builder
+
+	seen := make(map[string]bool, len(components))
+
+	for _, c := range components {
+		id := c.String()
+
+		if seen[id] || c.name == "@signature-params" {
+			return "", fmt.Errorf("%w: %s can't be covered", ErrInvalidComponent, id)
+		}
+
+		seen[id] = true
+
+		value, err := msg.value(c)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(id)
+		b.WriteString(": ")
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(signatureParams)
+
+	return b.String(), nil
+}
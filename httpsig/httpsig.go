@@ -0,0 +1,614 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct{}
+
+func New() modules.Module {
+	return jsmodule.New(func(modules.VU) interface{} { return &Module{} })
+}
+
+var (
+	ErrUnsupportedKey       = jwk.ErrUnsupportedKey
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrInvalidComponent     = errors.New("invalid component")
+	ErrMalformed            = errors.New("malformed signature")
+	ErrExpired              = errors.New("signature expired")
+	ErrNotCovered           = errors.New("component not covered")
+	ErrDigestMismatch       = errors.New("content digest mismatch")
+)
+
+const (
+	defaultLabel = "sig1"
+
+	headerSignature      = "Signature"
+	headerSignatureInput = "Signature-Input"
+	headerContentDigest  = "Content-Digest"
+)
+
+var defaultComponents = []string{"@method", "@authority", "@path"}
+
+// algorithms are the signature algorithms of HTTP Message Signatures (RFC 9421 section 3.3) with their JWS equivalent.
+var algorithms = map[string]jose.SignatureAlgorithm{
+	"rsa-pss-sha512":    jose.PS512,
+	"rsa-v1_5-sha256":   jose.RS256,
+	"hmac-sha256":       jose.HS256,
+	"ecdsa-p256-sha256": jose.ES256,
+	"ecdsa-p384-sha384": jose.ES384,
+	"ed25519":           jose.EdDSA,
+}
+
+var digests = map[string]crypto.Hash{"sha-256": crypto.SHA256, "sha-512": crypto.SHA512}
+
+type SignOptions struct {
+	Label      string      `js:"label"`
+	Components []string    `js:"components"`
+	Algorithm  string      `js:"alg"`
+	KeyID      *string     `js:"keyid"`
+	Created    int64       `js:"created"`
+	Lifetime   interface{} `js:"lifetime"`
+	Nonce      string      `js:"nonce"`
+	Tag        string      `js:"tag"`
+	Digest     string      `js:"digest"`
+}
+
+// Sign returns the Signature-Input and Signature header fields of the message (and Content-Digest on request).
+func (m *Module) Sign(key *jose.JSONWebKey, message *Message, options *SignOptions) (map[string]string, error) {
+	if options == nil {
+		options = &SignOptions{}
+	}
+
+	if message == nil {
+		message = &Message{}
+	}
+
+	name, alg, err := signatureAlgorithm(key, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, 3)
+
+	ids := options.Components
+	if ids == nil {
+		ids = defaultComponents
+	}
+
+	if options.Digest != "" {
+		if headers[headerContentDigest], err = contentDigest(options.Digest, message.Body); err != nil {
+			return nil, err
+		}
+
+		msg := *message
+		msg.Headers = make(map[string]interface{}, len(message.Headers)+1)
+
+		for k, v := range message.Headers {
+			if !strings.EqualFold(k, headerContentDigest) {
+				msg.Headers[k] = v
+			}
+		}
+
+		msg.Headers[headerContentDigest] = headers[headerContentDigest]
+		message = &msg
+
+		if !covers(ids, "content-digest") {
+			ids = append(append([]string{}, ids...), "content-digest")
+		}
+	}
+
+	components := make([]component, len(ids))
+
+	for i, id := range ids {
+		if components[i], err = parseComponent(id); err != nil {
+			return nil, err
+		}
+	}
+
+	sigParams, err := signatureParams(key, name, options)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, len(components))
+	for i := range components {
+		list[i] = components[i].String()
+	}
+
+	serialized := "(" + strings.Join(list, " ") + ")" + sigParams.String()
+
+	base, err := signatureBase(message, components, serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sign(key, alg, []byte(base))
+	if err != nil {
+		return nil, err
+	}
+
+	label := options.Label
+	if label == "" {
+		label = defaultLabel
+	}
+
+	headers[headerSignatureInput] = label + "=" + serialized
+	headers[headerSignature] = label + "=" + bareItem(signature)
+
+	return headers, nil
+}
+
+// signatureParams returns the signature parameters in the order of the RFC examples.
+func signatureParams(key *jose.JSONWebKey, alg string, options *SignOptions) (params, error) {
+	created := options.Created
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+
+	res := params{{key: "created", value: created}}
+
+	if options.Lifetime != nil {
+		lifetime, err := jwt.Duration(options.Lifetime)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, param{key: "expires", value: created + int64(lifetime/time.Second)})
+	}
+
+	keyid := key.KeyID
+	if options.KeyID != nil {
+		keyid = *options.KeyID
+	}
+
+	// alg is added only if requested, the key determines it otherwise
+	if options.Algorithm == "" {
+		alg = ""
+	}
+
+	for _, p := range []param{{"nonce", options.Nonce}, {"alg", alg}, {"keyid", keyid}, {"tag", options.Tag}} {
+		if p.value != "" {
+			res = append(res, p)
+		}
+	}
+
+	return res, nil
+}
+
+func covers(ids []string, name string) bool {
+	for _, id := range ids {
+		if c, err := parseComponent(id); err == nil && c.name == name && len(c.params) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contentDigest returns the Content-Digest field value (RFC 9530) of the body.
+func contentDigest(alg string, body interface{}) (string, error) {
+	hash, ok := digests[strings.ToLower(alg)]
+	if !ok {
+		return "", fmt.Errorf("%w: digest %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	content, err := buffer.Bytes(body)
+	if err != nil {
+		return "", err
+	}
+
+	h := hash.New()
+	_, _ = h.Write(content)
+
+	return strings.ToLower(alg) + "=" + bareItem(h.Sum(nil)), nil
+}
+
+// signatureAlgorithm returns the requested algorithm, or the algorithm of the key.
+func signatureAlgorithm(key *jose.JSONWebKey, requested string) (string, jose.SignatureAlgorithm, error) {
+	if requested != "" {
+		alg, ok := algorithms[requested]
+		if !ok {
+			return "", "", fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, requested)
+		}
+
+		return requested, alg, nil
+	}
+
+	for name, alg := range algorithms {
+		if string(alg) == key.Algorithm {
+			return name, alg, nil
+		}
+	}
+
+	k := key.Key
+	if signer, ok := k.(jose.OpaqueSigner); ok {
+		k = signer.Public().Key
+	}
+
+	switch k := k.(type) {
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return "ed25519", jose.EdDSA, nil
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return "rsa-pss-sha512", jose.PS512, nil
+	case []byte:
+		return "hmac-sha256", jose.HS256, nil
+	case *ecdsa.PrivateKey:
+		return ecdsaAlgorithm(&k.PublicKey)
+	case *ecdsa.PublicKey:
+		return ecdsaAlgorithm(k)
+	default:
+		return "", "", fmt.Errorf("%w: %T", ErrUnsupportedKey, k)
+	}
+}
+
+func ecdsaAlgorithm(key *ecdsa.PublicKey) (string, jose.SignatureAlgorithm, error) {
+	switch key.Curve {
+	case elliptic.P256():
+		return "ecdsa-p256-sha256", jose.ES256, nil
+	case elliptic.P384():
+		return "ecdsa-p384-sha384", jose.ES384, nil
+	default:
+		return "", "", fmt.Errorf("%w: %s curve", ErrUnsupportedKey, key.Curve.Params().Name)
+	}
+}
+
+// sign returns the signature of the signature base with the JWS equivalent of the algorithm,
+// the same signature formats are used (e.g. fixed size r and s of ECDSA).
+func sign(key *jose.JSONWebKey, alg jose.SignatureAlgorithm, base []byte) ([]byte, error) {
+	switch k := key.Key.(type) {
+	case jose.OpaqueSigner:
+		return k.SignPayload(base, alg)
+	case ed25519.PrivateKey:
+		if alg == jose.EdDSA {
+			return ed25519.Sign(k, base), nil
+		}
+	case []byte:
+		if alg == jose.HS256 {
+			mac := hmac.New(sha256.New, k)
+			_, _ = mac.Write(base)
+
+			return mac.Sum(nil), nil
+		}
+	case *rsa.PrivateKey:
+		switch alg {
+		case jose.PS512:
+			sum := sha512.Sum512(base)
+
+			return rsa.SignPSS(rand.Reader, k, crypto.SHA512, sum[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		case jose.RS256:
+			sum := sha256.Sum256(base)
+
+			return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:])
+		}
+	case *ecdsa.PrivateKey:
+		if name, expected, err := ecdsaAlgorithm(&k.PublicKey); err == nil && expected == alg {
+			return ecdsaSign(k, name, base)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key.Key, alg)
+}
+
+func ecdsaSign(key *ecdsa.PrivateKey, name string, base []byte) ([]byte, error) {
+	hash := crypto.SHA256
+	if name == "ecdsa-p384-sha384" {
+		hash = crypto.SHA384
+	}
+
+	h := hash.New()
+	_, _ = h.Write(base)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	size := (key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+
+	return sig, nil
+}
+
+type VerifyOptions struct {
+	Label      string      `js:"label"`
+	Components []string    `js:"components"`
+	Algorithms []string    `js:"algorithms"`
+	MaxAge     interface{} `js:"maxAge"`
+	Tag        string      `js:"tag"`
+}
+
+// Verified is the verified signature with its parameters.
+type Verified struct {
+	Label      string      `js:"label"`
+	Components []string    `js:"components"`
+	Created    interface{} `js:"created"`
+	Expires    interface{} `js:"expires"`
+	KeyID      string      `js:"keyid"`
+	Algorithm  string      `js:"alg"`
+	Nonce      string      `js:"nonce"`
+	Tag        string      `js:"tag"`
+
+	created, expires *int64
+}
+
+// Verify verifies the signature of the message with the label (or the first signature) with the key (or keys).
+func (m *Module) Verify(keyIn interface{}, message *Message, options *VerifyOptions) (*Verified, error) {
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+
+	if message == nil {
+		message = &Message{}
+	}
+
+	keys, err := jwk.KeySet(keyIn)
+	if err != nil {
+		return nil, err
+	}
+
+	input, signature, err := selectSignature(message, options.Label)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := newVerified(input)
+
+	if err := verified.check(options); err != nil {
+		return nil, err
+	}
+
+	if message.Body != nil {
+		if err := checkDigest(message); err != nil {
+			return nil, err
+		}
+	}
+
+	base, err := signatureBase(message, input.list, input.raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fmt.Errorf("%w: no key for %s", ErrUnsupportedKey, verified.Label)
+
+	for i := range keys {
+		if verified.KeyID != "" && keys[i].KeyID != "" && keys[i].KeyID != verified.KeyID {
+			continue
+		}
+
+		var alg jose.SignatureAlgorithm
+
+		if _, alg, err = signatureAlgorithm(&keys[i], verified.Algorithm); err != nil {
+			continue
+		}
+
+		if err = crit.Verify(alg, keys[i].Key, []byte(base), signature); err == nil {
+			return verified, nil
+		}
+	}
+
+	return nil, err
+}
+
+// selectSignature returns the Signature-Input and the Signature member of the label, or the first member.
+func selectSignature(message *Message, label string) (member, []byte, error) {
+	inputField, ok := message.header(headerSignatureInput)
+	if !ok {
+		return member{}, nil, fmt.Errorf("%w: missing %s header", ErrMalformed, headerSignatureInput)
+	}
+
+	signatureField, ok := message.header(headerSignature)
+	if !ok {
+		return member{}, nil, fmt.Errorf("%w: missing %s header", ErrMalformed, headerSignature)
+	}
+
+	inputs, err := parseDictionary(inputField)
+	if err != nil {
+		return member{}, nil, err
+	}
+
+	signatures, err := parseDictionary(signatureField)
+	if err != nil {
+		return member{}, nil, err
+	}
+
+	var input *member
+
+	for i := range inputs {
+		if label == "" || inputs[i].key == label {
+			input = &inputs[i]
+
+			break
+		}
+	}
+
+	if input == nil || !input.inner {
+		return member{}, nil, fmt.Errorf("%w: no signature input %s", ErrMalformed, label)
+	}
+
+	for _, s := range signatures {
+		if s.key != input.key {
+			continue
+		}
+
+		signature, ok := s.value.([]byte)
+		if !ok {
+			return member{}, nil, fmt.Errorf("%w: signature %s is not byte sequence", ErrMalformed, s.key)
+		}
+
+		return *input, signature, nil
+	}
+
+	return member{}, nil, fmt.Errorf("%w: no signature %s", ErrMalformed, input.key)
+}
+
+func newVerified(input member) *Verified {
+	verified := &Verified{Label: input.key, Components: make([]string, len(input.list))}
+
+	for i, c := range input.list {
+		verified.Components[i] = c.String()
+	}
+
+	for _, p := range input.params {
+		switch v := p.value.(type) {
+		case int64:
+			switch p.key {
+			case "created":
+				verified.Created, verified.created = v, &v
+			case "expires":
+				verified.Expires, verified.expires = v, &v
+			}
+		case string:
+			switch p.key {
+			case "keyid":
+				verified.KeyID = v
+			case "alg":
+				verified.Algorithm = v
+			case "nonce":
+				verified.Nonce = v
+			case "tag":
+				verified.Tag = v
+			}
+		}
+	}
+
+	return verified
+}
+
+// check validates the signature parameters against the options.
+func (v *Verified) check(options *VerifyOptions) error {
+	if options.Tag != "" && v.Tag != options.Tag {
+		return fmt.Errorf("%w: tag %q", ErrNotCovered, v.Tag)
+	}
+
+	if len(options.Algorithms) != 0 && !contains(options.Algorithms, v.Algorithm) {
+		return fmt.Errorf("%w: alg %q", ErrUnsupportedAlgorithm, v.Algorithm)
+	}
+
+	for _, id := range options.Components {
+		c, err := parseComponent(id)
+		if err != nil {
+			return err
+		}
+
+		if !contains(v.Components, c.String()) {
+			return fmt.Errorf("%w: %s", ErrNotCovered, c)
+		}
+	}
+
+	now := time.Now().Unix()
+
+	if v.expires != nil && *v.expires < now {
+		return fmt.Errorf("%w: at %d", ErrExpired, *v.expires)
+	}
+
+	if options.MaxAge == nil {
+		return nil
+	}
+
+	maxAge, err := jwt.Duration(options.MaxAge)
+	if err != nil {
+		return err
+	}
+
+	if v.created == nil || *v.created+int64(maxAge/time.Second) < now {
+		return fmt.Errorf("%w: older than %s", ErrExpired, maxAge)
+	}
+
+	return nil
+}
+
+// checkDigest compares the Content-Digest of the message (if any) with the body, unknown algorithms are ignored.
+func checkDigest(message *Message) error {
+	field, ok := message.header(headerContentDigest)
+	if !ok {
+		return nil
+	}
+
+	members, err := parseDictionary(field)
+	if err != nil {
+		return err
+	}
+
+	var found bool
+
+	for _, mem := range members {
+		if _, known := digests[mem.key]; !known {
+			continue
+		}
+
+		expected, err := contentDigest(mem.key, message.Body)
+		if err != nil {
+			return err
+		}
+
+		value, _ := mem.value.([]byte)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(mem.key+"="+bareItem(value))) != 1 {
+			return fmt.Errorf("%w: %s", ErrDigestMismatch, mem.key)
+		}
+
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("%w: no supported algorithm", ErrDigestMismatch)
+	}
+
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
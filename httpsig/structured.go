@@ -0,0 +1,384 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package httpsig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The subset of structured field values (RFC 8941) used by the Signature-Input, Signature
+// and Content-Digest fields: dictionaries of inner lists and byte sequences, with parameters.
+
+type param struct {
+	key   string
+	value interface{} // string, int64, bool or []byte
+}
+
+type params []param
+
+func (p params) get(key string) (interface{}, bool) {
+	for _, kv := range p {
+		if kv.key == key {
+			return kv.value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (p params) String() string {
+	var b strings.Builder
+
+	for _, kv := range p {
+		b.WriteByte(';')
+		b.WriteString(kv.key)
+
+		if v, ok := kv.value.(bool); ok && v {
+			continue
+		}
+
+		b.WriteByte('=')
+		b.WriteString(bareItem(kv.value))
+	}
+
+	return b.String()
+}
+
+func bareItem(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		if v {
+			return "?1"
+		}
+
+		return "?0"
+	case []byte:
+		return ":" + base64.StdEncoding.EncodeToString(v) + ":"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// item is a dictionary member value: an inner list (of strings) or a bare item, with parameters.
+// The raw field is the serialization of the member value as it was parsed.
+type item struct {
+	inner  bool
+	list   []component
+	value  interface{}
+	params params
+	raw    string
+}
+
+type member struct {
+	key string
+	item
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+// parseDictionary parses the dictionary field value, ErrMalformed is returned for invalid values.
+func parseDictionary(src string) ([]member, error) {
+	p := &parser{src: src}
+
+	var members []member
+
+	p.skip(" \t")
+
+	for p.pos < len(p.src) {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+
+		m := member{key: key}
+
+		if p.consume('=') {
+			start := p.pos
+
+			if m.item, err = p.member(); err != nil {
+				return nil, err
+			}
+
+			m.raw = p.src[start:p.pos]
+		} else {
+			m.value = true
+
+			if m.params, err = p.params(); err != nil {
+				return nil, err
+			}
+		}
+
+		members = append(members, m)
+
+		p.skip(" \t")
+
+		if p.pos == len(p.src) {
+			break
+		}
+
+		if !p.consume(',') {
+			return nil, p.errorf("expected ','")
+		}
+
+		p.skip(" \t")
+
+		if p.pos == len(p.src) {
+			return nil, p.errorf("trailing ','")
+		}
+	}
+
+	return members, nil
+}
+
+func (p *parser) member() (item, error) {
+	var (
+		it  item
+		err error
+	)
+
+	if it.inner = p.consume('('); it.inner {
+		if it.list, err = p.innerList(); err != nil {
+			return it, err
+		}
+	} else if it.value, err = p.bareItem(); err != nil {
+		return it, err
+	}
+
+	it.params, err = p.params()
+
+	return it, err
+}
+
+func (p *parser) innerList() ([]component, error) {
+	var list []component
+
+	for {
+		p.skip(" ")
+
+		if p.consume(')') {
+			return list, nil
+		}
+
+		value, err := p.bareItem()
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := value.(string)
+		if !ok {
+			return nil, p.errorf("component identifier must be string")
+		}
+
+		c := component{name: name}
+
+		if c.params, err = p.params(); err != nil {
+			return nil, err
+		}
+
+		list = append(list, c)
+
+		if p.pos < len(p.src) && p.src[p.pos] != ' ' && p.src[p.pos] != ')' {
+			return nil, p.errorf("expected ' ' or ')'")
+		}
+	}
+}
+
+func (p *parser) params() (params, error) {
+	var res params
+
+	for p.consume(';') {
+		p.skip(" ")
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{} = true
+
+		if p.consume('=') {
+			if value, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+
+		res = append(res, param{key: key, value: value})
+	}
+
+	return res, nil
+}
+
+func (p *parser) key() (string, error) {
+	start := p.pos
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*') {
+			break
+		}
+
+		p.pos++
+	}
+
+	if p.pos == start || p.src[start] >= '0' && p.src[start] <= '9' {
+		return "", p.errorf("expected key")
+	}
+
+	return p.src[start:p.pos], nil
+}
+
+func (p *parser) bareItem() (interface{}, error) {
+	if p.pos == len(p.src) {
+		return nil, p.errorf("expected item")
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '"':
+		return p.string()
+	case c == ':':
+		return p.bytes()
+	case c == '?':
+		if p.pos+1 < len(p.src) && (p.src[p.pos+1] == '0' || p.src[p.pos+1] == '1') {
+			p.pos += 2
+
+			return p.src[p.pos-1] == '1', nil
+		}
+
+		return nil, p.errorf("invalid boolean")
+	case c == '-' || c >= '0' && c <= '9':
+		return p.integer()
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '*':
+		start := p.pos
+
+		for p.pos < len(p.src) && !strings.ContainsRune(" \t,;()=\"", rune(p.src[p.pos])) {
+			p.pos++
+		}
+
+		return p.src[start:p.pos], nil
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *parser) string() (string, error) {
+	var b strings.Builder
+
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; c {
+		case '\\':
+			p.pos++
+
+			if p.pos == len(p.src) || (p.src[p.pos] != '"' && p.src[p.pos] != '\\') {
+				return "", p.errorf("invalid escape")
+			}
+
+			b.WriteByte(p.src[p.pos])
+		case '"':
+			p.pos++
+
+			return b.String(), nil
+		default:
+			if c < 0x20 || c > 0x7e {
+				return "", p.errorf("invalid string character")
+			}
+
+			b.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) bytes() ([]byte, error) {
+	end := strings.IndexByte(p.src[p.pos+1:], ':')
+	if end < 0 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+
+	src := p.src[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+
+	b, err := base64.StdEncoding.DecodeString(src)
+	if err != nil {
+		return nil, p.errorf("invalid byte sequence")
+	}
+
+	return b, nil
+}
+
+func (p *parser) integer() (int64, error) {
+	start := p.pos
+
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		return 0, p.errorf("decimal not supported")
+	}
+
+	n, err := strconv.ParseInt(p.src[start:p.pos], 10, 64)
+	if err != nil {
+		return 0, p.errorf("invalid integer")
+	}
+
+	return n, nil
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) skip(chars string) {
+	for p.pos < len(p.src) && strings.IndexByte(chars, p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at %d", ErrMalformed, fmt.Sprintf(format, args...), p.pos)
+}
@@ -1,8 +1,480 @@
 /**
  * xk6-jose provides an implementation of the *Javascript Object Signing and Encryption (JOSE)* standards.
- * The underlying implementation is https://github.com/square/go-jose
+ * The underlying implementation is https://github.com/go-jose/go-jose
  */
 
+/**
+ * Module jose is the root module, exporting the [jwk](../modules/jwk.md), [jwt](../modules/jwt.md), [jws](../modules/jws.md) and [jwe](../modules/jwe.md) modules as namespaces
+ * sharing the configuration set by [configure](../modules/jose.md#configure):
+ *
+ * ```js
+ * import jose, { jwk, jwt } from "k6/x/jose";
+ *
+ * jose.configure({ leeway: "30s", algorithms: ["ES256"] });
+ * ```
+ *
+ * The configuration is per VU, the standalone modules (e.g. `k6/x/jose/jwt`) are not affected by it.
+ *
+ * The defaults are read from environment variables (`-e` flags or system environment) when a module is imported,
+ * by the standalone modules too: `K6_JOSE_ALGORITHMS` (comma separated list), `K6_JOSE_LEEWAY`, `K6_JOSE_CACHE_TTL`
+ * (durations like `30s` or numbers of seconds), `K6_JOSE_KEY_SIZE` (bits), `K6_JOSE_CRITICAL` (comma separated list),
+ * `K6_JOSE_MAX_TOKEN_SIZE` (bytes), `K6_JOSE_MAX_SEGMENTS`, `K6_JOSE_MAX_DEPTH`, `K6_JOSE_STRICT_BASE64`, `K6_JOSE_STRICT_JSON` and `K6_JOSE_DEBUG` (booleans).
+ * Invalid values fail the import.
+ *
+ * The defaults can be set in the `ext.jose` object of the test options too, with the names of the
+ * [configure](../modules/jose.md#configure) options. They apply to the settings not set by the environment variables
+ * or `configure`, before the first call in the VU code (the options are not available in the init code).
+ * Invalid options are thrown by that call.
+ *
+ * ```js
+ * export const options = { ext: { jose: { leeway: "30s", algorithms: ["ES256"] } } };
+ * ```
+ */
+export namespace jose {
+  /**
+   * Shared configuration of the namespaces.
+   */
+  interface ConfigureOptions {
+    /**
+     * The default clock skew tolerance of [verifyClaims](../modules/jwt.md#verifyclaims),
+     * duration string (e.g. `30s`) or number of seconds (default: 1 minute)
+     */
+    leeway?: string | number;
+
+    /**
+     * Allowed `alg` header values of verified JWT and JWS and decrypted JWE (default: any).
+     * The `algorithms` option of [decrypt](../modules/jwe.md#decrypt) takes precedence.
+     */
+    algorithms?: string[];
+
+    /**
+     * Lifetime of the parsed key cache entries, duration string or number of seconds (default: 0, no expiration).
+     * The key caches are shared by every VU.
+     */
+    cacheTTL?: string | number;
+
+    /**
+     * Timeout of fetching keys from `https://` URLs by [parse](../modules/jwk.md#parse) and
+     * [parseKeySet](../modules/jwk.md#parsekeyset), duration string or number of seconds, 0 for the default (default: 30 seconds)
+     */
+    fetchTimeout?: string | number;
+
+    /**
+     * The RSA modulus size of generated keys in bits, when not given by the `bits` option (default: 2048)
+     */
+    keySize?: number;
+
+    /**
+     * Critical header parameters handled by the script (default: none). Verified JWT and JWS, and decrypted JWE with
+     * other parameters in the `crit` header are rejected with `CriticalHeaderError`, `b64` is always handled by JWS.
+     */
+    critical?: string[];
+
+    /**
+     * Maximum length of parsed tokens in bytes (default: 1 MiB). Longer tokens are rejected with
+     * `LimitExceededError` before decoding, raise it for deliberate oversized token tests.
+     * Compressed (`zip`) JWE content inflating beyond it is rejected with `MalformedError`.
+     */
+    maxTokenSize?: number;
+
+    /**
+     * Maximum number of dot and tilde separated segments of compact tokens (default: 100)
+     */
+    maxSegments?: number;
+
+    /**
+     * Maximum nesting depth of JSON objects and arrays of headers, JWT claims and JSON serializations (default: 32)
+     */
+    maxDepth?: number;
+
+    /**
+     * Reject parsed tokens with padding, characters outside of the URL safe alphabet (including whitespace)
+     * or non-zero trailing bits in base64url encoded parts with `MalformedError` (default: false, tolerant)
+     */
+    strictBase64?: boolean;
+
+    /**
+     * Reject parsed tokens with duplicate member names in headers, JWT claims or JSON serializations
+     * with `MalformedError`, before any decoding (default: false)
+     */
+    strictJSON?: boolean;
+
+    /**
+     * Log the failures of the `jwt`, `jws` and `jwe` sign, verify and decrypt functions as warnings:
+     * the stage of the failure (e.g. `limits`, `algorithm-policy`, `key-selection`, `signature`, `claims`),
+     * the decoded header, the kid and the kids of the candidate keys, and the claim names (values are never logged).
+     * Private key material is redacted from the header and the error (default: false)
+     */
+    debug?: boolean;
+
+    /**
+     * The clock of JWT signing, claim templates and claim validation, for reproducible temporal tests:
+     * fixed `Date`, RFC 3339 string or seconds since the epoch, or function returning one of them.
+     * Set to `system` to restore the wall clock (default).
+     */
+    clock?: Date | string | number | (() => Date | string | number);
+  }
+
+  /**
+   * The current configuration.
+   */
+  interface Config {
+    /**
+     * The default leeway in seconds, null for the default
+     */
+    leeway: number | null;
+
+    /**
+     * The allowed algorithms, empty for any
+     */
+    algorithms: string[];
+
+    /**
+     * The key cache TTL in seconds, null for no expiration
+     */
+    cacheTTL: number | null;
+
+    /**
+     * The key fetch timeout in seconds, null for the default
+     */
+    fetchTimeout: number | null;
+
+    /**
+     * The RSA modulus size in bits, null for the default
+     */
+    keySize: number | null;
+
+    /**
+     * The handled critical header parameters
+     */
+    critical: string[];
+
+    /**
+     * The maximum token size in bytes, null for the default
+     */
+    maxTokenSize: number | null;
+
+    /**
+     * The maximum number of compact token segments, null for the default
+     */
+    maxSegments: number | null;
+
+    /**
+     * The maximum JSON nesting depth, null for the default
+     */
+    maxDepth: number | null;
+
+    /**
+     * True in strict base64url mode
+     */
+    strictBase64: boolean;
+
+    /**
+     * True in strict JSON (duplicate member rejection) mode
+     */
+    strictJSON: boolean;
+
+    /**
+     * True in debug (failure logging) mode
+     */
+    debug: boolean;
+
+    /**
+     * True if a clock is configured instead of the wall clock
+     */
+    clock: boolean;
+  }
+
+  /**
+   * Get the current configuration, set by the environment variables and [configure](../modules/jose.md#configure).
+   *
+   * @returns The configuration
+   */
+  function config(): Config;
+
+  /**
+   * Update the shared configuration, options not given keep their current value.
+   * Tokens using an algorithm not allowed are rejected with `AlgorithmNotAllowedError`.
+   *
+   * @param options The options to update
+   */
+  function configure(options: ConfigureOptions): void;
+
+  /**
+   * Options for OpenID provider discovery.
+   */
+  interface DiscoverOptions {
+    /**
+     * Timeout of each request, duration string or number of seconds (default: 30 seconds)
+     */
+    timeout?: string | number;
+
+    /**
+     * Fetch the discovery document and the keys even if they are cached
+     */
+    refresh?: boolean;
+  }
+
+  /**
+   * The discovered OpenID provider, it can be passed as verification key (the keys of `jwks_uri`) to any module.
+   */
+  interface Issuer {
+    /**
+     * The issuer identifier
+     */
+    issuer: string;
+
+    /**
+     * The URL of the issuer's JWK set
+     */
+    jwksUri: string;
+
+    /**
+     * The discovery document (`token_endpoint`, `userinfo_endpoint`, etc.)
+     */
+    metadata: Record<string, any>;
+
+    /**
+     * The keys of the issuer
+     */
+    keys: jwk.Key[];
+  }
+
+  /**
+   * Discover the OpenID provider: fetch `/.well-known/openid-configuration` of the issuer URL and the keys of its `jwks_uri`.
+   * The `issuer` of the discovery document must equal the issuer URL.
+   * Providers are fetched once and shared by every VU, until the [cacheTTL](../interfaces/jose.configureoptions.md#cachettl) of the key caches expires.
+   * Requests in VU code go through the k6 transport (TLS and host options apply), but don't emit HTTP metrics.
+   *
+   * ```js
+   * const issuer = jose.discover("https://accounts.example.com");
+   *
+   * export default function () {
+   *   const claims = jwt.verify(token, issuer);
+   * }
+   * ```
+   *
+   * Failed requests and invalid documents throw `DiscoveryError`.
+   *
+   * @param issuer The issuer URL
+   * @param options The request options
+   * @returns The discovered provider
+   */
+  function discover(issuer: string, options?: DiscoverOptions): Issuer;
+
+  /**
+   * Named key registry shared by every VU, heavy keys and key sets are created once instead of being
+   * serialized through `setup()` data or `SharedArray`.
+   */
+  interface KeyStore {
+    /**
+     * Store the key, key set or [discovered](../modules/jose.md#discover) issuer with name, replacing the previous one.
+     * Empty names throw `InvalidArgumentError`.
+     *
+     * @param name The name of the entry
+     * @param key The key, key set or issuer
+     */
+    put(name: string, key: jwk.Key | jwk.Key[] | Issuer): void;
+
+    /**
+     * Get the entry stored with name.
+     *
+     * @param name The name of the entry
+     * @returns The key, key set or issuer, null if there is none
+     */
+    get(name: string): jwk.Key | jwk.Key[] | Issuer | null;
+
+    /**
+     * Get the entry stored with name, or store the value returned by the function. The function is called once
+     * per name, concurrent calls with the same name (e.g. in the init code of other VUs) wait for it.
+     * Nothing is stored if the function throws.
+     *
+     * @param name The name of the entry
+     * @param create Function returning the key, key set or issuer
+     * @returns The stored key, key set or issuer
+     */
+    getOrPut(name: string, create: () => jwk.Key | jwk.Key[] | Issuer): jwk.Key | jwk.Key[] | Issuer;
+
+    /**
+     * Check if there is an entry with name.
+     *
+     * @param name The name of the entry
+     * @returns True if the entry exists
+     */
+    has(name: string): boolean;
+
+    /**
+     * Remove the entry stored with name.
+     *
+     * @param name The name of the entry
+     * @returns False if there was no entry
+     */
+    delete(name: string): boolean;
+
+    /**
+     * The names of the entries.
+     *
+     * @returns The sorted names
+     */
+    names(): string[];
+  }
+
+  /**
+   * The key registry shared by every VU. Entries put in the init code of the first VU are visible to the others:
+   *
+   * ```js
+   * const issuerKey = jose.keys.getOrPut("issuer-a", () => jwk.generate("RS512"));
+   *
+   * export default function () {
+   *   const token = jwt.sign(issuerKey, { sub: "alice" });
+   * }
+   * ```
+   */
+  const keys: KeyStore;
+
+  /**
+   * Options for issuer registration.
+   */
+  interface IssuerOptions {
+    /**
+     * The issuer identifier, the `iss` claim of signed tokens and the expected issuer of verified ones (default: the name)
+     */
+    issuer?: string;
+
+    /**
+     * The signing key or keys, tokens are signed with the first one. Their public parts are verification keys as well.
+     */
+    keys?: jwk.Key | jwk.Key[];
+
+    /**
+     * Additional verification keys, or the `https://` URL (or `data:` URI) of the issuer's JWK set
+     */
+    jwks?: jwk.Key | jwk.Key[] | string;
+
+    /**
+     * Verify with the keys of the [discovered](../modules/jose.md#discover) `jwks_uri` of the issuer identifier
+     */
+    discover?: boolean;
+
+    /**
+     * The lifetime of the fetched (or discovered) keys, as seconds or duration string
+     * (default: the [cacheTTL](../interfaces/jose.configureoptions.md#cachettl) of the key caches), zero keeps them
+     */
+    cacheTTL?: number | string;
+
+    /**
+     * The `aud` claim of signed tokens and the expected audience of verified ones
+     */
+    audience?: string;
+
+    /**
+     * The lifetime of signed tokens, as seconds or duration string (default: 3600)
+     */
+    lifetime?: number | string;
+
+    /**
+     * Default claims of signed tokens, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Default header fields of signed tokens
+     */
+    header?: object;
+  }
+
+  /**
+   * Registry of named issuers shared by every VU, with their keys and token defaults.
+   */
+  interface IssuerRegistry {
+    /**
+     * Register the issuer with name, replacing the previous one.
+     * Empty names throw `InvalidArgumentError`, issuers without any key `KeyNotFoundError`.
+     *
+     * @param name The name of the issuer
+     * @param options The issuer options
+     */
+    register(name: string, options: IssuerOptions): void;
+
+    /**
+     * Sign JWT with the first key of the issuer. The `iss`, `aud` (if configured), `iat`, `exp` and random `jti`
+     * claims come first, then the default claims of the issuer and the claims.
+     * Unknown issuers throw `InvalidArgumentError`.
+     *
+     * @param name The name of the issuer
+     * @param claims The claims
+     * @param header Additional header fields
+     * @param options The sign options, the default lifetime is the one of the issuer
+     * @returns The signed token
+     */
+    sign(name: string, claims?: object, header?: object, options?: jwt.SignOptions): string;
+
+    /**
+     * Verify JWT with the keys of the issuer, as in [jwt.verifyClaims](../modules/jwt.md#verifyclaims).
+     * The issuer identifier and the audience of the issuer are the default expected `iss` and `aud`.
+     *
+     * @param name The name of the issuer
+     * @param token The token
+     * @param options The verify options
+     * @returns The claims
+     */
+    verify(name: string, token: string, options?: jwt.VerifyOptions): object;
+
+    /**
+     * The verification keys of the issuer, fetched (or discovered) on first use and after their cache TTL.
+     *
+     * @param name The name of the issuer
+     * @returns The keys
+     */
+    keys(name: string): jwk.Key[];
+
+    /**
+     * Check if there is an issuer with name.
+     *
+     * @param name The name of the issuer
+     * @returns True if the issuer exists
+     */
+    has(name: string): boolean;
+
+    /**
+     * Remove the issuer.
+     *
+     * @param name The name of the issuer
+     * @returns False if there was no issuer
+     */
+    delete(name: string): boolean;
+
+    /**
+     * The names of the issuers.
+     *
+     * @returns The sorted names
+     */
+    names(): string[];
+  }
+
+  /**
+   * The issuer registry shared by every VU, tokens of several simulated identity providers are signed and verified
+   * by issuer name:
+   *
+   * ```js
+   * jose.issuers.register("partner", {
+   *   issuer: "https://idp.partner.example.com",
+   *   keys: jwk.generate("ES256"),
+   *   audience: "orders-api",
+   *   lifetime: "15m",
+   *   claims: { scope: "orders:read" },
+   * });
+   * jose.issuers.register("corporate", { issuer: "https://login.example.com", discover: true, cacheTTL: "5m" });
+   *
+   * export default function () {
+   *   const token = jose.issuers.sign("partner", { sub: "alice" });
+   *   jose.issuers.verify("partner", token);
+   * }
+   * ```
+   */
+  const issuers: IssuerRegistry;
+}
+
 /**
  * Module jwk aims to provide an implementation of the JSON Web Key.
  */
@@ -13,83 +485,4415 @@ export namespace jwk {
   export type bytes = number[];
 
   /**
-   * Byte array convertible types
+   * Byte array convertible types.
+   *
+   * Strings are UTF-8 encoded, binary data should be passed as ArrayBuffer,
+   * typed array (Uint8Array or Int8Array) or array of numbers.
+   * Binary results are returned as ArrayBuffer.
    */
-  export type ByteArrayLike = ArrayBuffer | string | bytes;
+  export type ByteArrayLike = ArrayBuffer | Uint8Array | Int8Array | string | bytes;
 
   /**
    * Key represents a public or private key in JWK format.
    *
-   * The underlying implemetation is [JSONWebKey](https://pkg.go.dev/gopkg.in/square/go-jose.v2#JSONWebKey) from [Go JOSE](https://github.com/square/go-jose).
-   * JSON representation can be create with JSON.stringify
+   * The underlying implemetation is [JSONWebKey](https://pkg.go.dev/github.com/go-jose/go-jose/v4#JSONWebKey) from [Go JOSE](https://github.com/go-jose/go-jose).
+   * JSON representation can be create with JSON.stringify, or as plain object with [toObject](../modules/jwk.md#toobject).
+   * Functions accepting keys of other modules accept plain JWK (or JWKS) objects too, parsed (and cached) like [parse](../modules/jwk.md#parse) does.
+   *
+   * Key material is not visible as property, `console.log(key)` prints only the metadata of the key.
+   *
+   * Functions of every module accept `CryptoKey` objects of the WebCrypto API (global `crypto.subtle`) as keys too,
+   * converted like [fromCryptoKey](../modules/jwk.md#fromcryptokey) does.
    */
   interface Key {}
 
   /**
    * Parse a key from its JSON representation.
+   * Parsed keys are cached by source (up to 1024 sources), repeated calls with the same source are cheap.
+   * The same applies to [parseKeySet](../modules/jwk.md#parsekeyset) and [parsePEM](../modules/jwk.md#parsepem).
+   *
+   * The source can be an `https://` URL or a `data:` URI (base64 or percent encoded) of the JSON too. URLs are fetched
+   * once (until the [cacheTTL](../interfaces/jose.configureoptions.md#cachettl) expires), with the
+   * [fetchTimeout](../interfaces/jose.configureoptions.md#fetchtimeout). Requests in VU code go through the k6 transport.
+   * Failed requests throw `KeyFetchError`, invalid data URIs `InvalidArgumentError`.
    *
-   * @param source JSON source to parse
+   * ```js
+   * const keys = jwk.parseKeySet("https://auth.example.com/.well-known/jwks.json");
+   * ```
+   *
+   * @param source JSON source to parse, URL or data URI of it, or the JWK as plain object
    * @returns The parsed JWK representation
    */
-  function parse(source: string): Key;
+  function parse(source: string | ArrayBuffer | object): Key;
 
   /**
-   * Parse JSON Web Key Set into key array.
+   * Parse JSON Web Key Set into key array, the source can be an URL or data URI like of [parse](../modules/jwk.md#parse).
    *
-   * @param source JSON source to parse
+   * @param source JSON source to parse, URL or data URI of it, or the JWKS as plain object
    * @returns The array of keys from parsed JWKS
    */
-  function parseKeySet(source: string): Key[];
+  function parseKeySet(source: string | ArrayBuffer | object): Key[];
 
   /**
-   * Generates a new asymmetric key with the given algorithm (`algorithm`) or import exising private key from `seed`.
+   * Returns the JSON representation of the key as plain object, without `JSON.parse(JSON.stringify(key))` round trip in scripts.
    *
-   * @param algorithm Key algorithm, supported values: `ed25519`
-   * @param seed Seed value when importing private key
-   * @returns The generated key
+   * @param key The key
+   * @returns The JWK members (private members too for private keys)
    */
-  function generate(algorithm: string, seed?: ByteArrayLike): Key;
+  function toObject(key: Key): object;
 
   /**
-   * Adopt an existing asymmetric key with the given algorithm (`algorithm`).
+   * Serialize the key to a compact string, which can live in a `SharedArray` (or any other JSON data) and can be
+   * [rehydrated](../modules/jwk.md#rehydrate) without JSON parsing.
+   * The string holds `alg`, `kid`, `use` and the raw key material (Ed25519, EC or two-prime RSA private or public key),
+   * X.509 certificates of the key are not serialized.
    *
-   * @param algorithm Key algorithm, supported values: `ed25519`
-   * @param key private or public key
-   * @param isPublic true if `key` is a public key, false if it is a private key
-   * @returns The adopted key
+   * @param key The key to serialize
+   * @returns The serialized key
    */
-  function adopt(algorithm: string, key: ByteArrayLike, isPublic?: boolean): Key;
-}
+  function serialize(key: Key): string;
 
-/**
- * Module jwt aims to provide an implementation of the JSON Web Token.
- * Only compact serializtion is supported.
- */
-export namespace jwt {
   /**
-   * Create JSON Web Token from payload and optional header.
+   * Reconstruct the key [serialized](../modules/jwk.md#serialize) in the init context, typically per VU from a `SharedArray`.
+   * Rehydration is about ten times faster than [parse](../modules/jwk.md#parse) for Ed25519 and EC keys,
+   * RSA keys still need the precomputation of CRT values.
    *
-   * @param key The signing key
-   * @param payload The payload claims
-   * @param header The header fields
-   * @returns The signed JWT in compact serialization form
+   * ```js
+   * const keys = new SharedArray("keys", () => pregenerated.map((key) => jwk.serialize(key)));
+   *
+   * export default function () {
+   *   const key = jwk.rehydrate(keys[__VU % keys.length]);
+   * }
+   * ```
+   *
+   * @param serialized The serialized key
+   * @returns The key
    */
-  function sign(key: jwk.Key, payload: object, header?: object): string;
+  function rehydrate(serialized: string): Key;
 
   /**
-   * Decode JSON Web Token payload without signature validation.
+   * Options for key export.
+   */
+  interface ExportOptions {
+    /**
+     * Export private key material too, required for symmetric keys (default: false)
+     */
+    includePrivate?: boolean;
+  }
+
+  /**
+   * Export the key (or keys) as plain JWK data, which survives the JSON hand-off of `setup()` data to the VUs.
+   * Keys returned by `setup()` as is lose their key material, [import](../modules/jwk.md#importkeys) the exported data in VU code instead.
+   * Private key material is exported only with `includePrivate`, setup data may end up in logs and summaries.
    *
-   * @param token The JWT to decode
-   * @returns The decoded payload
+   * ```js
+   * export function setup() {
+   *   const key = jwk.generate("ES256");
+   *   return { signer: jwk.exportKeys(key, { includePrivate: true }), verifiers: jwk.exportKeys([key]) };
+   * }
+   *
+   * export default function (data) {
+   *   const token = jwt.sign(jwk.importKeys(data.signer), { sub: "alice" });
+   *   jwt.verify(token, jwk.importKeys(data.verifiers));
+   * }
+   * ```
+   *
+   * @param keys The key, key array or key set to export
+   * @param options The export options
+   * @returns The JWK as plain object for a key, array of JWK objects otherwise
    */
-  function decode(token: string): object;
+  function exportKeys(keys: Key | Key[] | object, options?: ExportOptions): object | object[];
 
   /**
-   * Verify JSON Web Token signature and decode payload on success.
+   * Import the key (or keys) [exported](../modules/jwk.md#exportkeys) in `setup()`.
+   * Imports are parsed (and cached) like [parse](../modules/jwk.md#parse) does.
    *
-   * @param token The JWT to verify
-   * @param key The signature validation key (or keys)
-   * @returns The payload of the verified token
+   * @param data The exported JWK object, array of JWK objects, or JWK set
+   * @returns The key for a JWK object, key array otherwise
    */
-  function verify(token: string, ...key: jwk.Key[]): object;
+  function importKeys(data: object | object[]): Key | Key[];
+
+  /**
+   * Convert the `CryptoKey` of the WebCrypto API to key.
+   * Supported algorithms are ECDSA, ECDH (P-256, P-384, P-521), RSASSA-PKCS1-v1_5, RSA-PSS, RSA-OAEP (SHA-1 or SHA-256), HMAC, AES-KW and AES-GCM,
+   * `alg` and `use` of the key are set from the algorithm of the `CryptoKey`.
+   * Non-extractable ECDSA, RSASSA-PKCS1-v1_5 and RSA-PSS private keys can sign, non-extractable RSA-OAEP private keys
+   * can decrypt, but their private parts are not exported. Secret keys must be extractable.
+   * ECDH keys of other curves (e.g. X25519) are not supported.
+   *
+   * @param cryptoKey The CryptoKey
+   * @returns The key
+   */
+  function fromCryptoKey(cryptoKey: object): Key;
+
+  /**
+   * Options for CryptoKey import.
+   */
+  interface CryptoKeyOptions {
+    /**
+     * The usages of the CryptoKey (default: `sign` and/or `verify` for signing keys, `encrypt`/`decrypt`, `deriveBits` or `wrapKey`/`unwrapKey` for encryption keys)
+     */
+    usages?: string[];
+
+    /**
+     * Whether the CryptoKey is extractable (default: true)
+     */
+    extractable?: boolean;
+  }
+
+  /**
+   * Import the key to the WebCrypto API, the WebCrypto algorithm follows `alg` of the key (the curve for EC keys without `alg`).
+   *
+   * ```js
+   * const cryptoKey = await jwk.toCryptoKey(jwk.generate("ES256"));
+   * const signature = await crypto.subtle.sign({ name: "ECDSA", hash: "SHA-256" }, cryptoKey, data);
+   * ```
+   *
+   * @param key The key to import
+   * @param options The usages and extractability of the CryptoKey
+   * @returns Promise of the CryptoKey
+   */
+  function toCryptoKey(key: Key, options?: CryptoKeyOptions): Promise<object>;
+
+  /**
+   * Parse PEM encoded key: PKCS #8, PKCS #1 or SEC 1 private key, or PKIX public key.
+   * The `alg` is `RS256`, `ES256`/`ES384`/`ES512` or `EdDSA` by key type, `kid` is the key's thumbprint.
+   *
+   * @param source PEM source to parse
+   * @returns The parsed JWK representation
+   */
+  function parsePEM(source: string): Key;
+
+  /**
+   * Options for key construction from components.
+   */
+  interface ComponentOptions {
+    /**
+     * The encoding of the component values: `base64url` or `hex` (`0x` prefix, colons and whitespace are ignored)
+     * (default: `base64url`)
+     */
+    encoding?: string;
+
+    /**
+     * The `alg` of the key (default: `RS256` for RSA keys, depends on the curve for EC keys)
+     */
+    alg?: string;
+
+    /**
+     * The `kid` of the key (default: the key's thumbprint)
+     */
+    kid?: string;
+
+    /**
+     * The `use` of the key (default: `sig`)
+     */
+    use?: string;
+  }
+
+  /**
+   * The components of RSA key, big-endian unsigned integers.
+   */
+  interface RSAComponents {
+    /**
+     * The modulus
+     */
+    n: string;
+
+    /**
+     * The public exponent
+     */
+    e: string;
+
+    /**
+     * The private exponent, for private key
+     */
+    d?: string;
+
+    /**
+     * The first prime factor (recovered from `n`, `e` and `d` if not given)
+     */
+    p?: string;
+
+    /**
+     * The second prime factor (recovered from `n`, `e` and `d` if not given)
+     */
+    q?: string;
+  }
+
+  /**
+   * Build RSA key from its components, e.g. exported by key ceremony tools. The CRT values are computed,
+   * the primes are recovered if only `n`, `e` and `d` are given. Throws `InvalidKeyError` if the components
+   * don't form a valid key.
+   *
+   * ```js
+   * const key = jwk.fromRSAComponents({ n, e: "010001", d }, { encoding: "hex", kid: "ceremony-1" });
+   * ```
+   *
+   * @param components The key components, public key without `d`
+   * @param options The encoding and key parameters
+   * @returns The key
+   */
+  function fromRSAComponents(components: RSAComponents, options?: ComponentOptions): Key;
+
+  /**
+   * The components of EC key, big-endian unsigned integers.
+   */
+  interface ECComponents {
+    /**
+     * The curve name: `P-256`, `P-384`, `P-521`, `BP-256`, `BP-384` or `BP-512`
+     */
+    crv: string;
+
+    /**
+     * The x coordinate (computed from `d` if not given)
+     */
+    x?: string;
+
+    /**
+     * The y coordinate (computed from `d` if not given)
+     */
+    y?: string;
+
+    /**
+     * The private key, for private key
+     */
+    d?: string;
+  }
+
+  /**
+   * Build EC key from the curve name and raw coordinates. The point is checked to be on the curve and to match
+   * `d` if both given. The default `alg` is `ES256`, `ES384`, `ES512`, `ESB256`, `ESB384` or `ESB512` depending on the curve. Throws
+   * `InvalidKeyError` if the components don't form a valid key.
+   *
+   * ```js
+   * const key = jwk.fromECComponents({ crv: "P-256", x, y });
+   * ```
+   *
+   * @param components The key components, public key without `d`
+   * @param options The encoding and key parameters
+   * @returns The key
+   */
+  function fromECComponents(components: ECComponents, options?: ComponentOptions): Key;
+
+  /**
+   * The components of OKP key, raw bytes.
+   */
+  interface OKPComponents {
+    /**
+     * The curve name: `Ed25519` or `X25519`
+     */
+    crv: string;
+
+    /**
+     * The public key (computed from `d` if not given)
+     */
+    x?: string;
+
+    /**
+     * The private key (the seed of Ed25519 key), for private key
+     */
+    d?: string;
+  }
+
+  /**
+   * Build Ed25519 or X25519 key from the raw public and private bytes. The default `alg` and `use` are `EdDSA`
+   * and `sig` for Ed25519, `ECDH-ES` and `enc` for X25519. X25519 keys are returned as plain JWK objects,
+   * usable with [deriveSharedSecret](../modules/jwk.md#derivesharedsecret). Throws `InvalidKeyError` if the
+   * components don't form a valid key.
+   *
+   * ```js
+   * const key = jwk.fromOKPComponents({ crv: "X25519", d: raw }, { encoding: "hex" });
+   * ```
+   *
+   * @param components The key components, public key without `d`
+   * @param options The encoding and key parameters
+   * @returns The key
+   */
+  function fromOKPComponents(components: OKPComponents, options?: ComponentOptions): Key | object;
+
+  /**
+   * Read a PEM or JWK encoded key from file.
+   * Files can be read only in the init context, relative paths are relative to the test script.
+   * Like files opened with `open()`, the file is included in the archive of the test.
+   *
+   * @param path Path of the key file
+   * @returns The parsed key
+   */
+  function parseFile(path: string): Key;
+
+  /**
+   * Read a key set from file: JWKS, single JWK or bundle of PEM encoded keys.
+   * Files can be read only in the init context, relative paths are relative to the test script.
+   *
+   * @param path Path of the key set file
+   * @returns The parsed keys
+   */
+  function parseKeySetFile(path: string): Key[];
+
+  /**
+   * Options for certificate chain validation.
+   */
+  interface ChainOptions {
+    /**
+     * Additional intermediate certificates, like `roots` (the certificates of the chain after the leaf are always used)
+     */
+    intermediates?: ByteArrayLike | ByteArrayLike[];
+
+    /**
+     * The time of validation for the validity windows: `Date`, RFC 3339 string, seconds since the epoch or function
+     * returning one of them (default: the configured clock, see [configure](../modules/jose.md#configure))
+     */
+    now?: Date | string | number | (() => Date | string | number);
+  }
+
+  /**
+   * Validate the `x5c` certificate chain of the key to one of the trusted roots, including the validity windows of the
+   * certificates. Throws `CertificateChainError` if the key has no `x5c` or the chain is not valid.
+   *
+   * ```js
+   * const key = jwk.verifyChain(jwk.parse(source), open("ca.pem"));
+   * ```
+   *
+   * @param key The key with `x5c` (leaf certificate first)
+   * @param roots The trusted root certificates: PEM bundle, DER encoded certificate or array of them
+   * @param options The intermediates and the time of validation
+   * @returns The key
+   */
+  function verifyChain(key: Key, roots: ByteArrayLike | ByteArrayLike[], options?: ChainOptions): Key;
+
+  /**
+   * Take the public key of the leaf certificate from the `x5c` header of a JWT or JWS (compact or JSON serialization),
+   * after validating the chain to one of the trusted roots like [verifyChain](../modules/jwk.md#verifychain).
+   * The `alg` and `kid` of the key are taken from the header. The token itself is not verified.
+   *
+   * @param token The token with `x5c` header
+   * @param roots The trusted root certificates: PEM bundle, DER encoded certificate or array of them
+   * @param options The intermediates and the time of validation
+   * @returns The public key of the leaf certificate
+   */
+  function fromX5C(token: string, roots: ByteArrayLike | ByteArrayLike[], options?: ChainOptions): Key;
+
+  /**
+   * The certificate thumbprint header values.
+   */
+  interface CertificateThumbprints {
+    /**
+     * The base64url encoded SHA-1 thumbprint of the DER encoded certificate
+     */
+    x5t: string;
+
+    /**
+     * The base64url encoded SHA-256 thumbprint of the DER encoded certificate
+     */
+    "x5t#S256": string;
+  }
+
+  /**
+   * Compute the `x5t` and `x5t#S256` header values of the certificate (the first one of PEM bundle or array),
+   * or of the leaf certificate (first `x5c` entry) of the key.
+   *
+   * ```js
+   * const token = jwt.sign(key, claims, { ...jwk.certificateThumbprints(open("cert.pem")) });
+   * ```
+   *
+   * @param source PEM, DER encoded certificate or array of them, or key with `x5c`
+   * @returns The thumbprints
+   */
+  function certificateThumbprints(source: ByteArrayLike | ByteArrayLike[] | Key): CertificateThumbprints;
+
+  /**
+   * The difference of two key set snapshots, by `kid` sorted (keys without `kid` are identified by their thumbprint).
+   */
+  interface KeySetDiff {
+    /**
+     * The kids only in the new key set
+     */
+    added: string[];
+
+    /**
+     * The kids only in the old key set
+     */
+    removed: string[];
+
+    /**
+     * The kids in both key sets with different public key, `alg` or `use`, or with different number of keys
+     */
+    changed: string[];
+
+    /**
+     * The kids in both key sets with the same keys
+     */
+    unchanged: string[];
+
+    /**
+     * The kids of more than one key in either key set (e.g. signing and encryption keys), compared together
+     */
+    duplicated: string[];
+
+    /**
+     * True if any key was added, removed or changed
+     */
+    rotated: boolean;
+  }
+
+  /**
+   * Compare two key set snapshots (e.g. JWKS of the identity provider fetched periodically during a soak test)
+   * to detect key rotation. Private keys are compared by their public key.
+   *
+   * ```js
+   * const jwks = http.get(jwksURI).json();
+   * const diff = jwk.diffKeySets(keys, jwks);
+   * if (diff.rotated) {
+   *   keys = jwk.parseKeySet(jwks);
+   * }
+   * ```
+   *
+   * @param before The old key set: keys or JWKS object
+   * @param after The new key set: keys or JWKS object
+   * @returns The difference of the key sets
+   */
+  function diffKeySets(before: Key[] | object, after: Key[] | object): KeySetDiff;
+
+  /**
+   * Parse the key from environment variable (like `__ENV`): PEM (newlines may be escaped as `\n`),
+   * JWK or [serialized](../modules/jwk.md#serialize) key.
+   *
+   * Variables passed with `-e` are saved to archives (`k6 archive`, cloud runs), prefer system environment or
+   * [fromSecret](../modules/jwk.md#fromsecret) there.
+   *
+   * @param name Name of the environment variable
+   * @returns The parsed key
+   */
+  function fromEnv(name: string): Key;
+
+  /**
+   * Parse the key from k6 secret source (`--secret-source`), encoded like for [fromEnv](../modules/jwk.md#fromenv).
+   * Secrets can be read only in the init context, the secret is redacted from the logs by k6.
+   *
+   * ```js
+   * const key = jwk.fromSecret("signing_key");
+   * ```
+   *
+   * @param name Name of the secret
+   * @param source Name of the secret source (default: the default source)
+   * @returns The parsed key
+   */
+  function fromSecret(name: string, source?: string): Key;
+
+  /**
+   * Generates a new asymmetric key with the given algorithm (`algorithm`) or import exising private key from `seed`.
+   * RSA keys are 2048 bits, `kid` is the key's thumbprint.
+   *
+   * `ESB256`, `ESB384` and `ESB512` are ECDSA with the Brainpool curves (`BP-256`, `BP-384` and `BP-512`,
+   * draft-ietf-jose-fully-specified-algorithms), supported by the JWT and JWS sign and verify functions and by
+   * [toObject](../modules/jwk.md#toobject). `JSON.stringify` of Brainpool keys is not supported.
+   *
+   * @param algorithm Key algorithm, supported values: `ed25519`, `EdDSA`, `ES256`, `ES384`, `ES512`,
+   *                  `RS256`, `RS384`, `RS512`, `PS256`, `PS384`, `PS512`, `ESB256`, `ESB384`, `ESB512`
+   * @param seed Seed value when importing private key (`ed25519` only)
+   * @returns The generated key
+   */
+  function generate(algorithm: string, seed?: ByteArrayLike): Key;
+
+  /**
+   * Generates a new key like [generate](#generate), outside of the VU's event loop.
+   * Other timers and callbacks of the VU are not blocked while RSA keys are generated.
+   *
+   * @param algorithm Key algorithm, see [generate](#generate)
+   * @param seed Seed value when importing private key (`ed25519` only)
+   * @returns Promise of the generated key
+   */
+  function generateAsync(algorithm: string, seed?: ByteArrayLike): Promise<Key>;
+
+  /**
+   * Options for VU key derivation.
+   */
+  interface DeriveOptions {
+    /**
+     * The VU number (default: `__VU` of the current VU, required in the init context)
+     */
+    vu?: number;
+
+    /**
+     * The iteration number, when given every iteration of the VU gets its own key (e.g. `__ITER`)
+     */
+    iteration?: number;
+
+    /**
+     * Key algorithm, supported values: `ed25519`, `EdDSA`, `ES256`, `ES384`, `ES512` (default: `ed25519`)
+     */
+    algorithm?: string;
+  }
+
+  /**
+   * Derive a stable key of the VU (and iteration) from the base seed with HKDF-SHA256, without coordination between VUs.
+   * The same base seed, VU and iteration give the same key in every run, different VUs get different keys.
+   * RSA keys can't be derived.
+   *
+   * @param baseSeed The secret base seed shared by the test runs
+   * @param options The VU and iteration numbers and key algorithm
+   * @returns The derived key
+   */
+  function deriveForVU(baseSeed: ByteArrayLike, options?: DeriveOptions): Key;
+
+  /**
+   * Options for shared secret derivation.
+   */
+  interface SharedSecretOptions {
+    /**
+     * The key derivation function: `none` for the raw shared secret or `concat` for Concat-KDF
+     * with SHA-256 as in ECDH-ES (RFC 7518 section 4.6.2) (default: `none`)
+     */
+    kdf?: string;
+
+    /**
+     * The size of the derived key in bytes (default: 32), only with `kdf`
+     */
+    length?: number;
+
+    /**
+     * The AlgorithmID of the Concat-KDF other info (e.g. `A128GCM`)
+     */
+    algorithm?: string;
+
+    /**
+     * The PartyUInfo of the Concat-KDF other info
+     */
+    apu?: ByteArrayLike;
+
+    /**
+     * The PartyVInfo of the Concat-KDF other info
+     */
+    apv?: ByteArrayLike;
+  }
+
+  /**
+   * Derive the ECDH shared secret of a private and a public key on the same curve (`P-256`, `P-384`, `P-521` or
+   * `X25519`), optionally passed through Concat-KDF. X25519 keys are given as OKP JWK (plain object or JSON source).
+   *
+   * ```js
+   * const cek = jwk.deriveSharedSecret(ephemeral, recipient, { kdf: "concat", length: 16, algorithm: "A128GCM" });
+   * ```
+   *
+   * @param privateKey The private key
+   * @param publicKey The public key (or private key of the other party)
+   * @param options The key derivation options
+   * @returns The shared secret or the derived key
+   */
+  function deriveSharedSecret(privateKey: Key | object | string, publicKey: Key | object | string, options?: SharedSecretOptions): ArrayBuffer;
+
+  /**
+   * Options for key pool creation.
+   */
+  interface PoolOptions {
+    /**
+     * The RSA modulus size in bits (default: 2048)
+     */
+    bits?: number;
+
+    /**
+     * The number of concurrent key generators (default: number of CPUs)
+     */
+    parallelism?: number;
+
+    /**
+     * Log the progress of key generation
+     */
+    progress?: boolean;
+  }
+
+  /**
+   * Pre-generated keys shared by the VUs.
+   */
+  interface Pool {
+    /**
+     * Hand out the next unused key of the pool.
+     *
+     * @returns The key, error if every key was handed out
+     */
+    take(): Key;
+
+    /**
+     * Get the key at index, the index wraps around (e.g. `__VU`).
+     *
+     * @param index The index of the key
+     * @returns The key
+     */
+    get(index: number): Key;
+
+    /**
+     * The number of keys in the pool.
+     *
+     * @returns The size of the pool
+     */
+    size(): number;
+  }
+
+  /**
+   * Create named key pool in init code or setup, the keys are generated concurrently.
+   * The keys are generated by the first call with the name, later calls (e.g. init code of other VUs)
+   * wait for the generation and return the same pool. Calls with the name of an existing pool must use the same
+   * algorithm, count and bits. A pool failed to generate is dropped, so it can be created again.
+   *
+   * @param name The name of the pool
+   * @param algorithm Key algorithm, as in [generate](../modules/jwk.md#generate)
+   * @param count The number of keys
+   * @param options The pool options
+   * @returns The key pool
+   */
+  function createPool(name: string, algorithm: string, count: number, options?: PoolOptions): Pool;
+
+  /**
+   * Generates keys concurrently with a bounded number of goroutines, returns when every key is generated.
+   * Unlike [createPool](../modules/jwk.md#createpool) the keys are generated on every call, e.g. in `setup()`.
+   *
+   * @param algorithm Key algorithm, the same as of [generate](../modules/jwk.md#generate)
+   * @param count The number of keys
+   * @param options The generation options (the same as of pools)
+   * @returns The generated keys
+   */
+  function generateMany(algorithm: string, count: number, options?: PoolOptions): Key[];
+
+  /**
+   * Adopt an existing asymmetric key with the given algorithm (`algorithm`).
+   * Ed25519 (the 64 bytes private key or the public key) and X25519 keys are checked and built like by
+   * [fromOKPComponents](../modules/jwk.md#fromokpcomponents), X25519 keys are plain JWK objects.
+   * Brainpool keys are the raw private key, or the uncompressed point (`0x04`, x and y) of the public key.
+   *
+   * @param algorithm Key algorithm, supported values: `ed25519`, `X25519`, `RSA1_5`, `ESB256`, `ESB384`, `ESB512`
+   * @param key private or public key
+   * @param isPublic true if `key` is a public key, false if it is a private key
+   * @returns The adopted key
+   */
+  function adopt(algorithm: string, key: ByteArrayLike, isPublic?: boolean): Key | object;
+}
+
+/**
+ * Module jwt aims to provide an implementation of the JSON Web Token.
+ * Only compact serializtion is supported.
+ */
+export namespace jwt {
+  /**
+   * Options for JWT creation, to produce tokens already expired or not yet valid.
+   * Durations are strings with unit suffix (e.g. `2h`, `10m`, `90s`) or numbers of seconds.
+   */
+  interface SignOptions {
+    /**
+     * The token expired this long ago (`exp` is in the past)
+     */
+    expiredBy?: string | number;
+
+    /**
+     * The token becomes valid in this time (`nbf` is in the future)
+     */
+    validIn?: string | number;
+
+    /**
+     * The difference of `exp` and `nbf` in seconds (default: 3600)
+     */
+    lifetime?: number;
+
+    /**
+     * Header parameters to list in the `crit` header, they must be in the header (sign only, signers take it from
+     * the signer options)
+     */
+    critical?: string[];
+
+    /**
+     * The time `expiredBy` and `validIn` are relative to: `Date`, RFC 3339 string, seconds since the epoch
+     * or function returning one of them (default: the configured clock)
+     */
+    now?: Date | string | number | (() => Date | string | number);
+
+    /**
+     * The salt length of `PS256`, `PS384` and `PS512` signatures: `hash` for the digest length, `max` for the maximum
+     * length allowed by the key, or number of bytes (default: `hash`, sign only, signers take it from the signer options).
+     * Verification accepts any salt length.
+     */
+    saltLength?: string | number;
+  }
+
+  /**
+   * Create JSON Web Token from payload and optional header.
+   * The `iat`, `nbf` and `exp` claims are overridden if `expiredBy` or `validIn` option is given.
+   *
+   * @param key The signing key
+   * @param payload The payload claims
+   * @param header The header fields
+   * @param options The signing options
+   * @returns The signed JWT in compact serialization form
+   */
+  function sign(key: jwk.Key, payload: object, header?: object, options?: SignOptions): string;
+
+  /**
+   * Create JSON Web Token like [sign](#sign), the signature is created outside of the VU's event loop.
+   * The token is timed when the function is called, the configured clock is not called later.
+   *
+   * @param key The signing key
+   * @param payload The payload claims
+   * @param header The header fields
+   * @param options The signing options
+   * @returns Promise of the signed JWT in compact serialization form, rejected with the errors thrown by sign
+   */
+  function signAsync(key: jwk.Key, payload: object, header?: object, options?: SignOptions): Promise<string>;
+
+  /**
+   * Options for token re-issuing.
+   */
+  interface ReissueOptions {
+    /**
+     * Replace the `jti` claim with a random one (control case of replay tests)
+     */
+    fresh?: boolean;
+
+    /**
+     * The signing algorithm (default: `alg` of the captured token)
+     */
+    alg?: string;
+  }
+
+  /**
+   * Re-sign a captured token with the same header (every protected parameter, including `kid`, `typ`, `nonce` and `x5c`) and claims.
+   * The `jti` is reused by default, to test replay detection.
+   *
+   * @param token The captured JWT
+   * @param key The signing key of the original issuer
+   * @param options The re-issuing options
+   * @returns The re-signed JWT
+   */
+  function reissue(token: string, key: jwk.Key, options?: ReissueOptions): string;
+
+  /**
+   * Options for signer creation.
+   */
+  interface SignerOptions {
+    /**
+     * The header fields of every token
+     */
+    header?: object;
+
+    /**
+     * Header parameters to list in the `crit` header, they must be in the header
+     */
+    critical?: string[];
+
+    /**
+     * The salt length of `PS256`, `PS384` and `PS512` signatures, see the `saltLength` sign option
+     */
+    saltLength?: string | number;
+  }
+
+  /**
+   * Reusable JWT signer, created once (e.g. in init code) for high rate token minting.
+   */
+  interface Signer {
+    /**
+     * Create JSON Web Token from payload with the key and header of the signer.
+     *
+     * @param payload The payload claims
+     * @param options The signing options
+     * @returns The signed JWT in compact serialization form
+     */
+    sign(payload: object, options?: SignOptions): string;
+  }
+
+  /**
+   * Create reusable signer, the signing key and header are processed only once.
+   *
+   * @param key The signing key
+   * @param options The signer options
+   * @returns The signer
+   */
+  function createSigner(key: jwk.Key, options?: SignerOptions): Signer;
+
+  /**
+   * Decode JSON Web Token payload without signature validation.
+   *
+   * @param token The JWT to decode
+   * @returns The decoded payload
+   */
+  function decode(token: string): object;
+
+  /**
+   * Verify JSON Web Token signature and decode payload on success.
+   * Tokens with `crit` header parameters not handled by the configuration are rejected.
+   *
+   * @param token The JWT to verify
+   * @param key The signature validation key (or keys)
+   * @returns The payload of the verified token
+   */
+  function verify(token: string, ...key: jwk.Key[]): object;
+
+  /**
+   * Verify JSON Web Token like [verify](#verify), outside of the VU's event loop.
+   *
+   * @param token The JWT to verify
+   * @param key The signature validation key (or keys)
+   * @returns Promise of the payload of the verified token, rejected with the errors thrown by verify
+   */
+  function verifyAsync(token: string, ...key: jwk.Key[]): Promise<object>;
+
+  /**
+   * Result of detailed JSON Web Token verification.
+   */
+  interface Verified {
+    /**
+     * The payload of the verified token
+     */
+    payload: object;
+
+    /**
+     * The index of the verified signature (always 0 for JWT)
+     */
+    index: number;
+
+    /**
+     * The key ID of the key that validated the signature
+     */
+    kid: string;
+
+    /**
+     * The signature algorithm
+     */
+    alg: string;
+
+    /**
+     * The key that validated the signature
+     */
+    key: jwk.Key;
+  }
+
+  /**
+   * Verify JSON Web Token signature and report which key validated it.
+   * Keys with matching `kid` are tried first, then every key.
+   *
+   * @param token The JWT to verify
+   * @param key The signature validation key (or keys)
+   * @returns The verification result
+   */
+  function verifyDetailed(token: string, ...key: jwk.Key[]): Verified;
+
+  /**
+   * Options for JWT claims validation.
+   */
+  interface VerifyOptions {
+    /**
+     * The expected audience, one of the `aud` claim values
+     */
+    audience?: string;
+
+    /**
+     * The expected `iss` claim
+     */
+    issuer?: string;
+
+    /**
+     * The expected `sub` claim
+     */
+    subject?: string;
+
+    /**
+     * The allowed clock skew for `exp`, `nbf` and `iat` validation,
+     * duration string (e.g. `30s`) or number of seconds (default: 1 minute)
+     */
+    leeway?: string | number;
+
+    /**
+     * The time of validation: `Date`, RFC 3339 string, seconds since the epoch or function returning one of them
+     * (default: the configured clock, see [configure](../modules/jose.md#configure))
+     */
+    now?: Date | string | number | (() => Date | string | number);
+
+    /**
+     * The trusted root certificates (PEM bundle, DER encoded certificate or array of them): the signature is verified
+     * with the key of the `x5c` header instead of the given keys, after validating its chain to one of the roots
+     * (see [fromX5C](../modules/jwk.md#fromx5c))
+     */
+    roots?: jwk.ByteArrayLike | jwk.ByteArrayLike[];
+
+    /**
+     * Additional intermediate certificates for the `x5c` chain validation, like `roots`
+     */
+    intermediates?: jwk.ByteArrayLike | jwk.ByteArrayLike[];
+  }
+
+  /**
+   * Verify JSON Web Token signature, then validate `exp`, `nbf` and `iat` and the expected claims.
+   * Failed verifications are counted by the `jose_verify_failures` metric with `reason` tag:
+   * `expired`, `not-yet-valid`, `wrong-audience`, `wrong-issuer`, `wrong-subject`, `bad-signature`, `untrusted-chain`, `unknown-kid`, `alg-not-allowed` or `invalid`.
+   *
+   * @param token The JWT to verify
+   * @param keys The signature validation key (or keys), ignored with the `roots` option
+   * @param options The expected claims
+   * @returns The payload of the verified token
+   */
+  function verifyClaims(token: string, keys: jwk.Key | jwk.Key[], options?: VerifyOptions): object;
+
+  /**
+   * Options for nested token consumption.
+   */
+  interface ConsumeOptions {
+    /**
+     * The decryption key of the JWE layers, or passphrase for password based encryption
+     */
+    decryptionKey?: jwk.Key | string;
+
+    /**
+     * The decryption options of the JWE layers
+     */
+    decrypt?: jwe.DecryptOptions;
+
+    /**
+     * The expected claims of the innermost JWT, the claims are validated like by [verifyClaims](../modules/jwt.md#verifyclaims)
+     * only if given (otherwise only the signature is verified like by [verify](../modules/jwt.md#verify))
+     */
+    verify?: VerifyOptions;
+
+    /**
+     * The maximum number of layers (default: 4)
+     */
+    maxLayers?: number;
+  }
+
+  /**
+   * A layer of nested token.
+   */
+  interface Layer {
+    /**
+     * The type of the layer: `JWE` or `JWS`
+     */
+    type: string;
+
+    /**
+     * The header of the layer (the merged header of the decrypted recipient for JWE)
+     */
+    header: object;
+  }
+
+  /**
+   * The result of nested token consumption.
+   */
+  interface Consumed {
+    /**
+     * The claims of the innermost JWT
+     */
+    claims: object;
+
+    /**
+     * The headers of the layers, the outermost first
+     */
+    layers: Layer[];
+  }
+
+  /**
+   * Unwrap nested JWT (RFC 7519 section 5.2) until the innermost claims. JWE layers are decrypted, JWS layers with
+   * `cty` header `JWT` are verified and their payload is consumed as the next layer, the first JWS without it is
+   * the innermost JWT. Throws `MalformedError` if the nesting is invalid or deeper than `maxLayers`.
+   *
+   * ```js
+   * const { claims, layers } = jwt.consume(token, idpKeys, { decryptionKey: clientKey, verify: { audience: "api" } });
+   * ```
+   *
+   * @param token The nested token (JWE or JWS in any serialization, the innermost JWT in compact serialization)
+   * @param keys The signature validation key (or keys) of the JWS layers
+   * @param options The decryption key and the claims validation options
+   * @returns The innermost claims and the layer headers
+   */
+  function consume(token: string, keys: jwk.Key | jwk.Key[], options?: ConsumeOptions): Consumed;
+
+  /**
+   * Create claim set from a claims profile template.
+   *
+   * Built-in templates:
+   * - `id_token`: OpenID Connect ID token, requires `iss`, `sub` and `aud`, populates `iat`, `exp`, `auth_time` and `nonce`
+   * - `access_token`: RFC 9068 access token, requires `iss`, `sub`, `aud` and `client_id`, populates `iat`, `exp` and `jti`
+   * - `refresh_token`: refresh token, requires `iss` and `sub`, populates `iat`, `exp` and `jti`
+   *
+   * Time based claims are relative to the current time, `nonce` and `jti` are random on every call.
+   * Overrides are applied as in [merge](#merge), so `null` valued overrides remove the claim.
+   *
+   * @param name The template name
+   * @param overrides The claims to add, replace or remove
+   * @returns The claim set
+   */
+  function template(name: string, overrides?: object): object;
+
+  /**
+   * Register a custom claims profile template (or replace an existing one).
+   * Registered templates are shared between VUs.
+   *
+   * @param name The template name
+   * @param claims The default claims of the template
+   */
+  function registerTemplate(name: string, claims: object): void;
+
+  /**
+   * Merge a base claim set with overrides, without modifying any of the arguments.
+   * Overrides are applied in order, `null` valued claims are removed (e.g. `{ exp: null }` omits `exp`).
+   * Nested objects are merged recursively.
+   *
+   * @param base The base claim set
+   * @param overrides The claims to add, replace or remove
+   * @returns The merged claim set
+   */
+  function merge(base: object, ...overrides: object[]): object;
+
+  /**
+   * A failed JSON Schema assertion.
+   */
+  interface Violation {
+    /**
+     * JSON Pointer (RFC 6901) to the claim, empty string for the claim set itself (e.g. `/aud/0`)
+     */
+    path: string;
+
+    /**
+     * The failed schema keyword (e.g. `required`, `type`, `pattern`)
+     */
+    keyword: string;
+
+    /**
+     * Human readable description of the violation
+     */
+    message: string;
+  }
+
+  /**
+   * Validate claims against a JSON Schema (2020-12 assertion and applicator keywords, `$ref` within the schema only),
+   * without leaving the VU. The `date-time`, `date`, `email`, `uri` and `uuid` formats are asserted.
+   * Invalid schemas throw `InvalidArgumentError`.
+   *
+   * ```js
+   * const violations = jwt.validateClaims(jwt.decode(token), {
+   *   type: "object",
+   *   required: ["sub", "exp"],
+   *   properties: { sub: { type: "string", minLength: 1 }, exp: { type: "integer" } },
+   * });
+   * ```
+   *
+   * @param claims The claims, like the result of [decode](../modules/jwt.md#decode)
+   * @param schema The JSON Schema as plain object
+   * @returns The violations, empty if the claims are valid
+   */
+  function validateClaims(claims: object, schema: object | boolean): Violation[];
+
+  /**
+   * Options for claims comparison.
+   */
+  interface DiffOptions {
+    /**
+     * Claims to ignore (e.g. volatile `iat`, `exp` and `jti`): top level claim names or JSON Pointers like `/act/sub`
+     */
+    ignore?: string[];
+
+    /**
+     * Ignore claims of the actual claims missing from the expected claims (default: false)
+     */
+    ignoreExtra?: boolean;
+  }
+
+  /**
+   * A difference of the compared claims.
+   */
+  interface Difference {
+    /**
+     * JSON Pointer (RFC 6901) to the claim (e.g. `/aud/1`)
+     */
+    path: string;
+
+    /**
+     * The kind of the difference: `missing` from the actual claims, `unexpected` in the actual claims or `changed`
+     */
+    kind: string;
+
+    /**
+     * The expected value (undefined for `unexpected`)
+     */
+    expected: any;
+
+    /**
+     * The actual value (undefined for `missing`)
+     */
+    actual: any;
+  }
+
+  /**
+   * Result of claims comparison.
+   */
+  interface ClaimsDiff {
+    /**
+     * True if there is no difference
+     */
+    equal: boolean;
+
+    /**
+     * The differences, ordered by path
+     */
+    differences: Difference[];
+
+    /**
+     * Readable diff for logs and check messages, one line per difference (`-` missing, `+` unexpected, `~` changed)
+     */
+    text: string;
+  }
+
+  /**
+   * Deep-compare expected and actual claims. Numbers are compared by value, arrays item by item.
+   *
+   * ```js
+   * const diff = jwt.diffClaims(expected, jwt.decode(token), { ignore: ["iat", "exp", "jti"] });
+   *
+   * check(diff, { "claims match": (d) => d.equal }) || console.warn(diff.text);
+   * ```
+   *
+   * @param expected The expected claims
+   * @param actual The actual claims, like the result of [decode](../modules/jwt.md#decode)
+   * @param options The ignored claims
+   * @returns The comparison result
+   */
+  function diffClaims(expected: object, actual: object, options?: DiffOptions): ClaimsDiff;
+
+  /**
+   * Decode the token (without signature verification) and select claim values with a JSONPath-like selector:
+   * `$` is the claim set, `.name` and `['name']` select members, `[n]` array items (negative from the end),
+   * `[*]` and `.*` every member or item, `..name` descendants at any depth.
+   * Definite selectors return the value (`null` if it doesn't exist), selectors with wildcard or `..` return the array
+   * of matching values. Parsed selectors are cached.
+   *
+   * ```js
+   * const roles = jwt.extract(token, "$.realm_access.roles[*]");
+   * const account = jwt.extract(token, "$.resource_access.account.roles[0]");
+   * ```
+   *
+   * @param token The JWT, or already decoded claims
+   * @param selector The selector
+   * @returns The selected value, or array of values
+   */
+  function extract(token: string | object, selector: string): any;
+}
+
+/**
+ * Module jws aims to provide an implementation of the JSON Web Signature.
+ * Unlike jwt, the payload is arbitrary binary content.
+ * Compact, flattened JSON and general JSON serializations are supported.
+ */
+export namespace jws {
+  /**
+   * Options for JWS creation.
+   */
+  interface SignOptions {
+    /**
+     * Serialization form: `compact` (default), `flattened` or `general`
+     */
+    serialization?: string;
+
+    /**
+     * Omit the payload from the result (`header..signature` in compact form), see [verifyDetached](#verifydetached)
+     */
+    detached?: boolean;
+
+    /**
+     * Set to false for unencoded payload (RFC 7797), `b64` and `crit` headers are added automatically
+     */
+    b64?: boolean;
+
+    /**
+     * Unprotected header for every signature, or array of per signature unprotected headers (JSON serializations only).
+     * When the unprotected header contains `kid`, it is omitted from the protected header.
+     */
+    unprotected?: object | object[];
+
+    /**
+     * Protected header parameters to list in the `crit` header (e.g. `sigT`), they must be in the header.
+     * Verification requires them to be handled, see the `critical` option of [configure](../modules/jose.md#configure).
+     */
+    critical?: string[];
+
+    /**
+     * The salt length of `PS256`, `PS384` and `PS512` signatures: `hash` for the digest length, `max` for the maximum
+     * length allowed by the key, or number of bytes (default: `hash`). Verification accepts any salt length.
+     */
+    saltLength?: string | number;
+  }
+
+  /**
+   * A signature of a parsed JWS.
+   */
+  interface Signature {
+    /**
+     * The protected header fields
+     */
+    protected: object;
+
+    /**
+     * The unprotected header fields, or null
+     */
+    header: object;
+
+    /**
+     * The signature value
+     */
+    signature: ArrayBuffer;
+  }
+
+  /**
+   * JSON Web Signature parsed from any serialization form.
+   */
+  interface Parsed {
+    /**
+     * The payload (empty for detached content)
+     */
+    payload: ArrayBuffer;
+
+    /**
+     * The signatures with their headers
+     */
+    signatures: Signature[];
+  }
+
+  /**
+   * Create JSON Web Signature of payload with optional protected header.
+   * When an array of keys is given, one signature is created with every key
+   * and the default serialization is `general`.
+   *
+   * @param key The signing key (or keys)
+   * @param payload The payload to sign
+   * @param header The protected header fields
+   * @param options The signing options
+   * @returns The JWS in the requested serialization form (JSON serializations as JSON string)
+   */
+  function sign(key: jwk.Key | jwk.Key[], payload: jwk.ByteArrayLike, header?: object, options?: SignOptions): string;
+
+  /**
+   * Create JSON Web Signature like [sign](#sign), outside of the VU's event loop.
+   * The payload is copied when the function is called.
+   *
+   * @param key The signing key (or keys)
+   * @param payload The payload to sign
+   * @param header The protected header fields
+   * @param options The signing options
+   * @returns Promise of the JWS, rejected with the errors thrown by sign
+   */
+  function signAsync(
+    key: jwk.Key | jwk.Key[],
+    payload: jwk.ByteArrayLike,
+    header?: object,
+    options?: SignOptions
+  ): Promise<string>;
+
+  /**
+   * Decode JSON Web Signature payload without signature validation.
+   * The serialization form is detected automatically.
+   *
+   * @param token The JWS to decode
+   * @returns The decoded payload
+   */
+  function decode(token: string): ArrayBuffer;
+
+  /**
+   * Parse JSON Web Signature without signature validation, exposing protected and unprotected headers.
+   *
+   * @param token The JWS to parse
+   * @returns The parsed JWS
+   */
+  function parse(token: string): Parsed;
+
+  /**
+   * Verify JSON Web Signature and decode payload on success.
+   * The serialization form is detected automatically.
+   * Any of the signatures must be valid, keys with matching `kid` are tried first, then every key.
+   * Signatures with `crit` header parameters other than `b64` and the configured ones are rejected.
+   *
+   * @param token The JWS to verify
+   * @param key The signature validation key (or keys)
+   * @returns The payload of the verified JWS
+   */
+  function verify(token: string, ...key: jwk.Key[]): ArrayBuffer;
+
+  /**
+   * Verify JSON Web Signature like [verify](#verify), outside of the VU's event loop.
+   *
+   * @param token The JWS to verify
+   * @param key The signature validation key (or keys)
+   * @returns Promise of the payload of the verified JWS, rejected with the errors thrown by verify
+   */
+  function verifyAsync(token: string, ...key: jwk.Key[]): Promise<ArrayBuffer>;
+
+  /**
+   * Verify every signature of a (multi-signature) JSON Web Signature and decode payload on success.
+   * Verification fails if any of the signatures can't be verified with the given keys.
+   *
+   * @param token The JWS to verify
+   * @param key The signature validation keys
+   * @returns The payload of the verified JWS
+   */
+  function verifyAll(token: string, ...key: jwk.Key[]): ArrayBuffer;
+
+  /**
+   * Result of detailed JSON Web Signature verification.
+   */
+  interface Verified {
+    /**
+     * The payload of the verified JWS
+     */
+    payload: ArrayBuffer;
+
+    /**
+     * The index of the first verified signature
+     */
+    index: number;
+
+    /**
+     * The key ID of the key that validated the signature
+     */
+    kid: string;
+
+    /**
+     * The signature algorithm
+     */
+    alg: string;
+
+    /**
+     * The key that validated the signature
+     */
+    key: jwk.Key;
+  }
+
+  /**
+   * Verify JSON Web Signature and report which signature and key validated it.
+   *
+   * @param token The JWS to verify
+   * @param key The signature validation key (or keys)
+   * @returns The verification result
+   */
+  function verifyDetailed(token: string, ...key: jwk.Key[]): Verified;
+
+  /**
+   * Verify JSON Web Signature with detached content (RFC 7515 Appendix F and RFC 7797).
+   * Throws an error if the signature is invalid.
+   *
+   * @param token The JWS to verify, with empty (or missing) payload
+   * @param payload The detached content
+   * @param key The signature validation key (or keys)
+   */
+  function verifyDetached(token: string, payload: jwk.ByteArrayLike, ...key: jwk.Key[]): void;
+
+  /**
+   * Signer for JSON Web Signature over content written in chunks, for payloads too large to be handled as a whole.
+   * The signing input is hashed incrementally (except for EdDSA, which needs the whole message in memory).
+   */
+  interface StreamSigner {
+    /**
+     * Append a chunk of content.
+     *
+     * @param chunk The next chunk of content
+     */
+    update(chunk: jwk.ByteArrayLike): void;
+
+    /**
+     * Complete the signature. The signer can't be used after finishing.
+     *
+     * @returns The JWS in compact serialization form with detached content (`header..signature`)
+     */
+    finish(): string;
+  }
+
+  /**
+   * Create a stream signer producing detached JWS, see [verifyDetached](#verifydetached).
+   * Only the `b64` and `critical` options are used from options.
+   *
+   * @param key The signing key
+   * @param header The protected header fields
+   * @param options The signing options
+   * @returns The stream signer
+   */
+  function createStreamSigner(key: jwk.Key, header?: object, options?: SignOptions): StreamSigner;
+
+  /**
+   * Compute bare HMAC of the data with an oct key, without JWS framing, e.g. for APIs
+   * signing request bodies in a header next to tokens.
+   *
+   * ```js
+   * const signature = encoding.b64encode(jws.hmac(secret, body, "HS256"));
+   * ```
+   *
+   * @param key The oct key
+   * @param data The data to authenticate
+   * @param algorithm `HS256`, `HS384` or `HS512` (default: `alg` of the key or `HS256`)
+   * @returns The HMAC value
+   */
+  function hmac(key: jwk.Key, data: jwk.ByteArrayLike, algorithm?: string): ArrayBuffer;
+
+  /**
+   * Verify bare HMAC of the data in constant time, see [hmac](#hmac).
+   * Throws an error if the signature is invalid.
+   *
+   * @param key The oct key
+   * @param data The authenticated data
+   * @param signature The HMAC value to verify
+   * @param algorithm `HS256`, `HS384` or `HS512` (default: `alg` of the key or `HS256`)
+   */
+  function verifyHmac(key: jwk.Key, data: jwk.ByteArrayLike, signature: jwk.ByteArrayLike, algorithm?: string): void;
+}
+
+/**
+ * Module jwe aims to provide an implementation of the JSON Web Encryption.
+ * The plaintext is arbitrary binary content.
+ * Compact, flattened JSON and general JSON serializations are supported.
+ */
+export namespace jwe {
+  /**
+   * Options for JWE creation.
+   */
+  interface EncryptOptions {
+    /**
+     * Additional protected header fields (e.g. `cty`, `typ`)
+     */
+    header?: object;
+
+    /**
+     * Serialization form: `compact` (default), `flattened` or `general`
+     */
+    serialization?: string;
+
+    /**
+     * Key management algorithm for every recipient: `RSA1_5`, `RSA-OAEP`, `RSA-OAEP-256`, `A128KW`, `A192KW`, `A256KW`, `dir`,
+     * `ECDH-ES`, `ECDH-ES+A128KW`, `ECDH-ES+A192KW`, `ECDH-ES+A256KW`, `A128GCMKW`, `A192GCMKW`, `A256GCMKW`,
+     * `PBES2-HS256+A128KW`, `PBES2-HS384+A192KW`, `PBES2-HS512+A256KW`, `ECDH-1PU`, `ECDH-1PU+A128KW`, `ECDH-1PU+A192KW` or `ECDH-1PU+A256KW`.
+     * ECDH-1PU key wrapping requires AES-CBC-HMAC content encryption (default `A256CBC-HS512`).
+     */
+    alg?: string;
+
+    /**
+     * Content encryption algorithm: `A128CBC-HS256`, `A192CBC-HS384`, `A256CBC-HS512`, `A128GCM`, `A192GCM` or `A256GCM` (default)
+     */
+    enc?: string;
+
+    /**
+     * Compression algorithm applied to the plaintext before encryption: `DEF` (raw DEFLATE).
+     * Compressed plaintext is decompressed by [decrypt](#decrypt) automatically.
+     */
+    zip?: string;
+
+    /**
+     * Additional authenticated data (JSON serializations only, the default serialization is `flattened` when set)
+     */
+    aad?: jwk.ByteArrayLike;
+
+    /**
+     * Agreement PartyUInfo for ECDH-ES key management algorithms, added to the header as `apu`
+     */
+    apu?: jwk.ByteArrayLike;
+
+    /**
+     * Agreement PartyVInfo for ECDH-ES key management algorithms, added to the header as `apv`
+     */
+    apv?: jwk.ByteArrayLike;
+
+    /**
+     * PBES2 iteration count, added to the header as `p2c` (default: 100000)
+     */
+    p2c?: number;
+
+    /**
+     * PBES2 salt input, added to the header as `p2s` (default: 16 random bytes)
+     */
+    p2s?: jwk.ByteArrayLike;
+
+    /**
+     * The sender's private EC or X25519 key for sender authenticated ECDH-1PU key agreement (draft-madden-jose-ecdh-1pu-04).
+     * The default key management algorithm of EC recipient keys is `ECDH-1PU` (`ECDH-1PU+A256KW` for multiple recipients)
+     * and `skid` header is added from the key ID of the sender key. Multiple recipients share one ephemeral key,
+     * sent as `epk` in the protected header, so they all must use the curve of the sender key.
+     */
+    sender?: jwk.Key;
+
+    /**
+     * Header parameters to list in the `crit` header, they must be in the header.
+     * Decryption requires them to be handled, see the `critical` option of [configure](../modules/jose.md#configure).
+     */
+    critical?: string[];
+  }
+
+  /**
+   * Options for JWE decryption.
+   */
+  interface DecryptOptions {
+    /**
+     * Allowed key management algorithms (default: any)
+     */
+    algorithms?: string[];
+
+    /**
+     * Allowed content encryption algorithms (default: any)
+     */
+    encryptions?: string[];
+
+    /**
+     * Expected additional authenticated data, decryption fails if the `aad` of the JWE is different
+     */
+    aad?: jwk.ByteArrayLike;
+
+    /**
+     * The sender's public key, required for ECDH-1PU key agreement
+     */
+    sender?: jwk.Key;
+  }
+
+  /**
+   * Encrypt plaintext to JSON Web Encryption.
+   *
+   * The key management algorithm is the `alg` option, or the `alg` of the key if it is a key management algorithm,
+   * otherwise it depends on the key type: `RSA-OAEP-256` for RSA, `ECDH-ES` for EC and `A128KW`, `A192KW` or `A256KW` for symmetric keys.
+   * The content encryption algorithm is the `enc` option (default `A256GCM`). For asymmetric keys the public part of the key is used.
+   *
+   * When the key is a string, it is used as passphrase for password based encryption (default algorithm `PBES2-HS256+A128KW`).
+   *
+   * When an array of keys is given, the content is encrypted to every recipient and the default serialization is `general`.
+   * Each recipient has its own key management algorithm, `ECDH-ES+A256KW` is used instead of `ECDH-ES` for EC keys
+   * (`ECDH-ES` and `dir` are not allowed with multiple recipients).
+   *
+   * @param key The recipient's key (or keys), or passphrase
+   * @param plaintext The content to encrypt
+   * @param options The encryption options
+   * @returns The JWE in the requested serialization form (JSON serializations as JSON string)
+   */
+  function encrypt(key: jwk.Key | jwk.Key[] | string, plaintext: jwk.ByteArrayLike, options?: EncryptOptions): string;
+
+  /**
+   * Decrypt JSON Web Encryption.
+   * The serialization form is detected automatically.
+   * Decryption fails if the key management algorithm of the recipient or the content encryption algorithm is not allowed.
+   *
+   * @param key The decryption key, or passphrase for password based encryption
+   * @param token The JWE to decrypt
+   * @param options The decryption options
+   * @returns The decrypted plaintext
+   */
+  function decrypt(key: jwk.Key | string, token: string, options?: DecryptOptions): ArrayBuffer;
+
+  /**
+   * File handle of the `k6/experimental/fs` module, the content is read from the current offset to the end.
+   */
+  interface File {
+    path: string;
+  }
+
+  /**
+   * Encrypt large binary content (hundreds of megabytes) to compact JWE bytes, e.g. for encrypted file uploads.
+   * The content is encrypted at once and base64url encoded in chunks straight into the result, neither the content
+   * nor the token is converted to a JS string. Files can be read with `open(path, "b")` in init code, or opened with
+   * `k6/experimental/fs`, its file contents are kept in memory once for all VUs.
+   *
+   * ```js
+   * const file = open("./archive.bin", "b");
+   *
+   * export default function () {
+   *   http.post(url, jwe.encryptBinary(key, file), { headers: { "Content-Type": "application/jose" } });
+   * }
+   * ```
+   *
+   * The options are the same as of [encrypt](#encrypt), but only the compact serialization is supported:
+   * one recipient, no `aad` and no ECDH-1PU key wrapping.
+   *
+   * @param key The recipient's key, or passphrase
+   * @param plaintext The content to encrypt, or file handle
+   * @param options The encryption options
+   * @returns The JWE in compact serialization form, as ASCII bytes
+   */
+  function encryptBinary(key: jwk.Key | string, plaintext: jwk.ByteArrayLike | File, options?: EncryptOptions): ArrayBuffer;
+
+  /**
+   * Decrypt large compact JWE given as bytes (e.g. response body with `responseType: "binary"`) or string,
+   * or file handle, see [encryptBinary](#encryptbinary). The content is decoded in chunks and decrypted at once.
+   * The [maxTokenSize](../interfaces/jose.configureoptions.md#maxtokensize) limit doesn't apply, ECDH-1PU is not supported.
+   *
+   * @param key The decryption key, or passphrase for password based encryption
+   * @param token The JWE to decrypt, or file handle
+   * @param options The decryption options
+   * @returns The decrypted plaintext
+   */
+  function decryptBinary(key: jwk.Key | string, token: jwk.ByteArrayLike | File, options?: DecryptOptions): ArrayBuffer;
+
+  /**
+   * Wrap (encrypt) a content key with a key management algorithm, without JWE construction.
+   *
+   * ```js
+   * const wrapped = jwe.wrapKey(kek, crypto.randomBytes(32), "A256KW");
+   * ```
+   *
+   * @param key The key encryption key: oct key for AES key wrap, RSA key for RSA-OAEP
+   * @param cek The content key to wrap
+   * @param algorithm `A128KW`, `A192KW`, `A256KW`, `RSA-OAEP` or `RSA-OAEP-256`
+   *                  (default: `alg` of the key, or derived from the key like for [encrypt](#encrypt))
+   * @returns The wrapped key
+   */
+  function wrapKey(key: jwk.Key, cek: jwk.ByteArrayLike, algorithm?: string): ArrayBuffer;
+
+  /**
+   * Unwrap (decrypt) a content key wrapped by [wrapKey](#wrapkey) or a JWE recipient.
+   * Throws `DecryptionError` if the wrapped key can't be decrypted with the key.
+   *
+   * @param key The key encryption key: oct key for AES key wrap, RSA private key for RSA-OAEP
+   * @param wrapped The wrapped key
+   * @param algorithm The algorithm, as for [wrapKey](#wrapkey)
+   * @returns The content key
+   */
+  function unwrapKey(key: jwk.Key, wrapped: jwk.ByteArrayLike, algorithm?: string): ArrayBuffer;
+}
+
+/**
+ * Module kms aims to provide signing with keys held by key management services, the private key never leaves the service.
+ *
+ * The returned keys sign like any other key in [jwt.sign](../modules/jwt.md#sign), [jws.sign](../modules/jws.md#sign)
+ * and the modules building on them, each signature is a request to the service (a SHA-2 digest is sent, never the payload).
+ * They verify their own signatures locally with the public key, [jwk.toObject](../modules/jwk.md#toobject) returns the public JWK.
+ * Requests in VU code go through the k6 transport (TLS and host options apply), but don't emit HTTP metrics.
+ *
+ * Failed requests throw `RemoteSignerError`, missing credentials `InvalidArgumentError`.
+ */
+export namespace kms {
+  /**
+   * Options for AWS KMS keys.
+   */
+  interface AWSOptions {
+    /**
+     * The AWS region (default: region of the key ARN, `AWS_REGION` or `AWS_DEFAULT_REGION` environment variable)
+     */
+    region?: string;
+
+    /**
+     * The access key ID (default: `AWS_ACCESS_KEY_ID` environment variable)
+     */
+    accessKeyId?: string;
+
+    /**
+     * The secret access key (default: `AWS_SECRET_ACCESS_KEY` environment variable)
+     */
+    secretAccessKey?: string;
+
+    /**
+     * The session token of temporary credentials (default: `AWS_SESSION_TOKEN` environment variable)
+     */
+    sessionToken?: string;
+
+    /**
+     * The KMS endpoint URL (default: `https://kms.<region>.amazonaws.com`)
+     */
+    endpoint?: string;
+
+    /**
+     * The JWS algorithm, one of the signing algorithms of the key (default: ES* for EC keys, RS* for RSA keys)
+     */
+    algorithm?: string;
+
+    /**
+     * The `kid` of the key (default: JWK thumbprint of the public key)
+     */
+    kid?: string;
+  }
+
+  /**
+   * Create signing key of the AWS KMS asymmetric `SIGN_VERIFY` key, requests are signed with AWS Signature Version 4.
+   * The public key is fetched on creation, create the keys in the init context.
+   *
+   * ```js
+   * const key = kms.aws("arn:aws:kms:eu-west-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab");
+   *
+   * export default function () {
+   *   const token = jwt.sign(key, { sub: "alice" });
+   * }
+   * ```
+   *
+   * @param keyId The key ID, key ARN, alias name or alias ARN
+   * @param options The region, credentials and algorithm
+   * @returns The signing key
+   */
+  function aws(keyId: string, options?: AWSOptions): jwk.Key;
+
+  /**
+   * Options for Google Cloud KMS keys.
+   */
+  interface GCPOptions {
+    /**
+     * The service account key, JSON string or parsed object (default: file of `GOOGLE_APPLICATION_CREDENTIALS` environment variable)
+     */
+    credentials?: string | object;
+
+    /**
+     * The OAuth 2.0 access token, used instead of the service account
+     */
+    accessToken?: string;
+
+    /**
+     * The Cloud KMS endpoint URL (default: `https://cloudkms.googleapis.com`)
+     */
+    endpoint?: string;
+
+    /**
+     * The `kid` of the key (default: JWK thumbprint of the public key)
+     */
+    kid?: string;
+  }
+
+  /**
+   * Create signing key of the Google Cloud KMS asymmetric signing key version.
+   * The access token is obtained with the service account key and renewed before expiry.
+   * The JWS algorithm follows the algorithm of the key version (`EC_SIGN_P256_SHA256` is ES256, `RSA_SIGN_PSS_2048_SHA256` is PS256 and so on).
+   * The public key is fetched on creation, create the keys in the init context.
+   *
+   * ```js
+   * const key = kms.gcp("projects/my-project/locations/global/keyRings/my-ring/cryptoKeys/my-key/cryptoKeyVersions/1", {
+   *   credentials: open("./service-account.json"),
+   * });
+   * ```
+   *
+   * @param name The resource name of the key version
+   * @param options The credentials
+   * @returns The signing key
+   */
+  function gcp(name: string, options?: GCPOptions): jwk.Key;
+
+  /**
+   * Options for Azure Key Vault keys.
+   */
+  interface AzureOptions {
+    /**
+     * The directory (tenant) ID of the client secret (default: `AZURE_TENANT_ID` environment variable)
+     */
+    tenantId?: string;
+
+    /**
+     * The application (client) ID, or the client ID of user-assigned managed identity (default: `AZURE_CLIENT_ID` environment variable)
+     */
+    clientId?: string;
+
+    /**
+     * The client secret (default: `AZURE_CLIENT_SECRET` environment variable)
+     */
+    clientSecret?: string;
+
+    /**
+     * Use the managed identity of the host, implied in App Service when `IDENTITY_ENDPOINT` environment variable is set (default: false)
+     */
+    managedIdentity?: boolean;
+
+    /**
+     * The OAuth 2.0 access token for `https://vault.azure.net`, used instead of the other credentials
+     */
+    accessToken?: string;
+
+    /**
+     * The Microsoft Entra authority host (default: `AZURE_AUTHORITY_HOST` environment variable or `https://login.microsoftonline.com`)
+     */
+    authorityHost?: string;
+
+    /**
+     * The JWS algorithm (default: ES* by curve for EC keys, RS256 for RSA keys)
+     */
+    algorithm?: string;
+
+    /**
+     * The `kid` of the key (default: JWK thumbprint of the public key)
+     */
+    kid?: string;
+  }
+
+  /**
+   * Create signing key of the Azure Key Vault (or Managed HSM) key, authenticated with client secret or managed identity.
+   * Without version in the key identifier the current version is used, and pinned for the lifetime of the key.
+   * The public key is fetched on creation, create the keys in the init context.
+   *
+   * ```js
+   * const key = kms.azure("https://my-vault.vault.azure.net/keys/signing", { managedIdentity: true });
+   * ```
+   *
+   * @param keyId The key identifier URL
+   * @param options The credentials and algorithm
+   * @returns The signing key
+   */
+  function azure(keyId: string, options?: AzureOptions): jwk.Key;
+
+  /**
+   * Options for HashiCorp Vault transit keys.
+   */
+  interface VaultOptions {
+    /**
+     * The Vault address (default: `VAULT_ADDR` environment variable)
+     */
+    address?: string;
+
+    /**
+     * The Vault token (default: `VAULT_TOKEN` environment variable)
+     */
+    token?: string;
+
+    /**
+     * The AppRole role ID, used when no token is given (default: `VAULT_ROLE_ID` environment variable)
+     */
+    roleId?: string;
+
+    /**
+     * The AppRole secret ID (default: `VAULT_SECRET_ID` environment variable)
+     */
+    secretId?: string;
+
+    /**
+     * The mount path of the AppRole auth method (default: `approle`)
+     */
+    approleMount?: string;
+
+    /**
+     * The Vault Enterprise namespace (default: `VAULT_NAMESPACE` environment variable)
+     */
+    namespace?: string;
+
+    /**
+     * The mount path of the transit secrets engine (default: `transit`)
+     */
+    mount?: string;
+
+    /**
+     * The JWS algorithm (default: ES* by curve for ECDSA keys, RS256 for RSA keys)
+     */
+    algorithm?: string;
+
+    /**
+     * The `kid` of the key (default: JWK thumbprint of the public key)
+     */
+    kid?: string;
+  }
+
+  /**
+   * Create signing key of the HashiCorp Vault transit secrets engine key, `ecdsa-*` and `rsa-*` key types are supported.
+   * The latest key version is pinned on creation, the AppRole login is renewed when the lease expires.
+   * Verification uses the public key locally, without calling Vault.
+   * The public key is fetched on creation, create the keys in the init context.
+   *
+   * ```js
+   * const key = kms.vault("signing", { address: "https://vault.example.com:8200", roleId: __ENV.ROLE_ID, secretId: __ENV.SECRET_ID });
+   * ```
+   *
+   * @param name The name of the transit key
+   * @param options The address, credentials and algorithm
+   * @returns The signing key
+   */
+  function vault(name: string, options?: VaultOptions): jwk.Key;
+
+  /**
+   * Options for PKCS#11 keys.
+   */
+  interface PKCS11Options {
+    /**
+     * The path of the PKCS#11 module shared library (default: `PKCS11_MODULE` environment variable)
+     */
+    module?: string;
+
+    /**
+     * The slot ID of the token (default: slot of the token with `tokenLabel`, or the first slot with token)
+     */
+    slot?: number;
+
+    /**
+     * The label of the token, used when no slot is given
+     */
+    tokenLabel?: string;
+
+    /**
+     * The user PIN (default: `PKCS11_PIN` environment variable)
+     */
+    pin?: string;
+
+    /**
+     * The JWS algorithm (default: ES* by curve for EC keys, RS256 for RSA keys)
+     */
+    algorithm?: string;
+
+    /**
+     * The `kid` of the key (default: JWK thumbprint of the public key)
+     */
+    kid?: string;
+  }
+
+  /**
+   * Create signing key of the private key on PKCS#11 token, like a network HSM or SoftHSM.
+   * The public key object must have the same label as the private key.
+   * The module is loaded once and shared by the VUs, signing operations of a key are serialized.
+   * Requires k6 built with cgo (`CGO_ENABLED=1`), create the keys in the init context.
+   *
+   * ```js
+   * const key = kms.pkcs11("signing", { module: "/usr/lib/softhsm/libsofthsm2.so", tokenLabel: "perf", pin: __ENV.PIN });
+   * ```
+   *
+   * @param label The label of the private and public key objects
+   * @param options The module, token and PIN
+   * @returns The signing key
+   */
+  function pkcs11(label: string, options?: PKCS11Options): jwk.Key;
+
+  /**
+   * Options for external signers, either `sign` or `url` is required.
+   */
+  interface ExternalOptions {
+    /**
+     * The public key of the signer, private parts are ignored
+     */
+    public: jwk.Key;
+
+    /**
+     * The JWS algorithm (default: `alg` of the public key, ES* by curve for EC keys, RS256 for RSA keys, EdDSA for Ed25519 keys)
+     */
+    algorithm?: string;
+
+    /**
+     * The callback returning the signature of the signing input, as ArrayBuffer, Uint8Array or base64url string.
+     * ECDSA signatures may be DER encoded, they are converted to JWS format.
+     * The callback must return synchronously, k6 HTTP requests can be used from VU code.
+     * The keys with callback can't be used by the async variants of the signing functions, they are rejected with `ERR_JOSE_INVALID_ARGUMENT`.
+     */
+    sign?: (input: ArrayBuffer, alg: string) => jwk.ByteArrayLike | string;
+
+    /**
+     * The signing endpoint, it gets POST of `{"alg", "kid", "input"}` and returns `{"signature"}` JSON, binary values are base64url encoded
+     */
+    url?: string;
+
+    /**
+     * Additional HTTP headers of the endpoint requests, like `Authorization`
+     */
+    headers?: Record<string, string>;
+
+    /**
+     * The `kid` of the key (default: `kid` of the public key or its JWK thumbprint)
+     */
+    kid?: string;
+  }
+
+  /**
+   * Create signing key of an external signer, the extension assembles the JWS from the signature of the signing input.
+   * Proprietary signing services can be used with a JS callback or an HTTP endpoint, without new Go backends.
+   * The callback belongs to the VU, create the keys in the init context.
+   *
+   * ```js
+   * const key = kms.external({
+   *   public: jwk.parsePEM(open("./signer.pub.pem")),
+   *   sign: (input, alg) => http.post("https://signer.example.com/sign", input).json("signature"),
+   * });
+   * ```
+   *
+   * @param options The public key and the callback or endpoint
+   * @returns The signing key
+   */
+  function external(options: ExternalOptions): jwk.Key;
+}
+
+/**
+ * Module oauth aims to provide helpers for OAuth 2.0 load tests.
+ */
+export namespace oauth {
+  /**
+   * Options for client assertion creation.
+   */
+  interface ClientAssertionOptions {
+    /**
+     * Lifetime of the assertion in seconds (default: 60)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields
+     */
+    header?: object;
+  }
+
+  /**
+   * Client assertion with the token request parameters.
+   */
+  interface ClientAssertion {
+    /**
+     * The signed assertion JWT
+     */
+    assertion: string;
+
+    /**
+     * Token request form parameters: `client_id`, `client_assertion_type` and `client_assertion`
+     */
+    params: object;
+  }
+
+  /**
+   * Create `private_key_jwt` client authentication assertion (RFC 7523).
+   * The `iss` and `sub` claims are the client ID, `aud` is the token endpoint, `jti` is random.
+   *
+   * @param key The client's signing key
+   * @param clientId The client ID
+   * @param tokenEndpoint The token endpoint URL
+   * @param options The assertion options
+   * @returns The assertion and the form parameters, ready to POST
+   */
+  function clientAssertion(
+    key: jwk.Key,
+    clientId: string,
+    tokenEndpoint: string,
+    options?: ClientAssertionOptions
+  ): ClientAssertion;
+
+  /**
+   * Options for client attestation creation.
+   */
+  interface ClientAttestationOptions {
+    /**
+     * Lifetime of the attestation in seconds (default: 3600)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims (e.g. `wallet_name`), applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields (default `typ` is `oauth-client-attestation+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Create client attestation JWT (OAuth 2.0 Attestation-Based Client Authentication), issued by the client attester
+   * (e.g. the wallet backend) for a client instance. The `sub` claim is the client ID, `cnf.jwk` is the public key of
+   * the client instance.
+   *
+   * @param key The client attester's signing key
+   * @param issuer The client attester's identifier
+   * @param clientId The client ID
+   * @param instanceKey The key of the client instance, only its public part is included
+   * @param options The attestation options
+   * @returns The client attestation JWT
+   */
+  function clientAttestation(
+    key: jwk.Key,
+    issuer: string,
+    clientId: string,
+    instanceKey: jwk.Key,
+    options?: ClientAttestationOptions
+  ): string;
+
+  /**
+   * Options for client attestation PoP creation.
+   */
+  interface ClientAttestationPoPOptions {
+    /**
+     * The challenge of the authorization server (from the `OAuth-Client-Attestation-Challenge` header)
+     */
+    challenge?: string;
+
+    /**
+     * Lifetime of the PoP in seconds (default: 60)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields (default `typ` is `oauth-client-attestation-pop+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Client attestation with its PoP and the request headers.
+   */
+  interface ClientAttestationPoP {
+    /**
+     * The client attestation JWT
+     */
+    attestation: string;
+
+    /**
+     * The client attestation PoP JWT
+     */
+    pop: string;
+
+    /**
+     * Request headers: `OAuth-Client-Attestation` and `OAuth-Client-Attestation-PoP`
+     */
+    headers: Record<string, string>;
+  }
+
+  /**
+   * Create client attestation PoP JWT, signed by the client instance.
+   * The `iss` claim is the client ID (the `sub` of the attestation), `aud` is the authorization server, `jti` is random.
+   * The key must be the one bound by the `cnf.jwk` claim of the attestation, otherwise `JWTClaimValidationError` is thrown.
+   *
+   * ```js
+   * const attestation = oauth.clientAttestation(attester, "https://wallet.example.com", "wallet-app", instance);
+   *
+   * export default function () {
+   *   const { headers } = oauth.clientAttestationPoP(instance, attestation, "https://as.example.com");
+   *   http.post("https://as.example.com/token", { grant_type: "authorization_code", code: code }, { headers: headers });
+   * }
+   * ```
+   *
+   * @param key The client instance's signing key
+   * @param attestation The client attestation JWT
+   * @param audience The authorization server's issuer identifier
+   * @param options The PoP options
+   * @returns The attestation, the PoP and the request headers
+   */
+  function clientAttestationPoP(
+    key: jwk.Key,
+    attestation: string,
+    audience: string,
+    options?: ClientAttestationPoPOptions
+  ): ClientAttestationPoP;
+
+  /**
+   * Options for request object creation.
+   */
+  interface RequestObjectOptions {
+    /**
+     * Lifetime of the request object in seconds (default: 300)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields (default `typ` is `oauth-authz-req+jwt`)
+     */
+    header?: object;
+
+    /**
+     * The authorization server's encryption key or key set (e.g. from its `jwks_uri`).
+     * If given, the signed request object is encrypted (nested JWT) to the key with `enc` use, or without use.
+     */
+    encryptionKey?: jwk.Key | jwk.Key[];
+
+    /**
+     * The encryption options, the `cty` header is `JWT`
+     */
+    encryption?: jwe.EncryptOptions;
+  }
+
+  /**
+   * Request object with the authorization request parameters.
+   */
+  interface RequestObject {
+    /**
+     * The signed (or nested encrypted) request object JWT
+     */
+    request: string;
+
+    /**
+     * Authorization request parameters: `client_id` and `request`
+     */
+    params: object;
+  }
+
+  /**
+   * Create signed authorization request object (JAR, RFC 9101).
+   * The `iss` and `client_id` claims are the client ID, `aud` is the authorization server's issuer,
+   * `iat`, `nbf`, `exp` and `jti` are set, `state` and `nonce` are random unless given in parameters.
+   * The `response_type` and `redirect_uri` parameters are required.
+   *
+   * @param key The client's signing key
+   * @param clientId The client ID
+   * @param issuer The authorization server's issuer identifier
+   * @param params The authorization request parameters
+   * @param options The request object options
+   * @returns The request object and the authorization request parameters
+   */
+  function requestObject(
+    key: jwk.Key,
+    clientId: string,
+    issuer: string,
+    params: object,
+    options?: RequestObjectOptions
+  ): RequestObject;
+
+  /**
+   * Options for CIBA authentication request creation.
+   */
+  interface CIBARequestOptions {
+    /**
+     * Lifetime of the request in seconds (default: 300)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied last as in [jwt.merge](../modules/jwt.md#merge), so they can make the request invalid
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields
+     */
+    header?: object;
+  }
+
+  /**
+   * Create signed authentication request of Client-Initiated Backchannel Authentication (CIBA Core 1.0, section 7.1.1)
+   * for the backchannel authentication endpoint.
+   * The `iss` claim is the client ID, `aud` is the OpenID provider's issuer, `iat`, `nbf`, `exp` and `jti` are set.
+   * The `scope` parameter must contain `openid` and exactly one of the `login_hint_token`, `id_token_hint` and
+   * `login_hint` parameters is required. The `requested_expiry` parameter (number or numeric string) is a JSON number
+   * claim, `binding_message`, `user_code`, `acr_values` and `client_notification_token` are passed as given.
+   *
+   * ```js
+   * const { params } = oauth.cibaRequest(key, "client-1", "https://op.example.com", {
+   *   scope: "openid payments",
+   *   login_hint: "alice@example.com",
+   *   binding_message: "W4SCT",
+   *   requested_expiry: 120,
+   * });
+   *
+   * http.post("https://op.example.com/bc-authorize", Object.assign(params, assertion.params));
+   * ```
+   *
+   * @param key The client's signing key
+   * @param clientId The client ID
+   * @param issuer The OpenID provider's issuer identifier
+   * @param params The authentication request parameters
+   * @param options The request options
+   * @returns The signed request and the authentication request parameters: `client_id` and `request`
+   */
+  function cibaRequest(
+    key: jwk.Key,
+    clientId: string,
+    issuer: string,
+    params: object,
+    options?: CIBARequestOptions
+  ): RequestObject;
+
+  /**
+   * Options for authorization response validation.
+   */
+  interface AuthorizationResponseOptions {
+    /**
+     * The expected `state` value
+     */
+    state?: string;
+
+    /**
+     * Allowed clock skew in seconds for the `exp` check (default: 0)
+     */
+    leeway?: number;
+  }
+
+  /**
+   * Validated authorization response.
+   */
+  interface AuthorizationResponse {
+    /**
+     * The authorization code
+     */
+    code: string;
+
+    /**
+     * The state value
+     */
+    state: string;
+
+    /**
+     * Every claim of the response JWT
+     */
+    claims: object;
+  }
+
+  /**
+   * Validate JWT Secured Authorization Response Mode (JARM) response.
+   * The signature is verified, `iss`, `aud` and `exp` are checked. Error responses (with `error` claim) are thrown.
+   *
+   * @param response The `response` parameter of the authorization response
+   * @param keys The authorization server's key or array of keys (e.g. from its `jwks_uri`)
+   * @param issuer The authorization server's issuer identifier
+   * @param clientId The client ID
+   * @param options The validation options
+   * @returns The authorization code and state
+   */
+  function authorizationResponse(
+    response: string,
+    keys: jwk.Key | jwk.Key[],
+    issuer: string,
+    clientId: string,
+    options?: AuthorizationResponseOptions
+  ): AuthorizationResponse;
+
+  /**
+   * Options for Sign in with Apple client secret creation.
+   */
+  interface AppleClientSecretOptions {
+    /**
+     * Lifetime of the client secret in seconds (default: 3600, maximum: 15777000)
+     */
+    lifetime?: number;
+  }
+
+  /**
+   * Create ES256 client secret JWT for Sign in with Apple.
+   * The `iss` claim is the team ID, `sub` is the client ID, `aud` is `https://appleid.apple.com`.
+   *
+   * @param key The private key downloaded from Apple (PEM encoded `.p8` content), or its JWK representation
+   * @param teamId The Apple developer team ID
+   * @param keyId The key ID, used as `kid` header
+   * @param clientId The client ID (services ID or bundle ID)
+   * @param options The client secret options
+   * @returns The client secret JWT
+   */
+  function appleClientSecret(
+    key: string | jwk.Key,
+    teamId: string,
+    keyId: string,
+    clientId: string,
+    options?: AppleClientSecretOptions
+  ): string;
+
+  /**
+   * Options for service account assertion creation.
+   */
+  interface ServiceAccountOptions {
+    /**
+     * The user to impersonate with domain-wide delegation, used as `sub` claim
+     */
+    subject?: string;
+
+    /**
+     * The `aud` claim (default: the `token_uri` of the credentials)
+     */
+    audience?: string;
+
+    /**
+     * Lifetime of the assertion in seconds (default and maximum: 3600)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+  }
+
+  /**
+   * Service account assertion with the token request parameters.
+   */
+  interface ServiceAccountAssertion {
+    /**
+     * The signed assertion JWT
+     */
+    assertion: string;
+
+    /**
+     * The token endpoint URL from the credentials
+     */
+    tokenUri: string;
+
+    /**
+     * Token request form parameters: `grant_type` and `assertion`
+     */
+    params: object;
+  }
+
+  /**
+   * Create RS256 JWT bearer assertion (RFC 7523) from Google service account key.
+   * The `iss` claim is the `client_email`, `kid` header is the `private_key_id` of the credentials.
+   *
+   * @param credentials The service account JSON key, as string or parsed object
+   * @param scopes The requested scopes, space separated in `scope` claim
+   * @param options The assertion options
+   * @returns The assertion and the form parameters, ready to POST to `tokenUri`
+   */
+  function serviceAccountAssertion(
+    credentials: string | object,
+    scopes: string[],
+    options?: ServiceAccountOptions
+  ): ServiceAccountAssertion;
+
+  /**
+   * Options for Firebase custom token creation.
+   */
+  interface FirebaseTokenOptions {
+    /**
+     * Developer claims (`claims` claim), reserved ID token claim names are not allowed
+     */
+    claims?: object;
+
+    /**
+     * The tenant of the user (`tenant_id` claim)
+     */
+    tenantId?: string;
+
+    /**
+     * The lifetime of the token in seconds (default and maximum: 3600)
+     */
+    lifetime?: number;
+  }
+
+  /**
+   * Create RS256 Firebase Authentication custom token for `signInWithCustomToken`,
+   * issued by the service account to the Identity Toolkit audience.
+   *
+   * @param credentials The service account key file content (JSON string or object)
+   * @param uid The user identifier, 1-128 characters
+   * @param options The token options
+   * @returns The custom token
+   */
+  function firebaseCustomToken(credentials: string | object, uid: string, options?: FirebaseTokenOptions): string;
+
+  /**
+   * Confirmation (`cnf` claim) binding.
+   */
+  interface ConfirmationOptions {
+    /**
+     * The bound key, its JWK SHA-256 thumbprint is the `jkt` member (e.g. DPoP key)
+     */
+    jwk?: jwk.Key;
+
+    /**
+     * The bound client certificate (PEM or DER), its SHA-256 hash is the `x5t#S256` member (mTLS)
+     */
+    cert?: string | ArrayBuffer | Uint8Array;
+  }
+
+  /**
+   * Compute `cnf` claim value with `jkt` (RFC 9449) and/or `x5t#S256` (RFC 8705) members.
+   *
+   * @param options The bound key and/or certificate
+   * @returns The `cnf` claim value
+   */
+  function confirmation(options: ConfirmationOptions): object;
+
+  /**
+   * Add `cnf` claim to the claims, see [confirmation](#confirmation). Existing `cnf` members are kept.
+   *
+   * @param claims The claim set
+   * @param options The bound key and/or certificate
+   * @returns The new claim set
+   */
+  function bind(claims: object, options: ConfirmationOptions): object;
+
+  /**
+   * Verify that `cnf` claim matches every given key and certificate, throws error if not.
+   *
+   * @param claims The claim set (e.g. verified access token payload)
+   * @param options The presented key and/or certificate
+   */
+  function verifyConfirmation(claims: object, options: ConfirmationOptions): void;
+
+  /**
+   * Options for JWT access token creation.
+   */
+  interface AccessTokenOptions {
+    /**
+     * Lifetime of the token in seconds (default: 3600)
+     */
+    lifetime?: number;
+
+    /**
+     * The scopes, space separated in `scope` claim
+     */
+    scope?: string[];
+
+    /**
+     * The `groups` claim
+     */
+    groups?: string[];
+
+    /**
+     * The `roles` claim
+     */
+    roles?: string[];
+
+    /**
+     * The `entitlements` claim
+     */
+    entitlements?: string[];
+
+    /**
+     * Additional header fields (default `typ` is `at+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Create JWT access token (RFC 9068). The `iat`, `exp` and `jti` claims are set unless given.
+   * The `iss`, `exp`, `aud`, `sub`, `client_id`, `iat` and `jti` claims are required, `scope` must be string.
+   *
+   * @param key The authorization server's signing key
+   * @param claims The claim set, applied over the defaults as in [jwt.merge](../modules/jwt.md#merge)
+   * @param options The access token options
+   * @returns The access token
+   */
+  function accessToken(key: jwk.Key, claims: object, options?: AccessTokenOptions): string;
+
+  /**
+   * Build nested `act` claim value for token exchange (RFC 8693).
+   * Actors are given by subject (string) or by claims (object), the first is the current actor,
+   * followed by the prior actors.
+   *
+   * @param actors The actors of the delegation chain
+   * @returns The `act` claim value
+   */
+  function act(actors: Array<string | object>): object;
+
+  /**
+   * Add new current actor to the claims, the previous `act` claim is nested into the new one.
+   *
+   * @param claims The claim set
+   * @param actor The new actor's subject or claims
+   * @returns The new claim set
+   */
+  function delegate(claims: object, actor: string | object): object;
+
+  /**
+   * Walk the `act` chain of the claims.
+   *
+   * @param claims The claim set
+   * @returns The actors (without nested `act`), starting with the current actor
+   */
+  function actors(claims: object): object[];
+
+  /**
+   * Options for delegation verification.
+   */
+  interface DelegationOptions {
+    /**
+     * The expected actors (subjects or claims), starting with the current actor.
+     * Object actors match if every given member is equal.
+     */
+    actors?: Array<string | object>;
+
+    /**
+     * Maximum length of the delegation chain
+     */
+    maxDepth?: number;
+
+    /**
+     * The `may_act` claim of the subject token, it must match the current actor
+     */
+    mayAct?: object;
+  }
+
+  /**
+   * Verify the `act` chain of the claims, throws error on mismatch.
+   *
+   * @param claims The claim set (e.g. verified token payload)
+   * @param options The expectations
+   */
+  function verifyDelegation(claims: object, options: DelegationOptions): void;
+
+  /**
+   * Options for introspection response creation.
+   */
+  interface IntrospectionOptions {
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields (default `typ` is `token-introspection+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Create JWT response for token introspection (RFC 9701).
+   * The introspection result is the `token_introspection` claim, `iss`, `aud` and `iat` are set.
+   *
+   * @param key The authorization server's signing key
+   * @param issuer The authorization server's issuer identifier
+   * @param audience The resource server's identifier
+   * @param introspection The introspection result, the `active` member is required
+   * @param options The response options
+   * @returns The introspection response JWT
+   */
+  function introspectionResponse(
+    key: jwk.Key,
+    issuer: string,
+    audience: string,
+    introspection: object,
+    options?: IntrospectionOptions
+  ): string;
+
+  /**
+   * Verify JWT introspection response. The `typ` header, `iss`, `aud` and `iat` claims are checked.
+   *
+   * @param response The introspection response JWT
+   * @param keys The authorization server's key or array of keys
+   * @param issuer The authorization server's issuer identifier
+   * @param audience The resource server's identifier
+   * @returns The introspection result (`token_introspection` claim)
+   */
+  function verifyIntrospectionResponse(
+    response: string,
+    keys: jwk.Key | jwk.Key[],
+    issuer: string,
+    audience: string
+  ): object;
+}
+
+/**
+ * Module dpop aims to provide an implementation of the OAuth 2.0 Demonstrating Proof of Possession (RFC 9449).
+ */
+export namespace dpop {
+  /**
+   * Options for DPoP proof creation.
+   */
+  interface ProofOptions {
+    /**
+     * The HTTP method of the request (default: `GET`)
+     */
+    method?: string;
+
+    /**
+     * The HTTP target URI of the request, query and fragment are omitted from the `htu` claim
+     */
+    url: string;
+
+    /**
+     * The access token, its hash is added as `ath` claim
+     */
+    accessToken?: string;
+
+    /**
+     * The server provided nonce (from the `DPoP-Nonce` response header)
+     */
+    nonce?: string;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+  }
+
+  /**
+   * Create DPoP proof JWT with `typ: dpop+jwt` and the public key in the `jwk` header.
+   * The `jti` is random and `iat` is the current time.
+   *
+   * @param key The private key of the client
+   * @param options The proof options
+   * @returns The DPoP proof JWT, the value of the `DPoP` request header
+   */
+  function proof(key: jwk.Key, options: ProofOptions): string;
+
+  /**
+   * DPoP session of a VU, creating proofs with the latest server provided nonce.
+   */
+  interface Session {
+    /**
+     * Create DPoP proof, with the stored nonce unless `nonce` option is given.
+     *
+     * @param options The proof options
+     * @returns The DPoP proof JWT
+     */
+    proof(options: ProofOptions): string;
+
+    /**
+     * Store the `DPoP-Nonce` header of a response (if any).
+     * The request needs to be retried with a new proof if the response is a `use_dpop_nonce` error
+     * (`400` error response of authorization server or `401` challenge of resource server) with new nonce.
+     * Header names are case-insensitive, the nonce is compared exactly (nonces differing in case are different).
+     *
+     * @param response The HTTP response (or an object with `status`, `headers` and `body` properties)
+     * @returns true if the request has to be retried
+     */
+    update(response: object): boolean;
+
+    /**
+     * The latest server provided nonce.
+     *
+     * @returns The nonce, or empty string
+     */
+    nonce(): string;
+
+    /**
+     * Set the nonce used for proofs.
+     *
+     * @param nonce The nonce value
+     */
+    setNonce(nonce: string): void;
+  }
+
+  /**
+   * Create DPoP session for a key, to handle server provided nonces.
+   *
+   * @param key The private key of the client
+   * @returns The DPoP session
+   */
+  function createSession(key: jwk.Key): Session;
+}
+
+/**
+ * Module httpsig aims to provide an implementation of the HTTP Message Signatures (RFC 9421).
+ *
+ * Supported algorithms are `rsa-pss-sha512`, `rsa-v1_5-sha256`, `hmac-sha256`, `ecdsa-p256-sha256`,
+ * `ecdsa-p384-sha384` and `ed25519`.
+ */
+export namespace httpsig {
+  /**
+   * The HTTP request or response to sign or verify.
+   */
+  interface Message {
+    /**
+     * The HTTP method of the request
+     */
+    method?: string;
+
+    /**
+     * The target URI of the request
+     */
+    url?: string;
+
+    /**
+     * The status code of the response
+     */
+    status?: number;
+
+    /**
+     * The header fields, names are case insensitive, values of repeated fields can be given as array
+     */
+    headers?: Record<string, string | string[]>;
+
+    /**
+     * The content, for the `Content-Digest` header field (RFC 9530)
+     */
+    body?: string | ArrayBuffer;
+  }
+
+  /**
+   * Options for message signing.
+   */
+  interface SignOptions {
+    /**
+     * The label of the signature (default: `sig1`)
+     */
+    label?: string;
+
+    /**
+     * The covered components (default: `@method`, `@authority` and `@path`).
+     * Header field names (lowercase) and derived components (`@method`, `@target-uri`, `@authority`, `@scheme`,
+     * `@request-target`, `@path`, `@query`, `@query-param` and `@status`) with optional parameters,
+     * like `@query-param;name=id`.
+     */
+    components?: string[];
+
+    /**
+     * The `alg` signature parameter, the key determines the algorithm without the parameter
+     */
+    alg?: string;
+
+    /**
+     * The `keyid` signature parameter (default: the `kid` of the key), empty string omits it
+     */
+    keyid?: string;
+
+    /**
+     * The `created` signature parameter in seconds since epoch (default: current time)
+     */
+    created?: number;
+
+    /**
+     * The lifetime of the signature for the `expires` signature parameter, as seconds or duration string
+     */
+    lifetime?: number | string;
+
+    /**
+     * The `nonce` signature parameter
+     */
+    nonce?: string;
+
+    /**
+     * The `tag` signature parameter
+     */
+    tag?: string;
+
+    /**
+     * Compute the `Content-Digest` header field of the body with the algorithm (`sha-256` or `sha-512`),
+     * the field is covered by the signature
+     */
+    digest?: string;
+  }
+
+  /**
+   * Sign HTTP message.
+   *
+   * @param key The private (or oct) key
+   * @param message The message to sign
+   * @param options The sign options
+   * @returns The `Signature-Input` and `Signature` (and `Content-Digest`) header fields to add to the message
+   */
+  function sign(key: jwk.Key, message: Message, options?: SignOptions): Record<string, string>;
+
+  /**
+   * Options for message signature verification.
+   */
+  interface VerifyOptions {
+    /**
+     * The label of the signature to verify (default: the first signature)
+     */
+    label?: string;
+
+    /**
+     * The components which must be covered by the signature
+     */
+    components?: string[];
+
+    /**
+     * The accepted `alg` signature parameter values
+     */
+    algorithms?: string[];
+
+    /**
+     * The maximal age of the signature by its `created` parameter, as seconds or duration string
+     */
+    maxAge?: number | string;
+
+    /**
+     * The expected `tag` signature parameter
+     */
+    tag?: string;
+  }
+
+  /**
+   * The verified signature.
+   */
+  interface Verified {
+    /**
+     * The label of the signature
+     */
+    label: string;
+
+    /**
+     * The covered components, in serialized form
+     */
+    components: string[];
+
+    /**
+     * The `created` signature parameter, or null
+     */
+    created: number | null;
+
+    /**
+     * The `expires` signature parameter, or null
+     */
+    expires: number | null;
+
+    /**
+     * The `keyid` signature parameter
+     */
+    keyid: string;
+
+    /**
+     * The `alg` signature parameter
+     */
+    alg: string;
+
+    /**
+     * The `nonce` signature parameter
+     */
+    nonce: string;
+
+    /**
+     * The `tag` signature parameter
+     */
+    tag: string;
+  }
+
+  /**
+   * Verify HTTP message signature, and the `Content-Digest` header field if the message has body.
+   * Keys with `kid` different from the `keyid` signature parameter are skipped.
+   * Expired signatures throw `JWTExpiredError`, modified messages or uncovered components `SignatureVerificationError`.
+   *
+   * @param key The public (or oct) key, or the array of keys
+   * @param message The signed message
+   * @param options The verify options
+   * @returns The verified signature
+   */
+  function verify(key: jwk.Key | jwk.Key[], message: Message, options?: VerifyOptions): Verified;
+}
+
+/**
+ * Module oidc aims to provide helpers for OpenID Connect load tests.
+ */
+export namespace oidc {
+  /**
+   * Compute `at_hash` ID token claim: the base64url encoded left-most half of the hash of the access token.
+   * The hash function is selected by the `alg` of the ID token (SHA-512 for `EdDSA`, SHA-256 for `ES256K`),
+   * unknown algorithms are rejected.
+   *
+   * @param accessToken The access token
+   * @param alg The signature algorithm of the ID token
+   * @returns The claim value
+   */
+  function atHash(accessToken: string, alg: string): string;
+
+  /**
+   * Compute `c_hash` ID token claim for the authorization code, see [atHash](#athash).
+   *
+   * @param code The authorization code
+   * @param alg The signature algorithm of the ID token
+   * @returns The claim value
+   */
+  function cHash(code: string, alg: string): string;
+
+  /**
+   * Compute `s_hash` ID token claim for the state, see [atHash](#athash).
+   *
+   * @param state The state
+   * @param alg The signature algorithm of the ID token
+   * @returns The claim value
+   */
+  function sHash(state: string, alg: string): string;
+
+  /**
+   * Options for logout token creation.
+   */
+  interface LogoutTokenOptions {
+    /**
+     * The `sub` claim
+     */
+    sub?: string;
+
+    /**
+     * The `sid` claim
+     */
+    sid?: string;
+
+    /**
+     * Lifetime of the token in seconds (default: 120)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields (default `typ` is `logout+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Create back-channel logout token. The `events` claim contains the back-channel logout event,
+   * `iat`, `exp` and `jti` are set, `nonce` is never set. At least one of `sub` and `sid` is required.
+   * Claims and header options are applied last, so deliberately invalid tokens can be created too.
+   *
+   * @param key The OP's signing key
+   * @param issuer The OP's issuer identifier
+   * @param audience The client ID of the RP
+   * @param options The logout token options
+   * @returns The logout token
+   */
+  function logoutToken(key: jwk.Key, issuer: string, audience: string, options?: LogoutTokenOptions): string;
+
+  /**
+   * Options for OpenID Federation entity statement creation.
+   */
+  interface EntityStatementOptions {
+    /**
+     * The subject's key or array of keys, their public part is the `jwks` claim
+     * (default for entity configuration: the signing key)
+     */
+    jwks?: jwk.Key | jwk.Key[];
+
+    /**
+     * The `metadata` claim
+     */
+    metadata?: object;
+
+    /**
+     * The `authority_hints` claim
+     */
+    authorityHints?: string[];
+
+    /**
+     * Lifetime of the statement in seconds (default: 86400)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields (default `typ` is `entity-statement+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Verified OpenID Federation entity statement.
+   */
+  interface EntityStatement {
+    /**
+     * The claims of the statement
+     */
+    payload: object;
+
+    /**
+     * The subject's keys from the `jwks` claim
+     */
+    keys: jwk.Key[];
+
+    /**
+     * True for entity configuration (`iss` and `sub` are the same)
+     */
+    configuration: boolean;
+  }
+
+  /**
+   * Create OpenID Federation entity statement, or entity configuration if the issuer and the subject are the same.
+   *
+   * @param key The issuer's signing key
+   * @param issuer The issuer's entity identifier
+   * @param subject The subject's entity identifier
+   * @param options The entity statement options
+   * @returns The entity statement JWT
+   */
+  function entityStatement(key: jwk.Key, issuer: string, subject: string, options?: EntityStatementOptions): string;
+
+  /**
+   * Verify OpenID Federation entity statement. The `typ` header and `exp` claim are checked.
+   * Entity configuration is verified with the keys from its own `jwks` claim if no keys given.
+   *
+   * @param token The entity statement JWT
+   * @param keys The issuer's keys (or key arrays), e.g. from the superior's entity configuration
+   * @returns The verified entity statement
+   */
+  function verifyEntityStatement(token: string, ...keys: Array<jwk.Key | jwk.Key[]>): EntityStatement;
+
+  /**
+   * Options for signed JWKS creation.
+   */
+  interface SignedJWKSOptions {
+    /**
+     * The `sub` claim (default: the issuer)
+     */
+    sub?: string;
+
+    /**
+     * Lifetime of the signed JWKS in seconds (default: 86400)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields (default `typ` is `jwk-set+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Create signed JWKS (the content of `signed_jwks_uri`): a JWT with the public part of the keys in `keys` claim.
+   *
+   * @param key The signing key (e.g. federation entity key)
+   * @param issuer The issuer's identifier
+   * @param keys The key or array of keys to publish
+   * @param options The signed JWKS options
+   * @returns The signed JWKS
+   */
+  function signedJWKS(key: jwk.Key, issuer: string, keys: jwk.Key | jwk.Key[], options?: SignedJWKSOptions): string;
+
+  /**
+   * Verify signed JWKS. The `typ` header and `exp` claim (if present) are checked.
+   *
+   * @param token The signed JWKS
+   * @param keys The signer's keys (or key arrays)
+   * @returns The array of keys from the signed JWKS
+   */
+  function verifySignedJWKS(token: string, ...keys: Array<jwk.Key | jwk.Key[]>): jwk.Key[];
+
+  /**
+   * Options for mock provider creation.
+   */
+  interface MockProviderOptions {
+    /**
+     * The issuer identifier (default: `https://idp.example.com`)
+     */
+    issuer?: string;
+
+    /**
+     * The `aud` claim of the tokens (default: `mock-client`)
+     */
+    audience?: string;
+
+    /**
+     * The `client_id` claim of access tokens (default: the audience)
+     */
+    clientId?: string;
+
+    /**
+     * The signature algorithm of the keys, like [jwk.generate](../modules/jwk.md#generate) (default: `RS256`)
+     */
+    algorithm?: string;
+
+    /**
+     * Number of signing keys (default: 2), negative numbers are rejected
+     */
+    keys?: number;
+
+    /**
+     * RSA modulus size of the keys in bits (default: 2048)
+     */
+    bits?: number;
+
+    /**
+     * Lifetime of the tokens in seconds (default: 3600)
+     */
+    lifetime?: number;
+  }
+
+  /**
+   * Fake OpenID provider for load tests of relying parties and resource servers.
+   * Providers can be passed as verification keys (their public keys are used).
+   */
+  interface MockProvider {
+    /**
+     * The issuer identifier
+     */
+    issuer: string;
+
+    /**
+     * The URL of the JWK set, as in the discovery document
+     */
+    jwksUri: string;
+
+    /**
+     * The JWKS document with the public keys, ready to serve from a stub `jwks_uri` endpoint
+     */
+    jwks: object;
+
+    /**
+     * The discovery document, ready to serve from a stub `/.well-known/openid-configuration` endpoint
+     */
+    discovery: object;
+
+    /**
+     * The signing keys
+     */
+    keys: jwk.Key[];
+
+    /**
+     * Sign the claims as is, with the next key: consecutive tokens are signed by the keys in turn (rotating `kid`).
+     *
+     * @param claims The claims
+     * @param header The header fields
+     * @returns The signed JWT
+     */
+    sign(claims: object, header?: object): string;
+
+    /**
+     * Issue ID token with `iss`, `sub` (`mock-user`), `aud`, `iat`, `exp`, `auth_time` and random `nonce`,
+     * the claims (e.g. `sub`) are applied last as in [jwt.merge](../modules/jwt.md#merge).
+     *
+     * @param claims The additional claims
+     * @returns The signed ID token
+     */
+    issueIDToken(claims?: object): string;
+
+    /**
+     * Issue JWT access token (RFC 9068, `typ` is `at+jwt`) with `iss`, `sub` (`mock-user`), `aud`, `client_id`,
+     * `iat`, `exp` and random `jti`, the claims (e.g. `sub`, `scope`) are applied last as in
+     * [jwt.merge](../modules/jwt.md#merge).
+     *
+     * @param claims The additional claims
+     * @returns The signed access token
+     */
+    issueAccessToken(claims?: object): string;
+  }
+
+  /**
+   * Create mock OpenID provider in one call: generate the signing keys (concurrently), their JWKS and discovery
+   * document, with token factories signing with rotating keys.
+   *
+   * ```js
+   * const idp = oidc.mockProvider({ issuer: "https://idp.test", audience: "orders-api" });
+   *
+   * export default function () {
+   *   const token = idp.issueAccessToken({ sub: "alice", scope: "orders:read" });
+   *   jwt.verifyClaims(token, idp, { issuer: "https://idp.test", audience: "orders-api" });
+   * }
+   * ```
+   *
+   * @param options The provider options
+   * @returns The mock provider
+   */
+  function mockProvider(options?: MockProviderOptions): MockProvider;
+
+  /**
+   * Options for service account token verification.
+   */
+  interface ServiceAccountTokenOptions {
+    /**
+     * The expected `iss` claim, the service account issuer of the cluster
+     * (default: the issuer identifier of the keys, if they are a discovered or mock issuer)
+     */
+    issuer?: string;
+
+    /**
+     * The expected audience, one of the `aud` claim values (e.g. the audience of the webhook authenticator)
+     */
+    audience?: string;
+
+    /**
+     * The expected namespace of the service account
+     */
+    namespace?: string;
+
+    /**
+     * The expected name of the service account
+     */
+    serviceAccount?: string;
+
+    /**
+     * The required bound object of the token: `pod`, `secret` or `node`
+     */
+    binding?: string;
+
+    /**
+     * The clock skew allowed for `exp`, `nbf` and `iat`, as seconds or duration string (default: `1m`)
+     */
+    leeway?: number | string;
+  }
+
+  /**
+   * Kubernetes object reference of the `kubernetes.io` claim.
+   */
+  interface ObjectRef {
+    /**
+     * The name of the object
+     */
+    name: string;
+
+    /**
+     * The UID of the object
+     */
+    uid: string;
+  }
+
+  /**
+   * The verified service account token.
+   */
+  interface ServiceAccountToken {
+    /**
+     * The user name of the service account (`system:serviceaccount:<namespace>:<name>`), as returned by TokenReview
+     */
+    username: string;
+
+    /**
+     * The namespace of the service account
+     */
+    namespace: string;
+
+    /**
+     * The service account
+     */
+    serviceAccount: ObjectRef;
+
+    /**
+     * The pod the token is bound to, or null
+     */
+    pod: ObjectRef | null;
+
+    /**
+     * The secret the token is bound to, or null
+     */
+    secret: ObjectRef | null;
+
+    /**
+     * The node the token is bound to, or null
+     */
+    node: ObjectRef | null;
+
+    /**
+     * The `warnafter` time of the token (seconds since epoch), or 0
+     */
+    warnAfter: number;
+
+    /**
+     * The claims of the token
+     */
+    claims: Record<string, any>;
+  }
+
+  /**
+   * Verify bound (projected) Kubernetes service account token, as the API server's authenticator does.
+   * The signature, `iss`, `aud`, `exp` (required), `nbf` and `iat` claims are checked, then the `kubernetes.io` claim:
+   * the namespace and service account (with UID), the `sub` claim matching them and the expected bound object.
+   *
+   * ```js
+   * const cluster = jose.discover("https://kubernetes.default.svc.cluster.local");
+   *
+   * export default function () {
+   *   const sa = oidc.verifyServiceAccountToken(token, cluster, { audience: "vault", binding: "pod" });
+   * }
+   * ```
+   *
+   * Invalid claims throw `JWTClaimValidationError`, expired tokens `JWTExpiredError`.
+   *
+   * @param token The service account token
+   * @param keys The cluster's discovered issuer, mock provider, key or array of keys
+   * @param options The verification options
+   * @returns The verified token
+   */
+  function verifyServiceAccountToken(
+    token: string,
+    keys: jose.Issuer | MockProvider | jwk.Key | jwk.Key[],
+    options?: ServiceAccountTokenOptions
+  ): ServiceAccountToken;
+}
+
+/**
+ * Module sdjwt aims to provide an implementation of the Selective Disclosure for JWTs (SD-JWT).
+ */
+export namespace sdjwt {
+  /**
+   * Options for SD-JWT issuance.
+   */
+  interface IssueOptions {
+    /**
+     * Paths of selectively disclosable claims. Nested claims are separated by dot (e.g. `address.street`),
+     * array elements are addressed by index (e.g. `nationalities.1`).
+     */
+    disclose?: string[];
+
+    /**
+     * Hash algorithm of the digests: `sha-256` (default), `sha-384` or `sha-512`
+     */
+    alg?: string;
+
+    /**
+     * Number of decoy digests added to every `_sd` array
+     */
+    decoys?: number;
+
+    /**
+     * Holder's key, its public part is added as `cnf` claim for key binding
+     */
+    holder?: jwk.Key;
+
+    /**
+     * Additional header fields (default `typ` is `sd+jwt`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Issued SD-JWT.
+   */
+  interface Issued {
+    /**
+     * The SD-JWT in combined format (`jwt~disclosure~...~`)
+     */
+    token: string;
+
+    /**
+     * The issuer-signed JWT
+     */
+    jwt: string;
+
+    /**
+     * The encoded disclosures
+     */
+    disclosures: string[];
+  }
+
+  /**
+   * Issue SD-JWT, the disclosable claims are replaced with the digests of their disclosures.
+   * Digests of `_sd` arrays are sorted, so their order doesn't reveal the original claim order.
+   *
+   * @param key The issuer's signing key
+   * @param claims The claim set
+   * @param options The issuance options
+   * @returns The issued SD-JWT
+   */
+  function issue(key: jwk.Key, claims: object, options?: IssueOptions): Issued;
+
+  /**
+   * Options for SD-JWT presentation.
+   */
+  interface PresentOptions {
+    /**
+     * Names of the claims to disclose (default: every disclosure). Array elements are selected by their values,
+     * string values as is, other values by their JSON text (e.g. `42` or `{"id":1}`).
+     */
+    disclose?: string[];
+
+    /**
+     * The `nonce` claim of the key binding JWT
+     */
+    nonce?: string;
+
+    /**
+     * The `aud` claim of the key binding JWT
+     */
+    aud?: string;
+
+    /**
+     * Additional claims of the key binding JWT
+     */
+    claims?: object;
+  }
+
+  /**
+   * Create SD-JWT presentation with the selected disclosures.
+   * If the holder's key is given, a key binding JWT (`typ` is `kb+jwt`) is appended with `iat`, `sd_hash`
+   * and the `nonce`/`aud` claims from options.
+   *
+   * @param token The SD-JWT in combined format
+   * @param holder The holder's signing key
+   * @param options The presentation options
+   * @returns The presentation in combined format
+   */
+  function present(token: string, holder?: jwk.Key, options?: PresentOptions): string;
+
+  /**
+   * Options for SD-JWT verification.
+   */
+  interface VerifyOptions {
+    /**
+     * The expected `nonce` claim of the key binding JWT
+     */
+    nonce?: string;
+
+    /**
+     * The expected `aud` claim of the key binding JWT
+     */
+    aud?: string;
+
+    /**
+     * Require key binding JWT
+     */
+    keyBinding?: boolean;
+
+    /**
+     * Maximum age of the key binding JWT's `iat` in seconds (default: 300)
+     */
+    maxAge?: number;
+  }
+
+  /**
+   * Verified SD-JWT presentation.
+   */
+  interface Verified {
+    /**
+     * The claim set with the disclosed claims, without `_sd` and `_sd_alg`
+     */
+    payload: object;
+
+    /**
+     * The payload of the key binding JWT (`null` if missing)
+     */
+    keyBinding: object;
+  }
+
+  /**
+   * Verify SD-JWT presentation. The issuer's signature, the disclosure digests and the key binding JWT
+   * (signature with the `cnf` key, `typ`, `sd_hash`, `nonce`, `aud` and the freshness of `iat`) are checked.
+   * Disclosures not referenced by any digest are rejected.
+   *
+   * @param token The presentation in combined format
+   * @param keys The issuer's key or array of keys
+   * @param options The verification options
+   * @returns The verified presentation
+   */
+  function verify(token: string, keys: jwk.Key | jwk.Key[], options?: VerifyOptions): Verified;
+}
+
+/**
+ * Module vc aims to provide an implementation of the W3C Verifiable Credentials JWT encoding.
+ */
+export namespace vc {
+  /**
+   * Status list entry of the credential.
+   */
+  interface StatusOptions {
+    /**
+     * The status list credential URL
+     */
+    url: string;
+
+    /**
+     * The index of the credential in the status list
+     */
+    index: number;
+
+    /**
+     * The status purpose (default: `revocation`)
+     */
+    purpose?: string;
+  }
+
+  /**
+   * Options for verifiable credential issuance.
+   */
+  interface IssueOptions {
+    /**
+     * The credential ID, used as `jti` claim (default: random `urn:uuid:`)
+     */
+    id?: string;
+
+    /**
+     * Additional credential types, after `VerifiableCredential`
+     */
+    types?: string[];
+
+    /**
+     * Additional JSON-LD contexts, after the credentials context
+     */
+    context?: string[];
+
+    /**
+     * Issuance date as seconds since epoch, used as `nbf` claim (default: now)
+     */
+    validFrom?: number;
+
+    /**
+     * Expiration date as seconds since epoch, used as `exp` claim
+     */
+    validUntil?: number;
+
+    /**
+     * `StatusList2021Entry` credential status
+     */
+    status?: StatusOptions;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields
+     */
+    header?: object;
+  }
+
+  /**
+   * Issue verifiable credential JWT. The credential is the `vc` claim, the `id` of the credential subject
+   * is moved to the `sub` claim.
+   *
+   * @param key The issuer's signing key (e.g. ES256 or EdDSA)
+   * @param issuer The issuer's identifier, used as `iss` claim
+   * @param credentialSubject The credential subject
+   * @param options The issuance options
+   * @returns The credential JWT
+   */
+  function issue(key: jwk.Key, issuer: string, credentialSubject: object, options?: IssueOptions): string;
+
+  /**
+   * Options for verifiable presentation creation.
+   */
+  interface PresentOptions {
+    /**
+     * The presentation ID, used as `jti` claim (default: random `urn:uuid:`)
+     */
+    id?: string;
+
+    /**
+     * The verifier provided nonce, used as `nonce` claim
+     */
+    nonce?: string;
+
+    /**
+     * The verifier, used as `aud` claim
+     */
+    aud?: string;
+
+    /**
+     * Lifetime of the presentation in seconds (default: 300)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional presentation types, after `VerifiablePresentation`
+     */
+    types?: string[];
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header fields
+     */
+    header?: object;
+  }
+
+  /**
+   * Create verifiable presentation JWT. The credentials are the `verifiableCredential` of the `vp` claim,
+   * the holder is the `iss` claim. Credentials with other subject (`sub` claim) than the holder are rejected.
+   *
+   * @param key The holder's signing key
+   * @param holder The holder's identifier
+   * @param credentials The credential JWTs
+   * @param options The presentation options
+   * @returns The presentation JWT
+   */
+  function present(key: jwk.Key, holder: string, credentials: string[], options?: PresentOptions): string;
+}
+
+/**
+ * Module cose aims to provide an implementation of the CBOR Object Signing (COSE_Sign1) and CBOR Web Token (CWT).
+ * JSON Web Keys are used as key material, the COSE algorithm is selected by the `alg` of the key.
+ */
+export namespace cose {
+  /**
+   * Options for COSE_Sign1 message creation.
+   */
+  interface Sign1Options {
+    /**
+     * Additional protected header parameters. Parameter names (`alg`, `crit`, `cty`, `kid`, `iv`, `partial_iv`)
+     * and numeric labels (as string keys) are converted to integer labels.
+     */
+    protected?: object;
+
+    /**
+     * Additional unprotected header parameters, see `protected`. The `kid` of the key is added unless given.
+     */
+    unprotected?: object;
+
+    /**
+     * External additional authenticated data
+     */
+    aad?: jwk.ByteArrayLike;
+
+    /**
+     * Create message with detached payload (`null` payload)
+     */
+    detached?: boolean;
+
+    /**
+     * Omit the COSE_Sign1 CBOR tag (18)
+     */
+    untagged?: boolean;
+  }
+
+  /**
+   * Options for COSE_Sign1 message verification.
+   */
+  interface VerifyOptions {
+    /**
+     * External additional authenticated data
+     */
+    aad?: jwk.ByteArrayLike;
+
+    /**
+     * The detached payload
+     */
+    payload?: jwk.ByteArrayLike;
+  }
+
+  /**
+   * Verified COSE_Sign1 message.
+   */
+  interface Verified {
+    /**
+     * The payload of the message
+     */
+    payload: ArrayBuffer;
+
+    /**
+     * The protected header, known labels are converted to parameter names
+     */
+    protected: object;
+
+    /**
+     * The unprotected header, known labels are converted to parameter names
+     */
+    unprotected: object;
+
+    /**
+     * The key ID from the header
+     */
+    kid: string;
+
+    /**
+     * The signature algorithm (JOSE name)
+     */
+    alg: string;
+
+    /**
+     * The key that validated the signature
+     */
+    key: jwk.Key;
+  }
+
+  /**
+   * Create COSE_Sign1 message (RFC 9052). Supported algorithms: `ES256`, `ES384`, `ES512`, `EdDSA`,
+   * `PS256`, `PS384`, `PS512`, `RS256`, `RS384` and `RS512`.
+   *
+   * @param key The signing key
+   * @param payload The payload
+   * @param options The signing options
+   * @returns The CBOR encoded message
+   */
+  function sign1(key: jwk.Key, payload: jwk.ByteArrayLike, options?: Sign1Options): ArrayBuffer;
+
+  /**
+   * Verify COSE_Sign1 message. Keys with matching `kid` are tried first, then every key.
+   *
+   * @param message The CBOR encoded message (tagged or untagged)
+   * @param keys The key or array of keys
+   * @param options The verification options
+   * @returns The verified message
+   */
+  function verify1(message: ArrayBuffer | Uint8Array, keys: jwk.Key | jwk.Key[], options?: VerifyOptions): Verified;
+
+  /**
+   * Options for CWT verification.
+   */
+  interface CWTOptions {
+    /**
+     * External additional authenticated data
+     */
+    aad?: jwk.ByteArrayLike;
+
+    /**
+     * Allowed clock skew in seconds for the `exp` and `nbf` checks (default: 0)
+     */
+    leeway?: number;
+  }
+
+  /**
+   * Create CBOR Web Token (RFC 8392) as COSE_Sign1 message.
+   * Claim names (`iss`, `sub`, `aud`, `exp`, `nbf`, `iat`, `cti`, `cnf`, `scope`) are converted to claim keys,
+   * numeric string keys to integers, `cti` string and ArrayBuffer values to byte strings.
+   *
+   * @param key The signing key
+   * @param claims The claim set
+   * @param options The signing options
+   * @returns The CBOR encoded token
+   */
+  function signCWT(key: jwk.Key, claims: object, options?: Sign1Options): ArrayBuffer;
+
+  /**
+   * Verify CBOR Web Token, `exp` and `nbf` claims are checked. Claim keys are converted to claim names,
+   * byte strings to ArrayBuffer.
+   *
+   * @param message The CBOR encoded token (optionally with CWT tag)
+   * @param keys The key or array of keys
+   * @param options The verification options
+   * @returns The claims
+   */
+  function verifyCWT(message: ArrayBuffer | Uint8Array, keys: jwk.Key | jwk.Key[], options?: CWTOptions): object;
+}
+
+/**
+ * Module acme aims to provide helpers for Automatic Certificate Management Environment (RFC 8555) load tests.
+ */
+export namespace acme {
+  /**
+   * Options for ACME request creation.
+   */
+  interface RequestOptions {
+    /**
+     * The account URL, the public key is embedded in the `jwk` header if missing (e.g. `newAccount` request)
+     */
+    kid?: string;
+  }
+
+  /**
+   * Create ACME request JWS in flattened JSON serialization with `nonce` and `url` protected header parameters.
+   * Objects are JSON encoded, missing payload is the empty payload of POST-as-GET requests.
+   *
+   * @param key The private key of the account
+   * @param url The request URL
+   * @param nonce The server provided nonce (from the `Replay-Nonce` response header)
+   * @param payload The request payload
+   * @param options The request options
+   * @returns The request body (`application/jose+json`)
+   */
+  function request(
+    key: jwk.Key,
+    url: string,
+    nonce: string,
+    payload?: object | string | ArrayBuffer | Uint8Array | null,
+    options?: RequestOptions
+  ): string;
+
+  /**
+   * ACME session of a VU, creating requests with the latest server provided nonce and the account URL.
+   */
+  interface Session {
+    /**
+     * Create ACME request JWS with the stored nonce, using `kid` header if the account URL is known.
+     *
+     * @param url The request URL
+     * @param payload The request payload, missing for POST-as-GET
+     * @returns The request body
+     */
+    request(url: string, payload?: object | string | ArrayBuffer | Uint8Array | null): string;
+
+    /**
+     * Store the `Replay-Nonce` header of a response (if any).
+     * The `Location` header of a successful response is stored as account URL if it's not known yet.
+     * The request needs to be retried if the response is a `badNonce` error with new nonce.
+     *
+     * @param response The HTTP response (or an object with `status`, `headers` and `body` properties)
+     * @returns true if the request has to be retried
+     */
+    update(response: object): boolean;
+
+    /**
+     * The latest server provided nonce.
+     *
+     * @returns The nonce, or empty string
+     */
+    nonce(): string;
+
+    /**
+     * Set the nonce used for requests (e.g. from `newNonce` response).
+     *
+     * @param nonce The nonce value
+     */
+    setNonce(nonce: string): void;
+
+    /**
+     * The account URL.
+     *
+     * @returns The account URL, or empty string
+     */
+    kid(): string;
+
+    /**
+     * Set the account URL used as `kid` header.
+     *
+     * @param kid The account URL
+     */
+    setKid(kid: string): void;
+  }
+
+  /**
+   * Create ACME session for an account key.
+   *
+   * @param key The private key of the account
+   * @param options The session options, `kid` for existing account
+   * @returns The ACME session
+   */
+  function createSession(key: jwk.Key, options?: RequestOptions): Session;
+}
+
+/**
+ * Module webpush aims to provide helpers for Web Push load tests.
+ */
+export namespace webpush {
+  /**
+   * Options for VAPID creation.
+   */
+  interface VAPIDOptions {
+    /**
+     * The contact of the application server (`mailto:` or `https:` URI)
+     */
+    sub?: string;
+
+    /**
+     * The lifetime of the token in seconds (default: 43200, maximum: 86400)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+  }
+
+  /**
+   * The result of VAPID creation.
+   */
+  interface VAPID {
+    /**
+     * The value of the `Authorization` request header (`vapid t=..., k=...`)
+     */
+    authorization: string;
+
+    /**
+     * The ES256 signed JWT
+     */
+    token: string;
+
+    /**
+     * The base64url encoded uncompressed public key of the application server
+     */
+    publicKey: string;
+  }
+
+  /**
+   * Create voluntary application server identification (RFC 8292) for a push message request.
+   * The `aud` claim is the origin of the push resource URL.
+   *
+   * @param key The P-256 private key of the application server
+   * @param endpoint The push resource URL of the subscription
+   * @param options The VAPID options
+   * @returns The authorization header, the token and the public key
+   */
+  function vapid(key: jwk.Key, endpoint: string, options?: VAPIDOptions): VAPID;
+}
+
+/**
+ * Module passport aims to provide an implementation of the Personal Assertion Token (RFC 8225)
+ * with SHAKEN extension (RFC 8588) for call authentication load tests.
+ */
+export namespace passport {
+  /**
+   * Options for PASSporT creation.
+   */
+  interface SignOptions {
+    /**
+     * The attestation level (`A`, `B` or `C`), required for SHAKEN
+     */
+    attest?: string;
+
+    /**
+     * The origination identifier (default: random UUID)
+     */
+    origid?: string;
+
+    /**
+     * The PASSporT extension (default: `shaken`)
+     */
+    ppt?: string;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header parameters
+     */
+    header?: object;
+  }
+
+  /**
+   * The result of PASSporT creation.
+   */
+  interface Signed {
+    /**
+     * The PASSporT
+     */
+    token: string;
+
+    /**
+     * The value of the SIP `Identity` header (RFC 8224)
+     */
+    identity: string;
+  }
+
+  /**
+   * Create PASSporT with `typ: passport` and `ppt`, `x5u` header parameters.
+   * Telephone numbers are converted to `tn` objects, `iat` is the current time.
+   *
+   * @param key The signing key of the service provider (ES256 for SHAKEN)
+   * @param x5u The URL of the signing certificate
+   * @param orig The originating telephone number, or `orig` claim object
+   * @param dest The destination telephone number(s), or `dest` claim object
+   * @param options The signing options
+   * @returns The PASSporT and the Identity header
+   */
+  function sign(
+    key: jwk.Key,
+    x5u: string,
+    orig: string | object,
+    dest: string | string[] | object,
+    options?: SignOptions
+  ): Signed;
+
+  /**
+   * Options for PASSporT verification.
+   */
+  interface VerifyOptions {
+    /**
+     * The expected PASSporT extension, the `ppt` header must match it if given (default: any or none)
+     */
+    ppt?: string;
+
+    /**
+     * The maximum allowed age of the `iat` claim in seconds (default: 60)
+     */
+    maxAge?: number;
+  }
+
+  /**
+   * The result of PASSporT verification.
+   */
+  interface Verified {
+    /**
+     * The header parameters (`alg`, `typ`, `x5u` and `ppt`, if the token has it)
+     */
+    header: object;
+
+    /**
+     * The claims
+     */
+    payload: object;
+  }
+
+  /**
+   * Verify PASSporT signature, header parameters, `iat` freshness and the `orig` and `dest` claims.
+   * SHAKEN PASSporT (`ppt` header is `shaken`) must be ES256 signed with valid `attest` and `origid` claims.
+   *
+   * @param token The PASSporT
+   * @param keys The public key of the certificate referenced by `x5u`, or array of keys
+   * @param options The verification options
+   * @returns The header and the claims
+   */
+  function verify(token: string, keys: jwk.Key | jwk.Key[], options?: VerifyOptions): Verified;
+}
+
+/**
+ * Module registry aims to provide helpers for Docker Registry v2 token authentication load tests.
+ */
+export namespace registry {
+  /**
+   * Options for registry token creation.
+   */
+  interface TokenOptions {
+    /**
+     * The signing certificate (PEM or DER), the `kid` is derived from its public key instead of the signing key
+     */
+    cert?: string | ArrayBuffer | Uint8Array;
+
+    /**
+     * The lifetime of the token in seconds (default: 300)
+     */
+    lifetime?: number;
+
+    /**
+     * Additional claims, applied as in [jwt.merge](../modules/jwt.md#merge)
+     */
+    claims?: object;
+
+    /**
+     * Additional header parameters (e.g. `x5c`)
+     */
+    header?: object;
+  }
+
+  /**
+   * Create registry bearer token with `access` claim. The `kid` header is the libtrust key ID,
+   * `iat`, `nbf` and `exp` are based on the current time, `jti` is random.
+   *
+   * @param key The signing key of the token server
+   * @param issuer The issuer (`iss` claim), as configured in the registry
+   * @param service The service name (`aud` claim)
+   * @param subject The user name (`sub` claim)
+   * @param access The scopes (e.g. `repository:samalba/my-app:pull,push`) or access entries
+   *               (objects with `type`, `name` and `actions` properties)
+   * @param options The token options
+   * @returns The bearer token
+   */
+  function token(
+    key: jwk.Key,
+    issuer: string,
+    service: string,
+    subject: string,
+    access: Array<string | object>,
+    options?: TokenOptions
+  ): string;
+
+  /**
+   * Compute libtrust key ID: the base32 encoded, truncated SHA-256 hash of the DER encoded public key,
+   * in groups of four characters separated by colons.
+   *
+   * @param key The key or the certificate (PEM or DER)
+   * @returns The key ID
+   */
+  function keyID(key: jwk.Key | string | ArrayBuffer | Uint8Array): string;
+
+  /**
+   * Parse the `scope` parameter of a token request to access entry.
+   *
+   * @param scope The scope (`type:name:actions`)
+   * @returns The access entry with `type`, `name` and `actions` properties
+   */
+  function scope(scope: string): object;
+}
+
+/**
+ * Module tamper aims to provide reproducible invalid tokens for negative tests.
+ * The functions work on compact serialization, the signature is the last segment (authentication tag of JWE).
+ */
+export namespace tamper {
+  /**
+   * Modify payload claims without re-signing, the signature is kept.
+   *
+   * @param token The compact JWS
+   * @param overrides The claims to change, applied as in [jwt.merge](../modules/jwt.md#merge)
+   * @returns The tampered token
+   */
+  function claims(token: string, overrides: object): string;
+
+  /**
+   * Modify header parameters without re-signing, the signature is kept.
+   *
+   * @param token The compact JWS or JWE
+   * @param overrides The header parameters to change, applied as in [jwt.merge](../modules/jwt.md#merge)
+   * @returns The tampered token
+   */
+  function header(token: string, overrides: object): string;
+
+  /**
+   * Swap the `alg` header parameter without re-signing.
+   *
+   * @param token The compact JWS or JWE
+   * @param alg The new algorithm (e.g. `none` or `HS256`)
+   * @returns The tampered token
+   */
+  function alg(token: string, alg: string): string;
+
+  /**
+   * Remove bytes from the end of the signature.
+   *
+   * @param token The compact JWS or JWE
+   * @param count The number of bytes to remove (default: 1)
+   * @returns The tampered token
+   */
+  function truncate(token: string, count?: number): string;
+
+  /**
+   * Invert a bit of the signature, the index wraps around (negative index counts from the end).
+   *
+   * @param token The compact JWS or JWE
+   * @param bit The index of the bit, 0 is the most significant bit of the first byte
+   * @returns The tampered token
+   */
+  function flipBit(token: string, bit: number): string;
+
+  /**
+   * Reorder the segments of the token, indexes may be repeated or omitted.
+   *
+   * @param token The compact JWS or JWE
+   * @param order The indexes of the segments (e.g. `[1, 0, 2]` swaps header and payload)
+   * @returns The tampered token
+   */
+  function reorder(token: string, order: number[]): string;
+
+  /**
+   * Options for algorithm confusion.
+   */
+  interface ConfusionOptions {
+    /**
+     * The HMAC algorithm (default: `HS256`, `HS384` or `HS512` by the hash size of the original `alg`)
+     */
+    alg?: string;
+
+    /**
+     * The encoding of the public key used as HMAC secret: `pem` (default, SPKI with trailing newline),
+     * `pkcs1` (RSA PUBLIC KEY PEM), `der`, `raw` (EC point or Ed25519 key) or `jwk` (JSON)
+     */
+    encoding?: string;
+  }
+
+  /**
+   * Re-sign the token with HMAC using the public key of the original signer as secret (key confusion attack).
+   * The header (except `alg`) and the payload are kept.
+   *
+   * @param token The compact JWS (e.g. RS256 or ES256 signed)
+   * @param key The public key of the original signer
+   * @param options The confusion options
+   * @returns The HMAC signed token
+   */
+  function confuse(token: string, key: jwk.Key, options?: ConfusionOptions): string;
+
+  /**
+   * Options for malformed token generation.
+   */
+  interface FuzzOptions {
+    /**
+     * The seed of the pseudo-random generator, the same seed produces the same tokens (default: 0)
+     */
+    seed?: number;
+
+    /**
+     * The number of tokens to generate (default: 16)
+     */
+    count?: number;
+
+    /**
+     * The mutation kinds to use (default: all, see [fuzzKinds](../modules/tamper.md#fuzzkinds))
+     */
+    kinds?: string[];
+  }
+
+  /**
+   * The malformed token.
+   */
+  interface Fuzzed {
+    /**
+     * The kind of the mutation
+     */
+    kind: string;
+
+    /**
+     * The malformed token
+     */
+    token: string;
+  }
+
+  /**
+   * Generate structurally broken variants of the token: invalid base64 characters and padding, missing, extra
+   * or empty segments, invalid JSON or non-object header, NUL bytes, whitespace and mixed JSON/compact serialization.
+   *
+   * @param token The compact JWS or JWE
+   * @param options The generator options
+   * @returns The malformed tokens
+   */
+  function fuzz(token: string, options?: FuzzOptions): Fuzzed[];
+
+  /**
+   * The available mutation kinds of [fuzz](../modules/tamper.md#fuzz).
+   *
+   * @returns The sorted kind names
+   */
+  function fuzzKinds(): string[];
+
+  /**
+   * Options for oversized token creation.
+   */
+  interface OversizeOptions {
+    /**
+     * The length of the padding claim value in characters (e.g. `4 * 1024 * 1024` for 4 MiB)
+     */
+    size?: number;
+
+    /**
+     * The number of `aud` entries, the original audience values come first
+     */
+    audience?: number;
+
+    /**
+     * The nesting depth of the nested claim object
+     */
+    depth?: number;
+
+    /**
+     * The name of the padding claim (default: `pad`)
+     */
+    padClaim?: string;
+
+    /**
+     * The name of the nested claim (default: `nested`)
+     */
+    nestedClaim?: string;
+
+    /**
+     * Additional header parameters
+     */
+    header?: object;
+  }
+
+  /**
+   * Create validly signed token with inflated claims to stress parser limits of the system under test.
+   * Parsing the token with this extension requires raised limits, see [configure](../modules/jose.md#configure).
+   *
+   * @param key The signing key
+   * @param claims The claims to inflate
+   * @param options The size options
+   * @returns The signed token
+   */
+  function oversize(key: jwk.Key, claims: object, options?: OversizeOptions): string;
+
+  /**
+   * Options for header key injection.
+   */
+  interface InjectOptions {
+    /**
+     * Additional header parameters (e.g. `kid` of the trusted key)
+     */
+    header?: object;
+  }
+
+  /**
+   * Create token self-signed by an attacker key, with its public key embedded in the `jwk` header.
+   * Services must not trust header supplied keys.
+   *
+   * @param key The attacker's private key
+   * @param claims The claims
+   * @param options The injection options
+   * @returns The signed token
+   */
+  function embedJWK(key: jwk.Key, claims: object, options?: InjectOptions): string;
+
+  /**
+   * Create token signed by an attacker key, with `jku` header pointing at a caller-supplied JWK Set URL.
+   * Services must not fetch keys from header supplied URLs.
+   *
+   * @param key The attacker's private key, its public key should be served at the URL
+   * @param claims The claims
+   * @param url The JWK Set URL
+   * @param options The injection options
+   * @returns The signed token
+   */
+  function jku(key: jwk.Key, claims: object, url: string, options?: InjectOptions): string;
+
+  /**
+   * Options for kid injection.
+   */
+  interface KidInjectionOptions {
+    /**
+     * The injection kinds: `command`, `ldap`, `path` and `sql` (default: all, unless `payloads` given)
+     */
+    kinds?: string[];
+
+    /**
+     * Custom kid values, with `custom` kind
+     */
+    payloads?: string[];
+
+    /**
+     * Additional header parameters
+     */
+    header?: object;
+  }
+
+  /**
+   * The token with injected kid.
+   */
+  interface KidToken {
+    /**
+     * The kind of the injection
+     */
+    kind: string;
+
+    /**
+     * The injected kid header value
+     */
+    kid: string;
+
+    /**
+     * The signed token
+     */
+    token: string;
+  }
+
+  /**
+   * Create validly signed tokens with SQL, path traversal, LDAP and command injection strings in the `kid` header,
+   * to test key lookup code paths.
+   *
+   * @param key The signing key
+   * @param claims The claims
+   * @param options The injection options
+   * @returns The tokens
+   */
+  function kidInjection(key: jwk.Key, claims: object, options?: KidInjectionOptions): KidToken[];
 }
@@ -0,0 +1,77 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package algorithm lists the JOSE algorithms the parsers of go-jose accept.
+// Tokens are parsed with every algorithm, the allowed algorithms are checked by the modules.
+package algorithm
+
+import "github.com/go-jose/go-jose/v4"
+
+// None is the alg of unsecured JWS (RFC 7515 Appendix A.5).
+const None = jose.SignatureAlgorithm("none")
+
+// Signatures includes none, so unsecured tokens can be decoded, no verifier accepts them.
+var Signatures = []jose.SignatureAlgorithm{
+	None,
+	jose.EdDSA,
+	jose.HS256, jose.HS384, jose.HS512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.PS256, jose.PS384, jose.PS512,
+	ESB256, ESB384, ESB512,
+}
+
+var Keys = []jose.KeyAlgorithm{
+	jose.RSA1_5, jose.RSA_OAEP, jose.RSA_OAEP_256,
+	jose.A128KW, jose.A192KW, jose.A256KW,
+	jose.DIRECT,
+	jose.ECDH_ES, jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW,
+	jose.A128GCMKW, jose.A192GCMKW, jose.A256GCMKW,
+	jose.PBES2_HS256_A128KW, jose.PBES2_HS384_A192KW, jose.PBES2_HS512_A256KW,
+}
+
+var Encryptions = []jose.ContentEncryption{
+	jose.A128CBC_HS256, jose.A192CBC_HS384, jose.A256CBC_HS512,
+	jose.A128GCM, jose.A192GCM, jose.A256GCM,
+}
+
+// senderKeys are the ECDH-1PU key agreement algorithms, implemented by the jwe module without go-jose.
+var senderKeys = []jose.KeyAlgorithm{"ECDH-1PU", "ECDH-1PU+A128KW", "ECDH-1PU+A192KW", "ECDH-1PU+A256KW"}
+
+var known = func() map[string]bool {
+	names := make(map[string]bool, len(Signatures)+len(Keys)+len(senderKeys))
+
+	for _, alg := range Signatures {
+		names[string(alg)] = true
+	}
+
+	for _, alg := range append(append([]jose.KeyAlgorithm{}, Keys...), senderKeys...) {
+		names[string(alg)] = true
+	}
+
+	return names
+}()
+
+// Known returns true for the signature and key management algorithm names supported by the extension.
+func Known(alg string) bool {
+	return known[alg]
+}
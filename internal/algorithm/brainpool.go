@@ -0,0 +1,182 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package algorithm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/ProtonMail/go-crypto/brainpool"
+	"github.com/go-jose/go-jose/v4"
+)
+
+// ECDSA algorithms of the Brainpool curves (draft-ietf-jose-fully-specified-algorithms), go-jose supports neither
+// the algorithms nor the curves.
+const (
+	ESB256 = jose.SignatureAlgorithm("ESB256")
+	ESB384 = jose.SignatureAlgorithm("ESB384")
+	ESB512 = jose.SignatureAlgorithm("ESB512")
+)
+
+type brainpoolCurve struct {
+	crv   string
+	alg   jose.SignatureAlgorithm
+	hash  crypto.Hash
+	curve elliptic.Curve
+}
+
+var brainpoolCurves = []brainpoolCurve{
+	{"BP-256", ESB256, crypto.SHA256, brainpool.P256r1()},
+	{"BP-384", ESB384, crypto.SHA384, brainpool.P384r1()},
+	{"BP-512", ESB512, crypto.SHA512, brainpool.P512r1()},
+}
+
+// BrainpoolCurve returns the curve and the algorithm of the JWK crv (BP-256, BP-384 or BP-512),
+// the curve name (brainpoolP256r1, brainpoolP384r1 or brainpoolP512r1) or the algorithm.
+func BrainpoolCurve(name string) (elliptic.Curve, jose.SignatureAlgorithm, bool) {
+	for _, c := range brainpoolCurves {
+		if c.crv == name || c.curve.Params().Name == name || string(c.alg) == name {
+			return c.curve, c.alg, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// BrainpoolCrv returns the JWK crv of the curve, false if it's not a Brainpool curve.
+func BrainpoolCrv(curve elliptic.Curve) (string, bool) {
+	for _, c := range brainpoolCurves {
+		if c.curve == curve {
+			return c.crv, true
+		}
+	}
+
+	return "", false
+}
+
+// IsBrainpool reports whether the algorithm is ESB256, ESB384 or ESB512.
+func IsBrainpool(alg string) bool {
+	_, found, ok := BrainpoolCurve(alg)
+
+	return ok && string(found) == alg
+}
+
+// BrainpoolKey returns copy of the key signing with opaque signer if it's a Brainpool EC private key,
+// the key itself otherwise.
+func BrainpoolKey(key *jose.JSONWebKey) *jose.JSONWebKey {
+	private, ok := key.Key.(*ecdsa.PrivateKey)
+	if !ok {
+		return key
+	}
+
+	if _, ok := BrainpoolCrv(private.Curve); !ok {
+		return key
+	}
+
+	public := key.Public()
+	signing := *key
+	signing.Key = &brainpoolSigner{key: private, public: &public}
+
+	return &signing
+}
+
+// SignBrainpool returns the signature as fixed size r and s, like of the ES algorithms.
+func SignBrainpool(key *ecdsa.PrivateKey, alg jose.SignatureAlgorithm, input []byte) ([]byte, error) {
+	c, ok := brainpoolOf(key.Curve, alg)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s with %s key", jose.ErrUnsupportedAlgorithm, alg, key.Curve.Params().Name)
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest(c.hash, input))
+	if err != nil {
+		return nil, err
+	}
+
+	size := (c.curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+
+	return sig, nil
+}
+
+// VerifyBrainpool verifies fixed size r and s signature, false if the algorithm doesn't match the curve of the key.
+func VerifyBrainpool(key *ecdsa.PublicKey, alg jose.SignatureAlgorithm, input, signature []byte) bool {
+	c, ok := brainpoolOf(key.Curve, alg)
+	if !ok {
+		return false
+	}
+
+	size := (c.curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	return ecdsa.Verify(key, digest(c.hash, input), r, s)
+}
+
+func brainpoolOf(curve elliptic.Curve, alg jose.SignatureAlgorithm) (brainpoolCurve, bool) {
+	for _, c := range brainpoolCurves {
+		if c.curve == curve && c.alg == alg {
+			return c, true
+		}
+	}
+
+	return brainpoolCurve{}, false
+}
+
+func digest(hash crypto.Hash, input []byte) []byte {
+	hasher := hash.New()
+	_, _ = hasher.Write(input)
+
+	return hasher.Sum(nil)
+}
+
+// brainpoolSigner is an opaque signer, for the signers of go-jose.
+type brainpoolSigner struct {
+	key    *ecdsa.PrivateKey
+	public *jose.JSONWebKey
+}
+
+func (s *brainpoolSigner) Public() *jose.JSONWebKey {
+	return s.public
+}
+
+func (s *brainpoolSigner) Algs() []jose.SignatureAlgorithm {
+	crv, _ := BrainpoolCrv(s.key.Curve)
+	_, alg, _ := BrainpoolCurve(crv)
+
+	return []jose.SignatureAlgorithm{alg}
+}
+
+func (s *brainpoolSigner) SignPayload(payload []byte, alg jose.SignatureAlgorithm) ([]byte, error) {
+	return SignBrainpool(s.key, alg, payload)
+}
@@ -0,0 +1,116 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package algorithm
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+var ErrInvalidSaltLength = errors.New("invalid salt length")
+
+const (
+	SaltLengthHash = "hash"
+	SaltLengthMax  = "max"
+)
+
+var pssHashes = map[jose.SignatureAlgorithm]crypto.Hash{
+	jose.PS256: crypto.SHA256, jose.PS384: crypto.SHA384, jose.PS512: crypto.SHA512,
+}
+
+// SaltLength returns the RSA-PSS salt length (rsa.PSSOptions) of the option: "hash" for the digest length (the default,
+// like go-jose), "max" for the maximum length allowed by the key, or number of bytes.
+// Verification detects the salt length, so every one of them is accepted.
+func SaltLength(in interface{}) (int, error) {
+	switch value := in.(type) {
+	case nil:
+		return rsa.PSSSaltLengthEqualsHash, nil
+	case string:
+		switch value {
+		case SaltLengthHash, "":
+			return rsa.PSSSaltLengthEqualsHash, nil
+		case SaltLengthMax:
+			return rsa.PSSSaltLengthAuto, nil
+		}
+	case int64:
+		if value > 0 {
+			return int(value), nil
+		}
+	case float64:
+		if value > 0 && value == float64(int(value)) {
+			return int(value), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %v, expected \"hash\", \"max\" or positive number", ErrInvalidSaltLength, in)
+}
+
+// PSSKey returns copy of the key signing with the salt length, if it's an RSA private key with PS256, PS384 or PS512
+// algorithm, the key itself otherwise.
+func PSSKey(key *jose.JSONWebKey, saltLength int) *jose.JSONWebKey {
+	alg := jose.SignatureAlgorithm(key.Algorithm)
+
+	private, ok := key.Key.(*rsa.PrivateKey)
+	if _, pss := pssHashes[alg]; !ok || !pss || saltLength == rsa.PSSSaltLengthEqualsHash {
+		return key
+	}
+
+	public := key.Public()
+	signing := *key
+	signing.Key = &pssSigner{key: private, public: &public, alg: alg, opts: &rsa.PSSOptions{SaltLength: saltLength}}
+
+	return &signing
+}
+
+// pssSigner is an opaque signer, go-jose always signs with digest length salt.
+type pssSigner struct {
+	key    *rsa.PrivateKey
+	public *jose.JSONWebKey
+	alg    jose.SignatureAlgorithm
+	opts   *rsa.PSSOptions
+}
+
+func (s *pssSigner) Public() *jose.JSONWebKey {
+	return s.public
+}
+
+func (s *pssSigner) Algs() []jose.SignatureAlgorithm {
+	return []jose.SignatureAlgorithm{s.alg}
+}
+
+func (s *pssSigner) SignPayload(payload []byte, alg jose.SignatureAlgorithm) ([]byte, error) {
+	hash, ok := pssHashes[alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", jose.ErrUnsupportedAlgorithm, alg)
+	}
+
+	hasher := hash.New()
+	_, _ = hasher.Write(payload)
+
+	return rsa.SignPSS(rand.Reader, s.key, hash, hasher.Sum(nil), s.opts)
+}
@@ -0,0 +1,119 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package async runs the CPU heavy operations, like RSA signing and key generation, outside of the event loop of the VU.
+package async
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/redact"
+	"go.k6.io/k6/js/modules"
+)
+
+// ErrEventLoop is returned for keys which can be used only on the event loop, like the ones calling a JS sign callback.
+var ErrEventLoop = errors.New("key can be used only synchronously")
+
+// LoopBound is implemented by the opaque signers calling into the JS runtime.
+type LoopBound interface {
+	LoopBound() bool
+}
+
+// Check returns ErrEventLoop if any of the keys (also in arrays) is bound to the event loop.
+func Check(keys ...interface{}) error {
+	for _, key := range keys {
+		switch val := key.(type) {
+		case *jose.JSONWebKey:
+			if bound, ok := val.Key.(LoopBound); ok && bound.LoopBound() {
+				return fmt.Errorf("%w: %s", ErrEventLoop, val.KeyID)
+			}
+		case []interface{}:
+			if err := Check(val...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Promise calls fn in a new goroutine and returns promise settled with its result on the event loop of the VU.
+// Byte slice results are resolved as ArrayBuffer, like the synchronous functions return them.
+// The goroutine must not use the JS runtime, arguments referring to JS memory should be detached before (see Detach).
+func Promise(vu modules.VU, fn func() (interface{}, error)) *sobek.Promise {
+	rt := vu.Runtime()
+	promise, resolve, reject := rt.NewPromise()
+	callback := vu.RegisterCallback()
+
+	go func() {
+		result, err := fn()
+
+		callback(func() error {
+			if err != nil {
+				return reject(rejection(rt, err))
+			}
+
+			if data, ok := result.([]byte); ok {
+				return resolve(rt.NewArrayBuffer(data))
+			}
+
+			return resolve(result)
+		})
+	}()
+
+	return promise
+}
+
+// Detach returns the values with the ArrayBuffers and byte views (also in arrays) replaced by copies, the script may change them
+// while the operation runs. It must be called on the event loop.
+func Detach(rt *sobek.Runtime, values ...interface{}) []interface{} {
+	out := make([]interface{}, len(values))
+
+	for i, value := range values {
+		switch val := value.(type) {
+		case sobek.ArrayBuffer:
+			out[i] = rt.NewArrayBuffer(append([]byte(nil), val.Bytes()...))
+		case []byte:
+			out[i] = append([]byte(nil), val...)
+		case []interface{}:
+			out[i] = Detach(rt, val...)
+		default:
+			out[i] = value
+		}
+	}
+
+	return out
+}
+
+// rejection is the error thrown by the synchronous function, with key material masked in its message.
+func rejection(rt *sobek.Runtime, err error) *sobek.Object {
+	obj := rt.NewGoError(err)
+
+	if message := err.Error(); redact.String(message) != message {
+		_ = obj.Set("message", redact.String(message))
+	}
+
+	return obj
+}
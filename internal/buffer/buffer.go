@@ -0,0 +1,128 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package buffer converts the binary values accepted from JS to bytes.
+package buffer
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+var (
+	ErrInvalidValue       = errors.New("invalid binary value")
+	ErrInvalidCertificate = errors.New("invalid PEM certificate")
+)
+
+// Bytes returns a copy of the bytes of an ArrayBuffer, Uint8Array, Int8Array or array of numbers.
+// Strings are UTF-8 encoded, binary data must not be passed as string. Nil and empty values return nil.
+func Bytes(in interface{}) ([]byte, error) {
+	if in == nil || reflect.ValueOf(in).IsZero() {
+		return nil, nil
+	}
+
+	switch val := in.(type) {
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return clone(val), nil
+	case sobek.ArrayBuffer:
+		return clone(val.Bytes()), nil
+	case []int8:
+		out := make([]byte, len(val))
+		for i, b := range val {
+			out[i] = byte(b)
+		}
+
+		return out, nil
+	case []interface{}:
+		return numbers(val)
+	default:
+		return nil, fmt.Errorf("%w: %T, expected ArrayBuffer, Uint8Array, array of numbers or string", ErrInvalidValue, in)
+	}
+}
+
+// View returns the bytes of an ArrayBuffer or Uint8Array without copying, for large content which is only read
+// during the call. Other values are converted by Bytes.
+func View(in interface{}) ([]byte, error) {
+	switch val := in.(type) {
+	case []byte:
+		return val, nil
+	case sobek.ArrayBuffer:
+		return val.Bytes(), nil
+	default:
+		return Bytes(in)
+	}
+}
+
+// Certificate returns the DER encoded certificate of a PEM string, other values are converted by Bytes.
+func Certificate(in interface{}) ([]byte, error) {
+	if source, ok := in.(string); ok && strings.HasPrefix(strings.TrimSpace(source), "-----BEGIN") {
+		block, _ := pem.Decode([]byte(source))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil, ErrInvalidCertificate
+		}
+
+		return block.Bytes, nil
+	}
+
+	return Bytes(in)
+}
+
+// clone copies the bytes, typed arrays and ArrayBuffers are views of memory owned (and modifiable) by JS.
+func clone(in []byte) []byte {
+	out := make([]byte, len(in))
+	copy(out, in)
+
+	return out
+}
+
+func numbers(in []interface{}) ([]byte, error) {
+	out := make([]byte, len(in))
+
+	for i, v := range in {
+		var n float64
+
+		switch num := v.(type) {
+		case int64:
+			n = float64(num)
+		case float64:
+			n = num
+		default:
+			return nil, fmt.Errorf("%w: %T at index %d", ErrInvalidValue, v, i)
+		}
+
+		if n < 0 || n > math.MaxUint8 || n != math.Trunc(n) {
+			return nil, fmt.Errorf("%w: %v at index %d is not a byte", ErrInvalidValue, v, i)
+		}
+
+		out[i] = byte(n)
+	}
+
+	return out, nil
+}
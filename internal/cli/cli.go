@@ -0,0 +1,271 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package cli implements the k6 x jose subcommand, generating key and token fixtures before the test run.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/spf13/cobra"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/cmd/state"
+	"go.k6.io/k6/lib/fsext"
+)
+
+var (
+	ErrMissingKeys = errors.New("no signing keys")
+	ErrInvalidFlag = errors.New("invalid flag")
+)
+
+const filePerm = 0o600
+
+// New returns the jose command, its subcommands write to the file of --out or to the standard output.
+func New(gs *state.GlobalState) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jose",
+		Short: "Generate JOSE key and token fixtures",
+		Long: `Generate keys, JWK sets and pre-signed tokens to files before the test run,
+so the key generation does not load the test.`,
+	}
+
+	cmd.AddCommand(keysCommand(gs), tokensCommand(gs))
+
+	return cmd
+}
+
+func keysCommand(gs *state.GlobalState) *cobra.Command {
+	var (
+		count       int
+		bits        int
+		out, public string
+		prefix      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "keys <algorithm>",
+		Short: "Generate private JWK set, and the public JWK set of it",
+		Example: `  k6 x jose keys ES256 --count 10 --out keys.json --public jwks.json
+  k6 x jose keys RS256 --bits 4096 --kid-prefix signer-`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if count < 1 {
+				return fmt.Errorf("%w: --count must be positive", ErrInvalidFlag)
+			}
+
+			keys, err := jwk.GenerateKeys(args[0], bits, count)
+			if err != nil {
+				return err
+			}
+
+			if prefix != "" {
+				for i := range keys {
+					keys[i].KeyID = fmt.Sprintf("%s%d", prefix, i+1)
+				}
+			}
+
+			if err := write(gs, out, jose.JSONWebKeySet{Keys: keys}); err != nil {
+				return err
+			}
+
+			if public == "" {
+				return nil
+			}
+
+			set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, len(keys))}
+
+			for i := range keys {
+				if set.Keys[i] = keys[i].Public(); !set.Keys[i].Valid() {
+					return fmt.Errorf("%w: %s keys have no public part", ErrInvalidFlag, args[0])
+				}
+			}
+
+			return write(gs, public, set)
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.IntVarP(&count, "count", "n", 1, "number of keys")
+	flags.IntVar(&bits, "bits", 0, "RSA modulus size in bits (default 2048)")
+	flags.StringVarP(&out, "out", "o", "", "private JWK set file (default standard output)")
+	flags.StringVar(&public, "public", "", "public JWK set file")
+	flags.StringVar(&prefix, "kid-prefix", "", "kid prefix, followed by the index of the key (default thumbprint)")
+
+	return cmd
+}
+
+func tokensCommand(gs *state.GlobalState) *cobra.Command {
+	var (
+		count    int
+		keysFile string
+		claims   string
+		subject  string
+		lifetime string
+		out      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Generate JSON array of pre-signed JWTs",
+		Long: `Generate JSON array of pre-signed JWTs, signed by the keys of the JWK set in turn.
+Every token gets iat, exp and a random jti claim, the %d of the subject is replaced by the index of the token.`,
+		Example: `  k6 x jose tokens --keys keys.json --count 1000 --subject user-%d --lifetime 2h --out tokens.json`,
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if count < 1 {
+				return fmt.Errorf("%w: --count must be positive", ErrInvalidFlag)
+			}
+
+			keys, err := readKeys(gs, keysFile)
+			if err != nil {
+				return err
+			}
+
+			template := map[string]interface{}{}
+
+			if claims != "" {
+				if err := json.Unmarshal([]byte(claims), &template); err != nil {
+					return fmt.Errorf("%w: --claims: %s", ErrInvalidFlag, err.Error())
+				}
+			}
+
+			ttl, err := duration(lifetime)
+			if err != nil {
+				return fmt.Errorf("%w: --lifetime: %s", ErrInvalidFlag, err.Error())
+			}
+
+			tokens := make([]string, count)
+			now := time.Now()
+
+			for i := range tokens {
+				payload := jwt.Merge(template, map[string]interface{}{
+					"iat": now.Unix(),
+					"exp": now.Add(ttl).Unix(),
+					"jti": jwt.RandomID(),
+				})
+
+				if subject != "" {
+					if strings.Contains(subject, "%d") {
+						payload["sub"] = fmt.Sprintf(subject, i+1)
+					} else {
+						payload["sub"] = subject
+					}
+				}
+
+				if tokens[i], err = jwt.Sign(&keys[i%len(keys)], payload, nil); err != nil {
+					return err
+				}
+			}
+
+			return write(gs, out, tokens)
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.IntVarP(&count, "count", "n", 1, "number of tokens")
+	flags.StringVarP(&keysFile, "keys", "k", "", "private JWK set (or JWK) file of the signing keys")
+	flags.StringVar(&claims, "claims", "", "claims of every token as JSON object")
+	flags.StringVar(&subject, "subject", "", "sub claim, %d is replaced by the index of the token")
+	flags.StringVar(&lifetime, "lifetime", "1h", "lifetime of the tokens, duration or seconds")
+	flags.StringVarP(&out, "out", "o", "", "output file (default standard output)")
+
+	_ = cmd.MarkFlagRequired("keys")
+
+	return cmd
+}
+
+// duration parses duration string or number of seconds, like the duration options of the JS API.
+func duration(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return jwt.Duration(seconds)
+	}
+
+	return jwt.Duration(value)
+}
+
+// readKeys reads JWK set or single JWK of private keys.
+func readKeys(gs *state.GlobalState, path string) ([]jose.JSONWebKey, error) {
+	raw, err := fsext.ReadFile(gs.FS, abs(gs, path))
+	if err != nil {
+		return nil, err
+	}
+
+	set := jose.JSONWebKeySet{}
+
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrMissingKeys, path, err.Error())
+	}
+
+	if len(set.Keys) == 0 {
+		key := jose.JSONWebKey{}
+
+		if err := key.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMissingKeys, path)
+		}
+
+		set.Keys = append(set.Keys, key)
+	}
+
+	for i := range set.Keys {
+		if set.Keys[i].IsPublic() {
+			return nil, fmt.Errorf("%w: %s has public key %s", ErrMissingKeys, path, set.Keys[i].KeyID)
+		}
+	}
+
+	return set.Keys, nil
+}
+
+// write writes the value as indented JSON to the file, or to the standard output if path is empty.
+func write(gs *state.GlobalState, path string, v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	raw = append(raw, '\n')
+
+	if path == "" {
+		_, err = gs.Stdout.Write(raw)
+
+		return err
+	}
+
+	return fsext.WriteFile(gs.FS, abs(gs, path), raw, filePerm)
+}
+
+func abs(gs *state.GlobalState, path string) string {
+	cwd, err := gs.Getwd()
+	if err != nil {
+		return path
+	}
+
+	return fsext.Abs(cwd, path)
+}
@@ -0,0 +1,116 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/cmd/tests"
+	"go.k6.io/k6/lib/fsext"
+)
+
+func run(ts *tests.GlobalTestState, args ...string) error {
+	cmd := New(ts.GlobalState)
+	cmd.SetArgs(args)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	return cmd.Execute()
+}
+
+func TestKeysTokens(t *testing.T) {
+	t.Parallel()
+
+	ts := tests.NewGlobalTestState(t)
+
+	if err := run(ts, "keys", "ES256", "--count", "2", "--out", "keys.json", "--public", "jwks.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(ts, "tokens", "--keys", "keys.json", "--count", "3", "--subject", "user-%d", "--out", "tokens.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		public jose.JSONWebKeySet
+		tokens []string
+	)
+
+	read(t, ts, "jwks.json", &public)
+	read(t, ts, "tokens.json", &tokens)
+
+	if len(public.Keys) != 2 || len(tokens) != 3 {
+		t.Fatalf("got %d keys and %d tokens", len(public.Keys), len(tokens))
+	}
+
+	for i, token := range tokens {
+		verified, err := jwt.Verify(token, &public)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := fmt.Sprintf("user-%d", i+1); verified.Payload["sub"] != want {
+			t.Fatalf("sub is %v, want %s", verified.Payload["sub"], want)
+		}
+
+		if kid := public.Keys[i%2].KeyID; verified.KeyID != kid {
+			t.Fatalf("token %d signed by %s, want %s", i, verified.KeyID, kid)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	t.Parallel()
+
+	ts := tests.NewGlobalTestState(t)
+
+	if err := run(ts, "keys", "ES256", "--count", "-1"); !errors.Is(err, ErrInvalidFlag) {
+		t.Fatalf("keys: expected ErrInvalidFlag, got %v", err)
+	}
+
+	if err := run(ts, "keys", "ES256", "--out", "keys.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(ts, "tokens", "--keys", "keys.json", "--count", "0"); !errors.Is(err, ErrInvalidFlag) {
+		t.Fatalf("tokens: expected ErrInvalidFlag, got %v", err)
+	}
+}
+
+func read(t *testing.T, ts *tests.GlobalTestState, path string, v interface{}) {
+	t.Helper()
+
+	raw, err := fsext.ReadFile(ts.FS, fsext.Abs(ts.Cwd, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+var ErrInvalidTime = errors.New("invalid time")
+
+// Time accepts Date, RFC 3339 string or number of seconds since the epoch (NumericDate).
+func Time(in interface{}) (time.Time, error) {
+	switch val := in.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTime, val)
+		}
+
+		return parsed, nil
+	case int64:
+		return time.Unix(val, 0), nil
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidTime, val)
+		}
+
+		sec, frac := math.Modf(val)
+
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidTime, in)
+	}
+}
+
+// Clock accepts a fixed time (see Time) or a function returning the current time, nil for the wall clock.
+func Clock(in interface{}) (func() (time.Time, error), error) {
+	switch val := in.(type) {
+	case nil:
+		return nil, nil
+	case func(sobek.FunctionCall) sobek.Value:
+		return func() (time.Time, error) { return Time(val(sobek.FunctionCall{}).Export()) }, nil
+	default:
+		fixed, err := Time(in)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() (time.Time, error) { return fixed, nil }, nil
+	}
+}
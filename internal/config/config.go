@@ -0,0 +1,397 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package config holds the configuration shared by the namespaces of the k6/x/jose root module.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+)
+
+var (
+	ErrAlgorithmNotAllowed = errors.New("algorithm not allowed")
+	ErrInvalidConfig       = errors.New("invalid configuration")
+)
+
+// Environment variables of the defaults, -e flags and the system environment both work.
+const (
+	EnvAlgorithms = "K6_JOSE_ALGORITHMS"
+	EnvLeeway     = "K6_JOSE_LEEWAY"
+	EnvCacheTTL   = "K6_JOSE_CACHE_TTL"
+	EnvKeySize    = "K6_JOSE_KEY_SIZE"
+	EnvCritical   = "K6_JOSE_CRITICAL"
+
+	EnvFetchTimeout = "K6_JOSE_FETCH_TIMEOUT"
+
+	EnvMaxTokenSize = "K6_JOSE_MAX_TOKEN_SIZE"
+	EnvMaxSegments  = "K6_JOSE_MAX_SEGMENTS"
+	EnvMaxDepth     = "K6_JOSE_MAX_DEPTH"
+
+	EnvStrictBase64 = "K6_JOSE_STRICT_BASE64"
+	EnvStrictJSON   = "K6_JOSE_STRICT_JSON"
+
+	EnvDebug = "K6_JOSE_DEBUG"
+)
+
+// OptionsName is the name of the module options in the extension options of the test (options.ext.jose),
+// named like the options of configure.
+const OptionsName = "jose"
+
+// optionNames are the environment variables of the test options by option name.
+var optionNames = map[string]string{
+	"algorithms":   EnvAlgorithms,
+	"leeway":       EnvLeeway,
+	"cacheTTL":     EnvCacheTTL,
+	"keySize":      EnvKeySize,
+	"critical":     EnvCritical,
+	"fetchTimeout": EnvFetchTimeout,
+	"maxTokenSize": EnvMaxTokenSize,
+	"maxSegments":  EnvMaxSegments,
+	"maxDepth":     EnvMaxDepth,
+	"strictBase64": EnvStrictBase64,
+	"strictJSON":   EnvStrictJSON,
+	"debug":        EnvDebug,
+}
+
+// Config is the configuration of a VU, initialized from the environment.
+type Config struct {
+	// Leeway is the default clock skew tolerance of claim validation, nil for the go-jose default.
+	Leeway *time.Duration
+	// Algorithms allowed in the alg header of verified and decrypted tokens, empty for any.
+	Algorithms []string
+	// CacheTTL is the lifetime of parsed key cache entries, nil for no expiration.
+	CacheTTL *time.Duration
+	// KeySize is the RSA modulus size of generated keys in bits, zero for the default.
+	KeySize int
+	// FetchTimeout is the timeout of fetching keys from URL, nil for the default.
+	FetchTimeout *time.Duration
+	// Critical header parameters handled by the script, verified tokens may list them in crit.
+	Critical []string
+	// Limits of token parsing.
+	Limits
+	// StrictBase64 rejects tokens with padding, non URL safe characters or non-zero trailing bits in base64url segments.
+	StrictBase64 bool
+	// StrictJSON rejects headers, claim sets and JSON serializations with duplicate member names.
+	StrictJSON bool
+	// Debug logs the decoded header, the claim names and the failure stage of failed operations.
+	Debug bool
+	// Clock returns the current time of signing and claim validation, nil for the wall clock.
+	Clock func() (time.Time, error)
+
+	vu         modules.VU
+	env        map[string]string
+	resolve    sync.Once
+	resolveErr error
+}
+
+// Default returns the configuration read from the environment of the VU, it must be called in the init context.
+// Invalid values are thrown, failing the import of the module.
+// The options of the test are applied by Resolve in the VU code.
+func Default(vu modules.VU) *Config {
+	cfg, err := FromEnv(vu.InitEnv().RuntimeOptions.Env)
+	if err != nil {
+		common.Throw(vu.Runtime(), err)
+	}
+
+	cfg.vu = vu
+
+	return cfg
+}
+
+// FromEnv returns the configuration of the environment variables.
+func FromEnv(env map[string]string) (*Config, error) {
+	return parse(env, func(name string) string { return name })
+}
+
+// parse returns the configuration of the values by environment variable name, invalid values are reported
+// with the label of their name.
+func parse(env map[string]string, label func(name string) string) (*Config, error) {
+	cfg := &Config{env: env}
+
+	cfg.Algorithms = envList(env, EnvAlgorithms)
+	cfg.Critical = envList(env, EnvCritical)
+
+	var err error
+
+	if cfg.Leeway, err = envDuration(env, EnvLeeway, label); err != nil {
+		return nil, err
+	}
+
+	if cfg.CacheTTL, err = envDuration(env, EnvCacheTTL, label); err != nil {
+		return nil, err
+	}
+
+	if cfg.FetchTimeout, err = envDuration(env, EnvFetchTimeout, label); err != nil {
+		return nil, err
+	}
+
+	for _, v := range []struct {
+		name  string
+		value *int
+	}{
+		{EnvKeySize, &cfg.KeySize},
+		{EnvMaxTokenSize, &cfg.MaxTokenSize},
+		{EnvMaxSegments, &cfg.MaxSegments},
+		{EnvMaxDepth, &cfg.MaxDepth},
+	} {
+		if *v.value, err = envPositive(env, v.name, label); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, v := range []struct {
+		name  string
+		value *bool
+	}{
+		{EnvStrictBase64, &cfg.StrictBase64},
+		{EnvStrictJSON, &cfg.StrictJSON},
+		{EnvDebug, &cfg.Debug},
+	} {
+		if value := strings.TrimSpace(env[v.name]); value != "" {
+			if *v.value, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("%w: %s=%s", ErrInvalidConfig, label(v.name), value)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// Resolve fills the settings not set by the environment variables or configure with the options of the test
+// (options.ext.jose), once in the VU code, where the options are available. Invalid options are returned.
+func (c *Config) Resolve() error {
+	if c == nil || c.vu == nil || c.vu.State() == nil {
+		return nil
+	}
+
+	c.resolve.Do(func() {
+		c.resolveErr = c.fill(c.vu.State().Options.External[OptionsName])
+	})
+
+	return c.resolveErr
+}
+
+// fill sets the unset settings from the JSON object of the options.
+func (c *Config) fill(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var options map[string]interface{}
+
+	if err := json.Unmarshal(raw, &options); err != nil {
+		return fmt.Errorf("%w: ext.%s: %s", ErrInvalidConfig, OptionsName, err.Error())
+	}
+
+	values := make(map[string]string, len(options))
+	labels := make(map[string]string, len(options))
+
+	for name, value := range options {
+		env, ok := optionNames[name]
+		if !ok {
+			return fmt.Errorf("%w: ext.%s.%s is unknown", ErrInvalidConfig, OptionsName, name)
+		}
+
+		values[env] = optionValue(value)
+		labels[env] = "ext." + OptionsName + "." + name
+	}
+
+	opts, err := parse(values, func(name string) string { return labels[name] })
+	if err != nil {
+		return err
+	}
+
+	// the environment variables take precedence, like the K6_ variables of the k6 options
+	unset := func(name string) bool {
+		return strings.TrimSpace(c.env[name]) == ""
+	}
+
+	if c.Leeway == nil && unset(EnvLeeway) {
+		c.Leeway = opts.Leeway
+	}
+
+	if c.CacheTTL == nil && unset(EnvCacheTTL) {
+		c.CacheTTL = opts.CacheTTL
+	}
+
+	if c.FetchTimeout == nil && unset(EnvFetchTimeout) {
+		c.FetchTimeout = opts.FetchTimeout
+	}
+
+	if len(c.Algorithms) == 0 && unset(EnvAlgorithms) {
+		c.Algorithms = opts.Algorithms
+	}
+
+	if len(c.Critical) == 0 && unset(EnvCritical) {
+		c.Critical = opts.Critical
+	}
+
+	for _, v := range []struct {
+		name       string
+		value, opt *int
+	}{
+		{EnvKeySize, &c.KeySize, &opts.KeySize},
+		{EnvMaxTokenSize, &c.MaxTokenSize, &opts.MaxTokenSize},
+		{EnvMaxSegments, &c.MaxSegments, &opts.MaxSegments},
+		{EnvMaxDepth, &c.MaxDepth, &opts.MaxDepth},
+	} {
+		if *v.value == 0 && unset(v.name) {
+			*v.value = *v.opt
+		}
+	}
+
+	for _, v := range []struct {
+		name       string
+		value, opt *bool
+	}{
+		{EnvStrictBase64, &c.StrictBase64, &opts.StrictBase64},
+		{EnvStrictJSON, &c.StrictJSON, &opts.StrictJSON},
+		{EnvDebug, &c.Debug, &opts.Debug},
+	} {
+		if !*v.value && unset(v.name) {
+			*v.value = *v.opt
+		}
+	}
+
+	return nil
+}
+
+// optionValue returns the option value in the format of the environment variables, lists comma separated.
+func optionValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+
+		for i := range v {
+			items[i] = optionValue(v[i])
+		}
+
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// envPositive parses positive integer, zero if the variable is not set.
+func envPositive(env map[string]string, name string, label func(string) string) (int, error) {
+	value := strings.TrimSpace(env[name])
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: %s=%s", ErrInvalidConfig, label(name), value)
+	}
+
+	return n, nil
+}
+
+// envList splits comma separated list, nil if the variable is not set.
+func envList(env map[string]string, name string) []string {
+	var list []string
+
+	for _, item := range strings.Split(env[name], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// envDuration parses Go duration string (e.g. "30s") or number of seconds, nil if the variable is not set.
+func envDuration(env map[string]string, name string, label func(string) string) (*time.Duration, error) {
+	value := strings.TrimSpace(env[name])
+	if value == "" {
+		return nil, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, perr := strconv.ParseFloat(value, 64)
+		if perr != nil {
+			return nil, fmt.Errorf("%w: %s=%s", ErrInvalidConfig, label(name), value)
+		}
+
+		d = time.Duration(seconds * float64(time.Second))
+	}
+
+	if d < 0 {
+		return nil, fmt.Errorf("%w: %s=%s is negative", ErrInvalidConfig, label(name), value)
+	}
+
+	return &d, nil
+}
+
+// Now returns the time of the configured clock, or the wall clock time.
+func (c *Config) Now() (time.Time, error) {
+	if c == nil || c.Clock == nil {
+		return time.Now(), nil
+	}
+
+	return c.Clock()
+}
+
+// OptionTime returns the time of the option (see Clock), or the time of the configured clock without option.
+func (c *Config) OptionTime(option interface{}) (time.Time, error) {
+	if option == nil {
+		return c.Now()
+	}
+
+	clock, err := Clock(option)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return clock()
+}
+
+// Allow returns ErrAlgorithmNotAllowed if algorithms are configured and alg is not one of them.
+func (c *Config) Allow(alg string) error {
+	if c == nil || len(c.Algorithms) == 0 {
+		return nil
+	}
+
+	for _, a := range c.Algorithms {
+		if a == alg {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, alg)
+}
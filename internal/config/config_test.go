@@ -0,0 +1,72 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFill(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := FromEnv(map[string]string{EnvMaxDepth: "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cfg.fill([]byte(`{"maxDepth":16,"maxTokenSize":4194304,"leeway":"30s",` +
+		`"algorithms":["ES256","RS256"],"strictJSON":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.MaxDepth != 8 {
+		t.Fatalf("environment overridden, maxDepth is %d", cfg.MaxDepth)
+	}
+
+	if cfg.MaxTokenSize != 4194304 || cfg.Leeway == nil || *cfg.Leeway != 30*time.Second {
+		t.Fatalf("options not applied: %d, %v", cfg.MaxTokenSize, cfg.Leeway)
+	}
+
+	if strings.Join(cfg.Algorithms, ",") != "ES256,RS256" || !cfg.StrictJSON {
+		t.Fatalf("options not applied: %v, %t", cfg.Algorithms, cfg.StrictJSON)
+	}
+}
+
+func TestFillInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{`{"leeway":"soon"}`, `{"maxDepth":-1}`, `{"unknown":1}`, `[]`} {
+		err := (&Config{}).fill([]byte(raw))
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("%s: expected ErrInvalidConfig, got %v", raw, err)
+		}
+
+		if !strings.Contains(err.Error(), "ext.jose") {
+			t.Fatalf("%s: option not named in %q", raw, err.Error())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrDuplicateMember = errors.New("duplicate JSON member")
+
+// checkDuplicates returns ErrDuplicateMember if an object of the JSON text has the same member name twice.
+// Parsers keep the last (or the first) one, the difference between parsers is a known smuggling vector.
+// Invalid JSON is left to the parsers.
+func checkDuplicates(data []byte) error {
+	type object struct {
+		names     map[string]struct{}
+		expectKey bool
+	}
+
+	var stack []*object
+
+	value := func() {
+		if n := len(stack); n != 0 && stack[n-1] != nil {
+			stack[n-1].expectKey = true
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			switch v {
+			case '{':
+				value()
+				stack = append(stack, &object{names: map[string]struct{}{}, expectKey: true})
+			case '[':
+				value()
+				stack = append(stack, nil) // arrays have no member names
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if n := len(stack); n != 0 && stack[n-1] != nil && stack[n-1].expectKey {
+				if _, ok := stack[n-1].names[v]; ok {
+					return fmt.Errorf("%w: %s", ErrDuplicateMember, v)
+				}
+
+				stack[n-1].names[v] = struct{}{}
+				stack[n-1].expectKey = false
+
+				continue
+			}
+
+			value()
+		default:
+			value()
+		}
+	}
+}
+
+// checkProtected checks the base64url encoded protected headers of JSON serialization for duplicate members.
+func checkProtected(token string) error {
+	raw := map[string]interface{}{}
+
+	if err := json.Unmarshal([]byte(token), &raw); err != nil {
+		return nil // left to the parsers
+	}
+
+	for _, obj := range jsonObjects(raw) {
+		protected, _ := obj["protected"].(string)
+
+		src, err := base64.RawURLEncoding.DecodeString(protected)
+		if err != nil {
+			continue
+		}
+
+		if err := checkDuplicates(src); err != nil {
+			return fmt.Errorf("%w in protected header", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrLimitExceeded = errors.New("parsing limit exceeded")
+
+// Default parsing limits, large enough for any sane token.
+const (
+	DefaultMaxTokenSize = 1 << 20
+	DefaultMaxSegments  = 100
+	DefaultMaxDepth     = 32
+)
+
+// Limits are the parsing limits of tokens, guarding the load generator against oversized responses.
+type Limits struct {
+	// MaxTokenSize is the maximum length of tokens in bytes, zero for the default.
+	MaxTokenSize int
+	// MaxSegments is the maximum number of dot and tilde separated parts of compact tokens, zero for the default.
+	MaxSegments int
+	// MaxDepth is the maximum nesting of JSON objects and arrays in headers, claims and JSON serializations,
+	// zero for the default.
+	MaxDepth int
+}
+
+func (l Limits) maxTokenSize() int {
+	if l.MaxTokenSize > 0 {
+		return l.MaxTokenSize
+	}
+
+	return DefaultMaxTokenSize
+}
+
+func (l Limits) maxSegments() int {
+	if l.MaxSegments > 0 {
+		return l.MaxSegments
+	}
+
+	return DefaultMaxSegments
+}
+
+func (l Limits) maxDepth() int {
+	if l.MaxDepth > 0 {
+		return l.MaxDepth
+	}
+
+	return DefaultMaxDepth
+}
+
+// MaxSize returns the maximum length of tokens, which is the limit of decompressed JWE content too.
+// The default limits apply to nil config.
+func (c *Config) MaxSize() int {
+	if c == nil {
+		return DefaultMaxTokenSize
+	}
+
+	return c.maxTokenSize()
+}
+
+// CheckToken returns ErrLimitExceeded if the token is too large, has too many segments or its header
+// (or JSON serialization) is nested too deep. The default limits apply to nil config.
+// In strict base64 mode ErrInvalidEncoding is returned for tokens with non-canonical base64url segments.
+func (c *Config) CheckToken(token string) error {
+	var limits Limits
+
+	if c != nil {
+		limits = c.Limits
+	}
+
+	if max := limits.maxTokenSize(); len(token) > max {
+		return fmt.Errorf("%w: token size %d exceeds %d bytes", ErrLimitExceeded, len(token), max)
+	}
+
+	if c != nil && c.StrictBase64 {
+		if err := checkStrict(token); err != nil {
+			return err
+		}
+	}
+
+	token = strings.TrimSpace(token)
+
+	if strings.HasPrefix(token, "{") {
+		if err := c.CheckJSON([]byte(token)); err != nil {
+			return err
+		}
+
+		if c != nil && c.StrictJSON {
+			return checkProtected(token)
+		}
+
+		return nil
+	}
+
+	if n, max := strings.Count(token, ".")+strings.Count(token, "~")+1, limits.maxSegments(); n > max {
+		return fmt.Errorf("%w: %d segments exceed %d", ErrLimitExceeded, n, max)
+	}
+
+	header := token
+	if idx := strings.IndexByte(token, '.'); idx >= 0 {
+		header = token[:idx]
+	}
+
+	return c.CheckSegment(header)
+}
+
+// CheckSegment returns ErrLimitExceeded if the JSON of the base64url encoded segment is nested too deep.
+// Segments which are not base64url encoded JSON are left to the parsers.
+func (c *Config) CheckSegment(segment string) error {
+	src, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil
+	}
+
+	return c.CheckJSON(src)
+}
+
+// CheckJSON returns ErrLimitExceeded if objects and arrays of the JSON text are nested too deep.
+// In strict JSON mode ErrDuplicateMember is returned for objects with duplicate member names.
+func (c *Config) CheckJSON(data []byte) error {
+	var limits Limits
+
+	if c != nil {
+		limits = c.Limits
+	}
+
+	max := limits.maxDepth()
+	depth := 0
+	inString, escaped := false, false
+
+	for _, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch b {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			if depth++; depth > max {
+				return fmt.Errorf("%w: JSON nesting exceeds depth %d", ErrLimitExceeded, max)
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+
+	if c != nil && c.StrictJSON {
+		return checkDuplicates(data)
+	}
+
+	return nil
+}
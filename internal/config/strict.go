@@ -0,0 +1,150 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidEncoding = errors.New("invalid base64url encoding")
+
+// jsonEncoded are the base64url encoded members of JWS and JWE JSON serializations.
+var jsonEncoded = []string{"protected", "payload", "signature", "encrypted_key", "iv", "ciphertext", "tag", "aad"}
+
+// checkStrict returns ErrInvalidEncoding if a base64url segment of the token has padding, characters outside
+// of the URL safe alphabet (including whitespace) or non-zero trailing bits.
+// Unencoded JWS payloads (b64 false) are not checked.
+func checkStrict(token string) error {
+	if strings.HasPrefix(strings.TrimSpace(token), "{") {
+		return checkStrictJSON(token)
+	}
+
+	parts := strings.Split(token, ".")
+
+	for i, part := range parts {
+		if i == 1 && unencoded(parts[0]) {
+			continue
+		}
+
+		// tilde separated SD-JWT disclosures and key binding JWT
+		for _, segment := range strings.Split(part, "~") {
+			if err := strictSegment(segment); err != nil {
+				return fmt.Errorf("%w: segment %d", err, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkStrictJSON(token string) error {
+	raw := map[string]interface{}{}
+
+	if err := json.Unmarshal([]byte(token), &raw); err != nil {
+		return nil // left to the parsers
+	}
+
+	objects := jsonObjects(raw)
+
+	skipPayload := false
+
+	for _, obj := range objects {
+		if protected, ok := obj["protected"].(string); ok && unencoded(protected) {
+			skipPayload = true
+		}
+	}
+
+	for _, obj := range objects {
+		for _, name := range jsonEncoded {
+			value, ok := obj[name].(string)
+			if !ok || (name == "payload" && skipPayload) {
+				continue
+			}
+
+			if err := strictSegment(value); err != nil {
+				return fmt.Errorf("%w: %s", err, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonObjects returns the JSON serialization and its per signature (or recipient) objects.
+func jsonObjects(raw map[string]interface{}) []map[string]interface{} {
+	objects := []map[string]interface{}{raw}
+
+	for _, name := range []string{"signatures", "recipients"} {
+		list, _ := raw[name].([]interface{})
+
+		for _, item := range list {
+			if obj, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, obj)
+			}
+		}
+	}
+
+	return objects
+}
+
+// unencoded returns true if the base64url encoded JWS header has b64 false.
+func unencoded(protected string) bool {
+	src, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return false
+	}
+
+	header := map[string]interface{}{}
+	if json.Unmarshal(src, &header) != nil {
+		return false
+	}
+
+	b64, ok := header["b64"].(bool)
+
+	return ok && !b64
+}
+
+func strictSegment(segment string) error {
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		case c == '=':
+			return fmt.Errorf("%w: padding", ErrInvalidEncoding)
+		default:
+			// also whitespace, ignored by the decoders of the standard library
+			return fmt.Errorf("%w: character %q", ErrInvalidEncoding, c)
+		}
+	}
+
+	if _, err := base64.RawURLEncoding.Strict().DecodeString(segment); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEncoding, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package crit processes the critical header parameter ("crit") of RFC 7515 section 4.1.11.
+// Extensions listed in crit must be understood by the recipient, tokens with other extensions are rejected.
+package crit
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// Header is the name of the critical header parameter.
+	Header = "crit"
+	// B64 is the unencoded payload extension (RFC 7797), always handled by JWS.
+	B64 = "b64"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported critical header")
+	ErrInvalid     = errors.New("invalid critical header")
+)
+
+// registered are the header parameters defined by the JWS, JWE and JWA specifications, they must not be critical.
+var registered = map[string]struct{}{
+	"alg": {}, "jku": {}, "jwk": {}, "kid": {}, "x5u": {}, "x5c": {}, "x5t": {}, "x5t#S256": {},
+	"typ": {}, "cty": {}, "crit": {}, "enc": {}, "zip": {},
+	"epk": {}, "apu": {}, "apv": {}, "iv": {}, "tag": {}, "p2s": {}, "p2c": {},
+}
+
+// Names returns the critical parameter names of the protected header.
+func Names(protected map[string]interface{}) ([]string, error) {
+	value, ok := protected[Header]
+	if !ok {
+		return nil, nil
+	}
+
+	var names []string
+
+	switch list := value.(type) {
+	case []string:
+		names = list
+	case []interface{}:
+		for _, v := range list {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %v is not a string", ErrInvalid, v)
+			}
+
+			names = append(names, name)
+		}
+	default:
+		return nil, fmt.Errorf("%w: not an array", ErrInvalid)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: empty array", ErrInvalid)
+	}
+
+	return names, nil
+}
+
+// Check returns the critical parameter names of the protected header, or error if the crit header is
+// invalid or lists a parameter not in handled.
+func Check(protected, unprotected map[string]interface{}, handled []string) ([]string, error) {
+	if _, ok := unprotected[Header]; ok {
+		return nil, fmt.Errorf("%w: must be integrity protected", ErrInvalid)
+	}
+
+	names, err := Names(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(protected, names); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if !contains(handled, name) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+		}
+	}
+
+	return names, nil
+}
+
+// Native returns true if every critical parameter is processed by go-jose (only b64 is).
+func Native(names []string) bool {
+	for _, name := range names {
+		if name != B64 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Mark returns a copy of the header with the names added to its crit parameter.
+// The header must contain the parameters, and they must not be registered ones.
+func Mark(header map[string]interface{}, names ...string) (map[string]interface{}, error) {
+	current, err := Names(header)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if !contains(current, name) {
+			current = append(current, name)
+		}
+	}
+
+	if err := validate(header, current); err != nil {
+		return nil, err
+	}
+
+	marked := make(map[string]interface{}, len(header)+1)
+
+	for k, v := range header {
+		marked[k] = v
+	}
+
+	if len(current) != 0 {
+		marked[Header] = current
+	}
+
+	return marked, nil
+}
+
+func validate(header map[string]interface{}, names []string) error {
+	for i, name := range names {
+		if _, ok := registered[name]; ok {
+			return fmt.Errorf("%w: %s is a registered header parameter", ErrInvalid, name)
+		}
+
+		if _, ok := header[name]; !ok {
+			return fmt.Errorf("%w: %s is not in the protected header", ErrInvalid, name)
+		}
+
+		if contains(names[:i], name) {
+			return fmt.Errorf("%w: duplicate %s", ErrInvalid, name)
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, name string) bool {
+	for _, s := range list {
+		if s == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package crit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"math/big"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+)
+
+var hashes = map[jose.SignatureAlgorithm]crypto.Hash{
+	jose.HS256: crypto.SHA256, jose.HS384: crypto.SHA384, jose.HS512: crypto.SHA512,
+	jose.RS256: crypto.SHA256, jose.RS384: crypto.SHA384, jose.RS512: crypto.SHA512,
+	jose.PS256: crypto.SHA256, jose.PS384: crypto.SHA384, jose.PS512: crypto.SHA512,
+	jose.ES256: crypto.SHA256, jose.ES384: crypto.SHA384, jose.ES512: crypto.SHA512,
+}
+
+var curveBits = map[jose.SignatureAlgorithm]int{jose.ES256: 256, jose.ES384: 384, jose.ES512: 521}
+
+// Verify verifies the signature of the signing input, go-jose refuses to verify JWS with crit extensions other than b64
+// and with Brainpool keys.
+// The key is the Key of a JSON Web Key (a public, private or symmetric key, or an opaque signer or verifier).
+func Verify(alg jose.SignatureAlgorithm, key interface{}, input, signature []byte) error {
+	hash := hashes[alg]
+
+	var ok bool
+
+	switch k := key.(type) {
+	case jose.OpaqueVerifier:
+		return k.VerifyPayload(input, signature, alg)
+	case jose.OpaqueSigner:
+		return Verify(alg, k.Public().Key, input, signature)
+	case []byte:
+		if alg == jose.HS256 || alg == jose.HS384 || alg == jose.HS512 {
+			mac := hmac.New(hash.New, k)
+			_, _ = mac.Write(input)
+
+			ok = hmac.Equal(mac.Sum(nil), signature)
+		}
+	case ed25519.PrivateKey:
+		return Verify(alg, k.Public(), input, signature)
+	case ed25519.PublicKey:
+		ok = alg == jose.EdDSA && ed25519.Verify(k, input, signature)
+	case *rsa.PrivateKey:
+		return Verify(alg, &k.PublicKey, input, signature)
+	case *rsa.PublicKey:
+		switch alg {
+		case jose.RS256, jose.RS384, jose.RS512:
+			ok = rsa.VerifyPKCS1v15(k, hash, digest(hash, input), signature) == nil
+		case jose.PS256, jose.PS384, jose.PS512:
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}
+			ok = rsa.VerifyPSS(k, hash, digest(hash, input), signature, opts) == nil
+		}
+	case *ecdsa.PrivateKey:
+		return Verify(alg, &k.PublicKey, input, signature)
+	case *ecdsa.PublicKey:
+		if _, brainpool := algorithm.BrainpoolCrv(k.Curve); brainpool {
+			ok = algorithm.VerifyBrainpool(k, alg, input, signature)
+		} else {
+			ok = ecdsaVerify(k, alg, hash, input, signature)
+		}
+	default:
+		return jose.ErrUnsupportedKeyType
+	}
+
+	if !ok {
+		return jose.ErrCryptoFailure
+	}
+
+	return nil
+}
+
+func ecdsaVerify(key *ecdsa.PublicKey, alg jose.SignatureAlgorithm, hash crypto.Hash, input, signature []byte) bool {
+	bits, ok := curveBits[alg]
+	if !ok || key.Curve.Params().BitSize != bits {
+		return false
+	}
+
+	size := (bits + 7) / 8
+	if len(signature) != 2*size {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	return ecdsa.Verify(key, digest(hash, input), r, s)
+}
+
+func digest(hash crypto.Hash, input []byte) []byte {
+	hasher := hash.New()
+	_, _ = hasher.Write(input)
+
+	return hasher.Sum(nil)
+}
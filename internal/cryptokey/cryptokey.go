@@ -0,0 +1,226 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package cryptokey converts the CryptoKey objects of the k6 WebCrypto API to JSON Web Keys.
+//
+// The key material of a CryptoKey is held by an unexported field, it is read with reflection,
+// WebCrypto offers only asynchronous export.
+package cryptokey
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+var ErrUnsupportedCryptoKey = errors.New("unsupported CryptoKey")
+
+// Is reports whether the value is the CryptoKey of the k6 WebCrypto API.
+func Is(value interface{}) bool {
+	return IsType(reflect.TypeOf(value))
+}
+
+// IsType reports whether the type is the pointer type of the k6 WebCrypto CryptoKey.
+func IsType(typ reflect.Type) bool {
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return false
+	}
+
+	elem := typ.Elem()
+
+	return elem.Kind() == reflect.Struct && elem.Name() == "CryptoKey" && strings.HasSuffix(elem.PkgPath(), "/webcrypto")
+}
+
+// Key returns the JSON Web Key of the CryptoKey. Non-extractable private keys are returned as opaque signer
+// or decrypter, they can be used but not exported. Non-extractable secret keys are not supported.
+func Key(value interface{}) (*jose.JSONWebKey, error) {
+	if !Is(value) {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedCryptoKey, value)
+	}
+
+	ck := reflect.ValueOf(value).Elem()
+
+	typeField, extractable, algorithm, field := ck.FieldByName("Type"), ck.FieldByName("Extractable"),
+		ck.FieldByName("Algorithm"), ck.FieldByName("handle")
+
+	// the layout of CryptoKey is checked by the tests with the k6 version of go.mod
+	if typeField.Kind() != reflect.String || extractable.Kind() != reflect.Bool || !algorithm.IsValid() ||
+		field.Kind() != reflect.Interface || !field.CanAddr() {
+		return nil, fmt.Errorf("%w: unknown CryptoKey layout of this k6 version", ErrUnsupportedCryptoKey)
+	}
+
+	typ := typeField.String()
+	if typ == "secret" && !extractable.Bool() {
+		return nil, fmt.Errorf("%w: non-extractable %s key", ErrUnsupportedCryptoKey, typ)
+	}
+
+	handle := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface()
+
+	alg, use, err := jwkAlgorithm(
+		text(member(algorithm, "Name")), text(member(algorithm, "NamedCurve")),
+		text(member(member(algorithm, "Hash"), "Name")), member(algorithm, "Length"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	material, err := keyMaterial(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jose.JSONWebKey{Key: material, Algorithm: alg, Use: use}
+
+	if _, symmetric := material.([]byte); !symmetric {
+		public := key.Public()
+
+		sum, err := public.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		key.KeyID = base64.RawURLEncoding.EncodeToString(sum)
+	}
+
+	if typ == "private" && !extractable.Bool() {
+		return opaqueKey(key)
+	}
+
+	return key, nil
+}
+
+// member returns the named (possibly promoted) field of the struct behind the value.
+// Hash algorithms may be given by plain name, the name of a string is the string itself.
+func member(value reflect.Value, name string) reflect.Value {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return value.FieldByName(name)
+	case reflect.String:
+		if name == "Name" {
+			return value
+		}
+	}
+
+	return reflect.Value{}
+}
+
+func text(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+
+	return ""
+}
+
+// jwkAlgorithm maps the WebCrypto algorithm to JWA algorithm and key use.
+func jwkAlgorithm(name, curve, hash string, length reflect.Value) (string, string, error) {
+	bits := strings.TrimPrefix(hash, "SHA-")
+
+	var size int64
+	if length.CanInt() {
+		size = length.Int()
+	}
+
+	switch {
+	case name == "ECDSA":
+		if alg, ok := curveAlgorithms[curve]; ok {
+			return alg, "sig", nil
+		}
+	case name == "ECDH":
+		return string(jose.ECDH_ES), "enc", nil
+	case name == "RSASSA-PKCS1-v1_5" && bits != "1":
+		return "RS" + bits, "sig", nil
+	case name == "RSA-PSS" && bits != "1":
+		return "PS" + bits, "sig", nil
+	case name == "RSA-OAEP" && bits == "1":
+		return string(jose.RSA_OAEP), "enc", nil
+	case name == "RSA-OAEP" && bits == "256":
+		return string(jose.RSA_OAEP_256), "enc", nil
+	case name == "HMAC" && bits != "1":
+		return "HS" + bits, "sig", nil
+	case name == "AES-KW" && size > 0:
+		return fmt.Sprintf("A%dKW", size), "enc", nil
+	case name == "AES-GCM" && size > 0:
+		return fmt.Sprintf("A%dGCMKW", size), "enc", nil
+	}
+
+	return "", "", fmt.Errorf("%w: %s algorithm", ErrUnsupportedCryptoKey, strings.TrimSpace(name+" "+curve+" "+hash))
+}
+
+var curveAlgorithms = map[string]string{
+	"P-256": string(jose.ES256),
+	"P-384": string(jose.ES384),
+	"P-521": string(jose.ES512),
+}
+
+// keyMaterial returns the key handle in the form go-jose expects, ECDH keys of NIST curves are converted to
+// ECDSA keys, others (X25519) are rejected.
+func keyMaterial(handle interface{}) (interface{}, error) {
+	switch key := handle.(type) {
+	case *ecdsa.PrivateKey, *ecdsa.PublicKey, *rsa.PrivateKey, *rsa.PublicKey:
+		return key, nil
+	case []byte:
+		return append([]byte(nil), key...), nil
+	case *ecdh.PrivateKey:
+		if !nistCurve(key.Curve()) {
+			return nil, fmt.Errorf("%w: ECDH key of %s curve", ErrUnsupportedCryptoKey, key.Curve())
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedCryptoKey, err.Error())
+		}
+
+		return x509.ParsePKCS8PrivateKey(der)
+	case *ecdh.PublicKey:
+		if !nistCurve(key.Curve()) {
+			return nil, fmt.Errorf("%w: ECDH key of %s curve", ErrUnsupportedCryptoKey, key.Curve())
+		}
+
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedCryptoKey, err.Error())
+		}
+
+		return x509.ParsePKIXPublicKey(der)
+	}
+
+	return nil, fmt.Errorf("%w: %T key handle", ErrUnsupportedCryptoKey, handle)
+}
+
+func nistCurve(curve ecdh.Curve) bool {
+	return curve == ecdh.P256() || curve == ecdh.P384() || curve == ecdh.P521()
+}
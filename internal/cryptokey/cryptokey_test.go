@@ -0,0 +1,57 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cryptokey
+
+import (
+	"runtime/debug"
+	"testing"
+
+	_ "go.k6.io/k6/js/modules" // links k6, so its version is in the build info
+)
+
+// checkedK6Version is the k6 version whose webcrypto CryptoKey layout (the unexported handle field) was checked.
+// Check the CryptoKey type of the new k6 version before updating it.
+const checkedK6Version = "v1.8.1"
+
+func TestK6Version(t *testing.T) {
+	t.Parallel()
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		t.Skip("no build info")
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != "go.k6.io/k6" {
+			continue
+		}
+
+		if dep.Version != checkedK6Version {
+			t.Fatalf("CryptoKey layout was checked with k6 %s, got %s", checkedK6Version, dep.Version)
+		}
+
+		return
+	}
+
+	t.Fatal("go.k6.io/k6 is not in the build info")
+}
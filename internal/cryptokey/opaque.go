@@ -0,0 +1,140 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cryptokey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// opaqueKey wraps the private key of non-extractable CryptoKey as go-jose opaque signer or key decrypter,
+// so it can sign or decrypt, but it can't be exported.
+func opaqueKey(key *jose.JSONWebKey) (*jose.JSONWebKey, error) {
+	public := key.Public()
+
+	var opaque interface{}
+
+	switch priv := key.Key.(type) {
+	case *ecdsa.PrivateKey:
+		if key.Use == "sig" {
+			opaque = &signer{key: priv, public: &public, alg: jose.SignatureAlgorithm(key.Algorithm)}
+		}
+	case *rsa.PrivateKey:
+		if key.Use == "sig" {
+			opaque = &signer{key: priv, public: &public, alg: jose.SignatureAlgorithm(key.Algorithm)}
+		} else if strings.HasPrefix(key.Algorithm, string(jose.RSA_OAEP)) {
+			opaque = &decrypter{key: priv, alg: jose.KeyAlgorithm(key.Algorithm)}
+		}
+	}
+
+	if opaque == nil {
+		return nil, fmt.Errorf("%w: non-extractable %s private key", ErrUnsupportedCryptoKey, key.Algorithm)
+	}
+
+	return &jose.JSONWebKey{Key: opaque, KeyID: key.KeyID, Algorithm: key.Algorithm, Use: key.Use}, nil
+}
+
+// signer is the opaque signer of non-extractable ECDSA and RSA signing keys.
+type signer struct {
+	key    crypto.Signer
+	public *jose.JSONWebKey
+	alg    jose.SignatureAlgorithm
+}
+
+func (s *signer) Public() *jose.JSONWebKey {
+	return s.public
+}
+
+func (s *signer) Algs() []jose.SignatureAlgorithm {
+	return []jose.SignatureAlgorithm{s.alg}
+}
+
+func (s *signer) SignPayload(payload []byte, alg jose.SignatureAlgorithm) ([]byte, error) {
+	if alg != s.alg {
+		return nil, fmt.Errorf("%w: %s, the key signs with %s", ErrUnsupportedCryptoKey, alg, s.alg)
+	}
+
+	hash := crypto.SHA256
+
+	switch {
+	case strings.HasSuffix(string(alg), "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(string(alg), "512"):
+		hash = crypto.SHA512
+	}
+
+	h := hash.New()
+	h.Write(payload)
+	digest := h.Sum(nil)
+
+	switch key := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		r, sv, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+
+		size := (key.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+
+		r.FillBytes(sig[:size])
+		sv.FillBytes(sig[size:])
+
+		return sig, nil
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(string(alg), "PS") {
+			return rsa.SignPSS(rand.Reader, key, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+
+		return rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedCryptoKey, s.key)
+	}
+}
+
+// decrypter is the opaque key decrypter of non-extractable RSA-OAEP keys.
+type decrypter struct {
+	key *rsa.PrivateKey
+	alg jose.KeyAlgorithm
+}
+
+func (d *decrypter) DecryptKey(encryptedKey []byte, header jose.Header) ([]byte, error) {
+	if jose.KeyAlgorithm(header.Algorithm) != d.alg {
+		return nil, fmt.Errorf("%w: %s, the key decrypts %s", ErrUnsupportedCryptoKey, header.Algorithm, d.alg)
+	}
+
+	hash := sha1.New()
+	if d.alg == jose.RSA_OAEP_256 {
+		hash = sha256.New()
+	}
+
+	return rsa.DecryptOAEP(hash, rand.Reader, d.key, encryptedKey, nil)
+}
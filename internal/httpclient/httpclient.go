@@ -0,0 +1,68 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package httpclient creates the HTTP clients of the modules fetching documents and calling services.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.k6.io/k6/js/modules"
+)
+
+var ErrTooLarge = errors.New("response too large")
+
+// New returns HTTP client with the timeout and the context of the request, requests of VU code
+// go through the transport of k6. The context is the background context in the init code.
+func New(vu modules.VU, timeout time.Duration) (*http.Client, context.Context) {
+	client := &http.Client{Timeout: timeout}
+
+	if state := vu.State(); state != nil {
+		client.Transport = state.Transport
+	}
+
+	ctx := vu.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return client, ctx
+}
+
+// ReadBody reads the body up to maxSize bytes, longer bodies return ErrTooLarge instead of being truncated.
+func ReadBody(body io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrTooLarge, maxSize)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,331 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package jserror gives the errors thrown to JS a stable name and code.
+package jserror
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/acme"
+	"github.com/szkiba/xk6-jose/cose"
+	"github.com/szkiba/xk6-jose/dpop"
+	"github.com/szkiba/xk6-jose/httpsig"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/async"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/cryptokey"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwe"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jws"
+	"github.com/szkiba/xk6-jose/jwt"
+	"github.com/szkiba/xk6-jose/kms"
+	"github.com/szkiba/xk6-jose/oauth"
+	"github.com/szkiba/xk6-jose/oidc"
+	"github.com/szkiba/xk6-jose/passport"
+	"github.com/szkiba/xk6-jose/registry"
+	"github.com/szkiba/xk6-jose/sdjwt"
+	"github.com/szkiba/xk6-jose/tamper"
+	"github.com/szkiba/xk6-jose/vc"
+	"github.com/szkiba/xk6-jose/webpush"
+	"go.k6.io/k6/js/modules"
+)
+
+// Class is the name and the code of the JS error.
+type Class struct {
+	Name string
+	Code string
+}
+
+// Error classes, names and codes are part of the API: never change them, add new ones instead.
+var (
+	JOSEError                  = Class{"JOSEError", "ERR_JOSE"}
+	InvalidArgumentError       = Class{"InvalidArgumentError", "ERR_JOSE_INVALID_ARGUMENT"}
+	MalformedError             = Class{"MalformedError", "ERR_JOSE_MALFORMED"}
+	UnsupportedAlgorithmError  = Class{"UnsupportedAlgorithmError", "ERR_JOSE_ALG_UNSUPPORTED"}
+	AlgorithmNotAllowedError   = Class{"AlgorithmNotAllowedError", "ERR_JOSE_ALG_NOT_ALLOWED"}
+	UnsupportedKeyError        = Class{"UnsupportedKeyError", "ERR_JOSE_KEY_UNSUPPORTED"}
+	InvalidKeyError            = Class{"InvalidKeyError", "ERR_JOSE_KEY_INVALID"}
+	KeyNotFoundError           = Class{"KeyNotFoundError", "ERR_JOSE_KID_UNKNOWN"}
+	KeyPoolExhaustedError      = Class{"KeyPoolExhaustedError", "ERR_JOSE_POOL_EXHAUSTED"}
+	SignatureVerificationError = Class{"SignatureVerificationError", "ERR_JOSE_SIGNATURE_INVALID"}
+	DecryptionError            = Class{"DecryptionError", "ERR_JWE_DECRYPTION_FAILED"}
+	JWTExpiredError            = Class{"JWTExpiredError", "ERR_JWT_EXPIRED"}
+	JWTNotYetValidError        = Class{"JWTNotYetValidError", "ERR_JWT_NOT_YET_VALID"}
+	JWTClaimValidationError    = Class{"JWTClaimValidationError", "ERR_JWT_CLAIM_INVALID"}
+	DiscoveryError             = Class{"DiscoveryError", "ERR_JOSE_DISCOVERY_FAILED"}
+	RemoteSignerError          = Class{"RemoteSignerError", "ERR_JOSE_REMOTE_SIGNER"}
+	CriticalHeaderError        = Class{"CriticalHeaderError", "ERR_JOSE_CRIT_UNSUPPORTED"}
+	LimitExceededError         = Class{"LimitExceededError", "ERR_JOSE_LIMIT_EXCEEDED"}
+	CertificateChainError      = Class{"CertificateChainError", "ERR_JOSE_CHAIN_INVALID"}
+	KeyFetchError              = Class{"KeyFetchError", "ERR_JOSE_FETCH_FAILED"}
+)
+
+// classes of the errors, the first matching one is used.
+var classes = []struct {
+	err   error
+	class Class
+}{
+	{jwk.ErrUnknownKeyID, KeyNotFoundError},
+	{oidc.ErrNoKeys, KeyNotFoundError},
+	{jwk.ErrPoolExhausted, KeyPoolExhaustedError},
+
+	{jose.ErrCryptoFailure, SignatureVerificationError},
+	{cose.ErrVerification, SignatureVerificationError},
+	{sdjwt.ErrKeyBinding, SignatureVerificationError},
+	{httpsig.ErrDigestMismatch, SignatureVerificationError},
+	{httpsig.ErrNotCovered, SignatureVerificationError},
+
+	{jwe.ErrDecryption, DecryptionError},
+	{jwe.ErrAADMismatch, DecryptionError},
+
+	{josejwt.ErrExpired, JWTExpiredError},
+	{httpsig.ErrExpired, JWTExpiredError},
+	{josejwt.ErrNotValidYet, JWTNotYetValidError},
+	{josejwt.ErrIssuedInTheFuture, JWTNotYetValidError},
+	{josejwt.ErrInvalidIssuer, JWTClaimValidationError},
+	{josejwt.ErrInvalidSubject, JWTClaimValidationError},
+	{josejwt.ErrInvalidAudience, JWTClaimValidationError},
+	{josejwt.ErrInvalidID, JWTClaimValidationError},
+	{jwt.ErrMissingClaim, JWTClaimValidationError},
+	{oauth.ErrInvalidClaim, JWTClaimValidationError},
+	{oauth.ErrMissingBinding, JWTClaimValidationError},
+	{oauth.ErrBindingMismatch, JWTClaimValidationError},
+	{oauth.ErrInvalidDelegation, JWTClaimValidationError},
+	{oauth.ErrInvalidIntrospection, JWTClaimValidationError},
+	{oauth.ErrInvalidResponse, JWTClaimValidationError},
+	{oauth.ErrErrorResponse, JWTClaimValidationError},
+	{oidc.ErrInvalidSignedJWKS, JWTClaimValidationError},
+	{oidc.ErrInvalidEntityStatement, JWTClaimValidationError},
+	{oidc.ErrInvalidServiceAccountToken, JWTClaimValidationError},
+	{sdjwt.ErrInvalidDisclosure, JWTClaimValidationError},
+	{vc.ErrInvalidCredential, JWTClaimValidationError},
+	{passport.ErrInvalidPASSporT, JWTClaimValidationError},
+	{cose.ErrInvalidClaims, JWTClaimValidationError},
+
+	{jwe.ErrAlgorithmNotAllowed, AlgorithmNotAllowedError},
+
+	{config.ErrLimitExceeded, LimitExceededError},
+
+	{jwk.ErrInvalidChain, CertificateChainError},
+
+	{crit.ErrUnsupported, CriticalHeaderError},
+	{jose.ErrUnsupportedCriticalHeader, CriticalHeaderError},
+
+	{jose.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+	{jwk.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+	{jws.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+	{jwe.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+	{cose.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+	{oidc.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+	{sdjwt.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+	{httpsig.ErrUnsupportedAlgorithm, UnsupportedAlgorithmError},
+
+	{jose.ErrUnsupportedKeyType, UnsupportedKeyError},
+	{jose.ErrInvalidKeySize, UnsupportedKeyError},
+	{jwk.ErrUnsupportedKey, UnsupportedKeyError},
+	{cryptokey.ErrUnsupportedCryptoKey, UnsupportedKeyError},
+	{tamper.ErrUnsupportedEncoding, UnsupportedKeyError},
+
+	{jwk.ErrInvalidPEM, InvalidKeyError},
+	{jwk.ErrInvalidSerialization, InvalidKeyError},
+	{jwk.ErrInvalidComponents, InvalidKeyError},
+	{registry.ErrInvalidCertificate, InvalidKeyError},
+	{oauth.ErrInvalidCredentials, InvalidKeyError},
+
+	{jws.ErrMalformed, MalformedError},
+	{crit.ErrInvalid, MalformedError},
+	{config.ErrInvalidEncoding, MalformedError},
+	{config.ErrDuplicateMember, MalformedError},
+	{jws.ErrInvalidHeader, MalformedError},
+	{jwe.ErrMalformed, MalformedError},
+	{jwt.ErrInvalidNesting, MalformedError},
+	{cose.ErrMalformed, MalformedError},
+	{cose.ErrInvalidMessage, MalformedError},
+	{sdjwt.ErrMalformed, MalformedError},
+	{tamper.ErrInvalidToken, MalformedError},
+	{httpsig.ErrMalformed, MalformedError},
+	{josejwt.ErrInvalidClaims, MalformedError},
+	{josejwt.ErrUnmarshalAudience, MalformedError},
+	{josejwt.ErrUnmarshalNumericDate, MalformedError},
+	{josejwt.ErrInvalidContentType, MalformedError},
+
+	{jsmodule.ErrNullKey, InvalidArgumentError},
+	{async.ErrEventLoop, InvalidArgumentError},
+	{buffer.ErrInvalidValue, InvalidArgumentError},
+	{buffer.ErrInvalidCertificate, InvalidKeyError},
+	{algorithm.ErrInvalidSaltLength, InvalidArgumentError},
+	{jws.ErrInvalidPayload, InvalidArgumentError},
+	{jws.ErrUnsupportedSerialization, InvalidArgumentError},
+	{jwe.ErrUnsupportedSerialization, InvalidArgumentError},
+	{jose.ErrNotSupported, InvalidArgumentError},
+	{jose.ErrUnprotectedNonce, InvalidArgumentError},
+	{jwt.ErrInvalidDuration, InvalidArgumentError},
+	{jwt.ErrInvalidTime, InvalidArgumentError},
+	{jwt.ErrUnknownTemplate, InvalidArgumentError},
+	{jwt.ErrInvalidSchema, InvalidArgumentError},
+	{jwt.ErrInvalidSelector, InvalidArgumentError},
+	{jwk.ErrInvalidCount, InvalidArgumentError},
+	{jwk.ErrPoolMismatch, InvalidArgumentError},
+	{jwk.ErrInvalidGenerator, InvalidArgumentError},
+	{jwk.ErrInitContext, InvalidArgumentError},
+	{jwk.ErrMissingPath, InvalidArgumentError},
+	{jwk.ErrInvalidSource, InvalidArgumentError},
+	{jwk.ErrMissingKey, InvalidArgumentError},
+	{jwk.ErrInvalidSeed, InvalidArgumentError},
+	{jwk.ErrInvalidName, InvalidArgumentError},
+	{kms.ErrMissingCredentials, InvalidArgumentError},
+	{kms.ErrMissingKey, InvalidArgumentError},
+	{kms.ErrInvalidSigner, InvalidArgumentError},
+	{config.ErrInvalidConfig, InvalidArgumentError},
+	{sdjwt.ErrInvalidPath, InvalidArgumentError},
+	{oauth.ErrInvalidLifetime, InvalidArgumentError},
+	{oauth.ErrInvalidUID, InvalidArgumentError},
+	{oauth.ErrMissingParameter, InvalidArgumentError},
+	{oidc.ErrMissingSubject, InvalidArgumentError},
+	{oidc.ErrMissingIssuer, InvalidArgumentError},
+	{oidc.ErrInvalidBinding, InvalidArgumentError},
+	{oidc.ErrUnknownIssuer, InvalidArgumentError},
+	{oidc.ErrInvalidMockOptions, InvalidArgumentError},
+	{acme.ErrMissingNonce, InvalidArgumentError},
+	{dpop.ErrInvalidURL, InvalidArgumentError},
+	{webpush.ErrInvalidURL, InvalidArgumentError},
+	{webpush.ErrInvalidLifetime, InvalidArgumentError},
+	{httpsig.ErrInvalidComponent, InvalidArgumentError},
+	{registry.ErrInvalidScope, InvalidArgumentError},
+	{tamper.ErrUnknownKind, InvalidArgumentError},
+	{tamper.ErrInvalidSize, InvalidArgumentError},
+
+	{oidc.ErrDiscovery, DiscoveryError},
+	{kms.ErrRemoteSigner, RemoteSignerError},
+	{jwk.ErrFetch, KeyFetchError},
+
+	{jws.ErrFinished, JOSEError},
+}
+
+// goJOSEPrefix is the prefix of the (not exported) errors of go-jose, like invalid serialization.
+const goJOSEPrefix = "go-jose/go-jose"
+
+// Classify returns the class of the error, false if it is not an error of the extension.
+func Classify(err error) (Class, bool) {
+	for _, c := range classes {
+		if errors.Is(err, c.err) {
+			return c.class, true
+		}
+	}
+
+	var unexpected *jose.ErrUnexpectedSignatureAlgorithm
+	if errors.As(err, &unexpected) {
+		return UnsupportedAlgorithmError, true
+	}
+
+	if strings.HasPrefix(err.Error(), goJOSEPrefix) {
+		return JOSEError, true
+	}
+
+	return Class{}, false
+}
+
+type module struct {
+	modules.Module
+}
+
+// Module installs the error names and codes in the runtime of every VU importing the module.
+func Module(m modules.Module) modules.Module {
+	return &module{Module: m}
+}
+
+func (m *module) NewModuleInstance(vu modules.VU) modules.Instance {
+	install(vu.Runtime())
+
+	return m.Module.NewModuleInstance(vu)
+}
+
+// install defines name and code of the GoError prototype, k6 throws returned Go errors as GoError.
+// Errors not belonging to the extension keep the original name and have no code.
+func install(rt *sobek.Runtime) {
+	proto := rt.NewGoError(errors.New("")).Prototype()
+	if proto == nil || proto.Get("code") != nil {
+		return
+	}
+
+	original := proto.Get("name")
+
+	classOf := func(this sobek.Value) (Class, bool) {
+		obj, ok := this.(*sobek.Object)
+		if !ok {
+			return Class{}, false
+		}
+
+		value := obj.Get("value")
+		if value == nil {
+			return Class{}, false
+		}
+
+		err, ok := value.Export().(error)
+		if !ok {
+			return Class{}, false
+		}
+
+		return Classify(err)
+	}
+
+	name := func(call sobek.FunctionCall) sobek.Value {
+		if class, ok := classOf(call.This); ok {
+			return rt.ToValue(class.Name)
+		}
+
+		return original
+	}
+
+	code := func(call sobek.FunctionCall) sobek.Value {
+		if class, ok := classOf(call.This); ok {
+			return rt.ToValue(class.Code)
+		}
+
+		return sobek.Undefined()
+	}
+
+	// assigning an error property shadows the accessor, like for ordinary data properties
+	setter := func(prop string) func(call sobek.FunctionCall) sobek.Value {
+		return func(call sobek.FunctionCall) sobek.Value {
+			if obj, ok := call.This.(*sobek.Object); ok {
+				_ = obj.DefineDataProperty(prop, call.Argument(0), sobek.FLAG_TRUE, sobek.FLAG_TRUE, sobek.FLAG_TRUE)
+			}
+
+			return sobek.Undefined()
+		}
+	}
+
+	for prop, getter := range map[string]func(sobek.FunctionCall) sobek.Value{"name": name, "code": code} {
+		get, set := rt.ToValue(getter), rt.ToValue(setter(prop))
+
+		_ = proto.DefineAccessorProperty(prop, get, set, sobek.FLAG_TRUE, sobek.FLAG_FALSE)
+	}
+}
@@ -0,0 +1,238 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package jsmodule implements the k6 module API for the JS facing module objects.
+package jsmodule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/cryptokey"
+	"github.com/szkiba/xk6-jose/internal/redact"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+)
+
+// ErrNullKey is thrown when a key argument is null or undefined.
+var ErrNullKey = errors.New("key is null or undefined")
+
+// OptionalKeys is implemented by the module objects having functions which accept null as some of their key
+// arguments, like the holder key of presentations without key binding.
+type OptionalKeys interface {
+	// OptionalKeys returns the indexes of the optional key arguments by JS function name.
+	OptionalKeys() map[string][]int
+}
+
+const optionalKeysName = "optionalKeys"
+
+// Resolver is implemented by the module objects having configuration, Resolve is called before their functions
+// to apply the options of the test (see config.Config.Resolve).
+type Resolver interface {
+	Resolve() error
+}
+
+const resolveName = "resolve"
+
+var keyType = reflect.TypeOf((*jose.JSONWebKey)(nil))
+
+// Root is the global module, creating the module object of every VU.
+type Root struct {
+	create func(vu modules.VU) interface{}
+}
+
+// New returns the global module, create is called with each VU importing the module.
+func New(create func(vu modules.VU) interface{}) *Root {
+	return &Root{create: create}
+}
+
+func (r *Root) NewModuleInstance(vu modules.VU) modules.Instance {
+	redact.Install(vu.Runtime())
+
+	return &instance{vu: vu, module: r.create(vu)}
+}
+
+type instance struct {
+	vu     modules.VU
+	module interface{}
+}
+
+// Exports the module object as default export, its methods are named exports too.
+func (i *instance) Exports() modules.Exports {
+	return modules.Exports{Default: Exports(i.vu.Runtime(), i.module)}
+}
+
+// Exports returns a copy of the module object whose functions accept WebCrypto CryptoKey arguments as keys,
+// and mask key material in the message of thrown errors.
+// Arguments are converted by the JS runtime, conversion errors quote the offending argument.
+// Null or undefined key arguments are rejected before the call, the Go functions get a nil key otherwise.
+func Exports(rt *sobek.Runtime, module interface{}) *sobek.Object {
+	src := rt.ToValue(module).ToObject(rt)
+	exports := rt.NewObject()
+	keys := keyArguments(module)
+	resolver, _ := module.(Resolver)
+
+	for _, name := range src.Keys() {
+		if name == optionalKeysName || name == resolveName {
+			continue
+		}
+
+		value := src.Get(name)
+
+		if fn, ok := sobek.AssertFunction(value); ok {
+			value = rt.ToValue(wrap(rt, name, keys[name], resolver, redact.Guard(fn)))
+		}
+
+		_ = exports.Set(name, value)
+	}
+
+	return exports
+}
+
+func wrap(
+	rt *sobek.Runtime,
+	name string,
+	keys []int,
+	resolver Resolver,
+	fn sobek.Callable,
+) func(sobek.FunctionCall) sobek.Value {
+	return func(call sobek.FunctionCall) sobek.Value {
+		if resolver != nil {
+			if err := resolver.Resolve(); err != nil {
+				common.Throw(rt, err)
+			}
+		}
+
+		for _, i := range keys {
+			if arg := call.Argument(i); sobek.IsUndefined(arg) || sobek.IsNull(arg) {
+				common.Throw(rt, fmt.Errorf("%w: argument %d of %s", ErrNullKey, i+1, name))
+			}
+		}
+
+		ret, err := fn(call.This, arguments(rt, call.Arguments)...)
+		if err != nil {
+			panic(err)
+		}
+
+		return ret
+	}
+}
+
+// keyArguments returns the indexes of the required key arguments of the module functions by JS function name.
+func keyArguments(module interface{}) map[string][]int {
+	var optional map[string][]int
+
+	if o, ok := module.(OptionalKeys); ok {
+		optional = o.OptionalKeys()
+	}
+
+	typ := reflect.TypeOf(module)
+	keys := make(map[string][]int)
+
+	for i := range typ.NumMethod() {
+		method := typ.Method(i)
+		name := common.MethodName(typ, method)
+
+		// the first input is the receiver
+		for in := 1; in < method.Type.NumIn(); in++ {
+			if method.Type.In(in) == keyType && !slices.Contains(optional[name], in-1) {
+				keys[name] = append(keys[name], in-1)
+			}
+		}
+	}
+
+	return keys
+}
+
+// arguments replaces CryptoKey arguments, and CryptoKey elements of array arguments with their JSON Web Key.
+func arguments(rt *sobek.Runtime, args []sobek.Value) []sobek.Value {
+	var converted []sobek.Value
+
+	for i, arg := range args {
+		value, ok := convert(rt, arg)
+		if !ok {
+			continue
+		}
+
+		if converted == nil {
+			converted = append([]sobek.Value(nil), args...)
+		}
+
+		converted[i] = value
+	}
+
+	if converted == nil {
+		return args
+	}
+
+	return converted
+}
+
+func convert(rt *sobek.Runtime, value sobek.Value) (sobek.Value, bool) {
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		return nil, false
+	}
+
+	if cryptokey.IsType(obj.ExportType()) {
+		key, err := cryptokey.Key(obj.Export())
+		if err != nil {
+			common.Throw(rt, err)
+		}
+
+		return rt.ToValue(key), true
+	}
+
+	if obj.ClassName() != "Array" {
+		return nil, false
+	}
+
+	length := obj.Get("length").ToInteger()
+	elems := make([]interface{}, length)
+	found := false
+
+	for i := range elems {
+		elem := obj.Get(strconv.Itoa(i))
+
+		// key arrays hold objects only, there is no need to scan payloads
+		if _, isObject := elem.(*sobek.Object); !isObject {
+			return nil, false
+		}
+
+		if key, ok := convert(rt, elem); ok {
+			elem, found = key, true
+		}
+
+		elems[i] = elem
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	return rt.NewArray(elems...), true
+}
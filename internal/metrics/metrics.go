@@ -0,0 +1,156 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package metrics emits k6 metrics of the JOSE operations.
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+// Operations, the duration of each is measured with its own trend metric.
+const (
+	Sign    = "sign"
+	Verify  = "verify"
+	Encrypt = "encrypt"
+	Decrypt = "decrypt"
+)
+
+// Metrics emits the samples of the VU.
+type Metrics struct {
+	vu             modules.VU
+	durations      map[string]*k6metrics.Metric
+	operations     *k6metrics.Metric
+	failures       *k6metrics.Metric
+	verifyFailures *k6metrics.Metric
+}
+
+// New registers the metrics in the init context of the VU, every VU gets the same (already registered) metrics.
+func New(vu modules.VU) *Metrics {
+	registry := vu.InitEnv().Registry
+
+	return &Metrics{
+		vu: vu,
+		durations: map[string]*k6metrics.Metric{
+			Sign:    registry.MustNewMetric("jose_sign_duration", k6metrics.Trend, k6metrics.Time),
+			Verify:  registry.MustNewMetric("jose_verify_duration", k6metrics.Trend, k6metrics.Time),
+			Encrypt: registry.MustNewMetric("jose_encrypt_duration", k6metrics.Trend, k6metrics.Time),
+			Decrypt: registry.MustNewMetric("jose_decrypt_duration", k6metrics.Trend, k6metrics.Time),
+		},
+		operations:     registry.MustNewMetric("jose_operations", k6metrics.Counter),
+		failures:       registry.MustNewMetric("jose_failures", k6metrics.Counter),
+		verifyFailures: registry.MustNewMetric("jose_verify_failures", k6metrics.Counter),
+	}
+}
+
+// reasons of verification failures, the first matching one is used.
+var reasons = []struct {
+	err    error
+	reason string
+}{
+	{jwk.ErrUnknownKeyID, "unknown-kid"},
+	{jose.ErrCryptoFailure, "bad-signature"},
+	{jwk.ErrInvalidChain, "untrusted-chain"},
+	{jwt.ErrExpired, "expired"},
+	{jwt.ErrNotValidYet, "not-yet-valid"},
+	{jwt.ErrIssuedInTheFuture, "not-yet-valid"},
+	{jwt.ErrInvalidAudience, "wrong-audience"},
+	{jwt.ErrInvalidIssuer, "wrong-issuer"},
+	{jwt.ErrInvalidSubject, "wrong-subject"},
+	{config.ErrAlgorithmNotAllowed, "alg-not-allowed"},
+}
+
+// Reason returns the reason of the verification failure, "invalid" for malformed tokens, unsupported keys, etc.
+func Reason(err error) string {
+	for _, r := range reasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+
+	return "invalid"
+}
+
+// unknownAlgorithm is the alg tag of algorithms not supported by the extension.
+const unknownAlgorithm = "unknown"
+
+// algTag returns the alg tag value: the alg header of failed tokens is not verified,
+// tampered or fuzzed tokens must not create new metric series.
+func algTag(alg string) string {
+	if algorithm.Known(alg) {
+		return alg
+	}
+
+	return unknownAlgorithm
+}
+
+// Measure emits duration, operation and failure samples of the operation started at start.
+// Operations outside of VU code (init context) are not measured.
+func (m *Metrics) Measure(module, operation, alg string, start time.Time, err error) {
+	state := m.vu.State()
+	if state == nil {
+		return
+	}
+
+	now := time.Now()
+	ctm := state.Tags.GetCurrentValues()
+
+	tags := ctm.Tags.With("module", module)
+	if alg != "" {
+		tags = tags.With("alg", algTag(alg))
+	}
+
+	sample := func(metric *k6metrics.Metric, tags *k6metrics.TagSet, value float64) k6metrics.Sample {
+		return k6metrics.Sample{
+			TimeSeries: k6metrics.TimeSeries{Metric: metric, Tags: tags},
+			Time:       now,
+			Metadata:   ctm.Metadata,
+			Value:      value,
+		}
+	}
+
+	opTags := tags.With("operation", operation)
+
+	samples := k6metrics.Samples{
+		sample(m.durations[operation], opTags, k6metrics.D(now.Sub(start))),
+		sample(m.operations, opTags, 1),
+	}
+
+	if err != nil {
+		samples = append(samples, sample(m.failures, opTags, 1))
+	}
+
+	if err != nil && operation == Verify {
+		samples = append(samples, sample(m.verifyFailures, tags.With("reason", Reason(err)), 1))
+	}
+
+	k6metrics.PushIfNotDone(m.vu.Context(), state.Samples, samples)
+}
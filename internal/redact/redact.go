@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package redact keeps private key material out of error messages and the JS representation of Go values.
+package redact
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"reflect"
+	"regexp"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+// Replacement is the text standing in for redacted content.
+const Replacement = "[REDACTED]"
+
+var (
+	// pemBlock matches PEM blocks, with real or escaped newlines, truncated ones too.
+	pemBlock = regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]+-----.*?(-----END [A-Z0-9 ]+-----|$)`)
+
+	// privateMember matches the private (and symmetric) members of a JSON serialized JWK.
+	privateMember = regexp.MustCompile(`(\\?"(?:d|p|q|dp|dq|qi|k)\\?"\s*:\s*)\\?"[^"\\]*\\?"`)
+)
+
+// String masks PEM blocks and private JWK members in s.
+func String(s string) string {
+	s = pemBlock.ReplaceAllLiteralString(s, Replacement)
+
+	return privateMember.ReplaceAllString(s, `$1"`+Replacement+`"`)
+}
+
+// Guard returns fn masking key material in the message of thrown errors.
+func Guard(fn sobek.Callable) sobek.Callable {
+	return func(this sobek.Value, args ...sobek.Value) (sobek.Value, error) {
+		ret, err := fn(this, args...)
+		if err == nil {
+			return ret, nil
+		}
+
+		var exception *sobek.Exception
+
+		if errors.As(err, &exception) {
+			if obj, ok := exception.Value().(*sobek.Object); ok {
+				if message := obj.Get("message"); message != nil {
+					if masked := String(message.String()); masked != message.String() {
+						_ = obj.Set("message", masked)
+					}
+				}
+			}
+		}
+
+		return nil, err
+	}
+}
+
+// hidden lists the struct fields holding private key material, per struct type.
+var hidden = map[reflect.Type]map[string]bool{
+	reflect.TypeOf(jose.JSONWebKey{}):  {"Key": true},
+	reflect.TypeOf(ecdsa.PrivateKey{}): {"D": true},
+	reflect.TypeOf(rsa.PrivateKey{}):   {"D": true, "Primes": true, "Precomputed": true},
+}
+
+// Install hides the hidden fields from the reflection based JS binding of rt,
+// so console.log and property enumeration never reach private key material.
+func Install(rt *sobek.Runtime) {
+	rt.SetFieldNameMapper(fieldNameMapper{})
+}
+
+type fieldNameMapper struct {
+	common.FieldNameMapper
+}
+
+func (m fieldNameMapper) FieldName(t reflect.Type, f reflect.StructField) string {
+	if hidden[t][f.Name] {
+		return ""
+	}
+
+	return m.FieldNameMapper.FieldName(t, f)
+}
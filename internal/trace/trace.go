@@ -0,0 +1,308 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package trace logs the details of failed operations in debug mode, claim values and key material are redacted.
+package trace
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+	"github.com/sirupsen/logrus"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/internal/redact"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
+)
+
+// Tracer logs the failed operations of the VU while the Debug option of the configuration is set.
+type Tracer struct {
+	vu     modules.VU
+	config *config.Config
+}
+
+func New(vu modules.VU, cfg *config.Config) *Tracer {
+	return &Tracer{vu: vu, config: cfg}
+}
+
+// stages of the failures, the first matching one is used.
+var stages = []struct {
+	err   error
+	stage string
+}{
+	{config.ErrLimitExceeded, "limits"},
+	{config.ErrInvalidEncoding, "encoding"},
+	{config.ErrDuplicateMember, "encoding"},
+	{crit.ErrUnsupported, "critical-header"},
+	{crit.ErrInvalid, "critical-header"},
+	{jose.ErrUnsupportedCriticalHeader, "critical-header"},
+	{config.ErrAlgorithmNotAllowed, "algorithm-policy"},
+	{jose.ErrUnsupportedAlgorithm, "algorithm"},
+	{jwk.ErrUnsupportedAlgorithm, "algorithm"},
+	{jwk.ErrUnknownKeyID, "key-selection"},
+	{jwk.ErrInvalidChain, "certificate-chain"},
+	{jose.ErrUnsupportedKeyType, "key"},
+	{jose.ErrInvalidKeySize, "key"},
+	{jwk.ErrUnsupportedKey, "key"},
+	{jwk.ErrMissingKey, "key"},
+	{josejwt.ErrExpired, "claims"},
+	{josejwt.ErrNotValidYet, "claims"},
+	{josejwt.ErrIssuedInTheFuture, "claims"},
+	{josejwt.ErrInvalidIssuer, "claims"},
+	{josejwt.ErrInvalidSubject, "claims"},
+	{josejwt.ErrInvalidAudience, "claims"},
+	{josejwt.ErrInvalidID, "claims"},
+	{josejwt.ErrInvalidClaims, "parse"},
+	{josejwt.ErrUnmarshalAudience, "parse"},
+	{josejwt.ErrUnmarshalNumericDate, "parse"},
+}
+
+// Stage returns the stage of the operation failed with err: "signature" or "decryption" for cryptographic failures,
+// "parse" for tokens which couldn't be decoded and "invalid" for anything else.
+func Stage(operation string, err error, decoded bool) string {
+	for _, s := range stages {
+		if errors.Is(err, s.err) {
+			return s.stage
+		}
+	}
+
+	switch {
+	case errors.Is(err, jose.ErrCryptoFailure) && operation == metrics.Decrypt:
+		return "decryption"
+	case errors.Is(err, jose.ErrCryptoFailure):
+		return "signature"
+	case !decoded:
+		return "parse"
+	default:
+		return "invalid"
+	}
+}
+
+// Token logs the failed verification or decryption of the compact or JSON serialized token with the keys.
+func (t *Tracer) Token(module, operation, token string, keys []interface{}, err error) {
+	if err == nil || !t.config.Debug {
+		return
+	}
+
+	var header, claims map[string]interface{}
+
+	// tokens exceeding the limits are not decoded, that is what the limits are for
+	if t.config.CheckToken(token) == nil {
+		header, claims = decode(token)
+	}
+
+	t.log(module, operation, Stage(operation, err, header != nil), header, claims, keys, err)
+}
+
+// Content logs the failed signing of the payload (claims or JSON object in binary) with the header and the keys.
+func (t *Tracer) Content(
+	module, operation string,
+	header map[string]interface{},
+	payload interface{},
+	keys []interface{},
+	err error,
+) {
+	if err == nil || !t.config.Debug {
+		return
+	}
+
+	claims, ok := payload.(map[string]interface{})
+	if !ok {
+		if data, berr := buffer.Bytes(payload); berr == nil {
+			claims = object(data)
+		}
+	}
+
+	t.log(module, operation, Stage(operation, err, true), header, claims, keys, err)
+}
+
+func (t *Tracer) log(
+	module, operation, stage string,
+	header, claims map[string]interface{},
+	keys []interface{},
+	err error,
+) {
+	var logger logrus.FieldLogger
+
+	if state := t.vu.State(); state != nil {
+		logger = state.Logger
+	} else if env := t.vu.InitEnv(); env != nil {
+		logger = env.Logger
+	} else {
+		return
+	}
+
+	kid, _ := header["kid"].(string)
+
+	fields := logrus.Fields{
+		"module":    module,
+		"operation": operation,
+		"stage":     stage,
+		"error":     redact.String(err.Error()),
+		"claims":    strings.Join(names(claims), ","),
+		"keys":      strings.Join(candidates(keys, kid), ","),
+	}
+
+	if data, jerr := json.Marshal(header); header != nil && jerr == nil {
+		fields["header"] = redact.String(string(data))
+	}
+
+	if kid != "" {
+		fields["kid"] = kid
+	}
+
+	logger.WithFields(fields).Warnf("%s %s failed at %s", module, operation, stage)
+}
+
+// candidates returns the kids of the keys selected by kid, "-" for keys without kid.
+func candidates(keys []interface{}, kid string) []string {
+	args := make([]interface{}, 0, len(keys))
+
+	for _, key := range keys {
+		if ptr, ok := key.(*jose.JSONWebKey); key == nil || (ok && ptr == nil) {
+			continue
+		}
+
+		args = append(args, key)
+	}
+
+	set, err := jwk.KeySet(args...)
+	if err != nil {
+		return nil
+	}
+
+	kids := make([]string, 0, len(set))
+
+	for _, key := range jwk.Candidates(set, kid) {
+		if key.KeyID == "" {
+			kids = append(kids, "-")
+		} else {
+			kids = append(kids, key.KeyID)
+		}
+	}
+
+	return kids
+}
+
+// names returns the sorted member names of the claims, their values are never logged.
+func names(claims map[string]interface{}) []string {
+	out := make([]string, 0, len(claims))
+
+	for name := range claims {
+		out = append(out, name)
+	}
+
+	slices.Sort(out)
+
+	return out
+}
+
+// part is the header of a JSON serialized token, or of its signature or recipient.
+type part struct {
+	Protected string                 `json:"protected"`
+	Header    map[string]interface{} `json:"header"`
+}
+
+// decode returns the protected header (merged with the unprotected ones) and the claims of compact or JSON
+// serialized token, nil if they can't be decoded. The claims of JWE are encrypted, so they are always nil.
+func decode(token string) (map[string]interface{}, map[string]interface{}) {
+	token = strings.TrimSpace(token)
+
+	if !strings.HasPrefix(token, "{") {
+		parts := strings.Split(token, ".")
+
+		header := segment(parts[0])
+		if header == nil || len(parts) != 3 {
+			return header, nil
+		}
+
+		return header, segment(parts[1])
+	}
+
+	var obj struct {
+		part
+		Unprotected map[string]interface{} `json:"unprotected"`
+		Payload     *string                `json:"payload"`
+		Signatures  []part                 `json:"signatures"`
+		Recipients  []part                 `json:"recipients"`
+	}
+
+	if json.Unmarshal([]byte(token), &obj) != nil {
+		return nil, nil
+	}
+
+	first := obj.part
+	if len(obj.Signatures) != 0 {
+		first = obj.Signatures[0]
+	}
+
+	if len(obj.Recipients) != 0 {
+		first.Header = obj.Recipients[0].Header
+	}
+
+	header := segment(first.Protected)
+	if header == nil {
+		header = map[string]interface{}{}
+	}
+
+	for _, members := range []map[string]interface{}{obj.Unprotected, obj.Header, first.Header} {
+		for name, value := range members {
+			if _, ok := header[name]; !ok {
+				header[name] = value
+			}
+		}
+	}
+
+	if obj.Payload == nil {
+		return header, nil
+	}
+
+	return header, segment(*obj.Payload)
+}
+
+// segment decodes the base64url encoded JSON object, nil if it isn't one.
+func segment(encoded string) map[string]interface{} {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil
+	}
+
+	return object(data)
+}
+
+func object(data []byte) map[string]interface{} {
+	var obj map[string]interface{}
+
+	if json.Unmarshal(data, &obj) != nil {
+		return nil
+	}
+
+	return obj
+}
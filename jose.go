@@ -23,13 +23,49 @@
 package jose
 
 import (
-	"go.k6.io/k6/js/modules"
+	"github.com/szkiba/xk6-jose/acme"
+	"github.com/szkiba/xk6-jose/cose"
+	"github.com/szkiba/xk6-jose/dpop"
+	"github.com/szkiba/xk6-jose/httpsig"
+	"github.com/szkiba/xk6-jose/internal/cli"
+	"github.com/szkiba/xk6-jose/internal/jserror"
+	"github.com/szkiba/xk6-jose/jwe"
 	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jws"
 	"github.com/szkiba/xk6-jose/jwt"
+	"github.com/szkiba/xk6-jose/kms"
+	"github.com/szkiba/xk6-jose/oauth"
+	"github.com/szkiba/xk6-jose/oidc"
+	"github.com/szkiba/xk6-jose/passport"
+	"github.com/szkiba/xk6-jose/registry"
+	"github.com/szkiba/xk6-jose/sdjwt"
+	"github.com/szkiba/xk6-jose/tamper"
+	"github.com/szkiba/xk6-jose/vc"
+	"github.com/szkiba/xk6-jose/webpush"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/subcommand"
 )
 
-// Register the extensions on module initialization.
+// Register the extensions and the jose subcommand on module initialization.
 func init() {
-	modules.Register("k6/x/jose/jwk", jwk.New())
-	modules.Register("k6/x/jose/jwt", jwt.New())
+	modules.Register("k6/x/jose", jserror.Module(New()))
+	modules.Register("k6/x/jose/acme", jserror.Module(acme.New()))
+	modules.Register("k6/x/jose/cose", jserror.Module(cose.New()))
+	modules.Register("k6/x/jose/dpop", jserror.Module(dpop.New()))
+	modules.Register("k6/x/jose/httpsig", jserror.Module(httpsig.New()))
+	modules.Register("k6/x/jose/jwe", jserror.Module(jwe.New()))
+	modules.Register("k6/x/jose/jwk", jserror.Module(jwk.New()))
+	modules.Register("k6/x/jose/jws", jserror.Module(jws.New()))
+	modules.Register("k6/x/jose/kms", jserror.Module(kms.New()))
+	modules.Register("k6/x/jose/jwt", jserror.Module(jwt.New()))
+	modules.Register("k6/x/jose/oauth", jserror.Module(oauth.New()))
+	modules.Register("k6/x/jose/oidc", jserror.Module(oidc.New()))
+	modules.Register("k6/x/jose/passport", jserror.Module(passport.New()))
+	modules.Register("k6/x/jose/registry", jserror.Module(registry.New()))
+	modules.Register("k6/x/jose/sdjwt", jserror.Module(sdjwt.New()))
+	modules.Register("k6/x/jose/tamper", jserror.Module(tamper.New()))
+	modules.Register("k6/x/jose/vc", jserror.Module(vc.New()))
+	modules.Register("k6/x/jose/webpush", jserror.Module(webpush.New()))
+
+	subcommand.RegisterExtension("jose", cli.New)
 }
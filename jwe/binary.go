@@ -0,0 +1,357 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwe
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+)
+
+// binaryChunkSize is the size of content base64url encoded (or decoded) at once, a multiple of the 3 byte groups
+// of base64, so the chunks are encoded and decoded independently.
+const binaryChunkSize = 3 << 16
+
+// EncryptBinary returns the compact JWE as bytes, the content is encrypted at once and encoded in chunks
+// straight into the result, without JS strings of the content or the token.
+func (m *Module) EncryptBinary(
+	keyIn interface{},
+	plaintextIn interface{},
+	options *EncryptOptions,
+) (result sobek.ArrayBuffer, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Encrypt, alg, start, err) }()
+
+	token, alg, err := encryptBinary(keyIn, plaintextIn, options)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(token), nil
+}
+
+func encryptBinary(keyIn interface{}, plaintextIn interface{}, options *EncryptOptions) ([]byte, string, error) {
+	var alg string
+
+	plaintext, err := binaryContent(plaintextIn)
+	if err != nil {
+		return nil, alg, err
+	}
+
+	if options == nil {
+		options = &EncryptOptions{}
+	}
+
+	if s := strings.ToLower(options.Serialization); s != "" && s != serializationCompact {
+		return nil, alg, fmt.Errorf("%w: %s for binary encryption", ErrUnsupportedSerialization, options.Serialization)
+	}
+
+	aad, err := buffer.Bytes(options.AAD)
+	if err != nil {
+		return nil, alg, err
+	}
+
+	if len(aad) != 0 {
+		return nil, alg, fmt.Errorf("%w: %s with aad", ErrUnsupportedSerialization, serializationCompact)
+	}
+
+	rcpts, err := recipients(keyIn, options)
+	if err != nil {
+		return nil, alg, err
+	}
+
+	alg = string(rcpts[0].alg)
+
+	if len(rcpts) != 1 {
+		return nil, alg, fmt.Errorf("%w: %s with %d recipients", ErrUnsupportedSerialization, serializationCompact, len(rcpts))
+	}
+
+	enc, header, plaintext, err := encryptionHeader(rcpts, plaintext, options)
+	if err != nil {
+		return nil, alg, err
+	}
+
+	res, cek, err := prepare(enc, rcpts, header, nil)
+	if err != nil {
+		return nil, alg, err
+	}
+
+	// the encrypted key of ECDH-1PU key wrapping depends on the tag, but precedes the content
+	if res.recipients[0].wrapTagged != nil {
+		return nil, alg, fmt.Errorf("%w: %s for binary encryption", ErrUnsupportedAlgorithm, alg)
+	}
+
+	aead, err := contentCipher(enc, cek)
+	if err != nil {
+		return nil, alg, err
+	}
+
+	if res.iv, err = random(aead.NonceSize()); err != nil {
+		return nil, alg, err
+	}
+
+	sealed := aead.Seal(nil, res.iv, plaintext, []byte(res.protected))
+
+	return sealBinary(res, sealed, tagSize(enc, len(cek))), alg, nil
+}
+
+// sealBinary returns the compact serialization of the sealed content (ciphertext followed by the tag),
+// the ciphertext is encoded in chunks.
+func sealBinary(res *encrypted, sealed []byte, tagLen int) []byte {
+	encoding := base64.RawURLEncoding
+	ciphertext, tag := sealed[:len(sealed)-tagLen], sealed[len(sealed)-tagLen:]
+
+	prefix := res.protected + "." + encode(res.recipients[0].encryptedKey) + "." + encode(res.iv) + "."
+	out := make([]byte, len(prefix)+encoding.EncodedLen(len(ciphertext))+1+encoding.EncodedLen(tagLen))
+	pos := copy(out, prefix)
+
+	for len(ciphertext) != 0 {
+		n := min(len(ciphertext), binaryChunkSize)
+
+		encoding.Encode(out[pos:], ciphertext[:n])
+
+		pos += encoding.EncodedLen(n)
+		ciphertext = ciphertext[n:]
+	}
+
+	out[pos] = '.'
+
+	encoding.Encode(out[pos+1:], tag)
+
+	return out
+}
+
+// DecryptBinary decrypts compact JWE given as bytes (or string), decoding the content in chunks.
+// The maxTokenSize limit doesn't apply, the token is expected to be large.
+func (m *Module) DecryptBinary(
+	keyIn interface{},
+	tokenIn interface{},
+	options *DecryptOptions,
+) (result sobek.ArrayBuffer, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Decrypt, alg, start, err) }()
+
+	alg, plaintext, err := decryptBinary(m.config, keyIn, tokenIn, options)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(plaintext), nil
+}
+
+func decryptBinary(
+	cfg *config.Config,
+	keyIn interface{},
+	tokenIn interface{},
+	options *DecryptOptions,
+) (alg string, plaintext []byte, err error) {
+	options = decryptOptions(cfg, options)
+
+	key, err := decryptionKey(keyIn)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	token, err := binaryContent(tokenIn)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	parts := bytes.SplitN(bytes.TrimSpace(token), []byte("."), 6)
+	if len(parts) != 5 {
+		return alg, nil, fmt.Errorf("%w: %d parts", ErrMalformed, bytes.Count(token, []byte("."))+1)
+	}
+
+	decoder := base64.RawURLEncoding
+	if cfg != nil && cfg.StrictBase64 {
+		decoder = decoder.Strict()
+	}
+
+	obj, err := parseBinary(cfg, parts, decoder)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	header := obj.headers[0]
+	alg, _ = header["alg"].(string)
+
+	if err := checkAlgorithms(obj.headers, options); err != nil {
+		return alg, nil, err
+	}
+
+	aad, err := buffer.Bytes(options.AAD)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	if aad != nil {
+		return alg, nil, ErrAADMismatch
+	}
+
+	if _, err := usesCritical(obj, cfg); err != nil {
+		return alg, nil, err
+	}
+
+	if uses1PU(obj.headers) {
+		return alg, nil, fmt.Errorf("%w: %s for binary decryption", ErrUnsupportedAlgorithm, alg)
+	}
+
+	cek, err := recipientKey(header, obj.recipients[0].encryptedKey, key)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	name, _ := header["enc"].(string)
+	enc := jose.ContentEncryption(name)
+
+	if size := contentKeySizes[enc]; len(cek) != size {
+		return alg, nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, enc)
+	}
+
+	aead, err := contentCipher(enc, cek)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	if len(obj.iv) != aead.NonceSize() || len(obj.tag) != tagSize(enc, len(cek)) {
+		return alg, nil, ErrDecryption
+	}
+
+	sealed, err := openBinary(parts[3], obj.tag, decoder)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	// GCM decrypts in place, the exact overlap of the ciphertext and the plaintext is allowed
+	if plaintext, err = aead.Open(sealed[:0], obj.iv, sealed, []byte(obj.protected)); err != nil {
+		return alg, nil, ErrDecryption
+	}
+
+	if zip, _ := header["zip"].(string); zip != "" {
+		plaintext, err = decompress(jose.CompressionAlgorithm(zip), plaintext, cfg.MaxSize())
+	}
+
+	return alg, plaintext, err
+}
+
+// parseBinary parses the parts of compact JWE except the content.
+func parseBinary(cfg *config.Config, parts [][]byte, decoder *base64.Encoding) (*parsed, error) {
+	protected := string(parts[0])
+
+	if err := cfg.CheckSegment(protected); err != nil {
+		return nil, err
+	}
+
+	header, err := decodeHeader(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &parsed{
+		encrypted:   encrypted{protected: protected, recipients: make([]recipientInfo, 1)},
+		headers:     []map[string]interface{}{header},
+		unprotected: []map[string]interface{}{{}},
+	}
+
+	for _, field := range []struct {
+		dst *[]byte
+		src []byte
+	}{{&obj.recipients[0].encryptedKey, parts[1]}, {&obj.iv, parts[2]}, {&obj.tag, parts[4]}} {
+		if *field.dst, err = decoder.DecodeString(string(field.src)); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+		}
+	}
+
+	return obj, nil
+}
+
+// openBinary decodes the content in chunks, followed by the tag, as the sealed input of cipher.AEAD.
+func openBinary(ciphertext, tag []byte, decoder *base64.Encoding) ([]byte, error) {
+	out := make([]byte, decoder.DecodedLen(len(ciphertext))+len(tag))
+	encoded := decoder.EncodedLen(binaryChunkSize)
+	pos := 0
+
+	for len(ciphertext) != 0 {
+		n := min(len(ciphertext), encoded)
+
+		decoded, err := decoder.Decode(out[pos:], ciphertext[:n])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+		}
+
+		ciphertext = ciphertext[n:]
+
+		// chunks are decoded independently only if they are whole (e.g. no line breaks in the content)
+		if len(ciphertext) != 0 && decoded != binaryChunkSize {
+			return nil, ErrDecryption
+		}
+
+		pos += decoded
+	}
+
+	return append(out[:pos], tag...), nil
+}
+
+// binaryContent returns the bytes of the content, k6/experimental/fs files are read from their offset to the end.
+// The file contents are kept once for all VUs, not in every VU like the result of open(path, "b").
+func binaryContent(in interface{}) ([]byte, error) {
+	if r, ok := fileReader(in); ok {
+		return io.ReadAll(r)
+	}
+
+	return buffer.View(in)
+}
+
+// fileReader returns the reader of k6/experimental/fs file handle, its exported ReadSeekStater field is meant to be
+// used by extensions.
+func fileReader(in interface{}) (io.Reader, bool) {
+	val := reflect.ValueOf(in)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	field := val.Elem().FieldByName("ReadSeekStater")
+	if !field.IsValid() || field.Kind() != reflect.Interface || field.IsNil() {
+		return nil, false
+	}
+
+	r, ok := field.Interface().(io.Reader)
+
+	return r, ok
+}
@@ -0,0 +1,216 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwe
+
+import (
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/crit"
+)
+
+// maxP2C is the highest accepted PBES2 iteration count, the same as of go-jose.
+const maxP2C = 1000000
+
+// usesCritical returns true if any recipient has critical header parameters, error if one of them is not handled.
+func usesCritical(obj *parsed, cfg *config.Config) (bool, error) {
+	protected, err := decodeHeader(obj.protected)
+	if err != nil {
+		return false, err
+	}
+
+	var handled []string
+	if cfg != nil {
+		handled = cfg.Critical
+	}
+
+	var found bool
+
+	for i := range obj.headers {
+		names, err := crit.Check(protected, obj.unprotected[i], handled)
+		if err != nil {
+			return false, err
+		}
+
+		found = found || len(names) != 0
+	}
+
+	return found, nil
+}
+
+// decryptCritical decrypts the first recipient which can be decrypted with the key, without go-jose,
+// which rejects every critical header parameter. The index of the decrypted recipient is returned.
+func decryptCritical(obj *parsed, key interface{}, maxSize int) (int, []byte, error) {
+	err := ErrDecryption
+
+	for i, header := range obj.headers {
+		var cek, plaintext []byte
+
+		if cek, err = recipientKey(header, obj.recipients[i].encryptedKey, key); err != nil {
+			continue
+		}
+
+		if plaintext, err = decryptContent(obj, header, cek, maxSize); err == nil {
+			return i, plaintext, nil
+		}
+	}
+
+	return 0, nil, err
+}
+
+// recipientKey returns the CEK decrypted with the key management algorithm of the recipient.
+func recipientKey(header map[string]interface{}, encryptedKey []byte, keyIn interface{}) ([]byte, error) {
+	key := keyIn
+	if k, ok := keyIn.(*jose.JSONWebKey); ok {
+		key = k.Key
+	}
+
+	algName, _ := header["alg"].(string)
+	enc, _ := header["enc"].(string)
+	alg := jose.KeyAlgorithm(algName)
+
+	size, ok := contentKeySizes[jose.ContentEncryption(enc)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, enc)
+	}
+
+	switch alg {
+	case jose.RSA1_5:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, alg)
+		}
+
+		// random CEK on padding error, the content decryption fails instead (RFC 7516 section 11.5)
+		cek, err := random(size)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rsa.DecryptPKCS1v15SessionKey(nil, priv, encryptedKey, cek); err != nil {
+			return nil, ErrDecryption
+		}
+
+		return cek, nil
+	case jose.RSA_OAEP, jose.RSA_OAEP_256, jose.A128KW, jose.A192KW, jose.A256KW:
+		return unwrap(alg, key, encryptedKey)
+	case jose.A128GCMKW, jose.A192GCMKW, jose.A256GCMKW:
+		kek, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, alg)
+		}
+
+		return gcmKeyUnwrap(kek, encryptedKey, header)
+	case jose.DIRECT:
+		cek, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, alg)
+		}
+
+		return append([]byte(nil), cek...), nil
+	case jose.ECDH_ES, jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW:
+		priv, ok := agreementPrivate(key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, alg)
+		}
+
+		pub, err := ephemeralKey(header, priv.Curve())
+		if err != nil {
+			return nil, err
+		}
+
+		apu, apv, err := headerPartyInfo(header)
+		if err != nil {
+			return nil, err
+		}
+
+		z, err := priv.ECDH(pub)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+		}
+
+		// Concat KDF of RFC 7518 section 4.6.2, as josecipher.DeriveECDHES
+		if alg == jose.ECDH_ES {
+			return concatKDF(z, enc, apu, apv, size, nil), nil
+		}
+
+		kek := concatKDF(z, algName, apu, apv, keyWrapSizes[alg], nil)
+
+		return keyUnwrap(kek, encryptedKey)
+	case jose.PBES2_HS256_A128KW, jose.PBES2_HS384_A192KW, jose.PBES2_HS512_A256KW:
+		password, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, alg)
+		}
+
+		p2sValue, _ := header["p2s"].(string)
+
+		p2s, err := decode(p2sValue)
+		if err != nil {
+			return nil, err
+		}
+
+		p2c, _ := header["p2c"].(float64)
+		if p2c <= 0 || p2c > maxP2C || p2c != float64(int(p2c)) {
+			return nil, fmt.Errorf("%w: p2c %v", ErrMalformed, header["p2c"])
+		}
+
+		kek := pbes2Key(alg, password, p2s, int(p2c))
+
+		return keyUnwrap(kek, encryptedKey)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+func gcmKeyUnwrap(kek []byte, encryptedKey []byte, header map[string]interface{}) ([]byte, error) {
+	aead, err := contentCipher(jose.A256GCM, kek)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	var iv, tag []byte
+
+	for _, field := range []struct {
+		dst  *[]byte
+		name string
+	}{{&iv, "iv"}, {&tag, "tag"}} {
+		value, _ := header[field.name].(string)
+		if *field.dst, err = decode(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(iv) != aead.NonceSize() || len(tag) != gcmTagSize {
+		return nil, ErrDecryption
+	}
+
+	cek, err := aead.Open(nil, iv, append(append([]byte{}, encryptedKey...), tag...), nil)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	return cek, nil
+}
@@ -0,0 +1,390 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwe
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	josecipher "github.com/go-jose/go-jose/v4/cipher"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+// ECDH-1PU key agreement (draft-madden-jose-ecdh-1pu-04), Z = Ze || Zs where Ze is agreed with ephemeral key and Zs with the sender's static key.
+const (
+	ecdh1PU       = jose.KeyAlgorithm("ECDH-1PU")
+	ecdh1PUA128KW = jose.KeyAlgorithm("ECDH-1PU+A128KW")
+	ecdh1PUA192KW = jose.KeyAlgorithm("ECDH-1PU+A192KW")
+	ecdh1PUA256KW = jose.KeyAlgorithm("ECDH-1PU+A256KW")
+)
+
+var ErrDecryption = errors.New("decryption failed")
+
+var ecdh1PUKeyWrap = map[jose.KeyAlgorithm]bool{
+	ecdh1PUA128KW: true,
+	ecdh1PUA192KW: true,
+	ecdh1PUA256KW: true,
+}
+
+// authenticated maps the default key agreement algorithms to their sender authenticated variant.
+var authenticated = map[jose.KeyAlgorithm]jose.KeyAlgorithm{
+	jose.ECDH_ES:        ecdh1PU,
+	jose.ECDH_ES_A256KW: ecdh1PUA256KW,
+}
+
+// ecdh1PUSecret returns the shared secret Z, the ephemeral key is generated (and added to the header as epk)
+// unless the recipients share one.
+func ecdh1PUSecret(rcpt recipient, header map[string]interface{}) ([]byte, error) {
+	pub, ok := agreementPublic(rcpt.key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, rcpt.key, rcpt.alg)
+	}
+
+	if rcpt.sender == nil || rcpt.sender.Curve() != pub.Curve() {
+		return nil, fmt.Errorf("%w: sender key for %s", ErrUnsupportedKey, rcpt.alg)
+	}
+
+	priv := rcpt.ephemeral
+	if priv == nil {
+		var err error
+
+		if priv, err = pub.Curve().GenerateKey(rand.Reader); err != nil {
+			return nil, err
+		}
+
+		header["epk"] = ephemeralHeader(priv.PublicKey())
+	}
+
+	partyInfo(rcpt, header)
+
+	return agreeZ(priv, pub, rcpt.sender, pub)
+}
+
+// sharedEphemeral returns the ephemeral key of multiple ECDH-1PU recipients, it is in the protected header
+// as the draft requires. The recipients must use the same curve, nil is returned for a single recipient.
+func sharedEphemeral(rcpts []recipient) (*ecdh.PrivateKey, error) {
+	if len(rcpts) < 2 {
+		return nil, nil
+	}
+
+	var curve ecdh.Curve
+
+	for _, rcpt := range rcpts {
+		if !ecdh1PUKeyWrap[rcpt.alg] {
+			continue
+		}
+
+		pub, ok := agreementPublic(rcpt.key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, rcpt.key, rcpt.alg)
+		}
+
+		if curve != nil && curve != pub.Curve() {
+			return nil, fmt.Errorf("%w: recipient keys of different curves for %s", ErrUnsupportedKey, rcpt.alg)
+		}
+
+		curve = pub.Curve()
+	}
+
+	if curve == nil {
+		return nil, nil
+	}
+
+	// the epk of ECDH-ES recipients would be duplicate of the protected one
+	for _, rcpt := range rcpts {
+		switch rcpt.alg {
+		case jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW:
+			return nil, fmt.Errorf("%w: %s with %s recipients", ErrUnsupportedAlgorithm, rcpt.alg, ecdh1PU)
+		}
+	}
+
+	return curve.GenerateKey(rand.Reader)
+}
+
+// concatKDF derives key of size bytes, the authentication tag is part of SuppPubInfo in key wrapping mode.
+func concatKDF(z []byte, alg string, apu, apv []byte, size int, tag []byte) []byte {
+	supPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(supPubInfo, uint32(size)*8)
+
+	if tag != nil {
+		supPubInfo = append(supPubInfo, lengthPrefixed(tag)...)
+	}
+
+	reader := josecipher.NewConcatKDF(
+		crypto.SHA256,
+		z,
+		lengthPrefixed([]byte(alg)),
+		lengthPrefixed(apu),
+		lengthPrefixed(apv),
+		supPubInfo,
+		[]byte{},
+	)
+
+	key := make([]byte, size)
+
+	// Read on the KDF never fails
+	_, _ = reader.Read(key)
+
+	return key
+}
+
+// agreeZ returns Ze || Zs, the X25519 agreement fails with low order points.
+func agreeZ(ephemeral *ecdh.PrivateKey, epub *ecdh.PublicKey, static *ecdh.PrivateKey, spub *ecdh.PublicKey) ([]byte, error) {
+	ze, err := ephemeral.ECDH(epub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	zs, err := static.ECDH(spub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	return append(ze, zs...), nil
+}
+
+// agreementPublic returns the ECDH public key of EC (NIST curves) and X25519 keys, public or private.
+func agreementPublic(key interface{}) (*ecdh.PublicKey, bool) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		pub, err := k.ECDH()
+
+		return pub, err == nil
+	case *ecdsa.PrivateKey:
+		pub, err := k.PublicKey.ECDH()
+
+		return pub, err == nil
+	case *ecdh.PublicKey:
+		return k, true
+	case *ecdh.PrivateKey:
+		return k.PublicKey(), true
+	}
+
+	return nil, false
+}
+
+// agreementPrivate returns the ECDH private key of EC (NIST curves) and X25519 private keys.
+func agreementPrivate(key interface{}) (*ecdh.PrivateKey, bool) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		priv, err := k.ECDH()
+
+		return priv, err == nil
+	case *ecdh.PrivateKey:
+		return k, true
+	}
+
+	return nil, false
+}
+
+// ephemeralHeader returns the epk header parameter of the public key.
+func ephemeralHeader(pub *ecdh.PublicKey) map[string]interface{} {
+	raw := pub.Bytes()
+
+	if pub.Curve() == ecdh.X25519() {
+		return map[string]interface{}{"kty": "OKP", "crv": "X25519", "x": encode(raw)}
+	}
+
+	// uncompressed point: 0x04 || X || Y
+	size := (len(raw) - 1) / 2
+
+	return map[string]interface{}{
+		"kty": "EC",
+		"crv": curveNames[pub.Curve()],
+		"x":   encode(raw[1 : 1+size]),
+		"y":   encode(raw[1+size:]),
+	}
+}
+
+var curveNames = map[ecdh.Curve]string{ecdh.P256(): "P-256", ecdh.P384(): "P-384", ecdh.P521(): "P-521"}
+
+func lengthPrefixed(data []byte) []byte {
+	out := make([]byte, len(data)+4)
+	binary.BigEndian.PutUint32(out, uint32(len(data)))
+	copy(out[4:], data)
+
+	return out
+}
+
+func uses1PU(headers []map[string]interface{}) bool {
+	for _, header := range headers {
+		if alg, _ := header["alg"].(string); strings.HasPrefix(alg, string(ecdh1PU)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decrypt1PU decrypts the first ECDH-1PU recipient which can be decrypted with the key, returns its index.
+func decrypt1PU(obj *parsed, key interface{}, sender interface{}, maxSize int) (int, []byte, error) {
+	recipientKey, _ := key.(*jose.JSONWebKey)
+	if recipientKey == nil {
+		return 0, nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, ecdh1PU)
+	}
+
+	priv, ok := agreementPrivate(recipientKey.Key)
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, recipientKey.Key, ecdh1PU)
+	}
+
+	spub, err := senderPublicKey(sender, priv.Curve())
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = ErrDecryption
+
+	for i, header := range obj.headers {
+		alg, _ := header["alg"].(string)
+		if !strings.HasPrefix(alg, string(ecdh1PU)) {
+			continue
+		}
+
+		var cek, plaintext []byte
+
+		if cek, err = unwrap1PU(obj, i, priv, spub); err != nil {
+			continue
+		}
+
+		if plaintext, err = decryptContent(obj, header, cek, maxSize); err == nil {
+			return i, plaintext, nil
+		}
+	}
+
+	return 0, nil, err
+}
+
+// ephemeralKey returns the epk header parameter, it must be on the curve of the recipient's key.
+func ephemeralKey(header map[string]interface{}, curve ecdh.Curve) (*ecdh.PublicKey, error) {
+	epk, ok := header["epk"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: epk %T", ErrMalformed, header["epk"])
+	}
+
+	keys, err := jwk.KeySet(epk)
+	if err != nil {
+		return nil, fmt.Errorf("%w: epk %s", ErrMalformed, err.Error())
+	}
+
+	pub, ok := agreementPublic(keys[0].Key)
+	if !ok || pub.Curve() != curve {
+		return nil, fmt.Errorf("%w: epk %T", ErrMalformed, keys[0].Key)
+	}
+
+	return pub, nil
+}
+
+// headerPartyInfo returns the decoded apu and apv header parameters.
+func headerPartyInfo(header map[string]interface{}) ([]byte, []byte, error) {
+	var apu, apv []byte
+
+	for _, field := range []struct {
+		dst  *[]byte
+		name string
+	}{{&apu, "apu"}, {&apv, "apv"}} {
+		value, _ := header[field.name].(string)
+
+		var err error
+
+		if *field.dst, err = decode(value); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return apu, apv, nil
+}
+
+func senderPublicKey(sender interface{}, curve ecdh.Curve) (*ecdh.PublicKey, error) {
+	if sender == nil {
+		return nil, fmt.Errorf("%w: missing sender key for %s", ErrUnsupportedKey, ecdh1PU)
+	}
+
+	keys, err := jwk.KeySet(sender)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("%w: %d sender keys for %s", ErrUnsupportedKey, len(keys), ecdh1PU)
+	}
+
+	pub, ok := agreementPublic(keys[0].Key)
+	if !ok || pub.Curve() != curve {
+		return nil, fmt.Errorf("%w: %T sender key for %s", ErrUnsupportedKey, keys[0].Key, ecdh1PU)
+	}
+
+	return pub, nil
+}
+
+// unwrap1PU returns the CEK of the idx-th recipient.
+func unwrap1PU(obj *parsed, idx int, priv *ecdh.PrivateKey, spub *ecdh.PublicKey) ([]byte, error) {
+	header := obj.headers[idx]
+
+	pub, err := ephemeralKey(header, priv.Curve())
+	if err != nil {
+		return nil, err
+	}
+
+	apu, apv, err := headerPartyInfo(header)
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := agreeZ(priv, pub, priv, spub)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, _ := header["alg"].(string)
+	enc, _ := header["enc"].(string)
+
+	if jose.KeyAlgorithm(alg) == ecdh1PU {
+		size, ok := contentKeySizes[jose.ContentEncryption(enc)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, enc)
+		}
+
+		return concatKDF(z, enc, apu, apv, size, nil), nil
+	}
+
+	size, ok := keyWrapSizes[jose.KeyAlgorithm(alg)]
+	if !ok || !ecdh1PUKeyWrap[jose.KeyAlgorithm(alg)] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	block, err := aes.NewCipher(concatKDF(z, alg, apu, apv, size, obj.tag))
+	if err != nil {
+		return nil, err
+	}
+
+	return josecipher.KeyUnwrap(block, obj.recipients[idx].encryptedKey)
+}
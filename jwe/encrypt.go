@@ -0,0 +1,571 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwe
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	josecipher "github.com/go-jose/go-jose/v4/cipher"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// go-jose's multi encrypter ignores extra headers, so encryption is done here with go-jose's cipher primitives.
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+
+const (
+	defaultP2C     = 100000
+	defaultP2SSize = 16
+	gcmTagSize     = 16
+)
+
+// contentKeySizes contains the CEK size of content encryption algorithms.
+var contentKeySizes = map[jose.ContentEncryption]int{
+	jose.A128CBC_HS256: 32,
+	jose.A192CBC_HS384: 48,
+	jose.A256CBC_HS512: 64,
+	jose.A128GCM:       16,
+	jose.A192GCM:       24,
+	jose.A256GCM:       32,
+}
+
+type recipient struct {
+	alg jose.KeyAlgorithm
+	key interface{}
+	kid string
+	apu []byte
+	apv []byte
+	p2c int
+	p2s []byte
+
+	sender    *ecdh.PrivateKey
+	skid      string
+	ephemeral *ecdh.PrivateKey
+}
+
+type recipientInfo struct {
+	header       map[string]interface{}
+	encryptedKey []byte
+
+	// wrapTagged wraps the CEK with key depending on the authentication tag (ECDH-1PU key wrapping)
+	wrapTagged func(tag []byte) ([]byte, error)
+}
+
+// encrypted is the result of encryption, before serialization.
+type encrypted struct {
+	protected  string
+	recipients []recipientInfo
+	aad        []byte
+	iv         []byte
+	ciphertext []byte
+	tag        []byte
+}
+
+func encrypt(
+	enc jose.ContentEncryption,
+	rcpts []recipient,
+	header map[string]interface{},
+	plaintext []byte,
+	aad []byte,
+) (*encrypted, error) {
+	res, cek, err := prepare(enc, rcpts, header, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := contentCipher(enc, cek)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.iv, err = random(aead.NonceSize()); err != nil {
+		return nil, err
+	}
+
+	authData := res.protected
+	if len(aad) != 0 {
+		authData += "." + base64.RawURLEncoding.EncodeToString(aad)
+	}
+
+	out := aead.Seal(nil, res.iv, plaintext, []byte(authData))
+	tagSize := tagSize(enc, len(cek))
+
+	res.ciphertext, res.tag = out[:len(out)-tagSize], out[len(out)-tagSize:]
+
+	for i := range res.recipients {
+		if res.recipients[i].wrapTagged == nil {
+			continue
+		}
+
+		if res.recipients[i].encryptedKey, err = res.recipients[i].wrapTagged(res.tag); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// prepare returns the encryption without content and the CEK: the protected header and the recipients.
+func prepare(
+	enc jose.ContentEncryption,
+	rcpts []recipient,
+	header map[string]interface{},
+	aad []byte,
+) (*encrypted, []byte, error) {
+	size, ok := contentKeySizes[enc]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, enc)
+	}
+
+	for _, rcpt := range rcpts {
+		if ecdh1PUKeyWrap[rcpt.alg] && !cbcEncryption(enc) {
+			return nil, nil, fmt.Errorf("%w: %s with %s", ErrUnsupportedAlgorithm, rcpt.alg, enc)
+		}
+	}
+
+	ephemeral, err := sharedEphemeral(rcpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ephemeral != nil {
+		rcpts = append([]recipient(nil), rcpts...)
+
+		for i := range rcpts {
+			if ecdh1PUKeyWrap[rcpts[i].alg] {
+				rcpts[i].ephemeral = ephemeral
+			}
+		}
+	}
+
+	cek, infos, err := contentKey(enc, size, rcpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protected := make(map[string]interface{}, len(header)+2)
+	for k, v := range header {
+		protected[k] = v
+	}
+
+	protected["enc"] = enc
+
+	if ephemeral != nil {
+		protected["epk"] = ephemeralHeader(ephemeral.PublicKey())
+	}
+
+	// single recipient headers are protected, as in compact serialization
+	if len(infos) == 1 {
+		for k, v := range infos[0].header {
+			protected[k] = v
+		}
+
+		infos[0].header = nil
+	}
+
+	b, err := json.Marshal(protected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &encrypted{protected: base64.RawURLEncoding.EncodeToString(b), recipients: infos, aad: aad}, cek, nil
+}
+
+func cbcEncryption(enc jose.ContentEncryption) bool {
+	return enc == jose.A128CBC_HS256 || enc == jose.A192CBC_HS384 || enc == jose.A256CBC_HS512
+}
+
+func tagSize(enc jose.ContentEncryption, size int) int {
+	if cbcEncryption(enc) {
+		// CBC-HMAC tag size is the MAC key size
+		return size / 2
+	}
+
+	return gcmTagSize
+}
+
+// contentKey generates (or agrees on, for dir, ECDH-ES and ECDH-1PU) the CEK and encrypts it to every recipient.
+func contentKey(enc jose.ContentEncryption, size int, rcpts []recipient) ([]byte, []recipientInfo, error) {
+	if len(rcpts) == 1 && (rcpts[0].alg == jose.DIRECT || rcpts[0].alg == jose.ECDH_ES || rcpts[0].alg == ecdh1PU) {
+		cek, info, err := agree(rcpts[0], string(enc), size)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return cek, []recipientInfo{info}, nil
+	}
+
+	cek, err := random(size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	infos := make([]recipientInfo, len(rcpts))
+
+	for i, rcpt := range rcpts {
+		if infos[i], err = wrap(rcpt, cek); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return cek, infos, nil
+}
+
+// agree returns the CEK of direct key agreement or direct encryption.
+func agree(rcpt recipient, enc string, size int) ([]byte, recipientInfo, error) {
+	info := newInfo(rcpt)
+
+	if rcpt.alg == jose.DIRECT {
+		key, ok := rcpt.key.([]byte)
+		if !ok || len(key) != size {
+			return nil, info, fmt.Errorf("%w: %T for %s with %s", ErrUnsupportedKey, rcpt.key, rcpt.alg, enc)
+		}
+
+		return key, info, nil
+	}
+
+	if rcpt.alg == ecdh1PU {
+		z, err := ecdh1PUSecret(rcpt, info.header)
+		if err != nil {
+			return nil, info, err
+		}
+
+		return concatKDF(z, enc, rcpt.apu, rcpt.apv, size, nil), info, nil
+	}
+
+	cek, err := deriveECDHES(rcpt, enc, size, info.header)
+
+	return cek, info, err
+}
+
+// wrap encrypts the CEK to the recipient.
+func wrap(rcpt recipient, cek []byte) (recipientInfo, error) {
+	info := newInfo(rcpt)
+
+	var err error
+
+	switch rcpt.alg {
+	case jose.RSA1_5, jose.RSA_OAEP, jose.RSA_OAEP_256:
+		pub, ok := rcpt.key.(*rsa.PublicKey)
+		if !ok {
+			return info, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, rcpt.key, rcpt.alg)
+		}
+
+		switch rcpt.alg {
+		case jose.RSA1_5:
+			info.encryptedKey, err = rsa.EncryptPKCS1v15(rand.Reader, pub, cek)
+		case jose.RSA_OAEP:
+			info.encryptedKey, err = rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, cek, nil)
+		default:
+			info.encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, cek, nil)
+		}
+	case jose.A128KW, jose.A192KW, jose.A256KW:
+		key, ok := rcpt.key.([]byte)
+		if !ok {
+			return info, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, rcpt.key, rcpt.alg)
+		}
+
+		info.encryptedKey, err = keyWrap(key, cek)
+	case jose.A128GCMKW, jose.A192GCMKW, jose.A256GCMKW:
+		key, ok := rcpt.key.([]byte)
+		if !ok {
+			return info, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, rcpt.key, rcpt.alg)
+		}
+
+		info.encryptedKey, err = gcmKeyWrap(key, cek, info.header)
+	case jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW:
+		var kek []byte
+
+		if kek, err = deriveECDHES(rcpt, string(rcpt.alg), keyWrapSizes[rcpt.alg], info.header); err != nil {
+			return info, err
+		}
+
+		info.encryptedKey, err = keyWrap(kek, cek)
+	case jose.PBES2_HS256_A128KW, jose.PBES2_HS384_A192KW, jose.PBES2_HS512_A256KW:
+		password, ok := rcpt.key.([]byte)
+		if !ok {
+			return info, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, rcpt.key, rcpt.alg)
+		}
+
+		info.encryptedKey, err = pbes2KeyWrap(rcpt, password, cek, info.header)
+	case ecdh1PUA128KW, ecdh1PUA192KW, ecdh1PUA256KW:
+		var z []byte
+
+		if z, err = ecdh1PUSecret(rcpt, info.header); err != nil {
+			return info, err
+		}
+
+		info.wrapTagged = func(tag []byte) ([]byte, error) {
+			return keyWrap(concatKDF(z, string(rcpt.alg), rcpt.apu, rcpt.apv, keyWrapSizes[rcpt.alg], tag), cek)
+		}
+	case jose.DIRECT, jose.ECDH_ES, ecdh1PU:
+		return info, fmt.Errorf("%w: %s with multiple recipients", ErrUnsupportedAlgorithm, rcpt.alg)
+	default:
+		return info, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, rcpt.alg)
+	}
+
+	return info, err
+}
+
+var keyWrapSizes = map[jose.KeyAlgorithm]int{
+	jose.ECDH_ES_A128KW:     16,
+	jose.ECDH_ES_A192KW:     24,
+	jose.ECDH_ES_A256KW:     32,
+	jose.PBES2_HS256_A128KW: 16,
+	jose.PBES2_HS384_A192KW: 24,
+	jose.PBES2_HS512_A256KW: 32,
+	ecdh1PUA128KW:           16,
+	ecdh1PUA192KW:           24,
+	ecdh1PUA256KW:           32,
+}
+
+func newInfo(rcpt recipient) recipientInfo {
+	header := map[string]interface{}{"alg": rcpt.alg}
+	if rcpt.kid != "" {
+		header["kid"] = rcpt.kid
+	}
+
+	return recipientInfo{header: header}
+}
+
+// deriveECDHES generates ephemeral key (added to the header as epk) and derives a key of size bytes with it.
+// The apu and apv PartyInfo values are added to the header too.
+func deriveECDHES(rcpt recipient, alg string, size int, header map[string]interface{}) ([]byte, error) {
+	pub, ok := rcpt.key.(*ecdsa.PublicKey)
+	if !ok || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, rcpt.key, rcpt.alg)
+	}
+
+	priv, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	header["epk"] = jose.JSONWebKey{Key: &priv.PublicKey}
+
+	partyInfo(rcpt, header)
+
+	return josecipher.DeriveECDHES(alg, rcpt.apu, rcpt.apv, priv, pub, size), nil
+}
+
+func partyInfo(rcpt recipient, header map[string]interface{}) {
+	if len(rcpt.apu) != 0 {
+		header["apu"] = base64.RawURLEncoding.EncodeToString(rcpt.apu)
+	}
+
+	if len(rcpt.apv) != 0 {
+		header["apv"] = base64.RawURLEncoding.EncodeToString(rcpt.apv)
+	}
+}
+
+func keyWrap(kek []byte, cek []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return josecipher.KeyWrap(block, cek)
+}
+
+func gcmKeyWrap(kek []byte, cek []byte, header map[string]interface{}) ([]byte, error) {
+	aead, err := contentCipher(jose.A256GCM, kek)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := random(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	out := aead.Seal(nil, iv, cek, nil)
+
+	header["iv"] = base64.RawURLEncoding.EncodeToString(iv)
+	header["tag"] = base64.RawURLEncoding.EncodeToString(out[len(out)-gcmTagSize:])
+
+	return out[:len(out)-gcmTagSize], nil
+}
+
+// pbes2KeyWrap wraps the CEK with key derived from the password, random salt input and default iteration count are used when not set.
+func pbes2KeyWrap(rcpt recipient, password []byte, cek []byte, header map[string]interface{}) ([]byte, error) {
+	p2s, p2c := rcpt.p2s, rcpt.p2c
+
+	if len(p2s) == 0 {
+		var err error
+
+		if p2s, err = random(defaultP2SSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if p2c <= 0 {
+		p2c = defaultP2C
+	}
+
+	header["p2c"] = p2c
+	header["p2s"] = base64.RawURLEncoding.EncodeToString(p2s)
+
+	return keyWrap(pbes2Key(rcpt.alg, password, p2s, p2c), cek)
+}
+
+// pbes2Key derives the key encryption key from the password.
+func pbes2Key(alg jose.KeyAlgorithm, password []byte, p2s []byte, p2c int) []byte {
+	var h func() hash.Hash
+
+	switch alg {
+	case jose.PBES2_HS256_A128KW:
+		h = sha256.New
+	case jose.PBES2_HS384_A192KW:
+		h = sha512.New384
+	default:
+		h = sha512.New
+	}
+
+	// salt is UTF8(Alg) || 0x00 || Salt Input
+	salt := append(append([]byte(alg), 0), p2s...)
+
+	return pbkdf2.Key(password, salt, p2c, keyWrapSizes[alg], h)
+}
+
+// contentCipher returns AEAD for GCM (based on key size) or CBC-HMAC content encryption.
+func contentCipher(enc jose.ContentEncryption, cek []byte) (cipher.AEAD, error) {
+	switch enc {
+	case jose.A128CBC_HS256, jose.A192CBC_HS384, jose.A256CBC_HS512:
+		return josecipher.NewCBCHMAC(cek, aes.NewCipher)
+	default:
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, err
+		}
+
+		return cipher.NewGCM(block)
+	}
+}
+
+// compress the plaintext with the given algorithm, only DEF (raw DEFLATE) is supported.
+func compress(alg jose.CompressionAlgorithm, plaintext []byte) ([]byte, error) {
+	if alg != jose.DEFLATE {
+		return nil, fmt.Errorf("%w: zip %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	var buff strings.Builder
+
+	w, err := flate.NewWriter(&buff, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return []byte(buff.String()), nil
+}
+
+// decryptContent decrypts (and decompresses, if needed) the content with the CEK.
+func decryptContent(obj *parsed, header map[string]interface{}, cek []byte, maxSize int) ([]byte, error) {
+	enc, _ := header["enc"].(string)
+
+	size, ok := contentKeySizes[jose.ContentEncryption(enc)]
+	if !ok || len(cek) != size {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, enc)
+	}
+
+	aead, err := contentCipher(jose.ContentEncryption(enc), cek)
+	if err != nil {
+		return nil, err
+	}
+
+	authData := obj.protected
+	if len(obj.aad) != 0 {
+		authData += "." + base64.RawURLEncoding.EncodeToString(obj.aad)
+	}
+
+	if len(obj.iv) != aead.NonceSize() || len(obj.tag) != tagSize(jose.ContentEncryption(enc), size) {
+		return nil, ErrDecryption
+	}
+
+	plaintext, err := aead.Open(nil, obj.iv, append(append([]byte{}, obj.ciphertext...), obj.tag...), []byte(authData))
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	if zip, _ := header["zip"].(string); zip != "" {
+		return decompress(jose.CompressionAlgorithm(zip), plaintext, maxSize)
+	}
+
+	return plaintext, nil
+}
+
+// decompress inflates the content, up to maxSize bytes: a small token must not expand to gigabytes.
+func decompress(alg jose.CompressionAlgorithm, compressed []byte, maxSize int) ([]byte, error) {
+	if alg != jose.DEFLATE {
+		return nil, fmt.Errorf("%w: zip %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	r := flate.NewReader(bytes.NewReader(compressed))
+	defer r.Close()
+
+	plaintext, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plaintext) > maxSize {
+		return nil, fmt.Errorf("%w: decompressed content exceeds %d bytes", ErrMalformed, maxSize)
+	}
+
+	return plaintext, nil
+}
+
+func random(size int) ([]byte, error) {
+	b := make([]byte, size)
+
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,602 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwe
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/internal/trace"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	vu      modules.VU
+	metrics *metrics.Metrics
+	trace   *trace.Tracer
+	config  *config.Config
+}
+
+type EncryptOptions struct {
+	Header        map[string]interface{} `js:"header"`
+	Serialization string                 `js:"serialization"`
+	Algorithm     string                 `js:"alg"`
+	Encryption    string                 `js:"enc"`
+	Compression   string                 `js:"zip"`
+	AAD           interface{}            `js:"aad"`
+	APU           interface{}            `js:"apu"`
+	APV           interface{}            `js:"apv"`
+	P2C           int                    `js:"p2c"`
+	P2S           interface{}            `js:"p2s"`
+	Sender        interface{}            `js:"sender"`
+	Critical      []string               `js:"critical"`
+}
+
+type DecryptOptions struct {
+	Algorithms  []string    `js:"algorithms"`
+	Encryptions []string    `js:"encryptions"`
+	AAD         interface{} `js:"aad"`
+	Sender      interface{} `js:"sender"`
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return NewModule(vu, config.Default(vu)) })
+}
+
+// NewModule returns the module object of the VU using the given configuration.
+func NewModule(vu modules.VU, cfg *config.Config) *Module {
+	return &Module{vu: vu, metrics: metrics.New(vu), trace: trace.New(vu, cfg), config: cfg}
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
+}
+
+var (
+	ErrUnsupportedKey      = jwk.ErrUnsupportedKey
+	ErrAlgorithmNotAllowed = config.ErrAlgorithmNotAllowed
+	ErrAADMismatch         = errors.New("additional authenticated data mismatch")
+)
+
+var keyAlgorithms = map[jose.KeyAlgorithm]bool{
+	jose.RSA1_5:             true,
+	jose.RSA_OAEP:           true,
+	jose.RSA_OAEP_256:       true,
+	jose.A128KW:             true,
+	jose.A192KW:             true,
+	jose.A256KW:             true,
+	jose.DIRECT:             true,
+	jose.ECDH_ES:            true,
+	jose.ECDH_ES_A128KW:     true,
+	jose.ECDH_ES_A192KW:     true,
+	jose.ECDH_ES_A256KW:     true,
+	jose.A128GCMKW:          true,
+	jose.A192GCMKW:          true,
+	jose.A256GCMKW:          true,
+	jose.PBES2_HS256_A128KW: true,
+	jose.PBES2_HS384_A192KW: true,
+	jose.PBES2_HS512_A256KW: true,
+	ecdh1PU:                 true,
+	ecdh1PUA128KW:           true,
+	ecdh1PUA192KW:           true,
+	ecdh1PUA256KW:           true,
+}
+
+const defaultEncryption = jose.A256GCM
+
+const moduleName = "jwe"
+
+func (m *Module) Encrypt(
+	keyIn interface{},
+	plaintextIn interface{},
+	options *EncryptOptions,
+) (token string, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Encrypt, alg, start, err) }()
+
+	token, alg, err = encryptToken(keyIn, plaintextIn, options)
+
+	return token, err
+}
+
+// Encrypt is the unmeasured variant of Module.Encrypt for callers outside of a VU.
+func Encrypt(keyIn interface{}, plaintextIn interface{}, options *EncryptOptions) (string, error) {
+	token, _, err := encryptToken(keyIn, plaintextIn, options)
+
+	return token, err
+}
+
+func encryptToken(
+	keyIn interface{},
+	plaintextIn interface{},
+	options *EncryptOptions,
+) (token string, alg string, err error) {
+	plaintext, err := buffer.Bytes(plaintextIn)
+	if err != nil {
+		return "", alg, err
+	}
+
+	if options == nil {
+		options = &EncryptOptions{}
+	}
+
+	rcpts, err := recipients(keyIn, options)
+	if err != nil {
+		return "", alg, err
+	}
+
+	alg = string(rcpts[0].alg)
+
+	enc, header, plaintext, err := encryptionHeader(rcpts, plaintext, options)
+	if err != nil {
+		return "", alg, err
+	}
+
+	aad, err := buffer.Bytes(options.AAD)
+	if err != nil {
+		return "", alg, err
+	}
+
+	obj, err := encrypt(enc, rcpts, header, plaintext, aad)
+	if err != nil {
+		return "", alg, err
+	}
+
+	serialization := options.Serialization
+	if serialization == "" {
+		switch {
+		case len(rcpts) > 1:
+			serialization = serializationGeneral
+		case len(aad) != 0:
+			serialization = serializationFlattened
+		}
+	}
+
+	token, err = serialize(obj, serialization)
+
+	return token, alg, err
+}
+
+// encryptionHeader returns the content encryption algorithm and the header of the options,
+// with the plaintext compressed if requested.
+func encryptionHeader(
+	rcpts []recipient,
+	plaintext []byte,
+	options *EncryptOptions,
+) (jose.ContentEncryption, map[string]interface{}, []byte, error) {
+	enc := jose.ContentEncryption(options.Encryption)
+	if enc == "" {
+		enc = defaultEncryption
+
+		for _, rcpt := range rcpts {
+			if ecdh1PUKeyWrap[rcpt.alg] {
+				enc = jose.A256CBC_HS512
+			}
+		}
+	}
+
+	if err := checkSupported(enc, rcpts); err != nil {
+		return enc, nil, nil, err
+	}
+
+	header := make(map[string]interface{}, len(options.Header)+2)
+	for k, v := range options.Header {
+		header[k] = v
+	}
+
+	if len(rcpts) != 0 && rcpts[0].skid != "" {
+		header["skid"] = rcpts[0].skid
+	}
+
+	var err error
+
+	if options.Compression != "" {
+		if plaintext, err = compress(jose.CompressionAlgorithm(options.Compression), plaintext); err != nil {
+			return enc, nil, nil, err
+		}
+
+		header["zip"] = options.Compression
+	}
+
+	if header, err = crit.Mark(header, options.Critical...); err != nil {
+		return enc, nil, nil, err
+	}
+
+	return enc, header, plaintext, nil
+}
+
+// checkSupported returns error if the content encryption or a key management algorithm is not one the parsers accept,
+// so tokens are not encrypted (and keys not wrapped) with algorithms the decryption rejects.
+func checkSupported(enc jose.ContentEncryption, rcpts []recipient) error {
+	if !slices.Contains(algorithm.Encryptions, enc) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, enc)
+	}
+
+	for _, rcpt := range rcpts {
+		if !slices.Contains(algorithm.Keys, rcpt.alg) && rcpt.alg != ecdh1PU && !ecdh1PUKeyWrap[rcpt.alg] {
+			return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, rcpt.alg)
+		}
+	}
+
+	return nil
+}
+
+// recipients creates recipients from the key (or keys, or passphrase) and the encryption options.
+func recipients(keyIn interface{}, options *EncryptOptions) ([]recipient, error) {
+	// passphrase is a symmetric key for password based encryption
+	if password, ok := keyIn.(string); ok {
+		keyIn = &jose.JSONWebKey{Key: []byte(password), Algorithm: string(jose.PBES2_HS256_A128KW)}
+	}
+
+	keys, err := jwk.KeySet(keyIn)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no recipient key", ErrUnsupportedKey)
+	}
+
+	alg := jose.KeyAlgorithm(options.Algorithm)
+	if alg != "" && !keyAlgorithms[alg] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	sender, skid, err := senderKey(options.Sender)
+	if err != nil {
+		return nil, err
+	}
+
+	var apu, apv, p2s []byte
+
+	for _, field := range []struct {
+		dst *[]byte
+		src interface{}
+	}{{&apu, options.APU}, {&apv, options.APV}, {&p2s, options.P2S}} {
+		if *field.dst, err = buffer.Bytes(field.src); err != nil {
+			return nil, err
+		}
+	}
+
+	rcpts := make([]recipient, len(keys))
+
+	for i := range keys {
+		rcpt, err := newRecipient(&keys[i], len(keys) > 1)
+		if err != nil {
+			return nil, err
+		}
+
+		// sender authenticated variant of the key agreement, the direct one is for single recipient only
+		if sender != nil {
+			if rcpt.alg == jose.ECDH_ES && len(keys) > 1 {
+				rcpt.alg = jose.ECDH_ES_A256KW
+			}
+
+			if a, ok := authenticated[rcpt.alg]; ok {
+				rcpt.alg = a
+			}
+		}
+
+		if alg != "" {
+			rcpt.alg = alg
+		}
+
+		rcpt.apu, rcpt.apv, rcpt.p2c, rcpt.p2s, rcpt.sender, rcpt.skid = apu, apv, options.P2C, p2s, sender, skid
+		rcpts[i] = rcpt
+	}
+
+	return rcpts, nil
+}
+
+func (m *Module) Decrypt(
+	keyIn interface{},
+	token string,
+	options *DecryptOptions,
+) (result sobek.ArrayBuffer, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() {
+		m.metrics.Measure(moduleName, metrics.Decrypt, alg, start, err)
+		m.trace.Token(moduleName, metrics.Decrypt, token, []interface{}{keyIn}, err)
+	}()
+
+	alg, decrypted, err := decryptToken(m.config, keyIn, token, options)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(decrypted.Plaintext), nil
+}
+
+// Decrypted is the plaintext of JWE with the merged header of its first recipient.
+type Decrypted struct {
+	Plaintext []byte
+	Header    map[string]interface{}
+}
+
+// DecryptWith is the unmeasured variant of Module.Decrypt with the given configuration.
+func DecryptWith(cfg *config.Config, keyIn interface{}, token string, options *DecryptOptions) (*Decrypted, error) {
+	_, decrypted, err := decryptToken(cfg, keyIn, token, options)
+
+	return decrypted, err
+}
+
+// decryptToken returns the key management algorithm (of the first recipient on error) and the decrypted JWE.
+func decryptToken(
+	cfg *config.Config,
+	keyIn interface{},
+	token string,
+	options *DecryptOptions,
+) (alg string, decrypted *Decrypted, err error) {
+	options = decryptOptions(cfg, options)
+
+	key, err := decryptionKey(keyIn)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	if err := cfg.CheckToken(token); err != nil {
+		return alg, nil, err
+	}
+
+	obj, err := parseEncrypted(token)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	if len(obj.headers) != 0 {
+		alg, _ = obj.headers[0]["alg"].(string)
+	}
+
+	if err := checkAlgorithms(obj.headers, options); err != nil {
+		return alg, nil, err
+	}
+
+	aad, err := buffer.Bytes(options.AAD)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	if aad != nil && subtle.ConstantTimeCompare(aad, obj.aad) != 1 {
+		return alg, nil, ErrAADMismatch
+	}
+
+	critical, err := usesCritical(obj, cfg)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	var (
+		index     int
+		plaintext []byte
+	)
+
+	// go-jose has no ECDH-1PU support and rejects critical header parameters
+	switch {
+	case uses1PU(obj.headers):
+		index, plaintext, err = decrypt1PU(obj, key, options.Sender, cfg.MaxSize())
+	case critical:
+		index, plaintext, err = decryptCritical(obj, key, cfg.MaxSize())
+	default:
+		index, plaintext, err = decryptJOSE(token, key)
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the header of the decrypted recipient, not of the first one
+	header := obj.headers[index]
+	alg, _ = header["alg"].(string)
+
+	if !allowed(alg, options.Algorithms) {
+		return alg, nil, fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, alg)
+	}
+
+	return alg, &Decrypted{Plaintext: plaintext, Header: header}, nil
+}
+
+// decryptOptions returns the options with the configured algorithms, if not given.
+func decryptOptions(cfg *config.Config, options *DecryptOptions) *DecryptOptions {
+	if options == nil {
+		options = &DecryptOptions{}
+	}
+
+	if options.Algorithms == nil && cfg != nil && len(cfg.Algorithms) != 0 {
+		opts := *options
+		opts.Algorithms = cfg.Algorithms
+		options = &opts
+	}
+
+	return options
+}
+
+// decryptJOSE decrypts with go-jose, returns the index of the decrypted recipient and the plaintext.
+func decryptJOSE(token string, key interface{}) (int, []byte, error) {
+	obj, err := jose.ParseEncrypted(token, algorithm.Keys, algorithm.Encryptions)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	index, _, plaintext, err := obj.DecryptMulti(key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return index, plaintext, nil
+}
+
+// decryptionKey returns the key, or the bytes of passphrase for password based encryption.
+func decryptionKey(in interface{}) (interface{}, error) {
+	if password, ok := in.(string); ok {
+		return []byte(password), nil
+	}
+
+	keys, err := jwk.KeySet(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("%w: %d decryption keys", ErrUnsupportedKey, len(keys))
+	}
+
+	return &keys[0], nil
+}
+
+// checkAlgorithms returns error if the content encryption algorithm is not allowed,
+// or none of the recipients uses an allowed key management algorithm.
+func checkAlgorithms(rcpts []map[string]interface{}, options *DecryptOptions) error {
+	if len(options.Algorithms) == 0 && len(options.Encryptions) == 0 {
+		return nil
+	}
+
+	found := make([]string, 0, len(rcpts))
+
+	for _, rcpt := range rcpts {
+		if enc, _ := rcpt["enc"].(string); !allowed(enc, options.Encryptions) {
+			return fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, enc)
+		}
+
+		alg, _ := rcpt["alg"].(string)
+		if allowed(alg, options.Algorithms) {
+			return nil
+		}
+
+		found = append(found, alg)
+	}
+
+	return fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, strings.Join(found, ", "))
+}
+
+func allowed(value string, list []string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newRecipient creates recipient from the public part of the key, with key algorithm taken from the key or derived from the key type.
+func newRecipient(key *jose.JSONWebKey, multi bool) (recipient, error) {
+	pub := key
+	if !key.IsPublic() {
+		if p := key.Public(); p.Key != nil {
+			pub = &p
+		}
+	}
+
+	// X25519 keys are not supported by go-jose
+	if priv, ok := pub.Key.(*ecdh.PrivateKey); ok {
+		p := *key
+		p.Key = priv.PublicKey()
+		pub = &p
+	}
+
+	alg := jose.KeyAlgorithm(key.Algorithm)
+	if !keyAlgorithms[alg] {
+		var err error
+
+		if alg, err = defaultAlgorithm(pub); err != nil {
+			return recipient{}, err
+		}
+
+		// direct key agreement is for single recipient only
+		if multi && alg == jose.ECDH_ES {
+			alg = jose.ECDH_ES_A256KW
+		}
+	}
+
+	return recipient{alg: alg, key: pub.Key, kid: key.KeyID}, nil
+}
+
+// senderKey returns the ECDH key and key ID of the sender's private EC or X25519 key, nil if not given.
+func senderKey(in interface{}) (*ecdh.PrivateKey, string, error) {
+	if in == nil {
+		return nil, "", nil
+	}
+
+	keys, err := jwk.KeySet(in)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(keys) != 1 {
+		return nil, "", fmt.Errorf("%w: %d sender keys", ErrUnsupportedKey, len(keys))
+	}
+
+	priv, ok := agreementPrivate(keys[0].Key)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %T sender key", ErrUnsupportedKey, keys[0].Key)
+	}
+
+	return priv, keys[0].KeyID, nil
+}
+
+func defaultAlgorithm(key *jose.JSONWebKey) (jose.KeyAlgorithm, error) {
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		return jose.RSA_OAEP_256, nil
+	case *ecdsa.PublicKey, *ecdh.PublicKey:
+		return jose.ECDH_ES, nil
+	case []byte:
+		switch len(k) {
+		case 16:
+			return jose.A128KW, nil
+		case 24:
+			return jose.A192KW, nil
+		case 32:
+			return jose.A256KW, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedKey, key.Key)
+}
@@ -0,0 +1,206 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwe
+
+import (
+	"crypto/aes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	josecipher "github.com/go-jose/go-jose/v4/cipher"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+// wrapAlgorithms are the key management algorithms usable without JWE construction.
+var wrapAlgorithms = map[jose.KeyAlgorithm]bool{
+	jose.RSA_OAEP:     true,
+	jose.RSA_OAEP_256: true,
+	jose.A128KW:       true,
+	jose.A192KW:       true,
+	jose.A256KW:       true,
+}
+
+var kekSizes = map[jose.KeyAlgorithm]int{
+	jose.A128KW: 16,
+	jose.A192KW: 24,
+	jose.A256KW: 32,
+}
+
+// WrapKey encrypts the content key to the key, like for a JWE recipient.
+func (m *Module) WrapKey(keyIn interface{}, cekIn interface{}, algorithm string) (wrapped sobek.ArrayBuffer, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Encrypt, alg, start, err) }()
+
+	cek, err := buffer.Bytes(cekIn)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	key, err := wrappingKey(keyIn)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	rcpt, err := newRecipient(key, false)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	if rcpt.alg, err = m.wrapAlgorithm(rcpt, algorithm); err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	alg = string(rcpt.alg)
+
+	info, err := wrap(rcpt, cek)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(info.encryptedKey), nil
+}
+
+// UnwrapKey decrypts the wrapped content key with the private (or symmetric) key.
+func (m *Module) UnwrapKey(keyIn interface{}, wrappedIn interface{}, algorithm string) (cek sobek.ArrayBuffer, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Decrypt, alg, start, err) }()
+
+	wrapped, err := buffer.Bytes(wrappedIn)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	key, err := wrappingKey(keyIn)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	rcpt, err := newRecipient(key, false)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	kalg, err := m.wrapAlgorithm(rcpt, algorithm)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	alg = string(kalg)
+
+	unwrapped, err := unwrap(kalg, key.Key, wrapped)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(unwrapped), nil
+}
+
+func wrappingKey(keyIn interface{}) (*jose.JSONWebKey, error) {
+	keys, err := jwk.KeySet(keyIn)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("%w: %d keys", ErrUnsupportedKey, len(keys))
+	}
+
+	return &keys[0], nil
+}
+
+// wrapAlgorithm returns the requested algorithm, or the algorithm of the key if not requested.
+func (m *Module) wrapAlgorithm(rcpt recipient, requested string) (jose.KeyAlgorithm, error) {
+	alg := rcpt.alg
+	if requested != "" {
+		alg = jose.KeyAlgorithm(strings.ToUpper(requested))
+	}
+
+	if !wrapAlgorithms[alg] {
+		return alg, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	if size, ok := kekSizes[alg]; ok {
+		if kek, isKEK := rcpt.key.([]byte); isKEK && len(kek) != size {
+			return alg, fmt.Errorf("%w: %d bytes key for %s", ErrUnsupportedKey, len(kek), alg)
+		}
+	}
+
+	return alg, m.config.Allow(string(alg))
+}
+
+func unwrap(alg jose.KeyAlgorithm, key interface{}, wrapped []byte) ([]byte, error) {
+	switch alg {
+	case jose.RSA_OAEP, jose.RSA_OAEP_256:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, alg)
+		}
+
+		hash := sha256.New()
+		if alg == jose.RSA_OAEP {
+			hash = sha1.New()
+		}
+
+		cek, err := rsa.DecryptOAEP(hash, nil, priv, wrapped, nil)
+		if err != nil {
+			return nil, ErrDecryption
+		}
+
+		return cek, nil
+	default:
+		kek, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrUnsupportedKey, key, alg)
+		}
+
+		return keyUnwrap(kek, wrapped)
+	}
+}
+
+func keyUnwrap(kek []byte, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	cek, err := josecipher.KeyUnwrap(block, wrapped)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	return cek, nil
+}
@@ -0,0 +1,261 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwe
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	serializationCompact   = "compact"
+	serializationFlattened = "flattened"
+	serializationGeneral   = "general"
+)
+
+var (
+	ErrUnsupportedSerialization = errors.New("unsupported serialization")
+	ErrMalformed                = errors.New("malformed jwe")
+)
+
+// serialize produces the requested serialization form.
+func serialize(obj *encrypted, serialization string) (string, error) {
+	switch strings.ToLower(serialization) {
+	case "", serializationCompact:
+		if len(obj.recipients) != 1 || obj.recipients[0].header != nil || len(obj.aad) != 0 {
+			return "", fmt.Errorf("%w: %s with %d recipients", ErrUnsupportedSerialization, serializationCompact, len(obj.recipients))
+		}
+
+		return strings.Join([]string{
+			obj.protected,
+			encode(obj.recipients[0].encryptedKey),
+			encode(obj.iv),
+			encode(obj.ciphertext),
+			encode(obj.tag),
+		}, "."), nil
+	case serializationFlattened:
+		if len(obj.recipients) != 1 {
+			return "", fmt.Errorf("%w: %s with %d recipients", ErrUnsupportedSerialization, serialization, len(obj.recipients))
+		}
+
+		raw := general(obj)
+
+		delete(raw, "recipients")
+
+		for k, v := range recipientJSON(obj.recipients[0]) {
+			raw[k] = v
+		}
+
+		return marshal(raw)
+	case serializationGeneral:
+		return marshal(general(obj))
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedSerialization, serialization)
+	}
+}
+
+func general(obj *encrypted) map[string]interface{} {
+	rcpts := make([]interface{}, len(obj.recipients))
+
+	for i, rcpt := range obj.recipients {
+		rcpts[i] = recipientJSON(rcpt)
+	}
+
+	raw := map[string]interface{}{
+		"protected":  obj.protected,
+		"recipients": rcpts,
+		"iv":         encode(obj.iv),
+		"ciphertext": encode(obj.ciphertext),
+		"tag":        encode(obj.tag),
+	}
+
+	if len(obj.aad) != 0 {
+		raw["aad"] = encode(obj.aad)
+	}
+
+	return raw
+}
+
+func recipientJSON(rcpt recipientInfo) map[string]interface{} {
+	raw := map[string]interface{}{}
+
+	if rcpt.header != nil {
+		raw["header"] = rcpt.header
+	}
+
+	if len(rcpt.encryptedKey) != 0 {
+		raw["encrypted_key"] = encode(rcpt.encryptedKey)
+	}
+
+	return raw
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func marshal(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// parsed is a JWE parsed from any serialization form, with the merged
+// (protected, shared unprotected and per recipient) header of every recipient.
+type parsed struct {
+	encrypted
+	headers     []map[string]interface{}
+	unprotected []map[string]interface{}
+}
+
+type rawRecipient struct {
+	Header       map[string]interface{} `json:"header"`
+	EncryptedKey string                 `json:"encrypted_key"`
+}
+
+type rawJSON struct {
+	rawRecipient
+	Protected   string                 `json:"protected"`
+	Unprotected map[string]interface{} `json:"unprotected"`
+	Recipients  []rawRecipient         `json:"recipients"`
+	AAD         string                 `json:"aad"`
+	IV          string                 `json:"iv"`
+	Ciphertext  string                 `json:"ciphertext"`
+	Tag         string                 `json:"tag"`
+}
+
+func parseEncrypted(token string) (*parsed, error) {
+	token = strings.TrimSpace(token)
+
+	var raw rawJSON
+
+	if strings.HasPrefix(token, "{") {
+		if err := json.Unmarshal([]byte(token), &raw); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+		}
+	} else {
+		parts := strings.Split(token, ".")
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("%w: %d parts", ErrMalformed, len(parts))
+		}
+
+		raw.Protected, raw.EncryptedKey, raw.IV, raw.Ciphertext, raw.Tag = parts[0], parts[1], parts[2], parts[3], parts[4]
+	}
+
+	if raw.Recipients == nil {
+		raw.Recipients = []rawRecipient{raw.rawRecipient}
+	}
+
+	shared, err := decodeHeader(raw.Protected)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range raw.Unprotected {
+		shared[k] = v
+	}
+
+	res := &parsed{encrypted: encrypted{protected: raw.Protected}}
+
+	for _, field := range []struct {
+		dst *[]byte
+		src string
+	}{{&res.aad, raw.AAD}, {&res.iv, raw.IV}, {&res.ciphertext, raw.Ciphertext}, {&res.tag, raw.Tag}} {
+		if *field.dst, err = decode(field.src); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rcpt := range raw.Recipients {
+		info := recipientInfo{header: rcpt.Header}
+
+		if info.encryptedKey, err = decode(rcpt.EncryptedKey); err != nil {
+			return nil, err
+		}
+
+		header := make(map[string]interface{}, len(shared)+len(rcpt.Header))
+
+		for k, v := range shared {
+			header[k] = v
+		}
+
+		for k, v := range rcpt.Header {
+			header[k] = v
+		}
+
+		unprotected := make(map[string]interface{}, len(raw.Unprotected)+len(rcpt.Header))
+
+		for k, v := range raw.Unprotected {
+			unprotected[k] = v
+		}
+
+		for k, v := range rcpt.Header {
+			unprotected[k] = v
+		}
+
+		res.recipients = append(res.recipients, info)
+		res.headers = append(res.headers, header)
+		res.unprotected = append(res.unprotected, unprotected)
+	}
+
+	return res, nil
+}
+
+func decode(src string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+	}
+
+	return b, nil
+}
+
+func decodeHeader(src string) (map[string]interface{}, error) {
+	header := map[string]interface{}{}
+
+	if src == "" {
+		return header, nil
+	}
+
+	b, err := decode(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+	}
+
+	// null unmarshals to nil map
+	if header == nil {
+		return nil, fmt.Errorf("%w: protected header is not a JSON object", ErrMalformed)
+	}
+
+	return header, nil
+}
@@ -0,0 +1,228 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+)
+
+// unmarshalKey parses the JSON of the key, go-jose supports no Brainpool curves.
+func unmarshalKey(raw []byte) (*jose.JSONWebKey, error) {
+	var probe struct {
+		Kty string `json:"kty"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		D   string `json:"d"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+	}
+
+	key := &jose.JSONWebKey{}
+
+	if json.Unmarshal(raw, &probe) != nil || probe.Kty != "EC" {
+		return key, key.UnmarshalJSON(raw)
+	}
+
+	curve, alg, ok := algorithm.BrainpoolCurve(probe.Crv)
+	if !ok {
+		return key, key.UnmarshalJSON(raw)
+	}
+
+	values, err := decodeComponents(encodingBase64URL, map[string]string{"x": probe.X, "y": probe.Y, "d": probe.D})
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Key, err = brainpoolMaterial(curve, values["x"], values["y"], values["d"]); err != nil {
+		return nil, err
+	}
+
+	key.KeyID, key.Algorithm, key.Use = probe.Kid, probe.Alg, probe.Use
+
+	if key.Algorithm == "" {
+		key.Algorithm = string(alg)
+	}
+
+	return key, nil
+}
+
+// unmarshalKeySet parses the JSON of the key set, with the Brainpool keys.
+func unmarshalKeySet(raw []byte) ([]jose.JSONWebKey, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]jose.JSONWebKey, len(set.Keys))
+
+	for i := range set.Keys {
+		key, err := unmarshalKey(set.Keys[i])
+		if err != nil {
+			return nil, err
+		}
+
+		keys[i] = *key
+	}
+
+	return keys, nil
+}
+
+// brainpoolMaterial returns the public key of the point, or the private key of d, x and y are computed from d
+// if not given. The point must be on the curve.
+func brainpoolMaterial(curve elliptic.Curve, x, y, d *big.Int) (interface{}, error) {
+	params := curve.Params()
+
+	if d != nil {
+		if d.Sign() <= 0 || d.Cmp(params.N) >= 0 {
+			return nil, fmt.Errorf("%w: d out of range", ErrInvalidComponents)
+		}
+
+		px, py := curve.ScalarBaseMult(d.FillBytes(make([]byte, (params.N.BitLen()+7)/8)))
+
+		if (x != nil || y != nil) && (x == nil || y == nil || px.Cmp(x) != 0 || py.Cmp(y) != 0) {
+			return nil, fmt.Errorf("%w: x and y don't match d", ErrInvalidComponents)
+		}
+
+		return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: px, Y: py}, D: d}, nil
+	}
+
+	if x == nil || y == nil {
+		return nil, fmt.Errorf("%w: missing x or y", ErrInvalidComponents)
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("%w: point is not on the %s curve", ErrInvalidComponents, params.Name)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// brainpoolCurve returns the curve of ESB256, ESB384 or ESB512 algorithm.
+func brainpoolCurve(alg string) (elliptic.Curve, bool) {
+	if !algorithm.IsBrainpool(alg) {
+		return nil, false
+	}
+
+	curve, _, _ := algorithm.BrainpoolCurve(alg)
+
+	return curve, true
+}
+
+// brainpoolAdopt returns the key of the raw private key, or of the uncompressed point (SEC 1) of the public key.
+func brainpoolAdopt(curve elliptic.Curve, alg string, in []byte, isPublic bool) (*jose.JSONWebKey, error) {
+	var x, y, d *big.Int
+
+	if isPublic {
+		size := (curve.Params().BitSize + 7) / 8
+		if len(in) != 1+2*size || in[0] != 4 {
+			return nil, fmt.Errorf("%w: %d bytes uncompressed point", ErrInvalidComponents, len(in))
+		}
+
+		x, y = new(big.Int).SetBytes(in[1:1+size]), new(big.Int).SetBytes(in[1+size:])
+	} else {
+		d = new(big.Int).SetBytes(in)
+	}
+
+	material, err := brainpoolMaterial(curve, x, y, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return componentKey(material, alg, &ComponentOptions{})
+}
+
+// brainpoolPublic returns the public key of Brainpool EC key, false for other keys.
+func brainpoolPublic(key interface{}) (*ecdsa.PublicKey, string, bool) {
+	var public *ecdsa.PublicKey
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		public = &k.PublicKey
+	case *ecdsa.PublicKey:
+		public = k
+	default:
+		return nil, "", false
+	}
+
+	crv, ok := algorithm.BrainpoolCrv(public.Curve)
+
+	return public, crv, ok
+}
+
+// brainpoolObject returns the JWK of Brainpool EC key as plain object, false for other keys.
+func brainpoolObject(key *jose.JSONWebKey) (map[string]interface{}, bool) {
+	public, crv, ok := brainpoolPublic(key.Key)
+	if !ok {
+		return nil, false
+	}
+
+	size := (public.Curve.Params().BitSize + 7) / 8
+
+	obj := map[string]interface{}{
+		"kty": "EC",
+		"crv": crv,
+		"x":   encode(public.X.FillBytes(make([]byte, size))),
+		"y":   encode(public.Y.FillBytes(make([]byte, size))),
+	}
+
+	if private, ok := key.Key.(*ecdsa.PrivateKey); ok {
+		obj["d"] = encode(private.D.FillBytes(make([]byte, (public.Curve.Params().N.BitLen()+7)/8)))
+	}
+
+	for name, value := range map[string]string{"kid": key.KeyID, "alg": key.Algorithm, "use": key.Use} {
+		if value != "" {
+			obj[name] = value
+		}
+	}
+
+	return obj, true
+}
+
+// brainpoolThumbprint returns the RFC 7638 thumbprint of Brainpool EC key, false for other keys.
+func brainpoolThumbprint(key *jose.JSONWebKey) (string, bool) {
+	public, crv, ok := brainpoolPublic(key.Key)
+	if !ok {
+		return "", false
+	}
+
+	size := (public.Curve.Params().BitSize + 7) / 8
+	input := fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`,
+		crv, encode(public.X.FillBytes(make([]byte, size))), encode(public.Y.FillBytes(make([]byte, size))))
+
+	sum := sha256.Sum256([]byte(input))
+
+	return encode(sum[:]), true
+}
@@ -0,0 +1,205 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"container/list"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/config"
+)
+
+const cacheSize = 1024
+
+// cacheTTL is the lifetime of cache entries in nanoseconds, zero means entries expire only by eviction.
+var cacheTTL atomic.Int64
+
+var cacheTTLOnce sync.Once
+
+// initCacheTTL sets the lifetime of the caches from the first configuration having it, instead of every VU setting it.
+func initCacheTTL(cfg *config.Config) {
+	if cfg.CacheTTL == nil {
+		return
+	}
+
+	cacheTTLOnce.Do(func() { SetCacheTTL(*cfg.CacheTTL) })
+}
+
+// SetCacheTTL sets the lifetime of the parsed key caches, zero disables expiration.
+func SetCacheTTL(ttl time.Duration) {
+	cacheTTL.Store(int64(ttl))
+}
+
+// CacheTTL returns the lifetime of cache entries, zero if entries expire only by eviction.
+func CacheTTL() time.Duration {
+	return time.Duration(cacheTTL.Load())
+}
+
+// keyCache is a bounded LRU cache of parsed keys by source, shared by the VUs.
+// Callers get deep copies of the cached keys, so a VU can't modify the key material of the others.
+type keyCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+}
+
+type cacheEntry struct {
+	source string
+	keys   []jose.JSONWebKey
+	added  time.Time
+}
+
+var (
+	parsedKeys    = newKeyCache(cacheSize)
+	parsedKeySets = newKeyCache(cacheSize)
+	parsedPEMs    = newKeyCache(cacheSize)
+)
+
+func newKeyCache(size int) *keyCache {
+	return &keyCache{entries: make(map[string]*list.Element), order: list.New(), size: size}
+}
+
+func (c *keyCache) get(source string) ([]jose.JSONWebKey, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[source]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+
+	if ttl := time.Duration(cacheTTL.Load()); ttl > 0 && time.Since(entry.added) > ttl {
+		c.order.Remove(elem)
+		delete(c.entries, source)
+
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return cloneKeys(entry.keys), true
+}
+
+func (c *keyCache) put(source string, keys []jose.JSONWebKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[source]; ok {
+		c.order.MoveToFront(elem)
+
+		return
+	}
+
+	entry := &cacheEntry{
+		source: source,
+		keys:   cloneKeys(keys),
+		added:  time.Now(),
+	}
+	c.entries[source] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).source)
+	}
+}
+
+func cloneKeys(keys []jose.JSONWebKey) []jose.JSONWebKey {
+	out := make([]jose.JSONWebKey, len(keys))
+
+	for i := range keys {
+		out[i] = keys[i]
+		out[i].Key = cloneKeyMaterial(keys[i].Key)
+		out[i].Certificates = append([]*x509.Certificate(nil), keys[i].Certificates...)
+		out[i].CertificateThumbprintSHA1 = cloneBytes(keys[i].CertificateThumbprintSHA1)
+		out[i].CertificateThumbprintSHA256 = cloneBytes(keys[i].CertificateThumbprintSHA256)
+	}
+
+	return out
+}
+
+// cloneKeyMaterial copies the mutable parts of the key, immutable keys (e.g. crypto/ecdh) are shared.
+func cloneKeyMaterial(key interface{}) interface{} {
+	switch k := key.(type) {
+	case []byte:
+		return cloneBytes(k)
+	case ed25519.PrivateKey:
+		return ed25519.PrivateKey(cloneBytes(k))
+	case ed25519.PublicKey:
+		return ed25519.PublicKey(cloneBytes(k))
+	case *ecdsa.PrivateKey:
+		pub := cloneKeyMaterial(&k.PublicKey).(*ecdsa.PublicKey)
+
+		return &ecdsa.PrivateKey{PublicKey: *pub, D: cloneInt(k.D)}
+	case *ecdsa.PublicKey:
+		return &ecdsa.PublicKey{Curve: k.Curve, X: cloneInt(k.X), Y: cloneInt(k.Y)}
+	case *rsa.PrivateKey:
+		// the struct copy keeps the precomputed values, their numbers are copied below
+		priv := *k
+
+		priv.N = cloneInt(k.N)
+		priv.D = cloneInt(k.D)
+		priv.Primes = make([]*big.Int, len(k.Primes))
+
+		for i, p := range k.Primes {
+			priv.Primes[i] = cloneInt(p)
+		}
+
+		priv.Precomputed.Dp = cloneInt(k.Precomputed.Dp)
+		priv.Precomputed.Dq = cloneInt(k.Precomputed.Dq)
+		priv.Precomputed.Qinv = cloneInt(k.Precomputed.Qinv)
+
+		return &priv
+	case *rsa.PublicKey:
+		return &rsa.PublicKey{N: cloneInt(k.N), E: k.E}
+	default:
+		return key
+	}
+}
+
+func cloneInt(n *big.Int) *big.Int {
+	if n == nil {
+		return nil
+	}
+
+	return new(big.Int).Set(n)
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
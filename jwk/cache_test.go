@@ -0,0 +1,83 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// TestCacheCopies checks that modifying a key got from the cache doesn't change the cached key.
+func TestCacheCopies(t *testing.T) {
+	t.Parallel()
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newKeyCache(cacheSize)
+	cache.put("keys", []jose.JSONWebKey{{Key: ec}, {Key: rs}, {Key: ed}, {Key: []byte("secret")}})
+
+	got, _ := cache.get("keys")
+
+	got[0].Key.(*ecdsa.PrivateKey).D.SetInt64(1)
+	got[1].Key.(*rsa.PrivateKey).N.SetInt64(1)
+	got[2].Key.(ed25519.PrivateKey)[0] ^= 0xff
+	got[3].Key.([]byte)[0] = 'x'
+
+	again, _ := cache.get("keys")
+
+	if again[0].Key.(*ecdsa.PrivateKey).D.Cmp(ec.D) != 0 || ec.D.Cmp(big.NewInt(1)) == 0 {
+		t.Error("EC key modified")
+	}
+
+	if again[1].Key.(*rsa.PrivateKey).N.Cmp(rs.N) != 0 || again[1].Key.(*rsa.PrivateKey).Validate() != nil {
+		t.Error("RSA key modified")
+	}
+
+	if !again[2].Key.(ed25519.PrivateKey).Equal(ed) {
+		t.Error("Ed25519 key modified")
+	}
+
+	if string(again[3].Key.([]byte)) != "secret" {
+		t.Error("symmetric key modified")
+	}
+}
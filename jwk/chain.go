@@ -0,0 +1,303 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/config"
+)
+
+var ErrInvalidChain = errors.New("invalid certificate chain")
+
+const pemCertificate = "CERTIFICATE"
+
+type ChainOptions struct {
+	Intermediates interface{} `js:"intermediates"`
+	Now           interface{} `js:"now"`
+}
+
+// VerifyChain returns the key if its x5c certificate chain is valid to one of the roots.
+func (m *Module) VerifyChain(key *jose.JSONWebKey, roots interface{}, options *ChainOptions) (*jose.JSONWebKey, error) {
+	if key == nil || len(key.Certificates) == 0 {
+		return nil, fmt.Errorf("%w: missing x5c", ErrInvalidChain)
+	}
+
+	if err := verifyChain(m.config, key.Certificates, roots, options); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (m *Module) FromX5C(token string, roots interface{}, options *ChainOptions) (*jose.JSONWebKey, error) {
+	return ChainKey(m.config, token, roots, options)
+}
+
+// ChainKey returns the public key of the leaf certificate from the x5c header of a JWS (or JWT),
+// after validating the chain to one of the roots. The alg and kid of the key are taken from the header.
+func ChainKey(cfg *config.Config, token string, roots interface{}, options *ChainOptions) (*jose.JSONWebKey, error) {
+	if err := cfg.CheckToken(token); err != nil {
+		return nil, err
+	}
+
+	header, err := tokenHeader(token)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := x5c(header["x5c"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChain(cfg, chain, roots, options); err != nil {
+		return nil, err
+	}
+
+	key := &jose.JSONWebKey{Key: chain[0].PublicKey, Certificates: chain, Use: "sig"}
+	key.Algorithm, _ = header["alg"].(string)
+	key.KeyID, _ = header["kid"].(string)
+
+	return key, nil
+}
+
+// verifyChain validates the chain (leaf first) to one of the roots, at the time of the options (the configured clock by
+// default). Certificates of the chain after the leaf and the intermediates of the options may be used as intermediates.
+func verifyChain(cfg *config.Config, chain []*x509.Certificate, roots interface{}, options *ChainOptions) error {
+	if options == nil {
+		options = &ChainOptions{}
+	}
+
+	rootPool, err := certPool(roots)
+	if err != nil {
+		return err
+	}
+
+	if rootPool == nil {
+		return fmt.Errorf("%w: missing roots", ErrInvalidChain)
+	}
+
+	extra, err := certificates(options.Intermediates)
+	if err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range append(chain[1:], extra...) {
+		intermediates.AddCert(cert)
+	}
+
+	now, err := cfg.OptionTime(options.Now)
+	if err != nil {
+		return err
+	}
+
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidChain, err.Error())
+	}
+
+	return nil
+}
+
+func certPool(in interface{}) (*x509.CertPool, error) {
+	certs, err := certificates(in)
+	if err != nil || len(certs) == 0 {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+
+	return pool, nil
+}
+
+// certificates parses PEM encoded certificates (bundle), DER encoded certificate or array of them.
+func certificates(in interface{}) ([]*x509.Certificate, error) {
+	switch value := in.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return pemCertificates(value)
+	case []interface{}:
+		if len(value) > 0 {
+			if _, ok := value[0].(float64); !ok {
+				var certs []*x509.Certificate
+
+				for _, item := range value {
+					sub, err := certificates(item)
+					if err != nil {
+						return nil, err
+					}
+
+					certs = append(certs, sub...)
+				}
+
+				return certs, nil
+			}
+		}
+	}
+
+	der, err := buffer.Bytes(in)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidChain, err.Error())
+	}
+
+	return []*x509.Certificate{cert}, nil
+}
+
+func pemCertificates(source string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for rest := []byte(source); ; {
+		var block *pem.Block
+
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+
+		if block.Type != pemCertificate {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidChain, err.Error())
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no PEM certificate", ErrInvalidChain)
+	}
+
+	return certs, nil
+}
+
+// x5c parses the base64 (not base64url) encoded DER certificates of the x5c header.
+func x5c(value interface{}) ([]*x509.Certificate, error) {
+	items, _ := value.([]interface{})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: missing x5c", ErrInvalidChain)
+	}
+
+	chain := make([]*x509.Certificate, 0, len(items))
+
+	for _, item := range items {
+		str, _ := item.(string)
+
+		der, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid x5c: %s", ErrInvalidChain, err.Error())
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid x5c: %s", ErrInvalidChain, err.Error())
+		}
+
+		chain = append(chain, cert)
+	}
+
+	return chain, nil
+}
+
+// tokenHeader returns the header of compact token, or the merged protected and unprotected header of JSON serialized
+// JWS (of the first signature for the general syntax).
+func tokenHeader(token string) (map[string]interface{}, error) {
+	token = strings.TrimSpace(token)
+
+	if !strings.HasPrefix(token, "{") {
+		header := map[string]interface{}{}
+
+		if err := protectedHeader(strings.SplitN(token, ".", 2)[0], header); err != nil {
+			return nil, err
+		}
+
+		return header, nil
+	}
+
+	type signature struct {
+		Protected string                 `json:"protected"`
+		Header    map[string]interface{} `json:"header"`
+	}
+
+	var serialized struct {
+		signature
+		Signatures []signature `json:"signatures"`
+	}
+
+	if err := json.Unmarshal([]byte(token), &serialized); err != nil {
+		return nil, err
+	}
+
+	sig := serialized.signature
+	if len(serialized.Signatures) > 0 {
+		sig = serialized.Signatures[0]
+	}
+
+	header := map[string]interface{}{}
+
+	for name, value := range sig.Header {
+		header[name] = value
+	}
+
+	if err := protectedHeader(sig.Protected, header); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+func protectedHeader(segment string, header map[string]interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidChain, err.Error())
+	}
+
+	return json.Unmarshal(raw, &header)
+}
@@ -0,0 +1,477 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+)
+
+var ErrInvalidComponents = errors.New("invalid key components")
+
+const (
+	encodingBase64URL = "base64url"
+	encodingHex       = "hex"
+)
+
+type ComponentOptions struct {
+	Encoding  string `js:"encoding"`
+	Algorithm string `js:"alg"`
+	KeyID     string `js:"kid"`
+	Use       string `js:"use"`
+}
+
+type RSAComponents struct {
+	N string `js:"n"`
+	E string `js:"e"`
+	D string `js:"d"`
+	P string `js:"p"`
+	Q string `js:"q"`
+}
+
+// FromRSAComponents builds RSA key from the modulus, exponents and (optionally) primes, the primes are
+// recovered from n, e and d if not given, the CRT values are always computed.
+func (m *Module) FromRSAComponents(components *RSAComponents, options *ComponentOptions) (*jose.JSONWebKey, error) {
+	if components == nil {
+		return nil, fmt.Errorf("%w: missing n and e", ErrInvalidComponents)
+	}
+
+	if options == nil {
+		options = &ComponentOptions{}
+	}
+
+	values, err := decodeComponents(options.Encoding, map[string]string{
+		"n": components.N, "e": components.E, "d": components.D, "p": components.P, "q": components.Q,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	n, e := values["n"], values["e"]
+	if n == nil || e == nil {
+		return nil, fmt.Errorf("%w: missing n or e", ErrInvalidComponents)
+	}
+
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: e out of range", ErrInvalidComponents)
+	}
+
+	pub := rsa.PublicKey{N: n, E: int(e.Int64())}
+
+	var key interface{} = &pub
+
+	if d := values["d"]; d != nil {
+		p, q := values["p"], values["q"]
+
+		if p == nil || q == nil {
+			if p, q, err = recoverPrimes(n, e, d); err != nil {
+				return nil, err
+			}
+		}
+
+		priv := &rsa.PrivateKey{PublicKey: pub, D: d, Primes: []*big.Int{p, q}}
+
+		if err := priv.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidComponents, err.Error())
+		}
+
+		priv.Precompute()
+
+		key = priv
+	}
+
+	return componentKey(key, string(jose.RS256), options)
+}
+
+type ECComponents struct {
+	Crv string `js:"crv"`
+	X   string `js:"x"`
+	Y   string `js:"y"`
+	D   string `js:"d"`
+}
+
+// FromECComponents builds EC key from the curve name and coordinates, the point is checked to be on the curve,
+// x and y are computed from d if not given.
+func (m *Module) FromECComponents(components *ECComponents, options *ComponentOptions) (*jose.JSONWebKey, error) {
+	if components == nil {
+		return nil, fmt.Errorf("%w: missing crv", ErrInvalidComponents)
+	}
+
+	if options == nil {
+		options = &ComponentOptions{}
+	}
+
+	var curve elliptic.Curve
+
+	switch components.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	}
+
+	values, err := decodeComponents(options.Encoding, map[string]string{
+		"x": components.X, "y": components.Y, "d": components.D,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	x, y, d := values["x"], values["y"], values["d"]
+
+	if curve == nil {
+		bp, alg, ok := algorithm.BrainpoolCurve(components.Crv)
+		if !ok || !strings.HasPrefix(components.Crv, "BP-") {
+			return nil, fmt.Errorf("%w: %s curve", ErrInvalidComponents, components.Crv)
+		}
+
+		material, err := brainpoolMaterial(bp, x, y, d)
+		if err != nil {
+			return nil, err
+		}
+
+		return componentKey(material, string(alg), options)
+	}
+
+	var pub *ecdsa.PublicKey
+
+	if x != nil || y != nil {
+		if pub, err = ecPoint(curve, x, y); err != nil {
+			return nil, err
+		}
+	}
+
+	alg := curveAlgorithms[components.Crv]
+
+	if d == nil {
+		if pub == nil {
+			return nil, fmt.Errorf("%w: missing x and y or d", ErrInvalidComponents)
+		}
+
+		return componentKey(pub, alg, options)
+	}
+
+	size := (curve.Params().N.BitLen() + 7) / 8
+	if len(d.Bytes()) > size {
+		return nil, fmt.Errorf("%w: d out of range", ErrInvalidComponents)
+	}
+
+	priv, err := ecdsa.ParseRawPrivateKey(curve, d.FillBytes(make([]byte, size)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidComponents, err.Error())
+	}
+
+	if pub != nil && !pub.Equal(&priv.PublicKey) {
+		return nil, fmt.Errorf("%w: x and y don't match d", ErrInvalidComponents)
+	}
+
+	return componentKey(priv, alg, options)
+}
+
+var curveAlgorithms = map[string]string{
+	"P-256": string(jose.ES256),
+	"P-384": string(jose.ES384),
+	"P-521": string(jose.ES512),
+}
+
+// ecPoint returns the public key of the coordinates, fails if the point is not on the curve.
+func ecPoint(curve elliptic.Curve, x, y *big.Int) (*ecdsa.PublicKey, error) {
+	if x == nil || y == nil {
+		return nil, fmt.Errorf("%w: missing x or y", ErrInvalidComponents)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x.Bytes()) > size || len(y.Bytes()) > size {
+		return nil, fmt.Errorf("%w: coordinates out of range", ErrInvalidComponents)
+	}
+
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+
+	x.FillBytes(point[1 : 1+size])
+	y.FillBytes(point[1+size:])
+
+	pub, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+	if err != nil {
+		return nil, fmt.Errorf("%w: point is not on the %s curve", ErrInvalidComponents, curve.Params().Name)
+	}
+
+	return pub, nil
+}
+
+type OKPComponents struct {
+	Crv string `js:"crv"`
+	X   string `js:"x"`
+	D   string `js:"d"`
+}
+
+// FromOKPComponents builds Ed25519 or X25519 key from the raw public and private bytes, x is computed from d
+// if not given. X25519 keys are not supported by go-jose, they are returned as plain JWK objects.
+func (m *Module) FromOKPComponents(components *OKPComponents, options *ComponentOptions) (interface{}, error) {
+	if components == nil {
+		return nil, fmt.Errorf("%w: missing crv", ErrInvalidComponents)
+	}
+
+	if options == nil {
+		options = &ComponentOptions{}
+	}
+
+	x, err := okpComponent(options.Encoding, "x", components.X)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := okpComponent(options.Encoding, "d", components.D)
+	if err != nil {
+		return nil, err
+	}
+
+	if x == nil && d == nil {
+		return nil, fmt.Errorf("%w: missing x or d", ErrInvalidComponents)
+	}
+
+	switch components.Crv {
+	case "Ed25519":
+		return ed25519Components(x, d, options)
+	case "X25519":
+		return x25519Components(x, d, options)
+	default:
+		return nil, fmt.Errorf("%w: %s curve", ErrInvalidComponents, components.Crv)
+	}
+}
+
+func ed25519Components(x, d []byte, options *ComponentOptions) (*jose.JSONWebKey, error) {
+	if d == nil {
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: %d bytes x", ErrInvalidComponents, len(x))
+		}
+
+		return componentKey(ed25519.PublicKey(x), string(jose.EdDSA), options)
+	}
+
+	if len(d) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%w: %d bytes d", ErrInvalidComponents, len(d))
+	}
+
+	priv := ed25519.NewKeyFromSeed(d)
+
+	if x != nil && !bytes.Equal(x, priv.Public().(ed25519.PublicKey)) {
+		return nil, fmt.Errorf("%w: x doesn't match d", ErrInvalidComponents)
+	}
+
+	return componentKey(priv, string(jose.EdDSA), options)
+}
+
+// x25519Components returns plain JWK object of the key, the defaults are ECDH-ES, "enc" and the thumbprint.
+func x25519Components(x, d []byte, options *ComponentOptions) (map[string]interface{}, error) {
+	if d != nil {
+		priv, err := ecdh.X25519().NewPrivateKey(d)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidComponents, err.Error())
+		}
+
+		public := priv.PublicKey().Bytes()
+
+		if x != nil && !bytes.Equal(x, public) {
+			return nil, fmt.Errorf("%w: x doesn't match d", ErrInvalidComponents)
+		}
+
+		x = public
+	}
+
+	public, err := ecdh.X25519().NewPublicKey(x)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidComponents, err.Error())
+	}
+
+	key := map[string]interface{}{
+		"kty": "OKP",
+		"crv": "X25519",
+		"x":   encode(x),
+		"alg": string(jose.ECDH_ES),
+		"use": "enc",
+		"kid": options.KeyID,
+	}
+
+	if d != nil {
+		key["d"] = encode(d)
+	}
+
+	if options.Algorithm != "" {
+		key["alg"] = options.Algorithm
+	}
+
+	if options.Use != "" {
+		key["use"] = options.Use
+	}
+
+	if options.KeyID == "" {
+		key["kid"], _ = x25519Thumbprint(&jose.JSONWebKey{Key: public})
+	}
+
+	return key, nil
+}
+
+// okpComponent decodes the raw bytes of non-empty value, unlike EC and RSA components leading zeros are kept.
+func okpComponent(encoding string, name string, value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	raw, err := decodeComponent(encoding, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrInvalidComponents, name, err.Error())
+	}
+
+	return raw, nil
+}
+
+// componentKey returns the key with the alg, use and kid of the options, the defaults are the algorithm,
+// "sig" and the thumbprint, like for generated keys.
+func componentKey(material interface{}, alg string, options *ComponentOptions) (*jose.JSONWebKey, error) {
+	key := &jose.JSONWebKey{Key: material, Algorithm: alg, Use: "sig", KeyID: options.KeyID}
+
+	if options.Algorithm != "" {
+		key.Algorithm = options.Algorithm
+	}
+
+	if options.Use != "" {
+		key.Use = options.Use
+	}
+
+	if key.KeyID == "" {
+		var err error
+
+		if key.KeyID, err = Thumbprint(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// decodeComponents decodes the non-empty values as big-endian unsigned integers.
+func decodeComponents(encoding string, in map[string]string) (map[string]*big.Int, error) {
+	out := make(map[string]*big.Int, len(in))
+
+	for name, value := range in {
+		if value == "" {
+			continue
+		}
+
+		raw, err := decodeComponent(encoding, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %s", ErrInvalidComponents, name, err.Error())
+		}
+
+		out[name] = new(big.Int).SetBytes(raw)
+	}
+
+	return out, nil
+}
+
+// decodeComponent decodes base64url (padding is ignored) or hex (with optional 0x prefix, colons and whitespace) value.
+func decodeComponent(encoding string, value string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", encodingBase64URL:
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	case encodingHex:
+		value = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "0x")
+		value = strings.NewReplacer(":", "", " ", "", "\n", "", "\t", "").Replace(value)
+
+		if len(value)%2 != 0 {
+			value = "0" + value
+		}
+
+		return hex.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %s", encoding)
+	}
+}
+
+// recoverPrimes factors the modulus from the exponents (NIST SP 800-56B Appendix C.2).
+func recoverPrimes(n, e, d *big.Int) (*big.Int, *big.Int, error) {
+	one := big.NewInt(1)
+	nMinusOne := new(big.Int).Sub(n, one)
+
+	// k = de - 1 = 2^t * r, with r odd
+	k := new(big.Int).Mul(d, e)
+	k.Sub(k, one)
+
+	if k.Sign() <= 0 || k.Bit(0) != 0 {
+		return nil, nil, fmt.Errorf("%w: d and e don't match", ErrInvalidComponents)
+	}
+
+	r := new(big.Int).Set(k)
+	t := 0
+
+	for r.Bit(0) == 0 {
+		r.Rsh(r, 1)
+		t++
+	}
+
+	for g := int64(2); g < 100; g++ {
+		y := new(big.Int).Exp(big.NewInt(g), r, n)
+		if y.Cmp(one) == 0 || y.Cmp(nMinusOne) == 0 {
+			continue
+		}
+
+		for i := 0; i < t; i++ {
+			x := new(big.Int).Exp(y, big.NewInt(2), n)
+
+			if x.Cmp(one) == 0 {
+				p := new(big.Int).GCD(nil, nil, new(big.Int).Sub(y, one), n)
+				q := new(big.Int).Div(n, p)
+
+				if p.Cmp(q) < 0 {
+					p, q = q, p
+				}
+
+				return p, q, nil
+			}
+
+			if x.Cmp(nMinusOne) == 0 {
+				break
+			}
+
+			y = x
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: primes can't be recovered from n, e and d", ErrInvalidComponents)
+}
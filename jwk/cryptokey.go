@@ -0,0 +1,138 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/cryptokey"
+)
+
+type CryptoKeyOptions struct {
+	Usages      []string `js:"usages"`
+	Extractable *bool    `js:"extractable"`
+}
+
+// FromCryptoKey returns the JSON Web Key of the WebCrypto CryptoKey.
+// CryptoKey arguments of the module functions are converted by the module binding already.
+func (m *Module) FromCryptoKey(in interface{}) (*jose.JSONWebKey, error) {
+	if key, ok := in.(*jose.JSONWebKey); ok {
+		return key, nil
+	}
+
+	return cryptokey.Key(in)
+}
+
+// ToCryptoKey imports the key with the WebCrypto API, the result is the promise of SubtleCrypto.importKey.
+func (m *Module) ToCryptoKey(key *jose.JSONWebKey, options *CryptoKeyOptions) (sobek.Value, error) {
+	if options == nil {
+		options = &CryptoKeyOptions{}
+	}
+
+	algorithm, usages, err := importParams(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Usages != nil {
+		usages = options.Usages
+	}
+
+	extractable := true
+	if options.Extractable != nil {
+		extractable = *options.Extractable
+	}
+
+	obj, err := m.ToObject(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := m.vu.Runtime()
+
+	subtle := rt.GlobalObject().Get("crypto")
+	if subtle != nil && !sobek.IsUndefined(subtle) {
+		subtle = subtle.ToObject(rt).Get("subtle")
+	}
+
+	if subtle == nil || sobek.IsUndefined(subtle) {
+		return nil, fmt.Errorf("%w: WebCrypto API is not available", ErrUnsupportedKey)
+	}
+
+	importKey, ok := sobek.AssertFunction(subtle.ToObject(rt).Get("importKey"))
+	if !ok {
+		return nil, fmt.Errorf("%w: WebCrypto API is not available", ErrUnsupportedKey)
+	}
+
+	return importKey(subtle, rt.ToValue("jwk"), rt.ToValue(obj), rt.ToValue(algorithm),
+		rt.ToValue(extractable), rt.ToValue(usages))
+}
+
+// importParams returns the WebCrypto import algorithm and the default usages of the key.
+func importParams(key *jose.JSONWebKey) (map[string]interface{}, []string, error) {
+	alg := key.Algorithm
+	public := key.IsPublic()
+
+	choose := func(private, pub []string) []string {
+		if public {
+			return pub
+		}
+
+		return private
+	}
+
+	if ec, ok := key.Public().Key.(*ecdsa.PublicKey); ok {
+		curve := ec.Curve.Params().Name
+
+		if strings.HasPrefix(alg, string(jose.ECDH_ES)) {
+			return map[string]interface{}{"name": "ECDH", "namedCurve": curve}, choose([]string{"deriveBits"}, []string{}), nil
+		}
+
+		return map[string]interface{}{"name": "ECDSA", "namedCurve": curve}, choose([]string{"sign"}, []string{"verify"}), nil
+	}
+
+	hash := "SHA-" + strings.TrimLeft(alg, "RSPH")
+
+	switch {
+	case strings.HasPrefix(alg, "RS"):
+		return map[string]interface{}{"name": "RSASSA-PKCS1-v1_5", "hash": hash}, choose([]string{"sign"}, []string{"verify"}), nil
+	case strings.HasPrefix(alg, "PS"):
+		return map[string]interface{}{"name": "RSA-PSS", "hash": hash}, choose([]string{"sign"}, []string{"verify"}), nil
+	case strings.HasPrefix(alg, "HS"):
+		return map[string]interface{}{"name": "HMAC", "hash": hash}, []string{"sign", "verify"}, nil
+	case alg == string(jose.RSA_OAEP):
+		return map[string]interface{}{"name": "RSA-OAEP", "hash": "SHA-1"}, choose([]string{"decrypt"}, []string{"encrypt"}), nil
+	case alg == string(jose.RSA_OAEP_256):
+		return map[string]interface{}{"name": "RSA-OAEP", "hash": "SHA-256"}, choose([]string{"decrypt"}, []string{"encrypt"}), nil
+	case strings.HasSuffix(alg, "GCMKW"):
+		return map[string]interface{}{"name": "AES-GCM"}, []string{"encrypt", "decrypt"}, nil
+	case strings.HasSuffix(alg, "KW"):
+		return map[string]interface{}{"name": "AES-KW"}, []string{"wrapKey", "unwrapKey"}, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: %q algorithm for WebCrypto", ErrUnsupportedKey, alg)
+}
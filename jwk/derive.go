@@ -0,0 +1,141 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+)
+
+var ErrInvalidSeed = errors.New("invalid seed")
+
+// maxDeriveAttempts bounds the retries of EC scalar derivation, a derived value is out of range with negligible chance.
+const maxDeriveAttempts = 16
+
+type DeriveOptions struct {
+	VU        *int64 `js:"vu"`
+	Iteration *int64 `js:"iteration"`
+	Algorithm string `js:"algorithm"`
+}
+
+// DeriveForVU derives the key of the VU (and iteration, if given) from the base seed with HKDF-SHA256.
+// The VU defaults to the current VU in VU code.
+func (m *Module) DeriveForVU(baseSeedIn interface{}, options *DeriveOptions) (*jose.JSONWebKey, error) {
+	if options == nil {
+		options = &DeriveOptions{}
+	}
+
+	seed, err := buffer.Bytes(baseSeedIn)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("%w: base seed is empty", ErrInvalidSeed)
+	}
+
+	vu := options.VU
+	if vu == nil {
+		state := m.vu.State()
+		if state == nil {
+			return nil, fmt.Errorf("%w: vu is required in the init context", ErrInvalidSeed)
+		}
+
+		id := int64(state.VUID)
+		vu = &id
+	}
+
+	info := fmt.Sprintf("xk6-jose/vu/%d", *vu)
+	if options.Iteration != nil {
+		info += fmt.Sprintf("/iteration/%d", *options.Iteration)
+	}
+
+	algorithm := options.Algorithm
+	if algorithm == "" {
+		algorithm = string(jose.ED25519)
+	}
+
+	return deriveKey(algorithm, seed, info)
+}
+
+// deriveKey derives ed25519 or EC signing key, RSA key generation can't be made deterministic.
+func deriveKey(algorithm string, seed []byte, info string) (*jose.JSONWebKey, error) {
+	alg := strings.ToUpper(algorithm)
+
+	var curve elliptic.Curve
+
+	switch jose.SignatureAlgorithm(alg) {
+	case jose.SignatureAlgorithm(jose.ED25519), jose.EdDSA:
+		priv, err := hkdf.Key(sha256.New, seed, nil, info, ed25519.SeedSize)
+		if err != nil {
+			return nil, err
+		}
+
+		return ed25519Adopt(ed25519.NewKeyFromSeed(priv), false), nil
+	case jose.ES256:
+		curve = elliptic.P256()
+	case jose.ES384:
+		curve = elliptic.P384()
+	case jose.ES512:
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("%w: %s can't be derived", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	bits := curve.Params().N.BitLen()
+	size := (bits + 7) / 8
+
+	for i := 0; i < maxDeriveAttempts; i++ {
+		raw, err := hkdf.Key(sha256.New, seed, nil, fmt.Sprintf("%s/%d", info, i), size)
+		if err != nil {
+			return nil, err
+		}
+
+		// clear the bits above the order size
+		raw[0] &= byte(0xff >> (size*8 - bits))
+
+		priv, err := ecdsa.ParseRawPrivateKey(curve, raw)
+		if err != nil {
+			continue
+		}
+
+		key := &jose.JSONWebKey{Key: priv, Algorithm: alg, Use: "sig"}
+
+		if key.KeyID, err = Thumbprint(key); err != nil {
+			return nil, err
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: no valid %s key derived", ErrInvalidSeed, algorithm)
+}
@@ -0,0 +1,158 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// KeySetDiff is the difference of two key set snapshots by kid, keys without kid are identified by their thumbprint.
+// The keys sharing a kid (e.g. signing and encryption keys) are compared together.
+type KeySetDiff struct {
+	Added      []string `js:"added"`
+	Removed    []string `js:"removed"`
+	Changed    []string `js:"changed"`
+	Unchanged  []string `js:"unchanged"`
+	Duplicated []string `js:"duplicated"`
+	Rotated    bool     `js:"rotated"`
+}
+
+func (m *Module) DiffKeySets(before, after interface{}) (*KeySetDiff, error) {
+	old, err := KeySet(before)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := KeySet(after)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffKeySets(old, current)
+}
+
+// DiffKeySets compares the key sets, keys with the same kid are changed if their public keys, alg or use differ,
+// or their number differs.
+func DiffKeySets(before, after []jose.JSONWebKey) (*KeySetDiff, error) {
+	oldKeys, err := keysByID(before)
+	if err != nil {
+		return nil, err
+	}
+
+	newKeys, err := keysByID(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &KeySetDiff{
+		Added:      []string{},
+		Removed:    []string{},
+		Changed:    []string{},
+		Unchanged:  []string{},
+		Duplicated: []string{},
+	}
+
+	for kid, keys := range newKeys {
+		old, ok := oldKeys[kid]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, kid)
+		case !slices.Equal(old, keys):
+			diff.Changed = append(diff.Changed, kid)
+		default:
+			diff.Unchanged = append(diff.Unchanged, kid)
+		}
+
+		if len(keys) > 1 || len(old) > 1 {
+			diff.Duplicated = append(diff.Duplicated, kid)
+		}
+	}
+
+	for kid, keys := range oldKeys {
+		if _, ok := newKeys[kid]; !ok {
+			diff.Removed = append(diff.Removed, kid)
+
+			if len(keys) > 1 {
+				diff.Duplicated = append(diff.Duplicated, kid)
+			}
+		}
+	}
+
+	for _, kids := range [][]string{diff.Added, diff.Removed, diff.Changed, diff.Unchanged, diff.Duplicated} {
+		sort.Strings(kids)
+	}
+
+	diff.Rotated = len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0
+
+	return diff, nil
+}
+
+// keyIdentity is compared to detect changed keys.
+type keyIdentity struct {
+	thumbprint string
+	alg        string
+	use        string
+}
+
+// keysByID returns the identities of the keys by kid, sorted to compare them regardless of their order.
+func keysByID(keys []jose.JSONWebKey) (map[string][]keyIdentity, error) {
+	ids := make(map[string][]keyIdentity, len(keys))
+
+	for i := range keys {
+		public := verificationKey(&keys[i])
+
+		thumbprint, err := Thumbprint(&public)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := public.KeyID
+		if kid == "" {
+			kid = thumbprint
+		}
+
+		ids[kid] = append(ids[kid], keyIdentity{thumbprint: thumbprint, alg: public.Algorithm, use: public.Use})
+	}
+
+	for _, identities := range ids {
+		sort.Slice(identities, func(i, j int) bool {
+			a, b := identities[i], identities[j]
+
+			if a.thumbprint != b.thumbprint {
+				return a.thumbprint < b.thumbprint
+			}
+
+			if a.alg != b.alg {
+				return a.alg < b.alg
+			}
+
+			return a.use < b.use
+		})
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,275 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+	josecipher "github.com/go-jose/go-jose/v4/cipher"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+)
+
+const (
+	kdfNone   = "none"
+	kdfConcat = "concat"
+
+	defaultSecretLength = 32
+)
+
+type SharedSecretOptions struct {
+	KDF       string      `js:"kdf"`
+	Length    int         `js:"length"`
+	Algorithm string      `js:"algorithm"`
+	APU       interface{} `js:"apu"`
+	APV       interface{} `js:"apv"`
+}
+
+func (m *Module) DeriveSharedSecret(
+	privateIn interface{},
+	publicIn interface{},
+	options *SharedSecretOptions,
+) (sobek.ArrayBuffer, error) {
+	if options == nil {
+		options = &SharedSecretOptions{}
+	}
+
+	secret, err := DeriveSharedSecret(privateIn, publicIn, options)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(secret), nil
+}
+
+// DeriveSharedSecret returns the raw ECDH shared secret Z, or the key derived from it with Concat-KDF (RFC 7518 4.6.2).
+func DeriveSharedSecret(privateIn interface{}, publicIn interface{}, options *SharedSecretOptions) ([]byte, error) {
+	priv, err := agreementKey(privateIn, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := agreementKey(publicIn, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, ok := pubKey.(*ecdh.PublicKey)
+	if !ok {
+		pub = pubKey.(*ecdh.PrivateKey).PublicKey()
+	}
+
+	key, ok := priv.(*ecdh.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: public key can't be used as private key", ErrUnsupportedKey)
+	}
+
+	if key.Curve() != pub.Curve() {
+		return nil, fmt.Errorf("%w: keys on different curves", ErrUnsupportedKey)
+	}
+
+	z, err := key.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	switch options.KDF {
+	case "", kdfNone:
+		if options.Length != 0 && options.Length != len(z) {
+			return nil, fmt.Errorf("%w: length %d without kdf", ErrUnsupportedAlgorithm, options.Length)
+		}
+
+		return z, nil
+	case kdfConcat:
+		return concatKDF(z, options)
+	default:
+		return nil, fmt.Errorf("%w: kdf %s", ErrUnsupportedAlgorithm, options.KDF)
+	}
+}
+
+// agreementKey returns the ECDH key of EC keys, or of X25519 OKP keys not supported by go-jose.
+func agreementKey(in interface{}, private bool) (interface{}, error) {
+	if key, ok, err := x25519Key(in, private); ok || err != nil {
+		return key, err
+	}
+
+	keys, err := KeySet(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("%w: %d keys", ErrUnsupportedKey, len(keys))
+	}
+
+	switch key := keys[0].Key.(type) {
+	case *ecdsa.PrivateKey:
+		return key.ECDH()
+	case *ecdsa.PublicKey:
+		return key.ECDH()
+	case *ecdh.PrivateKey, *ecdh.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: %T for ECDH", ErrUnsupportedKey, keys[0].Key)
+	}
+}
+
+// x25519Key parses X25519 OKP key from JSON source or plain object, ok is false for other keys.
+func x25519Key(in interface{}, private bool) (interface{}, bool, error) {
+	switch in.(type) {
+	case string, map[string]interface{}:
+	default:
+		return nil, false, nil
+	}
+
+	source, err := jsonSource(in)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var raw struct {
+		Kty string `json:"kty"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		D   string `json:"d"`
+	}
+
+	if json.Unmarshal([]byte(source), &raw) != nil || raw.Kty != "OKP" || raw.Crv != "X25519" {
+		return nil, false, nil
+	}
+
+	if private && raw.D != "" {
+		d, err := base64.RawURLEncoding.DecodeString(raw.D)
+		if err != nil {
+			return nil, true, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+		}
+
+		key, err := ecdh.X25519().NewPrivateKey(d)
+		if err != nil {
+			return nil, true, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+		}
+
+		return key, true, nil
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(raw.X)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	key, err := ecdh.X25519().NewPublicKey(x)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	return key, true, nil
+}
+
+// x25519Thumbprint returns RFC 8037 thumbprint of X25519 keys, ok is false for other keys.
+func x25519Thumbprint(key *jose.JSONWebKey) (string, bool) {
+	var public *ecdh.PublicKey
+
+	switch k := key.Key.(type) {
+	case *ecdh.PrivateKey:
+		public = k.PublicKey()
+	case *ecdh.PublicKey:
+		public = k
+	default:
+		return "", false
+	}
+
+	if public.Curve() != ecdh.X25519() {
+		return "", false
+	}
+
+	// the required members in lexicographic order
+	sum := sha256.Sum256([]byte(`{"crv":"X25519","kty":"OKP","x":"` + encode(public.Bytes()) + `"}`))
+
+	return encode(sum[:]), true
+}
+
+// concatKDF derives the key with the algorithm, apu and apv of the options as other info.
+func concatKDF(z []byte, options *SharedSecretOptions) ([]byte, error) {
+	size := options.Length
+	if size == 0 {
+		size = defaultSecretLength
+	}
+
+	if size < 0 || size > 1<<16 {
+		return nil, fmt.Errorf("%w: length %d", ErrUnsupportedAlgorithm, size)
+	}
+
+	apu, err := partyInfo(options.APU)
+	if err != nil {
+		return nil, err
+	}
+
+	apv, err := partyInfo(options.APV)
+	if err != nil {
+		return nil, err
+	}
+
+	supPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(supPubInfo, uint32(size)*8)
+
+	reader := josecipher.NewConcatKDF(
+		crypto.SHA256,
+		z,
+		lengthPrefixed([]byte(options.Algorithm)),
+		lengthPrefixed(apu),
+		lengthPrefixed(apv),
+		supPubInfo,
+		[]byte{},
+	)
+
+	key := make([]byte, size)
+
+	// Read on the KDF never fails
+	_, _ = reader.Read(key)
+
+	return key, nil
+}
+
+func partyInfo(in interface{}) ([]byte, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	return buffer.Bytes(in)
+}
+
+func lengthPrefixed(data []byte) []byte {
+	out := make([]byte, len(data)+4)
+	binary.BigEndian.PutUint32(out, uint32(len(data)))
+	copy(out[4:], data)
+
+	return out
+}
@@ -0,0 +1,88 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+type ExportOptions struct {
+	IncludePrivate bool `js:"includePrivate"`
+}
+
+// ExportKeys returns the key (or keys) as plain JSON data, which survives the setup() to VU hand-off of k6.
+// Private key material is exported only on request.
+func (m *Module) ExportKeys(in interface{}, options *ExportOptions) (interface{}, error) {
+	if options == nil {
+		options = &ExportOptions{}
+	}
+
+	switch key := in.(type) {
+	case *jose.JSONWebKey:
+		return m.exportKey(key, options.IncludePrivate)
+	case jose.JSONWebKey:
+		return m.exportKey(&key, options.IncludePrivate)
+	}
+
+	set, err := KeySet(in)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]interface{}, len(set))
+
+	for i := range set {
+		if data[i], err = m.exportKey(&set[i], options.IncludePrivate); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func (m *Module) exportKey(key *jose.JSONWebKey, includePrivate bool) (map[string]interface{}, error) {
+	if !includePrivate && !key.IsPublic() {
+		if _, ok := key.Key.([]byte); ok {
+			return nil, fmt.Errorf("%w: symmetric key without includePrivate", ErrUnsupportedKey)
+		}
+
+		public := key.Public()
+		key = &public
+	}
+
+	return m.ToObject(key)
+}
+
+// ImportKeys reconstructs the key (or keys) from the data exported by ExportKeys.
+// JWK objects become key, arrays and JWK sets become key array.
+func (m *Module) ImportKeys(data interface{}) (interface{}, error) {
+	if obj, ok := data.(map[string]interface{}); ok {
+		if _, isSet := obj["keys"]; !isSet {
+			return m.Parse(obj)
+		}
+	}
+
+	return KeySet(data)
+}
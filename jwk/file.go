@@ -0,0 +1,98 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"go.k6.io/k6/lib/fsext"
+)
+
+var (
+	ErrInitContext = errors.New("files can be read only in the init context")
+	ErrMissingPath = errors.New("missing key file path")
+)
+
+// ParseFile reads a PEM or JWK encoded key from file, relative paths are relative to the test script.
+func (m *Module) ParseFile(path string) (*jose.JSONWebKey, error) {
+	source, err := m.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSource(source)
+}
+
+// ParseKeySetFile reads a JWKS, a JWK or a bundle of PEM encoded keys from file.
+func (m *Module) ParseKeySetFile(path string) ([]jose.JSONWebKey, error) {
+	source, err := m.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(source, pemPrefix) {
+		return parsePEMs(source)
+	}
+
+	var probe struct {
+		Keys json.RawMessage `json:"keys"`
+	}
+
+	if err := json.Unmarshal([]byte(source), &probe); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err)
+	}
+
+	if probe.Keys != nil {
+		return parseKeySet(source)
+	}
+
+	key, err := parseKey(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return []jose.JSONWebKey{*key}, nil
+}
+
+// readFile reads the file through k6, so the file is included in archives (k6 archive, cloud runs).
+func (m *Module) readFile(path string) (string, error) {
+	env := m.vu.InitEnv()
+	if env == nil {
+		return "", fmt.Errorf("%w: %s", ErrInitContext, path)
+	}
+
+	if path == "" {
+		return "", ErrMissingPath
+	}
+
+	data, err := fsext.ReadFile(env.FileSystems["file"], env.GetAbsFilePath(path))
+	if err != nil {
+		return "", fmt.Errorf("%w", err)
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,102 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const defaultRSABits = 2048
+
+// generateKey generates signing key for JWS algorithm (or ed25519), bits is the RSA modulus size.
+func generateKey(algorithm string, bits int) (*jose.JSONWebKey, error) {
+	gen, err := keyGenerator(algorithm, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	return gen()
+}
+
+// keyGenerator returns key generator function for the algorithm.
+func keyGenerator(algorithm string, bits int) (func() (*jose.JSONWebKey, error), error) {
+	alg := strings.ToUpper(algorithm)
+
+	if alg == string(jose.ED25519) || alg == string(jose.EdDSA) {
+		return func() (*jose.JSONWebKey, error) {
+			_, priv, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return nil, err
+			}
+
+			return ed25519Adopt(priv, false), nil
+		}, nil
+	}
+
+	if bits <= 0 {
+		bits = defaultRSABits
+	}
+
+	var gen func() (interface{}, error)
+
+	switch jose.SignatureAlgorithm(alg) {
+	case jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512:
+		gen = func() (interface{}, error) { return rsa.GenerateKey(rand.Reader, bits) }
+	case jose.ES256:
+		gen = func() (interface{}, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) }
+	case jose.ES384:
+		gen = func() (interface{}, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) }
+	case jose.ES512:
+		gen = func() (interface{}, error) { return ecdsa.GenerateKey(elliptic.P521(), rand.Reader) }
+	default:
+		curve, ok := brainpoolCurve(alg)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+		}
+
+		gen = func() (interface{}, error) { return ecdsa.GenerateKey(curve, rand.Reader) }
+	}
+
+	return func() (*jose.JSONWebKey, error) {
+		priv, err := gen()
+		if err != nil {
+			return nil, err
+		}
+
+		key := &jose.JSONWebKey{Key: priv, Algorithm: alg, Use: "sig"}
+
+		if key.KeyID, err = Thumbprint(key); err != nil {
+			return nil, err
+		}
+
+		return key, nil
+	}, nil
+}
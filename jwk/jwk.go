@@ -23,70 +23,326 @@
 package jwk
 
 import (
+	"crypto"
 	"crypto/ed25519"
 	"crypto/rand"
-	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"reflect"
 	"strings"
 
-	"go.k6.io/k6/js/common"
-	"gopkg.in/square/go-jose.v2"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"go.k6.io/k6/js/modules"
 )
 
-type Module struct{}
+type Module struct {
+	vu     modules.VU
+	env    map[string]string
+	config *config.Config
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return NewModule(vu, config.Default(vu)) })
+}
+
+// NewModule returns the module object of the VU using the given configuration, it must be called in the init context.
+func NewModule(vu modules.VU, cfg *config.Config) *Module {
+	initCacheTTL(cfg)
+
+	// environment of the test is accessible only in the init context
+	return &Module{vu: vu, env: vu.InitEnv().RuntimeOptions.Env, config: cfg}
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	if err := m.config.Resolve(); err != nil {
+		return err
+	}
 
-func New() *Module {
-    return &Module{}
+	initCacheTTL(m.config)
+
+	return nil
 }
 
-var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+// bits returns the requested RSA modulus size, or the configured one if not requested.
+func (m *Module) bits(requested int) int {
+	if requested > 0 {
+		return requested
+	}
+
+	return m.config.KeySize
+}
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrUnsupportedKey       = errors.New("unsupported key")
+	ErrUnknownKeyID         = errors.New("unknown kid")
+)
+
+// Parse returns copy of the cached key if the same source was already parsed.
+// The source can be an https URL or data URI of the JSON too.
+func (m *Module) Parse(in interface{}) (*jose.JSONWebKey, error) {
+	source, err := jsonSource(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if !remoteSource(source) {
+		return parseKey(source)
+	}
 
-func (m *Module) Parse(source string) (*jose.JSONWebKey, error) {
-	key := &jose.JSONWebKey{}
+	keys, err := m.remoteKeys(parsedKeys, source, func(content string) ([]jose.JSONWebKey, error) {
+		key, err := unmarshalKey([]byte(content))
+		if err != nil {
+			return nil, err
+		}
 
-	if err := key.UnmarshalJSON([]byte(source)); err != nil {
+		return []jose.JSONWebKey{*key}, nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	return &keys[0], nil
+}
+
+func parseKey(source string) (*jose.JSONWebKey, error) {
+	if keys, ok := parsedKeys.get(source); ok {
+		return &keys[0], nil
+	}
+
+	key, err := unmarshalKey([]byte(source))
+	if err != nil {
+		return nil, err
+	}
+
+	parsedKeys.put(source, []jose.JSONWebKey{*key})
+
 	return key, nil
 }
 
-func (m *Module) ParseKeySet(source string) ([]jose.JSONWebKey, error) {
-	keyset := &jose.JSONWebKeySet{}
+func (m *Module) ParseKeySet(in interface{}) ([]jose.JSONWebKey, error) {
+	source, err := jsonSource(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if !remoteSource(source) {
+		return parseKeySet(source)
+	}
+
+	return m.remoteKeys(parsedKeySets, source, func(content string) ([]jose.JSONWebKey, error) {
+		return unmarshalKeySet([]byte(content))
+	})
+}
+
+func parseKeySet(source string) ([]jose.JSONWebKey, error) {
+	if keys, ok := parsedKeySets.get(source); ok {
+		return keys, nil
+	}
 
-	if err := json.Unmarshal([]byte(source), &keyset); err != nil {
+	keys, err := unmarshalKeySet([]byte(source))
+	if err != nil {
 		return nil, err
 	}
 
-	return keyset.Keys, nil
+	parsedKeySets.put(source, keys)
+
+	return keys, nil
 }
 
-func bytes(in interface{}) ([]byte, error) {
-	if in == nil || reflect.ValueOf(in).IsZero() {
-		return nil, nil
+// jsonSource returns the JSON source of the key (or key set) passed as string, ArrayBuffer or plain object.
+func jsonSource(in interface{}) (string, error) {
+	switch value := in.(type) {
+	case string:
+		return value, nil
+	case map[string]interface{}, []interface{}:
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		return string(raw), nil
 	}
 
-	val, err := common.ToBytes(in)
+	raw, err := buffer.Bytes(in)
 	if err != nil {
-		return nil, fmt.Errorf("%w", err)
+		return "", fmt.Errorf("%w: %T", ErrUnsupportedKey, in)
+	}
+
+	return string(raw), nil
+}
+
+// ToObject returns the JSON representation of the key as plain object, the public key for remote signers.
+func (m *Module) ToObject(key *jose.JSONWebKey) (map[string]interface{}, error) {
+	public := verificationKey(key)
+
+	if obj, ok := brainpoolObject(&public); ok {
+		return obj, nil
+	}
+
+	raw, err := public.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]interface{}{}
+
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// KeyProvider is implemented by values holding keys, like discovered OpenID issuers.
+type KeyProvider interface {
+	KeySet() []jose.JSONWebKey
+}
+
+// KeySet collects keys, key arrays and key sets passed from JS into a flat key list.
+func KeySet(keys ...interface{}) ([]jose.JSONWebKey, error) {
+	set := make([]jose.JSONWebKey, 0, len(keys))
+
+	for _, k := range keys {
+		switch key := k.(type) {
+		case jose.JSONWebKey:
+			set = append(set, key)
+		case *jose.JSONWebKey:
+			set = append(set, *key)
+		case []jose.JSONWebKey:
+			set = append(set, key...)
+		case *[]jose.JSONWebKey:
+			set = append(set, *key...)
+		case *jose.JSONWebKeySet:
+			set = append(set, key.Keys...)
+		case KeyProvider:
+			set = append(set, key.KeySet()...)
+		case []interface{}:
+			sub, err := KeySet(key...)
+			if err != nil {
+				return nil, err
+			}
+
+			set = append(set, sub...)
+		case map[string]interface{}:
+			sub, err := objectKeys(key)
+			if err != nil {
+				return nil, err
+			}
+
+			set = append(set, sub...)
+		default:
+			return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, k)
+		}
 	}
 
-	return val, nil
+	return set, nil
+}
+
+// verificationKey returns the public key of remote (opaque) signers, the key itself otherwise.
+func verificationKey(key *jose.JSONWebKey) jose.JSONWebKey {
+	if signer, ok := key.Key.(jose.OpaqueSigner); ok {
+		return *signer.Public()
+	}
+
+	return *key
+}
+
+// objectKeys parses plain JS object as key set (if it has "keys" member) or key.
+// X25519 OKP keys, not supported by go-jose, are returned with their crypto/ecdh key.
+func objectKeys(obj map[string]interface{}) ([]jose.JSONWebKey, error) {
+	if key, ok, err := x25519Key(obj, true); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+
+		kid, _ := obj["kid"].(string)
+		alg, _ := obj["alg"].(string)
+		use, _ := obj["use"].(string)
+
+		return []jose.JSONWebKey{{Key: key, KeyID: kid, Algorithm: alg, Use: use}}, nil
+	}
+
+	source, err := jsonSource(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := obj["keys"]; ok {
+		return parseKeySet(source)
+	}
+
+	key, err := parseKey(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return []jose.JSONWebKey{*key}, nil
+}
+
+// Candidates returns the keys matching kid, or every key if there is no such key.
+func Candidates(set []jose.JSONWebKey, kid string) []jose.JSONWebKey {
+	if kid == "" {
+		return set
+	}
+
+	keyset := jose.JSONWebKeySet{Keys: set}
+	if keys := keyset.Key(kid); len(keys) != 0 {
+		return keys
+	}
+
+	return set
+}
+
+// HasKeyID returns true if the set has key with the kid.
+func HasKeyID(set []jose.JSONWebKey, kid string) bool {
+	for i := range set {
+		if set[i].KeyID == kid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Thumbprint returns base64url encoded JWK SHA-256 thumbprint (RFC 7638).
+func Thumbprint(key *jose.JSONWebKey) (string, error) {
+	if thumbprint, ok := brainpoolThumbprint(key); ok {
+		return thumbprint, nil
+	}
+
+	if thumbprint, ok := x25519Thumbprint(key); ok {
+		return thumbprint, nil
+	}
+
+	public := key.Public()
+
+	sum, err := public.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(sum), nil
 }
 
 func (m *Module) Generate(algorithm string, seedIn interface{}) (*jose.JSONWebKey, error) {
 	alg := strings.ToUpper(algorithm)
 
 	if alg != string(jose.ED25519) {
-		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+		if seedIn != nil {
+			return nil, fmt.Errorf("%w: seed is supported only for %s", ErrUnsupportedAlgorithm, jose.ED25519)
+		}
+
+		return generateKey(algorithm, m.bits(0))
 	}
 
-	seed, err := bytes(seedIn)
+	seed, err := buffer.Bytes(seedIn)
 	if err != nil {
 		return nil, err
 	}
@@ -105,25 +361,62 @@ func (m *Module) Generate(algorithm string, seedIn interface{}) (*jose.JSONWebKe
 	return ed25519Adopt(priv, false), nil
 }
 
-func (m *Module) Adopt(algorithm string, keyIn interface{}, isPublic bool) (*jose.JSONWebKey, error) {
-    alg := strings.ToUpper(algorithm)
+// Adopt returns the key of the raw bytes, Ed25519 and X25519 keys are built like from their OKP components
+// (X25519 keys are plain JWK objects).
+func (m *Module) Adopt(algorithm string, keyIn interface{}, isPublic bool) (interface{}, error) {
+	alg := strings.ToUpper(algorithm)
 
 	switch alg {
 	case string(jose.ED25519):
-		key, err := bytes(keyIn)
+		key, err := buffer.Bytes(keyIn)
+		if err != nil {
+			return nil, err
+		}
+
+		return ed25519Raw(key, isPublic)
+	case "X25519":
+		key, err := buffer.Bytes(keyIn)
 		if err != nil {
 			return nil, err
 		}
-		return ed25519Adopt(key, isPublic), nil
+
+		if isPublic {
+			return x25519Components(key, nil, &ComponentOptions{})
+		}
+
+		return x25519Components(nil, key, &ComponentOptions{})
 	case string(jose.RSA1_5):
-		key, err := bytes(keyIn)
+		key, err := buffer.Bytes(keyIn)
 		if err != nil {
 			return nil, err
 		}
 		return rsa15Adopt(key, isPublic)
-	default:
+	}
+
+	curve, ok := brainpoolCurve(alg)
+	if !ok {
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
 	}
+
+	key, err := buffer.Bytes(keyIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return brainpoolAdopt(curve, alg, key, isPublic)
+}
+
+// ed25519Raw checks the public key, or the private key (seed and public key) like its OKP components.
+func ed25519Raw(in []byte, isPublic bool) (*jose.JSONWebKey, error) {
+	if isPublic {
+		return ed25519Components(in, nil, &ComponentOptions{})
+	}
+
+	if len(in) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: %d bytes private key", ErrInvalidComponents, len(in))
+	}
+
+	return ed25519Components(in[ed25519.SeedSize:], in[:ed25519.SeedSize], &ComponentOptions{})
 }
 
 func ed25519Adopt(in []byte, isPublic bool) *jose.JSONWebKey {
@@ -131,22 +424,14 @@ func ed25519Adopt(in []byte, isPublic bool) *jose.JSONWebKey {
 	k.Algorithm = string(jose.EdDSA)
 	k.Use = "sig"
 
-	var x string
-
 	if isPublic {
-		publicKey := ed25519.PublicKey(in)
-		k.Key = publicKey
-		x = base64.RawURLEncoding.EncodeToString(publicKey)
+		k.Key = ed25519.PublicKey(in)
 	} else {
-		privateKey := ed25519.PrivateKey(in)
-		k.Key = privateKey
-		x = base64.RawURLEncoding.EncodeToString(privateKey[ed25519.SeedSize:])
+		k.Key = ed25519.PrivateKey(in)
 	}
 
-	// workaround of k.Thumbprint() bug
-	kid := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, x)))
-
-	k.KeyID = base64.RawURLEncoding.EncodeToString(kid[:])
+	// keys of invalid size have no kid
+	k.KeyID, _ = Thumbprint(k)
 
 	return k
 }
@@ -156,31 +441,21 @@ func rsa15Adopt(in []byte, isPublic bool) (*jose.JSONWebKey, error) {
 	k.Algorithm = string(jose.RS256)
 	k.Use = "sig"
 
-	var x string
+	var err error
+
 	if isPublic {
-		publicKey := ed25519.PublicKey(in)
-		k.Key = publicKey
-		x = base64.RawURLEncoding.EncodeToString(publicKey)
+		k.Key, err = x509.ParsePKCS1PublicKey(in)
 	} else {
-		privateKey, err := x509.ParsePKCS1PrivateKey(in)
-		if err != nil {
-			return nil, err
-		}
-		k.Key = privateKey
-		x = "TODO" //base64.RawURLEncoding.EncodeToString(privateKey.)
-	}
-
-	//{
-	//  "use": "sig",
-	//  "kid": "1",
-	//  "kty": "RSA",
-	//  "n": "",
-	//  "e": "AQAB"
-	//}
-	// workaround of k.Thumbprint() bug
-	//TODO fill for RSA
-	kid := sha256.Sum256([]byte(fmt.Sprintf(`{"kty":"RSA"}`, x)))
-
-	k.KeyID = base64.RawURLEncoding.EncodeToString(kid[:])
+		k.Key, err = x509.ParsePKCS1PrivateKey(in)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if k.KeyID, err = Thumbprint(k); err != nil {
+		return nil, err
+	}
+
 	return k, nil
 }
@@ -0,0 +1,154 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+var ErrInvalidPEM = errors.New("invalid PEM")
+
+func (m *Module) ParsePEM(source string) (*jose.JSONWebKey, error) {
+	return FromPEM(source)
+}
+
+// FromPEM parses PKCS #8, PKCS #1 or SEC 1 private key, or PKIX public key (e.g. Apple .p8 or Google service account key).
+func FromPEM(source string) (*jose.JSONWebKey, error) {
+	if keys, ok := parsedPEMs.get(source); ok {
+		return &keys[0], nil
+	}
+
+	key, err := parsePEM(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedPEMs.put(source, []jose.JSONWebKey{*key})
+
+	return key, nil
+}
+
+func parsePEM(source string) (*jose.JSONWebKey, error) {
+	block, _ := pem.Decode([]byte(source))
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	return pemKey(block)
+}
+
+// parsePEMs parses every PEM block of the source, like a key bundle file.
+func parsePEMs(source string) ([]jose.JSONWebKey, error) {
+	var keys []jose.JSONWebKey
+
+	for rest := []byte(source); ; {
+		var block *pem.Block
+
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+
+		key, err := pemKey(block)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *key)
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrInvalidPEM
+	}
+
+	return keys, nil
+}
+
+func pemKey(block *pem.Block) (*jose.JSONWebKey, error) {
+	key, err := parsePEMBlock(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if priv, ok := key.(ed25519.PrivateKey); ok {
+		return ed25519Adopt(priv, false), nil
+	}
+
+	if pub, ok := key.(ed25519.PublicKey); ok {
+		return ed25519Adopt(pub, true), nil
+	}
+
+	k := &jose.JSONWebKey{Key: key, Use: "sig"}
+
+	switch v := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		k.Algorithm = string(jose.RS256)
+	case *ecdsa.PrivateKey:
+		k.Algorithm = ecAlgorithm(v.Curve.Params().BitSize)
+	case *ecdsa.PublicKey:
+		k.Algorithm = ecAlgorithm(v.Curve.Params().BitSize)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+
+	if k.KeyID, err = Thumbprint(k); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func parsePEMBlock(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPEM, block.Type)
+	}
+}
+
+func ecAlgorithm(bits int) string {
+	switch bits {
+	case 384:
+		return string(jose.ES384)
+	case 521:
+		return string(jose.ES512)
+	default:
+		return string(jose.ES256)
+	}
+}
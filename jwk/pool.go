@@ -0,0 +1,255 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+)
+
+var (
+	ErrPoolExhausted = errors.New("key pool exhausted")
+	ErrPoolMismatch  = errors.New("key pool already exists with different parameters")
+	ErrInvalidCount  = errors.New("invalid key count")
+)
+
+type PoolOptions struct {
+	Bits        int  `js:"bits"`
+	Parallelism int  `js:"parallelism"`
+	Progress    bool `js:"progress"`
+}
+
+// Pool holds pre-generated keys shared by the VUs, generated by the first CreatePool call of the name.
+type Pool struct {
+	name      string
+	algorithm string
+	bits      int
+	count     int
+	mu        sync.Mutex
+	keys      []jose.JSONWebKey
+	next      uint64
+}
+
+var (
+	pools   = map[string]*Pool{}
+	poolsMu sync.Mutex
+)
+
+// CreatePool returns the named pool, generating its keys concurrently if it doesn't exist yet.
+// The pool is dropped if the generation fails, so a later call can retry it.
+func (m *Module) CreatePool(name string, algorithm string, count int, options *PoolOptions) (*sobek.Object, error) {
+	if options == nil {
+		options = &PoolOptions{}
+	}
+
+	bits := m.bits(options.Bits)
+
+	poolsMu.Lock()
+
+	pool, ok := pools[name]
+	if !ok {
+		pool = &Pool{name: name, algorithm: algorithm, bits: bits, count: count}
+		pools[name] = pool
+	}
+
+	poolsMu.Unlock()
+
+	if pool.algorithm != algorithm || pool.bits != bits || pool.count != count {
+		return nil, fmt.Errorf("%w: %s has %d %s keys (%d bits)", ErrPoolMismatch, name, pool.count, pool.algorithm, pool.bits)
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.keys == nil {
+		var progress func(int)
+
+		if options.Progress {
+			logger := m.logger()
+			progress = func(done int) {
+				logger.Infof("key pool %s: %d/%d %s keys generated", name, done, count, algorithm)
+			}
+		}
+
+		keys, err := generateKeys(algorithm, bits, count, options.Parallelism, progress)
+		if err != nil {
+			poolsMu.Lock()
+			if pools[name] == pool {
+				delete(pools, name)
+			}
+			poolsMu.Unlock()
+
+			return nil, err
+		}
+
+		pool.keys = keys
+	}
+
+	return jsmodule.Exports(m.vu.Runtime(), pool), nil
+}
+
+// logger returns the logger of the VU, or the logger of the init environment in the init context.
+func (m *Module) logger() logrus.FieldLogger {
+	if state := m.vu.State(); state != nil {
+		return state.Logger
+	}
+
+	return m.vu.InitEnv().Logger
+}
+
+// Take returns the next unused key of the pool.
+func (p *Pool) Take() (*jose.JSONWebKey, error) {
+	idx := atomic.AddUint64(&p.next, 1) - 1
+	if idx >= uint64(len(p.keys)) {
+		return nil, fmt.Errorf("%w: %s has %d keys", ErrPoolExhausted, p.name, len(p.keys))
+	}
+
+	key := p.keys[idx]
+
+	return &key, nil
+}
+
+// Get returns the key at index, the index wraps around (e.g. __VU).
+func (p *Pool) Get(index int) (*jose.JSONWebKey, error) {
+	if len(p.keys) == 0 {
+		return nil, fmt.Errorf("%w: %s is empty", ErrPoolExhausted, p.name)
+	}
+
+	n := len(p.keys)
+	key := p.keys[((index%n)+n)%n]
+
+	return &key, nil
+}
+
+func (p *Pool) Size() int {
+	return len(p.keys)
+}
+
+// GenerateMany generates count keys concurrently, without sharing them like CreatePool does.
+func (m *Module) GenerateMany(algorithm string, count int, options *PoolOptions) ([]jose.JSONWebKey, error) {
+	if options == nil {
+		options = &PoolOptions{}
+	}
+
+	var progress func(int)
+
+	if options.Progress {
+		logger := m.logger()
+		progress = func(done int) {
+			logger.Infof("%d/%d %s keys generated", done, count, algorithm)
+		}
+	}
+
+	return generateKeys(algorithm, m.bits(options.Bits), count, options.Parallelism, progress)
+}
+
+// GenerateKeys generates count keys concurrently, bits is the RSA modulus size (zero for the default).
+func GenerateKeys(algorithm string, bits, count int) ([]jose.JSONWebKey, error) {
+	return generateKeys(algorithm, bits, count, 0, nil)
+}
+
+// generateKeys generates count keys with parallelism goroutines (default: number of CPUs).
+func generateKeys(algorithm string, bits, count, parallelism int, progress func(int)) ([]jose.JSONWebKey, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCount, count)
+	}
+
+	gen, err := keyGenerator(algorithm, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+
+	keys := make([]jose.JSONWebKey, count)
+	indexes := make(chan int)
+	errs := make(chan error, parallelism)
+
+	var (
+		wg   sync.WaitGroup
+		done int64
+	)
+
+	step := int64(count / 10)
+	if step == 0 {
+		step = 1
+	}
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range indexes {
+				key, err := gen()
+				if err != nil {
+					errs <- err
+
+					return
+				}
+
+				keys[idx] = *key
+
+				if n := atomic.AddInt64(&done, 1); progress != nil && (n%step == 0 || n == int64(count)) {
+					progress(int(n))
+				}
+			}
+		}()
+	}
+
+loop:
+	for i := 0; i < count; i++ {
+		select {
+		case indexes <- i:
+		case err = <-errs:
+			break loop
+		}
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
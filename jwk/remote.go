@@ -0,0 +1,170 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/httpclient"
+	"go.k6.io/k6/js/modules"
+)
+
+var (
+	ErrFetch         = errors.New("key fetch failed")
+	ErrInvalidSource = errors.New("invalid key source")
+)
+
+const (
+	defaultFetchTimeout = 30 * time.Second
+	maxFetchedSize      = 1 << 20
+
+	httpsPrefix = "https://"
+	dataPrefix  = "data:"
+)
+
+// remoteSource reports whether the source is an URL or data URI instead of inline JSON.
+func remoteSource(source string) bool {
+	return strings.HasPrefix(source, httpsPrefix) || strings.HasPrefix(source, dataPrefix)
+}
+
+// remoteKeys returns the keys of URL or data URI source, cached by the source like the inline ones.
+func (m *Module) remoteKeys(
+	cache *keyCache,
+	source string,
+	parse func(string) ([]jose.JSONWebKey, error),
+) ([]jose.JSONWebKey, error) {
+	if keys, ok := cache.get(source); ok {
+		return keys, nil
+	}
+
+	content, err := m.content(source)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.put(source, keys)
+
+	return keys, nil
+}
+
+// FetchKeySet returns the key set of URL or data URI source without the parsed key caches,
+// for callers with their own caching policy.
+func FetchKeySet(vu modules.VU, cfg *config.Config, source string) ([]jose.JSONWebKey, error) {
+	if !remoteSource(source) {
+		return nil, fmt.Errorf("%w: expected https URL or data URI", ErrInvalidSource)
+	}
+
+	m := &Module{vu: vu, config: cfg}
+
+	content, err := m.content(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalKeySet([]byte(content))
+}
+
+// content returns the decoded data URI, or the fetched document of the URL.
+func (m *Module) content(source string) (string, error) {
+	if strings.HasPrefix(source, dataPrefix) {
+		return dataContent(source)
+	}
+
+	return m.fetch(source)
+}
+
+// fetch gets the document of the URL, requests of VU code go through the transport of k6.
+func (m *Module) fetch(source string) (string, error) {
+	timeout := defaultFetchTimeout
+	if m.config.FetchTimeout != nil && *m.config.FetchTimeout > 0 {
+		timeout = *m.config.FetchTimeout
+	}
+
+	client, ctx := httpclient.New(m.vu, timeout)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSource, err.Error())
+	}
+
+	req.Header.Set("Accept", "application/json, application/jwk+json, application/jwk-set+json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrFetch, err.Error())
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s returned %s", ErrFetch, req.URL.Redacted(), res.Status)
+	}
+
+	body, err := httpclient.ReadBody(res.Body, maxFetchedSize)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %s", ErrFetch, req.URL.Redacted(), err.Error())
+	}
+
+	return string(body), nil
+}
+
+// dataContent decodes RFC 2397 data URI, with base64 (standard or URL safe) or percent encoded data.
+func dataContent(source string) (string, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(source, dataPrefix), ",")
+	if !ok {
+		return "", fmt.Errorf("%w: missing data of data URI", ErrInvalidSource)
+	}
+
+	if !strings.HasSuffix(header, ";base64") {
+		content, err := url.PathUnescape(data)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidSource, err.Error())
+		}
+
+		return content, nil
+	}
+
+	data = strings.TrimRight(data, "=")
+
+	raw, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		if raw, err = base64.RawURLEncoding.DecodeString(data); err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidSource, err.Error())
+		}
+	}
+
+	return string(raw), nil
+}
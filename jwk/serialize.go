@@ -0,0 +1,223 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+)
+
+var ErrInvalidSerialization = errors.New("invalid serialized key")
+
+const serializationPrefix = "jwk1"
+
+// Serialize returns compact string representation of the key: prefix, key type, alg, kid, use and key material,
+// separated by dots. Fields are raw key material, so rehydration needs no JSON parsing nor key derivation.
+func (m *Module) Serialize(key *jose.JSONWebKey) (string, error) {
+	var (
+		kty      string
+		material []string
+	)
+
+	switch k := key.Key.(type) {
+	case ed25519.PrivateKey:
+		kty, material = "OKP", []string{encode(k)}
+	case ed25519.PublicKey:
+		kty, material = "OKP", []string{encode(k)}
+	case *ecdsa.PrivateKey:
+		kty, material = "EC", []string{k.Curve.Params().Name, encodeInt(k.X), encodeInt(k.Y), encodeInt(k.D)}
+	case *ecdsa.PublicKey:
+		kty, material = "EC", []string{k.Curve.Params().Name, encodeInt(k.X), encodeInt(k.Y)}
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return "", fmt.Errorf("%w: multi-prime RSA key", ErrUnsupportedKey)
+		}
+
+		if k.Precomputed.Dp == nil {
+			return "", fmt.Errorf("%w: RSA key without precomputed values", ErrUnsupportedKey)
+		}
+
+		kty = "RSA"
+		material = []string{
+			encodeInt(k.N), encodeInt(big.NewInt(int64(k.E))), encodeInt(k.D),
+			encodeInt(k.Primes[0]), encodeInt(k.Primes[1]),
+			encodeInt(k.Precomputed.Dp), encodeInt(k.Precomputed.Dq), encodeInt(k.Precomputed.Qinv),
+		}
+	case *rsa.PublicKey:
+		kty, material = "RSA", []string{encodeInt(k.N), encodeInt(big.NewInt(int64(k.E)))}
+	default:
+		return "", fmt.Errorf("%w: %T", ErrUnsupportedKey, key.Key)
+	}
+
+	fields := append([]string{serializationPrefix, kty, key.Algorithm, encode([]byte(key.KeyID)), key.Use}, material...)
+
+	return strings.Join(fields, "."), nil
+}
+
+// Rehydrate reconstructs the key from its serialized representation.
+func (m *Module) Rehydrate(serialized string) (*jose.JSONWebKey, error) {
+	return rehydrate(serialized)
+}
+
+func rehydrate(serialized string) (*jose.JSONWebKey, error) {
+	fields := strings.Split(serialized, ".")
+	if len(fields) < 6 || fields[0] != serializationPrefix {
+		return nil, ErrInvalidSerialization
+	}
+
+	kid, err := decode(fields[3])
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jose.JSONWebKey{Algorithm: fields[2], KeyID: string(kid), Use: fields[4]}
+
+	if key.Key, err = rehydrateKey(fields[1], fields[5:]); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func rehydrateKey(kty string, material []string) (interface{}, error) {
+	switch {
+	case kty == "OKP" && len(material) == 1:
+		return rehydrateEd25519(material[0])
+	case kty == "EC" && (len(material) == 3 || len(material) == 4):
+		return rehydrateEC(material[0], material[1:])
+	case kty == "RSA" && (len(material) == 2 || len(material) == 8):
+		return rehydrateRSA(material)
+	}
+
+	return nil, fmt.Errorf("%w: %s key with %d fields", ErrInvalidSerialization, kty, len(material))
+}
+
+func rehydrateEd25519(field string) (interface{}, error) {
+	raw, err := decode(field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(raw) {
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	case ed25519.PublicKeySize:
+		return ed25519.PublicKey(raw), nil
+	}
+
+	return nil, fmt.Errorf("%w: %d bytes Ed25519 key", ErrInvalidSerialization, len(raw))
+}
+
+func rehydrateEC(name string, material []string) (interface{}, error) {
+	var curve elliptic.Curve
+
+	switch name {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		var ok bool
+
+		if curve, _, ok = algorithm.BrainpoolCurve(name); !ok {
+			return nil, fmt.Errorf("%w: %s curve", ErrInvalidSerialization, name)
+		}
+	}
+
+	ints, err := decodeInts(material)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := ecdsa.PublicKey{Curve: curve, X: ints[0], Y: ints[1]}
+
+	if len(ints) == 2 {
+		return &pub, nil
+	}
+
+	return &ecdsa.PrivateKey{PublicKey: pub, D: ints[2]}, nil
+}
+
+func rehydrateRSA(material []string) (interface{}, error) {
+	ints, err := decodeInts(material)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := rsa.PublicKey{N: ints[0], E: int(ints[1].Int64())}
+
+	if len(ints) == 2 {
+		return &pub, nil
+	}
+
+	key := &rsa.PrivateKey{PublicKey: pub, D: ints[2], Primes: ints[3:5]}
+
+	key.Precomputed.Dp, key.Precomputed.Dq, key.Precomputed.Qinv = ints[5], ints[6], ints[7]
+	key.Precompute()
+
+	return key, nil
+}
+
+func encode(raw []byte) string {
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func encodeInt(i *big.Int) string {
+	return encode(i.Bytes())
+}
+
+func decodeInts(fields []string) ([]*big.Int, error) {
+	ints := make([]*big.Int, len(fields))
+
+	for i, field := range fields {
+		raw, err := decode(field)
+		if err != nil {
+			return nil, err
+		}
+
+		ints[i] = new(big.Int).SetBytes(raw)
+	}
+
+	return ints, nil
+}
+
+func decode(field string) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(field)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSerialization, err.Error())
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,89 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"go.k6.io/k6/secretsource"
+)
+
+var ErrMissingKey = errors.New("missing key")
+
+const pemPrefix = "-----BEGIN"
+
+// FromEnv parses the key from the environment variable (__ENV), available in VU code too.
+func (m *Module) FromEnv(name string) (*jose.JSONWebKey, error) {
+	source, ok := m.env[name]
+	if !ok || strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("%w: environment variable %s", ErrMissingKey, name)
+	}
+
+	return parseSource(source)
+}
+
+// FromSecret parses the key from k6 secret source, only in the init context since sources may be remote.
+func (m *Module) FromSecret(name string, source string) (*jose.JSONWebKey, error) {
+	env := m.vu.InitEnv()
+	if env == nil {
+		return nil, fmt.Errorf("%w: secret %s", ErrInitContext, name)
+	}
+
+	if source == "" {
+		source = secretsource.DefaultSourceName
+	}
+
+	if env.SecretsManager == nil {
+		return nil, fmt.Errorf("%w: secret %s: no secret sources are configured", ErrMissingKey, name)
+	}
+
+	// the manager redacts the secret from the logs
+	secret, err := env.SecretsManager.Get(source, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret %s: %s", ErrMissingKey, name, err)
+	}
+
+	return parseSource(secret)
+}
+
+// parseSource parses PEM, serialized or JWK encoded key.
+// PEM with escaped newlines (single line environment variables) is accepted too.
+func parseSource(source string) (*jose.JSONWebKey, error) {
+	source = strings.TrimSpace(source)
+
+	switch {
+	case strings.Contains(source, pemPrefix):
+		if !strings.Contains(source, "\n") {
+			source = strings.ReplaceAll(source, `\n`, "\n")
+		}
+
+		return FromPEM(source)
+	case strings.HasPrefix(source, serializationPrefix+"."):
+		return rehydrate(source)
+	default:
+		return parseKey(source)
+	}
+}
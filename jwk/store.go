@@ -0,0 +1,184 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+)
+
+var (
+	ErrInvalidName      = errors.New("invalid key name")
+	ErrInvalidGenerator = errors.New("invalid key generator")
+)
+
+// Store is the named key registry shared by every VU, keys put in init code of one VU can be used by the others.
+type Store struct{}
+
+var (
+	stored   = map[string]interface{}{}
+	storedMu sync.RWMutex
+
+	// generating holds the locks of GetOrPut calls by name
+	generating = map[string]*sync.Mutex{}
+)
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Put stores the key, key set or key provider (e.g. discovered issuer) with name, replacing the previous one.
+func (s *Store) Put(name string, in interface{}) error {
+	if len(name) == 0 {
+		return ErrInvalidName
+	}
+
+	var value interface{}
+
+	switch key := in.(type) {
+	case *jose.JSONWebKey:
+		copied := *key
+		value = &copied
+	case jose.JSONWebKey:
+		value = &key
+	case KeyProvider:
+		value = key
+	default:
+		set, err := KeySet(in)
+		if err != nil {
+			return err
+		}
+
+		value = set
+	}
+
+	storedMu.Lock()
+	stored[name] = value
+	storedMu.Unlock()
+
+	return nil
+}
+
+// Get returns the value stored with name, null if there is none.
+func (s *Store) Get(name string) interface{} {
+	storedMu.RLock()
+	value, ok := stored[name]
+	storedMu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	switch key := value.(type) {
+	case *jose.JSONWebKey:
+		copied := *key
+
+		return &copied
+	case []jose.JSONWebKey:
+		return append([]jose.JSONWebKey{}, key...)
+	}
+
+	return value
+}
+
+// GetOrPut returns the value stored with name, or stores and returns the value created by fn.
+// fn is called once per name, concurrent calls with the name (e.g. init code of other VUs) wait for it.
+func (s *Store) GetOrPut(name string, fn sobek.Value) (interface{}, error) {
+	if len(name) == 0 {
+		return nil, ErrInvalidName
+	}
+
+	call, ok := sobek.AssertFunction(fn)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be created by a function", ErrInvalidGenerator, name)
+	}
+
+	storedMu.Lock()
+
+	lock, ok := generating[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		generating[name] = lock
+	}
+
+	storedMu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if value := s.Get(name); value != nil {
+		return value, nil
+	}
+
+	ret, err := call(sobek.Undefined())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Put(name, ret.Export()); err != nil {
+		return nil, err
+	}
+
+	return s.Get(name), nil
+}
+
+func (s *Store) Has(name string) bool {
+	storedMu.RLock()
+	defer storedMu.RUnlock()
+
+	_, ok := stored[name]
+
+	return ok
+}
+
+// Delete removes the value stored with name, returns false if there was none.
+func (s *Store) Delete(name string) bool {
+	storedMu.Lock()
+	defer storedMu.Unlock()
+
+	_, ok := stored[name]
+	delete(stored, name)
+
+	return ok
+}
+
+// Names returns the sorted names of the stored values.
+func (s *Store) Names() []string {
+	storedMu.RLock()
+	names := make([]string, 0, len(stored))
+
+	for name := range stored {
+		names = append(names, name)
+	}
+
+	storedMu.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
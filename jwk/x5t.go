@@ -0,0 +1,74 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwk
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+type CertificateThumbprints struct {
+	X5T     string `js:"x5t"`
+	X5TS256 string `js:"x5t#S256"`
+}
+
+// CertificateThumbprints returns the x5t and x5t#S256 header values of the certificate, or of the leaf certificate
+// of the key.
+func (m *Module) CertificateThumbprints(in interface{}) (*CertificateThumbprints, error) {
+	var certs []*x509.Certificate
+
+	switch value := in.(type) {
+	case *jose.JSONWebKey:
+		certs = value.Certificates
+	case jose.JSONWebKey:
+		certs = value.Certificates
+	default:
+		var err error
+
+		if certs, err = certificates(in); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no certificate", ErrInvalidChain)
+	}
+
+	return Thumbprints(certs[0]), nil
+}
+
+// Thumbprints returns the SHA-1 and SHA-256 thumbprints of the DER encoded certificate.
+func Thumbprints(cert *x509.Certificate) *CertificateThumbprints {
+	s1 := sha1.Sum(cert.Raw)
+	s256 := sha256.Sum256(cert.Raw)
+
+	return &CertificateThumbprints{
+		X5T:     base64.RawURLEncoding.EncodeToString(s1[:]),
+		X5TS256: base64.RawURLEncoding.EncodeToString(s256[:]),
+	}
+}
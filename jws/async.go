@@ -0,0 +1,54 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jws
+
+import (
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/async"
+)
+
+// SignAsync is Sign running outside of the event loop.
+func (m *Module) SignAsync(
+	keyIn interface{},
+	payloadIn interface{},
+	header map[string]interface{},
+	options *SignOptions,
+) *sobek.Promise {
+	args := async.Detach(m.vu.Runtime(), keyIn, payloadIn)
+	err := async.Check(keyIn)
+
+	return async.Promise(m.vu, func() (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		return m.Sign(args[0], args[1], header, options)
+	})
+}
+
+// VerifyAsync is Verify running outside of the event loop, the promise is resolved with the payload as ArrayBuffer.
+func (m *Module) VerifyAsync(token string, keys ...interface{}) *sobek.Promise {
+	keys = async.Detach(m.vu.Runtime(), keys...)
+
+	return async.Promise(m.vu, func() (interface{}, error) { return m.verify(token, keys) })
+}
@@ -0,0 +1,135 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jws
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+// Hmac returns the bare HMAC of data with the oct key, without JWS framing.
+func (m *Module) Hmac(keyIn interface{}, dataIn interface{}, algorithm string) (sum sobek.ArrayBuffer, err error) {
+	start := time.Now()
+
+	var alg string
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Sign, alg, start, err) }()
+
+	alg, mac, err := m.hmac(keyIn, dataIn, algorithm)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(mac), nil
+}
+
+// VerifyHmac compares the signature with the HMAC of data in constant time.
+func (m *Module) VerifyHmac(keyIn interface{}, dataIn interface{}, signatureIn interface{}, algorithm string) (err error) {
+	start := time.Now()
+
+	var alg string
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Verify, alg, start, err) }()
+
+	signature, err := buffer.Bytes(signatureIn)
+	if err != nil {
+		return err
+	}
+
+	alg, mac, err := m.hmac(keyIn, dataIn, algorithm)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(mac, signature) {
+		return jose.ErrCryptoFailure
+	}
+
+	return nil
+}
+
+func (m *Module) hmac(keyIn interface{}, dataIn interface{}, algorithm string) (string, []byte, error) {
+	keys, err := jwk.KeySet(keyIn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(keys) != 1 {
+		return "", nil, fmt.Errorf("%w: %d keys", jwk.ErrUnsupportedKey, len(keys))
+	}
+
+	secret, ok := keys[0].Key.([]byte)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %T is not an oct key", jwk.ErrUnsupportedKey, keys[0].Key)
+	}
+
+	alg := strings.ToUpper(algorithm)
+	if alg == "" {
+		alg = keys[0].Algorithm
+	}
+
+	if alg == "" {
+		alg = string(jose.HS256)
+	}
+
+	hash, err := hmacHash(alg)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	if err := m.config.Allow(alg); err != nil {
+		return alg, nil, err
+	}
+
+	data, err := buffer.Bytes(dataIn)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	mac := hmac.New(hash.New, secret)
+	_, _ = mac.Write(data)
+
+	return alg, mac.Sum(nil), nil
+}
+
+func hmacHash(alg string) (crypto.Hash, error) {
+	switch jose.SignatureAlgorithm(alg) {
+	case jose.HS256:
+		return crypto.SHA256, nil
+	case jose.HS384:
+		return crypto.SHA384, nil
+	case jose.HS512:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+}
@@ -0,0 +1,553 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jws
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/internal/trace"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	vu      modules.VU
+	metrics *metrics.Metrics
+	trace   *trace.Tracer
+	config  *config.Config
+}
+
+type SignOptions struct {
+	Serialization string      `js:"serialization"`
+	Detached      bool        `js:"detached"`
+	B64           *bool       `js:"b64"`
+	Unprotected   interface{} `js:"unprotected"`
+	Critical      []string    `js:"critical"`
+	SaltLength    interface{} `js:"saltLength"`
+}
+
+type Signature struct {
+	Protected map[string]interface{} `js:"protected"`
+	Header    map[string]interface{} `js:"header"`
+	Signature sobek.ArrayBuffer      `js:"signature"`
+}
+
+type Parsed struct {
+	Payload    sobek.ArrayBuffer `js:"payload"`
+	Signatures []Signature       `js:"signatures"`
+}
+
+type Verified struct {
+	Payload   sobek.ArrayBuffer `js:"payload"`
+	Index     int               `js:"index"`
+	KeyID     string            `js:"kid"`
+	Algorithm string            `js:"alg"`
+	Key       *jose.JSONWebKey  `js:"key"`
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return NewModule(vu, config.Default(vu)) })
+}
+
+// NewModule returns the module object of the VU using the given configuration.
+func NewModule(vu modules.VU, cfg *config.Config) *Module {
+	return &Module{vu: vu, metrics: metrics.New(vu), trace: trace.New(vu, cfg), config: cfg}
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
+}
+
+const moduleName = "jws"
+
+var ErrAlgorithmNotAllowed = config.ErrAlgorithmNotAllowed
+
+func (m *Module) Sign(
+	keyIn interface{},
+	payloadIn interface{},
+	header map[string]interface{},
+	options *SignOptions,
+) (token string, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() {
+		m.metrics.Measure(moduleName, metrics.Sign, alg, start, err)
+		m.trace.Content(moduleName, metrics.Sign, header, payloadIn, []interface{}{keyIn}, err)
+	}()
+
+	payload, err := buffer.Bytes(payloadIn)
+	if err != nil {
+		return "", err
+	}
+
+	keys, err := jwk.KeySet(keyIn)
+	if err != nil {
+		return "", err
+	}
+
+	if len(keys) != 0 {
+		alg = keys[0].Algorithm
+	}
+
+	if options == nil {
+		options = &SignOptions{}
+	}
+
+	unprotected, err := unprotectedHeaders(options.Unprotected, len(keys))
+	if err != nil {
+		return "", err
+	}
+
+	critical := options.Critical
+
+	if options.B64 != nil && !*options.B64 {
+		header = withHeader(header, crit.B64, false)
+		critical = append([]string{crit.B64}, critical...)
+	}
+
+	if header, err = crit.Mark(header, critical...); err != nil {
+		return "", err
+	}
+
+	saltLength, err := algorithm.SaltLength(options.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	opts := &jose.SignerOptions{}
+
+	for k, v := range header {
+		opts.WithHeader(jose.HeaderKey(k), v)
+	}
+
+	sigs := make([]jose.SigningKey, len(keys))
+
+	for i := range keys {
+		// kid goes to the unprotected header only, if it's there
+		if _, ok := unprotected[i]["kid"]; ok {
+			keys[i].KeyID = ""
+		}
+
+		sigs[i] = jose.SigningKey{
+			Algorithm: jose.SignatureAlgorithm(keys[i].Algorithm),
+			Key:       algorithm.BrainpoolKey(algorithm.PSSKey(&keys[i], saltLength)),
+		}
+	}
+
+	sig, err := jose.NewMultiSigner(sigs, opts)
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := sig.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	serialization := options.Serialization
+	if serialization == "" {
+		switch {
+		case len(keys) > 1:
+			serialization = serializationGeneral
+		case options.Unprotected != nil:
+			serialization = serializationFlattened
+		}
+	}
+
+	b64, ok := opts.ExtraHeaders["b64"].(bool)
+
+	msg := &message{obj: obj, payload: payload, unprotected: unprotected}
+
+	return serialize(msg, serialization, options.Detached, ok && !b64)
+}
+
+// withHeader returns a copy of the header with the parameter set.
+func withHeader(header map[string]interface{}, name string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(header)+1)
+
+	for k, v := range header {
+		copied[k] = v
+	}
+
+	copied[name] = value
+
+	return copied
+}
+
+// unprotectedHeaders converts the unprotected option (a header for every signature, or an array of per signature headers).
+func unprotectedHeaders(in interface{}, count int) ([]map[string]interface{}, error) {
+	headers := make([]map[string]interface{}, count)
+
+	switch value := in.(type) {
+	case nil:
+	case map[string]interface{}:
+		for i := range headers {
+			headers[i] = value
+		}
+	case []interface{}:
+		if len(value) > count {
+			return nil, fmt.Errorf("%w: %d unprotected headers for %d signatures", ErrInvalidHeader, len(value), count)
+		}
+
+		for i, v := range value {
+			if v == nil {
+				continue
+			}
+
+			header, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: %T", ErrInvalidHeader, v)
+			}
+
+			headers[i] = header
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrInvalidHeader, in)
+	}
+
+	return headers, nil
+}
+
+func (m *Module) Decode(token string) (sobek.ArrayBuffer, error) {
+	msg, err := parse(token, nil, m.config)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(msg.payload), nil
+}
+
+func (m *Module) Parse(token string) (*Parsed, error) {
+	msg, err := parse(token, nil, m.config)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := m.vu.Runtime()
+
+	parsed := &Parsed{
+		Payload:    rt.NewArrayBuffer(msg.payload),
+		Signatures: make([]Signature, len(msg.obj.Signatures)),
+	}
+
+	for i, sig := range msg.obj.Signatures {
+		parsed.Signatures[i] = Signature{
+			Protected: msg.protected[i],
+			Header:    msg.unprotected[i],
+			Signature: rt.NewArrayBuffer(sig.Signature),
+		}
+	}
+
+	return parsed, nil
+}
+
+func (m *Module) Verify(token string, keys ...interface{}) (sobek.ArrayBuffer, error) {
+	payload, err := m.verify(token, keys)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(payload), nil
+}
+
+// verify returns the payload of the token verified by any of its signatures, it doesn't use the JS runtime.
+func (m *Module) verify(token string, keys []interface{}) (payload []byte, err error) {
+	var msg *message
+
+	start := time.Now()
+
+	defer func() {
+		m.metrics.Measure(moduleName, metrics.Verify, msg.algorithm(), start, err)
+		m.trace.Token(moduleName, metrics.Verify, token, keys, err)
+	}()
+
+	msg, err = parse(token, nil, m.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = msg.allow(m.config); err != nil {
+		return nil, err
+	}
+
+	if err = msg.verifyAny(keys); err != nil {
+		return nil, err
+	}
+
+	return msg.payload, nil
+}
+
+// VerifyWith verifies any signature of the token like Module.Verify with the given configuration,
+// returns the payload and the protected header of the first signature.
+func VerifyWith(cfg *config.Config, token string, keys ...interface{}) ([]byte, map[string]interface{}, error) {
+	msg, err := parse(token, nil, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = msg.allow(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	if err = msg.verifyAny(keys); err != nil {
+		return nil, nil, err
+	}
+
+	return msg.payload, msg.protected[0], nil
+}
+
+func (m *Module) VerifyDetailed(token string, keys ...interface{}) (verified *Verified, err error) {
+	var msg *message
+
+	start := time.Now()
+
+	defer func() {
+		m.metrics.Measure(moduleName, metrics.Verify, msg.algorithm(), start, err)
+		m.trace.Token(moduleName, metrics.Verify, token, keys, err)
+	}()
+
+	msg, err = parse(token, nil, m.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = msg.allow(m.config); err != nil {
+		return nil, err
+	}
+
+	set, err := jwk.KeySet(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = jose.ErrCryptoFailure
+
+	for i := range msg.obj.Signatures {
+		var key *jose.JSONWebKey
+
+		if key, err = msg.verifySignature(i, set); err == nil {
+			return &Verified{
+				Payload:   m.vu.Runtime().NewArrayBuffer(msg.payload),
+				Index:     i,
+				KeyID:     key.KeyID,
+				Algorithm: msg.obj.Signatures[i].Header.Algorithm,
+				Key:       key,
+			}, nil
+		}
+	}
+
+	return nil, err
+}
+
+func (m *Module) VerifyAll(token string, keys ...interface{}) (payload sobek.ArrayBuffer, err error) {
+	var msg *message
+
+	start := time.Now()
+
+	defer func() {
+		m.metrics.Measure(moduleName, metrics.Verify, msg.algorithm(), start, err)
+		m.trace.Token(moduleName, metrics.Verify, token, keys, err)
+	}()
+
+	msg, err = parse(token, nil, m.config)
+	if err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	if err = msg.allow(m.config); err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	if err = msg.verifyAll(keys); err != nil {
+		return sobek.ArrayBuffer{}, err
+	}
+
+	return m.vu.Runtime().NewArrayBuffer(msg.payload), nil
+}
+
+func (m *Module) VerifyDetached(token string, payloadIn interface{}, keys ...interface{}) error {
+	start := time.Now()
+
+	msg, err := verifyDetached(token, payloadIn, keys, m.config)
+
+	m.metrics.Measure(moduleName, metrics.Verify, msg.algorithm(), start, err)
+	m.trace.Token(moduleName, metrics.Verify, token, keys, err)
+
+	return err
+}
+
+func verifyDetached(token string, payloadIn interface{}, keys []interface{}, cfg *config.Config) (*message, error) {
+	payload, err := buffer.Bytes(payloadIn)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	msg, err := parse(token, payload, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := msg.allow(cfg); err != nil {
+		return msg, err
+	}
+
+	return msg, msg.verifyAny(keys)
+}
+
+// message is a JWS with its (possibly detached or unencoded) payload and per signature raw headers.
+type message struct {
+	obj          *jose.JSONWebSignature
+	payload      []byte
+	protected    []map[string]interface{}
+	unprotected  []map[string]interface{}
+	rawProtected []string
+}
+
+// algorithm returns the algorithm of the first signature, or empty string if the message couldn't be parsed.
+func (msg *message) algorithm() string {
+	if msg == nil || len(msg.obj.Signatures) == 0 {
+		return ""
+	}
+
+	return msg.obj.Signatures[0].Header.Algorithm
+}
+
+// allow returns error if the algorithm of any signature is not allowed by the configuration,
+// or a signature has critical header parameters neither handled by the configuration nor by the module.
+func (msg *message) allow(cfg *config.Config) error {
+	handled := []string{crit.B64}
+	if cfg != nil {
+		handled = append(handled, cfg.Critical...)
+	}
+
+	for i, sig := range msg.obj.Signatures {
+		if err := cfg.Allow(sig.Header.Algorithm); err != nil {
+			return err
+		}
+
+		if _, err := crit.Check(msg.protected[i], msg.unprotected[i], handled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (msg *message) verifyAny(keys []interface{}) error {
+	set, err := jwk.KeySet(keys...)
+	if err != nil {
+		return err
+	}
+
+	err = jose.ErrCryptoFailure
+
+	for i := range msg.obj.Signatures {
+		if _, err = msg.verifySignature(i, set); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (msg *message) verifyAll(keys []interface{}) error {
+	set, err := jwk.KeySet(keys...)
+	if err != nil {
+		return err
+	}
+
+	for i := range msg.obj.Signatures {
+		if _, err := msg.verifySignature(i, set); err != nil {
+			return fmt.Errorf("signature %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// verifySignature verifies the idx-th signature with the keys matching its kid header,
+// or with every key if there is no such key. The matching key is returned.
+func (msg *message) verifySignature(idx int, set []jose.JSONWebKey) (*jose.JSONWebKey, error) {
+	candidates := jwk.Candidates(set, msg.obj.Signatures[idx].Header.KeyID)
+
+	single := *msg.obj
+	single.Signatures = msg.obj.Signatures[idx : idx+1]
+
+	verify := func(key *jose.JSONWebKey) error { return single.DetachedVerify(msg.payload, key) }
+
+	// go-jose verifies only b64 critical signatures, and no Brainpool signatures
+	names, _ := crit.Names(msg.protected[idx])
+	if !crit.Native(names) || algorithm.IsBrainpool(msg.obj.Signatures[idx].Header.Algorithm) {
+		verify = func(key *jose.JSONWebKey) error { return msg.verifyCritical(idx, key) }
+	}
+
+	err := jose.ErrCryptoFailure
+
+	for i := range candidates {
+		if err = verify(&candidates[i]); err == nil {
+			return &candidates[i], nil
+		}
+	}
+
+	if kid := msg.obj.Signatures[idx].Header.KeyID; kid != "" && !jwk.HasKeyID(set, kid) {
+		return nil, fmt.Errorf("%w: %s", jwk.ErrUnknownKeyID, kid)
+	}
+
+	return nil, err
+}
+
+// verifyCritical verifies the idx-th signature over the raw protected header and payload.
+func (msg *message) verifyCritical(idx int, key *jose.JSONWebKey) error {
+	alg := msg.obj.Signatures[idx].Header.Algorithm
+
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return jose.ErrCryptoFailure
+	}
+
+	input := msg.rawProtected[idx] + "."
+
+	if b64, ok := msg.protected[idx][crit.B64].(bool); ok && !b64 {
+		input += string(msg.payload)
+	} else {
+		input += base64.RawURLEncoding.EncodeToString(msg.payload)
+	}
+
+	return crit.Verify(jose.SignatureAlgorithm(alg), key.Key, []byte(input), msg.obj.Signatures[idx].Signature)
+}
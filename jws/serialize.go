@@ -0,0 +1,328 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jws
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/config"
+)
+
+const (
+	serializationCompact   = "compact"
+	serializationFlattened = "flattened"
+	serializationGeneral   = "general"
+)
+
+var (
+	ErrUnsupportedSerialization = errors.New("unsupported serialization")
+	ErrInvalidPayload           = errors.New("invalid payload")
+	ErrMalformed                = errors.New("malformed JWS")
+	ErrInvalidHeader            = errors.New("invalid header")
+)
+
+// serialize produces the requested serialization form, JSON forms are reshaped from go-jose's full serialization.
+func serialize(msg *message, serialization string, detached bool, unencoded bool) (string, error) {
+	// the unencoded payload is part of the token string, binary data can not be represented
+	if unencoded && !detached && !utf8.Valid(msg.payload) {
+		return "", fmt.Errorf("%w: unencoded payload must be valid UTF-8", ErrInvalidPayload)
+	}
+
+	switch strings.ToLower(serialization) {
+	case "", serializationCompact:
+		for _, header := range msg.unprotected {
+			if header != nil {
+				return "", fmt.Errorf("%w: %s with unprotected header", ErrUnsupportedSerialization, serialization)
+			}
+		}
+
+		if !detached && !unencoded {
+			return msg.obj.CompactSerialize()
+		}
+
+		str, err := msg.obj.DetachedCompactSerialize()
+		if err != nil || detached {
+			return str, err
+		}
+
+		if strings.ContainsRune(string(msg.payload), '.') {
+			return "", fmt.Errorf("%w: unencoded compact payload must not contain '.'", ErrInvalidPayload)
+		}
+
+		parts := strings.Split(str, ".")
+
+		return parts[0] + "." + string(msg.payload) + "." + parts[2], nil
+	case serializationFlattened:
+		raw, err := general(msg, detached, unencoded)
+		if err != nil {
+			return "", err
+		}
+
+		sigs, _ := raw["signatures"].([]interface{})
+		if len(sigs) != 1 {
+			return "", fmt.Errorf("%w: %s with %d signatures", ErrUnsupportedSerialization, serialization, len(sigs))
+		}
+
+		flat, _ := sigs[0].(map[string]interface{})
+
+		if payload, ok := raw["payload"]; ok {
+			flat["payload"] = payload
+		}
+
+		return marshal(flat)
+	case serializationGeneral:
+		raw, err := general(msg, detached, unencoded)
+		if err != nil {
+			return "", err
+		}
+
+		return marshal(raw)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedSerialization, serialization)
+	}
+}
+
+func general(msg *message, detached bool, unencoded bool) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+
+	if err := json.Unmarshal([]byte(msg.obj.FullSerialize()), &raw); err != nil {
+		return nil, err
+	}
+
+	if _, ok := raw["signatures"]; !ok {
+		sig := map[string]interface{}{}
+
+		for _, k := range []string{"protected", "header", "signature"} {
+			if v, ok := raw[k]; ok {
+				sig[k] = v
+			}
+		}
+
+		raw = map[string]interface{}{
+			"payload":    raw["payload"],
+			"signatures": []interface{}{sig},
+		}
+	}
+
+	sigs, _ := raw["signatures"].([]interface{})
+
+	for i, header := range msg.unprotected {
+		if sig, ok := sigs[i].(map[string]interface{}); ok && header != nil {
+			sig["header"] = header
+		}
+	}
+
+	switch {
+	case detached:
+		delete(raw, "payload")
+	case unencoded:
+		raw["payload"] = string(msg.payload)
+	}
+
+	return raw, nil
+}
+
+func marshal(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// parse parses compact or JSON serialized JWS, taking care of unencoded (RFC 7797) payloads.
+// The detached payload must be nil unless the JWS has detached content. Tokens exceeding the parsing limits
+// of the configuration are rejected.
+func parse(token string, detached []byte, cfg *config.Config) (*message, error) {
+	if err := cfg.CheckToken(token); err != nil {
+		return nil, err
+	}
+
+	token = strings.TrimSpace(token)
+
+	if strings.HasPrefix(token, "{") {
+		return parseJSON(token, detached)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: compact JWS must have three parts", ErrMalformed)
+	}
+
+	payload := detached
+
+	switch {
+	case detached != nil:
+		if parts[1] != "" {
+			return nil, fmt.Errorf("%w: payload is not detached", ErrMalformed)
+		}
+	case unencoded(parts[0]):
+		payload = []byte(parts[1])
+	default:
+		var err error
+
+		if payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	obj, err := jose.ParseDetached(parts[0]+".."+parts[2], payload, algorithm.Signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	protected, err := decodeHeader(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &message{
+		obj:          obj,
+		payload:      payload,
+		protected:    []map[string]interface{}{protected},
+		unprotected:  []map[string]interface{}{nil},
+		rawProtected: []string{parts[0]},
+	}, nil
+}
+
+func parseJSON(token string, detached []byte) (*message, error) {
+	raw := map[string]interface{}{}
+
+	if err := json.Unmarshal([]byte(token), &raw); err != nil {
+		return nil, err
+	}
+
+	str, hasPayload := raw["payload"].(string)
+
+	payload := detached
+
+	switch {
+	case detached != nil:
+		if hasPayload && str != "" {
+			return nil, fmt.Errorf("%w: payload is not detached", ErrMalformed)
+		}
+	case !hasPayload:
+		return nil, fmt.Errorf("%w: missing payload", ErrMalformed)
+	case unencodedJSON(raw):
+		payload = []byte(str)
+	default:
+		var err error
+
+		if payload, err = base64.RawURLEncoding.DecodeString(str); err != nil {
+			return nil, err
+		}
+	}
+
+	raw["payload"] = ""
+
+	src, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := jose.ParseSigned(string(src), algorithm.Signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &message{obj: obj, payload: payload}
+
+	sigs, ok := raw["signatures"].([]interface{})
+	if !ok {
+		sigs = []interface{}{raw}
+	}
+
+	for _, s := range sigs {
+		sig, _ := s.(map[string]interface{})
+		str, _ := sig["protected"].(string)
+
+		protected, err := decodeHeader(str)
+		if err != nil {
+			return nil, err
+		}
+
+		header, _ := sig["header"].(map[string]interface{})
+
+		msg.protected = append(msg.protected, protected)
+		msg.unprotected = append(msg.unprotected, header)
+		msg.rawProtected = append(msg.rawProtected, str)
+	}
+
+	return msg, nil
+}
+
+func decodeHeader(protected string) (map[string]interface{}, error) {
+	if protected == "" {
+		return nil, nil
+	}
+
+	src, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	header := map[string]interface{}{}
+
+	if err := json.Unmarshal(src, &header); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+func unencoded(protected string) bool {
+	header, err := decodeHeader(protected)
+	if err != nil {
+		return false
+	}
+
+	b64, ok := header["b64"].(bool)
+
+	return ok && !b64
+}
+
+func unencodedJSON(raw map[string]interface{}) bool {
+	if protected, ok := raw["protected"].(string); ok {
+		return unencoded(protected)
+	}
+
+	sigs, _ := raw["signatures"].([]interface{})
+
+	for _, s := range sigs {
+		if sig, ok := s.(map[string]interface{}); ok {
+			if protected, ok := sig["protected"].(string); ok && unencoded(protected) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,269 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jws
+
+import (
+	gobytes "bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrFinished             = errors.New("stream signer already finished")
+)
+
+// StreamSigner creates detached JWS over content written in chunks.
+// The signing input is hashed incrementally, except for EdDSA, which needs the whole message.
+type StreamSigner struct {
+	key       interface{}
+	alg       jose.SignatureAlgorithm
+	protected string
+	hash      crypto.Hash
+	digest    hash.Hash
+	message   *gobytes.Buffer
+	sink      io.Writer
+	encoder   io.WriteCloser
+	finished  bool
+}
+
+// curve of the ES* algorithms
+var curveNames = map[jose.SignatureAlgorithm]string{
+	jose.ES256: "P-256",
+	jose.ES384: "P-384",
+	jose.ES512: "P-521",
+}
+
+func (m *Module) CreateStreamSigner(
+	key *jose.JSONWebKey,
+	header map[string]interface{},
+	options *SignOptions,
+) (*sobek.Object, error) {
+	if options == nil {
+		options = &SignOptions{}
+	}
+
+	alg := jose.SignatureAlgorithm(key.Algorithm)
+
+	// alg of the header overrides the key's algorithm, it is checked against the key by init
+	if override, ok := header["alg"]; ok {
+		name, isString := override.(string)
+		if !isString {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, override)
+		}
+
+		alg = jose.SignatureAlgorithm(name)
+	}
+
+	protected := map[string]interface{}{}
+
+	if key.KeyID != "" {
+		protected["kid"] = key.KeyID
+	}
+
+	for k, v := range header {
+		protected[k] = v
+	}
+
+	protected["alg"] = string(alg)
+
+	critical := options.Critical
+
+	unencoded := options.B64 != nil && !*options.B64
+	if unencoded {
+		protected[crit.B64] = false
+		critical = append([]string{crit.B64}, critical...)
+	}
+
+	protected, err := crit.Mark(protected, critical...)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StreamSigner{key: key.Key, alg: alg, protected: base64.RawURLEncoding.EncodeToString(src)}
+
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+
+	_, _ = io.WriteString(s.sink, s.protected+".")
+
+	if !unencoded {
+		s.encoder = base64.NewEncoder(base64.RawURLEncoding, s.sink)
+	}
+
+	return jsmodule.Exports(m.vu.Runtime(), s), nil
+}
+
+func (s *StreamSigner) init() error {
+	switch s.alg {
+	case jose.HS256, jose.RS256, jose.PS256, jose.ES256:
+		s.hash = crypto.SHA256
+	case jose.HS384, jose.RS384, jose.PS384, jose.ES384:
+		s.hash = crypto.SHA384
+	case jose.HS512, jose.RS512, jose.PS512, jose.ES512:
+		s.hash = crypto.SHA512
+	case jose.EdDSA:
+		if _, ok := s.key.(ed25519.PrivateKey); !ok {
+			return fmt.Errorf("%w: %s with %T", ErrUnsupportedAlgorithm, s.alg, s.key)
+		}
+
+		s.message = &gobytes.Buffer{}
+		s.sink = s.message
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, s.alg)
+	}
+
+	family := string(s.alg[:2])
+
+	switch key := s.key.(type) {
+	case []byte:
+		if family != "HS" {
+			return fmt.Errorf("%w: %s with %T", ErrUnsupportedAlgorithm, s.alg, s.key)
+		}
+
+		if len(key) < s.hash.Size() {
+			return fmt.Errorf("%w: expected at least %d bytes, got %d", jose.ErrInvalidKeySize, s.hash.Size(), len(key))
+		}
+
+		s.digest = hmac.New(s.hash.New, key)
+	case *rsa.PrivateKey:
+		if family != "RS" && family != "PS" {
+			return fmt.Errorf("%w: %s with %T", ErrUnsupportedAlgorithm, s.alg, s.key)
+		}
+
+		s.digest = s.hash.New()
+	case *ecdsa.PrivateKey:
+		if curveNames[s.alg] != key.Curve.Params().Name {
+			return fmt.Errorf("%w: %s with %s key", ErrUnsupportedAlgorithm, s.alg, key.Curve.Params().Name)
+		}
+
+		s.digest = s.hash.New()
+	default:
+		return fmt.Errorf("%w: %s with %T", ErrUnsupportedAlgorithm, s.alg, s.key)
+	}
+
+	s.sink = s.digest
+
+	return nil
+}
+
+func (s *StreamSigner) Update(chunkIn interface{}) error {
+	if s.finished {
+		return ErrFinished
+	}
+
+	chunk, err := buffer.Bytes(chunkIn)
+	if err != nil {
+		return err
+	}
+
+	if s.encoder != nil {
+		_, err = s.encoder.Write(chunk)
+	} else {
+		_, err = s.sink.Write(chunk)
+	}
+
+	return err
+}
+
+func (s *StreamSigner) Finish() (string, error) {
+	if s.finished {
+		return "", ErrFinished
+	}
+
+	s.finished = true
+
+	if s.encoder != nil {
+		if err := s.encoder.Close(); err != nil {
+			return "", err
+		}
+	}
+
+	sig, err := s.sign()
+	if err != nil {
+		return "", err
+	}
+
+	return s.protected + ".." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (s *StreamSigner) sign() ([]byte, error) {
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, s.message.Bytes()), nil
+	case []byte:
+		return s.digest.Sum(nil), nil
+	case *rsa.PrivateKey:
+		if s.alg == jose.PS256 || s.alg == jose.PS384 || s.alg == jose.PS512 {
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+
+			return rsa.SignPSS(rand.Reader, key, s.hash, s.digest.Sum(nil), opts)
+		}
+
+		return rsa.SignPKCS1v15(rand.Reader, key, s.hash, s.digest.Sum(nil))
+	case *ecdsa.PrivateKey:
+		return ecdsaSign(key, s.digest.Sum(nil))
+	default:
+		return nil, fmt.Errorf("%w: %s with %T", ErrUnsupportedAlgorithm, s.alg, s.key)
+	}
+}
+
+// ecdsaSign produces the fixed size r || s signature form of RFC 7518.
+func ecdsaSign(key *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	size := (key.Curve.Params().BitSize + 7) / 8
+	out := make([]byte, 2*size)
+
+	r.FillBytes(out[:size])
+	s.FillBytes(out[size:])
+
+	return out, nil
+}
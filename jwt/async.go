@@ -0,0 +1,63 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/async"
+)
+
+// SignAsync is Sign running outside of the event loop, the sign time is taken (and the clock is called) before.
+func (m *Module) SignAsync(
+	key *jose.JSONWebKey,
+	payload, header map[string]interface{},
+	options *SignOptions,
+) *sobek.Promise {
+	opts := SignOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	now, err := signTime(options, m.config)
+	opts.Now = now
+
+	if err == nil {
+		err = async.Check(key)
+	}
+
+	return async.Promise(m.vu, func() (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		return m.Sign(key, payload, header, &opts)
+	})
+}
+
+// VerifyAsync is Verify running outside of the event loop.
+func (m *Module) VerifyAsync(compact string, keys ...interface{}) *sobek.Promise {
+	keys = async.Detach(m.vu.Runtime(), keys...)
+
+	return async.Promise(m.vu, func() (interface{}, error) { return m.Verify(compact, keys...) })
+}
@@ -0,0 +1,81 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"container/list"
+	"sync"
+)
+
+const compiledCacheSize = 256
+
+// compiledCache is a bounded LRU cache of compiled selectors and patterns by source, shared by the VUs.
+// The cached values are immutable, so they are returned without copying.
+type compiledCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+}
+
+type compiledEntry struct {
+	source string
+	value  interface{}
+}
+
+func newCompiledCache(size int) *compiledCache {
+	return &compiledCache{entries: make(map[string]*list.Element), order: list.New(), size: size}
+}
+
+func (c *compiledCache) get(source string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[source]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return elem.Value.(*compiledEntry).value, true
+}
+
+func (c *compiledCache) put(source string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[source]; ok {
+		c.order.MoveToFront(elem)
+
+		return
+	}
+
+	c.entries[source] = c.order.PushFront(&compiledEntry{source: source, value: value})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*compiledEntry).source)
+	}
+}
@@ -0,0 +1,55 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestCompiledCacheEvicts(t *testing.T) {
+	t.Parallel()
+
+	cache := newCompiledCache(2)
+
+	cache.put("a", 1)
+	cache.put("b", 2)
+
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("a evicted before the cache is full")
+	}
+
+	cache.put("c", 3)
+
+	if _, ok := cache.get("b"); ok {
+		t.Fatal("least recently used entry not evicted")
+	}
+
+	for i := range 10 {
+		cache.put(strconv.Itoa(i), i)
+	}
+
+	if len(cache.entries) != 2 || cache.order.Len() != 2 {
+		t.Fatalf("cache grew to %d entries", len(cache.entries))
+	}
+}
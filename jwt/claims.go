@@ -0,0 +1,66 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+func (m *Module) Merge(base map[string]interface{}, overrides ...map[string]interface{}) map[string]interface{} {
+	return Merge(base, overrides...)
+}
+
+// Merge applies overrides in order to a copy of base.
+func Merge(base map[string]interface{}, overrides ...map[string]interface{}) map[string]interface{} {
+	claims := merge(nil, base)
+
+	for _, o := range overrides {
+		claims = merge(claims, o)
+	}
+
+	return claims
+}
+
+// merge applies overrides to a copy of base, nil valued overrides remove the claim.
+func merge(base, overrides map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(base)+len(overrides))
+
+	for k, v := range base {
+		out[k] = v
+	}
+
+	for k, v := range overrides {
+		if v == nil {
+			delete(out, k)
+
+			continue
+		}
+
+		if vm, ok := v.(map[string]interface{}); ok {
+			bm, _ := out[k].(map[string]interface{})
+			out[k] = merge(bm, vm)
+
+			continue
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
@@ -0,0 +1,242 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/crit"
+)
+
+// compactSigner creates compact serialized JWTs with the protected header encoded only once.
+// Keys without direct support (opaque signers) are signed by go-jose.
+type compactSigner struct {
+	header   []byte
+	sign     func(input []byte) ([]byte, error)
+	fallback jose.Signer
+}
+
+// newSigner creates signer of the key, PS256, PS384 and PS512 signatures use the salt length (see algorithm.SaltLength).
+func newSigner(key *jose.JSONWebKey, header map[string]interface{}, saltLength int) (*compactSigner, error) {
+	header, err := crit.Mark(header)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &jose.SignerOptions{}
+	opts = opts.WithType("JWT")
+
+	for k, v := range header {
+		opts.WithHeader(jose.HeaderKey(k), v)
+	}
+
+	sign := signatureFunc(jose.SignatureAlgorithm(key.Algorithm), key.Key, saltLength)
+	if sign == nil {
+		sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(key.Algorithm), Key: key}, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &compactSigner{fallback: sig}, nil
+	}
+
+	// same protected header as go-jose's: alg, kid, then the extra headers
+	protected := map[string]interface{}{"alg": key.Algorithm}
+	if key.KeyID != "" {
+		protected["kid"] = key.KeyID
+	}
+
+	for k, v := range opts.ExtraHeaders {
+		protected[string(k)] = v
+	}
+
+	raw, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	return &compactSigner{header: append(appendBase64(nil, raw), '.'), sign: sign}, nil
+}
+
+// scratch buffers are reused between signatures, the hot path of high rate signing.
+type scratch struct {
+	claims bytes.Buffer
+	token  []byte
+}
+
+var scratches = sync.Pool{New: func() interface{} { return &scratch{} }}
+
+func (s *compactSigner) signClaims(payload map[string]interface{}) (string, error) {
+	buff, _ := scratches.Get().(*scratch)
+	defer scratches.Put(buff)
+
+	buff.claims.Reset()
+
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+
+	if err := json.NewEncoder(&buff.claims).Encode(payload); err != nil {
+		return "", err
+	}
+
+	claims := bytes.TrimSuffix(buff.claims.Bytes(), []byte("\n"))
+
+	if s.fallback != nil {
+		obj, err := s.fallback.Sign(claims)
+		if err != nil {
+			return "", err
+		}
+
+		return obj.CompactSerialize()
+	}
+
+	token := appendBase64(append(buff.token[:0], s.header...), claims)
+
+	sig, err := s.sign(token)
+	if err != nil {
+		return "", err
+	}
+
+	token = appendBase64(append(token, '.'), sig)
+	buff.token = token
+
+	return string(token), nil
+}
+
+func appendBase64(dst, src []byte) []byte {
+	n := base64.RawURLEncoding.EncodedLen(len(src))
+
+	if cap(dst)-len(dst) < n {
+		grown := make([]byte, len(dst), 2*cap(dst)+n)
+		copy(grown, dst)
+		dst = grown
+	}
+
+	base64.RawURLEncoding.Encode(dst[len(dst):len(dst)+n], src)
+
+	return dst[:len(dst)+n]
+}
+
+var hashes = map[jose.SignatureAlgorithm]crypto.Hash{
+	jose.HS256: crypto.SHA256, jose.HS384: crypto.SHA384, jose.HS512: crypto.SHA512,
+	jose.RS256: crypto.SHA256, jose.RS384: crypto.SHA384, jose.RS512: crypto.SHA512,
+	jose.PS256: crypto.SHA256, jose.PS384: crypto.SHA384, jose.PS512: crypto.SHA512,
+	jose.ES256: crypto.SHA256, jose.ES384: crypto.SHA384, jose.ES512: crypto.SHA512,
+}
+
+var curveBits = map[jose.SignatureAlgorithm]int{jose.ES256: 256, jose.ES384: 384, jose.ES512: 521}
+
+// signatureFunc returns the signature function of the algorithm and key, or nil if the key isn't supported directly.
+func signatureFunc(alg jose.SignatureAlgorithm, key interface{}, saltLength int) func([]byte) ([]byte, error) {
+	hash := hashes[alg]
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		if alg == jose.EdDSA {
+			return func(input []byte) ([]byte, error) { return ed25519.Sign(k, input), nil }
+		}
+	case []byte:
+		if alg == jose.HS256 || alg == jose.HS384 || alg == jose.HS512 {
+			return func(input []byte) ([]byte, error) {
+				// RFC 7518 section 3.2, the key must be at least of the hash size, like go-jose checks it
+				if len(k) < hash.Size() {
+					return nil, fmt.Errorf("%w: expected at least %d bytes, got %d", jose.ErrInvalidKeySize, hash.Size(), len(k))
+				}
+
+				mac := hmac.New(hash.New, k)
+				_, _ = mac.Write(input)
+
+				return mac.Sum(nil), nil
+			}
+		}
+	case *rsa.PrivateKey:
+		switch alg {
+		case jose.RS256, jose.RS384, jose.RS512:
+			return func(input []byte) ([]byte, error) {
+				return rsa.SignPKCS1v15(rand.Reader, k, hash, digest(hash, input))
+			}
+		case jose.PS256, jose.PS384, jose.PS512:
+			opts := &rsa.PSSOptions{SaltLength: saltLength}
+
+			return func(input []byte) ([]byte, error) {
+				return rsa.SignPSS(rand.Reader, k, hash, digest(hash, input), opts)
+			}
+		}
+	case *ecdsa.PrivateKey:
+		if _, brainpool := algorithm.BrainpoolCrv(k.Curve); brainpool {
+			return func(input []byte) ([]byte, error) { return algorithm.SignBrainpool(k, alg, input) }
+		}
+
+		if bits, ok := curveBits[alg]; ok {
+			return func(input []byte) ([]byte, error) { return ecdsaSign(k, bits, hash, input) }
+		}
+	}
+
+	return nil
+}
+
+func digest(hash crypto.Hash, input []byte) []byte {
+	hasher := hash.New()
+	_, _ = hasher.Write(input)
+
+	return hasher.Sum(nil)
+}
+
+// ecdsaSign returns the signature as fixed size r and s (RFC 7518 section 3.4), s is normalized to the lower half
+// of the curve order, so the tokens are accepted by verifiers rejecting malleable (high-S) signatures too.
+func ecdsaSign(key *ecdsa.PrivateKey, bits int, hash crypto.Hash, input []byte) ([]byte, error) {
+	if key.Curve.Params().BitSize != bits {
+		return nil, fmt.Errorf("%w: expected %d bit key, got %d bits", ErrUnsupportedKey, bits, key.Curve.Params().BitSize)
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest(hash, input))
+	if err != nil {
+		return nil, err
+	}
+
+	if order := key.Curve.Params().N; s.Cmp(new(big.Int).Rsh(order, 1)) > 0 {
+		s.Sub(order, s)
+	}
+
+	size := (bits + 7) / 8
+	sig := make([]byte, 2*size)
+
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+
+	return sig, nil
+}
@@ -0,0 +1,185 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// goJOSESign is the signing of go-jose's jwt.Builder, which the compact signer replaced.
+func goJOSESign(key *jose.JSONWebKey, payload map[string]interface{}) (string, error) {
+	sig, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.SignatureAlgorithm(key.Algorithm), Key: key},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.Signed(sig).Claims(payload).Serialize()
+}
+
+func benchmarkKeys(b *testing.B) map[string]*jose.JSONWebKey {
+	b.Helper()
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	rs, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return map[string]*jose.JSONWebKey{
+		"ES256": {Key: ec, KeyID: "ec", Algorithm: string(jose.ES256)},
+		"EdDSA": {Key: ed, KeyID: "ed", Algorithm: string(jose.EdDSA)},
+		"RS256": {Key: rs, KeyID: "rs", Algorithm: string(jose.RS256)},
+	}
+}
+
+func benchmarkClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   "https://issuer.example.com",
+		"sub":   "alice",
+		"aud":   "api",
+		"iat":   1700000000,
+		"nbf":   1700000000,
+		"exp":   1700003600,
+		"scope": "read write",
+	}
+}
+
+// BenchmarkSign compares go-jose's signing with the compact signer, per token (jwt.sign) and with reused signer
+// (createSigner).
+func BenchmarkSign(b *testing.B) {
+	keys := benchmarkKeys(b)
+	claims := benchmarkClaims()
+
+	for _, alg := range []string{"ES256", "EdDSA", "RS256"} {
+		key := keys[alg]
+
+		b.Run(alg+"/go-jose", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if _, err := goJOSESign(key, claims); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(alg+"/compact", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if _, err := Sign(key, claims, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	key := keys["EdDSA"]
+
+	b.Run("signer/EdDSA/go-jose", func(b *testing.B) {
+		sig, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.EdDSA, Key: key},
+			(&jose.SignerOptions{}).WithType("JWT"),
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportAllocs()
+
+		for b.Loop() {
+			if _, err := jwt.Signed(sig).Claims(claims).Serialize(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("signer/EdDSA/compact", func(b *testing.B) {
+		sig, err := newSigner(key, nil, rsa.PSSSaltLengthEqualsHash)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportAllocs()
+
+		for b.Loop() {
+			if _, err := sig.signClaims(claims); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestECDSALowS(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	half := new(big.Int).Rsh(key.Curve.Params().N, 1)
+	input := []byte("header.payload")
+	sum := sha256.Sum256(input)
+
+	// high-S signatures are produced randomly by half the signing operations
+	for range 64 {
+		sig, err := ecdsaSign(key, 256, crypto.SHA256, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+
+		if s.Cmp(half) > 0 {
+			t.Fatalf("high-S signature: %x", sig)
+		}
+
+		if !ecdsa.Verify(&key.PublicKey, sum[:], r, s) {
+			t.Fatal("normalized signature doesn't verify")
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type DiffOptions struct {
+	Ignore      []string `js:"ignore"`
+	IgnoreExtra bool     `js:"ignoreExtra"`
+}
+
+// Difference of expected and actual claims, the path is a JSON Pointer (RFC 6901) to the claim.
+type Difference struct {
+	Path     string      `js:"path"`
+	Kind     string      `js:"kind"`
+	Expected interface{} `js:"expected"`
+	Actual   interface{} `js:"actual"`
+}
+
+const (
+	DiffMissing    = "missing"
+	DiffUnexpected = "unexpected"
+	DiffChanged    = "changed"
+)
+
+type ClaimsDiff struct {
+	Equal       bool         `js:"equal"`
+	Differences []Difference `js:"differences"`
+	Text        string       `js:"text"`
+}
+
+func (m *Module) DiffClaims(expected, actual map[string]interface{}, options *DiffOptions) *ClaimsDiff {
+	return DiffClaims(expected, actual, options)
+}
+
+// DiffClaims deep-compares the claims, ignored claims are top level claim names or JSON Pointers (with their children).
+func DiffClaims(expected, actual map[string]interface{}, options *DiffOptions) *ClaimsDiff {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+
+	d := &differ{ignore: map[string]struct{}{}, ignoreExtra: options.IgnoreExtra}
+
+	for _, name := range options.Ignore {
+		if !strings.HasPrefix(name, "/") {
+			name = "/" + escapePointer(name)
+		}
+
+		d.ignore[name] = struct{}{}
+	}
+
+	d.diff("", normalize(expected), normalize(actual))
+
+	result := &ClaimsDiff{Equal: len(d.differences) == 0, Differences: d.differences}
+
+	if result.Differences == nil {
+		result.Differences = []Difference{}
+	}
+
+	lines := make([]string, 0, len(d.differences))
+
+	for _, diff := range d.differences {
+		switch diff.Kind {
+		case DiffMissing:
+			lines = append(lines, fmt.Sprintf("- %s: %s (missing)", diff.Path, jsonText(diff.Expected)))
+		case DiffUnexpected:
+			lines = append(lines, fmt.Sprintf("+ %s: %s (unexpected)", diff.Path, jsonText(diff.Actual)))
+		default:
+			lines = append(lines, fmt.Sprintf("~ %s: %s != %s", diff.Path, jsonText(diff.Expected), jsonText(diff.Actual)))
+		}
+	}
+
+	result.Text = strings.Join(lines, "\n")
+
+	return result
+}
+
+type differ struct {
+	ignore      map[string]struct{}
+	ignoreExtra bool
+	differences []Difference
+}
+
+func (d *differ) add(path, kind string, expected, actual interface{}) {
+	d.differences = append(d.differences, Difference{Path: path, Kind: kind, Expected: expected, Actual: actual})
+}
+
+func (d *differ) diff(path string, expected, actual interface{}) {
+	if _, ok := d.ignore[path]; ok && path != "" {
+		return
+	}
+
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		if act, ok := actual.(map[string]interface{}); ok {
+			d.members(path, exp, act)
+
+			return
+		}
+	case []interface{}:
+		if act, ok := actual.([]interface{}); ok {
+			d.items(path, exp, act)
+
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		d.add(path, DiffChanged, expected, actual)
+	}
+}
+
+func (d *differ) members(path string, expected, actual map[string]interface{}) {
+	names := make([]string, 0, len(expected)+len(actual))
+
+	for name := range expected {
+		names = append(names, name)
+	}
+
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		member := path + "/" + escapePointer(name)
+
+		if _, ok := d.ignore[member]; ok {
+			continue
+		}
+
+		exp, inExpected := expected[name]
+		act, inActual := actual[name]
+
+		switch {
+		case !inActual:
+			d.add(member, DiffMissing, exp, nil)
+		case !inExpected:
+			if !d.ignoreExtra {
+				d.add(member, DiffUnexpected, nil, act)
+			}
+		default:
+			d.diff(member, exp, act)
+		}
+	}
+}
+
+func (d *differ) items(path string, expected, actual []interface{}) {
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		item := path + "/" + strconv.Itoa(i)
+
+		switch {
+		case i >= len(actual):
+			d.add(item, DiffMissing, expected[i], nil)
+		case i >= len(expected):
+			d.add(item, DiffUnexpected, nil, actual[i])
+		default:
+			d.diff(item, expected[i], actual[i])
+		}
+	}
+}
+
+func jsonText(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(raw)
+}
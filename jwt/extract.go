@@ -0,0 +1,284 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSelector = errors.New("invalid selector")
+
+type stepKind int
+
+const (
+	stepName stepKind = iota
+	stepIndex
+	stepWildcard
+	stepDescend
+)
+
+type step struct {
+	kind  stepKind
+	name  string
+	index int
+}
+
+// selector is a parsed JSONPath-like claim selector.
+type selector struct {
+	steps      []step
+	indefinite bool
+}
+
+var selectors = newCompiledCache(compiledCacheSize) // parsed selectors by source, reused by every extraction
+
+// Extract decodes the token (without signature verification) and returns the claim values selected by the selector,
+// see Select.
+func (m *Module) Extract(in interface{}, source string) (interface{}, error) {
+	claims := in
+
+	if compact, ok := in.(string); ok {
+		decoded, err := m.Decode(compact)
+		if err != nil {
+			return nil, err
+		}
+
+		claims = decoded
+	}
+
+	return Select(claims, source)
+}
+
+// Select returns the value of a definite path (nil if it doesn't exist), or the array of matching values
+// if the selector has wildcard or recursive descent. The selector syntax is a subset of JSONPath (RFC 9535):
+// $ root, .name and ['name'] members, [n] array items (negative from the end), [*] and .* wildcards, ..name descendants.
+func Select(claims interface{}, source string) (interface{}, error) {
+	sel, err := parseSelector(source)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []interface{}{claims}
+
+	for _, s := range sel.steps {
+		nodes = s.apply(nodes)
+	}
+
+	if sel.indefinite {
+		if nodes == nil {
+			return []interface{}{}, nil
+		}
+
+		return nodes, nil
+	}
+
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	return nodes[0], nil
+}
+
+func (s step) apply(nodes []interface{}) []interface{} {
+	var out []interface{}
+
+	for _, node := range nodes {
+		switch s.kind {
+		case stepName:
+			if obj, ok := node.(map[string]interface{}); ok {
+				if value, ok := obj[s.name]; ok {
+					out = append(out, value)
+				}
+			}
+		case stepIndex:
+			if arr, ok := node.([]interface{}); ok {
+				idx := s.index
+				if idx < 0 {
+					idx += len(arr)
+				}
+
+				if idx >= 0 && idx < len(arr) {
+					out = append(out, arr[idx])
+				}
+			}
+		case stepWildcard:
+			out = append(out, children(node)...)
+		case stepDescend:
+			out = descendants(node, out)
+		}
+	}
+
+	return out
+}
+
+// children returns the member values (ordered by name) or items of the node.
+func children(node interface{}) []interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+
+		for name := range value {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		out := make([]interface{}, 0, len(names))
+
+		for _, name := range names {
+			out = append(out, value[name])
+		}
+
+		return out
+	case []interface{}:
+		return value
+	default:
+		return nil
+	}
+}
+
+// descendants appends the node and all of its descendants (depth first).
+func descendants(node interface{}, out []interface{}) []interface{} {
+	out = append(out, node)
+
+	for _, child := range children(node) {
+		out = descendants(child, out)
+	}
+
+	return out
+}
+
+func parseSelector(source string) (*selector, error) {
+	if sel, ok := selectors.get(source); ok {
+		return sel.(*selector), nil
+	}
+
+	sel, err := compileSelector(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %s", ErrInvalidSelector, source, err.Error())
+	}
+
+	selectors.put(source, sel)
+
+	return sel, nil
+}
+
+func compileSelector(source string) (*selector, error) {
+	if !strings.HasPrefix(source, "$") {
+		return nil, errors.New("must start with $")
+	}
+
+	sel := &selector{}
+
+	for rest := source[1:]; rest != ""; {
+		var (
+			s   step
+			err error
+		)
+
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			sel.steps = append(sel.steps, step{kind: stepDescend})
+			sel.indefinite = true
+
+			if strings.HasPrefix(rest[2:], "[") {
+				rest = rest[2:]
+
+				continue
+			}
+
+			s, rest, err = dotStep(rest[2:])
+		case rest[0] == '.':
+			s, rest, err = dotStep(rest[1:])
+		case rest[0] == '[':
+			s, rest, err = bracketStep(rest[1:])
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if s.kind == stepWildcard {
+			sel.indefinite = true
+		}
+
+		sel.steps = append(sel.steps, s)
+	}
+
+	return sel, nil
+}
+
+// dotStep parses the member name (or wildcard) after a dot, names end at the next dot or bracket.
+func dotStep(rest string) (step, string, error) {
+	end := strings.IndexAny(rest, ".[")
+	if end < 0 {
+		end = len(rest)
+	}
+
+	name := rest[:end]
+
+	switch name {
+	case "":
+		return step{}, "", errors.New("missing member name")
+	case "*":
+		return step{kind: stepWildcard}, rest[end:], nil
+	default:
+		return step{kind: stepName, name: name}, rest[end:], nil
+	}
+}
+
+// bracketStep parses quoted member name, index or wildcard until the closing bracket.
+func bracketStep(rest string) (step, string, error) {
+	if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+		quote := rest[0]
+
+		end := strings.IndexByte(rest[1:], quote)
+		if end < 0 || !strings.HasPrefix(rest[end+2:], "]") {
+			return step{}, "", errors.New("unterminated member name")
+		}
+
+		return step{kind: stepName, name: rest[1 : end+1]}, rest[end+3:], nil
+	}
+
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return step{}, "", errors.New("missing ]")
+	}
+
+	inner := strings.TrimSpace(rest[:end])
+	if inner == "*" {
+		return step{kind: stepWildcard}, rest[end+1:], nil
+	}
+
+	idx, err := strconv.Atoi(inner)
+	if err != nil {
+		return step{}, "", fmt.Errorf("invalid index %q", inner)
+	}
+
+	return step{kind: stepIndex, index: idx}, rest[end+1:], nil
+}
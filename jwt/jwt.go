@@ -23,47 +23,141 @@
 package jwt
 
 import (
-	"errors"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"log"
+	"strings"
+	"time"
 
-	"gopkg.in/square/go-jose.v2"
-	"gopkg.in/square/go-jose.v2/jwt"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/internal/trace"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
 )
 
-type Module struct{}
+type Module struct {
+	vu      modules.VU
+	metrics *metrics.Metrics
+	trace   *trace.Tracer
+	config  *config.Config
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return NewModule(vu, config.Default(vu)) })
+}
+
+// NewModule returns the module object of the VU using the given configuration.
+func NewModule(vu modules.VU, cfg *config.Config) *Module {
+	return &Module{vu: vu, metrics: metrics.New(vu), trace: trace.New(vu, cfg), config: cfg}
+}
 
-func New() *Module {
-	return &Module{}
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
 }
 
-var ErrUnsupportedKey = errors.New("unsupported key")
+var (
+	ErrUnsupportedKey      = jwk.ErrUnsupportedKey
+	ErrAlgorithmNotAllowed = config.ErrAlgorithmNotAllowed
+)
+
+const moduleName = "jwt"
+
+func (m *Module) Sign(
+	key *jose.JSONWebKey,
+	payload, header map[string]interface{},
+	options *SignOptions,
+) (token string, err error) {
+	start := time.Now()
 
-func (m *Module) Sign(key *jose.JSONWebKey, payload, header map[string]interface{}) (string, error) {
-	opts := &jose.SignerOptions{}
-	opts = opts.WithType("JWT")
+	defer func() {
+		m.metrics.Measure(moduleName, metrics.Sign, keyAlgorithm(key), start, err)
+		m.trace.Content(moduleName, metrics.Sign, header, payload, []interface{}{key}, err)
+	}()
 
-	for k, v := range header {
-		opts.WithHeader(jose.HeaderKey(k), v)
+	now, err := signTime(options, m.config)
+	if err != nil {
+		return "", err
 	}
 
-	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(key.Algorithm), Key: key}, opts)
+	claims, err := timing(payload, options, now)
 	if err != nil {
-		log.Println("error creating signer: %s", err.Error())
 		return "", err
 	}
 
-	str, err := jwt.Signed(sig).Claims(payload).CompactSerialize()
+	saltLength := rsa.PSSSaltLengthEqualsHash
+
+	if options != nil {
+		if header, err = crit.Mark(header, options.Critical...); err != nil {
+			return "", err
+		}
+
+		if saltLength, err = algorithm.SaltLength(options.SaltLength); err != nil {
+			return "", err
+		}
+	}
+
+	sig, err := newSigner(key, header, saltLength)
 	if err != nil {
-		log.Println("error sign: %s", err.Error())
 		return "", err
 	}
 
-	return str, nil
+	return sig.signClaims(claims)
+}
+
+func keyAlgorithm(key *jose.JSONWebKey) string {
+	if key == nil {
+		return ""
+	}
+
+	return key.Algorithm
+}
+
+// Sign creates compact JWT, header fields override the default "typ" too.
+func Sign(key *jose.JSONWebKey, payload, header map[string]interface{}) (string, error) {
+	sig, err := newSigner(key, header, rsa.PSSSaltLengthEqualsHash)
+	if err != nil {
+		return "", err
+	}
+
+	return sig.signClaims(payload)
 }
 
 func (m *Module) Decode(compact string) (interface{}, error) {
-	token, err := jwt.ParseSigned(compact)
+	if err := m.checkToken(compact); err != nil {
+		return nil, err
+	}
+
+	return Decode(compact)
+}
+
+// checkToken returns error if the token exceeds the configured parsing limits, the claims are checked too.
+func (m *Module) checkToken(compact string) error {
+	return checkToken(m.config, compact)
+}
+
+func checkToken(cfg *config.Config, compact string) error {
+	if err := cfg.CheckToken(compact); err != nil {
+		return err
+	}
+
+	if parts := strings.Split(compact, "."); len(parts) > 1 {
+		return cfg.CheckSegment(parts[1])
+	}
+
+	return nil
+}
+
+// Decode returns the claims of the JWT without signature verification.
+func Decode(compact string) (map[string]interface{}, error) {
+	token, err := jwt.ParseSigned(compact, algorithm.Signatures)
 	if err != nil {
 		return nil, err
 	}
@@ -78,31 +172,181 @@ func (m *Module) Decode(compact string) (interface{}, error) {
 }
 
 func (m *Module) Verify(compact string, keys ...interface{}) (interface{}, error) {
-	token, err := jwt.ParseSigned(compact)
+	verified, err := m.VerifyDetailed(compact, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return verified.Payload, nil
+}
+
+func (m *Module) VerifyDetailed(compact string, keys ...interface{}) (*Verified, error) {
+	start := time.Now()
+
+	verified, err := m.verify(compact, keys...)
 	if err != nil {
+		m.metrics.Measure(moduleName, metrics.Verify, m.headerAlgorithm(compact), start, err)
+		m.trace.Token(moduleName, metrics.Verify, compact, keys, err)
+
 		return nil, err
 	}
 
-	set := make([]jose.JSONWebKey, len(keys))
+	m.metrics.Measure(moduleName, metrics.Verify, verified.Algorithm, start, nil)
+
+	return verified, nil
+}
+
+// headerAlgorithm returns the "alg" header of the compact token, or empty string if it can't be parsed
+// (or exceeds the parsing limits).
+func (m *Module) headerAlgorithm(compact string) string {
+	if m.config.CheckToken(compact) != nil {
+		return ""
+	}
+
+	token, err := jwt.ParseSigned(compact, algorithm.Signatures)
+	if err != nil || len(token.Headers) == 0 {
+		return ""
+	}
+
+	return token.Headers[0].Algorithm
+}
+
+type Verified struct {
+	Payload   map[string]interface{} `js:"payload"`
+	Index     int                    `js:"index"`
+	KeyID     string                 `js:"kid"`
+	Algorithm string                 `js:"alg"`
+	Key       *jose.JSONWebKey       `js:"key"`
+}
+
+// verify is VerifyWith the configuration of the module.
+func (m *Module) verify(compact string, keys ...interface{}) (*Verified, error) {
+	return VerifyWith(m.config, compact, keys...)
+}
+
+// Verify tries the keys with matching kid first, then every key.
+// Tokens with critical header parameters are rejected and the default parsing limits apply, see VerifyWith.
+func Verify(compact string, keys ...interface{}) (*Verified, error) {
+	return VerifyWith(nil, compact, keys...)
+}
 
-	for _, k := range keys {
-		switch k.(type) {
-		case jose.JSONWebKey:
-			set = append(set, k.(jose.JSONWebKey))
-		case *jose.JSONWebKey:
-			set = append(set, *k.(*jose.JSONWebKey))
-		case *jose.JSONWebKeySet:
-			set = append(set, k.(*jose.JSONWebKeySet).Keys...)
-		default:
-			return nil, fmt.Errorf("%w: %T %v", ErrUnsupportedKey, k, k)
+// ParseWith parses the compact token with the parsing limits and allowed algorithms of the configuration,
+// the signature isn't verified, the header can be checked before VerifyWith.
+func ParseWith(cfg *config.Config, compact string) (*jwt.JSONWebToken, error) {
+	if err := checkToken(cfg, compact); err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseSigned(compact, algorithm.Signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Allow(token.Headers[0].Algorithm); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// VerifyWith is Verify restricted to the allowed algorithms of the configuration, with its parsing limits and
+// handled critical header parameters.
+func VerifyWith(cfg *config.Config, compact string, keys ...interface{}) (*Verified, error) {
+	token, err := ParseWith(cfg, compact)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(compact, ".")
+
+	protected, err := decodeHeader(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var handled []string
+	if cfg != nil {
+		handled = cfg.Critical
+	}
+
+	names, err := crit.Check(protected, nil, handled)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := jwk.KeySet(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	header := token.Headers[0]
+	candidates := jwk.Candidates(set, header.KeyID)
+
+	err = jose.ErrCryptoFailure
+
+	claims := func(key *jose.JSONWebKey, payload *map[string]interface{}) error { return token.Claims(key, payload) }
+
+	// go-jose verifies only b64 critical signatures, and no Brainpool signatures
+	if !crit.Native(names) || algorithm.IsBrainpool(header.Algorithm) {
+		claims = func(key *jose.JSONWebKey, payload *map[string]interface{}) error {
+			return verifyCritical(parts, header.Algorithm, key, payload)
 		}
 	}
 
-	payload := map[string]interface{}{}
+	for i := range candidates {
+		payload := map[string]interface{}{}
+
+		if err = claims(&candidates[i], &payload); err == nil {
+			return &Verified{
+				Payload:   payload,
+				KeyID:     candidates[i].KeyID,
+				Algorithm: header.Algorithm,
+				Key:       &candidates[i],
+			}, nil
+		}
+	}
+
+	if header.KeyID != "" && !jwk.HasKeyID(set, header.KeyID) {
+		return nil, fmt.Errorf("%w: %s", jwk.ErrUnknownKeyID, header.KeyID)
+	}
+
+	return nil, err
+}
+
+// verifyCritical verifies the signature of the compact token parts and decodes the claims.
+func verifyCritical(parts []string, alg string, key *jose.JSONWebKey, payload *map[string]interface{}) error {
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return jose.ErrCryptoFailure
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+
+	if err := crit.Verify(jose.SignatureAlgorithm(alg), key.Key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return err
+	}
 
-	if err := token.Claims(&jose.JSONWebKeySet{Keys: set}, &payload); err != nil {
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(claims, payload)
+}
+
+func decodeHeader(protected string) (map[string]interface{}, error) {
+	src, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
 		return nil, err
 	}
 
-	return payload, nil
+	header := map[string]interface{}{}
+
+	if err := json.Unmarshal(src, &header); err != nil {
+		return nil, err
+	}
+
+	return header, nil
 }
@@ -0,0 +1,170 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/jwe"
+	"github.com/szkiba/xk6-jose/jws"
+)
+
+var ErrInvalidNesting = errors.New("invalid nested token")
+
+const (
+	layerJWE = "JWE"
+	layerJWS = "JWS"
+
+	defaultMaxLayers = 4
+)
+
+type ConsumeOptions struct {
+	DecryptionKey interface{}         `js:"decryptionKey"`
+	Decrypt       *jwe.DecryptOptions `js:"decrypt"`
+	Verify        *VerifyOptions      `js:"verify"`
+	MaxLayers     int                 `js:"maxLayers"`
+}
+
+type Layer struct {
+	Type   string                 `js:"type"`
+	Header map[string]interface{} `js:"header"`
+}
+
+type Consumed struct {
+	Claims map[string]interface{} `js:"claims"`
+	Layers []Layer                `js:"layers"`
+}
+
+// Consume unwraps the nested token layer by layer: JWE layers are decrypted, JWS layers with JWT content type
+// are verified, the innermost JWT is verified (and validated with the verify options, if given).
+func (m *Module) Consume(token string, keys interface{}, options *ConsumeOptions) (consumed *Consumed, err error) {
+	var alg string
+
+	start := time.Now()
+
+	defer func() { m.metrics.Measure(moduleName, metrics.Verify, alg, start, err) }()
+
+	if options == nil {
+		options = &ConsumeOptions{}
+	}
+
+	maxLayers := options.MaxLayers
+	if maxLayers <= 0 {
+		maxLayers = defaultMaxLayers
+	}
+
+	layers := []Layer{}
+
+	for len(layers) < maxLayers {
+		if err := m.config.CheckToken(token); err != nil {
+			return nil, err
+		}
+
+		if encrypted(token) {
+			if options.DecryptionKey == nil {
+				return nil, fmt.Errorf("%w: decryption key is required for JWE layer", ErrInvalidNesting)
+			}
+
+			decrypted, err := jwe.DecryptWith(m.config, options.DecryptionKey, token, options.Decrypt)
+			if err != nil {
+				return nil, err
+			}
+
+			layers = append(layers, Layer{Type: layerJWE, Header: decrypted.Header})
+			token = string(decrypted.Plaintext)
+
+			continue
+		}
+
+		if header, ok := compactHeader(token); ok && !nestedJWT(header) {
+			alg, _ = header["alg"].(string)
+
+			verified, err := m.innermost(token, keys, options.Verify)
+			if err != nil {
+				return nil, err
+			}
+
+			return &Consumed{Claims: verified.Payload, Layers: append(layers, Layer{Type: layerJWS, Header: header})}, nil
+		}
+
+		payload, header, err := jws.VerifyWith(m.config, token, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		if !nestedJWT(header) {
+			return nil, fmt.Errorf("%w: innermost token is not a compact JWT", ErrInvalidNesting)
+		}
+
+		layers = append(layers, Layer{Type: layerJWS, Header: header})
+		token = string(payload)
+	}
+
+	return nil, fmt.Errorf("%w: more than %d layers", ErrInvalidNesting, maxLayers)
+}
+
+// innermost verifies the innermost JWT, the claims are validated only if verify options are given.
+func (m *Module) innermost(compact string, keys interface{}, options *VerifyOptions) (*Verified, error) {
+	if options == nil {
+		return m.verify(compact, keys)
+	}
+
+	return m.verifyClaims(compact, keys, options)
+}
+
+// compactHeader returns the decoded header of compact JWS.
+func compactHeader(token string) (map[string]interface{}, bool) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	header, err := decodeHeader(parts[0])
+	if err != nil {
+		return nil, false
+	}
+
+	return header, true
+}
+
+// encrypted returns true for JWE in compact (five parts) or JSON serialization.
+func encrypted(token string) bool {
+	token = strings.TrimSpace(token)
+
+	if strings.HasPrefix(token, "{") {
+		return strings.Contains(token, `"ciphertext"`)
+	}
+
+	return strings.Count(token, ".") == 4
+}
+
+// nestedJWT returns true if the content type header is JWT (RFC 7519 section 5.2).
+func nestedJWT(header map[string]interface{}) bool {
+	cty, _ := header["cty"].(string)
+
+	return strings.EqualFold(cty, "JWT") || strings.EqualFold(cty, "application/jwt")
+}
@@ -0,0 +1,114 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+)
+
+type ReissueOptions struct {
+	Fresh     bool   `js:"fresh"`
+	Algorithm string `js:"alg"`
+}
+
+// Reissue re-signs the token with the same header and claims, reusing the jti unless fresh one requested.
+// Every protected header parameter is kept, the algorithm too unless other one requested.
+func (m *Module) Reissue(compact string, key *jose.JSONWebKey, options *ReissueOptions) (string, error) {
+	if options == nil {
+		options = &ReissueOptions{}
+	}
+
+	if err := m.checkToken(compact); err != nil {
+		return "", err
+	}
+
+	obj, err := jose.ParseSigned(compact, algorithm.Signatures)
+	if err != nil {
+		return "", err
+	}
+
+	payload := obj.UnsafePayloadWithoutVerification()
+
+	if options.Fresh {
+		if payload, err = freshID(payload); err != nil {
+			return "", err
+		}
+	}
+
+	// the raw header, go-jose parses some members (like nonce and x5c) into fields of its own
+	protected, err := decodeHeader(strings.SplitN(compact, ".", 2)[0])
+	if err != nil {
+		return "", err
+	}
+
+	header := obj.Signatures[0].Protected
+	opts := &jose.SignerOptions{}
+
+	for k, v := range protected {
+		if k != "alg" && k != "kid" {
+			opts.WithHeader(jose.HeaderKey(k), v)
+		}
+	}
+
+	alg := options.Algorithm
+	if alg == "" {
+		alg = header.Algorithm
+	}
+
+	signing := *key
+	signing.KeyID = header.KeyID
+
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(alg), Key: algorithm.BrainpoolKey(&signing)}, opts)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := sig.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return signed.CompactSerialize()
+}
+
+// freshID replaces the jti claim with random ID, numbers are kept as they are.
+func freshID(payload []byte) ([]byte, error) {
+	claims := map[string]interface{}{}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	if err := dec.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	claims["jti"] = RandomID()
+
+	return json.Marshal(claims)
+}
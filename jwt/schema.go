@@ -0,0 +1,821 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// maxSchemaDepth limits the nesting of subschemas (and $ref chains), recursive schemas are rejected beyond it.
+const maxSchemaDepth = 64
+
+// Violation is a failed JSON Schema assertion, the path is a JSON Pointer (RFC 6901) to the claim.
+type Violation struct {
+	Path    string `js:"path"`
+	Keyword string `js:"keyword"`
+	Message string `js:"message"`
+}
+
+func (m *Module) ValidateClaims(claims interface{}, schema interface{}) ([]Violation, error) {
+	return ValidateClaims(claims, schema)
+}
+
+// ValidateClaims validates the claims against the JSON Schema and returns the violations, empty if the claims are valid.
+// The assertion and applicator keywords of JSON Schema 2020-12 are supported (annotations like unevaluatedProperties are
+// not), $ref may refer to the schema itself only (e.g. "#/$defs/name").
+func ValidateClaims(claims interface{}, schema interface{}) ([]Violation, error) {
+	v := &schemaValidator{root: normalize(schema)}
+
+	if err := v.validate(v.root, normalize(claims), "", 0); err != nil {
+		return nil, err
+	}
+
+	if v.violations == nil {
+		return []Violation{}, nil
+	}
+
+	return v.violations, nil
+}
+
+type schemaValidator struct {
+	root       interface{}
+	violations []Violation
+}
+
+func (v *schemaValidator) fail(path, keyword, format string, args ...interface{}) {
+	for i, arg := range args {
+		if num, ok := arg.(float64); ok {
+			args[i] = strconv.FormatFloat(num, 'f', -1, 64)
+		}
+	}
+
+	v.violations = append(v.violations, Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid returns true if the instance is valid against the subschema, without reporting its violations.
+func (v *schemaValidator) valid(schema, instance interface{}, path string, depth int) (bool, error) {
+	sub := &schemaValidator{root: v.root}
+
+	if err := sub.validate(schema, instance, path, depth); err != nil {
+		return false, err
+	}
+
+	return len(sub.violations) == 0, nil
+}
+
+func (v *schemaValidator) validate(schema, instance interface{}, path string, depth int) error {
+	if depth > maxSchemaDepth {
+		return fmt.Errorf("%w: nested deeper than %d levels", ErrInvalidSchema, maxSchemaDepth)
+	}
+
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "false", "not allowed")
+		}
+
+		return nil
+	case map[string]interface{}:
+		return v.object(s, instance, path, depth)
+	default:
+		return fmt.Errorf("%w: schema must be object or boolean, got %T", ErrInvalidSchema, schema)
+	}
+}
+
+func (v *schemaValidator) object(schema map[string]interface{}, instance interface{}, path string, depth int) error {
+	if ref, ok := schema["$ref"]; ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			return err
+		}
+
+		if err := v.validate(target, instance, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	checks := []func(map[string]interface{}, interface{}, string, int) error{
+		v.generic, v.combined, v.numeric, v.text, v.array, v.members,
+	}
+
+	for _, check := range checks {
+		if err := check(schema, instance, path, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve returns the subschema of a local reference.
+func (v *schemaValidator) resolve(ref interface{}) (interface{}, error) {
+	str, _ := ref.(string)
+	if str != "#" && !strings.HasPrefix(str, "#/") {
+		return nil, fmt.Errorf("%w: unsupported $ref %v", ErrInvalidSchema, ref)
+	}
+
+	target := v.root
+
+	for _, token := range strings.Split(str, "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+
+		switch node := target.(type) {
+		case map[string]interface{}:
+			target = node[token]
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("%w: unresolvable $ref %s", ErrInvalidSchema, str)
+			}
+
+			target = node[idx]
+		default:
+			target = nil
+		}
+
+		if target == nil {
+			return nil, fmt.Errorf("%w: unresolvable $ref %s", ErrInvalidSchema, str)
+		}
+	}
+
+	return target, nil
+}
+
+// generic validates type, enum and const.
+func (v *schemaValidator) generic(schema map[string]interface{}, instance interface{}, path string, _ int) error {
+	if typ, ok := schema["type"]; ok {
+		types, err := stringList(typ, "type")
+		if err != nil {
+			return err
+		}
+
+		matched := false
+
+		for _, t := range types {
+			if !knownType(t) {
+				return fmt.Errorf("%w: unknown type %q", ErrInvalidSchema, t)
+			}
+
+			matched = matched || hasType(instance, t)
+		}
+
+		if !matched {
+			v.fail(path, "type", "expected %s, got %s", strings.Join(types, " or "), typeOf(instance))
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		values, ok := enum.([]interface{})
+		if !ok {
+			return fmt.Errorf("%w: enum must be array", ErrInvalidSchema)
+		}
+
+		if !contains(values, instance) {
+			v.fail(path, "enum", "value is not one of the allowed values")
+		}
+	}
+
+	if value, ok := schema["const"]; ok && !equal(value, instance) {
+		v.fail(path, "const", "value is not the expected constant")
+	}
+
+	return nil
+}
+
+// combined validates allOf, anyOf, oneOf, not and if/then/else.
+func (v *schemaValidator) combined(schema map[string]interface{}, instance interface{}, path string, depth int) error {
+	if err := v.allOf(schema, instance, path, depth); err != nil {
+		return err
+	}
+
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		subs, err := schemaList(schema, keyword)
+		if err != nil {
+			return err
+		}
+
+		if subs == nil {
+			continue
+		}
+
+		count := 0
+
+		for _, sub := range subs {
+			ok, err := v.valid(sub, instance, path, depth+1)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				count++
+			}
+		}
+
+		if keyword == "anyOf" && count == 0 {
+			v.fail(path, keyword, "value is not valid against any of the subschemas")
+		}
+
+		if keyword == "oneOf" && count != 1 {
+			v.fail(path, keyword, "value is valid against %d of the subschemas, expected exactly one", count)
+		}
+	}
+
+	if not, ok := schema["not"]; ok {
+		valid, err := v.valid(not, instance, path, depth+1)
+		if err != nil {
+			return err
+		}
+
+		if valid {
+			v.fail(path, "not", "value must not be valid against the subschema")
+		}
+	}
+
+	cond, ok := schema["if"]
+	if !ok {
+		return nil
+	}
+
+	valid, err := v.valid(cond, instance, path, depth+1)
+	if err != nil {
+		return err
+	}
+
+	branch := "else"
+	if valid {
+		branch = "then"
+	}
+
+	if sub, ok := schema[branch]; ok {
+		return v.validate(sub, instance, path, depth+1)
+	}
+
+	return nil
+}
+
+func (v *schemaValidator) allOf(schema map[string]interface{}, instance interface{}, path string, depth int) error {
+	subs, err := schemaList(schema, "allOf")
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if err := v.validate(sub, instance, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// numeric validates minimum, maximum, exclusiveMinimum, exclusiveMaximum and multipleOf of numbers.
+func (v *schemaValidator) numeric(schema map[string]interface{}, instance interface{}, path string, _ int) error {
+	num, ok := instance.(float64)
+	if !ok {
+		return nil
+	}
+
+	bounds := []struct {
+		keyword string
+		failed  func(num, limit float64) bool
+		message string
+	}{
+		{"minimum", below, "must be >= %v"},
+		{"maximum", above, "must be <= %v"},
+		{"exclusiveMinimum", func(n, l float64) bool { return n <= l }, "must be > %v"},
+		{"exclusiveMaximum", func(n, l float64) bool { return n >= l }, "must be < %v"},
+	}
+
+	for _, bound := range bounds {
+		if err := v.limit(schema, bound.keyword, num, bound.failed, path, bound.message); err != nil {
+			return err
+		}
+	}
+
+	divisor, ok, err := number(schema, "multipleOf")
+	if err != nil || !ok {
+		return err
+	}
+
+	if divisor <= 0 {
+		return fmt.Errorf("%w: multipleOf must be positive", ErrInvalidSchema)
+	}
+
+	if q := num / divisor; math.Abs(q-math.Round(q)) > 1e-9 {
+		v.fail(path, "multipleOf", "must be multiple of %v", divisor)
+	}
+
+	return nil
+}
+
+var (
+	patterns   = newCompiledCache(compiledCacheSize) // compiled regular expressions by source, reused by every validation
+	uuidFormat = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+func pattern(source string) (*regexp.Regexp, error) {
+	if re, ok := patterns.get(source); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: pattern %q: %s", ErrInvalidSchema, source, err.Error())
+	}
+
+	patterns.put(source, re)
+
+	return re, nil
+}
+
+// text validates minLength, maxLength, pattern and format (date-time, date, email, uri and uuid) of strings.
+func (v *schemaValidator) text(schema map[string]interface{}, instance interface{}, path string, _ int) error {
+	str, ok := instance.(string)
+	if !ok {
+		return nil
+	}
+
+	length := float64(utf8.RuneCountInString(str))
+
+	if err := v.limit(schema, "minLength", length, below, path, "must be at least %v characters long"); err != nil {
+		return err
+	}
+
+	if err := v.limit(schema, "maxLength", length, above, path, "must be at most %v characters long"); err != nil {
+		return err
+	}
+
+	if source, ok := schema["pattern"].(string); ok {
+		re, err := pattern(source)
+		if err != nil {
+			return err
+		}
+
+		if !re.MatchString(str) {
+			v.fail(path, "pattern", "must match pattern %q", source)
+		}
+	}
+
+	if format, ok := schema["format"].(string); ok && !validFormat(format, str) {
+		v.fail(path, "format", "must be valid %s", format)
+	}
+
+	return nil
+}
+
+// validFormat returns true for valid values and unknown formats (format is an annotation by default).
+func validFormat(format, str string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, str)
+
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, str)
+
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(str)
+
+		return err == nil && addr.Address == str
+	case "uri":
+		u, err := url.Parse(str)
+
+		return err == nil && u.IsAbs()
+	case "uuid":
+		return uuidFormat.MatchString(str)
+	default:
+		return true
+	}
+}
+
+// array validates items, prefixItems, contains, minItems, maxItems and uniqueItems of arrays.
+func (v *schemaValidator) array(schema map[string]interface{}, instance interface{}, path string, depth int) error {
+	items, ok := instance.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	prefix, err := schemaList(schema, "prefixItems")
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(prefix) && i < len(items); i++ {
+		if err := v.validate(prefix[i], items[i], path+"/"+strconv.Itoa(i), depth+1); err != nil {
+			return err
+		}
+	}
+
+	if sub, ok := schema["items"]; ok {
+		for i := len(prefix); i < len(items); i++ {
+			if err := v.validate(sub, items[i], path+"/"+strconv.Itoa(i), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := v.contains(schema, items, path, depth); err != nil {
+		return err
+	}
+
+	count := float64(len(items))
+
+	if err := v.limit(schema, "minItems", count, below, path, "must have at least %v items"); err != nil {
+		return err
+	}
+
+	if err := v.limit(schema, "maxItems", count, above, path, "must have at most %v items"); err != nil {
+		return err
+	}
+
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range items {
+			if contains(items[:i], items[i]) {
+				v.fail(path, "uniqueItems", "items must be unique, item %d is a duplicate", i)
+
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v *schemaValidator) contains(schema map[string]interface{}, items []interface{}, path string, depth int) error {
+	sub, ok := schema["contains"]
+	if !ok {
+		return nil
+	}
+
+	count := 0
+
+	for i, item := range items {
+		valid, err := v.valid(sub, item, path+"/"+strconv.Itoa(i), depth+1)
+		if err != nil {
+			return err
+		}
+
+		if valid {
+			count++
+		}
+	}
+
+	minimum, hasMin, err := number(schema, "minContains")
+	if err != nil {
+		return err
+	}
+
+	if !hasMin {
+		minimum = 1
+	}
+
+	if float64(count) < minimum {
+		v.fail(path, "contains", "must contain at least %v matching items", minimum)
+	}
+
+	return v.limit(schema, "maxContains", float64(count), above, path, "must contain at most %v matching items")
+}
+
+// members validates required, properties, patternProperties, additionalProperties, propertyNames, minProperties,
+// maxProperties and dependentRequired of objects.
+func (v *schemaValidator) members(schema map[string]interface{}, instance interface{}, path string, depth int) error {
+	obj, ok := instance.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if err := v.required(schema, obj, path); err != nil {
+		return err
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+	names, hasNames := schema["propertyNames"]
+
+	for _, name := range sortedKeys(obj) {
+		member := path + "/" + escapePointer(name)
+		matched := false
+
+		if sub, ok := properties[name]; ok {
+			matched = true
+
+			if err := v.validate(sub, obj[name], member, depth+1); err != nil {
+				return err
+			}
+		}
+
+		for _, source := range sortedKeys(patternProperties) {
+			re, err := pattern(source)
+			if err != nil {
+				return err
+			}
+
+			if re.MatchString(name) {
+				matched = true
+
+				if err := v.validate(patternProperties[source], obj[name], member, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+
+		if hasAdditional && !matched {
+			if err := v.additional(additional, obj[name], member, depth); err != nil {
+				return err
+			}
+		}
+
+		if hasNames {
+			if err := v.validate(names, name, member, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	count := float64(len(obj))
+
+	if err := v.limit(schema, "minProperties", count, below, path, "must have at least %v claims"); err != nil {
+		return err
+	}
+
+	if err := v.limit(schema, "maxProperties", count, above, path, "must have at most %v claims"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *schemaValidator) additional(schema, instance interface{}, path string, depth int) error {
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			v.fail(path, "additionalProperties", "claim is not allowed")
+		}
+
+		return nil
+	}
+
+	return v.validate(schema, instance, path, depth+1)
+}
+
+func (v *schemaValidator) required(schema map[string]interface{}, obj map[string]interface{}, path string) error {
+	if req, ok := schema["required"]; ok {
+		names, err := stringList(req, "required")
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if _, ok := obj[name]; !ok {
+				v.fail(path+"/"+escapePointer(name), "required", "missing required claim %q", name)
+			}
+		}
+	}
+
+	dependent, _ := schema["dependentRequired"].(map[string]interface{})
+
+	for _, trigger := range sortedKeys(dependent) {
+		if _, ok := obj[trigger]; !ok {
+			continue
+		}
+
+		names, err := stringList(dependent[trigger], "dependentRequired")
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if _, ok := obj[name]; !ok {
+				v.fail(path+"/"+escapePointer(name), "dependentRequired", "claim %q is required by %q", name, trigger)
+			}
+		}
+	}
+
+	return nil
+}
+
+func below(value, limit float64) bool { return value < limit }
+
+func above(value, limit float64) bool { return value > limit }
+
+// limit reports the keyword if the value fails the limit of the schema, the message is formatted with the limit.
+func (v *schemaValidator) limit(
+	schema map[string]interface{},
+	keyword string,
+	value float64,
+	failed func(value, limit float64) bool,
+	path, message string,
+) error {
+	limit, ok, err := number(schema, keyword)
+	if err != nil {
+		return err
+	}
+
+	if ok && failed(value, limit) {
+		v.fail(path, keyword, message, limit)
+	}
+
+	return nil
+}
+
+func number(schema map[string]interface{}, keyword string) (float64, bool, error) {
+	value, ok := schema[keyword]
+	if !ok {
+		return 0, false, nil
+	}
+
+	num, ok := value.(float64)
+	if !ok {
+		return 0, false, fmt.Errorf("%w: %s must be number", ErrInvalidSchema, keyword)
+	}
+
+	return num, true, nil
+}
+
+func schemaList(schema map[string]interface{}, keyword string) ([]interface{}, error) {
+	value, ok := schema[keyword]
+	if !ok {
+		return nil, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%w: %s must be non-empty array", ErrInvalidSchema, keyword)
+	}
+
+	return list, nil
+}
+
+func stringList(value interface{}, keyword string) ([]string, error) {
+	if str, ok := value.(string); ok {
+		return []string{str}, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be string or array of strings", ErrInvalidSchema, keyword)
+	}
+
+	out := make([]string, 0, len(list))
+
+	for _, item := range list {
+		str, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be string or array of strings", ErrInvalidSchema, keyword)
+		}
+
+		out = append(out, str)
+	}
+
+	return out, nil
+}
+
+func knownType(name string) bool {
+	switch name {
+	case "null", "boolean", "object", "array", "number", "integer", "string":
+		return true
+	default:
+		return false
+	}
+}
+
+func hasType(instance interface{}, name string) bool {
+	actual := typeOf(instance)
+
+	return actual == name || (name == "number" && actual == "integer")
+}
+
+func typeOf(instance interface{}) string {
+	switch value := instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case float64:
+		if value == math.Trunc(value) && !math.IsInf(value, 0) {
+			return "integer"
+		}
+
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%T", instance)
+	}
+}
+
+func contains(values []interface{}, instance interface{}) bool {
+	for _, value := range values {
+		if equal(value, instance) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equal compares normalized JSON values, numbers by value.
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+
+	for key := range obj {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// normalize converts values exported from JS (or Go values) to the types of encoding/json: numbers to float64,
+// arrays to []interface{}.
+func normalize(in interface{}) interface{} {
+	switch value := in.(type) {
+	case nil, bool, string, float64:
+		return value
+	case int64:
+		return float64(value)
+	case int:
+		return float64(value)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+
+		for k, item := range value {
+			out[k] = normalize(item)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+
+		for i, item := range value {
+			out[i] = normalize(item)
+		}
+
+		return out
+	}
+
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return in
+	}
+
+	var out interface{}
+
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return in
+	}
+
+	return out
+}
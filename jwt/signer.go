@@ -0,0 +1,97 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/crit"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+)
+
+type SignerOptions struct {
+	Header     map[string]interface{} `js:"header"`
+	Critical   []string               `js:"critical"`
+	SaltLength interface{}            `js:"saltLength"`
+}
+
+// Signer creates JWTs with the same key and header, the underlying signer is created only once.
+type Signer struct {
+	signer    *compactSigner
+	algorithm string
+	metrics   *metrics.Metrics
+	config    *config.Config
+}
+
+func (m *Module) CreateSigner(key *jose.JSONWebKey, options *SignerOptions) (*sobek.Object, error) {
+	if options == nil {
+		options = &SignerOptions{}
+	}
+
+	header, err := crit.Mark(options.Header, options.Critical...)
+	if err != nil {
+		return nil, err
+	}
+
+	saltLength, err := algorithm.SaltLength(options.SaltLength)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := newSigner(key, header, saltLength)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := &Signer{signer: sig, algorithm: key.Algorithm, metrics: m.metrics, config: m.config}
+
+	return jsmodule.Exports(m.vu.Runtime(), signer), nil
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (s *Signer) Resolve() error {
+	return s.config.Resolve()
+}
+
+func (s *Signer) Sign(payload map[string]interface{}, options *SignOptions) (token string, err error) {
+	start := time.Now()
+
+	defer func() { s.metrics.Measure(moduleName, metrics.Sign, s.algorithm, start, err) }()
+
+	now, err := signTime(options, s.config)
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := timing(payload, options, now)
+	if err != nil {
+		return "", err
+	}
+
+	return s.signer.signClaims(claims)
+}
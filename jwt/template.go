@@ -0,0 +1,137 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownTemplate = errors.New("unknown template")
+	ErrMissingClaim    = errors.New("missing claim")
+)
+
+type template struct {
+	defaults func(now time.Time) map[string]interface{}
+	required []string
+}
+
+var (
+	templates = map[string]*template{
+		"id_token": {
+			defaults: func(now time.Time) map[string]interface{} {
+				return map[string]interface{}{
+					"iat":       now.Unix(),
+					"exp":       now.Add(time.Hour).Unix(),
+					"auth_time": now.Unix(),
+					"nonce":     RandomID(),
+				}
+			},
+			required: []string{"iss", "sub", "aud"},
+		},
+		"access_token": {
+			defaults: func(now time.Time) map[string]interface{} {
+				return map[string]interface{}{
+					"iat": now.Unix(),
+					"exp": now.Add(time.Hour).Unix(),
+					"jti": RandomID(),
+				}
+			},
+			required: []string{"iss", "sub", "aud", "client_id"},
+		},
+		"refresh_token": {
+			defaults: func(now time.Time) map[string]interface{} {
+				return map[string]interface{}{
+					"iat": now.Unix(),
+					"exp": now.Add(30 * 24 * time.Hour).Unix(),
+					"jti": RandomID(),
+				}
+			},
+			required: []string{"iss", "sub"},
+		},
+	}
+	templatesMu sync.RWMutex
+)
+
+func RandomID() string {
+	buff := make([]byte, 16)
+
+	if _, err := rand.Read(buff); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buff)
+}
+
+func (m *Module) Template(name string, overrides map[string]interface{}) (map[string]interface{}, error) {
+	templatesMu.RLock()
+	tmpl, ok := templates[name]
+	templatesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	now, err := m.config.Now()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := merge(tmpl.defaults(now), overrides)
+
+	for _, k := range tmpl.required {
+		if _, ok := claims[k]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingClaim, k)
+		}
+	}
+
+	return claims, nil
+}
+
+func (m *Module) RegisterTemplate(name string, claims map[string]interface{}) {
+	defaults := make(map[string]interface{}, len(claims))
+
+	for k, v := range claims {
+		defaults[k] = v
+	}
+
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+
+	templates[name] = &template{
+		defaults: func(time.Time) map[string]interface{} {
+			claims := make(map[string]interface{}, len(defaults))
+
+			for k, v := range defaults {
+				claims[k] = v
+			}
+
+			return claims
+		},
+	}
+}
@@ -0,0 +1,127 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/szkiba/xk6-jose/internal/config"
+)
+
+var (
+	ErrInvalidDuration = errors.New("invalid duration")
+	ErrInvalidTime     = config.ErrInvalidTime
+)
+
+const defaultLifetimeSeconds = 3600
+
+type SignOptions struct {
+	ExpiredBy  interface{} `js:"expiredBy"`
+	ValidIn    interface{} `js:"validIn"`
+	Lifetime   int         `js:"lifetime"`
+	Critical   []string    `js:"critical"`
+	Now        interface{} `js:"now"`
+	SaltLength interface{} `js:"saltLength"`
+}
+
+// signTime returns the time of the now option, or the time of the configured clock.
+func signTime(options *SignOptions, cfg *config.Config) (time.Time, error) {
+	if options == nil {
+		return cfg.Now()
+	}
+
+	return cfg.OptionTime(options.Now)
+}
+
+// timing returns copy of claims with iat, nbf and exp relative to now, as requested by the options.
+func timing(claims map[string]interface{}, options *SignOptions, now time.Time) (map[string]interface{}, error) {
+	if options == nil || (options.ExpiredBy == nil && options.ValidIn == nil) {
+		return claims, nil
+	}
+
+	lifetime := time.Duration(options.Lifetime) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultLifetimeSeconds * time.Second
+	}
+
+	var nbf time.Time
+
+	switch {
+	case options.ExpiredBy != nil && options.ValidIn != nil:
+		return nil, fmt.Errorf("%w: expiredBy and validIn are exclusive", ErrInvalidDuration)
+	case options.ExpiredBy != nil:
+		by, err := Duration(options.ExpiredBy)
+		if err != nil {
+			return nil, err
+		}
+
+		nbf = now.Add(-by - lifetime)
+	default:
+		in, err := Duration(options.ValidIn)
+		if err != nil {
+			return nil, err
+		}
+
+		nbf = now.Add(in)
+	}
+
+	iat := nbf
+	if iat.After(now) {
+		iat = now
+	}
+
+	return Merge(claims, map[string]interface{}{
+		"iat": iat.Unix(),
+		"nbf": nbf.Unix(),
+		"exp": nbf.Add(lifetime).Unix(),
+	}), nil
+}
+
+// Duration accepts Go duration string (e.g. "2h", "10m") or number of seconds.
+func Duration(in interface{}) (time.Duration, error) {
+	var d time.Duration
+
+	switch val := in.(type) {
+	case string:
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidDuration, val)
+		}
+
+		d = parsed
+	case int64:
+		d = time.Duration(val) * time.Second
+	case float64:
+		d = time.Duration(val * float64(time.Second))
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrInvalidDuration, in)
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("%w: %v is negative", ErrInvalidDuration, in)
+	}
+
+	return d, nil
+}
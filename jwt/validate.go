@@ -0,0 +1,127 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jwt
+
+import (
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/szkiba/xk6-jose/internal/algorithm"
+	"github.com/szkiba/xk6-jose/internal/metrics"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+type VerifyOptions struct {
+	Audience string      `js:"audience"`
+	Issuer   string      `js:"issuer"`
+	Subject  string      `js:"subject"`
+	Leeway   interface{} `js:"leeway"`
+	Now      interface{} `js:"now"`
+
+	Roots         interface{} `js:"roots"`
+	Intermediates interface{} `js:"intermediates"`
+}
+
+// VerifyClaims verifies the signature, then validates exp, nbf and iat, and the expected claims of the options.
+func (m *Module) VerifyClaims(
+	compact string,
+	keys interface{},
+	options *VerifyOptions,
+) (map[string]interface{}, error) {
+	start := time.Now()
+
+	verified, err := m.verifyClaims(compact, keys, options)
+	if err != nil {
+		m.metrics.Measure(moduleName, metrics.Verify, m.headerAlgorithm(compact), start, err)
+		m.trace.Token(moduleName, metrics.Verify, compact, []interface{}{keys}, err)
+
+		return nil, err
+	}
+
+	m.metrics.Measure(moduleName, metrics.Verify, verified.Algorithm, start, nil)
+
+	return verified.Payload, nil
+}
+
+func (m *Module) verifyClaims(
+	compact string,
+	keys interface{},
+	options *VerifyOptions,
+) (*Verified, error) {
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+
+	now, err := m.config.OptionTime(options.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	leeway := jwt.DefaultLeeway
+
+	if m.config.Leeway != nil {
+		leeway = *m.config.Leeway
+	}
+
+	if options.Leeway != nil {
+		if leeway, err = Duration(options.Leeway); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Roots != nil {
+		chainOptions := &jwk.ChainOptions{Intermediates: options.Intermediates, Now: now}
+
+		if keys, err = jwk.ChainKey(m.config, compact, options.Roots, chainOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	verified, err := m.verify(compact, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseSigned(compact, algorithm.Signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.Claims{}
+
+	if err := token.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, err
+	}
+
+	expected := jwt.Expected{Issuer: options.Issuer, Subject: options.Subject, Time: now}
+
+	if options.Audience != "" {
+		expected.AnyAudience = jwt.Audience{options.Audience}
+	}
+
+	if err := claims.ValidateWithLeeway(expected, leeway); err != nil {
+		return nil, err
+	}
+
+	return verified, nil
+}
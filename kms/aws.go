@@ -0,0 +1,189 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+type AWSOptions struct {
+	Region          string `js:"region"`
+	AccessKeyID     string `js:"accessKeyId"`
+	SecretAccessKey string `js:"secretAccessKey"`
+	SessionToken    string `js:"sessionToken"`
+	Endpoint        string `js:"endpoint"`
+	Algorithm       string `js:"algorithm"`
+	KeyID           string `js:"kid"`
+}
+
+// awsAlgorithms maps the JWS algorithms to AWS KMS signing algorithms, in order of preference.
+var awsAlgorithms = []struct {
+	alg  jose.SignatureAlgorithm
+	name string
+}{
+	{jose.ES256, "ECDSA_SHA_256"},
+	{jose.ES384, "ECDSA_SHA_384"},
+	{jose.ES512, "ECDSA_SHA_512"},
+	{jose.RS256, "RSASSA_PKCS1_V1_5_SHA_256"},
+	{jose.RS384, "RSASSA_PKCS1_V1_5_SHA_384"},
+	{jose.RS512, "RSASSA_PKCS1_V1_5_SHA_512"},
+	{jose.PS256, "RSASSA_PSS_SHA_256"},
+	{jose.PS384, "RSASSA_PSS_SHA_384"},
+	{jose.PS512, "RSASSA_PSS_SHA_512"},
+}
+
+type awsKMS struct {
+	m        *Module
+	endpoint string
+	region   string
+	creds    awsCredentials
+}
+
+// Aws returns the signing key of the AWS KMS key (key id, ARN or alias), the public key is fetched on creation.
+// Region and credentials default to the standard AWS environment variables.
+func (m *Module) Aws(keyID string, options *AWSOptions) (*jose.JSONWebKey, error) {
+	if options == nil {
+		options = &AWSOptions{}
+	}
+
+	if keyID == "" {
+		return nil, ErrMissingKey
+	}
+
+	region := options.Region
+	if parts := strings.Split(keyID, ":"); region == "" && len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+
+	svc := &awsKMS{
+		m:      m,
+		region: m.lookup(region, "AWS_REGION", "AWS_DEFAULT_REGION"),
+		creds: awsCredentials{
+			accessKeyID:     m.lookup(options.AccessKeyID, "AWS_ACCESS_KEY_ID"),
+			secretAccessKey: m.lookup(options.SecretAccessKey, "AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    m.lookup(options.SessionToken, "AWS_SESSION_TOKEN"),
+		},
+	}
+
+	if svc.region == "" {
+		return nil, fmt.Errorf("%w: AWS region", ErrMissingCredentials)
+	}
+
+	if svc.creds.accessKeyID == "" || svc.creds.secretAccessKey == "" {
+		return nil, fmt.Errorf("%w: AWS access key", ErrMissingCredentials)
+	}
+
+	svc.endpoint = options.Endpoint
+	if svc.endpoint == "" {
+		svc.endpoint = "https://kms." + svc.region + ".amazonaws.com"
+	}
+
+	var public struct {
+		PublicKey         []byte
+		KeyUsage          string
+		SigningAlgorithms []string
+	}
+
+	if err := svc.call("GetPublicKey", map[string]interface{}{"KeyId": keyID}, &public); err != nil {
+		return nil, err
+	}
+
+	if public.KeyUsage != "SIGN_VERIFY" {
+		return nil, fmt.Errorf("%w: %s key usage", jwk.ErrUnsupportedKey, public.KeyUsage)
+	}
+
+	alg, name, err := awsAlgorithm(options.Algorithm, public.SigningAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	return remoteKey(public.PublicKey, alg, options.KeyID, func(digest []byte) ([]byte, error) {
+		var signed struct{ Signature []byte }
+
+		in := map[string]interface{}{
+			"KeyId":            keyID,
+			"Message":          digest,
+			"MessageType":      "DIGEST",
+			"SigningAlgorithm": name,
+		}
+
+		if err := svc.call("Sign", in, &signed); err != nil {
+			return nil, err
+		}
+
+		if isECDSA(alg) {
+			return rawECDSA(signed.Signature, alg)
+		}
+
+		return signed.Signature, nil
+	})
+}
+
+// awsAlgorithm returns the requested (or the preferred) JWS algorithm supported by the key, with its KMS name.
+func awsAlgorithm(requested string, supported []string) (jose.SignatureAlgorithm, string, error) {
+	for _, a := range awsAlgorithms {
+		if requested != "" && !strings.EqualFold(requested, string(a.alg)) {
+			continue
+		}
+
+		for _, name := range supported {
+			if name == a.name {
+				return a.alg, a.name, nil
+			}
+		}
+	}
+
+	if requested == "" {
+		requested = "no JWS algorithm"
+	}
+
+	return "", "", fmt.Errorf("%w: %s, the key supports %s", jwk.ErrUnsupportedAlgorithm, requested, strings.Join(supported, ", "))
+}
+
+// call invokes the KMS JSON API action with SigV4 signed request.
+func (svc *awsKMS) call(action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, svc.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+
+	signV4(req, body, svc.creds, svc.region, "kms", time.Now())
+
+	return svc.m.do(req, out)
+}
@@ -0,0 +1,255 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kms
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+type AzureOptions struct {
+	TenantID        string `js:"tenantId"`
+	ClientID        string `js:"clientId"`
+	ClientSecret    string `js:"clientSecret"`
+	ManagedIdentity bool   `js:"managedIdentity"`
+	AccessToken     string `js:"accessToken"`
+	AuthorityHost   string `js:"authorityHost"`
+	Algorithm       string `js:"algorithm"`
+	KeyID           string `js:"kid"`
+}
+
+const (
+	azureAPIVersion    = "7.4"
+	azureAuthorityHost = "https://login.microsoftonline.com"
+	azureResource      = "https://vault.azure.net"
+	azureIMDS          = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+type azureKeyVault struct {
+	m      *Module
+	kid    string
+	tokens *tokenSource
+}
+
+// Azure returns the signing key of the Key Vault key (key identifier URL, optionally with version),
+// the public key is fetched on creation. Credentials default to the AZURE_* environment variables.
+func (m *Module) Azure(keyURL string, options *AzureOptions) (*jose.JSONWebKey, error) {
+	if options == nil {
+		options = &AzureOptions{}
+	}
+
+	if !strings.Contains(keyURL, "/keys/") {
+		return nil, fmt.Errorf("%w: key identifier URL", ErrMissingKey)
+	}
+
+	tokens, err := m.azureTokens(options)
+	if err != nil {
+		return nil, err
+	}
+
+	vault := &azureKeyVault{m: m, kid: strings.TrimSuffix(keyURL, "/"), tokens: tokens}
+
+	var bundle struct {
+		Key json.RawMessage `json:"key"`
+	}
+
+	if err := vault.call(http.MethodGet, "", nil, &bundle); err != nil {
+		return nil, err
+	}
+
+	public, kid, err := azurePublicKey(bundle.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	// pin the key version returned by Key Vault
+	vault.kid = kid
+
+	alg, err := keyAlgorithm(public, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("%w: public key: %s", ErrRemoteSigner, err.Error())
+	}
+
+	return remoteKey(der, alg, options.KeyID, func(digest []byte) ([]byte, error) {
+		var signed struct {
+			Value string `json:"value"`
+		}
+
+		in := map[string]string{"alg": string(alg), "value": base64.RawURLEncoding.EncodeToString(digest)}
+
+		if err := vault.call(http.MethodPost, "/sign", in, &signed); err != nil {
+			return nil, err
+		}
+
+		// Key Vault returns ECDSA signatures in JWS format
+		sig, err := base64.RawURLEncoding.DecodeString(signed.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: signature: %s", ErrRemoteSigner, err.Error())
+		}
+
+		return sig, nil
+	})
+}
+
+// azureTokens returns the token source of the static token, client secret or managed identity, in this order.
+func (m *Module) azureTokens(options *AzureOptions) (*tokenSource, error) {
+	if options.AccessToken != "" {
+		return &tokenSource{token: options.AccessToken}, nil
+	}
+
+	clientID := m.lookup(options.ClientID, "AZURE_CLIENT_ID")
+
+	if secret := m.lookup(options.ClientSecret, "AZURE_CLIENT_SECRET"); secret != "" && !options.ManagedIdentity {
+		tenant := m.lookup(options.TenantID, "AZURE_TENANT_ID")
+		if tenant == "" || clientID == "" {
+			return nil, fmt.Errorf("%w: tenant and client ID of the client secret", ErrMissingCredentials)
+		}
+
+		host := strings.TrimSuffix(m.lookup(options.AuthorityHost, "AZURE_AUTHORITY_HOST"), "/")
+		if host == "" {
+			host = azureAuthorityHost
+		}
+
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {clientID},
+			"client_secret": {secret},
+			"scope":         {azureResource + "/.default"},
+		}
+
+		return &tokenSource{fetch: m.oauthToken(func() (*http.Request, error) {
+			req, err := http.NewRequest(http.MethodPost, host+"/"+tenant+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+			}
+
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			return req, nil
+		})}, nil
+	}
+
+	endpoint, header := m.env["IDENTITY_ENDPOINT"], m.env["IDENTITY_HEADER"]
+
+	if !options.ManagedIdentity && endpoint == "" {
+		return nil, fmt.Errorf("%w: client secret, managed identity or access token", ErrMissingCredentials)
+	}
+
+	query := url.Values{"resource": {azureResource}}
+
+	if endpoint == "" {
+		// Azure Instance Metadata Service of virtual machines and AKS
+		endpoint = azureIMDS
+		query.Set("api-version", "2018-02-01")
+	} else {
+		// App Service, Functions and Container Apps
+		query.Set("api-version", "2019-08-01")
+	}
+
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+
+	return &tokenSource{fetch: m.oauthToken(func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+		}
+
+		if header != "" {
+			req.Header.Set("X-IDENTITY-HEADER", header)
+		} else {
+			req.Header.Set("Metadata", "true")
+		}
+
+		return req, nil
+	})}, nil
+}
+
+// azurePublicKey parses the JWK of the key bundle, returns the public key and the versioned key identifier.
+func azurePublicKey(data []byte) (interface{}, string, error) {
+	var raw map[string]interface{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, "", fmt.Errorf("%w: public key: %s", ErrRemoteSigner, err.Error())
+	}
+
+	kid, _ := raw["kid"].(string)
+
+	// HSM protected keys have EC-HSM and RSA-HSM key types
+	if kty, ok := raw["kty"].(string); ok {
+		raw["kty"] = strings.TrimSuffix(kty, "-HSM")
+	}
+
+	// key operations are not relevant for the public key
+	delete(raw, "key_ops")
+
+	data, _ = json.Marshal(raw)
+
+	var key jose.JSONWebKey
+
+	if err := key.UnmarshalJSON(data); err != nil {
+		return nil, "", fmt.Errorf("%w: public key: %s", ErrRemoteSigner, err.Error())
+	}
+
+	return key.Key, kid, nil
+}
+
+// call invokes the Key Vault REST operation of the key with bearer token.
+func (vault *azureKeyVault) call(method, suffix string, in, out interface{}) error {
+	token, err := vault.tokens.accessToken()
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+
+	if in != nil {
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, vault.kid+suffix+"?api-version="+azureAPIVersion, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	return vault.m.do(req, out)
+}
@@ -0,0 +1,193 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+type ExternalOptions struct {
+	Public    interface{}       `js:"public"`
+	Algorithm string            `js:"algorithm"`
+	Sign      sobek.Value       `js:"sign"`
+	URL       string            `js:"url"`
+	Headers   map[string]string `js:"headers"`
+	KeyID     string            `js:"kid"`
+}
+
+// External returns the signing key of the public key, the signature of the signing input is created by
+// the sign callback or the HTTP endpoint. The callback belongs to the VU, the key must be created in its init context.
+func (m *Module) External(options *ExternalOptions) (*jose.JSONWebKey, error) {
+	if options == nil || options.Public == nil {
+		return nil, fmt.Errorf("%w: public key of the external signer", ErrMissingKey)
+	}
+
+	keys, err := jwk.KeySet(options.Public)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("%w: exactly one public key is required", jwk.ErrUnsupportedKey)
+	}
+
+	public := keys[0].Public()
+	if !public.Valid() {
+		return nil, fmt.Errorf("%w: symmetric keys can not be signed externally", jwk.ErrUnsupportedKey)
+	}
+
+	requested := options.Algorithm
+	if requested == "" {
+		requested = public.Algorithm
+	}
+
+	alg, err := keyAlgorithm(public.Key, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := options.KeyID
+	if kid == "" {
+		kid = public.KeyID
+	}
+
+	var sign func([]byte) ([]byte, error)
+
+	switch {
+	case options.Sign != nil && !sobek.IsUndefined(options.Sign) && !sobek.IsNull(options.Sign):
+		fn, ok := sobek.AssertFunction(options.Sign)
+		if !ok {
+			return nil, fmt.Errorf("%w: sign must be a function", ErrInvalidSigner)
+		}
+
+		sign = m.callbackSigner(fn, alg)
+	case options.URL != "":
+		sign = m.endpointSigner(options.URL, options.Headers, alg, kid)
+	default:
+		return nil, fmt.Errorf("%w: sign callback or url is required", ErrInvalidSigner)
+	}
+
+	return signerKey(&remoteSigner{alg: alg, raw: true, loop: true, sign: func(input []byte) ([]byte, error) {
+		sig, err := sign(input)
+		if err != nil {
+			return nil, err
+		}
+
+		// DER encoded ECDSA signatures are accepted too
+		if isECDSA(alg) && len(sig) != 2*ecdsaSizes[alg] && sig[0] == 0x30 {
+			return rawECDSA(sig, alg)
+		}
+
+		return sig, nil
+	}}, public.Key, kid)
+}
+
+// callbackSigner calls the JS function with the signing input (ArrayBuffer) and the algorithm.
+func (m *Module) callbackSigner(fn sobek.Callable, alg jose.SignatureAlgorithm) func([]byte) ([]byte, error) {
+	return func(input []byte) ([]byte, error) {
+		rt := m.vu.Runtime()
+
+		ret, err := fn(sobek.Undefined(), rt.ToValue(rt.NewArrayBuffer(input)), rt.ToValue(string(alg)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: sign callback: %s", ErrRemoteSigner, err.Error())
+		}
+
+		out := ret.Export()
+
+		if _, ok := out.(*sobek.Promise); ok {
+			return nil, fmt.Errorf("%w: sign callback must return the signature synchronously", ErrRemoteSigner)
+		}
+
+		return signatureBytes(out)
+	}
+}
+
+// endpointSigner posts {alg, kid, input} to the URL, the response must contain the signature.
+// Binary values are base64url encoded.
+func (m *Module) endpointSigner(url string, headers map[string]string, alg jose.SignatureAlgorithm, kid string) func([]byte) ([]byte, error) {
+	return func(input []byte) ([]byte, error) {
+		body, err := json.Marshal(map[string]string{
+			"alg":   string(alg),
+			"kid":   kid,
+			"input": base64.RawURLEncoding.EncodeToString(input),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		var signed struct {
+			Signature string `json:"signature"`
+		}
+
+		if err := m.do(req, &signed); err != nil {
+			return nil, err
+		}
+
+		return signatureBytes(signed.Signature)
+	}
+}
+
+// signatureBytes returns the binary signature, strings are base64url encoded.
+func signatureBytes(in interface{}) ([]byte, error) {
+	var (
+		sig []byte
+		err error
+	)
+
+	if str, ok := in.(string); ok {
+		sig, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(str, "="))
+	} else {
+		sig, err = buffer.Bytes(in)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %s", ErrRemoteSigner, err.Error())
+	}
+
+	if len(sig) == 0 {
+		return nil, fmt.Errorf("%w: empty signature", ErrRemoteSigner)
+	}
+
+	return sig, nil
+}
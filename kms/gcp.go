@@ -0,0 +1,209 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kms
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/oauth"
+)
+
+type GCPOptions struct {
+	Credentials interface{} `js:"credentials"`
+	AccessToken string      `js:"accessToken"`
+	Endpoint    string      `js:"endpoint"`
+	KeyID       string      `js:"kid"`
+}
+
+const (
+	gcpEndpoint = "https://cloudkms.googleapis.com"
+	gcpScope    = "https://www.googleapis.com/auth/cloudkms"
+)
+
+type gcpKMS struct {
+	m        *Module
+	endpoint string
+	name     string
+	tokens   *tokenSource
+}
+
+// Gcp returns the signing key of the Cloud KMS asymmetric key version, the public key is fetched on creation.
+// The JWS algorithm follows the algorithm of the key version.
+func (m *Module) Gcp(name string, options *GCPOptions) (*jose.JSONWebKey, error) {
+	if options == nil {
+		options = &GCPOptions{}
+	}
+
+	if !strings.Contains(name, "/cryptoKeyVersions/") {
+		return nil, fmt.Errorf("%w: key version resource name", ErrMissingKey)
+	}
+
+	svc := &gcpKMS{m: m, name: name, endpoint: options.Endpoint, tokens: &tokenSource{token: options.AccessToken}}
+
+	if svc.endpoint == "" {
+		svc.endpoint = gcpEndpoint
+	}
+
+	if options.AccessToken == "" {
+		credentials := options.Credentials
+
+		if credentials == nil {
+			path := m.env["GOOGLE_APPLICATION_CREDENTIALS"]
+			if path == "" {
+				return nil, fmt.Errorf("%w: service account credentials or access token", ErrMissingCredentials)
+			}
+
+			source, err := m.readFile(path)
+			if err != nil {
+				return nil, err
+			}
+
+			credentials = source
+		}
+
+		svc.tokens.fetch = m.oauthToken(func() (*http.Request, error) { return gcpTokenRequest(credentials) })
+	}
+
+	var public struct {
+		Pem       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+
+	if err := svc.call(http.MethodGet, "/publicKey", nil, &public); err != nil {
+		return nil, err
+	}
+
+	alg, err := gcpAlgorithm(public.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(public.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("%w: public key: %s", ErrRemoteSigner, jwk.ErrInvalidPEM)
+	}
+
+	digestName := "sha" + string(alg)[2:]
+
+	return remoteKey(block.Bytes, alg, options.KeyID, func(digest []byte) ([]byte, error) {
+		var signed struct {
+			Signature []byte `json:"signature"`
+		}
+
+		in := map[string]interface{}{"digest": map[string]interface{}{digestName: digest}}
+
+		if err := svc.call(http.MethodPost, ":asymmetricSign", in, &signed); err != nil {
+			return nil, err
+		}
+
+		if isECDSA(alg) {
+			return rawECDSA(signed.Signature, alg)
+		}
+
+		return signed.Signature, nil
+	})
+}
+
+// gcpAlgorithm maps the Cloud KMS signing algorithm (like EC_SIGN_P256_SHA256 or RSA_SIGN_PSS_2048_SHA256) to JWS algorithm.
+func gcpAlgorithm(name string) (jose.SignatureAlgorithm, error) {
+	var prefix string
+
+	switch {
+	case name == "EC_SIGN_P256_SHA256":
+		return jose.ES256, nil
+	case name == "EC_SIGN_P384_SHA384":
+		return jose.ES384, nil
+	case strings.HasPrefix(name, "RSA_SIGN_PKCS1_"):
+		prefix = "RS"
+	case strings.HasPrefix(name, "RSA_SIGN_PSS_"):
+		prefix = "PS"
+	}
+
+	if i := strings.LastIndex(name, "_SHA"); prefix != "" && i > 0 {
+		if bits, err := strconv.Atoi(name[i+4:]); err == nil {
+			alg := jose.SignatureAlgorithm(prefix + strconv.Itoa(bits))
+			if _, ok := hashes[alg]; ok {
+				return alg, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s key version", jwk.ErrUnsupportedAlgorithm, name)
+}
+
+// call invokes the Cloud KMS REST method of the key version with bearer token.
+func (svc *gcpKMS) call(method, suffix string, in, out interface{}) error {
+	token, err := svc.tokens.accessToken()
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+
+	if in != nil {
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, svc.endpoint+"/v1/"+svc.name+suffix, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	return svc.m.do(req, out)
+}
+
+// gcpTokenRequest creates the token request for the JWT bearer assertion of the service account.
+func gcpTokenRequest(credentials interface{}) (*http.Request, error) {
+	assertion, err := new(oauth.Module).ServiceAccountAssertion(credentials, []string{gcpScope}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	for k, v := range assertion.Params {
+		form.Set(k, v)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, assertion.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, nil
+}
@@ -0,0 +1,120 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package kms signs with keys held by key management services, the private key never leaves the service.
+// The keys are usable as signing keys of the other modules, and as verification keys by their public part.
+package kms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/szkiba/xk6-jose/internal/httpclient"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwk"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib/fsext"
+)
+
+type Module struct {
+	vu  modules.VU
+	env map[string]string
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} {
+		return &Module{vu: vu, env: vu.InitEnv().RuntimeOptions.Env}
+	})
+}
+
+var (
+	ErrRemoteSigner       = errors.New("remote signer failed")
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrMissingKey         = errors.New("missing key identifier")
+	ErrInvalidSigner      = errors.New("invalid external signer")
+)
+
+const (
+	requestTimeout  = 30 * time.Second
+	maxResponseSize = 1 << 20
+)
+
+// lookup returns the option value if given, the first non-empty environment variable otherwise.
+func (m *Module) lookup(value string, names ...string) string {
+	if value != "" {
+		return value
+	}
+
+	for _, name := range names {
+		if v := m.env[name]; v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// readFile reads the credentials file through k6, only in the init context.
+func (m *Module) readFile(path string) (string, error) {
+	env := m.vu.InitEnv()
+	if env == nil {
+		return "", fmt.Errorf("%w: %s", jwk.ErrInitContext, path)
+	}
+
+	data, err := fsext.ReadFile(env.FileSystems["file"], env.GetAbsFilePath(path))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMissingCredentials, err.Error())
+	}
+
+	return string(data), nil
+}
+
+// do sends the request through the transport of k6 in VU code, and decodes the JSON response into out.
+func (m *Module) do(req *http.Request, out interface{}) error {
+	client, ctx := httpclient.New(m.vu, requestTimeout)
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+	}
+
+	defer res.Body.Close()
+
+	body, err := httpclient.ReadBody(res.Body, maxResponseSize)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrRemoteSigner, req.URL.Host, err.Error())
+	}
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("%w: %s returned %s: %s", ErrRemoteSigner, req.URL.Host, res.Status, bytes.TrimSpace(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrRemoteSigner, req.URL.Host, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build cgo
+
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/miekg/pkcs11"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+// pkcs11Modules holds the loaded and initialized modules by path, a module can be initialized once per process.
+var pkcs11Modules = struct {
+	sync.Mutex
+	ctx map[string]*pkcs11.Ctx
+}{ctx: map[string]*pkcs11.Ctx{}}
+
+// digestInfoPrefixes are the DER DigestInfo prefixes of CKM_RSA_PKCS signatures.
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pssMechanisms are the hash and MGF mechanisms of CKM_RSA_PKCS_PSS signatures.
+var pssMechanisms = map[crypto.Hash][2]uint{
+	crypto.SHA256: {pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256},
+	crypto.SHA384: {pkcs11.CKM_SHA384, pkcs11.CKG_MGF1_SHA384},
+	crypto.SHA512: {pkcs11.CKM_SHA512, pkcs11.CKG_MGF1_SHA512},
+}
+
+var ecCurves = map[string]elliptic.Curve{
+	"1.2.840.10045.3.1.7": elliptic.P256(),
+	"1.3.132.0.34":        elliptic.P384(),
+	"1.3.132.0.35":        elliptic.P521(),
+}
+
+// Pkcs11 returns the signing key of the private key with the given label on the PKCS#11 token.
+// The module path and PIN default to PKCS11_MODULE and PKCS11_PIN environment variables.
+func (m *Module) Pkcs11(label string, options *PKCS11Options) (*jose.JSONWebKey, error) {
+	if options == nil {
+		options = &PKCS11Options{}
+	}
+
+	if label == "" {
+		return nil, ErrMissingKey
+	}
+
+	path := m.lookup(options.Module, "PKCS11_MODULE")
+	if path == "" {
+		return nil, fmt.Errorf("%w: PKCS#11 module path", ErrMissingCredentials)
+	}
+
+	ctx, err := pkcs11Module(path)
+	if err != nil {
+		return nil, err
+	}
+
+	slot, err := pkcs11Slot(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, pkcs11Error(err)
+	}
+
+	// the login state is shared by the sessions of the token
+	if pin := m.lookup(options.PIN, "PKCS11_PIN"); pin != "" {
+		if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+			return nil, pkcs11Error(err)
+		}
+	}
+
+	private, err := pkcs11Object(ctx, session, pkcs11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+
+	publicObject, err := pkcs11Object(ctx, session, pkcs11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := pkcs11PublicKey(ctx, session, publicObject)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err := keyAlgorithm(public, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("%w: public key: %s", ErrRemoteSigner, err.Error())
+	}
+
+	hash := hashes[alg]
+
+	// operations of a session must not be interleaved
+	var mu sync.Mutex
+
+	return remoteKey(der, alg, options.KeyID, func(digest []byte) ([]byte, error) {
+		var mechanism *pkcs11.Mechanism
+
+		switch {
+		case isECDSA(alg):
+			// CKM_ECDSA signatures are already in JWS format
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)
+		case strings.HasPrefix(string(alg), "PS"):
+			params := pkcs11.NewPSSParams(pssMechanisms[hash][0], pssMechanisms[hash][1], uint(hash.Size()))
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, params)
+		default:
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+			digest = append(append([]byte{}, digestInfoPrefixes[hash]...), digest...)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := ctx.SignInit(session, []*pkcs11.Mechanism{mechanism}, private); err != nil {
+			return nil, pkcs11Error(err)
+		}
+
+		sig, err := ctx.Sign(session, digest)
+		if err != nil {
+			return nil, pkcs11Error(err)
+		}
+
+		return sig, nil
+	})
+}
+
+func pkcs11Module(path string) (*pkcs11.Ctx, error) {
+	pkcs11Modules.Lock()
+	defer pkcs11Modules.Unlock()
+
+	if ctx, ok := pkcs11Modules.ctx[path]; ok {
+		return ctx, nil
+	}
+
+	ctx := pkcs11.New(path)
+	if ctx == nil {
+		return nil, fmt.Errorf("%w: unable to load PKCS#11 module %s", ErrRemoteSigner, path)
+	}
+
+	if err := ctx.Initialize(); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		return nil, pkcs11Error(err)
+	}
+
+	pkcs11Modules.ctx[path] = ctx
+
+	return ctx, nil
+}
+
+// pkcs11Slot returns the given slot, the slot of the token with the given label or the first slot with token.
+func pkcs11Slot(ctx *pkcs11.Ctx, options *PKCS11Options) (uint, error) {
+	if options.Slot != nil {
+		return *options.Slot, nil
+	}
+
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, pkcs11Error(err)
+	}
+
+	for _, slot := range slots {
+		if options.TokenLabel == "" {
+			return slot, nil
+		}
+
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, pkcs11Error(err)
+		}
+
+		if strings.TrimSpace(info.Label) == options.TokenLabel {
+			return slot, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: PKCS#11 token %s", ErrMissingKey, options.TokenLabel)
+}
+
+func pkcs11Object(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, class uint, label string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+
+	if err := ctx.FindObjectsInit(session, template); err != nil {
+		return 0, pkcs11Error(err)
+	}
+
+	objects, _, err := ctx.FindObjects(session, 1)
+
+	if final := ctx.FindObjectsFinal(session); err == nil {
+		err = final
+	}
+
+	if err != nil {
+		return 0, pkcs11Error(err)
+	}
+
+	if len(objects) == 0 {
+		return 0, fmt.Errorf("%w: PKCS#11 object %s", ErrMissingKey, label)
+	}
+
+	return objects[0], nil
+}
+
+// pkcs11PublicKey reads the RSA or EC public key from the attributes of the object.
+func pkcs11PublicKey(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, object pkcs11.ObjectHandle) (interface{}, error) {
+	attrs, err := ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil)})
+	if err != nil {
+		return nil, pkcs11Error(err)
+	}
+
+	switch keyType := ulong(attrs[0].Value); keyType {
+	case pkcs11.CKK_RSA:
+		attrs, err = ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, pkcs11Error(err)
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}, nil
+	case pkcs11.CKK_EC:
+		attrs, err = ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, pkcs11Error(err)
+		}
+
+		return pkcs11ECPublicKey(attrs[0].Value, attrs[1].Value)
+	default:
+		return nil, fmt.Errorf("%w: PKCS#11 key type %d", jwk.ErrUnsupportedKey, keyType)
+	}
+}
+
+// pkcs11ECPublicKey decodes the named curve OID and the DER encoded uncompressed point.
+func pkcs11ECPublicKey(params, point []byte) (*ecdsa.PublicKey, error) {
+	var oid asn1.ObjectIdentifier
+
+	if _, err := asn1.Unmarshal(params, &oid); err != nil {
+		return nil, fmt.Errorf("%w: EC parameters: %s", jwk.ErrUnsupportedKey, err.Error())
+	}
+
+	curve, ok := ecCurves[oid.String()]
+	if !ok {
+		return nil, fmt.Errorf("%w: curve %s", jwk.ErrUnsupportedKey, oid)
+	}
+
+	var raw []byte
+
+	if _, err := asn1.Unmarshal(point, &raw); err != nil {
+		// some modules return the point without the OCTET STRING wrapping
+		raw = point
+	}
+
+	key, err := ecdsa.ParseUncompressedPublicKey(curve, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: EC point: %s", jwk.ErrUnsupportedKey, err.Error())
+	}
+
+	return key, nil
+}
+
+// ulong decodes the CK_ULONG attribute value, stored in native byte order.
+func ulong(value []byte) uint64 {
+	switch len(value) {
+	case 8:
+		return binary.NativeEndian.Uint64(value)
+	case 4:
+		return uint64(binary.NativeEndian.Uint32(value))
+	default:
+		return 0
+	}
+}
+
+func pkcs11Error(err error) error {
+	return fmt.Errorf("%w: PKCS#11: %s", ErrRemoteSigner, err.Error())
+}
@@ -0,0 +1,222 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+// remoteSigner is the opaque signer of go-jose, the signature of the payload digest is created by the service.
+// Signers with raw set get the signing input instead of its digest.
+type remoteSigner struct {
+	public *jose.JSONWebKey
+	alg    jose.SignatureAlgorithm
+	sign   func(digest []byte) ([]byte, error)
+	raw    bool
+	loop   bool
+}
+
+func (s *remoteSigner) LoopBound() bool {
+	return s.loop
+}
+
+func (s *remoteSigner) Public() *jose.JSONWebKey {
+	return s.public
+}
+
+func (s *remoteSigner) Algs() []jose.SignatureAlgorithm {
+	return []jose.SignatureAlgorithm{s.alg}
+}
+
+func (s *remoteSigner) SignPayload(payload []byte, alg jose.SignatureAlgorithm) ([]byte, error) {
+	if alg != s.alg {
+		return nil, fmt.Errorf("%w: %s, the key signs with %s", jwk.ErrUnsupportedAlgorithm, alg, s.alg)
+	}
+
+	if s.raw {
+		return s.sign(payload)
+	}
+
+	hash := hashes[alg]
+
+	h := hash.New()
+	h.Write(payload)
+
+	return s.sign(h.Sum(nil))
+}
+
+// VerifyPayload verifies the signature locally with the public key, the key of a remote signer verifies its tokens too.
+func (s *remoteSigner) VerifyPayload(payload []byte, signature []byte, alg jose.SignatureAlgorithm) error {
+	if pub, ok := s.public.Key.(ed25519.PublicKey); ok && alg == s.alg {
+		if !ed25519.Verify(pub, payload, signature) {
+			return jose.ErrCryptoFailure
+		}
+
+		return nil
+	}
+
+	hash, ok := hashes[alg]
+	if !ok || alg != s.alg {
+		return fmt.Errorf("%w: %s", jwk.ErrUnsupportedAlgorithm, alg)
+	}
+
+	h := hash.New()
+	h.Write(payload)
+	digest := h.Sum(nil)
+
+	switch pub := s.public.Key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(string(alg), "PS") {
+			return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: hash})
+		}
+
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		size := ecdsaSizes[alg]
+		if len(signature) != 2*size {
+			return jose.ErrCryptoFailure
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		sv := new(big.Int).SetBytes(signature[size:])
+
+		if !ecdsa.Verify(pub, digest, r, sv) {
+			return jose.ErrCryptoFailure
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %T", jwk.ErrUnsupportedKey, s.public.Key)
+}
+
+var hashes = map[jose.SignatureAlgorithm]crypto.Hash{
+	jose.RS256: crypto.SHA256, jose.RS384: crypto.SHA384, jose.RS512: crypto.SHA512,
+	jose.PS256: crypto.SHA256, jose.PS384: crypto.SHA384, jose.PS512: crypto.SHA512,
+	jose.ES256: crypto.SHA256, jose.ES384: crypto.SHA384, jose.ES512: crypto.SHA512,
+}
+
+// remoteKey returns the key handle of the remote signer, kid defaults to the thumbprint of the public key.
+func remoteKey(publicDER []byte, alg jose.SignatureAlgorithm, kid string, sign func([]byte) ([]byte, error)) (*jose.JSONWebKey, error) {
+	pub, err := x509.ParsePKIXPublicKey(publicDER)
+	if err != nil {
+		return nil, fmt.Errorf("%w: public key: %s", ErrRemoteSigner, err.Error())
+	}
+
+	return signerKey(&remoteSigner{alg: alg, sign: sign}, pub, kid)
+}
+
+// signerKey returns the key handle of the signer with the public key, EdDSA is supported only by raw signers.
+func signerKey(signer *remoteSigner, pub interface{}, kid string) (*jose.JSONWebKey, error) {
+	alg := signer.alg
+
+	if _, ok := hashes[alg]; !ok && (alg != jose.EdDSA || !signer.raw) {
+		return nil, fmt.Errorf("%w: %s", jwk.ErrUnsupportedAlgorithm, alg)
+	}
+
+	signer.public = &jose.JSONWebKey{Key: pub, Algorithm: string(alg), Use: "sig", KeyID: kid}
+
+	if kid == "" {
+		var err error
+
+		if signer.public.KeyID, err = jwk.Thumbprint(signer.public); err != nil {
+			return nil, err
+		}
+	}
+
+	return &jose.JSONWebKey{Key: signer, Algorithm: string(alg), Use: "sig", KeyID: signer.public.KeyID}, nil
+}
+
+var ecdsaSizes = map[jose.SignatureAlgorithm]int{jose.ES256: 32, jose.ES384: 48, jose.ES512: 66}
+
+// rawECDSA converts ASN.1 DER encoded ECDSA signature to the fixed size R || S form of JWS.
+func rawECDSA(der []byte, alg jose.SignatureAlgorithm) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("%w: ECDSA signature: %s", ErrRemoteSigner, err.Error())
+	}
+
+	size := ecdsaSizes[alg]
+
+	if sig.R == nil || sig.S == nil || sig.R.BitLen() > 8*size || sig.S.BitLen() > 8*size {
+		return nil, fmt.Errorf("%w: invalid %s signature", ErrRemoteSigner, alg)
+	}
+
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+
+	return raw, nil
+}
+
+// isECDSA reports whether the algorithm signs with ECDSA, their remote signatures are usually DER encoded.
+func isECDSA(alg jose.SignatureAlgorithm) bool {
+	return alg == jose.ES256 || alg == jose.ES384 || alg == jose.ES512
+}
+
+// keyAlgorithm returns the requested algorithm, or the default one of the public key (ES* by curve, RS256, EdDSA).
+func keyAlgorithm(public interface{}, requested string) (jose.SignatureAlgorithm, error) {
+	if _, ok := public.(ed25519.PublicKey); ok {
+		if requested != "" && requested != string(jose.EdDSA) {
+			return "", fmt.Errorf("%w: %s for the OKP key", jwk.ErrUnsupportedAlgorithm, requested)
+		}
+
+		return jose.EdDSA, nil
+	}
+
+	ec, ok := public.(*ecdsa.PublicKey)
+
+	alg, kty := jose.RS256, "RSA"
+
+	if ok {
+		kty = "EC"
+
+		for a, size := range ecdsaSizes {
+			if (ec.Curve.Params().BitSize+7)/8 == size {
+				alg = a
+			}
+		}
+	}
+
+	if requested == "" {
+		return alg, nil
+	}
+
+	if isECDSA(jose.SignatureAlgorithm(requested)) != ok || (ok && requested != string(alg)) {
+		return "", fmt.Errorf("%w: %s for the %s key", jwk.ErrUnsupportedAlgorithm, requested, kty)
+	}
+
+	return jose.SignatureAlgorithm(requested), nil
+}
@@ -0,0 +1,93 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kms
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is the time before expiry when the access token is renewed.
+const tokenRefreshMargin = time.Minute
+
+// tokenSource caches the access token returned by fetch until it expires, the token is static if fetch is nil.
+// Zero lifetime means the token does not expire.
+type tokenSource struct {
+	fetch func() (string, time.Duration, error)
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (ts *tokenSource) accessToken() (string, error) {
+	if ts.fetch == nil {
+		return ts.token, nil
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && (ts.expiry.IsZero() || time.Until(ts.expiry) > tokenRefreshMargin) {
+		return ts.token, nil
+	}
+
+	token, lifetime, err := ts.fetch()
+	if err != nil {
+		return "", err
+	}
+
+	ts.token, ts.expiry = token, time.Time{}
+
+	if lifetime > 0 {
+		ts.expiry = time.Now().Add(lifetime)
+	}
+
+	return ts.token, nil
+}
+
+// oauthToken returns fetch function of the OAuth 2.0 token response to the request of newRequest.
+func (m *Module) oauthToken(newRequest func() (*http.Request, error)) func() (string, time.Duration, error) {
+	return func() (string, time.Duration, error) {
+		req, err := newRequest()
+		if err != nil {
+			return "", 0, err
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			// some identity endpoints return expires_in as string
+			ExpiresIn json.Number `json:"expires_in"`
+		}
+
+		if err := m.do(req, &token); err != nil {
+			return "", 0, err
+		}
+
+		seconds, _ := token.ExpiresIn.Int64()
+
+		return token.AccessToken, time.Duration(seconds) * time.Second, nil
+	}
+}
@@ -0,0 +1,260 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kms
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+type VaultOptions struct {
+	Address      string `js:"address"`
+	Token        string `js:"token"`
+	RoleID       string `js:"roleId"`
+	SecretID     string `js:"secretId"`
+	AppRoleMount string `js:"approleMount"`
+	Namespace    string `js:"namespace"`
+	Mount        string `js:"mount"`
+	Algorithm    string `js:"algorithm"`
+	KeyID        string `js:"kid"`
+}
+
+type vaultTransit struct {
+	m         *Module
+	address   string
+	namespace string
+	tokens    *tokenSource
+}
+
+// vaultHashes maps the JWS algorithms to the hash algorithms of the transit engine.
+var vaultHashes = map[jose.SignatureAlgorithm]string{
+	jose.RS256: "sha2-256", jose.PS256: "sha2-256", jose.ES256: "sha2-256",
+	jose.RS384: "sha2-384", jose.PS384: "sha2-384", jose.ES384: "sha2-384",
+	jose.RS512: "sha2-512", jose.PS512: "sha2-512", jose.ES512: "sha2-512",
+}
+
+// Vault returns the signing key of the transit engine key with the given name, the latest key version is used.
+// Address and credentials default to the standard VAULT_* environment variables.
+func (m *Module) Vault(name string, options *VaultOptions) (*jose.JSONWebKey, error) {
+	if options == nil {
+		options = &VaultOptions{}
+	}
+
+	if name == "" {
+		return nil, ErrMissingKey
+	}
+
+	vault := &vaultTransit{
+		m:         m,
+		address:   strings.TrimSuffix(m.lookup(options.Address, "VAULT_ADDR"), "/"),
+		namespace: m.lookup(options.Namespace, "VAULT_NAMESPACE"),
+	}
+
+	if vault.address == "" {
+		return nil, fmt.Errorf("%w: Vault address", ErrMissingCredentials)
+	}
+
+	if err := vault.login(options); err != nil {
+		return nil, err
+	}
+
+	mount := strings.Trim(options.Mount, "/")
+	if mount == "" {
+		mount = "transit"
+	}
+
+	var key struct {
+		Data struct {
+			Type          string `json:"type"`
+			LatestVersion int    `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+
+	if err := vault.call(http.MethodGet, mount+"/keys/"+name, nil, &key); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(key.Data.Type, "ecdsa-") && !strings.HasPrefix(key.Data.Type, "rsa-") {
+		return nil, fmt.Errorf("%w: %s transit key", jwk.ErrUnsupportedKey, key.Data.Type)
+	}
+
+	version := strconv.Itoa(key.Data.LatestVersion)
+
+	block, _ := pem.Decode([]byte(key.Data.Keys[version].PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("%w: public key: %s", ErrRemoteSigner, jwk.ErrInvalidPEM)
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: public key: %s", ErrRemoteSigner, err.Error())
+	}
+
+	alg, err := keyAlgorithm(public, options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	in := map[string]interface{}{
+		"prehashed":      true,
+		"hash_algorithm": vaultHashes[alg],
+		"key_version":    key.Data.LatestVersion,
+	}
+
+	switch {
+	case isECDSA(alg):
+		in["marshaling_algorithm"] = "jws"
+	case strings.HasPrefix(string(alg), "PS"):
+		in["signature_algorithm"], in["salt_length"] = "pss", "hash"
+	default:
+		in["signature_algorithm"] = "pkcs1v15"
+	}
+
+	return remoteKey(block.Bytes, alg, options.KeyID, func(digest []byte) ([]byte, error) {
+		var signed struct {
+			Data struct {
+				Signature string `json:"signature"`
+			} `json:"data"`
+		}
+
+		in["input"] = base64.StdEncoding.EncodeToString(digest)
+
+		if err := vault.call(http.MethodPost, mount+"/sign/"+name, in, &signed); err != nil {
+			return nil, err
+		}
+
+		// the signature is prefixed with vault:v<version>:
+		value := signed.Data.Signature[strings.LastIndex(signed.Data.Signature, ":")+1:]
+
+		encoding := base64.StdEncoding
+		if isECDSA(alg) {
+			encoding = base64.RawURLEncoding
+		}
+
+		sig, err := encoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: signature: %s", ErrRemoteSigner, err.Error())
+		}
+
+		return sig, nil
+	})
+}
+
+// login sets up the static token, or the AppRole login renewed when the lease expires.
+func (vault *vaultTransit) login(options *VaultOptions) error {
+	m := vault.m
+
+	// explicit options take precedence over the environment
+	token := options.Token
+	if token == "" && options.RoleID == "" {
+		token = m.env["VAULT_TOKEN"]
+	}
+
+	if token != "" {
+		vault.tokens = &tokenSource{token: token}
+
+		return nil
+	}
+
+	roleID, secretID := m.lookup(options.RoleID, "VAULT_ROLE_ID"), m.lookup(options.SecretID, "VAULT_SECRET_ID")
+	if roleID == "" {
+		return fmt.Errorf("%w: Vault token or AppRole role ID", ErrMissingCredentials)
+	}
+
+	mount := strings.Trim(options.AppRoleMount, "/")
+	if mount == "" {
+		mount = "approle"
+	}
+
+	vault.tokens = &tokenSource{fetch: func() (string, time.Duration, error) {
+		var login struct {
+			Auth struct {
+				ClientToken   string `json:"client_token"`
+				LeaseDuration int64  `json:"lease_duration"`
+			} `json:"auth"`
+		}
+
+		in := map[string]string{"role_id": roleID, "secret_id": secretID}
+
+		if err := vault.request(http.MethodPost, "auth/"+mount+"/login", "", in, &login); err != nil {
+			return "", 0, err
+		}
+
+		return login.Auth.ClientToken, time.Duration(login.Auth.LeaseDuration) * time.Second, nil
+	}}
+
+	return nil
+}
+
+// call invokes the Vault API path with the current token.
+func (vault *vaultTransit) call(method, path string, in, out interface{}) error {
+	token, err := vault.tokens.accessToken()
+	if err != nil {
+		return err
+	}
+
+	return vault.request(method, path, token, in, out)
+}
+
+func (vault *vaultTransit) request(method, path, token string, in, out interface{}) error {
+	var body []byte
+
+	if in != nil {
+		var err error
+
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, vault.address+"/v1/"+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrRemoteSigner, err.Error())
+	}
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	if vault.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vault.namespace)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return vault.m.do(req, out)
+}
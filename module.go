@@ -0,0 +1,270 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package jose
+
+import (
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/httpclient"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwe"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jws"
+	"github.com/szkiba/xk6-jose/jwt"
+	"github.com/szkiba/xk6-jose/oidc"
+	"go.k6.io/k6/js/modules"
+)
+
+// Module is the k6/x/jose root module, its namespaces share the configuration set by Configure.
+type Module struct {
+	JWK *sobek.Object `js:"jwk"`
+	JWT *sobek.Object `js:"jwt"`
+	JWS *sobek.Object `js:"jws"`
+	JWE *sobek.Object `js:"jwe"`
+
+	Keys    *sobek.Object `js:"keys"`
+	Issuers *sobek.Object `js:"issuers"`
+
+	vu     modules.VU
+	config *config.Config
+}
+
+type ConfigureOptions struct {
+	Leeway     interface{} `js:"leeway"`
+	Algorithms []string    `js:"algorithms"`
+	CacheTTL   interface{} `js:"cacheTTL"`
+	KeySize    int         `js:"keySize"`
+	Critical   []string    `js:"critical"`
+
+	FetchTimeout interface{} `js:"fetchTimeout"`
+
+	MaxTokenSize int `js:"maxTokenSize"`
+	MaxSegments  int `js:"maxSegments"`
+	MaxDepth     int `js:"maxDepth"`
+
+	StrictBase64 *bool       `js:"strictBase64"`
+	StrictJSON   *bool       `js:"strictJSON"`
+	Debug        *bool       `js:"debug"`
+	Clock        interface{} `js:"clock"`
+}
+
+// systemClock is the clock option value restoring the wall clock.
+const systemClock = "system"
+
+type DiscoverOptions struct {
+	Timeout interface{} `js:"timeout"`
+	Refresh bool        `js:"refresh"`
+}
+
+const defaultDiscoverTimeout = 30 * time.Second
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} {
+		cfg := config.Default(vu)
+		rt := vu.Runtime()
+
+		jwtModule := jwt.NewModule(vu, cfg)
+
+		m := &Module{
+			JWK:    jsmodule.Exports(rt, jwk.NewModule(vu, cfg)),
+			JWT:    jsmodule.Exports(rt, jwtModule),
+			JWS:    jsmodule.Exports(rt, jws.NewModule(vu, cfg)),
+			JWE:    jsmodule.Exports(rt, jwe.NewModule(vu, cfg)),
+			Keys:   jsmodule.Exports(rt, jwk.NewStore()),
+			vu:     vu,
+			config: cfg,
+		}
+
+		m.Issuers = jsmodule.Exports(rt, oidc.NewRegistry(jwtModule, cfg, m.fetchKeySet, m.discoverIssuer))
+
+		return m
+	})
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
+}
+
+// Config returns the current configuration, durations in seconds, null (or empty) for the defaults.
+func (m *Module) Config() map[string]interface{} {
+	seconds := func(d *time.Duration) interface{} {
+		if d == nil {
+			return nil
+		}
+
+		return d.Seconds()
+	}
+
+	positive := func(n int) interface{} {
+		if n <= 0 {
+			return nil
+		}
+
+		return n
+	}
+
+	return map[string]interface{}{
+		"leeway":       seconds(m.config.Leeway),
+		"algorithms":   append([]string{}, m.config.Algorithms...),
+		"cacheTTL":     seconds(m.config.CacheTTL),
+		"keySize":      positive(m.config.KeySize),
+		"critical":     append([]string{}, m.config.Critical...),
+		"fetchTimeout": seconds(m.config.FetchTimeout),
+		"maxTokenSize": positive(m.config.MaxTokenSize),
+		"maxSegments":  positive(m.config.MaxSegments),
+		"maxDepth":     positive(m.config.MaxDepth),
+		"strictBase64": m.config.StrictBase64,
+		"strictJSON":   m.config.StrictJSON,
+		"debug":        m.config.Debug,
+		"clock":        m.config.Clock != nil,
+	}
+}
+
+// Configure updates the configuration, options not given keep their current value.
+// The cache TTL applies to the key caches shared by every VU.
+func (m *Module) Configure(options *ConfigureOptions) error {
+	if options == nil {
+		return nil
+	}
+
+	var leeway, ttl, timeout time.Duration
+
+	var err error
+
+	if options.Leeway != nil {
+		if leeway, err = jwt.Duration(options.Leeway); err != nil {
+			return err
+		}
+	}
+
+	if options.CacheTTL != nil {
+		if ttl, err = jwt.Duration(options.CacheTTL); err != nil {
+			return err
+		}
+	}
+
+	if options.FetchTimeout != nil {
+		if timeout, err = jwt.Duration(options.FetchTimeout); err != nil {
+			return err
+		}
+	}
+
+	var clock func() (time.Time, error)
+
+	if options.Clock != nil && options.Clock != systemClock {
+		if clock, err = config.Clock(options.Clock); err != nil {
+			return err
+		}
+	}
+
+	if options.Clock != nil {
+		m.config.Clock = clock
+	}
+
+	if options.StrictBase64 != nil {
+		m.config.StrictBase64 = *options.StrictBase64
+	}
+
+	if options.StrictJSON != nil {
+		m.config.StrictJSON = *options.StrictJSON
+	}
+
+	if options.Debug != nil {
+		m.config.Debug = *options.Debug
+	}
+
+	if options.Leeway != nil {
+		m.config.Leeway = &leeway
+	}
+
+	if options.CacheTTL != nil {
+		m.config.CacheTTL = &ttl
+		jwk.SetCacheTTL(ttl)
+	}
+
+	if options.FetchTimeout != nil {
+		m.config.FetchTimeout = &timeout
+	}
+
+	if options.KeySize > 0 {
+		m.config.KeySize = options.KeySize
+	}
+
+	if options.MaxTokenSize > 0 {
+		m.config.MaxTokenSize = options.MaxTokenSize
+	}
+
+	if options.MaxSegments > 0 {
+		m.config.MaxSegments = options.MaxSegments
+	}
+
+	if options.MaxDepth > 0 {
+		m.config.MaxDepth = options.MaxDepth
+	}
+
+	if options.Algorithms != nil {
+		m.config.Algorithms = append([]string(nil), options.Algorithms...)
+	}
+
+	if options.Critical != nil {
+		m.config.Critical = append([]string(nil), options.Critical...)
+	}
+
+	return nil
+}
+
+// Discover returns the OpenID provider of the issuer URL with the keys of its jwks_uri, usable as verification keys.
+// Providers are cached like parsed keys, requests of VU code go through the transport of k6.
+func (m *Module) Discover(issuer string, options *DiscoverOptions) (*oidc.Issuer, error) {
+	if options == nil {
+		options = &DiscoverOptions{}
+	}
+
+	timeout := defaultDiscoverTimeout
+
+	if options.Timeout != nil {
+		var err error
+
+		if timeout, err = jwt.Duration(options.Timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	client, ctx := httpclient.New(m.vu, timeout)
+
+	return oidc.Discover(ctx, client, issuer, options.Refresh)
+}
+
+// fetchKeySet fetches the jwks URL of registered issuers, with the configured fetch timeout.
+func (m *Module) fetchKeySet(source string) ([]jose.JSONWebKey, error) {
+	return jwk.FetchKeySet(m.vu, m.config, source)
+}
+
+// discoverIssuer discovers registered issuers, they are refreshed by the cache TTL of the issuer.
+func (m *Module) discoverIssuer(issuer string) (*oidc.Issuer, error) {
+	return m.Discover(issuer, &DiscoverOptions{Refresh: true})
+}
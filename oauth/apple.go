@@ -0,0 +1,84 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidLifetime = errors.New("invalid lifetime")
+
+const (
+	appleAudience       = "https://appleid.apple.com"
+	defaultAppleSeconds = 3600
+	maxAppleSeconds     = 15777000
+)
+
+type AppleClientSecretOptions struct {
+	Lifetime int `js:"lifetime"`
+}
+
+// AppleClientSecret creates ES256 client secret for Sign in with Apple.
+func (m *Module) AppleClientSecret(
+	keyIn interface{},
+	teamID string,
+	keyID string,
+	clientID string,
+	options *AppleClientSecretOptions,
+) (string, error) {
+	if options == nil {
+		options = &AppleClientSecretOptions{}
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultAppleSeconds
+	}
+
+	if lifetime > maxAppleSeconds {
+		return "", fmt.Errorf("%w: %d seconds, maximum is %d", ErrInvalidLifetime, lifetime, maxAppleSeconds)
+	}
+
+	key, err := signingKey(keyIn)
+	if err != nil {
+		return "", err
+	}
+
+	key.KeyID = keyID
+	key.Algorithm = string(jose.ES256)
+
+	now := time.Now()
+
+	return jwt.Sign(key, map[string]interface{}{
+		"iss": teamID,
+		"sub": clientID,
+		"aud": appleAudience,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+	}, nil)
+}
@@ -0,0 +1,192 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+const (
+	clientAttestationType      = "oauth-client-attestation+jwt"
+	clientAttestationPoPType   = "oauth-client-attestation-pop+jwt"
+	headerClientAttestation    = "OAuth-Client-Attestation"
+	headerClientAttestationPoP = "OAuth-Client-Attestation-PoP"
+	defaultAttestationSeconds  = 3600
+)
+
+type ClientAttestationOptions struct {
+	Lifetime int                    `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+	Header   map[string]interface{} `js:"header"`
+}
+
+// ClientAttestation creates client attestation JWT (OAuth 2.0 Attestation-Based Client Authentication),
+// signed by the client attester and bound to the public key of the client instance with the cnf claim.
+func (m *Module) ClientAttestation(
+	key *jose.JSONWebKey,
+	issuer string,
+	clientID string,
+	instanceKey *jose.JSONWebKey,
+	options *ClientAttestationOptions,
+) (string, error) {
+	if options == nil {
+		options = &ClientAttestationOptions{}
+	}
+
+	if instanceKey == nil {
+		return "", fmt.Errorf("%w: instance key required", ErrMissingBinding)
+	}
+
+	public := instanceKey.Public()
+	if !public.Valid() {
+		return "", fmt.Errorf("%w: instance key has no public key", jwk.ErrUnsupportedKey)
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultAttestationSeconds
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss":    issuer,
+		"sub":    clientID,
+		"iat":    now.Unix(),
+		"exp":    now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		cnfClaim: map[string]interface{}{"jwk": public},
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": clientAttestationType}, options.Header)
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+}
+
+type ClientAttestationPoPOptions struct {
+	Challenge string                 `js:"challenge"`
+	Lifetime  int                    `js:"lifetime"`
+	Claims    map[string]interface{} `js:"claims"`
+	Header    map[string]interface{} `js:"header"`
+}
+
+type ClientAttestationPoP struct {
+	Attestation string            `js:"attestation"`
+	PoP         string            `js:"pop"`
+	Headers     map[string]string `js:"headers"`
+}
+
+// ClientAttestationPoP creates client attestation PoP JWT for the audience, signed by the instance key which must be
+// the one in the cnf claim of the attestation. The client is the subject of the attestation.
+func (m *Module) ClientAttestationPoP(
+	key *jose.JSONWebKey,
+	attestation string,
+	audience string,
+	options *ClientAttestationPoPOptions,
+) (*ClientAttestationPoP, error) {
+	if options == nil {
+		options = &ClientAttestationPoPOptions{}
+	}
+
+	attested, err := jwt.Decode(attestation)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID, _ := attested["sub"].(string)
+	if clientID == "" {
+		return nil, fmt.Errorf("%w: attestation without sub", ErrInvalidClaim)
+	}
+
+	if err := checkAttestationKey(attested, key); err != nil {
+		return nil, err
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultAssertionSeconds
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss": clientID,
+		"aud": audience,
+		"jti": jwt.RandomID(),
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+	}
+
+	if options.Challenge != "" {
+		claims["challenge"] = options.Challenge
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": clientAttestationPoPType}, options.Header)
+
+	pop, err := jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientAttestationPoP{
+		Attestation: attestation,
+		PoP:         pop,
+		Headers: map[string]string{
+			headerClientAttestation:    attestation,
+			headerClientAttestationPoP: pop,
+		},
+	}, nil
+}
+
+// checkAttestationKey compares the thumbprint of the key with the thumbprint of the cnf.jwk claim of the attestation.
+func checkAttestationKey(attested map[string]interface{}, key *jose.JSONWebKey) error {
+	cnf, ok := attested[cnfClaim].(map[string]interface{})
+	if !ok || cnf["jwk"] == nil {
+		return fmt.Errorf("%w: %s.jwk claim", ErrMissingBinding, cnfClaim)
+	}
+
+	bound, err := jwk.KeySet(cnf["jwk"])
+	if err != nil || len(bound) != 1 {
+		return fmt.Errorf("%w: invalid %s.jwk claim", ErrMissingBinding, cnfClaim)
+	}
+
+	expected, err := jwk.Thumbprint(&bound[0])
+	if err != nil {
+		return err
+	}
+
+	actual, err := jwk.Thumbprint(key)
+	if err != nil {
+		return err
+	}
+
+	if actual != expected {
+		return fmt.Errorf("%w: %s.jwk", ErrBindingMismatch, cnfClaim)
+	}
+
+	return nil
+}
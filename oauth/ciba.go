@@ -0,0 +1,173 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+const defaultCIBASeconds = 300
+
+// cibaHints are the user identification hints of the authentication request, exactly one is required.
+var cibaHints = []string{"login_hint_token", "id_token_hint", "login_hint"}
+
+type CIBARequestOptions struct {
+	Lifetime int                    `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+	Header   map[string]interface{} `js:"header"`
+}
+
+// CibaRequest creates signed authentication request of Client-Initiated Backchannel Authentication (CIBA Core 7.1.1).
+func (m *Module) CibaRequest(
+	key *jose.JSONWebKey,
+	clientID string,
+	issuer string,
+	params map[string]interface{},
+	options *CIBARequestOptions,
+) (*RequestObject, error) {
+	if options == nil {
+		options = &CIBARequestOptions{}
+	}
+
+	claims, err := cibaClaims(clientID, issuer, params, options)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := jwt.Sign(key, claims, options.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestObject{
+		Request: request,
+		Params: map[string]string{
+			"client_id": clientID,
+			"request":   request,
+		},
+	}, nil
+}
+
+func cibaClaims(
+	clientID string,
+	issuer string,
+	params map[string]interface{},
+	options *CIBARequestOptions,
+) (map[string]interface{}, error) {
+	if err := checkCIBAParams(params); err != nil {
+		return nil, err
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultCIBASeconds
+	}
+
+	now := time.Now()
+
+	claims := jwt.Merge(map[string]interface{}{
+		"iss": clientID,
+		"aud": issuer,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jti": jwt.RandomID(),
+	}, params)
+
+	if expiry, ok := params["requested_expiry"]; ok {
+		seconds, err := requestedExpiry(expiry)
+		if err != nil {
+			return nil, err
+		}
+
+		claims["requested_expiry"] = seconds
+	}
+
+	return jwt.Merge(claims, options.Claims), nil
+}
+
+// checkCIBAParams checks the openid scope, the hints and the binding message of the authentication request.
+func checkCIBAParams(params map[string]interface{}) error {
+	scope, ok := params["scope"].(string)
+	if !ok {
+		return fmt.Errorf("%w: scope", ErrMissingParameter)
+	}
+
+	if !slices.Contains(strings.Fields(scope), "openid") {
+		return fmt.Errorf("%w: scope must contain openid", ErrInvalidClaim)
+	}
+
+	var hints []string
+
+	for _, name := range cibaHints {
+		if _, ok := params[name]; ok {
+			hints = append(hints, name)
+		}
+	}
+
+	switch len(hints) {
+	case 0:
+		return fmt.Errorf("%w: one of %s", ErrMissingParameter, strings.Join(cibaHints, ", "))
+	case 1:
+	default:
+		return fmt.Errorf("%w: only one of %s allowed", ErrInvalidClaim, strings.Join(hints, ", "))
+	}
+
+	if message, ok := params["binding_message"]; ok {
+		if _, ok := message.(string); !ok {
+			return fmt.Errorf("%w: binding_message must be string", ErrInvalidClaim)
+		}
+	}
+
+	return nil
+}
+
+// requestedExpiry returns the requested_expiry as JSON number, numeric strings are accepted as well.
+func requestedExpiry(in interface{}) (int64, error) {
+	var seconds int64
+
+	switch v := in.(type) {
+	case int64:
+		seconds = v
+	case float64:
+		seconds = int64(v)
+		if float64(seconds) != v {
+			seconds = 0
+		}
+	case string:
+		seconds, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	if seconds <= 0 {
+		return 0, fmt.Errorf("%w: requested_expiry must be positive integer, got %v", ErrInvalidClaim, in)
+	}
+
+	return seconds, nil
+}
@@ -0,0 +1,122 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var (
+	ErrMissingBinding  = errors.New("missing confirmation binding")
+	ErrBindingMismatch = errors.New("confirmation binding mismatch")
+)
+
+const (
+	cnfClaim      = "cnf"
+	jktMember     = "jkt"
+	x5tS256Member = "x5t#S256"
+)
+
+type ConfirmationOptions struct {
+	Key         *jose.JSONWebKey `js:"jwk"`
+	Certificate interface{}      `js:"cert"`
+}
+
+// Confirmation computes cnf claim value with jkt (RFC 9449) and/or x5t#S256 (RFC 8705) members.
+func (m *Module) Confirmation(options *ConfirmationOptions) (map[string]interface{}, error) {
+	return confirmation(options)
+}
+
+// Bind returns copy of claims with the cnf claim, the existing cnf members are kept.
+func (m *Module) Bind(claims map[string]interface{}, options *ConfirmationOptions) (map[string]interface{}, error) {
+	cnf, err := confirmation(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.Merge(claims, map[string]interface{}{cnfClaim: cnf}), nil
+}
+
+// VerifyConfirmation checks that the cnf claim matches every given key and certificate.
+func (m *Module) VerifyConfirmation(claims map[string]interface{}, options *ConfirmationOptions) error {
+	expected, err := confirmation(options)
+	if err != nil {
+		return err
+	}
+
+	cnf, ok := claims[cnfClaim].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: %s claim", ErrMissingBinding, cnfClaim)
+	}
+
+	for member, value := range expected {
+		actual, ok := cnf[member].(string)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrMissingBinding, member)
+		}
+
+		if subtle.ConstantTimeCompare([]byte(actual), []byte(value.(string))) != 1 {
+			return fmt.Errorf("%w: %s", ErrBindingMismatch, member)
+		}
+	}
+
+	return nil
+}
+
+func confirmation(options *ConfirmationOptions) (map[string]interface{}, error) {
+	if options == nil || (options.Key == nil && options.Certificate == nil) {
+		return nil, fmt.Errorf("%w: jwk or cert required", ErrMissingBinding)
+	}
+
+	cnf := map[string]interface{}{}
+
+	if options.Key != nil {
+		jkt, err := jwk.Thumbprint(options.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		cnf[jktMember] = jkt
+	}
+
+	if options.Certificate != nil {
+		der, err := buffer.Certificate(options.Certificate)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(der)
+		cnf[x5tS256Member] = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	return cnf, nil
+}
@@ -0,0 +1,177 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidDelegation = errors.New("invalid delegation")
+
+const actClaim = "act"
+
+type DelegationOptions struct {
+	Actors   []interface{}          `js:"actors"`
+	MaxDepth int                    `js:"maxDepth"`
+	MayAct   map[string]interface{} `js:"mayAct"`
+}
+
+// Act builds nested act claim (RFC 8693), the first actor is the current one, followed by the prior actors.
+func (m *Module) Act(actors []interface{}) (map[string]interface{}, error) {
+	var act map[string]interface{}
+
+	for i := len(actors) - 1; i >= 0; i-- {
+		actor, err := actorClaims(actors[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if act != nil {
+			actor[actClaim] = act
+		}
+
+		act = actor
+	}
+
+	if act == nil {
+		return nil, fmt.Errorf("%w: no actor", ErrInvalidDelegation)
+	}
+
+	return act, nil
+}
+
+// Delegate returns copy of claims with new current actor, the previous act claim is nested into it.
+func (m *Module) Delegate(claims map[string]interface{}, actorIn interface{}) (map[string]interface{}, error) {
+	actor, err := actorClaims(actorIn)
+	if err != nil {
+		return nil, err
+	}
+
+	out := jwt.Merge(claims)
+
+	if prior, ok := out[actClaim]; ok {
+		actor[actClaim] = prior
+	}
+
+	out[actClaim] = actor
+
+	return out, nil
+}
+
+// Actors walks the act chain, returns the actors (without nested act) starting with the current one.
+func (m *Module) Actors(claims map[string]interface{}) ([]map[string]interface{}, error) {
+	return actorChain(claims)
+}
+
+// VerifyDelegation checks the act chain against the expected actors, chain depth and may_act claim.
+func (m *Module) VerifyDelegation(claims map[string]interface{}, options *DelegationOptions) error {
+	if options == nil {
+		options = &DelegationOptions{}
+	}
+
+	chain, err := actorChain(claims)
+	if err != nil {
+		return err
+	}
+
+	if options.MaxDepth > 0 && len(chain) > options.MaxDepth {
+		return fmt.Errorf("%w: chain depth %d exceeds %d", ErrInvalidDelegation, len(chain), options.MaxDepth)
+	}
+
+	if options.Actors != nil {
+		if len(chain) != len(options.Actors) {
+			return fmt.Errorf("%w: %d actors, expected %d", ErrInvalidDelegation, len(chain), len(options.Actors))
+		}
+
+		for i, expected := range options.Actors {
+			if err := matchActor(chain[i], expected); err != nil {
+				return err
+			}
+		}
+	}
+
+	if options.MayAct != nil {
+		if len(chain) == 0 {
+			return fmt.Errorf("%w: missing act claim", ErrInvalidDelegation)
+		}
+
+		if err := matchActor(chain[0], options.MayAct); err != nil {
+			return fmt.Errorf("%w: current actor is not authorized by may_act", err)
+		}
+	}
+
+	return nil
+}
+
+func actorChain(claims map[string]interface{}) ([]map[string]interface{}, error) {
+	chain := []map[string]interface{}{}
+
+	for next, ok := claims[actClaim]; ok; {
+		actor, isObject := next.(map[string]interface{})
+		if !isObject {
+			return nil, fmt.Errorf("%w: act claim must be object", ErrInvalidDelegation)
+		}
+
+		next, ok = actor[actClaim]
+
+		actor = jwt.Merge(actor, map[string]interface{}{actClaim: nil})
+		chain = append(chain, actor)
+	}
+
+	return chain, nil
+}
+
+// actorClaims accepts subject string or actor claims.
+func actorClaims(in interface{}) (map[string]interface{}, error) {
+	switch v := in.(type) {
+	case string:
+		return map[string]interface{}{"sub": v}, nil
+	case map[string]interface{}:
+		return jwt.Merge(v), nil
+	default:
+		return nil, fmt.Errorf("%w: actor must be string or object, got %T", ErrInvalidDelegation, in)
+	}
+}
+
+func matchActor(actor map[string]interface{}, expectedIn interface{}) error {
+	expected, err := actorClaims(expectedIn)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range expected {
+		if k == actClaim {
+			continue
+		}
+
+		// compared by string form, numbers may be int64 or float64 by their source
+		if actual, ok := actor[k]; !ok || fmt.Sprint(actual) != fmt.Sprint(v) {
+			return fmt.Errorf("%w: actor %s is %v, expected %v", ErrInvalidDelegation, k, actor[k], v)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidUID = errors.New("invalid uid")
+
+const (
+	firebaseAudience = "https://identitytoolkit.googleapis.com/google.identity.identitytoolkit.v1.IdentityToolkit"
+	maxUIDLength     = 128
+)
+
+// reserved claim names of Firebase ID tokens, developer claims must not use them
+var firebaseReservedClaims = []string{
+	"acr", "amr", "at_hash", "aud", "auth_time", "azp", "cnf", "c_hash",
+	"exp", "firebase", "iat", "iss", "jti", "nbf", "nonce", "sub",
+}
+
+type FirebaseTokenOptions struct {
+	Claims   map[string]interface{} `js:"claims"`
+	TenantID string                 `js:"tenantId"`
+	Lifetime int                    `js:"lifetime"`
+}
+
+// FirebaseCustomToken creates Firebase Authentication custom token signed by the service account key.
+func (m *Module) FirebaseCustomToken(
+	credentials interface{},
+	uid string,
+	options *FirebaseTokenOptions,
+) (string, error) {
+	if options == nil {
+		options = &FirebaseTokenOptions{}
+	}
+
+	if uid == "" || len(uid) > maxUIDLength {
+		return "", fmt.Errorf("%w: must be 1-%d characters", ErrInvalidUID, maxUIDLength)
+	}
+
+	for _, name := range firebaseReservedClaims {
+		if _, ok := options.Claims[name]; ok {
+			return "", fmt.Errorf("%w: reserved claim %s", ErrInvalidClaim, name)
+		}
+	}
+
+	account, err := parseServiceAccount(credentials)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := account.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = maxGoogleSeconds
+	}
+
+	if lifetime > maxGoogleSeconds {
+		return "", fmt.Errorf("%w: %d seconds, maximum is %d", ErrInvalidLifetime, lifetime, maxGoogleSeconds)
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss": account.ClientEmail,
+		"sub": account.ClientEmail,
+		"aud": firebaseAudience,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"uid": uid,
+	}
+
+	if len(options.Claims) != 0 {
+		claims["claims"] = options.Claims
+	}
+
+	if options.TenantID != "" {
+		claims["tenant_id"] = options.TenantID
+	}
+
+	return jwt.Sign(key, claims, nil)
+}
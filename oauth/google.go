@@ -0,0 +1,175 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidCredentials = errors.New("invalid service account credentials")
+
+const (
+	jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	googleTokenURI     = "https://oauth2.googleapis.com/token"
+	maxGoogleSeconds   = 3600
+	serviceAccountType = "service_account"
+)
+
+type ServiceAccountOptions struct {
+	Subject  string                 `js:"subject"`
+	Audience string                 `js:"audience"`
+	Lifetime int                    `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+}
+
+type ServiceAccountAssertion struct {
+	Assertion string            `js:"assertion"`
+	TokenURI  string            `js:"tokenUri"`
+	Params    map[string]string `js:"params"`
+}
+
+type serviceAccount struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// ServiceAccountAssertion creates RS256 JWT bearer assertion from Google service account key.
+func (m *Module) ServiceAccountAssertion(
+	credentials interface{},
+	scopes []string,
+	options *ServiceAccountOptions,
+) (*ServiceAccountAssertion, error) {
+	if options == nil {
+		options = &ServiceAccountOptions{}
+	}
+
+	account, err := parseServiceAccount(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := account.signingKey()
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = maxGoogleSeconds
+	}
+
+	if lifetime > maxGoogleSeconds {
+		return nil, fmt.Errorf("%w: %d seconds, maximum is %d", ErrInvalidLifetime, lifetime, maxGoogleSeconds)
+	}
+
+	tokenURI := account.TokenURI
+	if tokenURI == "" {
+		tokenURI = googleTokenURI
+	}
+
+	aud := options.Audience
+	if aud == "" {
+		aud = tokenURI
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss": account.ClientEmail,
+		"aud": aud,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+	}
+
+	if len(scopes) != 0 {
+		claims["scope"] = strings.Join(scopes, " ")
+	}
+
+	if options.Subject != "" {
+		claims["sub"] = options.Subject
+	}
+
+	assertion, err := jwt.Sign(key, jwt.Merge(claims, options.Claims), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceAccountAssertion{
+		Assertion: assertion,
+		TokenURI:  tokenURI,
+		Params: map[string]string{
+			"grant_type": jwtBearerGrantType,
+			"assertion":  assertion,
+		},
+	}, nil
+}
+
+// parseServiceAccount accepts the JSON key file content or the parsed object.
+func parseServiceAccount(in interface{}) (*serviceAccount, error) {
+	var (
+		raw []byte
+		err error
+	)
+
+	if source, ok := in.(string); ok {
+		raw = []byte(source)
+	} else if raw, err = json.Marshal(in); err != nil {
+		return nil, err
+	}
+
+	account := &serviceAccount{}
+
+	if err := json.Unmarshal(raw, account); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, err.Error())
+	}
+
+	if account.Type != serviceAccountType || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("%w: missing service account fields", ErrInvalidCredentials)
+	}
+
+	return account, nil
+}
+
+// signingKey returns the RS256 private key of the service account.
+func (account *serviceAccount) signingKey() (*jose.JSONWebKey, error) {
+	key, err := jwk.FromPEM(account.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key.KeyID = account.PrivateKeyID
+	key.Algorithm = string(jose.RS256)
+
+	return key, nil
+}
@@ -0,0 +1,115 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidIntrospection = errors.New("invalid introspection response")
+
+const (
+	introspectionType  = "token-introspection+jwt"
+	introspectionClaim = "token_introspection"
+)
+
+type IntrospectionOptions struct {
+	Claims map[string]interface{} `js:"claims"`
+	Header map[string]interface{} `js:"header"`
+}
+
+// IntrospectionResponse creates JWT response for token introspection (RFC 9701).
+func (m *Module) IntrospectionResponse(
+	key *jose.JSONWebKey,
+	issuer string,
+	audience string,
+	introspection map[string]interface{},
+	options *IntrospectionOptions,
+) (string, error) {
+	if options == nil {
+		options = &IntrospectionOptions{}
+	}
+
+	if _, ok := introspection["active"].(bool); !ok {
+		return "", fmt.Errorf("%w: active member required", ErrInvalidIntrospection)
+	}
+
+	claims := map[string]interface{}{
+		"iss":              issuer,
+		"aud":              audience,
+		"iat":              time.Now().Unix(),
+		introspectionClaim: introspection,
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": introspectionType}, options.Header)
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+}
+
+// VerifyIntrospectionResponse verifies JWT introspection response and returns the token_introspection claim.
+func (m *Module) VerifyIntrospectionResponse(
+	response string,
+	keys interface{},
+	issuer string,
+	audience string,
+) (map[string]interface{}, error) {
+	parsed, err := jwt.ParseWith(m.config, response)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ, _ := parsed.Headers[0].ExtraHeaders[jose.HeaderType].(string); typ != introspectionType {
+		return nil, fmt.Errorf("%w: typ %s", ErrInvalidIntrospection, typ)
+	}
+
+	verified, err := jwt.VerifyWith(m.config, response, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := verified.Payload
+
+	if claims["iss"] != issuer {
+		return nil, fmt.Errorf("%w: iss %v", ErrInvalidIntrospection, claims["iss"])
+	}
+
+	if !containsAudience(claims["aud"], audience) {
+		return nil, fmt.Errorf("%w: aud %v", ErrInvalidIntrospection, claims["aud"])
+	}
+
+	if _, ok := claims["iat"]; !ok {
+		return nil, fmt.Errorf("%w: missing iat", ErrInvalidIntrospection)
+	}
+
+	introspection, ok := claims[introspectionClaim].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidIntrospection, introspectionClaim)
+	}
+
+	return introspection, nil
+}
@@ -0,0 +1,124 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	config *config.Config
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return &Module{config: config.Default(vu)} })
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
+}
+
+const (
+	clientAssertionType     = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	defaultAssertionSeconds = 60
+)
+
+type ClientAssertionOptions struct {
+	Lifetime int                    `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+	Header   map[string]interface{} `js:"header"`
+}
+
+type ClientAssertion struct {
+	Assertion string            `js:"assertion"`
+	Params    map[string]string `js:"params"`
+}
+
+// ClientAssertion creates private_key_jwt client authentication assertion (RFC 7523).
+func (m *Module) ClientAssertion(
+	key *jose.JSONWebKey,
+	clientID string,
+	endpoint string,
+	options *ClientAssertionOptions,
+) (*ClientAssertion, error) {
+	if options == nil {
+		options = &ClientAssertionOptions{}
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultAssertionSeconds
+	}
+
+	now := time.Now()
+
+	claims := jwt.Merge(map[string]interface{}{
+		"iss": clientID,
+		"sub": clientID,
+		"aud": endpoint,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jti": jwt.RandomID(),
+	}, options.Claims)
+
+	assertion, err := jwt.Sign(key, claims, options.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientAssertion{
+		Assertion: assertion,
+		Params: map[string]string{
+			"client_id":             clientID,
+			"client_assertion_type": clientAssertionType,
+			"client_assertion":      assertion,
+		},
+	}, nil
+}
+
+// signingKey returns copy of the key, PEM encoded keys are accepted too.
+func signingKey(in interface{}) (*jose.JSONWebKey, error) {
+	if source, ok := in.(string); ok {
+		return jwk.FromPEM(source)
+	}
+
+	keys, err := jwk.KeySet(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("%w: %d signing keys", jwk.ErrUnsupportedKey, len(keys))
+	}
+
+	return &keys[0], nil
+}
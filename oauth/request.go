@@ -0,0 +1,166 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwe"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrMissingParameter = errors.New("missing request parameter")
+
+const (
+	requestObjectType     = "oauth-authz-req+jwt"
+	defaultRequestSeconds = 300
+)
+
+type RequestObjectOptions struct {
+	Lifetime int                    `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+	Header   map[string]interface{} `js:"header"`
+
+	EncryptionKey interface{}         `js:"encryptionKey"`
+	Encryption    *jwe.EncryptOptions `js:"encryption"`
+}
+
+type RequestObject struct {
+	Request string            `js:"request"`
+	Params  map[string]string `js:"params"`
+}
+
+// RequestObject creates signed authorization request object (RFC 9101).
+func (m *Module) RequestObject(
+	key *jose.JSONWebKey,
+	clientID string,
+	issuer string,
+	params map[string]interface{},
+	options *RequestObjectOptions,
+) (*RequestObject, error) {
+	if options == nil {
+		options = &RequestObjectOptions{}
+	}
+
+	claims, err := requestClaims(clientID, issuer, params, options)
+	if err != nil {
+		return nil, err
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": requestObjectType}, options.Header)
+
+	request, err := jwt.Sign(key, claims, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.EncryptionKey != nil {
+		if request, err = encryptRequest(request, options); err != nil {
+			return nil, err
+		}
+	}
+
+	return &RequestObject{
+		Request: request,
+		Params: map[string]string{
+			"client_id": clientID,
+			"request":   request,
+		},
+	}, nil
+}
+
+func requestClaims(
+	clientID string,
+	issuer string,
+	params map[string]interface{},
+	options *RequestObjectOptions,
+) (map[string]interface{}, error) {
+	for _, name := range []string{"response_type", "redirect_uri"} {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingParameter, name)
+		}
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultRequestSeconds
+	}
+
+	now := time.Now()
+
+	claims := jwt.Merge(map[string]interface{}{
+		"iss":       clientID,
+		"aud":       issuer,
+		"client_id": clientID,
+		"iat":       now.Unix(),
+		"nbf":       now.Unix(),
+		"exp":       now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jti":       jwt.RandomID(),
+		"state":     jwt.RandomID(),
+		"nonce":     jwt.RandomID(),
+	}, params, options.Claims)
+
+	return claims, nil
+}
+
+// encryptRequest encrypts the signed request object (nested JWT) to the encryption key of the given key set.
+func encryptRequest(request string, options *RequestObjectOptions) (string, error) {
+	keys, err := jwk.KeySet(options.EncryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	key := encryptionKey(keys)
+	if key == nil {
+		return "", fmt.Errorf("%w: no encryption key", jwk.ErrUnsupportedKey)
+	}
+
+	opts := jwe.EncryptOptions{}
+	if options.Encryption != nil {
+		opts = *options.Encryption
+	}
+
+	opts.Header = jwt.Merge(map[string]interface{}{"cty": "JWT"}, opts.Header)
+
+	return jwe.Encrypt(key, request, &opts)
+}
+
+// encryptionKey prefers keys with "enc" use, keys without use are accepted too.
+func encryptionKey(keys []jose.JSONWebKey) *jose.JSONWebKey {
+	var key *jose.JSONWebKey
+
+	for i := range keys {
+		switch {
+		case keys[i].Use == "enc":
+			return &keys[i]
+		case keys[i].Use == "" && key == nil:
+			key = &keys[i]
+		}
+	}
+
+	return key
+}
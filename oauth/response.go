@@ -0,0 +1,124 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var (
+	ErrInvalidResponse = errors.New("invalid authorization response")
+	ErrErrorResponse   = errors.New("authorization error response")
+)
+
+type AuthorizationResponseOptions struct {
+	State  string `js:"state"`
+	Leeway int    `js:"leeway"`
+}
+
+type AuthorizationResponse struct {
+	Code   string                 `js:"code"`
+	State  string                 `js:"state"`
+	Claims map[string]interface{} `js:"claims"`
+}
+
+// AuthorizationResponse validates JWT secured authorization response (JARM).
+func (m *Module) AuthorizationResponse(
+	response string,
+	keys interface{},
+	issuer string,
+	clientID string,
+	options *AuthorizationResponseOptions,
+) (*AuthorizationResponse, error) {
+	if options == nil {
+		options = &AuthorizationResponseOptions{}
+	}
+
+	verified, err := jwt.VerifyWith(m.config, response, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := verified.Payload
+
+	if err := validateResponse(claims, issuer, clientID, options); err != nil {
+		return nil, err
+	}
+
+	if e, ok := claims["error"].(string); ok {
+		desc, _ := claims["error_description"].(string)
+
+		return nil, fmt.Errorf("%w: %s %s", ErrErrorResponse, e, desc)
+	}
+
+	code, _ := claims["code"].(string)
+	state, _ := claims["state"].(string)
+
+	return &AuthorizationResponse{Code: code, State: state, Claims: claims}, nil
+}
+
+func validateResponse(
+	claims map[string]interface{},
+	issuer string,
+	clientID string,
+	options *AuthorizationResponseOptions,
+) error {
+	if claims["iss"] != issuer {
+		return fmt.Errorf("%w: iss %v", ErrInvalidResponse, claims["iss"])
+	}
+
+	if !containsAudience(claims["aud"], clientID) {
+		return fmt.Errorf("%w: aud %v", ErrInvalidResponse, claims["aud"])
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Unix() > int64(exp)+int64(options.Leeway) {
+		return fmt.Errorf("%w: expired", ErrInvalidResponse)
+	}
+
+	if options.State != "" && claims["state"] != options.State {
+		return fmt.Errorf("%w: state mismatch", ErrInvalidResponse)
+	}
+
+	return nil
+}
+
+// containsAudience checks aud claim, which is either a single string or an array.
+func containsAudience(aud interface{}, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []interface{}:
+		for _, a := range v {
+			if a == expected {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,120 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidClaim = errors.New("invalid claim")
+
+const (
+	accessTokenType      = "at+jwt"
+	defaultAccessSeconds = 3600
+)
+
+var accessTokenClaims = []string{"iss", "exp", "aud", "sub", "client_id", "iat", "jti"}
+
+type AccessTokenOptions struct {
+	Lifetime     int                    `js:"lifetime"`
+	Scope        []string               `js:"scope"`
+	Groups       []string               `js:"groups"`
+	Roles        []string               `js:"roles"`
+	Entitlements []string               `js:"entitlements"`
+	Header       map[string]interface{} `js:"header"`
+}
+
+// AccessToken creates JWT access token (RFC 9068), the required claims are validated.
+func (m *Module) AccessToken(
+	key *jose.JSONWebKey,
+	claims map[string]interface{},
+	options *AccessTokenOptions,
+) (string, error) {
+	if options == nil {
+		options = &AccessTokenOptions{}
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultAccessSeconds
+	}
+
+	now := time.Now()
+
+	defaults := map[string]interface{}{
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jti": jwt.RandomID(),
+	}
+
+	if len(options.Scope) != 0 {
+		defaults["scope"] = strings.Join(options.Scope, " ")
+	}
+
+	for name, values := range map[string][]string{
+		"groups":       options.Groups,
+		"roles":        options.Roles,
+		"entitlements": options.Entitlements,
+	} {
+		if len(values) != 0 {
+			defaults[name] = values
+		}
+	}
+
+	all := jwt.Merge(defaults, claims)
+
+	if err := validateAccessToken(all); err != nil {
+		return "", err
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": accessTokenType}, options.Header)
+
+	return jwt.Sign(key, all, header)
+}
+
+func validateAccessToken(claims map[string]interface{}) error {
+	for _, name := range accessTokenClaims {
+		if _, ok := claims[name]; !ok {
+			return fmt.Errorf("%w: %s", jwt.ErrMissingClaim, name)
+		}
+	}
+
+	if scope, ok := claims["scope"]; ok {
+		if _, ok := scope.(string); !ok {
+			return fmt.Errorf("%w: scope must be space separated string", ErrInvalidClaim)
+		}
+	}
+
+	switch claims["aud"].(type) {
+	case string, []interface{}:
+		return nil
+	default:
+		return fmt.Errorf("%w: aud must be string or array", ErrInvalidClaim)
+	}
+}
@@ -0,0 +1,162 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/httpclient"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+var ErrDiscovery = errors.New("discovery failed")
+
+const (
+	discoveryPath   = "/.well-known/openid-configuration"
+	maxDocumentSize = 1 << 20
+)
+
+// Issuer is the discovered OpenID provider: its metadata and the keys of its jwks_uri.
+type Issuer struct {
+	Issuer   string                 `js:"issuer"`
+	JWKSURI  string                 `js:"jwksUri"`
+	Metadata map[string]interface{} `js:"metadata"`
+	Keys     []jose.JSONWebKey      `js:"keys"`
+
+	fetched time.Time
+}
+
+// KeySet returns the keys of the issuer, issuers can be passed as verification keys.
+func (i *Issuer) KeySet() []jose.JSONWebKey {
+	return i.Keys
+}
+
+type discoveryEntry struct {
+	mu     sync.Mutex
+	issuer *Issuer
+}
+
+var (
+	discovered   = map[string]*discoveryEntry{}
+	discoveredMu sync.Mutex
+)
+
+// Discover returns the issuer, fetched by the first call (or after key cache TTL) of the issuer URL.
+// Concurrent calls of the VUs wait for the same fetch.
+func Discover(ctx context.Context, client *http.Client, issuer string, refresh bool) (*Issuer, error) {
+	discoveredMu.Lock()
+
+	entry, ok := discovered[issuer]
+	if !ok {
+		entry = &discoveryEntry{}
+		discovered[issuer] = entry
+	}
+
+	discoveredMu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.issuer != nil && !refresh {
+		if ttl := jwk.CacheTTL(); ttl <= 0 || time.Since(entry.issuer.fetched) <= ttl {
+			return entry.issuer, nil
+		}
+	}
+
+	fetched, err := discover(ctx, client, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.issuer = fetched
+
+	return fetched, nil
+}
+
+func discover(ctx context.Context, client *http.Client, issuer string) (*Issuer, error) {
+	metadata := map[string]interface{}{}
+
+	if err := fetchJSON(ctx, client, strings.TrimSuffix(issuer, "/")+discoveryPath, &metadata); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := metadata["issuer"].(string); iss != issuer {
+		return nil, fmt.Errorf("%w: issuer %q of the discovery document", ErrDiscovery, iss)
+	}
+
+	uri, _ := metadata["jwks_uri"].(string)
+	if uri == "" {
+		return nil, fmt.Errorf("%w: missing jwks_uri", ErrDiscovery)
+	}
+
+	set := jose.JSONWebKeySet{}
+
+	if err := fetchJSON(ctx, client, uri, &set); err != nil {
+		return nil, err
+	}
+
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoKeys, uri)
+	}
+
+	return &Issuer{Issuer: issuer, JWKSURI: uri, Metadata: metadata, Keys: set.Keys, fetched: time.Now()}, nil
+}
+
+func fetchJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDiscovery, err.Error())
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDiscovery, err.Error())
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %s", ErrDiscovery, url, res.Status)
+	}
+
+	body, err := httpclient.ReadBody(res.Body, maxDocumentSize)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrDiscovery, url, err.Error())
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrDiscovery, url, err.Error())
+	}
+
+	return nil
+}
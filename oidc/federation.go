@@ -0,0 +1,175 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidEntityStatement = errors.New("invalid entity statement")
+
+const (
+	entityStatementType     = "entity-statement+jwt"
+	defaultStatementSeconds = 86400
+)
+
+type EntityStatementOptions struct {
+	Keys           interface{}            `js:"jwks"`
+	Metadata       map[string]interface{} `js:"metadata"`
+	AuthorityHints []string               `js:"authorityHints"`
+	Lifetime       int                    `js:"lifetime"`
+	Claims         map[string]interface{} `js:"claims"`
+	Header         map[string]interface{} `js:"header"`
+}
+
+type EntityStatement struct {
+	Payload       map[string]interface{} `js:"payload"`
+	Keys          []jose.JSONWebKey      `js:"keys"`
+	Configuration bool                   `js:"configuration"`
+}
+
+// EntityStatement creates entity statement, or entity configuration if issuer and subject are the same.
+func (m *Module) EntityStatement(
+	key *jose.JSONWebKey,
+	issuer string,
+	subject string,
+	options *EntityStatementOptions,
+) (string, error) {
+	if options == nil {
+		options = &EntityStatementOptions{}
+	}
+
+	keys, err := publicKeys(key, issuer == subject, options.Keys)
+	if err != nil {
+		return "", err
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultStatementSeconds
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss":  issuer,
+		"sub":  subject,
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jwks": map[string]interface{}{"keys": keys},
+	}
+
+	if options.Metadata != nil {
+		claims["metadata"] = options.Metadata
+	}
+
+	if len(options.AuthorityHints) != 0 {
+		claims["authority_hints"] = options.AuthorityHints
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": entityStatementType}, options.Header)
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+}
+
+// publicKeys returns subject's public keys, entity configuration defaults to the signing key.
+func publicKeys(key *jose.JSONWebKey, self bool, in interface{}) ([]jose.JSONWebKey, error) {
+	if in == nil {
+		if !self {
+			return nil, fmt.Errorf("%w: missing subject jwks", ErrInvalidEntityStatement)
+		}
+
+		return []jose.JSONWebKey{key.Public()}, nil
+	}
+
+	keys, err := jwk.KeySet(in)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		keys[i] = keys[i].Public()
+	}
+
+	return keys, nil
+}
+
+// VerifyEntityStatement verifies entity statement with the issuer's keys,
+// entity configuration is verified with its own jwks if no keys given.
+func (m *Module) VerifyEntityStatement(token string, keys ...interface{}) (*EntityStatement, error) {
+	parsed, err := jwt.ParseWith(m.config, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ, _ := parsed.Headers[0].ExtraHeaders[jose.HeaderType].(string); typ != entityStatementType {
+		return nil, fmt.Errorf("%w: typ %s", ErrInvalidEntityStatement, typ)
+	}
+
+	claims := map[string]interface{}{}
+
+	if err := parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, err
+	}
+
+	subjectKeys, err := entityKeys(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	configuration := claims["iss"] == claims["sub"]
+
+	if len(keys) == 0 {
+		if !configuration {
+			return nil, fmt.Errorf("%w: missing issuer keys", ErrInvalidEntityStatement)
+		}
+
+		keys = []interface{}{subjectKeys}
+	}
+
+	verified, err := jwt.VerifyWith(m.config, token, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	if exp, ok := verified.Payload["exp"].(float64); !ok || time.Now().Unix() > int64(exp) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidEntityStatement)
+	}
+
+	return &EntityStatement{Payload: verified.Payload, Keys: subjectKeys, Configuration: configuration}, nil
+}
+
+func entityKeys(claims map[string]interface{}) ([]jose.JSONWebKey, error) {
+	keys, err := keySet(claims["jwks"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEntityStatement, err.Error())
+	}
+
+	return keys, nil
+}
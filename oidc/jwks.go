@@ -0,0 +1,144 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var (
+	ErrInvalidSignedJWKS = errors.New("invalid signed jwks")
+	ErrNoKeys            = errors.New("missing keys")
+)
+
+const (
+	signedJWKSType     = "jwk-set+jwt"
+	defaultJWKSSeconds = 86400
+)
+
+type SignedJWKSOptions struct {
+	Subject  string                 `js:"sub"`
+	Lifetime int                    `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+	Header   map[string]interface{} `js:"header"`
+}
+
+// SignedJWKS creates JWT with the public part of the keys as JWKS payload (signed_jwks_uri content).
+func (m *Module) SignedJWKS(
+	key *jose.JSONWebKey,
+	issuer string,
+	keys interface{},
+	options *SignedJWKSOptions,
+) (string, error) {
+	if options == nil {
+		options = &SignedJWKSOptions{}
+	}
+
+	set, err := jwk.KeySet(keys)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range set {
+		set[i] = set[i].Public()
+	}
+
+	subject := options.Subject
+	if subject == "" {
+		subject = issuer
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultJWKSSeconds
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"keys": set,
+		"iss":  issuer,
+		"sub":  subject,
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Duration(lifetime) * time.Second).Unix(),
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": signedJWKSType}, options.Header)
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+}
+
+// VerifySignedJWKS verifies signed JWKS and returns its keys.
+func (m *Module) VerifySignedJWKS(token string, keys ...interface{}) ([]jose.JSONWebKey, error) {
+	parsed, err := jwt.ParseWith(m.config, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ, _ := parsed.Headers[0].ExtraHeaders[jose.HeaderType].(string); typ != signedJWKSType {
+		return nil, fmt.Errorf("%w: typ %s", ErrInvalidSignedJWKS, typ)
+	}
+
+	verified, err := jwt.VerifyWith(m.config, token, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	if exp, ok := verified.Payload["exp"].(float64); ok && time.Now().Unix() > int64(exp) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidSignedJWKS)
+	}
+
+	set, err := keySet(verified.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSignedJWKS, err.Error())
+	}
+
+	return set, nil
+}
+
+// keySet converts decoded JWKS claim value to keys.
+func keySet(in interface{}) ([]jose.JSONWebKey, error) {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	set := jose.JSONWebKeySet{}
+
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	if len(set.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	return set.Keys, nil
+}
@@ -0,0 +1,91 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrMissingSubject = errors.New("logout token requires sub or sid")
+
+const (
+	logoutTokenType      = "logout+jwt"
+	backchannelLogout    = "http://schemas.openid.net/event/backchannel-logout"
+	defaultLogoutSeconds = 120
+)
+
+type LogoutTokenOptions struct {
+	Subject   string                 `js:"sub"`
+	SessionID string                 `js:"sid"`
+	Lifetime  int                    `js:"lifetime"`
+	Claims    map[string]interface{} `js:"claims"`
+	Header    map[string]interface{} `js:"header"`
+}
+
+// LogoutToken creates back-channel logout token, claims and header options are applied last, so they can make it invalid.
+func (m *Module) LogoutToken(
+	key *jose.JSONWebKey,
+	issuer string,
+	audience string,
+	options *LogoutTokenOptions,
+) (string, error) {
+	if options == nil {
+		options = &LogoutTokenOptions{}
+	}
+
+	if options.Subject == "" && options.SessionID == "" {
+		return "", ErrMissingSubject
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultLogoutSeconds
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss":    issuer,
+		"aud":    audience,
+		"iat":    now.Unix(),
+		"exp":    now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jti":    jwt.RandomID(),
+		"events": map[string]interface{}{backchannelLogout: map[string]interface{}{}},
+	}
+
+	if options.Subject != "" {
+		claims["sub"] = options.Subject
+	}
+
+	if options.SessionID != "" {
+		claims["sid"] = options.SessionID
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": logoutTokenType}, options.Header)
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+}
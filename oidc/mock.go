@@ -0,0 +1,208 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+const (
+	defaultMockIssuer   = "https://idp.example.com"
+	defaultMockAudience = "mock-client"
+	defaultMockSubject  = "mock-user"
+	defaultMockAlg      = "RS256"
+	defaultMockKeys     = 2
+	defaultMockLifetime = 3600
+
+	accessTokenType = "at+jwt"
+)
+
+var ErrInvalidMockOptions = errors.New("invalid mock provider options")
+
+type MockProviderOptions struct {
+	Issuer    string `js:"issuer"`
+	Audience  string `js:"audience"`
+	ClientID  string `js:"clientId"`
+	Algorithm string `js:"algorithm"`
+	Keys      int    `js:"keys"`
+	Bits      int    `js:"bits"`
+	Lifetime  int    `js:"lifetime"`
+}
+
+// MockProvider is a fake OpenID provider: signing keys, the documents to serve from stub endpoints and token factories.
+type MockProvider struct {
+	Issuer    string                 `js:"issuer"`
+	JWKSURI   string                 `js:"jwksUri"`
+	JWKS      map[string]interface{} `js:"jwks"`
+	Discovery map[string]interface{} `js:"discovery"`
+	Keys      []jose.JSONWebKey      `js:"keys"`
+
+	audience string
+	clientID string
+	lifetime time.Duration
+	public   []jose.JSONWebKey
+	next     uint64
+}
+
+// MockProvider generates the keys of the provider (concurrently), their JWKS and the discovery document.
+func (m *Module) MockProvider(options *MockProviderOptions) (*MockProvider, error) {
+	if options == nil {
+		options = &MockProviderOptions{}
+	}
+
+	issuer := strings.TrimSuffix(orDefault(options.Issuer, defaultMockIssuer), "/")
+	alg := orDefault(options.Algorithm, defaultMockAlg)
+
+	count := options.Keys
+	if count < 0 {
+		return nil, fmt.Errorf("%w: negative keys %d", ErrInvalidMockOptions, count)
+	}
+
+	if count == 0 {
+		count = defaultMockKeys
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultMockLifetime
+	}
+
+	keys, err := jwk.GenerateKeys(alg, options.Bits, count)
+	if err != nil {
+		return nil, err
+	}
+
+	public := make([]jose.JSONWebKey, len(keys))
+	for i := range keys {
+		public[i] = keys[i].Public()
+	}
+
+	set, err := keySetObject(public)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &MockProvider{
+		Issuer:   issuer,
+		JWKSURI:  issuer + "/.well-known/jwks.json",
+		JWKS:     set,
+		Keys:     keys,
+		audience: orDefault(options.Audience, defaultMockAudience),
+		clientID: orDefault(options.ClientID, orDefault(options.Audience, defaultMockAudience)),
+		lifetime: time.Duration(lifetime) * time.Second,
+		public:   public,
+	}
+
+	provider.Discovery = map[string]interface{}{
+		"issuer":                                issuer,
+		"jwks_uri":                              provider.JWKSURI,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{keys[0].Algorithm},
+	}
+
+	return provider, nil
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+
+	return value
+}
+
+// keySetObject returns the JWKS document of the keys as plain object.
+func keySetObject(keys []jose.JSONWebKey) (map[string]interface{}, error) {
+	raw, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]interface{}{}
+
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// KeySet returns the public keys, providers can be passed as verification keys.
+func (p *MockProvider) KeySet() []jose.JSONWebKey {
+	return p.public
+}
+
+// Sign signs the claims with the next key (round-robin), so consecutive tokens have rotating kids.
+func (p *MockProvider) Sign(claims, header map[string]interface{}) (string, error) {
+	idx := (atomic.AddUint64(&p.next, 1) - 1) % uint64(len(p.Keys))
+
+	return jwt.Sign(&p.Keys[idx], claims, header)
+}
+
+// IssueIDToken issues ID token with iss, sub, aud, iat, exp, auth_time and nonce claims, the claims are applied last.
+func (p *MockProvider) IssueIDToken(claims map[string]interface{}) (string, error) {
+	now := time.Now()
+
+	defaults := map[string]interface{}{
+		"iss":       p.Issuer,
+		"sub":       defaultMockSubject,
+		"aud":       p.audience,
+		"iat":       now.Unix(),
+		"exp":       now.Add(p.lifetime).Unix(),
+		"auth_time": now.Unix(),
+		"nonce":     jwt.RandomID(),
+	}
+
+	return p.Sign(jwt.Merge(defaults, claims), nil)
+}
+
+// IssueAccessToken issues JWT access token (RFC 9068) with iss, sub, aud, client_id, iat, exp and jti claims,
+// the claims are applied last.
+func (p *MockProvider) IssueAccessToken(claims map[string]interface{}) (string, error) {
+	now := time.Now()
+
+	defaults := map[string]interface{}{
+		"iss":       p.Issuer,
+		"sub":       defaultMockSubject,
+		"aud":       p.audience,
+		"client_id": p.clientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(p.lifetime).Unix(),
+		"jti":       jwt.RandomID(),
+	}
+
+	return p.Sign(jwt.Merge(defaults, claims), map[string]interface{}{"typ": accessTokenType})
+}
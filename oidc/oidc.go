@@ -0,0 +1,86 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	config *config.Config
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return &Module{config: config.Default(vu)} })
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
+}
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+
+func (m *Module) AtHash(accessToken string, alg string) (string, error) {
+	return halfHash(accessToken, alg)
+}
+
+func (m *Module) CHash(code string, alg string) (string, error) {
+	return halfHash(code, alg)
+}
+
+func (m *Module) SHash(state string, alg string) (string, error) {
+	return halfHash(state, alg)
+}
+
+// halfHashes are the hash functions of the ID token algorithms, ES256K uses SHA-256 (RFC 8812).
+var halfHashes = map[string]func() hash.Hash{
+	"HS256": sha256.New, "RS256": sha256.New, "PS256": sha256.New, "ES256": sha256.New, "ES256K": sha256.New,
+	"HS384": sha512.New384, "RS384": sha512.New384, "PS384": sha512.New384, "ES384": sha512.New384,
+	"HS512": sha512.New, "RS512": sha512.New, "PS512": sha512.New, "ES512": sha512.New,
+	"ESB256": sha256.New, "ESB384": sha512.New384, "ESB512": sha512.New,
+	"EdDSA": sha512.New,
+}
+
+// halfHash returns base64url encoded left-most half of the hash of value, hash function is the one used by the alg of the ID token.
+func halfHash(value string, alg string) (string, error) {
+	newHash, ok := halfHashes[alg]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	h := newHash()
+	h.Write([]byte(value))
+	sum := h.Sum(nil)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2]), nil
+}
@@ -0,0 +1,385 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrUnknownIssuer = errors.New("unknown issuer")
+
+const defaultIssuerSeconds = 3600
+
+type IssuerOptions struct {
+	Issuer   string                 `js:"issuer"`
+	Keys     interface{}            `js:"keys"`
+	JWKS     interface{}            `js:"jwks"`
+	Discover bool                   `js:"discover"`
+	CacheTTL interface{}            `js:"cacheTTL"`
+	Audience string                 `js:"audience"`
+	Lifetime interface{}            `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+	Header   map[string]interface{} `js:"header"`
+}
+
+// Registry is the registry of named issuers shared by every VU, with their signing keys, verification keys
+// and token defaults. Issuers are registered usually in init code, tokens are signed and verified by issuer name.
+type Registry struct {
+	jwt      *jwt.Module
+	config   *config.Config
+	fetch    func(source string) ([]jose.JSONWebKey, error)
+	discover func(issuer string) (*Issuer, error)
+}
+
+// registeredIssuer is an issuer of the registry, the keys of the jwks URL (or discovery) are fetched on first use
+// and after its cache TTL.
+type registeredIssuer struct {
+	issuer   string
+	audience string
+	lifetime time.Duration
+	claims   map[string]interface{}
+	header   map[string]interface{}
+
+	signing []jose.JSONWebKey
+	static  []jose.JSONWebKey
+	source  string
+	ttl     *time.Duration
+
+	mu        sync.Mutex
+	fetched   []jose.JSONWebKey
+	fetchedAt time.Time
+}
+
+var (
+	registered   = map[string]*registeredIssuer{}
+	registeredMu sync.RWMutex
+)
+
+// NewRegistry returns the registry of a VU, fetching the jwks URL and discovering the issuers with the given functions.
+func NewRegistry(
+	jwtModule *jwt.Module,
+	cfg *config.Config,
+	fetch func(source string) ([]jose.JSONWebKey, error),
+	discover func(issuer string) (*Issuer, error),
+) *Registry {
+	return &Registry{jwt: jwtModule, config: cfg, fetch: fetch, discover: discover}
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (r *Registry) Resolve() error {
+	return r.config.Resolve()
+}
+
+// Register adds the issuer with name, replacing the previous one. The issuer identifier defaults to the name.
+func (r *Registry) Register(name string, options *IssuerOptions) error {
+	if len(name) == 0 {
+		return jwk.ErrInvalidName
+	}
+
+	if options == nil {
+		options = &IssuerOptions{}
+	}
+
+	entry, err := newRegisteredIssuer(name, options)
+	if err != nil {
+		return err
+	}
+
+	registeredMu.Lock()
+	registered[name] = entry
+	registeredMu.Unlock()
+
+	return nil
+}
+
+func newRegisteredIssuer(name string, options *IssuerOptions) (*registeredIssuer, error) {
+	entry := &registeredIssuer{
+		issuer:   options.Issuer,
+		audience: options.Audience,
+		lifetime: defaultIssuerSeconds * time.Second,
+		claims:   jwt.Merge(map[string]interface{}{}, options.Claims),
+		header:   jwt.Merge(map[string]interface{}{}, options.Header),
+	}
+
+	if entry.issuer == "" {
+		entry.issuer = name
+	}
+
+	var err error
+
+	if options.Lifetime != nil {
+		if entry.lifetime, err = jwt.Duration(options.Lifetime); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.CacheTTL != nil {
+		ttl, err := jwt.Duration(options.CacheTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.ttl = &ttl
+	}
+
+	if options.Keys != nil {
+		if entry.signing, err = jwk.KeySet(options.Keys); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range entry.signing {
+		entry.static = append(entry.static, verificationKey(&entry.signing[i]))
+	}
+
+	if source, ok := jwksSource(options.JWKS); ok {
+		entry.source = source
+	} else if options.JWKS != nil {
+		keys, err := jwk.KeySet(options.JWKS)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.static = append(entry.static, keys...)
+	}
+
+	if options.Discover {
+		if entry.source != "" {
+			return nil, fmt.Errorf("%w: jwks URL and discover are exclusive", config.ErrInvalidConfig)
+		}
+
+		entry.source = discoverySource
+	}
+
+	if len(entry.static) == 0 && entry.source == "" {
+		return nil, fmt.Errorf("%w: issuer %s", ErrNoKeys, name)
+	}
+
+	return entry, nil
+}
+
+// jwksSource returns the jwks option if it is an URL or data URI, to be fetched instead of parsed.
+func jwksSource(in interface{}) (string, bool) {
+	source, ok := in.(string)
+
+	return source, ok && (strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "data:"))
+}
+
+// discoverySource is the source of issuers with keys of the discovered jwks_uri.
+const discoverySource = "discover"
+
+// verificationKey returns the public part of the key, symmetric keys are returned as is.
+func verificationKey(key *jose.JSONWebKey) jose.JSONWebKey {
+	if public := key.Public(); public.Valid() {
+		return public
+	}
+
+	return *key
+}
+
+func (r *Registry) lookup(name string) (*registeredIssuer, error) {
+	registeredMu.RLock()
+	entry, ok := registered[name]
+	registeredMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIssuer, name)
+	}
+
+	return entry, nil
+}
+
+// Sign creates JWT signed with the first key of the issuer. The iss, aud (if configured), iat, exp and random jti
+// claims come first, then the default claims of the issuer and the claims.
+func (r *Registry) Sign(
+	name string,
+	claims map[string]interface{},
+	header map[string]interface{},
+	options *jwt.SignOptions,
+) (string, error) {
+	entry, err := r.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entry.signing) == 0 {
+		return "", fmt.Errorf("%w: no signing key of issuer %s", jwk.ErrMissingKey, name)
+	}
+
+	opts := jwt.SignOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	if opts.Lifetime <= 0 {
+		opts.Lifetime = int(entry.lifetime / time.Second)
+	}
+
+	now, err := r.config.OptionTime(opts.Now)
+	if err != nil {
+		return "", err
+	}
+
+	defaults := map[string]interface{}{
+		"iss": entry.issuer,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(opts.Lifetime) * time.Second).Unix(),
+		"jti": jwt.RandomID(),
+	}
+
+	if entry.audience != "" {
+		defaults["aud"] = entry.audience
+	}
+
+	key := entry.signing[0]
+
+	return r.jwt.Sign(&key, jwt.Merge(defaults, entry.claims, claims), jwt.Merge(entry.header, header), &opts)
+}
+
+// Verify verifies the JWT with the keys of the issuer, the issuer identifier and the configured audience
+// are the default expected iss and aud.
+func (r *Registry) Verify(name string, token string, options *jwt.VerifyOptions) (map[string]interface{}, error) {
+	entry, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := entry.keys(r)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := jwt.VerifyOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	if opts.Issuer == "" {
+		opts.Issuer = entry.issuer
+	}
+
+	if opts.Audience == "" {
+		opts.Audience = entry.audience
+	}
+
+	return r.jwt.VerifyClaims(token, keys, &opts)
+}
+
+// Keys returns the verification keys of the issuer, fetched from the jwks URL (or discovered) when needed.
+func (r *Registry) Keys(name string) ([]jose.JSONWebKey, error) {
+	entry, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.keys(r)
+}
+
+func (r *Registry) Has(name string) bool {
+	_, err := r.lookup(name)
+
+	return err == nil
+}
+
+// Delete removes the issuer, returns false if there was none.
+func (r *Registry) Delete(name string) bool {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	_, ok := registered[name]
+	delete(registered, name)
+
+	return ok
+}
+
+// Names returns the sorted names of the registered issuers.
+func (r *Registry) Names() []string {
+	registeredMu.RLock()
+	names := make([]string, 0, len(registered))
+
+	for name := range registered {
+		names = append(names, name)
+	}
+
+	registeredMu.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+// keys returns the static keys and the fetched ones, concurrent calls of the VUs wait for the same fetch.
+func (i *registeredIssuer) keys(r *Registry) ([]jose.JSONWebKey, error) {
+	keys := append(make([]jose.JSONWebKey, 0, len(i.static)), i.static...)
+
+	if i.source == "" {
+		return keys, nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.fetched == nil || i.expired() {
+		fetched, err := i.fetch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		i.fetched, i.fetchedAt = fetched, time.Now()
+	}
+
+	return append(keys, i.fetched...), nil
+}
+
+// expired reports whether the fetched keys are older than the cache TTL of the issuer (or of the key caches).
+func (i *registeredIssuer) expired() bool {
+	ttl := jwk.CacheTTL()
+	if i.ttl != nil {
+		ttl = *i.ttl
+	}
+
+	return ttl > 0 && time.Since(i.fetchedAt) > ttl
+}
+
+func (i *registeredIssuer) fetch(r *Registry) ([]jose.JSONWebKey, error) {
+	if i.source != discoverySource {
+		return r.fetch(i.source)
+	}
+
+	discovered, err := r.discover(i.issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return discovered.Keys, nil
+}
@@ -0,0 +1,229 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var (
+	ErrInvalidServiceAccountToken = errors.New("invalid service account token")
+	ErrMissingIssuer              = errors.New("missing issuer")
+	ErrInvalidBinding             = errors.New("invalid binding")
+)
+
+const (
+	kubernetesClaim       = "kubernetes.io"
+	serviceAccountSubject = "system:serviceaccount:"
+)
+
+type ServiceAccountTokenOptions struct {
+	Issuer         string      `js:"issuer"`
+	Audience       string      `js:"audience"`
+	Namespace      string      `js:"namespace"`
+	ServiceAccount string      `js:"serviceAccount"`
+	Binding        string      `js:"binding"`
+	Leeway         interface{} `js:"leeway"`
+}
+
+// ObjectRef is a Kubernetes object reference of the kubernetes.io claim.
+type ObjectRef struct {
+	Name string `json:"name" js:"name"`
+	UID  string `json:"uid"  js:"uid"`
+}
+
+// ServiceAccountToken is the verified Kubernetes service account token, with the identity of the TokenReview API.
+type ServiceAccountToken struct {
+	Username       string                 `js:"username"`
+	Namespace      string                 `js:"namespace"`
+	ServiceAccount ObjectRef              `js:"serviceAccount"`
+	Pod            *ObjectRef             `js:"pod"`
+	Secret         *ObjectRef             `js:"secret"`
+	Node           *ObjectRef             `js:"node"`
+	WarnAfter      int64                  `js:"warnAfter"`
+	Claims         map[string]interface{} `js:"claims"`
+}
+
+// kubernetesClaims is the kubernetes.io claim of bound (projected) service account tokens.
+type kubernetesClaims struct {
+	Namespace      string     `json:"namespace"`
+	ServiceAccount ObjectRef  `json:"serviceaccount"`
+	Pod            *ObjectRef `json:"pod"`
+	Secret         *ObjectRef `json:"secret"`
+	Node           *ObjectRef `json:"node"`
+	WarnAfter      int64      `json:"warnafter"`
+}
+
+// VerifyServiceAccountToken verifies bound service account token of a Kubernetes cluster. Keys are usually the
+// discovered (or mock) issuer of the cluster, its issuer identifier is the default expected issuer.
+func (m *Module) VerifyServiceAccountToken(
+	token string,
+	keys interface{},
+	options *ServiceAccountTokenOptions,
+) (*ServiceAccountToken, error) {
+	if options == nil {
+		options = &ServiceAccountTokenOptions{}
+	}
+
+	issuer := options.Issuer
+	if issuer == "" {
+		switch provider := keys.(type) {
+		case *Issuer:
+			issuer = provider.Issuer
+		case *MockProvider:
+			issuer = provider.Issuer
+		}
+	}
+
+	if issuer == "" {
+		return nil, ErrMissingIssuer
+	}
+
+	leeway := josejwt.DefaultLeeway
+
+	if options.Leeway != nil {
+		var err error
+
+		if leeway, err = jwt.Duration(options.Leeway); err != nil {
+			return nil, err
+		}
+	}
+
+	verified, err := jwt.VerifyWith(m.config, token, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := jwt.ParseWith(m.config, token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := josejwt.Claims{}
+
+	if err := parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, err
+	}
+
+	if claims.Expiry == nil {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidServiceAccountToken)
+	}
+
+	expected := josejwt.Expected{Issuer: issuer, Time: time.Now()}
+
+	if options.Audience != "" {
+		expected.AnyAudience = josejwt.Audience{options.Audience}
+	}
+
+	if err := claims.ValidateWithLeeway(expected, leeway); err != nil {
+		return nil, err
+	}
+
+	k8s, err := serviceAccountClaims(verified.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	username := serviceAccountSubject + k8s.Namespace + ":" + k8s.ServiceAccount.Name
+	if claims.Subject != username {
+		return nil, fmt.Errorf("%w: sub %s", ErrInvalidServiceAccountToken, claims.Subject)
+	}
+
+	if err := checkServiceAccount(k8s, options); err != nil {
+		return nil, err
+	}
+
+	return &ServiceAccountToken{
+		Username:       username,
+		Namespace:      k8s.Namespace,
+		ServiceAccount: k8s.ServiceAccount,
+		Pod:            k8s.Pod,
+		Secret:         k8s.Secret,
+		Node:           k8s.Node,
+		WarnAfter:      k8s.WarnAfter,
+		Claims:         verified.Payload,
+	}, nil
+}
+
+func serviceAccountClaims(payload map[string]interface{}) (*kubernetesClaims, error) {
+	raw, ok := payload[kubernetesClaim].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidServiceAccountToken, kubernetesClaim)
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	k8s := &kubernetesClaims{}
+
+	if err := json.Unmarshal(data, k8s); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidServiceAccountToken, err.Error())
+	}
+
+	if k8s.Namespace == "" || k8s.ServiceAccount.Name == "" || k8s.ServiceAccount.UID == "" {
+		return nil, fmt.Errorf("%w: incomplete %s claim", ErrInvalidServiceAccountToken, kubernetesClaim)
+	}
+
+	return k8s, nil
+}
+
+// checkServiceAccount compares the identity and the bound object of the token with the expected ones.
+func checkServiceAccount(k8s *kubernetesClaims, options *ServiceAccountTokenOptions) error {
+	if options.Namespace != "" && k8s.Namespace != options.Namespace {
+		return fmt.Errorf("%w: namespace %s", ErrInvalidServiceAccountToken, k8s.Namespace)
+	}
+
+	if options.ServiceAccount != "" && k8s.ServiceAccount.Name != options.ServiceAccount {
+		return fmt.Errorf("%w: service account %s", ErrInvalidServiceAccountToken, k8s.ServiceAccount.Name)
+	}
+
+	var bound *ObjectRef
+
+	switch strings.ToLower(options.Binding) {
+	case "":
+		return nil
+	case "pod":
+		bound = k8s.Pod
+	case "secret":
+		bound = k8s.Secret
+	case "node":
+		bound = k8s.Node
+	default:
+		return fmt.Errorf("%w: %s, expected pod, secret or node", ErrInvalidBinding, options.Binding)
+	}
+
+	if bound == nil || bound.Name == "" || bound.UID == "" {
+		return fmt.Errorf("%w: not bound to %s", ErrInvalidServiceAccountToken, options.Binding)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package passport
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/internal/uuid"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	config *config.Config
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return &Module{config: config.Default(vu)} })
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
+}
+
+var ErrInvalidPASSporT = errors.New("invalid PASSporT")
+
+const (
+	passportType      = "passport"
+	shakenExtension   = "shaken"
+	defaultMaxSeconds = 60
+)
+
+type SignOptions struct {
+	Attest    string                 `js:"attest"`
+	OrigID    string                 `js:"origid"`
+	Extension string                 `js:"ppt"`
+	Claims    map[string]interface{} `js:"claims"`
+	Header    map[string]interface{} `js:"header"`
+}
+
+type Signed struct {
+	Token    string `js:"token"`
+	Identity string `js:"identity"`
+}
+
+type VerifyOptions struct {
+	Extension string `js:"ppt"`
+	MaxAge    int    `js:"maxAge"`
+}
+
+type Verified struct {
+	Header  map[string]interface{} `js:"header"`
+	Payload map[string]interface{} `js:"payload"`
+}
+
+// Sign creates PASSporT (RFC 8225) with SHAKEN extension (RFC 8588) by default.
+func (m *Module) Sign(
+	key *jose.JSONWebKey,
+	x5u string,
+	orig interface{},
+	dest interface{},
+	options *SignOptions,
+) (*Signed, error) {
+	if options == nil {
+		options = &SignOptions{}
+	}
+
+	ppt := options.Extension
+	if ppt == "" {
+		ppt = shakenExtension
+	}
+
+	claims := map[string]interface{}{
+		"iat":  time.Now().Unix(),
+		"orig": identity(orig, false),
+		"dest": identity(dest, true),
+	}
+
+	if ppt == shakenExtension {
+		if !validAttestation(options.Attest) {
+			return nil, fmt.Errorf("%w: attest %q", ErrInvalidPASSporT, options.Attest)
+		}
+
+		origid := options.OrigID
+		if origid == "" {
+			var err error
+
+			if origid, err = uuid.New(); err != nil {
+				return nil, err
+			}
+		}
+
+		claims["attest"] = options.Attest
+		claims["origid"] = origid
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": passportType, "ppt": ppt, "x5u": x5u}, options.Header)
+
+	token, err := jwt.Sign(key, jwt.Merge(claims, options.Claims), header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signed{
+		Token:    token,
+		Identity: fmt.Sprintf("%s;info=<%s>;alg=%s;ppt=%s", token, x5u, key.Algorithm, ppt),
+	}, nil
+}
+
+// Verify verifies PASSporT signature, header parameters, freshness and the required claims of the extension.
+func (m *Module) Verify(token string, keys interface{}, options *VerifyOptions) (*Verified, error) {
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+
+	maxAge := options.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultMaxSeconds
+	}
+
+	parsed, err := jwt.ParseWith(m.config, token)
+	if err != nil {
+		return nil, err
+	}
+
+	header := parsed.Headers[0]
+
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != passportType {
+		return nil, fmt.Errorf("%w: typ %s", ErrInvalidPASSporT, typ)
+	}
+
+	// the extension of the token is checked, it must be the expected one only if it's given
+	ppt, _ := header.ExtraHeaders["ppt"].(string)
+	if options.Extension != "" && ppt != options.Extension {
+		return nil, fmt.Errorf("%w: ppt %s", ErrInvalidPASSporT, ppt)
+	}
+
+	x5u, _ := header.ExtraHeaders["x5u"].(string)
+	if x5u == "" {
+		return nil, fmt.Errorf("%w: missing x5u", ErrInvalidPASSporT)
+	}
+
+	if ppt == shakenExtension && header.Algorithm != string(jose.ES256) {
+		return nil, fmt.Errorf("%w: alg %s", ErrInvalidPASSporT, header.Algorithm)
+	}
+
+	verified, err := jwt.VerifyWith(m.config, token, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := verified.Payload
+
+	if err := checkClaims(claims, ppt, maxAge); err != nil {
+		return nil, err
+	}
+
+	res := &Verified{
+		Header:  map[string]interface{}{"alg": header.Algorithm, "typ": passportType, "x5u": x5u},
+		Payload: claims,
+	}
+
+	if ppt != "" {
+		res.Header["ppt"] = ppt
+	}
+
+	return res, nil
+}
+
+func checkClaims(claims map[string]interface{}, ppt string, maxAge int) error {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing iat", ErrInvalidPASSporT)
+	}
+
+	if age := time.Since(time.Unix(int64(iat), 0)); age > time.Duration(maxAge)*time.Second ||
+		-age > time.Duration(maxAge)*time.Second {
+		return fmt.Errorf("%w: iat %d is not fresh", ErrInvalidPASSporT, int64(iat))
+	}
+
+	for _, name := range []string{"orig", "dest"} {
+		if _, ok := claims[name].(map[string]interface{}); !ok {
+			return fmt.Errorf("%w: missing %s", ErrInvalidPASSporT, name)
+		}
+	}
+
+	if ppt != shakenExtension {
+		return nil
+	}
+
+	if attest, _ := claims["attest"].(string); !validAttestation(attest) {
+		return fmt.Errorf("%w: attest %v", ErrInvalidPASSporT, claims["attest"])
+	}
+
+	if origid, _ := claims["origid"].(string); origid == "" {
+		return fmt.Errorf("%w: missing origid", ErrInvalidPASSporT)
+	}
+
+	return nil
+}
+
+// identity returns orig or dest claim, telephone numbers are converted to tn objects.
+func identity(in interface{}, multiple bool) interface{} {
+	switch val := in.(type) {
+	case string:
+		if multiple {
+			return map[string]interface{}{"tn": []interface{}{val}}
+		}
+
+		return map[string]interface{}{"tn": val}
+	case []interface{}:
+		return map[string]interface{}{"tn": val}
+	}
+
+	return in
+}
+
+func validAttestation(attest string) bool {
+	return attest == "A" || attest == "B" || attest == "C"
+}
@@ -0,0 +1,218 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package registry
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/buffer"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct{}
+
+func New() modules.Module {
+	return jsmodule.New(func(modules.VU) interface{} { return &Module{} })
+}
+
+var (
+	ErrInvalidScope       = errors.New("invalid scope")
+	ErrInvalidCertificate = errors.New("invalid certificate")
+)
+
+const defaultTokenSeconds = 300
+
+type TokenOptions struct {
+	Certificate interface{}            `js:"cert"`
+	Lifetime    int                    `js:"lifetime"`
+	Claims      map[string]interface{} `js:"claims"`
+	Header      map[string]interface{} `js:"header"`
+}
+
+// Token creates Docker Registry v2 bearer token with access claim, kid is the libtrust key ID.
+func (m *Module) Token(
+	key *jose.JSONWebKey,
+	issuer string,
+	service string,
+	subject string,
+	access []interface{},
+	options *TokenOptions,
+) (string, error) {
+	if options == nil {
+		options = &TokenOptions{}
+	}
+
+	grants, err := accessClaim(access)
+	if err != nil {
+		return "", err
+	}
+
+	var kid string
+
+	if options.Certificate != nil {
+		kid, err = certificateKeyID(options.Certificate)
+	} else {
+		kid, err = keyID(key.Public().Key)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultTokenSeconds
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss":    issuer,
+		"sub":    subject,
+		"aud":    service,
+		"iat":    now.Unix(),
+		"nbf":    now.Unix(),
+		"exp":    now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jti":    jwt.RandomID(),
+		"access": grants,
+	}
+
+	signing := *key
+	signing.KeyID = kid
+
+	return jwt.Sign(&signing, jwt.Merge(claims, options.Claims), options.Header)
+}
+
+// KeyID returns the libtrust key ID of the key or the PEM/DER certificate.
+func (m *Module) KeyID(in interface{}) (string, error) {
+	switch key := in.(type) {
+	case *jose.JSONWebKey:
+		return keyID(key.Public().Key)
+	case jose.JSONWebKey:
+		return keyID(key.Public().Key)
+	}
+
+	return certificateKeyID(in)
+}
+
+// Scope parses the scope parameter of token request (type:name:actions) to access claim entry.
+func (m *Module) Scope(scope string) (map[string]interface{}, error) {
+	return parseScope(scope)
+}
+
+func accessClaim(access []interface{}) ([]interface{}, error) {
+	grants := make([]interface{}, 0, len(access))
+
+	for _, item := range access {
+		if scope, ok := item.(string); ok {
+			grant, err := parseScope(scope)
+			if err != nil {
+				return nil, err
+			}
+
+			grants = append(grants, grant)
+
+			continue
+		}
+
+		grant, ok := item.(map[string]interface{})
+		if !ok || grant["type"] == nil || grant["name"] == nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidScope, item)
+		}
+
+		if grant["actions"] == nil {
+			grant = jwt.Merge(grant, map[string]interface{}{"actions": []interface{}{}})
+		}
+
+		grants = append(grants, grant)
+	}
+
+	return grants, nil
+}
+
+// parseScope splits scope at the first and the last colon, the name may contain registry host with port.
+func parseScope(scope string) (map[string]interface{}, error) {
+	first := strings.Index(scope, ":")
+	last := strings.LastIndex(scope, ":")
+
+	if first <= 0 || last == first || last == first+1 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+	}
+
+	actions := []interface{}{}
+
+	for _, action := range strings.Split(scope[last+1:], ",") {
+		if action != "" {
+			actions = append(actions, action)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":    scope[:first],
+		"name":    scope[first+1 : last],
+		"actions": actions,
+	}, nil
+}
+
+func certificateKeyID(in interface{}) (string, error) {
+	der, err := buffer.Certificate(in)
+	if err != nil {
+		return "", err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidCertificate, err.Error())
+	}
+
+	return keyID(cert.PublicKey)
+}
+
+// keyID returns the truncated (240 bit) SHA-256 hash of the DER encoded public key in base32, grouped by 4 characters.
+func keyID(pub interface{}) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", jwk.ErrUnsupportedKey, err.Error())
+	}
+
+	sum := sha256.Sum256(der)
+	enc := base32.StdEncoding.EncodeToString(sum[:30])
+
+	groups := make([]string, 0, len(enc)/4)
+
+	for i := 0; i < len(enc); i += 4 {
+		groups = append(groups, enc[i:i+4])
+	}
+
+	return strings.Join(groups, ":"), nil
+}
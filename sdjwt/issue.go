@@ -0,0 +1,234 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package sdjwt
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+type IssueOptions struct {
+	Disclose  []string               `js:"disclose"`
+	Algorithm string                 `js:"alg"`
+	Decoys    int                    `js:"decoys"`
+	Holder    *jose.JSONWebKey       `js:"holder"`
+	Header    map[string]interface{} `js:"header"`
+}
+
+type Issued struct {
+	Token       string   `js:"token"`
+	JWT         string   `js:"jwt"`
+	Disclosures []string `js:"disclosures"`
+}
+
+// Issue creates SD-JWT, claims at the given paths are replaced with digests of their disclosures.
+func (m *Module) Issue(key *jose.JSONWebKey, claims map[string]interface{}, options *IssueOptions) (*Issued, error) {
+	if options == nil {
+		options = &IssueOptions{}
+	}
+
+	alg := options.Algorithm
+	if alg == "" {
+		alg = defaultAlgorithm
+	}
+
+	if _, err := newHash(alg); err != nil {
+		return nil, err
+	}
+
+	payload := copyValue(claims).(map[string]interface{})
+
+	c := &concealer{alg: alg, decoys: options.Decoys}
+
+	// deepest paths first, so nested disclosures are part of the enclosing disclosure
+	paths := append([]string{}, options.Disclose...)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return strings.Count(paths[i], ".") > strings.Count(paths[j], ".")
+	})
+
+	for _, path := range paths {
+		if err := c.conceal(payload, strings.Split(path, ".")); err != nil {
+			return nil, err
+		}
+	}
+
+	payload[sdAlgClaim] = alg
+
+	if options.Holder != nil {
+		payload["cnf"] = map[string]interface{}{"jwk": options.Holder.Public()}
+	}
+
+	header := jwt.Merge(map[string]interface{}{"typ": defaultType}, options.Header)
+
+	token, err := jwt.Sign(key, payload, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Issued{
+		Token:       strings.Join(append([]string{token}, c.disclosures...), separator) + separator,
+		JWT:         token,
+		Disclosures: c.disclosures,
+	}, nil
+}
+
+type concealer struct {
+	alg         string
+	decoys      int
+	disclosures []string
+}
+
+func (c *concealer) conceal(obj map[string]interface{}, path []string) error {
+	var parent interface{} = obj
+
+	for _, name := range path[:len(path)-1] {
+		next, err := child(parent, name)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, strings.Join(path, "."))
+		}
+
+		parent = next
+	}
+
+	name := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		value, ok := p[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrInvalidPath, strings.Join(path, "."))
+		}
+
+		dig, err := c.disclose(name, value)
+		if err != nil {
+			return err
+		}
+
+		delete(p, name)
+
+		sd, ok := p[sdClaim].([]interface{})
+		if !ok {
+			if sd, err = c.decoyDigests(); err != nil {
+				return err
+			}
+		}
+
+		sd = append(sd, dig)
+
+		// sorted to hide the original order
+		sort.Slice(sd, func(i, j int) bool { return sd[i].(string) < sd[j].(string) })
+
+		p[sdClaim] = sd
+	case []interface{}:
+		idx, err := strconv.Atoi(name)
+		if err != nil || idx < 0 || idx >= len(p) {
+			return fmt.Errorf("%w: %s", ErrInvalidPath, strings.Join(path, "."))
+		}
+
+		dig, err := c.disclose("", p[idx])
+		if err != nil {
+			return err
+		}
+
+		p[idx] = map[string]interface{}{arrayElement: dig}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidPath, strings.Join(path, "."))
+	}
+
+	return nil
+}
+
+func (c *concealer) disclose(name string, value interface{}) (string, error) {
+	disc, err := disclosure(name, value)
+	if err != nil {
+		return "", err
+	}
+
+	c.disclosures = append(c.disclosures, disc)
+
+	return digest(c.alg, disc)
+}
+
+func (c *concealer) decoyDigests() ([]interface{}, error) {
+	sd := make([]interface{}, 0, c.decoys+1)
+
+	for i := 0; i < c.decoys; i++ {
+		buff := make([]byte, 32)
+
+		if _, err := rand.Read(buff); err != nil {
+			return nil, err
+		}
+
+		dig, err := digest(c.alg, string(buff))
+		if err != nil {
+			return nil, err
+		}
+
+		sd = append(sd, dig)
+	}
+
+	return sd, nil
+}
+
+func child(parent interface{}, name string) (interface{}, error) {
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if v, ok := p[name]; ok {
+			return v, nil
+		}
+	case []interface{}:
+		if idx, err := strconv.Atoi(name); err == nil && idx >= 0 && idx < len(p) {
+			return p[idx], nil
+		}
+	}
+
+	return nil, ErrInvalidPath
+}
+
+// copyValue returns deep copy of JSON like values.
+func copyValue(in interface{}) interface{} {
+	switch v := in.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[k] = copyValue(val)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = copyValue(val)
+		}
+
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,185 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package sdjwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrMalformed = errors.New("malformed sd-jwt")
+
+const keyBindingType = "kb+jwt"
+
+type PresentOptions struct {
+	Disclose []string               `js:"disclose"`
+	Nonce    string                 `js:"nonce"`
+	Audience string                 `js:"aud"`
+	Claims   map[string]interface{} `js:"claims"`
+}
+
+// OptionalKeys of the module, presentations without holder key have no key binding JWT.
+func (m *Module) OptionalKeys() map[string][]int {
+	return map[string][]int{"present": {1}}
+}
+
+// Present creates SD-JWT presentation with the selected disclosures and key binding JWT signed by the holder's key.
+func (m *Module) Present(token string, holder *jose.JSONWebKey, options *PresentOptions) (string, error) {
+	if options == nil {
+		options = &PresentOptions{}
+	}
+
+	issuerJWT, disclosures, _, err := split(token)
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{issuerJWT}
+
+	for _, d := range disclosures {
+		if options.Disclose != nil {
+			name, value, err := decodeDisclosure(d)
+			if err != nil {
+				return "", err
+			}
+
+			if name == "" {
+				name = elementName(value)
+			}
+
+			if !contains(options.Disclose, name) {
+				continue
+			}
+		}
+
+		parts = append(parts, d)
+	}
+
+	presentation := strings.Join(parts, separator) + separator
+
+	if holder == nil {
+		return presentation, nil
+	}
+
+	payload, err := unverifiedPayload(issuerJWT)
+	if err != nil {
+		return "", err
+	}
+
+	alg, _ := payload[sdAlgClaim].(string)
+	if alg == "" {
+		alg = defaultAlgorithm
+	}
+
+	sdHash, err := digest(alg, presentation)
+	if err != nil {
+		return "", err
+	}
+
+	claims := map[string]interface{}{"iat": time.Now().Unix(), "sd_hash": sdHash}
+
+	if options.Nonce != "" {
+		claims["nonce"] = options.Nonce
+	}
+
+	if options.Audience != "" {
+		claims["aud"] = options.Audience
+	}
+
+	kb, err := jwt.Sign(holder, jwt.Merge(claims, options.Claims), map[string]interface{}{"typ": keyBindingType})
+	if err != nil {
+		return "", err
+	}
+
+	return presentation + kb, nil
+}
+
+// split returns the issuer-signed JWT, the disclosures and the key binding JWT (or empty string) of the combined format.
+func split(token string) (string, []string, string, error) {
+	parts := strings.Split(strings.TrimSpace(token), separator)
+	if len(parts) < 2 || parts[0] == "" {
+		return "", nil, "", fmt.Errorf("%w: missing separator", ErrMalformed)
+	}
+
+	return parts[0], parts[1 : len(parts)-1], parts[len(parts)-1], nil
+}
+
+// decodeDisclosure returns claim name (empty for array element) and value of the disclosure.
+func decodeDisclosure(disclosure string) (string, interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(disclosure)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+	}
+
+	var content []interface{}
+
+	if err := json.Unmarshal(b, &content); err != nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+	}
+
+	switch len(content) {
+	case 2:
+		return "", content[1], nil
+	case 3:
+		if name, ok := content[1].(string); ok {
+			return name, content[2], nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("%w: disclosure with %d elements", ErrMalformed, len(content))
+}
+
+// elementName returns the name of array element disclosure used for selection: string value or JSON of the value.
+func elementName(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+func unverifiedPayload(compact string) (map[string]interface{}, error) {
+	return jwt.Decode(compact)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
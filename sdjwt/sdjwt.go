@@ -0,0 +1,106 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package sdjwt
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/szkiba/xk6-jose/internal/config"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct {
+	config *config.Config
+}
+
+func New() modules.Module {
+	return jsmodule.New(func(vu modules.VU) interface{} { return &Module{config: config.Default(vu)} })
+}
+
+// Resolve applies the options of the test to the configuration before the first call in the VU code.
+func (m *Module) Resolve() error {
+	return m.config.Resolve()
+}
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrInvalidPath          = errors.New("invalid path")
+)
+
+const (
+	separator        = "~"
+	defaultAlgorithm = "sha-256"
+	defaultType      = "sd+jwt"
+	sdClaim          = "_sd"
+	sdAlgClaim       = "_sd_alg"
+	arrayElement     = "..."
+)
+
+func newHash(alg string) (hash.Hash, error) {
+	switch strings.ToLower(alg) {
+	case "sha-256":
+		return sha256.New(), nil
+	case "sha-384":
+		return sha512.New384(), nil
+	case "sha-512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+// digest returns the base64url encoded hash of the (encoded) disclosure.
+func digest(alg string, disclosure string) (string, error) {
+	h, err := newHash(alg)
+	if err != nil {
+		return "", err
+	}
+
+	h.Write([]byte(disclosure))
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// disclosure creates encoded disclosure of object property (or array element, if name is empty).
+func disclosure(name string, value interface{}) (string, error) {
+	content := []interface{}{jwt.RandomID()}
+	if name != "" {
+		content = append(content, name)
+	}
+
+	b, err := json.Marshal(append(content, value))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
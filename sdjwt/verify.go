@@ -0,0 +1,323 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package sdjwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var (
+	ErrInvalidDisclosure = errors.New("invalid disclosure")
+	ErrKeyBinding        = errors.New("key binding verification failed")
+)
+
+type VerifyOptions struct {
+	Nonce      string `js:"nonce"`
+	Audience   string `js:"aud"`
+	KeyBinding bool   `js:"keyBinding"`
+	MaxAge     int    `js:"maxAge"`
+}
+
+const defaultMaxAge = 300
+
+type Verified struct {
+	Payload    map[string]interface{} `js:"payload"`
+	KeyBinding map[string]interface{} `js:"keyBinding"`
+}
+
+// Verify verifies the issuer signature, the disclosures and the key binding JWT (if any), returns the disclosed claims.
+func (m *Module) Verify(token string, keys interface{}, options *VerifyOptions) (*Verified, error) {
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+
+	issuerJWT, disclosures, kb, err := split(token)
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := jwt.VerifyWith(m.config, issuerJWT, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, _ := verified.Payload[sdAlgClaim].(string)
+	if alg == "" {
+		alg = defaultAlgorithm
+	}
+
+	r := &resolver{digests: make(map[string]*disclosed, len(disclosures)), used: map[string]bool{}}
+
+	for _, d := range disclosures {
+		dig, err := digest(alg, d)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := r.digests[dig]; ok {
+			return nil, fmt.Errorf("%w: duplicate disclosure %s", ErrInvalidDisclosure, dig)
+		}
+
+		name, value, err := decodeDisclosure(d)
+		if err != nil {
+			return nil, err
+		}
+
+		r.digests[dig] = &disclosed{name: name, value: value}
+	}
+
+	payload, err := r.resolve(verified.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.used) != len(r.digests) {
+		return nil, fmt.Errorf("%w: %d unreferenced disclosures", ErrInvalidDisclosure, len(r.digests)-len(r.used))
+	}
+
+	claims, _ := payload.(map[string]interface{})
+	delete(claims, sdAlgClaim)
+
+	res := &Verified{Payload: claims}
+
+	if kb == "" {
+		if options.KeyBinding {
+			return nil, fmt.Errorf("%w: missing key binding JWT", ErrKeyBinding)
+		}
+
+		return res, nil
+	}
+
+	presentation := strings.TrimSuffix(strings.TrimSpace(token), kb)
+
+	if res.KeyBinding, err = m.verifyKeyBinding(kb, presentation, alg, verified.Payload, options); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *Module) verifyKeyBinding(
+	kb string,
+	presentation string,
+	alg string,
+	payload map[string]interface{},
+	options *VerifyOptions,
+) (map[string]interface{}, error) {
+	cnf, _ := payload["cnf"].(map[string]interface{})
+
+	raw, err := json.Marshal(cnf["jwk"])
+	if err != nil {
+		return nil, err
+	}
+
+	var holder jose.JSONWebKey
+
+	if err := holder.UnmarshalJSON(raw); err != nil {
+		return nil, fmt.Errorf("%w: invalid cnf jwk: %s", ErrKeyBinding, err.Error())
+	}
+
+	parsed, err := jwt.ParseWith(m.config, kb)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ, _ := parsed.Headers[0].ExtraHeaders[jose.HeaderType].(string); typ != keyBindingType {
+		return nil, fmt.Errorf("%w: typ %s", ErrKeyBinding, typ)
+	}
+
+	verified, err := jwt.VerifyWith(m.config, kb, &holder)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyBinding, err.Error())
+	}
+
+	claims := verified.Payload
+
+	sdHash, err := digest(alg, presentation)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims["sd_hash"] != sdHash {
+		return nil, fmt.Errorf("%w: sd_hash mismatch", ErrKeyBinding)
+	}
+
+	if err := m.checkIssuedAt(claims, options.MaxAge); err != nil {
+		return nil, err
+	}
+
+	if options.Nonce != "" && claims["nonce"] != options.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrKeyBinding)
+	}
+
+	if options.Audience != "" && claims["aud"] != options.Audience {
+		return nil, fmt.Errorf("%w: aud mismatch", ErrKeyBinding)
+	}
+
+	return claims, nil
+}
+
+// checkIssuedAt requires iat of the key binding JWT not older than maxAge seconds and not in the future
+// (beyond the leeway).
+func (m *Module) checkIssuedAt(claims map[string]interface{}, maxAge int) error {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing iat", ErrKeyBinding)
+	}
+
+	if maxAge <= 0 {
+		maxAge = defaultMaxAge
+	}
+
+	now, err := m.config.Now()
+	if err != nil {
+		return err
+	}
+
+	leeway := josejwt.DefaultLeeway
+	if m.config.Leeway != nil {
+		leeway = *m.config.Leeway
+	}
+
+	issued := time.Unix(int64(iat), 0)
+
+	if now.Sub(issued) > time.Duration(maxAge)*time.Second || issued.Sub(now) > leeway {
+		return fmt.Errorf("%w: iat %d is not fresh", ErrKeyBinding, int64(iat))
+	}
+
+	return nil
+}
+
+type disclosed struct {
+	name  string
+	value interface{}
+}
+
+// resolver replaces digests with the disclosed claims, undisclosed digests (and decoys) are removed.
+type resolver struct {
+	digests map[string]*disclosed
+	used    map[string]bool
+}
+
+func (r *resolver) resolve(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case map[string]interface{}:
+		return r.resolveObject(v)
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+
+		for _, elem := range v {
+			if obj, ok := elem.(map[string]interface{}); ok && len(obj) == 1 {
+				if dig, ok := obj[arrayElement].(string); ok {
+					d, err := r.use(dig, true)
+					if err != nil {
+						return nil, err
+					}
+
+					if d == nil {
+						continue
+					}
+
+					elem = d.value
+				}
+			}
+
+			value, err := r.resolve(elem)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, value)
+		}
+
+		return out, nil
+	default:
+		return in, nil
+	}
+}
+
+func (r *resolver) resolveObject(v map[string]interface{}) (interface{}, error) {
+	out := make(map[string]interface{}, len(v))
+
+	for k, val := range v {
+		if k == sdClaim {
+			continue
+		}
+
+		value, err := r.resolve(val)
+		if err != nil {
+			return nil, err
+		}
+
+		out[k] = value
+	}
+
+	sd, _ := v[sdClaim].([]interface{})
+
+	for _, elem := range sd {
+		dig, _ := elem.(string)
+
+		d, err := r.use(dig, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if d == nil {
+			continue
+		}
+
+		if _, ok := out[d.name]; ok || d.name == sdClaim || d.name == arrayElement {
+			return nil, fmt.Errorf("%w: claim %s", ErrInvalidDisclosure, d.name)
+		}
+
+		if out[d.name], err = r.resolve(d.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// use returns the disclosure of the digest (nil for undisclosed or decoy digest), every disclosure can be used once.
+func (r *resolver) use(dig string, element bool) (*disclosed, error) {
+	d, ok := r.digests[dig]
+	if !ok {
+		return nil, nil
+	}
+
+	if r.used[dig] || element != (d.name == "") {
+		return nil, fmt.Errorf("%w: digest %s", ErrInvalidDisclosure, dig)
+	}
+
+	r.used[dig] = true
+
+	return d, nil
+}
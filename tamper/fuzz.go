@@ -0,0 +1,230 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tamper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const defaultFuzzCount = 16
+
+type FuzzOptions struct {
+	Seed  int64    `js:"seed"`
+	Count int      `js:"count"`
+	Kinds []string `js:"kinds"`
+}
+
+type Fuzzed struct {
+	Kind  string `js:"kind"`
+	Token string `js:"token"`
+}
+
+type mutation func(segments []string, r *rand.Rand) string
+
+// mutations of the compact serialization, every mutation produces a structurally broken token
+var mutations = map[string]mutation{
+	"bad_base64":          badBase64,
+	"padding":             padding,
+	"missing_segment":     missingSegment,
+	"extra_segment":       extraSegment,
+	"empty_segment":       emptySegment,
+	"invalid_json_header": invalidJSONHeader,
+	"non_object_header":   nonObjectHeader,
+	"nul_byte":            nulByte,
+	"whitespace":          whitespace,
+	"mixed_serialization": mixedSerialization,
+}
+
+// FuzzKinds returns the names of the mutations.
+func (m *Module) FuzzKinds() []string {
+	kinds := make([]string, 0, len(mutations))
+
+	for kind := range mutations {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+
+	return kinds
+}
+
+// Fuzz generates malformed variants of the token, the same seed produces the same tokens.
+func (m *Module) Fuzz(token string, options *FuzzOptions) ([]Fuzzed, error) {
+	if options == nil {
+		options = &FuzzOptions{}
+	}
+
+	kinds := options.Kinds
+	if len(kinds) == 0 {
+		kinds = m.FuzzKinds()
+	}
+
+	for _, kind := range kinds {
+		if _, ok := mutations[kind]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+		}
+	}
+
+	count := options.Count
+	if count <= 0 {
+		count = defaultFuzzCount
+	}
+
+	segments := strings.Split(token, ".")
+	if len(segments) < 2 {
+		return nil, fmt.Errorf("%w: not a compact serialization", ErrInvalidToken)
+	}
+
+	r := rand.New(rand.NewSource(options.Seed))
+
+	result := make([]Fuzzed, count)
+
+	for i := range result {
+		kind := kinds[r.Intn(len(kinds))]
+		result[i] = Fuzzed{Kind: kind, Token: mutations[kind](append([]string{}, segments...), r)}
+	}
+
+	return result, nil
+}
+
+func badBase64(segments []string, r *rand.Rand) string {
+	const invalid = "!*+/$%~\\"
+
+	idx := r.Intn(len(segments))
+	seg := segments[idx]
+	pos := r.Intn(len(seg) + 1)
+
+	segments[idx] = seg[:pos] + string(invalid[r.Intn(len(invalid))]) + seg[pos:]
+
+	return strings.Join(segments, ".")
+}
+
+func padding(segments []string, r *rand.Rand) string {
+	idx := r.Intn(len(segments))
+	segments[idx] += strings.Repeat("=", 1+r.Intn(3))
+
+	return strings.Join(segments, ".")
+}
+
+func missingSegment(segments []string, r *rand.Rand) string {
+	idx := r.Intn(len(segments))
+
+	return strings.Join(append(segments[:idx], segments[idx+1:]...), ".")
+}
+
+func extraSegment(segments []string, r *rand.Rand) string {
+	idx := r.Intn(len(segments) + 1)
+	extra := segments[r.Intn(len(segments))]
+
+	return strings.Join(append(segments[:idx], append([]string{extra}, segments[idx:]...)...), ".")
+}
+
+func emptySegment(segments []string, r *rand.Rand) string {
+	segments[r.Intn(len(segments))] = ""
+
+	return strings.Join(segments, ".")
+}
+
+func invalidJSONHeader(segments []string, r *rand.Rand) string {
+	raw, err := base64.RawURLEncoding.DecodeString(segments[0])
+	if err != nil || len(raw) < 2 {
+		raw = []byte(`{"alg":"none"}`)
+	}
+
+	// truncated, unterminated or trailing garbage JSON
+	switch r.Intn(3) {
+	case 0:
+		raw = raw[:1+r.Intn(len(raw)-1)]
+	case 1:
+		raw = append(raw[:len(raw)-1], ',')
+	default:
+		raw = append(raw, '}')
+	}
+
+	segments[0] = base64.RawURLEncoding.EncodeToString(raw)
+
+	return strings.Join(segments, ".")
+}
+
+func nonObjectHeader(segments []string, r *rand.Rand) string {
+	values := []string{"null", "[]", `"alg"`, "42", "true", `[{"alg":"none"}]`}
+
+	segments[0] = base64.RawURLEncoding.EncodeToString([]byte(values[r.Intn(len(values))]))
+
+	return strings.Join(segments, ".")
+}
+
+func nulByte(segments []string, r *rand.Rand) string {
+	idx := r.Intn(len(segments))
+
+	// NUL in the raw token or in the decoded JSON
+	if raw, err := base64.RawURLEncoding.DecodeString(segments[idx]); err == nil && idx < 2 && r.Intn(2) == 0 {
+		pos := r.Intn(len(raw) + 1)
+		raw = append(raw[:pos], append([]byte{0}, raw[pos:]...)...)
+		segments[idx] = base64.RawURLEncoding.EncodeToString(raw)
+
+		return strings.Join(segments, ".")
+	}
+
+	seg := segments[idx]
+	pos := r.Intn(len(seg) + 1)
+	segments[idx] = seg[:pos] + "\x00" + seg[pos:]
+
+	return strings.Join(segments, ".")
+}
+
+func whitespace(segments []string, r *rand.Rand) string {
+	const spaces = " \t\r\n"
+
+	token := strings.Join(segments, ".")
+	pos := r.Intn(len(token) + 1)
+
+	return token[:pos] + string(spaces[r.Intn(len(spaces))]) + token[pos:]
+}
+
+func mixedSerialization(segments []string, r *rand.Rand) string {
+	flattened := map[string]string{"protected": segments[0], "payload": segments[1]}
+	if len(segments) > 2 {
+		flattened["signature"] = segments[len(segments)-1]
+	}
+
+	raw, _ := json.Marshal(flattened)
+
+	// JSON serialization mixed with compact segments
+	switch r.Intn(3) {
+	case 0:
+		return string(raw) + "." + segments[len(segments)-1]
+	case 1:
+		return segments[0] + "." + string(raw)
+	default:
+		flattened["payload"] = strings.Join(segments, ".")
+		raw, _ = json.Marshal(flattened)
+
+		return string(raw)
+	}
+}
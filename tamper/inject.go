@@ -0,0 +1,63 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tamper
+
+import (
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+type InjectOptions struct {
+	Header map[string]interface{} `js:"header"`
+}
+
+// EmbedJWK signs the claims with the key and embeds its public key in the jwk header.
+func (m *Module) EmbedJWK(
+	key *jose.JSONWebKey,
+	claims map[string]interface{},
+	options *InjectOptions,
+) (string, error) {
+	if options == nil {
+		options = &InjectOptions{}
+	}
+
+	header := jwt.Merge(map[string]interface{}{"jwk": key.Public()}, options.Header)
+
+	return jwt.Sign(key, claims, header)
+}
+
+// Jku signs the claims with the key and points the jku header at the URL (hosting the key set of the key).
+func (m *Module) Jku(
+	key *jose.JSONWebKey,
+	claims map[string]interface{},
+	url string,
+	options *InjectOptions,
+) (string, error) {
+	if options == nil {
+		options = &InjectOptions{}
+	}
+
+	header := jwt.Merge(map[string]interface{}{"jku": url}, options.Header)
+
+	return jwt.Sign(key, claims, header)
+}
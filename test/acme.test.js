@@ -0,0 +1,118 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import acme from "k6/x/jose/acme";
+import jws from "k6/x/jose/jws";
+import jwk from "k6/x/jose/jwk";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256 } from "./keys.js";
+
+const DIRECTORY = "https://acme.example.com/acme";
+const ACCOUNT = DIRECTORY + "/acct/1";
+
+function header(body) {
+  return JSON.parse(b64decode(JSON.parse(body).protected, "rawurl", "s"));
+}
+
+function payload(body) {
+  return b64decode(JSON.parse(body).payload, "rawurl", "s");
+}
+
+export default function () {
+  describe("request", (t) => {
+    const key = jwk.parse(EC_P256);
+    const url = DIRECTORY + "/new-account";
+    const body = acme.request(key, url, "nonce-1", { termsOfServiceAgreed: true });
+    const hdr = header(body);
+
+    t.expect(Object.keys(JSON.parse(body)).sort().join()).as("members").toEqual("payload,protected,signature");
+    t.expect(hdr.alg).as("alg").toEqual("ES256");
+    t.expect(hdr.nonce).as("nonce").toEqual("nonce-1");
+    t.expect(hdr.url).as("url").toEqual(url);
+    t.expect(hdr.jwk.kty).as("jwk kty").toEqual("EC");
+    t.expect("d" in hdr.jwk).as("jwk private").toEqual(false);
+    t.expect("kid" in hdr).as("kid present").toEqual(false);
+    t.expect(JSON.parse(payload(body)).termsOfServiceAgreed).as("payload").toEqual(true);
+    t.expect(jws.verify(body, key.public()).byteLength).as("verified").toBeGreaterThan(0);
+
+    const get = acme.request(key, ACCOUNT + "/orders", "nonce-2", null, { kid: ACCOUNT });
+
+    t.expect(header(get).kid).as("kid").toEqual(ACCOUNT);
+    t.expect("jwk" in header(get)).as("jwk present").toEqual(false);
+    t.expect(JSON.parse(get).payload).as("POST-as-GET payload").toEqual("");
+    t.expect(payload(acme.request(key, url, "nonce-3", {}, { kid: ACCOUNT }))).as("empty object").toEqual("{}");
+
+    let err = null;
+    try {
+      acme.request(key, url, "", {});
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("missing nonce error").toBeTruthy();
+  });
+
+  describe("session", (t) => {
+    const key = jwk.parse(EC_P256);
+    const session = acme.createSession(key);
+
+    session.setNonce("nonce-1");
+
+    const account = session.request(DIRECTORY + "/new-account", { termsOfServiceAgreed: true });
+
+    t.expect(header(account).nonce).as("nonce").toEqual("nonce-1");
+    t.expect("jwk" in header(account)).as("jwk present").toEqual(true);
+
+    const created = session.update({
+      status: 201,
+      headers: { "Replay-Nonce": "nonce-2", Location: ACCOUNT },
+    });
+
+    t.expect(created).as("created retry").toEqual(false);
+    t.expect(session.kid()).as("kid").toEqual(ACCOUNT);
+    t.expect(session.nonce()).as("updated nonce").toEqual("nonce-2");
+
+    const order = session.request(DIRECTORY + "/new-order", { identifiers: [{ type: "dns", value: "example.com" }] });
+
+    t.expect(header(order).kid).as("order kid").toEqual(ACCOUNT);
+    t.expect(header(order).nonce).as("order nonce").toEqual("nonce-2");
+
+    const retry = session.update({
+      status: 400,
+      headers: { "replay-nonce": "nonce-3", Location: DIRECTORY + "/order/1" },
+      body: '{"type":"urn:ietf:params:acme:error:badNonce"}',
+    });
+
+    t.expect(retry).as("bad nonce retry").toEqual(true);
+    t.expect(session.nonce()).as("retry nonce").toEqual("nonce-3");
+    t.expect(session.kid()).as("unchanged kid").toEqual(ACCOUNT);
+
+    const existing = acme.createSession(key, { kid: ACCOUNT });
+
+    existing.setNonce("nonce-4");
+    t.expect(header(existing.request(ACCOUNT)).kid).as("existing kid").toEqual(ACCOUNT);
+  });
+}
@@ -0,0 +1,147 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import cose from "k6/x/jose/cose";
+import jwk from "k6/x/jose/jwk";
+import { describe } from "./expect.js";
+import { EC_P256, EC_P256_2, RSA_2048 } from "./keys.js";
+
+// RFC 8392 Appendix A.2.3 key and A.3 signed CWT
+const RFC_KEY =
+  '{"kty":"EC","crv":"P-256","kid":"AsymmetricECDSA256",' +
+  '"x":"FDMpzOeGjkFpJ1mc9lo0884v_aVafspp7YkZo5TULw8","y":"YPfxp4DYp4O_t6LdayeW6BKNu87509Fo25Uplxo257k"}';
+const RFC_CWT =
+  "d28443a10126a104524173796d6d657472696345434453413235365850a70175636f61703a2f2f61732e6578616d706c652e636f6d02" +
+  "656572696b77037818636f61703a2f2f6c696768742e6578616d706c652e636f6d041a5612aeb0051a5610d9f0061a5610d9f00742" +
+  "0b7158405427c1ff28d23fbad1f29c4c7c6a555e601d6fa29f9179bc3d7438bacaca5acd08c8d4d4f96131680c429a01f85951ecee" +
+  "743a52b9b63632c57209120e1c9e30";
+
+function unhex(str) {
+  const bytes = new Uint8Array(str.length / 2);
+  bytes.forEach((_, idx) => (bytes[idx] = parseInt(str.substr(idx * 2, 2), 16)));
+  return bytes.buffer;
+}
+
+function text(buff) {
+  return String.fromCharCode.apply(null, new Uint8Array(buff));
+}
+
+function fails(fn) {
+  try {
+    fn();
+  } catch (e) {
+    return true;
+  }
+  return false;
+}
+
+export default function () {
+  describe("sign1", (t) => {
+    const key = jwk.parse(EC_P256);
+    const msg = cose.sign1(key, "hello", { protected: { cty: 0 }, unprotected: { "-70000": "x" } });
+    const bytes = new Uint8Array(msg);
+
+    t.expect(bytes[0]).as("COSE_Sign1 tag").toEqual(0xd2);
+    t.expect(bytes[1]).as("array of 4").toEqual(0x84);
+
+    const result = cose.verify1(msg, [jwk.parse(EC_P256_2).public(), key.public()]);
+
+    t.expect(text(result.payload)).as("payload").toEqual("hello");
+    t.expect(result.alg).as("alg").toEqual("ES256");
+    t.expect(result.kid).as("kid").toEqual("ec-1");
+    t.expect(result.protected.alg).as("protected alg").toEqual("ES256");
+    t.expect(result.protected.cty).as("protected cty").toEqual(0);
+    t.expect(result.unprotected.kid).as("unprotected kid").toEqual("ec-1");
+    t.expect(result.unprotected["-70000"]).as("unprotected label").toEqual("x");
+
+    const ed = jwk.generate("ed25519");
+    const rsa = jwk.parse(RSA_2048);
+
+    t.expect(cose.verify1(cose.sign1(ed, "hi"), ed.public()).alg).as("EdDSA").toEqual("EdDSA");
+    t.expect(cose.verify1(cose.sign1(rsa, "hi"), rsa.public()).alg).as("RS256").toEqual("RS256");
+    t.expect(fails(() => cose.verify1(msg, jwk.parse(EC_P256_2).public()))).as("wrong key").toEqual(true);
+  });
+
+  describe("sign1 key algorithm", (t) => {
+    const obj = jwk.toObject(jwk.parse(EC_P256));
+    const bare = jwk.parse(JSON.stringify(Object.assign({}, obj, { alg: undefined })));
+    const mismatch = jwk.parse(JSON.stringify(Object.assign({}, obj, { alg: "ES512" })));
+
+    t.expect(cose.verify1(cose.sign1(bare, "hi"), bare.public()).alg).as("default alg").toEqual("ES256");
+    t.expect(fails(() => cose.sign1(mismatch, "hi"))).as("curve mismatch").toEqual(true);
+  });
+
+  describe("sign1 options", (t) => {
+    const key = jwk.parse(EC_P256);
+    const aad = "external";
+
+    const untagged = new Uint8Array(cose.sign1(key, "hello", { untagged: true }));
+    t.expect(untagged[0]).as("untagged").toEqual(0x84);
+
+    const withAAD = cose.sign1(key, "hello", { aad: aad });
+    t.expect(text(cose.verify1(withAAD, key, { aad: aad }).payload)).as("aad").toEqual("hello");
+    t.expect(fails(() => cose.verify1(withAAD, key))).as("missing aad").toEqual(true);
+
+    const detached = cose.sign1(key, "hello", { detached: true });
+    t.expect(text(cose.verify1(detached, key, { payload: "hello" }).payload)).as("detached").toEqual("hello");
+    t.expect(fails(() => cose.verify1(detached, key, { payload: "other" }))).as("wrong payload").toEqual(true);
+    t.expect(fails(() => cose.verify1(detached, key))).as("missing payload").toEqual(true);
+    t.expect(fails(() => cose.verify1(unhex("d28443a1"), key))).as("malformed").toEqual(true);
+  });
+
+  describe("signCWT", (t) => {
+    const key = jwk.parse(EC_P256);
+    const now = Math.floor(Date.now() / 1000);
+    const msg = cose.signCWT(key, { iss: "coap://as.example.com", sub: "device-1", exp: now + 60, cti: "id-1", 42: "x" });
+    const claims = cose.verifyCWT(msg, key.public());
+
+    t.expect(claims.iss).as("iss").toEqual("coap://as.example.com");
+    t.expect(claims.sub).as("sub").toEqual("device-1");
+    t.expect(claims.exp).as("exp").toEqual(now + 60);
+    t.expect(text(claims.cti)).as("cti").toEqual("id-1");
+    t.expect(claims["42"]).as("custom claim").toEqual("x");
+
+    const expired = cose.signCWT(key, { exp: now - 120 });
+    t.expect(fails(() => cose.verifyCWT(expired, key))).as("expired").toEqual(true);
+    t.expect(fails(() => cose.verifyCWT(expired, key, { leeway: 300 }))).as("leeway").toEqual(false);
+  });
+
+  describe("RFC 8392 signed CWT", (t) => {
+    const key = jwk.parse(RFC_KEY);
+    const result = cose.verify1(unhex(RFC_CWT), key);
+
+    t.expect(result.kid).as("kid").toEqual("AsymmetricECDSA256");
+    t.expect(result.alg).as("alg").toEqual("ES256");
+
+    const claims = cose.verifyCWT(unhex(RFC_CWT), key, { leeway: 2000000000 });
+
+    t.expect(claims.iss).as("iss").toEqual("coap://as.example.com");
+    t.expect(claims.sub).as("sub").toEqual("erikw");
+    t.expect(claims.aud).as("aud").toEqual("coap://light.example.com");
+    t.expect(claims.exp).as("exp").toEqual(1444064944);
+    t.expect(new Uint8Array(claims.cti)[1]).as("cti").toEqual(0x71);
+  });
+}
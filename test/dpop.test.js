@@ -0,0 +1,108 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import dpop from "k6/x/jose/dpop";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { sha256 } from "k6/crypto";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256 } from "./keys.js";
+
+function header(token) {
+  return JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+}
+
+export default function () {
+  describe("proof", (t) => {
+    const key = jwk.parse(EC_P256);
+    const proof = dpop.proof(key, {
+      method: "post",
+      url: "https://rs.example.com/resource?id=1#top",
+      accessToken: "access-token",
+      nonce: "server-nonce",
+    });
+    const hdr = header(proof);
+    const claims = jwt.verify(proof, key.public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    t.expect(hdr.typ).as("typ").toEqual("dpop+jwt");
+    t.expect(hdr.alg).as("alg").toEqual("ES256");
+    t.expect(hdr.jwk.kty).as("jwk kty").toEqual("EC");
+    t.expect("d" in hdr.jwk).as("jwk private").toEqual(false);
+
+    expect("htm").toEqual("POST");
+    expect("htu").toEqual("https://rs.example.com/resource");
+    expect("ath").toEqual(sha256("access-token", "base64rawurl"));
+    expect("nonce").toEqual("server-nonce");
+    t.expect(claims.jti.length).as("jti length").toBeGreaterThan(0);
+    t.expect(claims.iat).as("iat").toBeGreaterThan(0);
+
+    const plain = jwt.decode(dpop.proof(key, { url: "https://as.example.com/token" }));
+
+    t.expect(plain.htm).as("default htm").toEqual("GET");
+    t.expect("ath" in plain).as("ath present").toEqual(false);
+    t.expect("nonce" in plain).as("nonce present").toEqual(false);
+  });
+
+  describe("session", (t) => {
+    const key = jwk.parse(EC_P256);
+    const session = dpop.createSession(key);
+    const url = "https://as.example.com/token";
+
+    t.expect("nonce" in jwt.decode(session.proof({ method: "POST", url: url }))).as("initial nonce").toEqual(false);
+
+    const retry = session.update({
+      status: 400,
+      headers: { "Dpop-Nonce": "nonce-1" },
+      body: '{"error":"use_dpop_nonce"}',
+    });
+
+    t.expect(retry).as("retry").toEqual(true);
+    t.expect(session.nonce()).as("nonce").toEqual("nonce-1");
+    t.expect(jwt.decode(session.proof({ method: "POST", url: url })).nonce).as("proof nonce").toEqual("nonce-1");
+
+    t.expect(session.update({ status: 200, headers: { "Dpop-Nonce": "nonce-2" } })).as("rotated retry").toEqual(false);
+    t.expect(session.nonce()).as("rotated nonce").toEqual("nonce-2");
+
+    const challenge = session.update({
+      status: 401,
+      headers: { "Www-Authenticate": 'DPoP error="use_dpop_nonce"', "Dpop-Nonce": "nonce-3" },
+    });
+
+    t.expect(challenge).as("challenge retry").toEqual(true);
+    t.expect(session.update({ status: 401, headers: { "Dpop-Nonce": "nonce-3" } })).as("same nonce retry").toEqual(false);
+
+    const lower = session.update({
+      status: 401,
+      headers: { "www-authenticate": 'DPoP error="use_dpop_nonce"', "dpop-nonce": "NONCE-3" },
+    });
+
+    t.expect(lower).as("lower case header names").toEqual(true);
+    t.expect(session.nonce()).as("nonce compared exactly").toEqual("NONCE-3");
+    t.expect(session.update({ status: 401, headers: { "DPOP-NONCE": "NONCE-3" } })).as("upper case name").toEqual(false);
+  });
+}
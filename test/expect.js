@@ -28,7 +28,23 @@ import { check, group } from "k6";
 import { Rate } from "k6/metrics";
 
 export let errors = new Rate("errors");
-export let options = { thresholds: { errors: ["rate==0"] } };
+export let options = {
+  thresholds: {
+    errors: ["rate==0"],
+    // built-in metrics of the JOSE operations, failures are expected from negative tests
+    "jose_operations{module:jwt,operation:sign}": ["count>0"],
+    "jose_operations{module:jwe,operation:decrypt}": ["count>0"],
+    "jose_failures{module:jws,operation:verify}": ["count>0"],
+    "jose_verify_duration{module:jwt}": ["max>0"],
+    "jose_verify_failures{reason:expired}": ["count>0"],
+    "jose_verify_failures{reason:bad-signature}": ["count>0"],
+    "jose_verify_failures{reason:unknown-kid}": ["count>0"],
+    "jose_verify_failures{reason:wrong-audience}": ["count>0"],
+    "jose_failures{module:jwe,alg:unknown}": ["count>0"],
+  },
+  // the module options, the K6_JOSE_CACHE_TTL environment variable of the test run takes precedence
+  ext: { jose: { maxSegments: 100, cacheTTL: 60 } },
+};
 
 export class FunkBrokenChainException extends Error {
   constructor(message) {
@@ -76,7 +92,7 @@ class Funk {
           [checkName]: isSuccessful,
         },
         {
-          value: value,
+          value: String(value),
         }
       );
     } else {
@@ -26,10 +26,42 @@ import { group } from "k6";
 
 export { options } from "./expect.js";
 
+import testACME from "./acme.test.js";
+import testCOSE from "./cose.test.js";
+import testDPoP from "./dpop.test.js";
+import testHTTPSig from "./httpsig.test.js";
 import testJWK from "./jwk.test.js";
 import testJWT from "./jwt.test.js";
+import testJWS from "./jws.test.js";
+import testJWE from "./jwe.test.js";
+import testKMS from "./kms.test.js";
+import testOAuth from "./oauth.test.js";
+import testOIDC from "./oidc.test.js";
+import testSDJWT from "./sdjwt.test.js";
+import testVC from "./vc.test.js";
+import testWebPush from "./webpush.test.js";
+import testPASSporT from "./passport.test.js";
+import testRegistry from "./registry.test.js";
+import testRoot from "./root.test.js";
+import testTamper from "./tamper.test.js";
 
 export default function () {
   group("JWK", testJWK);
   group("JWT", testJWT);
+  group("JWS", testJWS);
+  group("JWE", testJWE);
+  group("KMS", testKMS);
+  group("OAuth", testOAuth);
+  group("DPoP", testDPoP);
+  group("HTTPSig", testHTTPSig);
+  group("OIDC", testOIDC);
+  group("SD-JWT", testSDJWT);
+  group("VC", testVC);
+  group("COSE", testCOSE);
+  group("ACME", testACME);
+  group("WebPush", testWebPush);
+  group("PASSporT", testPASSporT);
+  group("Registry", testRegistry);
+  group("Tamper", testTamper);
+  group("Root", testRoot);
 }
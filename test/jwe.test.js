@@ -0,0 +1,516 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import jwe from "k6/x/jose/jwe";
+import jwk from "k6/x/jose/jwk";
+import { describe } from "./expect.js";
+import { open as openFile } from "k6/experimental/fs";
+import { b64decode, b64encode } from "k6/encoding";
+import { EC_P256, EC_P256_2, RSA_2048, A256KW } from "./keys.js";
+
+// file handles are read from their offset to the end, encrypted once in the init context
+const FILE_CONTENT = open("./fixtures/ec-p256.pem", "b");
+const FILE_TOKEN = jwe.encryptBinary(jwk.parse(A256KW), await openFile("./fixtures/ec-p256.pem"));
+
+function binary() {
+  const buff = new ArrayBuffer(256);
+  const bytes = new Uint8Array(buff);
+  bytes.forEach((_, idx) => (bytes[idx] = idx));
+  return buff;
+}
+
+function same(a, b) {
+  const x = new Uint8Array(a);
+  const y = new Uint8Array(b);
+  return x.length === y.length && x.every((value, idx) => value === y[idx]);
+}
+
+function header(token) {
+  return JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+}
+
+export default function () {
+  describe("encrypt", (t) => {
+    const token = jwe.encrypt(jwk.parse(RSA_2048).public(), "hello", { header: { cty: "text/plain" } });
+    const hdr = header(token);
+
+    t.expect(token.split(".").length).as("number of fields").toEqual(5);
+    t.expect(hdr.alg).as("alg").toEqual("RSA-OAEP-256");
+    t.expect(hdr.enc).as("enc").toEqual("A256GCM");
+    t.expect(hdr.cty).as("cty").toEqual("text/plain");
+    t.expect(hdr.kid).as("kid").toEqual("rsa-1");
+  });
+
+  describe("flattened", (t) => {
+    const key = jwk.parse(RSA_2048);
+    const payload = binary();
+    const token = jwe.encrypt(key, payload, { serialization: "flattened" });
+    const json = JSON.parse(token);
+
+    ["protected", "encrypted_key", "iv", "ciphertext", "tag"].forEach((prop) =>
+      t.expect(typeof json[prop]).as(prop + " type").toEqual("string")
+    );
+    t.expect(same(jwe.decrypt(key, token), payload)).as("plaintext").toBeTruthy();
+  });
+
+  describe("general", (t) => {
+    const key = jwk.parse(RSA_2048);
+    const payload = binary();
+    const token = jwe.encrypt(key, payload, { serialization: "general" });
+    const json = JSON.parse(token);
+
+    t.expect(json.recipients.length).as("number of recipients").toEqual(1);
+    t.expect(typeof json.recipients[0].encrypted_key).as("encrypted_key type").toEqual("string");
+    t.expect("encrypted_key" in json).as("top level encrypted_key").toEqual(false);
+    t.expect(same(jwe.decrypt(key, token), payload)).as("plaintext").toBeTruthy();
+  });
+
+  describe("multiple recipients", (t) => {
+    const keys = [jwk.parse(RSA_2048), jwk.parse(EC_P256), jwk.parse(A256KW)];
+    const payload = binary();
+    const token = jwe.encrypt(keys, payload, { header: { cty: "application/octet-stream" } });
+    const json = JSON.parse(token);
+    const protected_ = JSON.parse(b64decode(json.protected, "rawurl", "s"));
+
+    t.expect(json.recipients.length).as("number of recipients").toEqual(3);
+    t.expect(protected_.cty).as("cty").toEqual("application/octet-stream");
+    t.expect(json.recipients.map((r) => r.header.alg).join(",")).as("alg").toEqual("RSA-OAEP-256,ECDH-ES+A256KW,A256KW");
+    t.expect(json.recipients.map((r) => r.header.kid).join(",")).as("kid").toEqual("rsa-1,ec-1,oct-2");
+
+    keys.forEach((key, idx) =>
+      t.expect(same(jwe.decrypt(key, token), payload)).as("plaintext " + idx).toBeTruthy()
+    );
+
+    let err = null;
+    try {
+      jwe.encrypt(keys, payload, { serialization: "compact" });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("compact error").toBeTruthy();
+  });
+
+  describe("key management algorithms", (t) => {
+    const payload = binary();
+
+    [
+      [jwk.parse(RSA_2048), "RSA1_5"],
+      [jwk.parse(RSA_2048), "RSA-OAEP"],
+      [jwk.parse(EC_P256), "ECDH-ES+A128KW"],
+      [jwk.parse(A256KW), "A256GCMKW"],
+      [jwk.parse(A256KW), "dir"],
+    ].forEach(([key, alg]) => {
+      const token = jwe.encrypt(key, payload, { alg: alg });
+
+      t.expect(header(token).alg).as(alg + " alg").toEqual(alg);
+      t.expect(same(jwe.decrypt(key, token, { algorithms: [alg] }), payload)).as(alg + " plaintext").toBeTruthy();
+    });
+
+    const key = jwk.parse(RSA_2048);
+    const errors = [
+      () => jwe.encrypt(key, payload, { alg: "RS256" }),
+      () => jwe.decrypt(key, jwe.encrypt(key, payload, { alg: "RSA1_5" }), { algorithms: ["RSA-OAEP-256"] }),
+    ].map((fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    });
+    t.expect(errors.join(",")).as("error codes").toEqual("ERR_JOSE_ALG_UNSUPPORTED,ERR_JOSE_ALG_NOT_ALLOWED");
+  });
+
+  describe("content encryption algorithms", (t) => {
+    const key = jwk.parse(RSA_2048);
+    const payload = binary();
+
+    ["A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512", "A128GCM", "A192GCM", "A256GCM"].forEach((enc) => {
+      const token = jwe.encrypt(key, payload, { enc: enc });
+
+      t.expect(header(token).enc).as(enc + " enc").toEqual(enc);
+      t.expect(same(jwe.decrypt(key, token, { encryptions: [enc] }), payload)).as(enc + " plaintext").toBeTruthy();
+    });
+
+    let err = null;
+    try {
+      jwe.decrypt(key, jwe.encrypt(key, payload, { enc: "A128GCM" }), { encryptions: ["A128CBC-HS256"] });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("not allowed error").toBeTruthy();
+
+    const unsupported = [
+      () => jwe.encrypt(key, payload, { enc: "A512GCM" }),
+      () => jwe.encryptBinary(key, payload, { enc: "A512GCM" }),
+    ].map((fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    });
+    t.expect(unsupported.join(",")).as("unsupported codes")
+      .toEqual("ERR_JOSE_ALG_UNSUPPORTED,ERR_JOSE_ALG_UNSUPPORTED");
+  });
+
+  describe("compression", (t) => {
+    const key = jwk.parse(RSA_2048);
+    const plaintext = "a".repeat(10000);
+
+    const token = jwe.encrypt(key, plaintext, { zip: "DEF" });
+    const plain = jwe.encrypt(key, plaintext);
+
+    t.expect(header(token).zip).as("zip").toEqual("DEF");
+    t.expect(token.length).as("compressed length").toBeLessThan(plain.length / 10);
+    const decrypted = new Uint8Array(jwe.decrypt(key, token));
+
+    t.expect(decrypted.length).as("plaintext length").toEqual(plaintext.length);
+    t.expect(decrypted.every((c) => c === 97)).as("plaintext").toBeTruthy();
+  });
+
+  describe("additional authenticated data", (t) => {
+    const key = jwk.parse(RSA_2048);
+    const payload = binary();
+    const token = jwe.encrypt(key, payload, { aad: "GET /resource" });
+    const json = JSON.parse(token);
+
+    t.expect(b64decode(json.aad, "rawurl", "s")).as("aad").toEqual("GET /resource");
+    t.expect(same(jwe.decrypt(key, token, { aad: "GET /resource" }), payload)).as("plaintext").toBeTruthy();
+
+    json.aad = "R0VUIC9vdGhlcg";
+
+    const errors = [
+      () => jwe.decrypt(key, token, { aad: "GET /other" }),
+      () => jwe.decrypt(key, JSON.stringify(json)),
+      () => jwe.encrypt(key, payload, { aad: "GET /resource", serialization: "compact" }),
+    ].filter((fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return true;
+      }
+      return false;
+    });
+    t.expect(errors.length).as("number of errors").toEqual(3);
+  });
+
+  describe("party info", (t) => {
+    const key = jwk.parse(EC_P256);
+    const payload = binary();
+
+    ["ECDH-ES", "ECDH-ES+A256KW"].forEach((alg) => {
+      const token = jwe.encrypt(key, payload, { alg: alg, apu: "Alice", apv: "Bob" });
+      const hdr = header(token);
+
+      t.expect(b64decode(hdr.apu, "rawurl", "s")).as(alg + " apu").toEqual("Alice");
+      t.expect(b64decode(hdr.apv, "rawurl", "s")).as(alg + " apv").toEqual("Bob");
+      t.expect(same(jwe.decrypt(key, token), payload)).as(alg + " plaintext").toBeTruthy();
+    });
+  });
+
+  describe("password based", (t) => {
+    const payload = binary();
+
+    ["PBES2-HS256+A128KW", "PBES2-HS384+A192KW", "PBES2-HS512+A256KW"].forEach((alg) => {
+      const token = jwe.encrypt("correct horse battery staple", payload, { alg: alg, p2c: 1000, p2s: "saltsaltsaltsalt" });
+      const hdr = header(token);
+
+      t.expect(hdr.alg).as(alg + " alg").toEqual(alg);
+      t.expect(hdr.p2c).as(alg + " p2c").toEqual(1000);
+      t.expect(b64decode(hdr.p2s, "rawurl", "s")).as(alg + " p2s").toEqual("saltsaltsaltsalt");
+      t.expect(same(jwe.decrypt("correct horse battery staple", token), payload)).as(alg + " plaintext").toBeTruthy();
+    });
+
+    const token = jwe.encrypt("correct horse battery staple", payload);
+
+    t.expect(header(token).alg).as("default alg").toEqual("PBES2-HS256+A128KW");
+    t.expect(header(token).p2c).as("default p2c").toEqual(100000);
+
+    let err = null;
+    try {
+      jwe.decrypt("wrong password", token);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("wrong password error").toBeTruthy();
+  });
+
+  describe("sender authenticated", (t) => {
+    const alice = jwk.parse(EC_P256_2);
+    const bob = jwk.parse(EC_P256);
+    const payload = binary();
+
+    const token = jwe.encrypt(bob.public(), payload, { sender: alice, apu: "Alice", apv: "Bob" });
+    const hdr = header(token);
+
+    t.expect(hdr.alg).as("alg").toEqual("ECDH-1PU");
+    t.expect(hdr.skid).as("skid").toEqual("ec-2");
+    t.expect(same(jwe.decrypt(bob, token, { sender: alice.public() }), payload)).as("plaintext").toBeTruthy();
+
+    const multi = jwe.encrypt([bob.public(), alice.public()], payload, { sender: alice });
+    const json = JSON.parse(multi);
+
+    t.expect(json.recipients[0].header.alg).as("multi alg").toEqual("ECDH-1PU+A256KW");
+    t.expect(JSON.parse(b64decode(json.protected, "rawurl", "s")).enc).as("multi enc").toEqual("A256CBC-HS512");
+    [bob, alice].forEach((key, idx) =>
+      t.expect(same(jwe.decrypt(key, multi, { sender: alice.public() }), payload)).as("multi plaintext " + idx).toBeTruthy()
+    );
+
+    const shared = JSON.parse(b64decode(json.protected, "rawurl", "s"));
+
+    t.expect(shared.epk && shared.epk.crv).as("shared epk").toEqual("P-256");
+    t.expect(json.recipients.filter((r) => r.header.epk).length).as("recipient epk").toEqual(0);
+
+    // RFC 7748 section 6.1
+    const x25519 = (d) => jwk.fromOKPComponents({ crv: "X25519", d }, { encoding: "hex" });
+    const carol = x25519("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a");
+    const dave = x25519("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb");
+    const okpPublic = (key) => ({ kty: key.kty, crv: key.crv, x: key.x });
+    const okp = jwe.encrypt(okpPublic(dave), payload, { sender: carol });
+
+    t.expect(header(okp).alg + header(okp).epk.crv).as("X25519 alg").toEqual("ECDH-1PUX25519");
+    t.expect(same(jwe.decrypt(dave, okp, { sender: okpPublic(carol) }), payload)).as("X25519 plaintext").toBeTruthy();
+
+    const okpMulti = jwe.encrypt([okpPublic(dave), okpPublic(carol)], payload, { sender: carol });
+
+    [dave, carol].forEach((key, idx) =>
+      t.expect(same(jwe.decrypt(key, okpMulti, { sender: okpPublic(carol) }), payload)).as("X25519 multi " + idx).toBeTruthy()
+    );
+
+    const errors = [
+      () => jwe.decrypt(bob, token, { sender: bob.public() }),
+      () => jwe.encrypt([bob.public(), okpPublic(dave)], payload, { sender: alice }),
+      () => jwe.decrypt(bob, token),
+      () => jwe.encrypt(bob.public(), payload, { sender: alice, alg: "ECDH-1PU+A128KW", enc: "A128GCM" }),
+    ].filter((fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return true;
+      }
+      return false;
+    });
+    t.expect(errors.length).as("number of errors").toEqual(4);
+
+    const bomb = jwe.encrypt(bob.public(), "a".repeat(2 << 20), { sender: alice, zip: "DEF" });
+
+    let err = null;
+    try {
+      jwe.decrypt(bob, bomb, { sender: alice.public() });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(bomb.length < 1 << 20).as("compressed").toBeTruthy();
+    t.expect(err && err.code).as("decompressed size").toEqual("ERR_JOSE_MALFORMED");
+  });
+
+  describe("decrypt", (t) => {
+    const payload = binary();
+
+    [
+      ["RSA", jwk.parse(RSA_2048), "RSA-OAEP-256"],
+      ["EC", jwk.parse(EC_P256), "ECDH-ES"],
+      ["oct", jwk.parse(A256KW), "A256KW"],
+    ].forEach(([name, key, alg]) => {
+      const token = jwe.encrypt(key, payload);
+
+      t.expect(header(token).alg).as(name + " alg").toEqual(alg);
+      t.expect(same(jwe.decrypt(key, token), payload)).as(name + " plaintext").toBeTruthy();
+    });
+
+    let err = null;
+    try {
+      jwe.decrypt(jwk.parse(RSA_2048), jwe.encrypt(jwk.parse(EC_P256), payload));
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("wrong key error").toBeTruthy();
+
+    const nullHeader = JSON.stringify({
+      protected: b64encode("null", "rawurl"),
+      unprotected: { alg: "dir", enc: "A128GCM" },
+      iv: "",
+      ciphertext: "",
+      tag: "",
+    });
+
+    err = null;
+    try {
+      jwe.decrypt("password", nullHeader);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err && err.code).as("null protected header").toEqual("ERR_JOSE_MALFORMED");
+
+    // the alg of failed tokens is tagged "unknown" unless supported, see the thresholds
+    const parts = jwe.encrypt(jwk.parse(EC_P256), payload).split(".");
+    parts[0] = b64encode(JSON.stringify({ alg: "ECDH-ES-" + Date.now(), enc: "A256GCM" }), "rawurl");
+
+    err = null;
+    try {
+      jwe.decryptBinary(jwk.parse(EC_P256), parts.join("."));
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("fuzzed alg").toBeTruthy();
+  });
+
+  describe("key wrap", (t) => {
+    // RFC 3394 sections 4.1 and 4.3
+    const kek128 = jwk.parse({ kty: "oct", k: "AAECAwQFBgcICQoLDA0ODw" });
+    const kek256 = jwk.parse(A256KW);
+    const cek = b64decode("ABEiM0RVZneImaq7zN3u_w", "rawurl");
+
+    t.expect(b64encode(jwe.wrapKey(kek128, cek), "rawurl")).as("A128KW").toEqual("H6aLCoEStEeu80vY-1p7gp0-hiNx0s_l");
+    t.expect(b64encode(jwe.wrapKey(kek256, cek), "rawurl")).as("A256KW").toEqual("ZOjD-c4PW6Jj6Xd5BYGKKpPIGR59born");
+    t.expect(same(jwe.unwrapKey(kek256, b64decode("ZOjD-c4PW6Jj6Xd5BYGKKpPIGR59born", "rawurl")), cek))
+      .as("A256KW unwrap")
+      .toBeTruthy();
+
+    const rsa = jwk.parse(RSA_2048);
+
+    ["RSA-OAEP", "RSA-OAEP-256"].forEach((alg) => {
+      const wrapped = jwe.wrapKey(rsa.public(), cek, alg);
+
+      t.expect(wrapped.byteLength).as(alg + " size").toEqual(256);
+      t.expect(same(jwe.unwrapKey(rsa, wrapped, alg), cek)).as(alg + " unwrap").toBeTruthy();
+    });
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwe.unwrapKey(kek128, jwe.wrapKey(kek256, cek)))).as("wrong key").toEqual("ERR_JWE_DECRYPTION_FAILED");
+    t.expect(code(() => jwe.unwrapKey(rsa, jwe.wrapKey(rsa, cek, "RSA-OAEP"), "RSA-OAEP-256")))
+      .as("wrong algorithm")
+      .toEqual("ERR_JWE_DECRYPTION_FAILED");
+    t.expect(code(() => jwe.wrapKey(kek128, cek, "A256KW"))).as("key size").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+    t.expect(code(() => jwe.wrapKey(jwk.parse(EC_P256), cek))).as("ECDH").toEqual("ERR_JOSE_ALG_UNSUPPORTED");
+  });
+
+  describe("critical", (t) => {
+    const key = jwk.parse(EC_P256);
+    const token = jwe.encrypt(key.public(), "hello", { header: { exp: 1700000000 }, critical: ["exp"] });
+
+    t.expect(header(token).crit.join(",")).as("crit header").toEqual("exp");
+
+    const failure = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(failure(() => jwe.decrypt(key, token))).as("not handled").toEqual("ERR_JOSE_CRIT_UNSUPPORTED");
+    t.expect(failure(() => jwe.encrypt(key.public(), "hello", { critical: ["exp"] }))).as("missing").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(failure(() => jwe.encrypt(key.public(), "hello", { zip: "DEF", critical: ["zip"] })))
+      .as("registered")
+      .toEqual("ERR_JOSE_MALFORMED");
+  });
+
+  describe("binary", (t) => {
+    const key = jwk.parse(RSA_2048);
+    const large = new Uint8Array(600000);
+    large.forEach((_, idx) => (large[idx] = (idx * 31 + (idx >> 8)) & 0xff));
+
+    const text = (buff) => {
+      const bytes = new Uint8Array(buff);
+      let str = "";
+      for (let i = 0; i < bytes.length; i += 8192) {
+        str += String.fromCharCode.apply(null, bytes.subarray(i, i + 8192));
+      }
+      return str;
+    };
+
+    ["A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512", "A128GCM", "A192GCM", "A256GCM"].forEach((enc) => {
+      const token = jwe.encryptBinary(key, large.buffer, { enc: enc });
+
+      t.expect(header(text(token).split(".")[0] + ".").enc).as(enc + " enc").toEqual(enc);
+      t.expect(same(jwe.decrypt(key, text(token)), large.buffer)).as(enc + " decrypt").toBeTruthy();
+      t.expect(same(jwe.decryptBinary(key, token), large.buffer)).as(enc + " decryptBinary").toBeTruthy();
+      t.expect(same(jwe.decryptBinary(key, jwe.encrypt(key, large.buffer, { enc: enc })), large.buffer))
+        .as(enc + " from encrypt")
+        .toBeTruthy();
+    });
+
+    const sizes = [0, 1, 15, 16, 17, 47, 48, 49];
+    const sized = sizes.map((size) => {
+      const buff = new Uint8Array(size).map((_, idx) => idx);
+      const cbc = jwe.decrypt(key, text(jwe.encryptBinary(key, buff.buffer, { enc: "A128CBC-HS256" })));
+      const gcm = jwe.decrypt(key, text(jwe.encryptBinary(key, buff.buffer)));
+      return same(cbc, buff.buffer) && same(gcm, buff.buffer);
+    });
+
+    t.expect(sized.every((ok) => ok)).as("sizes").toBeTruthy();
+
+    [
+      [jwk.parse(EC_P256).public(), jwk.parse(EC_P256)],
+      [jwk.parse(A256KW), jwk.parse(A256KW)],
+      ["secret", "secret"],
+    ].forEach(([recipient, k]) => {
+      const token = jwe.encryptBinary(recipient, large, { zip: k === "secret" ? "DEF" : "" });
+
+      t.expect(same(jwe.decryptBinary(k, token), large.buffer)).as(header(text(token).split(".")[0] + ".").alg).toBeTruthy();
+    });
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    const tampered = new Uint8Array(jwe.encryptBinary(key, large));
+    const index = tampered.length - 100;
+
+    // replaced with another base64url character, so the token stays well formed
+    tampered[index] = tampered[index] === 65 ? 66 : 65;
+
+    t.expect(same(jwe.decryptBinary(jwk.parse(A256KW), FILE_TOKEN), FILE_CONTENT)).as("file handle").toBeTruthy();
+    t.expect(code(() => jwe.decryptBinary(key, tampered.buffer))).as("tampered").toEqual("ERR_JWE_DECRYPTION_FAILED");
+    t.expect(code(() => jwe.decryptBinary(key, "a.b.c"))).as("malformed").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwe.encryptBinary(key, large, { serialization: "general" })))
+      .as("serialization")
+      .toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwe.encryptBinary([key, jwk.parse(A256KW)], large)))
+      .as("recipients")
+      .toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwe.decryptBinary(key, jwe.encryptBinary(key, large), { algorithms: ["A256KW"] })))
+      .as("not allowed")
+      .toEqual("ERR_JOSE_ALG_NOT_ALLOWED");
+  });
+}
@@ -26,15 +26,66 @@ export { options } from "./expect.js";
 
 import { describe } from "./expect.js";
 
-import { b64encode } from "k6/encoding";
+import { b64decode, b64encode } from "k6/encoding";
 import { randomBytes } from "k6/crypto";
 import { group } from "k6";
+import { SharedArray } from "k6/data";
 
 import jwk from "k6/x/jose/jwk";
+import jwe from "k6/x/jose/jwe";
+import jws from "k6/x/jose/jws";
+import jwt from "k6/x/jose/jwt";
 import xcrypto from "k6/x/crypto";
+import { EC_P256, EC_P256_PEM, HS256, RSA_2048 } from "./keys.js";
 
 const ALG = "ed25519";
 
+const SERIALIZED = new SharedArray("serialized keys", () =>
+  ["ed25519", "ES256", "ES384"].map((alg) => jwk.serialize(jwk.generate(alg)))
+);
+
+const FILE_KEY = jwk.parseFile("fixtures/ec-p256.pem");
+const FILE_JWK = jwk.parseFile("fixtures/ec-p256.json");
+const FILE_JWKS = jwk.parseKeySetFile("fixtures/jwks.json");
+const FILE_BUNDLE = jwk.parseKeySetFile("fixtures/bundle.pem");
+
+const CHAIN_ROOT = open("fixtures/chain-root.pem");
+const CHAIN_OTHER_ROOT = open("fixtures/chain-other-root.pem");
+const CHAIN_INTERMEDIATE = open("fixtures/chain-intermediate.pem");
+const CHAIN_LEAF = open("fixtures/chain-leaf.pem");
+const CHAIN_LEAF_KEY = jwk.parseFile("fixtures/chain-leaf-key.pem");
+
+const ENV_KEY = jwk.fromEnv("JOSE_TEST_KEY");
+const SECRET_KEY = jwk.fromSecret("signing_key");
+const SECRET_PEM = jwk.fromSecret("pem_key", "default");
+
+const ECDSA_PAIR = await crypto.subtle.generateKey({ name: "ECDSA", namedCurve: "P-256" }, true, ["sign", "verify"]);
+const HMAC_KEY = await crypto.subtle.generateKey({ name: "HMAC", hash: "SHA-384" }, true, ["sign", "verify"]);
+const OAEP_PAIR = await crypto.subtle.generateKey(
+  { name: "RSA-OAEP", modulusLength: 2048, publicExponent: new Uint8Array([1, 0, 1]), hash: "SHA-256" },
+  false,
+  ["encrypt", "decrypt"]
+);
+const OPAQUE_ECDSA = await crypto.subtle.generateKey({ name: "ECDSA", namedCurve: "P-384" }, false, ["sign", "verify"]);
+const OPAQUE_HMAC = await crypto.subtle.generateKey({ name: "HMAC", hash: "SHA-256" }, false, ["sign", "verify"]);
+const IMPORTED = await jwk.toCryptoKey(jwk.generate("ES384"));
+const IMPORTED_PUBLIC = await jwk.toCryptoKey(jwk.generate("ES384").public(), { extractable: false });
+
+let INIT_DERIVE = null;
+try {
+  jwk.deriveForVU("base seed");
+} catch (e) {
+  INIT_DERIVE = e.code;
+}
+
+const ASYNC_RSA = await jwk.generateAsync("RS256");
+const ASYNC_SEED = new Uint8Array(32).fill(7);
+const ASYNC_SEEDED = await jwk.generateAsync(ALG, ASYNC_SEED);
+const ASYNC_UNSUPPORTED = await jwk.generateAsync("XS256").then(
+  () => null,
+  (e) => e.code
+);
+
 export default function () {
   describe("generate", (t) => {
     const key = JSON.parse(JSON.stringify(jwk.generate(ALG)));
@@ -50,6 +101,92 @@ export default function () {
     expectLength("kid").toBeGreaterThan(0);
   });
 
+  describe("generateAsync", (t) => {
+    t.expect(ASYNC_RSA.algorithm).as("alg").toEqual("RS256");
+    t.expect(jwk.toObject(ASYNC_RSA).n.length).as("modulus").toEqual(342);
+    t.expect(jwk.toObject(ASYNC_SEEDED).d).as("seed").toEqual(b64encode(ASYNC_SEED.buffer, "rawurl"));
+    t.expect(ASYNC_UNSUPPORTED).as("unsupported").toEqual("ERR_JOSE_ALG_UNSUPPORTED");
+  });
+
+  describe("generate with algorithm", (t) => {
+    const ec = JSON.parse(JSON.stringify(jwk.generate("ES384")));
+    const rsa = JSON.parse(JSON.stringify(jwk.generate("PS256")));
+
+    t.expect(ec.crv).as("crv").toEqual("P-384");
+    t.expect(ec.alg).as("ec alg").toEqual("ES384");
+    t.expect(ec.kid.length).as("ec kid length").toEqual(43);
+    t.expect(rsa.kty).as("kty").toEqual("RSA");
+    t.expect(rsa.alg).as("rsa alg").toEqual("PS256");
+
+    let err = null;
+    try {
+      jwk.generate("HS256");
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("unsupported algorithm error").toBeTruthy();
+  });
+
+  describe("createPool", (t) => {
+    const pool = jwk.createPool("test-es256", "ES256", 8, { parallelism: 3 });
+    const kid = (key) => JSON.parse(JSON.stringify(key)).kid;
+    const kids = [];
+
+    t.expect(pool.size()).as("size").toEqual(8);
+
+    for (let i = 0; i < 8; i++) {
+      kids.push(kid(pool.take()));
+    }
+
+    t.expect(new Set(kids).size).as("distinct keys").toEqual(8);
+    t.expect(kid(pool.get(9))).as("wrap around").toEqual(kid(pool.get(1)));
+    t.expect(kid(jwk.createPool("test-es256", "ES256", 8).get(0))).as("same pool").toEqual(kid(pool.get(0)));
+
+    let err = null;
+    try {
+      pool.take();
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("exhausted error").toBeTruthy();
+
+    const rsa = jwk.createPool("test-rsa", "RS256", 2, { bits: 1024, progress: true });
+
+    t.expect(JSON.parse(JSON.stringify(rsa.get(0))).kty).as("rsa pool").toEqual("RSA");
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.createPool("test-es256", "ES384", 8))).as("other alg").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwk.createPool("test-es256", "ES256", 4))).as("other count").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwk.createPool("test-retry", "XX", 1))).as("failed").toEqual("ERR_JOSE_ALG_UNSUPPORTED");
+    t.expect(jwk.createPool("test-retry", "ES256", 1).size()).as("retry").toEqual(1);
+  });
+
+  describe("generateMany", (t) => {
+    const keys = jwk.generateMany("ES256", 20, { parallelism: 4 });
+    const kids = keys.map((key) => jwk.toObject(key).kid);
+
+    t.expect(keys.length).as("number of keys").toEqual(20);
+    t.expect(new Set(kids).size).as("distinct keys").toEqual(20);
+    t.expect(jwk.toObject(keys[19]).crv).as("crv").toEqual("P-256");
+    t.expect(jwk.generateMany("ed25519", 0).length).as("no keys").toEqual(0);
+
+    let err = null;
+    try {
+      jwk.generateMany("ES256", -1);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("invalid count error").toBeTruthy();
+  });
+
   describe("generate from seed", (t) => {
     const seed = new ArrayBuffer(32);
     const bytes = new Uint8Array(seed);
@@ -66,6 +203,128 @@ export default function () {
 
     expect("d").toEqual(b64encode(seed, "rawurl"));
     expect("x").toEqual(b64encode(pair.publicKey, "rawurl"));
+
+    const typed = JSON.parse(JSON.stringify(jwk.generate(ALG, bytes)));
+    t.expect(typed.d).as("Uint8Array seed").toEqual(key.d);
+
+    const raw = new Uint8Array(pair.privateKey).slice();
+    const adopted = jwk.adopt(ALG, raw);
+    raw.fill(0);
+    t.expect(JSON.parse(JSON.stringify(adopted)).x).as("adopted copy").toEqual(key.x);
+  });
+
+  describe("deriveForVU", (t) => {
+    const kid = (key) => jwk.toObject(key).kid;
+    const derive = (options) => kid(jwk.deriveForVU("base seed", options));
+
+    t.expect(derive({ vu: 1 })).as("reproducible").toEqual("sLqbZo-kD6lwgEtH13Aix8a1bggHvptoQ5xf9rP_L2c");
+    t.expect(derive({ vu: 1 })).as("stable").toEqual(derive({ vu: 1 }));
+    t.expect(derive({ vu: 1 }) !== derive({ vu: 2 })).as("unique per VU").toBeTruthy();
+    t.expect(derive({ vu: 1, iteration: 0 }) !== derive({ vu: 1, iteration: 1 })).as("unique per iteration").toBeTruthy();
+    t.expect(derive({ vu: 1, iteration: 0 }) !== derive({ vu: 1 })).as("iteration key").toBeTruthy();
+    t.expect(derive({})).as("current VU").toEqual(derive({ vu: __VU }));
+    const binary = new Uint8Array("base seed".split("").map((c) => c.charCodeAt(0)));
+    t.expect(kid(jwk.deriveForVU(binary, { vu: 1 }))).as("binary seed").toEqual(derive({ vu: 1 }));
+    t.expect(derive({ vu: 1, algorithm: "ES256" })).as("ES256").toEqual("nqjC-QGO3pDh4RkooG8EinYpzKrT-Zg_qfySt8d-KMw");
+
+    for (const alg of ["ES256", "ES384", "ES512"]) {
+      const key = jwk.deriveForVU("base seed", { vu: 7, algorithm: alg });
+      t.expect(jwt.verify(jwt.sign(key, { sub: alg }), key.public()).sub).as(alg + " signs").toEqual(alg);
+    }
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.deriveForVU("base seed", { vu: 1, algorithm: "RS256" }))).as("RSA").toEqual("ERR_JOSE_ALG_UNSUPPORTED");
+    t.expect(code(() => jwk.deriveForVU("", { vu: 1 }))).as("empty seed").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(INIT_DERIVE).as("init context").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("parseFile", (t) => {
+    t.expect(JSON.stringify(FILE_KEY)).as("PEM").toEqual(JSON.stringify(jwk.parsePEM(EC_P256_PEM)));
+    t.expect(JSON.stringify(FILE_JWK)).as("JWK").toEqual(JSON.stringify(jwk.parse(EC_P256)));
+    t.expect(FILE_JWKS.map((k) => jwk.toObject(k).kid).join(",")).as("JWKS").toEqual("ec-1,ec-2");
+    t.expect(FILE_BUNDLE.map((k) => k.algorithm).join(",")).as("PEM bundle").toEqual("ES256,RS256");
+
+    let code = null;
+    try {
+      jwk.parseFile("fixtures/ec-p256.pem");
+    } catch (e) {
+      code = e.code;
+    }
+    t.expect(code).as("VU context error").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("fromEnv and fromSecret", (t) => {
+    const pem = JSON.stringify(jwk.parsePEM(EC_P256_PEM));
+
+    t.expect(JSON.stringify(ENV_KEY)).as("env").toEqual(pem);
+    t.expect(JSON.stringify(jwk.fromEnv("JOSE_TEST_KEY"))).as("env in VU").toEqual(pem);
+    t.expect(JSON.stringify(SECRET_KEY)).as("secret").toEqual(JSON.stringify(jwk.parse(HS256)));
+    t.expect(JSON.stringify(SECRET_PEM)).as("escaped PEM secret").toEqual(pem);
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.fromEnv("JOSE_TEST_MISSING"))).as("missing env").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwk.fromSecret("signing_key"))).as("secret in VU").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("redaction", (t) => {
+    const inspect = (v) =>
+      v !== null && typeof v === "object"
+        ? Object.keys(v)
+            .map((k) => k + ": " + inspect(v[k]))
+            .join(", ")
+        : String(v);
+
+    const ec = jwk.parsePEM(EC_P256_PEM);
+    const oct = jwk.parse(HS256);
+
+    t.expect(Object.keys(ec).includes("key")).as("key material hidden").toEqual(false);
+    t.expect(inspect(ec).includes(jwk.toObject(ec).kid)).as("metadata visible").toEqual(true);
+    t.expect(inspect(oct)).as("symmetric key").toEqual(inspect(jwk.parse(HS256.replace("c2Vj", "AAAA"))));
+
+    const secret = jwk.toObject(oct).k;
+    const raw = new Uint8Array(secret.split("").map((c) => c.charCodeAt(0)));
+    const message = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.message;
+      }
+      return "";
+    };
+
+    for (const [name, fn] of [
+      ["PEM as key", () => jwt.sign(EC_P256_PEM, {})],
+      ["PEM as algorithm", () => jwk.generate(EC_P256_PEM)],
+      ["PEM as key argument", () => jws.sign(EC_P256_PEM, "payload")],
+      ["JWK as key", () => jwt.sign(HS256, {})],
+      ["raw key bytes", () => jws.sign(raw, "payload")],
+      ["returned signer", () => jwt.createSigner(oct).sign({}, { now: EC_P256_PEM })],
+    ]) {
+      const msg = message(fn);
+
+      t.expect(msg.length).as(name + " throws").toBeGreaterThan(0);
+      const leaked = [EC_P256_PEM.split("\n")[1], secret, raw.join(" ")].some((part) => msg.includes(part));
+
+      t.expect(leaked || msg.includes("PRIVATE KEY"))
+        .as(name + " redacted")
+        .toEqual(false);
+    }
   });
 
   describe("parseKeySet", (t) => {
@@ -75,6 +334,142 @@ export default function () {
     t.expect(all.length).as("number of keys").toEqual(2);
   });
 
+  describe("parse cached", (t) => {
+    const str = JSON.stringify(jwk.generate(ALG));
+    const set = JSON.stringify({ keys: [JSON.parse(str)] });
+
+    t.expect(JSON.stringify(jwk.parse(str))).as("parse again").toEqual(JSON.stringify(jwk.parse(str)));
+    t.expect(jwk.parseKeySet(set).length).as("key set").toEqual(1);
+    t.expect(jwk.parseKeySet(set).length).as("key set again").toEqual(1);
+    t.expect(JSON.stringify(jwk.parseKeySet(set)[0])).as("key set key").toEqual(JSON.stringify(jwk.parse(str)));
+    t.expect(JSON.stringify(jwk.parsePEM(EC_P256_PEM)))
+      .as("parsePEM again")
+      .toEqual(JSON.stringify(jwk.parsePEM(EC_P256_PEM)));
+  });
+
+  describe("parse object", (t) => {
+    const key = jwk.generate(ALG);
+    const obj = jwk.toObject(key);
+
+    t.expect(obj.kty).as("kty").toEqual("OKP");
+    t.expect(obj.d.length).as("d length").toBeGreaterThan(0);
+    t.expect(jwk.toObject(key.public()).d).as("public d").toEqual(undefined);
+    t.expect(jwk.toObject(jwk.parse(obj)).kid).as("parse object").toEqual(obj.kid);
+    t.expect(jwk.toObject(jwk.parse(new Uint8Array([...JSON.stringify(obj)].map((c) => c.charCodeAt(0))).buffer)).kid)
+      .as("parse ArrayBuffer")
+      .toEqual(obj.kid);
+    t.expect(jwk.parseKeySet({ keys: [obj, jwk.toObject(jwk.generate(ALG))] }).length)
+      .as("parseKeySet object")
+      .toEqual(2);
+
+    let err = null;
+    try {
+      jwk.parse(42);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("unsupported source error").toBeTruthy();
+  });
+
+  describe("rehydrate", (t) => {
+    for (let i = 0; i < SERIALIZED.length; i++) {
+      const key = jwk.rehydrate(SERIALIZED[i]);
+
+      t.expect(jwk.serialize(key)).as("serialize again").toEqual(SERIALIZED[i]);
+      t.expect(jwk.toObject(key).kid.length).as("kid length").toEqual(43);
+      t.expect(jwk.serialize(key.public()).length).as("public serialized").toBeLessThan(SERIALIZED[i].length);
+    }
+
+    const rsa = jwk.toObject(jwk.generate("RS256"));
+    const pub = jwk.rehydrate(jwk.serialize(jwk.parse(rsa).public()));
+
+    t.expect(jwk.toObject(jwk.rehydrate(jwk.serialize(jwk.parse(rsa)))).d).as("rsa d").toEqual(rsa.d);
+    t.expect(jwk.toObject(pub).n).as("rsa n").toEqual(rsa.n);
+    t.expect(jwk.toObject(pub).d).as("rsa public d").toEqual(undefined);
+
+    let err = null;
+    try {
+      jwk.rehydrate("jwk1.EC.ES256..sig.P-256");
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("invalid serialization error").toBeTruthy();
+  });
+
+  describe("exportKeys and importKeys", (t) => {
+    const handOff = (data) => JSON.parse(JSON.stringify(data));
+    const signer = jwk.generate("ES256");
+    const keys = [signer, jwk.generate("ed25519")];
+
+    const data = handOff({ signer: jwk.exportKeys(signer, { includePrivate: true }), keys: jwk.exportKeys(keys) });
+    const imported = jwk.importKeys(data.signer);
+    const verifiers = jwk.importKeys(data.keys);
+
+    t.expect(data.signer.d).as("private d").toEqual(jwk.toObject(signer).d);
+    t.expect(data.keys.length).as("keys length").toEqual(2);
+    t.expect(data.keys[0].d).as("public d").toEqual(undefined);
+    t.expect(data.keys[1].x).as("public x").toEqual(jwk.toObject(keys[1]).x);
+    t.expect(verifiers.length).as("imported keys length").toEqual(2);
+
+    const token = jwt.sign(imported, { sub: "setup" });
+    t.expect(jwt.verify(token, verifiers).sub).as("verified sub").toEqual("setup");
+    t.expect(jwt.verify(token, signer.public()).sub).as("verified with original").toEqual("setup");
+
+    const set = jwk.importKeys(handOff({ keys: jwk.exportKeys(keys) }));
+    t.expect(set.length).as("key set length").toEqual(2);
+
+    let err = null;
+    try {
+      jwk.exportKeys(jwk.parse({ kty: "oct", k: "c2VjcmV0" }));
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null && err.code).as("symmetric key error").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+    t.expect(jwk.exportKeys(jwk.parse({ kty: "oct", k: "c2VjcmV0" }), { includePrivate: true }).k)
+      .as("symmetric key")
+      .toEqual("c2VjcmV0");
+  });
+
+  describe("webcrypto", (t) => {
+    const token = jwt.sign(ECDSA_PAIR.privateKey, { sub: "webcrypto" });
+    t.expect(jwt.verify(token, ECDSA_PAIR.publicKey).sub).as("verified sub").toEqual("webcrypto");
+    t.expect(jwt.verify(token, [HMAC_KEY, ECDSA_PAIR.publicKey]).sub).as("verified by key array").toEqual("webcrypto");
+    t.expect(jwt.verify(token, jwk.fromCryptoKey(ECDSA_PAIR.publicKey)).sub).as("verified by converted").toEqual("webcrypto");
+
+    const priv = jwk.toObject(jwk.fromCryptoKey(ECDSA_PAIR.privateKey));
+    t.expect(priv.alg).as("alg").toEqual("ES256");
+    t.expect(priv.use).as("use").toEqual("sig");
+    t.expect(priv.kid).as("kid").toEqual(jwk.toObject(jwk.fromCryptoKey(ECDSA_PAIR.publicKey)).kid);
+    t.expect(priv.d.length).as("d length").toEqual(43);
+    t.expect(jwk.toObject(jwk.fromCryptoKey(HMAC_KEY)).alg).as("hmac alg").toEqual("HS384");
+    t.expect(jwk.toObject(jwk.fromCryptoKey(OAEP_PAIR.publicKey)).alg).as("oaep alg").toEqual("RSA-OAEP-256");
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    const sealed = jwe.encrypt(OAEP_PAIR.publicKey, "webcrypto");
+    t.expect(String.fromCharCode(...new Uint8Array(jwe.decrypt(OAEP_PAIR.privateKey, sealed))))
+      .as("non-extractable decrypt")
+      .toEqual("webcrypto");
+
+    const opaque = jwt.sign(OPAQUE_ECDSA.privateKey, { sub: "opaque" });
+    t.expect(jwt.verify(opaque, OPAQUE_ECDSA.publicKey).sub).as("non-extractable sign").toEqual("opaque");
+    t.expect(jwk.toObject(jwk.fromCryptoKey(OPAQUE_ECDSA.privateKey)).d).as("not exported").toEqual(undefined);
+    t.expect(code(() => jwk.fromCryptoKey(OPAQUE_HMAC))).as("non-extractable secret").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+
+    t.expect(IMPORTED.type).as("imported type").toEqual("private");
+    t.expect(IMPORTED.algorithm.name).as("imported algorithm").toEqual("ECDSA");
+    t.expect(IMPORTED.usages.join()).as("imported usages").toEqual("sign");
+    t.expect(IMPORTED_PUBLIC.usages.join()).as("imported public usages").toEqual("verify");
+    t.expect(jwk.toObject(jwk.fromCryptoKey(IMPORTED)).alg).as("round trip alg").toEqual("ES384");
+  });
+
   describe("adopt", (t) => {
     const seed = new ArrayBuffer(32);
     const bytes = new Uint8Array(seed);
@@ -105,4 +500,377 @@ export default function () {
       expect("d").toEqual(undefined);
     });
   });
+
+  describe("parsePEM", (t) => {
+    const key = JSON.parse(JSON.stringify(jwk.parsePEM(EC_P256_PEM)));
+    const expect = (prop) => t.expect(key[prop]).as(prop);
+
+    expect("alg").toEqual("ES256");
+    expect("crv").toEqual("P-256");
+    expect("kty").toEqual("EC");
+    expect("use").toEqual("sig");
+    t.expect(key.kid.length).as("kid length").toEqual(43);
+    t.expect(typeof key.d).as("d type").toEqual("string");
+
+    let err = null;
+    try {
+      jwk.parsePEM("not a PEM");
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("invalid PEM error").toBeTruthy();
+  });
+
+  describe("certificate chain", (t) => {
+    const der = (pem) => pem.replace(/-----[A-Z ]+-----|\s/g, "");
+    const x5c = [der(CHAIN_LEAF), der(CHAIN_INTERMEDIATE)];
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+    };
+
+    const key = jwk.parse({ ...jwk.toObject(CHAIN_LEAF_KEY), x5c });
+    const kid = (k) => jwk.toObject(k).kid;
+
+    t.expect(kid(jwk.verifyChain(key, CHAIN_ROOT))).as("valid chain").toEqual(kid(key));
+    t.expect(kid(jwk.verifyChain(key, [CHAIN_OTHER_ROOT, CHAIN_ROOT]))).as("root array").toEqual(kid(key));
+    t.expect(code(() => jwk.verifyChain(key, CHAIN_OTHER_ROOT))).as("wrong root").toEqual("ERR_JOSE_CHAIN_INVALID");
+    t.expect(code(() => jwk.verifyChain(key, CHAIN_ROOT, { now: "2040-01-01T00:00:00Z" })))
+      .as("expired")
+      .toEqual("ERR_JOSE_CHAIN_INVALID");
+    t.expect(code(() => jwk.verifyChain(key, CHAIN_ROOT, { now: "2024-01-01T00:00:00Z" })))
+      .as("not yet valid")
+      .toEqual("ERR_JOSE_CHAIN_INVALID");
+    t.expect(code(() => jwk.verifyChain(CHAIN_LEAF_KEY, CHAIN_ROOT))).as("missing x5c").toEqual("ERR_JOSE_CHAIN_INVALID");
+
+    const leafOnly = jwk.parse({ ...jwk.toObject(CHAIN_LEAF_KEY), x5c: [x5c[0]] });
+
+    t.expect(code(() => jwk.verifyChain(leafOnly, CHAIN_ROOT))).as("missing intermediate").toEqual("ERR_JOSE_CHAIN_INVALID");
+    t.expect(kid(jwk.verifyChain(leafOnly, CHAIN_ROOT, { intermediates: CHAIN_INTERMEDIATE })))
+      .as("given intermediate")
+      .toEqual(kid(key));
+
+    const token = jwt.sign(CHAIN_LEAF_KEY, { sub: "alice" }, { x5c, kid: "leaf" });
+    const embedded = jwk.fromX5C(token, CHAIN_ROOT);
+    const object = jwk.toObject(embedded);
+
+    t.expect(object.kid).as("embedded kid").toEqual("leaf");
+    t.expect(object.x5c.length).as("embedded x5c").toEqual(2);
+    t.expect(object.alg).as("embedded alg").toEqual("ES256");
+    t.expect(jwt.verify(token, embedded).sub).as("embedded key").toEqual("alice");
+    t.expect(jwt.verifyClaims(token, null, { roots: CHAIN_ROOT, subject: "alice" }).sub).as("roots option").toEqual("alice");
+    t.expect(code(() => jwt.verifyClaims(token, null, { roots: CHAIN_OTHER_ROOT })))
+      .as("untrusted roots")
+      .toEqual("ERR_JOSE_CHAIN_INVALID");
+
+    const forged = jwt.sign(jwk.generate("ES256"), { sub: "mallory" }, { x5c, kid: "leaf" });
+
+    t.expect(code(() => jwt.verifyClaims(forged, null, { roots: CHAIN_ROOT })))
+      .as("forged signature")
+      .toEqual("ERR_JOSE_SIGNATURE_INVALID");
+
+    const serialized = jws.sign(CHAIN_LEAF_KEY, "payload", { x5c }, { serialization: "general" });
+
+    t.expect(jwk.toObject(jwk.fromX5C(serialized, CHAIN_ROOT)).alg).as("JSON serialization").toEqual("ES256");
+    t.expect(code(() => jwk.fromX5C(jwt.sign(CHAIN_LEAF_KEY, { sub: "alice" }), CHAIN_ROOT)))
+      .as("token without x5c")
+      .toEqual("ERR_JOSE_CHAIN_INVALID");
+  });
+
+  describe("diffKeySets", (t) => {
+    const [first, second, third, fourth, fifth] = jwk.generateMany("ES256", 5);
+    const entry = (key, kid) => Object.assign(jwk.toObject(key), { kid });
+    const before = { keys: [entry(first, "a"), entry(second, "b"), entry(third, "c")] };
+    const after = { keys: [entry(first, "a"), entry(fourth, "b"), entry(fifth, "d")] };
+
+    const diff = jwk.diffKeySets(before, after);
+
+    t.expect(diff.added.join()).as("added").toEqual("d");
+    t.expect(diff.removed.join()).as("removed").toEqual("c");
+    t.expect(diff.changed.join()).as("changed").toEqual("b");
+    t.expect(diff.unchanged.join()).as("unchanged").toEqual("a");
+    t.expect(diff.rotated).as("rotated").toEqual(true);
+
+    const same = jwk.diffKeySets([first, second], [second, first]);
+
+    t.expect(same.rotated).as("same keys").toEqual(false);
+    t.expect(same.unchanged.length).as("thumbprint kids").toEqual(2);
+
+    const use = { keys: [Object.assign(entry(first, "a"), { use: "enc" })] };
+
+    t.expect(jwk.diffKeySets({ keys: [entry(first, "a")] }, use).changed.join()).as("use").toEqual("a");
+
+    const pair = { keys: [entry(first, "a"), Object.assign(entry(second, "a"), { use: "enc" })] };
+    const swapped = { keys: [entry(first, "a"), Object.assign(entry(third, "a"), { use: "enc" })] };
+
+    t.expect(jwk.diffKeySets(pair, swapped).changed.join()).as("duplicate kid changed").toEqual("a");
+    const reversed = { keys: pair.keys.slice().reverse() };
+
+    t.expect(jwk.diffKeySets(pair, reversed).rotated).as("duplicate kid order").toEqual(false);
+    t.expect(jwk.diffKeySets({ keys: [entry(first, "a")] }, pair).changed.join()).as("duplicate added").toEqual("a");
+    t.expect(jwk.diffKeySets(before, pair).duplicated.join()).as("duplicated").toEqual("a");
+  });
+
+  describe("deriveSharedSecret", (t) => {
+    const alice = jwk.generate("ES256");
+    const bob = jwk.generate("ES256");
+
+    t.expect(b64encode(jwk.deriveSharedSecret(alice, bob.public())))
+      .as("P-256 agreement")
+      .toEqual(b64encode(jwk.deriveSharedSecret(bob, alice.public())));
+    t.expect(jwk.deriveSharedSecret(alice, bob).byteLength).as("P-256 size").toEqual(32);
+
+    // RFC 7748 section 6.1
+    const x25519 = jwk.deriveSharedSecret(
+      { kty: "OKP", crv: "X25519", d: "dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo" },
+      JSON.stringify({ kty: "OKP", crv: "X25519", x: "3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08" }),
+    );
+
+    t.expect(b64encode(x25519)).as("X25519").toEqual("Sl2dW6TOLeFyjjv0gDUPJeB+IclH0Z4zdvCbPB4WF0I=");
+
+    // RFC 7518 Appendix C
+    const ephemeral = {
+      kty: "EC",
+      crv: "P-256",
+      x: "gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0",
+      y: "SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps",
+      d: "0_NxaRPUMQoAJt50Gz8YiTr8gRTwyEaCumd-MToTmIo",
+    };
+    const recipient = {
+      kty: "EC",
+      crv: "P-256",
+      x: "weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ",
+      y: "e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck",
+    };
+    const options = { kdf: "concat", length: 16, algorithm: "A128GCM", apu: "Alice", apv: "Bob" };
+
+    t.expect(b64encode(jwk.deriveSharedSecret(ephemeral, recipient, options), "rawurl"))
+      .as("Concat-KDF")
+      .toEqual("VqqN6vgjbSBcIijNcacQGg");
+    t.expect(jwk.deriveSharedSecret(alice, bob, { kdf: "concat" }).byteLength).as("default length").toEqual(32);
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.deriveSharedSecret(alice, jwk.generate("ES384")))).as("curves").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+    t.expect(code(() => jwk.deriveSharedSecret(alice.public(), bob))).as("public").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+    t.expect(code(() => jwk.deriveSharedSecret(alice, bob, { kdf: "hkdf" }))).as("kdf").toEqual("ERR_JOSE_ALG_UNSUPPORTED");
+  });
+
+  describe("certificateThumbprints", (t) => {
+    const der = b64decode(CHAIN_LEAF.replace(/-----[A-Z ]+-----|\s/g, ""));
+    const thumbprints = jwk.certificateThumbprints(CHAIN_LEAF);
+
+    t.expect(thumbprints.x5t).as("x5t").toEqual("__Pj_g0L-boT9uPW_u-FjoJxxRc");
+    t.expect(thumbprints["x5t#S256"]).as("x5t#S256").toEqual("8Y3W8CTjNZgwh2UV0R86ZTm0rSwRD3yJ8KBp9CE57ik");
+    t.expect(jwk.certificateThumbprints(der).x5t).as("DER").toEqual(thumbprints.x5t);
+    t.expect(jwk.certificateThumbprints(CHAIN_LEAF + CHAIN_INTERMEDIATE).x5t).as("leaf of bundle").toEqual(thumbprints.x5t);
+
+    const x5c = [CHAIN_LEAF, CHAIN_INTERMEDIATE].map((pem) => pem.replace(/-----[A-Z ]+-----|\s/g, ""));
+    const key = jwk.fromX5C(jwt.sign(CHAIN_LEAF_KEY, { sub: "alice" }, { x5c }), CHAIN_ROOT);
+
+    t.expect(jwk.certificateThumbprints(key)["x5t#S256"]).as("key x5c").toEqual(thumbprints["x5t#S256"]);
+
+    const token = jwt.sign(CHAIN_LEAF_KEY, { sub: "alice" }, { ...thumbprints });
+    const header = JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+
+    t.expect(header["x5t#S256"]).as("header").toEqual(thumbprints["x5t#S256"]);
+
+    let err = null;
+    try {
+      jwk.certificateThumbprints(CHAIN_LEAF_KEY);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null && err.code).as("key without x5c").toEqual("ERR_JOSE_CHAIN_INVALID");
+  });
+
+  describe("fromRSAComponents", (t) => {
+    const source = jwk.toObject(jwk.parse(RSA_2048));
+    const hex = (value) =>
+      Array.from(new Uint8Array(b64decode(value, "rawurl")), (b) => b.toString(16).padStart(2, "0")).join(":");
+
+    const recovered = jwk.fromRSAComponents({ n: source.n, e: source.e, d: source.d });
+    const object = jwk.toObject(recovered);
+
+    t.expect(object.p + object.q).as("recovered primes").toEqual(source.p + source.q);
+    t.expect(object.dp).as("CRT values").toEqual(source.dp);
+    t.expect(object.kid).as("thumbprint kid").toEqual(jwk.toObject(jwk.fromRSAComponents({ n: source.n, e: source.e })).kid);
+    t.expect(jwt.verify(jwt.sign(recovered, { sub: "rsa" }), jwk.parse(RSA_2048).public()).sub).as("signs").toEqual("rsa");
+
+    const fromHex = jwk.fromRSAComponents(
+      { n: hex(source.n), e: "0x010001", d: hex(source.d), p: hex(source.p), q: hex(source.q) },
+      { encoding: "hex", alg: "PS256", kid: "ceremony" },
+    );
+
+    t.expect(jwk.toObject(fromHex).d).as("hex").toEqual(source.d);
+    t.expect(jwk.toObject(fromHex).alg + jwk.toObject(fromHex).kid).as("options").toEqual("PS256ceremony");
+    t.expect(jwk.toObject(jwk.fromRSAComponents({ n: source.n, e: source.e })).d).as("public").toEqual(undefined);
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.fromRSAComponents({ n: source.n, e: source.e, d: source.p }))).as("wrong d").toEqual("ERR_JOSE_KEY_INVALID");
+    t.expect(code(() => jwk.fromRSAComponents({ n: source.n }))).as("missing e").toEqual("ERR_JOSE_KEY_INVALID");
+    t.expect(code(() => jwk.fromRSAComponents({ n: "zz", e: "AQAB" }, { encoding: "hex" }))).as("bad hex").toEqual("ERR_JOSE_KEY_INVALID");
+  });
+
+  describe("fromECComponents", (t) => {
+    const source = jwk.toObject(jwk.generate("ES384"));
+
+    const derived = jwk.fromECComponents({ crv: "P-384", d: source.d });
+    const object = jwk.toObject(derived);
+
+    t.expect(object.x + object.y).as("derived point").toEqual(source.x + source.y);
+    t.expect(object.alg + object.kid).as("defaults").toEqual("ES384" + source.kid);
+
+    const pub = jwk.fromECComponents({ crv: "P-384", x: source.x, y: source.y });
+
+    t.expect(jwt.verify(jwt.sign(derived, { sub: "ec" }), pub).sub).as("signs").toEqual("ec");
+    t.expect(jwk.toObject(pub).d).as("public").toEqual(undefined);
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.fromECComponents({ crv: "P-384", x: source.y, y: source.x }))).as("off curve").toEqual("ERR_JOSE_KEY_INVALID");
+    t.expect(code(() => jwk.fromECComponents({ crv: "P-256", x: source.x, y: source.y }))).as("wrong curve").toEqual("ERR_JOSE_KEY_INVALID");
+    t.expect(code(() => jwk.fromECComponents({ crv: "P-384", x: source.x, y: source.y, d: jwk.toObject(jwk.generate("ES384")).d }))).as("mismatch").toEqual("ERR_JOSE_KEY_INVALID");
+    t.expect(code(() => jwk.fromECComponents({ crv: "secp256k1", d: source.d }))).as("curve").toEqual("ERR_JOSE_KEY_INVALID");
+  });
+
+  describe("brainpool", (t) => {
+    const key = jwk.generate("ESB384");
+    const object = jwk.toObject(key);
+
+    t.expect(object.crv + object.alg + object.d.length).as("generate").toEqual("BP-384ESB38464");
+    t.expect(jwk.toObject(key.public()).d).as("public").toEqual(undefined);
+
+    const parsed = jwk.parse(object);
+
+    t.expect(jwk.toObject(parsed).kid).as("parse").toEqual(object.kid);
+    t.expect(jwk.parseKeySet({ keys: [jwk.toObject(key.public())] }).length).as("parseKeySet").toEqual(1);
+    t.expect(jwk.toObject(jwk.rehydrate(jwk.serialize(key))).d).as("serialize").toEqual(object.d);
+
+    const derived = jwk.fromECComponents({ crv: "BP-384", d: object.d });
+
+    t.expect(jwk.toObject(derived).x + jwk.toObject(derived).y).as("fromECComponents").toEqual(object.x + object.y);
+
+    const raw = (value) => Array.from(new Uint8Array(b64decode(value, "rawurl")));
+    const point = new Uint8Array([4, ...raw(object.x), ...raw(object.y)]).buffer;
+
+    t.expect(jwk.toObject(jwk.adopt("ESB384", b64decode(object.d, "rawurl"))).kid).as("adopt").toEqual(object.kid);
+    t.expect(jwk.toObject(jwk.adopt("ESB384", point, true)).kid).as("adopt public").toEqual(object.kid);
+
+    const token = jwt.sign(key, { sub: "bp" });
+
+    t.expect(JSON.parse(b64decode(token.split(".")[0], "rawurl", "s")).alg).as("alg").toEqual("ESB384");
+    t.expect(jwt.verify(token, parsed.public()).sub).as("jwt").toEqual("bp");
+    t.expect(String.fromCharCode(...new Uint8Array(jws.verify(jws.sign(key, "bp"), key.public())))).as("jws").toEqual("bp");
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    const other = jwk.generate("ESB384");
+
+    t.expect(code(() => jwt.verify(token, other.public()))).as("wrong key").toEqual("ERR_JOSE_KID_UNKNOWN");
+    const forged = [...token.split(".").slice(0, 2), jwt.sign(other, { sub: "bp" }).split(".")[2]].join(".");
+
+    t.expect(code(() => jwt.verify(forged, key.public()))).as("wrong signature").toEqual("ERR_JOSE_SIGNATURE_INVALID");
+    t.expect(code(() => jwk.parse({ ...object, d: undefined, y: object.x }))).as("off curve").toEqual("ERR_JOSE_KEY_INVALID");
+  });
+
+  describe("parse data URI", (t) => {
+    const key = jwk.parse(EC_P256);
+    const set = JSON.stringify({ keys: [JSON.parse(EC_P256)] });
+
+    t.expect(jwk.toObject(jwk.parse("data:application/jwk+json;base64," + b64encode(EC_P256))).kid)
+      .as("base64")
+      .toEqual(jwk.toObject(key).kid);
+    t.expect(jwk.toObject(jwk.parse("data:application/jwk+json;base64," + b64encode(EC_P256, "rawurl"))).d)
+      .as("base64url")
+      .toEqual(jwk.toObject(key).d);
+    t.expect(jwk.parseKeySet("data:application/jwk-set+json," + encodeURIComponent(set)).length).as("percent encoded").toEqual(1);
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.parse("data:application/json;base64"))).as("missing data").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwk.parse("data:;base64,!!!"))).as("bad base64").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwk.parseKeySet("https://127.0.0.1:1/jwks.json"))).as("unreachable").toEqual("ERR_JOSE_FETCH_FAILED");
+  });
+
+  describe("fromOKPComponents", (t) => {
+    // RFC 8037 Appendix A
+    const ed = jwk.fromOKPComponents({ crv: "Ed25519", d: "nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A" });
+    const pub = jwk.fromOKPComponents({ crv: "Ed25519", x: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo" });
+
+    t.expect(jwk.toObject(ed).x).as("Ed25519 x").toEqual("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo");
+    t.expect(jwk.toObject(pub).kid).as("Ed25519 kid").toEqual("kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k");
+    t.expect(jwt.verify(jwt.sign(ed, { sub: "okp" }), pub).sub).as("signs").toEqual("okp");
+
+    // RFC 7748 section 6.1
+    const alice = jwk.fromOKPComponents(
+      { crv: "X25519", d: "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a" },
+      { encoding: "hex" },
+    );
+
+    t.expect(alice.kty + alice.crv + alice.x).as("X25519").toEqual("OKPX25519hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo");
+    t.expect(alice.alg + alice.use).as("X25519 defaults").toEqual("ECDH-ESenc");
+
+    const bob = jwk.fromOKPComponents({ crv: "X25519", x: "3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08" });
+
+    t.expect(bob.d).as("X25519 public").toEqual(undefined);
+    t.expect(b64encode(jwk.deriveSharedSecret(alice, bob))).as("agreement").toEqual("Sl2dW6TOLeFyjjv0gDUPJeB+IclH0Z4zdvCbPB4WF0I=");
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code(() => jwk.fromOKPComponents({ crv: "Ed25519", x: "AAAA" }))).as("size").toEqual("ERR_JOSE_KEY_INVALID");
+    t.expect(code(() => jwk.fromOKPComponents({ crv: "X25519", x: alice.x, d: jwk.toObject(ed).d }))).as("mismatch").toEqual("ERR_JOSE_KEY_INVALID");
+    t.expect(code(() => jwk.fromOKPComponents({ crv: "X448", x: alice.x }))).as("curve").toEqual("ERR_JOSE_KEY_INVALID");
+
+    const adopted = jwk.adopt("X25519", b64decode(alice.d, "rawurl"));
+    t.expect(adopted.x + adopted.kid).as("adopt X25519").toEqual(alice.x + alice.kid);
+    t.expect(jwk.adopt("X25519", b64decode(bob.x, "rawurl"), true).kid).as("adopt X25519 public").toEqual(bob.kid);
+    t.expect(code(() => jwk.adopt("ed25519", b64decode(alice.x, "rawurl")))).as("adopt size").toEqual("ERR_JOSE_KEY_INVALID");
+  });
 }
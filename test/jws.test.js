@@ -0,0 +1,358 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import jws from "k6/x/jose/jws";
+import jwk from "k6/x/jose/jwk";
+import { describe } from "./expect.js";
+import { b64decode, b64encode } from "k6/encoding";
+import { EC_P256, RSA_2048, HS256 } from "./keys.js";
+
+const ALG = "ed25519";
+
+function binary() {
+  const buff = new ArrayBuffer(256);
+  const bytes = new Uint8Array(buff);
+  bytes.forEach((_, idx) => (bytes[idx] = idx));
+  return buff;
+}
+
+function same(a, b) {
+  const x = new Uint8Array(a);
+  const y = new Uint8Array(b);
+  return x.length === y.length && x.every((value, idx) => value === y[idx]);
+}
+
+const ASYNC_KEY = jwk.generate(ALG);
+const ASYNC_PAYLOAD = binary();
+const ASYNC_TOKEN = await jws.signAsync(ASYNC_KEY, ASYNC_PAYLOAD, { cty: "application/octet-stream" });
+const ASYNC_VERIFIED = await jws.verifyAsync(ASYNC_TOKEN, jwk.generate(ALG).public(), ASYNC_KEY.public());
+const ASYNC_REJECTED = await jws.verifyAsync(ASYNC_TOKEN, jwk.generate(ALG).public()).then(
+  () => null,
+  (e) => e.code
+);
+
+export default function () {
+  describe("sign", (t) => {
+    const token = jws.sign(jwk.generate(ALG), "hello");
+
+    t.expect(token.length).as("token length").toBeGreaterThan(0);
+    t.expect(token.split(".").length).as("number of fields").toEqual(3);
+  });
+
+  describe("verify", (t) => {
+    const key1 = jwk.generate(ALG);
+    const key2 = jwk.generate(ALG);
+    const payload = binary();
+
+    const token = jws.sign(key2, payload, { cty: "application/octet-stream" });
+    const verified = jws.verify(token, key1.public(), key2.public());
+
+    t.expect(same(verified, payload)).as("payload").toBeTruthy();
+
+    let err = null;
+    try {
+      jws.verify(token, key1.public());
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("wrong key error").toBeTruthy();
+  });
+
+  describe("signAsync and verifyAsync", (t) => {
+    t.expect(ASYNC_VERIFIED instanceof ArrayBuffer).as("ArrayBuffer").toEqual(true);
+    t.expect(same(ASYNC_VERIFIED, ASYNC_PAYLOAD)).as("payload").toBeTruthy();
+    t.expect(same(jws.verify(ASYNC_TOKEN, ASYNC_KEY.public()), ASYNC_PAYLOAD)).as("verify").toBeTruthy();
+    t.expect(ASYNC_REJECTED).as("wrong key").toEqual("ERR_JOSE_KID_UNKNOWN");
+  });
+
+  describe("flattened", (t) => {
+    const key = jwk.generate(ALG);
+    const payload = binary();
+    const token = jws.sign(key, payload, null, { serialization: "flattened" });
+    const json = JSON.parse(token);
+
+    t.expect(typeof json.payload).as("payload type").toEqual("string");
+    t.expect(typeof json.protected).as("protected type").toEqual("string");
+    t.expect(typeof json.signature).as("signature type").toEqual("string");
+    t.expect(same(jws.verify(token, key.public()), payload)).as("payload").toBeTruthy();
+  });
+
+  describe("general", (t) => {
+    const key = jwk.generate(ALG);
+    const payload = binary();
+    const token = jws.sign(key, payload, null, { serialization: "general" });
+    const json = JSON.parse(token);
+
+    t.expect(json.signatures.length).as("number of signatures").toEqual(1);
+    t.expect(typeof json.signatures[0].protected).as("protected type").toEqual("string");
+    t.expect(same(jws.verify(token, key.public()), payload)).as("payload").toBeTruthy();
+  });
+
+  describe("multiple signatures", (t) => {
+    const key1 = jwk.generate(ALG);
+    const key2 = jwk.generate(ALG);
+    const other = jwk.generate(ALG);
+    const payload = binary();
+    const token = jws.sign([key1, key2], payload);
+
+    t.expect(JSON.parse(token).signatures.length).as("number of signatures").toEqual(2);
+    t.expect(same(jws.verify(token, key2.public()), payload)).as("any payload").toBeTruthy();
+    t.expect(same(jws.verifyAll(token, key1.public(), key2.public()), payload)).as("all payload").toBeTruthy();
+
+    let err = null;
+    try {
+      jws.verifyAll(token, key1.public(), other.public());
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("missing key error").toBeTruthy();
+  });
+
+  describe("detached", (t) => {
+    const key = jwk.generate(ALG);
+    const body = '{"Data":{"Amount":"10.00"}}';
+    const token = jws.sign(key, body, null, { detached: true, b64: false });
+    const parts = token.split(".");
+    const header = JSON.parse(b64decode(parts[0], "rawurl", "s"));
+
+    t.expect(parts.length).as("number of fields").toEqual(3);
+    t.expect(parts[1]).as("payload field").toEqual("");
+    t.expect(header.b64).as("b64 header").toEqual(false);
+    t.expect(header.crit[0]).as("crit header").toEqual("b64");
+
+    jws.verifyDetached(token, body, key.public());
+
+    let err = null;
+    try {
+      jws.verifyDetached(token, body + " ", key.public());
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("tampered content error").toBeTruthy();
+
+    const encoded = jws.sign(key, binary(), null, { detached: true, serialization: "flattened" });
+    t.expect("payload" in JSON.parse(encoded)).as("payload present").toEqual(false);
+    jws.verifyDetached(encoded, binary(), key.public());
+  });
+
+  describe("unencoded", (t) => {
+    const key = jwk.generate(ALG);
+    const token = jws.sign(key, "hello world", null, { b64: false, serialization: "flattened" });
+
+    t.expect(JSON.parse(token).payload).as("payload").toEqual("hello world");
+    t.expect(String.fromCharCode(...new Uint8Array(jws.verify(token, key.public())))).as("verified").toEqual("hello world");
+  });
+
+  describe("binary inputs", (t) => {
+    const key = jwk.generate(ALG);
+    const bytes = new Uint8Array([0xff, 0xfe, 0x00, 0x80, 0xc3]);
+
+    const typed = jws.sign(key, bytes);
+    t.expect(same(jws.verify(typed, key.public()), bytes.buffer)).as("Uint8Array").toBeTruthy();
+
+    const view = jws.sign(key, bytes.subarray(1, 3));
+    t.expect(same(jws.decode(view), bytes.slice(1, 3).buffer)).as("Uint8Array view").toBeTruthy();
+
+    const array = jws.sign(key, Array.from(bytes));
+    t.expect(same(jws.decode(array), bytes.buffer)).as("array of numbers").toBeTruthy();
+
+    jws.verifyDetached(jws.sign(key, bytes, null, { detached: true }), bytes.buffer, key.public());
+
+    let err = null;
+    try {
+      jws.sign(key, bytes, null, { b64: false });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("unencoded binary error").toBeTruthy();
+
+    err = null;
+    try {
+      jws.sign(key, [1, 256]);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("invalid byte error").toBeTruthy();
+  });
+
+  describe("unprotected", (t) => {
+    const key1 = jwk.generate(ALG);
+    const key2 = jwk.generate(ALG);
+    const kid = JSON.parse(JSON.stringify(key1)).kid;
+
+    const token = jws.sign(key1, "hello", null, { unprotected: { kid: kid } });
+    const parsed = jws.parse(token);
+
+    t.expect(parsed.signatures.length).as("number of signatures").toEqual(1);
+    t.expect(parsed.signatures[0].header.kid).as("unprotected kid").toEqual(kid);
+    t.expect("kid" in parsed.signatures[0].protected).as("protected kid").toEqual(false);
+    t.expect(parsed.signatures[0].protected.alg).as("protected alg").toEqual("EdDSA");
+    jws.verify(token, key2.public(), key1.public());
+
+    const multi = jws.sign([key1, key2], "hello", null, { unprotected: [null, { foo: "bar" }] });
+    const sigs = jws.parse(multi).signatures;
+
+    t.expect(sigs[0].header).as("first header").toEqual(null);
+    t.expect(sigs[1].header.foo).as("second header").toEqual("bar");
+    jws.verifyAll(multi, key1.public(), key2.public());
+  });
+
+  describe("saltLength", (t) => {
+    const key = jwk.generate("PS384");
+    const other = jwk.generate(ALG);
+    const token = jws.sign([key, other], "hello", null, { saltLength: "max" });
+    const header = jws.parse(token).signatures[0].protected;
+
+    t.expect(header.alg).as("alg").toEqual("PS384");
+    t.expect(header.kid).as("kid").toEqual(jwk.toObject(key).kid);
+    jws.verifyAll(token, key.public(), other.public());
+
+    let code = null;
+    try {
+      jws.sign(key, "hello", null, { saltLength: -1 });
+    } catch (e) {
+      code = e.code;
+    }
+    t.expect(code).as("invalid").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("critical", (t) => {
+    const key = jwk.generate(ALG);
+    const token = jws.sign(key, "hello", { sigT: "2026-10-14T12:00:00Z" }, { critical: ["sigT"] });
+    const header = JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+
+    t.expect(header.crit.join(",")).as("crit header").toEqual("sigT");
+
+    const failure = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(failure(() => jws.verify(token, key.public()))).as("not handled").toEqual("ERR_JOSE_CRIT_UNSUPPORTED");
+    t.expect(failure(() => jws.sign(key, "hello", null, { critical: ["sigT"] }))).as("missing").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(failure(() => jws.sign(key, "hello", { typ: "JOSE" }, { critical: ["typ"] }))).as("registered").toEqual("ERR_JOSE_MALFORMED");
+
+    const both = jws.sign(key, "hello", { sigT: "now" }, { b64: false, critical: ["sigT"], serialization: "flattened" });
+    t.expect(JSON.parse(b64decode(JSON.parse(both).protected, "rawurl", "s")).crit.join(",")).as("with b64").toEqual("b64,sigT");
+  });
+
+  describe("verifyDetailed", (t) => {
+    const key1 = jwk.generate(ALG);
+    const key2 = jwk.generate(ALG);
+    const kid = JSON.parse(JSON.stringify(key2)).kid;
+
+    const token = jws.sign([key1, key2], "hello");
+    const result = jws.verifyDetailed(token, key2.public());
+
+    t.expect(result.index).as("index").toEqual(1);
+    t.expect(result.kid).as("kid").toEqual(kid);
+    t.expect(result.alg).as("alg").toEqual("EdDSA");
+    t.expect(new Uint8Array(result.payload).length).as("payload length").toEqual(5);
+  });
+
+  describe("createStreamSigner", (t) => {
+    const payload = binary();
+    const chunks = [payload.slice(0, 100), payload.slice(100, 101), payload.slice(101)];
+
+    [jwk.generate(ALG), jwk.parse(EC_P256), jwk.parse(RSA_2048), jwk.parse(HS256)].forEach((key) => {
+      const alg = JSON.parse(JSON.stringify(key)).alg;
+      const signer = jws.createStreamSigner(key, { cty: "application/octet-stream" });
+
+      chunks.forEach((chunk) => signer.update(chunk));
+
+      const token = signer.finish();
+
+      t.expect(token.split(".")[1]).as(alg + " payload field").toEqual("");
+      jws.verifyDetached(token, payload, alg == "HS256" ? key : key.public());
+    });
+
+    const unencoded = jws.createStreamSigner(jwk.parse(EC_P256), null, { b64: false });
+    unencoded.update("hello ");
+    unencoded.update("world");
+    jws.verifyDetached(unencoded.finish(), "hello world", jwk.parse(EC_P256).public());
+
+    const rsa = jwk.parse(RSA_2048);
+    const pss = jws.createStreamSigner(rsa, { alg: "PS384" });
+    pss.update("hello");
+
+    const pssToken = pss.finish();
+
+    t.expect(JSON.parse(b64decode(pssToken.split(".")[0], "rawurl", "s")).alg).as("alg override").toEqual("PS384");
+    jws.verifyDetached(pssToken, "hello", rsa.public());
+
+    const fails = (key, header) => {
+      try {
+        jws.createStreamSigner(key, header);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails(jwk.parse(EC_P256), { alg: "ES384" })).as("curve mismatch").toEqual(true);
+    t.expect(fails(rsa, { alg: "HS256" })).as("key type mismatch").toEqual(true);
+    t.expect(fails(rsa, { alg: 256 })).as("alg type").toEqual(true);
+  });
+
+  describe("decode", (t) => {
+    const payload = binary();
+    const token = jws.sign(jwk.generate(ALG), payload);
+
+    t.expect(same(jws.decode(token), payload)).as("payload").toBeTruthy();
+  });
+
+  describe("hmac", (t) => {
+    const key = jwk.parse({ kty: "oct", k: "a2V5" });
+    const data = "The quick brown fox jumps over the lazy dog";
+    const mac = jws.hmac(key, data, "HS256");
+
+    t.expect(b64encode(mac)).as("HS256").toEqual("97yD9DBThCSxMpjmqm+xQ+9NWaFJRhdZl0edvC0aPNg=");
+    t.expect(b64encode(jws.hmac(key, data))).as("default algorithm").toEqual(b64encode(mac));
+    t.expect(jws.hmac(key, data, "HS384").byteLength).as("HS384").toEqual(48);
+    t.expect(jws.hmac(jwk.parse(HS256), data, "HS512").byteLength).as("HS512").toEqual(64);
+
+    jws.verifyHmac(key, data, mac);
+    jws.verifyHmac(jwk.parse(HS256), data, jws.hmac(jwk.parse(HS256), data));
+
+    const failure = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(failure(() => jws.verifyHmac(key, data + ".", mac))).as("tampered").toEqual("ERR_JOSE_SIGNATURE_INVALID");
+    t.expect(failure(() => jws.verifyHmac(key, data, mac, "HS512"))).as("other algorithm").toEqual("ERR_JOSE_SIGNATURE_INVALID");
+    t.expect(failure(() => jws.hmac(key, data, "RS256"))).as("not HMAC").toEqual("ERR_JOSE_ALG_UNSUPPORTED");
+    t.expect(failure(() => jws.hmac(jwk.parse(EC_P256), data))).as("not oct").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+  });
+}
@@ -26,10 +26,68 @@ export { options } from "./expect.js";
 
 import jwt from "k6/x/jose/jwt";
 import jwk from "k6/x/jose/jwk";
+import jws from "k6/x/jose/jws";
+import jwe from "k6/x/jose/jwe";
 import { describe } from "./expect.js";
+import { b64decode, b64encode } from "k6/encoding";
+import { EC_P256 } from "./keys.js";
 
 const ALG = "ed25519";
 
+// RSA-PSS salt lengths of signatures, checked by WebCrypto verification with digest length and maximal salt
+const PSS_PAIR = await crypto.subtle.generateKey(
+  { name: "RSA-PSS", modulusLength: 2048, publicExponent: new Uint8Array([1, 0, 1]), hash: "SHA-256" },
+  true,
+  ["sign", "verify"]
+);
+const PSS_KEY = jwk.fromCryptoKey(PSS_PAIR.privateKey);
+const PSS_PUBLIC = PSS_PAIR.publicKey;
+
+async function saltLength(token) {
+  const [header, payload, signature] = token.split(".");
+  const input = b64decode(b64encode(`${header}.${payload}`));
+  const verify = (saltLength) =>
+    crypto.subtle.verify({ name: "RSA-PSS", saltLength }, PSS_PUBLIC, b64decode(signature, "rawurl"), input);
+
+  if (await verify(32)) {
+    return "hash";
+  }
+
+  return (await verify(222)) ? "max" : "other";
+}
+
+const PSS_SALTS = {
+  default: await saltLength(jwt.sign(PSS_KEY, { sub: "alice" })),
+  hash: await saltLength(jwt.sign(PSS_KEY, { sub: "alice" }, null, { saltLength: "hash" })),
+  max: await saltLength(jwt.sign(PSS_KEY, { sub: "alice" }, null, { saltLength: "max" })),
+  number: await saltLength(jwt.sign(PSS_KEY, { sub: "alice" }, null, { saltLength: 222 })),
+  signer: await saltLength(jwt.createSigner(PSS_KEY, { saltLength: "max" }).sign({ sub: "alice" })),
+};
+
+// x5c certificate of the reissued header, standard base64 DER
+const X5C = open("./fixtures/chain-leaf.pem")
+  .split("\n")
+  .filter((line) => line && !line.startsWith("-----"))
+  .join("");
+
+// async variants settled in the init context, the clock option is called before signing
+const ASYNC_KEY = jwk.generate("ES256");
+const ASYNC_TOKEN = await jwt.signAsync(
+  ASYNC_KEY,
+  { sub: "alice" },
+  { kid: "async" },
+  { expiredBy: 0, now: () => 1700000000 }
+);
+const ASYNC_CLAIMS = await jwt.verifyAsync(ASYNC_TOKEN, ASYNC_KEY.public());
+const ASYNC_REJECTED = await jwt.verifyAsync(ASYNC_TOKEN, jwk.generate("ES256").public()).then(
+  () => null,
+  (e) => e.code
+);
+const ASYNC_INVALID = await jwt.signAsync(ASYNC_KEY, {}, null, { now: {} }).then(
+  () => null,
+  (e) => e.code
+);
+
 export default function () {
   describe("sign", (t) => {
     const token = jwt.sign(jwk.generate(ALG), { foo: "bar" });
@@ -38,6 +96,301 @@ export default function () {
     t.expect(token.split(".").length).as("number of fields").toEqual(3);
   });
 
+  describe("sign algorithms", (t) => {
+    const secret = "c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0LXNlY3JldA";
+    const hmac = jwk.parse({ kty: "oct", k: secret, alg: "HS256", kid: "hmac" });
+    const keys = ["RS256", "PS384", "ES256", "ES384", "ES512"].map((alg) => jwk.generate(alg)).concat([hmac]);
+
+    for (const key of keys) {
+      const alg = jwk.toObject(key).alg;
+      const token = jwt.sign(key, { sub: alg }, { cty: "test" });
+      const header = JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+      const verifier = alg === "HS256" ? key : key.public();
+
+      t.expect(header.alg).as(alg + " alg").toEqual(alg);
+      t.expect(header.typ).as(alg + " typ").toEqual("JWT");
+      t.expect(header.cty).as(alg + " cty").toEqual("test");
+      t.expect(jwt.verify(token, verifier).sub).as(alg + " verify").toEqual(alg);
+    }
+
+    const mismatch = jwk.toObject(jwk.generate("ES384"));
+
+    mismatch.alg = "ES256";
+
+    let err = null;
+    try {
+      jwt.sign(jwk.parse(mismatch), {});
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("curve mismatch error").toBeTruthy();
+
+    const short = jwk.parse({ kty: "oct", k: "c2hvcnQtc2VjcmV0", alg: "HS256" });
+
+    err = null;
+    try {
+      jwt.sign(short, {});
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err && err.code).as("short hmac key").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+  });
+
+  describe("sign with relative time", (t) => {
+    const key = jwk.generate(ALG);
+    const now = Math.floor(Date.now() / 1000);
+
+    const expired = jwt.decode(jwt.sign(key, { sub: "alice", exp: now + 60 }, null, { expiredBy: "2h" }));
+
+    t.expect(Math.abs(expired.exp - (now - 7200))).as("expired exp").toBeLessThan(2);
+    t.expect(expired.exp - expired.nbf).as("expired lifetime").toEqual(3600);
+    t.expect(expired.iat).as("expired iat").toEqual(expired.nbf);
+    t.expect(expired.sub).as("sub").toEqual("alice");
+
+    const future = jwt.decode(jwt.sign(key, {}, null, { validIn: "10m", lifetime: 300 }));
+
+    t.expect(Math.abs(future.nbf - (now + 600))).as("future nbf").toBeLessThan(2);
+    t.expect(future.exp - future.nbf).as("future lifetime").toEqual(300);
+    t.expect(future.iat).as("future iat").toBeLessThan(future.nbf);
+
+    const seconds = jwt.decode(jwt.sign(key, {}, null, { expiredBy: 30 }));
+
+    t.expect(Math.abs(seconds.exp - (now - 30))).as("seconds").toBeLessThan(2);
+
+    const fails = (options) => {
+      try {
+        jwt.sign(key, {}, null, options);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails({ expiredBy: "2 hours" })).as("invalid duration").toEqual(true);
+    t.expect(fails({ expiredBy: "-1h" })).as("negative duration").toEqual(true);
+    t.expect(fails({ expiredBy: "1h", validIn: "1h" })).as("exclusive").toEqual(true);
+    t.expect(fails({ expiredBy: "1h", now: "yesterday" })).as("invalid now").toEqual(true);
+  });
+
+  describe("validateClaims", (t) => {
+    const schema = {
+      type: "object",
+      required: ["sub", "exp", "scope"],
+      properties: {
+        sub: { type: "string", pattern: "^user-[0-9]+$" },
+        exp: { type: "integer", minimum: 1700000000 },
+        aud: { oneOf: [{ type: "string" }, { type: "array", items: { $ref: "#/$defs/uri" }, minItems: 1 }] },
+        scope: { enum: ["read", "write"] },
+      },
+      additionalProperties: false,
+      $defs: { uri: { type: "string", format: "uri" } },
+    };
+
+    const valid = { sub: "user-1", exp: 1800000000, aud: ["https://api.example.com"], scope: "read" };
+
+    t.expect(jwt.validateClaims(valid, schema).length).as("valid").toEqual(0);
+    t.expect(jwt.validateClaims(jwt.decode(jwt.sign(jwk.parse(EC_P256), valid)), schema).length)
+      .as("decoded")
+      .toEqual(0);
+
+    const violations = jwt.validateClaims({ sub: "alice", exp: 1.5, aud: ["not a uri"], extra: true }, schema);
+    const found = violations.map((v) => `${v.path} ${v.keyword}`).join(", ");
+
+    t.expect(violations.length).as("violations").toEqual(6);
+    t.expect(found)
+      .as("found")
+      .toEqual("/scope required, /aud oneOf, /exp type, /exp minimum, /extra additionalProperties, /sub pattern");
+    t.expect(violations[0].message).as("message").toEqual('missing required claim "scope"');
+
+    t.expect(jwt.validateClaims({}, true).length).as("true schema").toEqual(0);
+    t.expect(jwt.validateClaims({}, false)[0].keyword).as("false schema").toEqual("false");
+
+    let code = null;
+    try {
+      jwt.validateClaims(valid, { properties: { sub: { $ref: "https://example.com/schema" } } });
+    } catch (e) {
+      code = e.code;
+    }
+    t.expect(code).as("invalid schema").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("diffClaims", (t) => {
+    const expected = { iss: "https://op.example.com", aud: ["api", "web"], exp: 1, act: { sub: "svc", iat: 1 }, scope: "read" };
+    const token = jwt.sign(jwk.parse(EC_P256), {
+      iss: "https://op.example.com",
+      aud: ["api", "mobile", "web"],
+      act: { sub: "svc", iat: 2 },
+      jti: "x",
+    }, null, { validIn: 0 });
+
+    const diff = jwt.diffClaims(expected, jwt.decode(token), { ignore: ["exp", "iat", "nbf", "/act/iat"] });
+
+    t.expect(diff.equal).as("equal").toEqual(false);
+    t.expect(diff.differences.map((d) => `${d.kind} ${d.path}`).join(", "))
+      .as("differences")
+      .toEqual("changed /aud/1, unexpected /aud/2, unexpected /jti, missing /scope");
+    t.expect(diff.text.split("\n")[0]).as("text").toEqual('~ /aud/1: "web" != "mobile"');
+    t.expect(diff.differences[3].expected).as("expected value").toEqual("read");
+
+    const same = jwt.diffClaims({ n: 1, nested: { list: [1, "a"] } }, { n: 1.0, nested: { list: [1, "a"] }, extra: true }, {
+      ignoreExtra: true,
+    });
+
+    t.expect(same.equal).as("ignore extra").toEqual(true);
+    t.expect(same.text).as("empty text").toEqual("");
+  });
+
+  describe("extract", (t) => {
+    const claims = {
+      sub: "alice",
+      realm_access: { roles: ["admin", "user"] },
+      resource_access: { account: { roles: ["view"] }, "my-app": { roles: ["edit", "delete"] } },
+    };
+    const token = jwt.sign(jwk.parse(EC_P256), claims);
+
+    t.expect(jwt.extract(token, "$.realm_access.roles[*]").join()).as("wildcard").toEqual("admin,user");
+    t.expect(jwt.extract(token, "$.sub")).as("definite").toEqual("alice");
+    t.expect(jwt.extract(token, "$.realm_access.roles[-1]")).as("negative index").toEqual("user");
+    t.expect(jwt.extract(token, "$.resource_access['my-app'].roles[0]")).as("bracket name").toEqual("edit");
+    t.expect(jwt.extract(token, "$..roles[*]").join()).as("descendants").toEqual("admin,user,view,edit,delete");
+    t.expect(jwt.extract(token, "$.resource_access.*.roles").length).as("member wildcard").toEqual(2);
+    t.expect(jwt.extract(token, "$.missing.path")).as("missing").toEqual(null);
+    t.expect(jwt.extract(token, "$.missing[*]").length).as("missing wildcard").toEqual(0);
+    t.expect(jwt.extract(claims, "$.realm_access.roles[0]")).as("claims object").toEqual("admin");
+
+    let code = null;
+    try {
+      jwt.extract(token, "realm_access.roles");
+    } catch (e) {
+      code = e.code;
+    }
+    t.expect(code).as("invalid selector").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("saltLength", (t) => {
+    t.expect(PSS_SALTS.default).as("default").toEqual("hash");
+    t.expect(PSS_SALTS.hash).as("hash").toEqual("hash");
+    t.expect(PSS_SALTS.max).as("max").toEqual("max");
+    t.expect(PSS_SALTS.number).as("number").toEqual("max");
+    t.expect(PSS_SALTS.signer).as("signer").toEqual("max");
+
+    const token = jwt.sign(PSS_KEY, { sub: "alice" }, null, { saltLength: "max" });
+
+    t.expect(jwt.verify(token, PSS_KEY.public()).sub).as("verify max").toEqual("alice");
+
+    let code = null;
+    try {
+      jwt.sign(PSS_KEY, { sub: "alice" }, null, { saltLength: "min" });
+    } catch (e) {
+      code = e.code;
+    }
+    t.expect(code).as("invalid").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("now", (t) => {
+    const key = jwk.parse(EC_P256);
+    const fixed = jwt.decode(jwt.sign(key, {}, null, { validIn: "1m", now: 1700000000 }));
+
+    t.expect(fixed.nbf).as("seconds").toEqual(1700000060);
+    t.expect(fixed.iat).as("iat").toEqual(1700000000);
+
+    const date = jwt.decode(jwt.sign(key, {}, null, { expiredBy: 0, lifetime: 60, now: new Date(1700000000000) }));
+
+    t.expect(date.exp).as("date").toEqual(1700000000);
+    t.expect(jwt.decode(jwt.sign(key, {}, null, { expiredBy: 0, now: "2023-11-14T22:13:20Z" })).exp).as("string").toEqual(1700000000);
+    t.expect(jwt.decode(jwt.sign(key, {}, null, { expiredBy: 0, now: () => 1700000000 })).exp).as("function").toEqual(1700000000);
+
+    const token = jwt.sign(key, { sub: "alice", nbf: 1700000000, exp: 1700000060 });
+
+    t.expect(jwt.verifyClaims(token, key.public(), { now: 1700000030 }).sub).as("valid then").toEqual("alice");
+
+    const fails = (now) => {
+      try {
+        jwt.verifyClaims(token, key.public(), { now, leeway: 0 });
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(fails(1700000061)).as("expired then").toEqual("ERR_JWT_EXPIRED");
+    t.expect(fails(new Date(1699999999000))).as("not yet valid then").toEqual("ERR_JWT_NOT_YET_VALID");
+    t.expect(fails(undefined)).as("wall clock").toEqual("ERR_JWT_EXPIRED");
+    t.expect(fails({})).as("invalid").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("reissue", (t) => {
+    const key = jwk.parse(EC_P256);
+    const token = jwt.sign(key, { sub: "alice", jti: "jti-1", exp: 2000000000 }, { typ: "at+jwt", kid: "issuer-1" });
+
+    const replay = jwt.reissue(token, key);
+    const fresh = jwt.reissue(token, key, { fresh: true });
+
+    t.expect(replay !== token).as("new signature").toEqual(true);
+    t.expect(replay.split(".")[0]).as("header").toEqual(token.split(".")[0]);
+    t.expect(replay.split(".")[1]).as("payload").toEqual(token.split(".")[1]);
+    t.expect(jwt.verify(replay, key.public()).jti).as("reused jti").toEqual("jti-1");
+
+    const claims = jwt.verify(fresh, key.public());
+    const header = JSON.parse(b64decode(fresh.split(".")[0], "rawurl", "s"));
+
+    t.expect(claims.jti !== "jti-1").as("fresh jti").toEqual(true);
+    t.expect(claims.sub).as("sub").toEqual("alice");
+    t.expect(claims.exp).as("exp").toEqual(2000000000);
+    t.expect(header.typ).as("typ").toEqual("at+jwt");
+    t.expect(header.kid).as("kid").toEqual("issuer-1");
+
+    const members = jwt.sign(key, { sub: "alice" }, { nonce: "nonce-1", x5c: [X5C], jwk: key.public() });
+    const noalg = jwk.toObject(key);
+
+    delete noalg.alg;
+
+    const kept = jwt.reissue(members, jwk.parse(noalg));
+    const keptHeader = JSON.parse(b64decode(kept.split(".")[0], "rawurl", "s"));
+
+    t.expect(keptHeader.nonce).as("nonce").toEqual("nonce-1");
+    t.expect(keptHeader.x5c[0]).as("x5c").toEqual(X5C);
+    t.expect(keptHeader.jwk.x).as("jwk").toEqual(jwk.toObject(key).x);
+    t.expect(keptHeader.alg).as("alg").toEqual("ES256");
+    t.expect(jwt.verify(kept, key.public()).sub).as("original alg").toEqual("alice");
+  });
+
+  describe("createSigner", (t) => {
+    const key = jwk.parse(EC_P256);
+    const signer = jwt.createSigner(key, { header: { typ: "at+jwt" } });
+
+    const first = signer.sign({ sub: "alice" });
+    const second = signer.sign({ sub: "bob" }, { expiredBy: "1h" });
+    const header = JSON.parse(b64decode(first.split(".")[0], "rawurl", "s"));
+
+    t.expect(header.typ).as("typ").toEqual("at+jwt");
+    t.expect(header.kid).as("kid").toEqual("ec-1");
+    t.expect(jwt.verify(first, key.public()).sub).as("first").toEqual("alice");
+    t.expect(jwt.verify(second, key.public()).sub).as("second").toEqual("bob");
+    t.expect(jwt.decode(second).exp).as("expired").toBeLessThan(Math.floor(Date.now() / 1000));
+    t.expect(JSON.parse(b64decode(jwt.createSigner(key).sign({}).split(".")[0], "rawurl", "s")).typ)
+      .as("default typ")
+      .toEqual("JWT");
+  });
+
+  describe("signAsync and verifyAsync", (t) => {
+    t.expect(ASYNC_CLAIMS.sub).as("sub").toEqual("alice");
+    t.expect(ASYNC_CLAIMS.exp).as("clock").toEqual(1700000000);
+    t.expect(JSON.parse(b64decode(ASYNC_TOKEN.split(".")[0], "rawurl", "s")).kid).as("header").toEqual("async");
+    t.expect(ASYNC_REJECTED).as("wrong key").toEqual("ERR_JOSE_KID_UNKNOWN");
+    t.expect(ASYNC_INVALID).as("invalid now").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+
+    const key = jwk.generate(ALG);
+    const pending = jwt.signAsync(key, { sub: "bob" });
+
+    t.expect(pending instanceof Promise).as("promise").toEqual(true);
+
+    // settled on the event loop of the VU, after the iteration's synchronous code
+    pending
+      .then((token) => jwt.verifyAsync(token, key.public()))
+      .then((claims) => describe("verifyAsync in VU", (t) => t.expect(claims.sub).as("settled sub").toEqual("bob")));
+  });
+
   describe("verify", (t) => {
     const key1 = jwk.generate(ALG);
     const key2 = jwk.generate(ALG);
@@ -50,6 +403,113 @@ export default function () {
     expect("foo").toEqual("bar");
   });
 
+  describe("verify with plain object keys", (t) => {
+    const key = jwk.generate(ALG);
+    const token = jwt.sign(key, { foo: "bar" });
+    const obj = jwk.toObject(key.public());
+
+    t.expect(jwt.verify(token, obj).foo).as("key object").toEqual("bar");
+    t.expect(jwt.verify(token, { keys: [jwk.toObject(jwk.generate(ALG)), obj] }).foo)
+      .as("key set object")
+      .toEqual("bar");
+  });
+
+  describe("verifyClaims", (t) => {
+    const key = jwk.parse(EC_P256);
+    const other = jwk.generate("ES256");
+    const now = Math.floor(Date.now() / 1000);
+    const claims = { iss: "https://issuer", sub: "alice", aud: ["api", "web"], exp: now + 60 };
+    const token = jwt.sign(key, claims);
+    const options = { audience: "api", issuer: "https://issuer" };
+
+    t.expect(jwt.verifyClaims(token, key.public(), options).sub).as("sub").toEqual("alice");
+    t.expect(jwt.verifyClaims(token, [other.public(), key.public()]).sub).as("key array").toEqual("alice");
+
+    const reason = (token, keys, options) => {
+      try {
+        jwt.verifyClaims(token, keys, options);
+      } catch (e) {
+        return String(e);
+      }
+      return "";
+    };
+
+    const expect = (name, err, part) => t.expect(err.indexOf(part) >= 0).as(name).toEqual(true);
+
+    expect("expired", reason(jwt.sign(key, claims, null, { expiredBy: "1h" }), key.public()), "expired");
+    expect("leeway", reason(jwt.sign(key, { exp: now - 30 }), key.public(), { leeway: 0 }), "expired");
+    t.expect(reason(jwt.sign(key, { exp: now - 30 }), key.public())).as("default leeway").toEqual("");
+    expect("not yet valid", reason(jwt.sign(key, claims, null, { validIn: "1h" }), key.public()), "not valid yet");
+    expect("wrong audience", reason(token, key.public(), { audience: "other" }), "aud");
+    expect("wrong issuer", reason(token, key.public(), { issuer: "https://other" }), "iss");
+    const impostor = jwk.toObject(other.public());
+
+    impostor.kid = "ec-1";
+
+    expect("bad signature", reason(token, impostor), "cryptographic");
+    expect("unknown kid", reason(token, jwk.generate("ES256").public()), "unknown kid: ec-1");
+    expect("invalid leeway", reason(token, key.public(), { leeway: "1 minute" }), "invalid duration");
+  });
+
+  describe("error classes", (t) => {
+    const key = jwk.parse(EC_P256);
+    const now = Math.floor(Date.now() / 1000);
+    const token = jwt.sign(key, { iss: "https://issuer", exp: now + 60 });
+
+    const error = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e;
+      }
+      return {};
+    };
+
+    const expect = (name, err, class_, code) => {
+      t.expect(err.name).as(name + " name").toEqual(class_);
+      t.expect(err.code).as(name + " code").toEqual(code);
+    };
+
+    const expired = jwt.sign(key, {}, null, { expiredBy: "1h" });
+    expect("expired", error(() => jwt.verifyClaims(expired, key.public())), "JWTExpiredError", "ERR_JWT_EXPIRED");
+
+    const early = jwt.sign(key, {}, null, { validIn: "1h" });
+    const notYet = error(() => jwt.verifyClaims(early, key.public()));
+    expect("not yet valid", notYet, "JWTNotYetValidError", "ERR_JWT_NOT_YET_VALID");
+
+    const issuer = error(() => jwt.verifyClaims(token, key.public(), { issuer: "https://other" }));
+    expect("wrong issuer", issuer, "JWTClaimValidationError", "ERR_JWT_CLAIM_INVALID");
+
+    const unknown = error(() => jwt.verify(token, jwk.generate("ES256").public()));
+    expect("unknown kid", unknown, "KeyNotFoundError", "ERR_JOSE_KID_UNKNOWN");
+
+    const impostor = jwk.toObject(jwk.generate("ES256").public());
+    impostor.kid = "ec-1";
+    const signature = error(() => jwt.verify(token, impostor));
+    expect("bad signature", signature, "SignatureVerificationError", "ERR_JOSE_SIGNATURE_INVALID");
+
+    expect("malformed", error(() => jwt.verify("not a token", key.public())), "JOSEError", "ERR_JOSE");
+    const duration = error(() => jwt.sign(key, {}, null, { expiredBy: "2 hours" }));
+    expect("invalid duration", duration, "InvalidArgumentError", "ERR_JOSE_INVALID_ARGUMENT");
+
+    const err = error(() => jwt.verifyClaims(expired, key.public()));
+    t.expect(String(err).indexOf("JWTExpiredError: ") === 0).as("string").toEqual(true);
+  });
+
+  describe("verifyDetailed", (t) => {
+    const key1 = jwk.generate(ALG);
+    const key2 = jwk.generate(ALG);
+    const kid = JSON.parse(JSON.stringify(key2)).kid;
+
+    const token = jwt.sign(key2, { foo: "bar" });
+    const result = jwt.verifyDetailed(token, key1.public(), key2.public());
+
+    t.expect(result.payload.foo).as("foo").toEqual("bar");
+    t.expect(result.kid).as("kid").toEqual(kid);
+    t.expect(result.alg).as("alg").toEqual("EdDSA");
+    t.expect(result.index).as("index").toEqual(0);
+  });
+
   describe("decode", (t) => {
     const key = jwk.generate(ALG);
     const token = jwt.sign(key, { foo: "bar", answer: 42 });
@@ -60,4 +520,99 @@ export default function () {
     expect("answer").toEqual(42);
     expect("foo").toEqual("bar");
   });
+
+  describe("template", (t) => {
+    const claims = jwt.template("id_token", { iss: "https://issuer", sub: "alice", aud: "client" });
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    expect("iss").toEqual("https://issuer");
+    expect("sub").toEqual("alice");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(3600);
+    t.expect(claims.nonce.length).as("nonce length").toBeGreaterThan(0);
+
+    let err = null;
+    try {
+      jwt.template("access_token", { iss: "https://issuer" });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("missing claim error").toBeTruthy();
+  });
+
+  describe("merge", (t) => {
+    const base = { iss: "https://issuer", exp: 42, cnf: { jkt: "abc", x5t: "def" } };
+    const claims = jwt.merge(base, { exp: null, sub: "bob" }, { cnf: { x5t: null } });
+
+    t.expect(claims.iss).as("iss").toEqual("https://issuer");
+    t.expect(claims.sub).as("sub").toEqual("bob");
+    t.expect("exp" in claims).as("exp present").toEqual(false);
+    t.expect(claims.cnf.jkt).as("cnf.jkt").toEqual("abc");
+    t.expect("x5t" in claims.cnf).as("cnf.x5t present").toEqual(false);
+    t.expect(base.exp).as("base exp").toEqual(42);
+  });
+
+  describe("registerTemplate", (t) => {
+    jwt.registerTemplate("custom", { iss: "https://custom", scope: "read" });
+
+    const claims = jwt.template("custom", { scope: "write" });
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    expect("iss").toEqual("https://custom");
+    expect("scope").toEqual("write");
+  });
+
+  describe("consume", (t) => {
+    const signer = jwk.generate("ES256");
+    const recipient = jwk.generate("ES256");
+    const now = Math.floor(Date.now() / 1000);
+
+    const inner = jwt.sign(signer, { sub: "alice", aud: "api", exp: now + 60 });
+    const signed = jws.sign(signer, inner, { cty: "JWT" });
+    const token = jwe.encrypt(recipient.public(), signed, { header: { cty: "JWT" } });
+
+    const consumed = jwt.consume(token, signer.public(), { decryptionKey: recipient, verify: { audience: "api" } });
+
+    t.expect(consumed.claims.sub).as("claims").toEqual("alice");
+    t.expect(consumed.layers.map((layer) => layer.type).join()).as("layers").toEqual("JWE,JWS,JWS");
+    t.expect(consumed.layers[0].header.enc).as("JWE header").toEqual("A256GCM");
+    t.expect(consumed.layers[1].header.cty).as("JWS header").toEqual("JWT");
+    t.expect(consumed.layers[2].header.alg).as("JWT header").toEqual("ES256");
+    t.expect(jwt.consume(inner, signer.public()).layers.length).as("plain JWT").toEqual(1);
+
+    const general = jws.sign(signer, jwe.encrypt(recipient.public(), inner), { cty: "jwt" }, { serialization: "general" });
+
+    t.expect(jwt.consume(general, signer.public(), { decryptionKey: recipient }).claims.sub).as("JSON serialization").toEqual("alice");
+
+    const rsa = jwk.generate("RS256");
+    const multi = jwe.encrypt([rsa.public(), recipient.public()], signed, { header: { cty: "JWT" } });
+    const second = jwt.consume(multi, signer.public(), { decryptionKey: recipient }).layers[0].header;
+
+    t.expect(second.alg + " " + second.kid).as("decrypted recipient header").toEqual("ECDH-ES+A256KW " + jwk.toObject(recipient).kid);
+
+    const code = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    const expired = jwe.encrypt(recipient.public(), jwt.sign(signer, { sub: "bob", exp: now - 3600 }));
+
+    t.expect(jwt.consume(expired, signer.public(), { decryptionKey: recipient }).claims.sub).as("signature only").toEqual("bob");
+    t.expect(code(() => jwt.consume(expired, signer.public(), { decryptionKey: recipient, verify: {} })))
+      .as("expired")
+      .toEqual("ERR_JWT_EXPIRED");
+    t.expect(code(() => jwt.consume(token, jwk.generate("ES256").public(), { decryptionKey: recipient })))
+      .as("wrong signer")
+      .toEqual("ERR_JOSE_KID_UNKNOWN");
+    t.expect(code(() => jwt.consume(token, signer.public()))).as("decryption key").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwt.consume(token, signer.public(), { decryptionKey: recipient, maxLayers: 2 })))
+      .as("max layers")
+      .toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwt.consume(jws.sign(signer, "text", {}, { serialization: "flattened" }), signer.public())))
+      .as("not a JWT")
+      .toEqual("ERR_JOSE_MALFORMED");
+  });
 }
@@ -0,0 +1,112 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import kms from "k6/x/jose/kms";
+import jwk from "k6/x/jose/jwk";
+import jwt from "k6/x/jose/jwt";
+import { describe } from "./expect.js";
+
+const code = (fn) => {
+  try {
+    fn();
+  } catch (e) {
+    return e.code;
+  }
+  return null;
+};
+
+const GCP = { accessToken: "token", endpoint: "http://127.0.0.1:1" };
+const GCP_KEY = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1";
+
+const AZURE = { accessToken: "token" };
+const AZURE_KEY = "http://127.0.0.1:1/keys/signing";
+
+const VAULT = { address: "http://127.0.0.1:1", token: "token" };
+
+const EXTERNAL_KEY = jwk.generate("ES256");
+
+const AWS = { region: "eu-west-1", accessKeyId: "AKIDEXAMPLE", secretAccessKey: "secret", endpoint: "http://127.0.0.1:1" };
+
+// callbacks run on the event loop only, the async variants reject them
+const ASYNC_EXTERNAL = await jwt
+  .signAsync(kms.external({ public: EXTERNAL_KEY, sign: () => "c2lnbmF0dXJl" }), {})
+  .then(
+    () => null,
+    (e) => e.code
+  );
+
+export default function () {
+  describe("aws", (t) => {
+    t.expect(code(() => kms.aws("", AWS))).as("missing key").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.aws("alias/signing", AWS))).as("unreachable").toEqual("ERR_JOSE_REMOTE_SIGNER");
+  });
+
+  describe("gcp", (t) => {
+    t.expect(code(() => kms.gcp("projects/p/locations/global/keyRings/r/cryptoKeys/k", GCP))).as("missing version").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.gcp(GCP_KEY, { endpoint: GCP.endpoint }))).as("missing credentials").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.gcp(GCP_KEY, GCP))).as("unreachable").toEqual("ERR_JOSE_REMOTE_SIGNER");
+  });
+
+  describe("azure", (t) => {
+    t.expect(code(() => kms.azure("http://127.0.0.1:1/secrets/signing", AZURE))).as("missing key").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.azure(AZURE_KEY, { clientSecret: "secret" }))).as("missing tenant").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.azure(AZURE_KEY, AZURE))).as("unreachable").toEqual("ERR_JOSE_REMOTE_SIGNER");
+  });
+
+  describe("vault", (t) => {
+    t.expect(code(() => kms.vault("", VAULT))).as("missing key").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.vault("signing", { address: VAULT.address }))).as("missing credentials").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.vault("signing", VAULT))).as("unreachable").toEqual("ERR_JOSE_REMOTE_SIGNER");
+  });
+
+  describe("pkcs11", (t) => {
+    t.expect(code(() => kms.pkcs11("", { module: "/nonexistent/libpkcs11.so" }))).as("missing key").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.pkcs11("signing", {}))).as("missing module").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.pkcs11("signing", { module: "/nonexistent/libpkcs11.so" }))).as("unloadable").toEqual("ERR_JOSE_REMOTE_SIGNER");
+  });
+
+  describe("external", (t) => {
+    let seen = {};
+    const key = kms.external({
+      public: EXTERNAL_KEY,
+      sign: (input, alg) => {
+        seen = { input: String.fromCharCode(...new Uint8Array(input)), alg };
+        return "c2lnbmF0dXJl";
+      },
+    });
+    const token = jwt.sign(key, { sub: "external" });
+
+    t.expect(seen.alg).as("alg").toEqual("ES256");
+    t.expect(token).as("token").toEqual(`${seen.input}.c2lnbmF0dXJl`);
+    t.expect(jwk.toObject(key).d).as("public").toEqual(undefined);
+    t.expect(code(() => kms.external({ public: EXTERNAL_KEY }))).as("missing sign").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => kms.external({ sign: () => "" }))).as("missing public").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+
+    const failing = kms.external({ public: EXTERNAL_KEY, sign: () => { throw new Error("unavailable") } });
+    t.expect(code(() => jwt.sign(failing, {}))).as("failing").toEqual("ERR_JOSE_REMOTE_SIGNER");
+    t.expect(ASYNC_EXTERNAL).as("async").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+}
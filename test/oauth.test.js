@@ -0,0 +1,531 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import oauth from "k6/x/jose/oauth";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import jwe from "k6/x/jose/jwe";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256, EC_P256_2, EC_P256_PEM, RSA_2048, GOOGLE_SERVICE_ACCOUNT, CLIENT_CERT_PEM } from "./keys.js";
+
+const ISSUER = "https://as.example.com";
+const TOKEN_ENDPOINT = "https://as.example.com/token";
+
+export default function () {
+  describe("clientAssertion", (t) => {
+    const key = jwk.parse(EC_P256);
+    const result = oauth.clientAssertion(key, "client-1", TOKEN_ENDPOINT);
+    const claims = jwt.verify(result.assertion, key.public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    expect("iss").toEqual("client-1");
+    expect("sub").toEqual("client-1");
+    expect("aud").toEqual(TOKEN_ENDPOINT);
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(60);
+    t.expect(claims.jti.length).as("jti length").toBeGreaterThan(0);
+
+    t.expect(result.params.client_assertion).as("client_assertion").toEqual(result.assertion);
+    t.expect(result.params.client_assertion_type)
+      .as("client_assertion_type")
+      .toEqual("urn:ietf:params:oauth:client-assertion-type:jwt-bearer");
+    t.expect(result.params.client_id).as("client_id").toEqual("client-1");
+
+    const other = oauth.clientAssertion(key, "client-1", TOKEN_ENDPOINT, { lifetime: 300, claims: { jti: "fixed" } });
+    const otherClaims = jwt.decode(other.assertion);
+
+    t.expect(otherClaims.exp - otherClaims.iat).as("custom lifetime").toEqual(300);
+    t.expect(otherClaims.jti).as("custom jti").toEqual("fixed");
+  });
+
+  describe("clientAttestation", (t) => {
+    const attester = jwk.parse(RSA_2048);
+    const instance = jwk.parse(EC_P256);
+    const attestation = oauth.clientAttestation(attester, "https://wallet.example.com", "wallet-app", instance, {
+      claims: { wallet_name: "Example Wallet" },
+    });
+    const claims = jwt.verify(attestation, attester.public());
+    const header = JSON.parse(b64decode(attestation.split(".")[0], "rawurl", "s"));
+
+    t.expect(header.typ).as("typ").toEqual("oauth-client-attestation+jwt");
+    t.expect(claims.iss).as("iss").toEqual("https://wallet.example.com");
+    t.expect(claims.sub).as("sub").toEqual("wallet-app");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(3600);
+    t.expect(claims.wallet_name).as("wallet_name").toEqual("Example Wallet");
+    t.expect(claims.cnf.jwk.x).as("cnf.jwk").toEqual(JSON.parse(EC_P256).x);
+    t.expect(claims.cnf.jwk.d).as("public cnf.jwk").toEqual(undefined);
+
+    const result = oauth.clientAttestationPoP(instance, attestation, ISSUER, { challenge: "c-1" });
+    const pop = jwt.verify(result.pop, jwk.parse(claims.cnf.jwk));
+    const popHeader = JSON.parse(b64decode(result.pop.split(".")[0], "rawurl", "s"));
+
+    t.expect(popHeader.typ).as("pop typ").toEqual("oauth-client-attestation-pop+jwt");
+    t.expect(pop.iss).as("pop iss").toEqual("wallet-app");
+    t.expect(pop.aud).as("pop aud").toEqual(ISSUER);
+    t.expect(pop.challenge).as("pop challenge").toEqual("c-1");
+    t.expect(pop.exp - pop.iat).as("pop lifetime").toEqual(60);
+    t.expect(pop.jti.length).as("pop jti length").toBeGreaterThan(0);
+    t.expect(result.headers["OAuth-Client-Attestation"]).as("attestation header").toEqual(attestation);
+    t.expect(result.headers["OAuth-Client-Attestation-PoP"]).as("pop header").toEqual(result.pop);
+
+    let err = null;
+
+    try {
+      oauth.clientAttestationPoP(jwk.parse(EC_P256_2), attestation, ISSUER);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err && err.code).as("other instance key").toEqual("ERR_JWT_CLAIM_INVALID");
+
+    err = null;
+    try {
+      oauth.clientAttestationPoP(instance, jwt.sign(attester, { sub: "wallet-app" }), ISSUER);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err && err.code).as("unbound attestation").toEqual("ERR_JWT_CLAIM_INVALID");
+  });
+
+  describe("requestObject", (t) => {
+    const key = jwk.parse(EC_P256);
+    const params = { response_type: "code", redirect_uri: "https://client.example.com/cb", scope: "openid accounts" };
+    const result = oauth.requestObject(key, "client-1", ISSUER, params);
+    const claims = jwt.verify(result.request, key.public());
+    const header = JSON.parse(b64decode(result.request.split(".")[0], "rawurl", "s"));
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    t.expect(header.typ).as("typ").toEqual("oauth-authz-req+jwt");
+    expect("iss").toEqual("client-1");
+    expect("aud").toEqual(ISSUER);
+    expect("client_id").toEqual("client-1");
+    expect("response_type").toEqual("code");
+    expect("redirect_uri").toEqual("https://client.example.com/cb");
+    expect("scope").toEqual("openid accounts");
+    expect("nbf").toEqual(claims.iat);
+    t.expect(claims.exp - claims.nbf).as("lifetime").toEqual(300);
+    t.expect(claims.state.length).as("state length").toBeGreaterThan(0);
+    t.expect(claims.nonce.length).as("nonce length").toBeGreaterThan(0);
+    t.expect(result.params.request).as("request param").toEqual(result.request);
+    t.expect(result.params.client_id).as("client_id param").toEqual("client-1");
+
+    const other = jwt.decode(
+      oauth.requestObject(key, "client-1", ISSUER, Object.assign({ state: "s1" }, params), {
+        lifetime: 60,
+        claims: { nonce: null },
+      }).request
+    );
+
+    t.expect(other.state).as("custom state").toEqual("s1");
+    t.expect("nonce" in other).as("nonce present").toEqual(false);
+    t.expect(other.exp - other.nbf).as("custom lifetime").toEqual(60);
+
+    let err = null;
+    try {
+      oauth.requestObject(key, "client-1", ISSUER, { response_type: "code" });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("missing redirect_uri error").toBeTruthy();
+  });
+
+  describe("cibaRequest", (t) => {
+    const key = jwk.parse(EC_P256);
+    const params = {
+      scope: "openid payments",
+      login_hint: "alice@example.com",
+      binding_message: "W4SCT",
+      requested_expiry: "120",
+    };
+    const result = oauth.cibaRequest(key, "client-1", ISSUER, params);
+    const claims = jwt.verify(result.request, key.public());
+
+    t.expect(claims.iss).as("iss").toEqual("client-1");
+    t.expect(claims.aud).as("aud").toEqual(ISSUER);
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(300);
+    t.expect(claims.nbf).as("nbf").toEqual(claims.iat);
+    t.expect(claims.jti.length).as("jti length").toBeGreaterThan(0);
+    t.expect(claims.login_hint).as("login_hint").toEqual("alice@example.com");
+    t.expect(claims.binding_message).as("binding_message").toEqual("W4SCT");
+    t.expect(claims.requested_expiry).as("requested_expiry").toEqual(120);
+    t.expect(result.params.request).as("request param").toEqual(result.request);
+    t.expect(result.params.client_id).as("client_id param").toEqual("client-1");
+
+    const hinted = jwt.decode(
+      oauth.cibaRequest(key, "client-1", ISSUER, { scope: "openid", id_token_hint: "eyJ" }, { lifetime: 60 }).request
+    );
+
+    t.expect(hinted.id_token_hint).as("id_token_hint").toEqual("eyJ");
+    t.expect(hinted.exp - hinted.iat).as("custom lifetime").toEqual(60);
+    t.expect("requested_expiry" in hinted).as("no requested_expiry").toEqual(false);
+
+    const code = (p) => {
+      try {
+        oauth.cibaRequest(key, "client-1", ISSUER, p);
+      } catch (e) {
+        return e.code;
+      }
+      return null;
+    };
+
+    t.expect(code({ login_hint: "alice" })).as("missing scope").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code({ scope: "payments", login_hint: "alice" })).as("no openid").toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code({ scope: "openid" })).as("missing hint").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code({ scope: "openid", login_hint: "alice", id_token_hint: "eyJ" }))
+      .as("multiple hints")
+      .toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code({ scope: "openid", login_hint: "alice", requested_expiry: -1 }))
+      .as("negative requested_expiry")
+      .toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code({ scope: "openid", login_hint: "alice", binding_message: 42 }))
+      .as("binding_message type")
+      .toEqual("ERR_JWT_CLAIM_INVALID");
+  });
+
+  describe("requestObject encryption", (t) => {
+    const key = jwk.parse(EC_P256);
+    const encKey = JSON.parse(RSA_2048);
+    encKey.use = "enc";
+    const decryptionKey = jwk.parse(JSON.stringify(encKey));
+    const params = { response_type: "code id_token", redirect_uri: "https://client.example.com/cb" };
+
+    const result = oauth.requestObject(key, "client-1", ISSUER, params, {
+      encryptionKey: [key.public(), decryptionKey.public()],
+      encryption: { alg: "RSA-OAEP" },
+    });
+    const hdr = JSON.parse(b64decode(result.request.split(".")[0], "rawurl", "s"));
+
+    t.expect(result.request.split(".").length).as("number of fields").toEqual(5);
+    t.expect(hdr.cty).as("cty").toEqual("JWT");
+    t.expect(hdr.alg).as("alg").toEqual("RSA-OAEP");
+    t.expect(hdr.kid).as("kid").toEqual("rsa-1");
+
+    const nested = String.fromCharCode.apply(null, new Uint8Array(jwe.decrypt(decryptionKey, result.request)));
+    const claims = jwt.verify(nested, key.public());
+
+    t.expect(claims.response_type).as("response_type").toEqual("code id_token");
+    t.expect(result.params.request).as("request param").toEqual(result.request);
+  });
+
+  describe("authorizationResponse", (t) => {
+    const key = jwk.parse(EC_P256);
+    const now = Math.floor(Date.now() / 1000);
+    const sign = (claims) =>
+      jwt.sign(key, Object.assign({ iss: ISSUER, aud: "client-1", exp: now + 60, code: "c-1", state: "s-1" }, claims));
+
+    const result = oauth.authorizationResponse(sign({}), [key.public()], ISSUER, "client-1", { state: "s-1" });
+
+    t.expect(result.code).as("code").toEqual("c-1");
+    t.expect(result.state).as("state").toEqual("s-1");
+    t.expect(result.claims.iss).as("iss").toEqual(ISSUER);
+
+    const fails = (response, options) => {
+      try {
+        oauth.authorizationResponse(response, key.public(), ISSUER, "client-1", options);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails(sign({ aud: ["other", "client-1"] }))).as("aud array").toEqual(false);
+    t.expect(fails(sign({ iss: "https://evil.example.com" }))).as("wrong iss").toEqual(true);
+    t.expect(fails(sign({ aud: "other" }))).as("wrong aud").toEqual(true);
+    t.expect(fails(sign({ exp: now - 120 }))).as("expired").toEqual(true);
+    t.expect(fails(sign({ exp: now - 120 }), { leeway: 300 })).as("leeway").toEqual(false);
+    t.expect(fails(sign({}), { state: "s-2" })).as("wrong state").toEqual(true);
+    t.expect(fails(sign({ code: null, error: "access_denied" }))).as("error response").toEqual(true);
+    t.expect(fails(jwt.sign(jwk.generate("ed25519"), { iss: ISSUER }))).as("wrong key").toEqual(true);
+  });
+
+  describe("appleClientSecret", (t) => {
+    const secret = oauth.appleClientSecret(EC_P256_PEM, "TEAM123456", "ABC123DEFG", "com.example.app");
+    const hdr = JSON.parse(b64decode(secret.split(".")[0], "rawurl", "s"));
+    const claims = jwt.verify(secret, jwk.parsePEM(EC_P256_PEM).public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    t.expect(hdr.alg).as("alg").toEqual("ES256");
+    t.expect(hdr.kid).as("kid").toEqual("ABC123DEFG");
+    expect("iss").toEqual("TEAM123456");
+    expect("sub").toEqual("com.example.app");
+    expect("aud").toEqual("https://appleid.apple.com");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(3600);
+
+    const other = jwt.decode(
+      oauth.appleClientSecret(jwk.parsePEM(EC_P256_PEM), "TEAM123456", "ABC123DEFG", "com.example.app", {
+        lifetime: 15777000,
+      })
+    );
+    t.expect(other.exp - other.iat).as("maximum lifetime").toEqual(15777000);
+
+    let err = null;
+    try {
+      oauth.appleClientSecret(EC_P256_PEM, "TEAM123456", "ABC123DEFG", "com.example.app", { lifetime: 15777001 });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("lifetime error").toBeTruthy();
+  });
+
+  describe("serviceAccountAssertion", (t) => {
+    const scopes = ["https://www.googleapis.com/auth/cloud-platform", "openid"];
+    const result = oauth.serviceAccountAssertion(JSON.stringify(GOOGLE_SERVICE_ACCOUNT), scopes);
+    const hdr = JSON.parse(b64decode(result.assertion.split(".")[0], "rawurl", "s"));
+    const claims = jwt.verify(result.assertion, jwk.parsePEM(GOOGLE_SERVICE_ACCOUNT.private_key).public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    t.expect(hdr.alg).as("alg").toEqual("RS256");
+    t.expect(hdr.kid).as("kid").toEqual("0123456789abcdef");
+    expect("iss").toEqual("k6@load-test.iam.gserviceaccount.com");
+    expect("aud").toEqual("https://oauth2.googleapis.com/token");
+    expect("scope").toEqual("https://www.googleapis.com/auth/cloud-platform openid");
+    t.expect("sub" in claims).as("sub present").toEqual(false);
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(3600);
+    t.expect(result.tokenUri).as("tokenUri").toEqual("https://oauth2.googleapis.com/token");
+    t.expect(result.params.grant_type).as("grant_type").toEqual("urn:ietf:params:oauth:grant-type:jwt-bearer");
+    t.expect(result.params.assertion).as("assertion param").toEqual(result.assertion);
+
+    const other = jwt.decode(
+      oauth.serviceAccountAssertion(GOOGLE_SERVICE_ACCOUNT, [], {
+        subject: "admin@example.com",
+        audience: "https://pubsub.googleapis.com/",
+        lifetime: 600,
+      }).assertion
+    );
+
+    t.expect(other.sub).as("subject").toEqual("admin@example.com");
+    t.expect(other.aud).as("audience").toEqual("https://pubsub.googleapis.com/");
+    t.expect("scope" in other).as("scope present").toEqual(false);
+    t.expect(other.exp - other.iat).as("custom lifetime").toEqual(600);
+
+    let err = null;
+    try {
+      oauth.serviceAccountAssertion({ type: "authorized_user" }, scopes);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("invalid credentials error").toBeTruthy();
+  });
+
+  describe("firebaseCustomToken", (t) => {
+    const token = oauth.firebaseCustomToken(GOOGLE_SERVICE_ACCOUNT, "user-42", {
+      claims: { premium: true, role: "tester" },
+      tenantId: "tenant-1",
+    });
+    const claims = jwt.verify(token, jwk.parsePEM(GOOGLE_SERVICE_ACCOUNT.private_key).public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    expect("iss").toEqual("k6@load-test.iam.gserviceaccount.com");
+    expect("sub").toEqual("k6@load-test.iam.gserviceaccount.com");
+    expect("aud").toEqual("https://identitytoolkit.googleapis.com/google.identity.identitytoolkit.v1.IdentityToolkit");
+    expect("uid").toEqual("user-42");
+    expect("tenant_id").toEqual("tenant-1");
+    t.expect(claims.claims.role).as("developer claim").toEqual("tester");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(3600);
+
+    const plain = jwt.decode(oauth.firebaseCustomToken(JSON.stringify(GOOGLE_SERVICE_ACCOUNT), "user-43"));
+
+    t.expect("claims" in plain).as("claims present").toEqual(false);
+    t.expect("tenant_id" in plain).as("tenant_id present").toEqual(false);
+
+    const fails = (uid, options) => {
+      try {
+        oauth.firebaseCustomToken(GOOGLE_SERVICE_ACCOUNT, uid, options);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails("user", { lifetime: 7200 })).as("too long").toEqual(true);
+    t.expect(fails("x".repeat(129))).as("uid length").toEqual(true);
+    t.expect(fails("")).as("empty uid").toEqual(true);
+    t.expect(fails("user", { claims: { firebase: {} } })).as("reserved claim").toEqual(true);
+  });
+
+  describe("confirmation", (t) => {
+    const key = jwk.parse(EC_P256);
+    // JWK thumbprint (RFC 7638) and SHA-256 of the DER encoded certificate
+    const JKT = "_vieawIQ4UMdK264oUxMYCOSZoMVLjEhFPulYSqnGBc";
+    const X5T = "zsP2iwpLF1YAzQmgltJe6fdqH5ylbT6LLQ_dju-f1xU";
+
+    const cnf = oauth.confirmation({ jwk: key.public(), cert: CLIENT_CERT_PEM });
+
+    t.expect(cnf.jkt).as("jkt").toEqual(JKT);
+    t.expect(cnf["x5t#S256"]).as("x5t#S256").toEqual(X5T);
+
+    const ed = jwk.generate("ed25519");
+    t.expect(oauth.confirmation({ jwk: ed }).jkt)
+      .as("ed25519 jkt")
+      .toEqual(JSON.parse(JSON.stringify(ed)).kid);
+
+    const claims = oauth.bind({ sub: "alice", cnf: { kid: "k-1" } }, { jwk: key });
+
+    t.expect(claims.sub).as("sub").toEqual("alice");
+    t.expect(claims.cnf.jkt).as("bound jkt").toEqual(JKT);
+    t.expect(claims.cnf.kid).as("kept cnf member").toEqual("k-1");
+
+    const fails = (claims, options) => {
+      try {
+        oauth.verifyConfirmation(claims, options);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails(claims, { jwk: key.public() })).as("matching jkt").toEqual(false);
+    t.expect(fails(claims, { jwk: jwk.parse(EC_P256_2) })).as("other key").toEqual(true);
+    t.expect(fails(claims, { cert: CLIENT_CERT_PEM })).as("missing x5t#S256").toEqual(true);
+    t.expect(fails({ sub: "alice" }, { jwk: key })).as("missing cnf").toEqual(true);
+    t.expect(fails({ cnf: { "x5t#S256": X5T } }, { cert: CLIENT_CERT_PEM })).as("matching x5t#S256").toEqual(false);
+  });
+
+  describe("accessToken", (t) => {
+    const key = jwk.parse(EC_P256);
+    const claims = { iss: ISSUER, sub: "alice", aud: "https://rs.example.com", client_id: "client-1" };
+    const token = oauth.accessToken(key, claims, { scope: ["read", "write"], groups: ["admins"], roles: ["editor"] });
+    const payload = jwt.verify(token, key.public());
+    const expect = (prop) => t.expect(payload[prop]).as(prop);
+
+    t.expect(JSON.parse(b64decode(token.split(".")[0], "rawurl", "s")).typ).as("typ").toEqual("at+jwt");
+    expect("iss").toEqual(ISSUER);
+    expect("sub").toEqual("alice");
+    expect("client_id").toEqual("client-1");
+    expect("scope").toEqual("read write");
+    t.expect(payload.groups[0]).as("groups").toEqual("admins");
+    t.expect(payload.roles[0]).as("roles").toEqual("editor");
+    t.expect("entitlements" in payload).as("entitlements present").toEqual(false);
+    t.expect(payload.exp - payload.iat).as("lifetime").toEqual(3600);
+    t.expect(payload.jti.length).as("jti length").toBeGreaterThan(0);
+
+    const fails = (claims) => {
+      try {
+        oauth.accessToken(key, claims);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails(Object.assign({}, claims, { aud: ["a", "b"], scope: "read" }))).as("aud array").toEqual(false);
+    t.expect(fails(Object.assign({}, claims, { client_id: null }))).as("missing client_id").toEqual(true);
+    t.expect(fails(Object.assign({}, claims, { jti: null }))).as("missing jti").toEqual(true);
+    t.expect(fails(Object.assign({}, claims, { scope: ["read"] }))).as("scope array").toEqual(true);
+    t.expect(fails(Object.assign({}, claims, { aud: 42 }))).as("aud number").toEqual(true);
+  });
+
+  describe("token exchange actors", (t) => {
+    const act = oauth.act(["service-c", { sub: "service-b", iss: ISSUER }, "service-a"]);
+
+    t.expect(act.sub).as("current actor").toEqual("service-c");
+    t.expect(act.act.sub).as("prior actor").toEqual("service-b");
+    t.expect(act.act.iss).as("prior actor iss").toEqual(ISSUER);
+    t.expect(act.act.act.sub).as("first actor").toEqual("service-a");
+    t.expect("act" in act.act.act).as("chain end").toEqual(false);
+
+    const base = { sub: "alice", act: { sub: "service-a" } };
+    const claims = oauth.delegate(base, "service-b");
+
+    t.expect(claims.act.sub).as("delegated actor").toEqual("service-b");
+    t.expect(claims.act.act.sub).as("nested prior actor").toEqual("service-a");
+    t.expect("act" in base.act).as("base unchanged").toEqual(false);
+
+    const key = jwk.parse(EC_P256);
+    const payload = jwt.verify(jwt.sign(key, Object.assign({ sub: "alice" }, { act: act })), key.public());
+    const actors = oauth.actors(payload);
+
+    t.expect(actors.map((a) => a.sub).join(",")).as("actors").toEqual("service-c,service-b,service-a");
+    t.expect("act" in actors[0]).as("actor without act").toEqual(false);
+    t.expect(oauth.actors({ sub: "alice" }).length).as("no actors").toEqual(0);
+
+    const fails = (options) => {
+      try {
+        oauth.verifyDelegation(payload, options);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails({ actors: ["service-c", { sub: "service-b", iss: ISSUER }, "service-a"] }))
+      .as("matching chain")
+      .toEqual(false);
+    t.expect(fails({ actors: ["service-c", "service-a"] })).as("wrong chain").toEqual(true);
+    t.expect(fails({ actors: ["service-c", { sub: "service-b", iss: "other" }, "service-a"] }))
+      .as("wrong actor iss")
+      .toEqual(true);
+    t.expect(fails({ maxDepth: 3 })).as("depth within limit").toEqual(false);
+    t.expect(fails({ maxDepth: 2 })).as("depth over limit").toEqual(true);
+    t.expect(fails({ mayAct: { sub: "service-c" } })).as("may_act allowed").toEqual(false);
+    t.expect(fails({ mayAct: { sub: "service-x" } })).as("may_act denied").toEqual(true);
+  });
+
+  describe("introspectionResponse", (t) => {
+    const key = jwk.parse(EC_P256);
+    const introspection = { active: true, scope: "read", client_id: "client-1", sub: "alice" };
+    const response = oauth.introspectionResponse(key, ISSUER, "rs-1", introspection);
+    const claims = jwt.decode(response);
+
+    t.expect(JSON.parse(b64decode(response.split(".")[0], "rawurl", "s")).typ)
+      .as("typ")
+      .toEqual("token-introspection+jwt");
+    t.expect(claims.iss).as("iss").toEqual(ISSUER);
+    t.expect(claims.aud).as("aud").toEqual("rs-1");
+    t.expect(claims.iat > 0).as("iat").toBeTruthy();
+
+    const result = oauth.verifyIntrospectionResponse(response, key.public(), ISSUER, "rs-1");
+
+    t.expect(result.active).as("active").toEqual(true);
+    t.expect(result.sub).as("sub").toEqual("alice");
+
+    const inactive = oauth.introspectionResponse(key, ISSUER, "rs-1", { active: false });
+    t.expect(oauth.verifyIntrospectionResponse(inactive, key.public(), ISSUER, "rs-1").active)
+      .as("inactive")
+      .toEqual(false);
+
+    const fails = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails(() => oauth.verifyIntrospectionResponse(response, key.public(), "https://other", "rs-1")))
+      .as("wrong iss")
+      .toEqual(true);
+    t.expect(fails(() => oauth.verifyIntrospectionResponse(response, key.public(), ISSUER, "rs-2")))
+      .as("wrong aud")
+      .toEqual(true);
+    t.expect(fails(() => oauth.verifyIntrospectionResponse(jwt.sign(key, claims), key.public(), ISSUER, "rs-1")))
+      .as("wrong typ")
+      .toEqual(true);
+    t.expect(fails(() => oauth.introspectionResponse(key, ISSUER, "rs-1", { scope: "read" })))
+      .as("missing active")
+      .toEqual(true);
+  });
+}
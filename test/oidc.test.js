@@ -0,0 +1,317 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import oidc from "k6/x/jose/oidc";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256, EC_P256_2, RSA_2048 } from "./keys.js";
+
+const ISSUER = "https://op.example.com";
+
+const MOCK_IDP = oidc.mockProvider({ issuer: "https://idp.test/", audience: "orders-api", algorithm: "ES256", keys: 3 });
+const DEFAULT_IDP = oidc.mockProvider();
+const CLUSTER = oidc.mockProvider({ issuer: "https://kubernetes.default.svc.cluster.local", audience: "vault" });
+
+// projected service account token, as issued by the kubelet for a pod
+function serviceAccountToken(overrides) {
+  const now = Math.floor(Date.now() / 1000);
+
+  return CLUSTER.sign(
+    jwt.merge(
+      {
+        aud: ["vault", "https://kubernetes.default.svc.cluster.local"],
+        exp: now + 3600,
+        iat: now,
+        nbf: now,
+        iss: "https://kubernetes.default.svc.cluster.local",
+        jti: "9b2a7f3c-4a5e-4d57-9f1e-2c0b6b7f7f10",
+        "kubernetes.io": {
+          namespace: "payments",
+          node: { name: "node-7", uid: "5e7c2f1a-0c55-4d8e-8d0c-6f1b9a3c2d11" },
+          pod: { name: "checkout-6d9f7c-x2x4z", uid: "0f8d1c0e-4c1b-4b8e-9a51-3e2d1c0b9a87" },
+          serviceaccount: { name: "checkout", uid: "a3c1e2d4-7b6f-4e8a-9c0d-1f2e3d4c5b6a" },
+          warnafter: now + 600,
+        },
+        sub: "system:serviceaccount:payments:checkout",
+      },
+      overrides || {}
+    ),
+    { typ: "JWT" }
+  );
+}
+
+function code(fn) {
+  try {
+    fn();
+  } catch (e) {
+    return e.code;
+  }
+
+  return null;
+}
+
+function header(token) {
+  return JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+}
+
+export default function () {
+  describe("atHash", (t) => {
+    // OpenID Connect Core 1.0 Appendix A.3
+    t.expect(oidc.atHash("jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y", "RS256"))
+      .as("at_hash")
+      .toEqual("77QmUPtjPfzWtF2AnpK9RQ");
+    t.expect(oidc.atHash("token", "ES384").length).as("ES384 length").toEqual(32);
+    t.expect(oidc.atHash("token", "EdDSA").length).as("EdDSA length").toEqual(43);
+    t.expect(oidc.atHash("token", "ES256K")).as("ES256K").toEqual(oidc.atHash("token", "ES256"));
+  });
+
+  describe("cHash", (t) => {
+    // OpenID Connect Core 1.0 Appendix A.4
+    t.expect(oidc.cHash("Qcb0Orv1zh30vL1MPRsbm-diHiMwcLyZvn1arpZv-Jxf_11jnpEX3Tgfvk", "RS256"))
+      .as("c_hash")
+      .toEqual("LDktKdoQak3Pk0cnXxCltA");
+  });
+
+  describe("sHash", (t) => {
+    t.expect(oidc.sHash("state", "PS512")).as("s_hash").toEqual(oidc.cHash("state", "HS512"));
+
+    let err = null;
+    try {
+      oidc.sHash("state", "none");
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("unsupported alg error").toBeTruthy();
+
+    err = null;
+    try {
+      oidc.sHash("state", "XX256");
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("unknown alg with hash size suffix").toBeTruthy();
+  });
+
+  describe("logoutToken", (t) => {
+    const key = jwk.parse(EC_P256);
+    const token = oidc.logoutToken(key, ISSUER, "client-1", { sid: "session-1", sub: "alice" });
+    const claims = jwt.verify(token, key.public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    t.expect(header(token).typ).as("typ").toEqual("logout+jwt");
+    expect("iss").toEqual(ISSUER);
+    expect("aud").toEqual("client-1");
+    expect("sid").toEqual("session-1");
+    expect("sub").toEqual("alice");
+    t.expect("nonce" in claims).as("nonce present").toEqual(false);
+    t.expect(typeof claims.events["http://schemas.openid.net/event/backchannel-logout"])
+      .as("backchannel-logout event")
+      .toEqual("object");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(120);
+    t.expect(claims.jti.length).as("jti length").toBeGreaterThan(0);
+
+    const invalid = jwt.decode(
+      oidc.logoutToken(key, ISSUER, "client-1", { sub: "alice", claims: { events: null, nonce: "n-1" } })
+    );
+
+    t.expect("events" in invalid).as("events present").toEqual(false);
+    t.expect(invalid.nonce).as("nonce").toEqual("n-1");
+    t.expect("sid" in invalid).as("sid present").toEqual(false);
+
+    let err = null;
+    try {
+      oidc.logoutToken(key, ISSUER, "client-1");
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("missing sub and sid error").toBeTruthy();
+  });
+
+  describe("entityStatement", (t) => {
+    const anchor = jwk.parse(EC_P256);
+    const leaf = jwk.parse(EC_P256_2);
+    const LEAF = "https://rp.example.com";
+    const metadata = { openid_relying_party: { client_name: "k6" } };
+
+    const configuration = oidc.entityStatement(leaf, LEAF, LEAF, { metadata: metadata, authorityHints: [ISSUER] });
+    const conf = oidc.verifyEntityStatement(configuration);
+
+    t.expect(header(configuration).typ).as("typ").toEqual("entity-statement+jwt");
+    t.expect(conf.configuration).as("configuration").toEqual(true);
+    t.expect(conf.payload.authority_hints[0]).as("authority_hints").toEqual(ISSUER);
+    t.expect(conf.payload.metadata.openid_relying_party.client_name).as("metadata").toEqual("k6");
+    t.expect(conf.payload.jwks.keys.length).as("number of jwks keys").toEqual(1);
+    t.expect("d" in conf.payload.jwks.keys[0]).as("private part present").toEqual(false);
+    t.expect(conf.keys.length).as("number of keys").toEqual(1);
+
+    const statement = oidc.entityStatement(anchor, ISSUER, LEAF, { jwks: conf.keys, lifetime: 600 });
+    const stmt = oidc.verifyEntityStatement(statement, anchor.public());
+
+    t.expect(stmt.configuration).as("subordinate configuration").toEqual(false);
+    t.expect(stmt.payload.exp - stmt.payload.iat).as("lifetime").toEqual(600);
+    t.expect(JSON.parse(JSON.stringify(stmt.keys[0])).kid).as("subject kid").toEqual("ec-2");
+
+    const fails = (fn) => {
+      try {
+        fn();
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    t.expect(fails(() => oidc.verifyEntityStatement(statement))).as("missing issuer keys").toEqual(true);
+    t.expect(fails(() => oidc.verifyEntityStatement(statement, leaf.public()))).as("wrong issuer key").toEqual(true);
+    t.expect(fails(() => oidc.entityStatement(anchor, ISSUER, LEAF))).as("missing subject jwks").toEqual(true);
+    t.expect(fails(() => oidc.verifyEntityStatement(jwt.sign(leaf, { iss: LEAF, sub: LEAF }))))
+      .as("wrong typ")
+      .toEqual(true);
+    t.expect(
+      fails(() => oidc.verifyEntityStatement(oidc.entityStatement(leaf, LEAF, LEAF, { jwks: [anchor.public()] })))
+    )
+      .as("not self-signed")
+      .toEqual(true);
+  });
+
+  describe("signedJWKS", (t) => {
+    const key = jwk.parse(EC_P256);
+    const token = oidc.signedJWKS(key, ISSUER, [jwk.parse(EC_P256_2), jwk.parse(RSA_2048)]);
+    const claims = jwt.decode(token);
+
+    t.expect(header(token).typ).as("typ").toEqual("jwk-set+jwt");
+    t.expect(claims.iss).as("iss").toEqual(ISSUER);
+    t.expect(claims.sub).as("sub").toEqual(ISSUER);
+    t.expect(claims.keys.length).as("number of claim keys").toEqual(2);
+    t.expect("d" in claims.keys[0]).as("private part present").toEqual(false);
+
+    const keys = oidc.verifySignedJWKS(token, key.public());
+    const kids = keys.map((k) => JSON.parse(JSON.stringify(k)).kid);
+
+    t.expect(kids.join(",")).as("kids").toEqual("ec-2,rsa-1");
+    const signed = jwt.sign(jwk.parse(EC_P256_2), { foo: "bar" });
+
+    t.expect(jwt.verify(signed, keys).foo).as("verify with keys").toEqual("bar");
+
+    let err = null;
+    try {
+      oidc.verifySignedJWKS(oidc.signedJWKS(key, ISSUER, key.public()), jwk.parse(EC_P256_2).public());
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("wrong key error").toBeTruthy();
+
+    err = null;
+    try {
+      oidc.verifySignedJWKS(jwt.sign(key, { keys: [] }), key.public());
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("wrong typ error").toBeTruthy();
+  });
+
+  describe("mockProvider", (t) => {
+    t.expect(MOCK_IDP.issuer).as("issuer").toEqual("https://idp.test");
+    t.expect(MOCK_IDP.keys.length).as("keys").toEqual(3);
+    t.expect(MOCK_IDP.jwks.keys.length).as("jwks keys").toEqual(3);
+    t.expect(MOCK_IDP.jwks.keys[0].d).as("public jwks").toEqual(undefined);
+    t.expect(MOCK_IDP.discovery.jwks_uri).as("jwks_uri").toEqual(MOCK_IDP.jwksUri);
+    t.expect(MOCK_IDP.discovery.issuer).as("discovery issuer").toEqual("https://idp.test");
+    t.expect(MOCK_IDP.discovery.id_token_signing_alg_values_supported[0]).as("discovery alg").toEqual("ES256");
+
+    const kids = [1, 2, 3].map(() => header(MOCK_IDP.sign({ sub: "alice" })).kid);
+
+    t.expect(new Set(kids).size).as("rotating kids").toEqual(3);
+    t.expect(kids.every((kid) => MOCK_IDP.jwks.keys.some((key) => key.kid === kid))).as("kids in jwks").toBeTruthy();
+
+    const verify = { issuer: "https://idp.test", audience: "orders-api", subject: "alice" };
+    const id = jwt.verifyClaims(MOCK_IDP.issueIDToken({ sub: "alice" }), MOCK_IDP, verify);
+
+    t.expect(typeof id.nonce).as("nonce").toEqual("string");
+    t.expect(id.exp - id.iat).as("lifetime").toEqual(3600);
+
+    const access = MOCK_IDP.issueAccessToken({ sub: "alice", scope: "orders:read", jti: null });
+    const claims = jwt.verifyClaims(access, jwk.parseKeySet(MOCK_IDP.jwks), verify);
+
+    t.expect(header(access).typ).as("access typ").toEqual("at+jwt");
+    t.expect(claims.client_id).as("client_id").toEqual("orders-api");
+    t.expect(claims.scope).as("scope").toEqual("orders:read");
+    t.expect(claims.jti).as("removed jti").toEqual(undefined);
+
+    t.expect(header(DEFAULT_IDP.issueIDToken({ sub: "bob" })).alg).as("default alg").toEqual("RS256");
+    t.expect(jwt.decode(DEFAULT_IDP.issueIDToken({ sub: "bob" })).iss).as("default issuer").toEqual("https://idp.example.com");
+    t.expect(jwt.decode(DEFAULT_IDP.issueIDToken()).sub).as("default sub").toEqual("mock-user");
+    t.expect(jwt.decode(DEFAULT_IDP.issueAccessToken()).sub).as("default access sub").toEqual("mock-user");
+    t.expect(code(() => oidc.mockProvider({ keys: -1 }))).as("negative keys").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("verifyServiceAccountToken", (t) => {
+    const sa = oidc.verifyServiceAccountToken(serviceAccountToken(), CLUSTER, {
+      audience: "vault",
+      namespace: "payments",
+      serviceAccount: "checkout",
+      binding: "pod",
+    });
+
+    t.expect(sa.username).as("username").toEqual("system:serviceaccount:payments:checkout");
+    t.expect(sa.namespace).as("namespace").toEqual("payments");
+    t.expect(sa.serviceAccount.uid).as("service account uid").toEqual("a3c1e2d4-7b6f-4e8a-9c0d-1f2e3d4c5b6a");
+    t.expect(sa.pod.name).as("pod").toEqual("checkout-6d9f7c-x2x4z");
+    t.expect(sa.secret).as("secret").toEqual(null);
+    t.expect(sa.warnAfter > 0).as("warnAfter").toBeTruthy();
+    t.expect(sa.claims.jti).as("claims").toEqual("9b2a7f3c-4a5e-4d57-9f1e-2c0b6b7f7f10");
+
+    const verify = (token, options) => () => oidc.verifyServiceAccountToken(token, CLUSTER, options);
+    const now = Math.floor(Date.now() / 1000);
+
+    t.expect(code(verify(serviceAccountToken(), { audience: "other" }))).as("audience").toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(verify(serviceAccountToken({ iss: "https://issuer.example.com" }))))
+      .as("issuer")
+      .toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(verify(serviceAccountToken({ exp: now - 3600, nbf: now - 7200, iat: now - 7200 }))))
+      .as("expired")
+      .toEqual("ERR_JWT_EXPIRED");
+    t.expect(code(verify(serviceAccountToken({ exp: null })))).as("missing exp").toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(verify(serviceAccountToken({ sub: "system:serviceaccount:payments:admin" }))))
+      .as("subject")
+      .toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(verify(serviceAccountToken(), { namespace: "default" }))).as("namespace").toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(verify(serviceAccountToken(), { binding: "secret" }))).as("binding").toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(verify(serviceAccountToken(), { binding: "job" }))).as("invalid binding").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(verify(serviceAccountToken({ "kubernetes.io": null })))).as("legacy").toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(verify(MOCK_IDP.sign({ sub: "alice" })))).as("other issuer keys").toEqual("ERR_JOSE_KID_UNKNOWN");
+
+    const keys = jwk.parseKeySet(CLUSTER.jwks);
+
+    t.expect(code(() => oidc.verifyServiceAccountToken(serviceAccountToken(), keys, {})))
+      .as("missing issuer")
+      .toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(oidc.verifyServiceAccountToken(serviceAccountToken(), keys, { issuer: CLUSTER.issuer }).namespace)
+      .as("keys")
+      .toEqual("payments");
+  });
+}
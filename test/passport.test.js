@@ -0,0 +1,108 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import passport from "k6/x/jose/passport";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256 } from "./keys.js";
+
+const X5U = "https://cert.example.org/passport.pem";
+
+function header(token) {
+  return JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+}
+
+function fails(fn) {
+  try {
+    fn();
+  } catch (e) {
+    return true;
+  }
+  return false;
+}
+
+export default function () {
+  describe("sign", (t) => {
+    const key = jwk.parse(EC_P256);
+    const signed = passport.sign(key, X5U, "12155551212", "12155551213", { attest: "A" });
+    const hdr = header(signed.token);
+    const claims = jwt.decode(signed.token);
+
+    t.expect(hdr.typ).as("typ").toEqual("passport");
+    t.expect(hdr.ppt).as("ppt").toEqual("shaken");
+    t.expect(hdr.x5u).as("x5u").toEqual(X5U);
+    t.expect(hdr.alg).as("alg").toEqual("ES256");
+    t.expect(claims.orig.tn).as("orig").toEqual("12155551212");
+    t.expect(claims.dest.tn[0]).as("dest").toEqual("12155551213");
+    t.expect(claims.attest).as("attest").toEqual("A");
+    t.expect(claims.origid.length).as("origid length").toEqual(36);
+    t.expect(signed.identity).as("identity").toEqual(`${signed.token};info=<${X5U}>;alg=ES256;ppt=shaken`);
+
+    const opts = { attest: "C", origid: "123e4567-e89b-12d3-a456-426655440000" };
+    const uri = jwt.decode(passport.sign(key, X5U, "12155551212", { uri: ["sip:alice@example.com"] }, opts).token);
+
+    t.expect(uri.dest.uri[0]).as("dest uri").toEqual("sip:alice@example.com");
+    t.expect(uri.origid).as("origid").toEqual("123e4567-e89b-12d3-a456-426655440000");
+
+    t.expect(fails(() => passport.sign(key, X5U, "12155551212", "12155551213"))).as("missing attest").toEqual(true);
+    t.expect(fails(() => passport.sign(key, X5U, "1", "2", { attest: "D" }))).as("invalid attest").toEqual(true);
+  });
+
+  describe("verify", (t) => {
+    const key = jwk.parse(EC_P256);
+    const signed = passport.sign(key, X5U, "12155551212", ["12155551213", "12155551214"], { attest: "B" });
+    const verified = passport.verify(signed.token, key.public());
+
+    t.expect(verified.header.x5u).as("x5u").toEqual(X5U);
+    t.expect(verified.payload.attest).as("attest").toEqual("B");
+    t.expect(verified.payload.dest.tn.length).as("dest count").toEqual(2);
+
+    const stale = passport.sign(key, X5U, "1", "2", { attest: "A", claims: { iat: 1600000000 } });
+    const plain = jwt.sign(key, { iat: Math.floor(Date.now() / 1000), orig: { tn: "1" }, dest: { tn: ["2"] } });
+    const div = passport.sign(key, X5U, "1", "2", { ppt: "div" });
+    const other = jwk.generate("ed25519");
+
+    t.expect(fails(() => passport.verify(stale.token, key.public()))).as("stale").toEqual(true);
+    t.expect(passport.verify(stale.token, key.public(), { maxAge: 2000000000 }).payload.iat)
+      .as("max age")
+      .toEqual(1600000000);
+    t.expect(fails(() => passport.verify(plain, key.public()))).as("typ").toEqual(true);
+    t.expect(fails(() => passport.verify(div.token, key.public(), { ppt: "shaken" }))).as("ppt").toEqual(true);
+    t.expect(passport.verify(div.token, key.public(), { ppt: "div" }).header.ppt).as("div").toEqual("div");
+    t.expect(passport.verify(div.token, key.public()).header.ppt).as("token ppt").toEqual("div");
+
+    const base = jwt.sign(key, { iat: Math.floor(Date.now() / 1000), orig: { tn: "1" }, dest: { tn: ["2"] } }, {
+      typ: "passport",
+      x5u: X5U,
+    });
+
+    t.expect("ppt" in passport.verify(base, key.public()).header).as("no ppt").toEqual(false);
+    t.expect(fails(() => passport.verify(base, key.public(), { ppt: "shaken" }))).as("missing ppt").toEqual(true);
+    t.expect(fails(() => passport.verify(signed.token, other.public()))).as("wrong key").toEqual(true);
+  });
+}
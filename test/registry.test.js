@@ -0,0 +1,105 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import registry from "k6/x/jose/registry";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256_PEM, CLIENT_CERT_PEM } from "./keys.js";
+
+const ISSUER = "registry-token-issuer";
+const KEY_ID = "KMAT:S6WY:RCAI:4E6O:JYUV:P4ER:KEV7:MMVV:M2NM:ICY6:7TWO:SDHR";
+const CERT_KEY_ID = "YSS2:JN2X:6CL7:JNPN:5GPU:VSLH:Y7MN:D6OZ:VWKS:VJAB:AUJY:HAMY";
+
+function header(token) {
+  return JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+}
+
+function fails(fn) {
+  try {
+    fn();
+  } catch (e) {
+    return true;
+  }
+  return false;
+}
+
+export default function () {
+  describe("token", (t) => {
+    const key = jwk.parsePEM(EC_P256_PEM);
+    const token = registry.token(key, ISSUER, "registry.docker.io", "alice", [
+      "repository:samalba/my-app:pull,push",
+      { type: "registry", name: "catalog", actions: ["*"] },
+    ]);
+    const claims = jwt.verify(token, key.public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    t.expect(header(token).kid).as("kid").toEqual(KEY_ID);
+    expect("iss").toEqual(ISSUER);
+    expect("aud").toEqual("registry.docker.io");
+    expect("sub").toEqual("alice");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(300);
+    t.expect(claims.nbf).as("nbf").toEqual(claims.iat);
+    t.expect(claims.jti.length).as("jti length").toBeGreaterThan(0);
+    t.expect(claims.access.length).as("access count").toEqual(2);
+    t.expect(claims.access[0].type).as("type").toEqual("repository");
+    t.expect(claims.access[0].name).as("name").toEqual("samalba/my-app");
+    t.expect(claims.access[0].actions.join()).as("actions").toEqual("pull,push");
+    t.expect(claims.access[1].actions[0]).as("catalog actions").toEqual("*");
+
+    const cert = registry.token(key, ISSUER, "registry", "bob", [], { cert: CLIENT_CERT_PEM, lifetime: 60 });
+
+    t.expect(header(cert).kid).as("cert kid").toEqual(CERT_KEY_ID);
+    t.expect(jwt.decode(cert).access.length).as("empty access").toEqual(0);
+
+    t.expect(fails(() => registry.token(key, ISSUER, "registry", "bob", ["repository"]))).as("bad scope").toEqual(true);
+  });
+
+  describe("keyID", (t) => {
+    t.expect(registry.keyID(jwk.parsePEM(EC_P256_PEM))).as("key").toEqual(KEY_ID);
+    t.expect(registry.keyID(jwk.parsePEM(EC_P256_PEM).public())).as("public key").toEqual(KEY_ID);
+    t.expect(registry.keyID(CLIENT_CERT_PEM)).as("certificate").toEqual(CERT_KEY_ID);
+
+    let err = null;
+    try {
+      registry.keyID("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----");
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err && err.code).as("invalid PEM").toEqual("ERR_JOSE_KEY_INVALID");
+  });
+
+  describe("scope", (t) => {
+    const scope = registry.scope("repository:localhost:5000/foo/bar:pull");
+
+    t.expect(scope.type).as("type").toEqual("repository");
+    t.expect(scope.name).as("name").toEqual("localhost:5000/foo/bar");
+    t.expect(scope.actions.join()).as("actions").toEqual("pull");
+    t.expect(registry.scope("repository:foo:").actions.length).as("no actions").toEqual(0);
+    t.expect(fails(() => registry.scope("repository:foo"))).as("invalid").toEqual(true);
+  });
+}
@@ -0,0 +1,466 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import jose, { jwk, jwt, jws, jwe } from "k6/x/jose";
+import standalone from "k6/x/jose/jwt";
+import cose from "k6/x/jose/cose";
+import httpsig from "k6/x/jose/httpsig";
+import oauth from "k6/x/jose/oauth";
+import oidc from "k6/x/jose/oidc";
+import registry from "k6/x/jose/registry";
+import sdjwt from "k6/x/jose/sdjwt";
+import tamper from "k6/x/jose/tamper";
+import vc from "k6/x/jose/vc";
+import webpush from "k6/x/jose/webpush";
+import { describe } from "./expect.js";
+import { sleep } from "k6";
+import { b64decode, b64encode } from "k6/encoding";
+import { HS256 } from "./keys.js";
+
+// the options of the test apply in the VU code
+const INIT_CONFIG = jose.config();
+
+const code = (fn) => {
+  try {
+    fn();
+  } catch (e) {
+    return e.code;
+  }
+  return null;
+};
+
+if (!jose.keys.has("root-init")) {
+  jose.keys.put("root-init", jwk.generate("ES256"));
+}
+
+if (!jose.issuers.has("root-partner")) {
+  jose.issuers.register("root-partner", {
+    issuer: "https://idp.partner.test",
+    keys: jwk.generate("ES256"),
+    audience: "orders-api",
+    lifetime: "15m",
+    claims: { scope: "orders:read" },
+    header: { typ: "at+jwt" },
+  });
+}
+
+export default function () {
+  describe("namespaces", (t) => {
+    const key = jwk.generate("ES256");
+    const token = jwt.sign(key, { sub: "root" });
+
+    t.expect(jose.jwt === jwt).as("default export").toEqual(true);
+    t.expect(jwt.verify(token, key.public()).sub).as("jwt").toEqual("root");
+    t.expect(String.fromCharCode(...new Uint8Array(jws.verify(jws.sign(key, "jws"), key.public())))).as("jws").toEqual("jws");
+    t.expect(String.fromCharCode(...new Uint8Array(jwe.decrypt(key, jwe.encrypt(key.public(), "jwe"))))).as("jwe").toEqual("jwe");
+  });
+
+  describe("null key", (t) => {
+    const calls = {
+      "jwt.sign": (key) => jwt.sign(key, { sub: "root" }),
+      "jwt.createSigner": (key) => jwt.createSigner(key),
+      "jws.createStreamSigner": (key) => jws.createStreamSigner(key),
+      "jwk.toObject": (key) => jwk.toObject(key),
+      "jwk.serialize": (key) => jwk.serialize(key),
+      "jwk.toCryptoKey": (key) => jwk.toCryptoKey(key),
+      "standalone jwt.sign": (key) => standalone.sign(key, { sub: "root" }),
+      "cose.signCWT": (key) => cose.signCWT(key, { sub: "root" }),
+      "httpsig.sign": (key) => httpsig.sign(key, { method: "GET", url: "https://api.test/" }),
+      "webpush.vapid": (key) => webpush.vapid(key, "https://push.test/send"),
+      "vc.issue": (key) => vc.issue(key, {}),
+      "vc.present": (key) => vc.present(key, "did:example:holder", []),
+      "sdjwt.issue": (key) => sdjwt.issue(key, { sub: "root" }),
+      "oauth.clientAssertion": (key) => oauth.clientAssertion(key, "client", "https://as.test/token"),
+      "oidc.entityStatement": (key) => oidc.entityStatement(key, {}),
+      "registry.token": (key) => registry.token(key, {}),
+      "tamper.embedJWK": (key) => tamper.embedJWK(key, { sub: "root" }),
+      "tamper.jku": (key) => tamper.jku(key, { sub: "root" }, "https://evil.test/jwks"),
+      "tamper.kidInjection": (key) => tamper.kidInjection(key, { sub: "root" }),
+      "tamper.oversize": (key) => tamper.oversize(key, { sub: "root" }),
+    };
+
+    for (const [name, call] of Object.entries(calls)) {
+      t.expect(code(() => call(null))).as(name + " null").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+      t.expect(code(() => call(undefined))).as(name + " undefined").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    }
+
+    const key = jwk.generate("ES256");
+    const issued = sdjwt.issue(key, { sub: "root" });
+
+    t.expect(code(() => sdjwt.present(issued.token, null))).as("optional holder key").toEqual(null);
+  });
+
+  describe("environment", (t) => {
+    t.expect(jose.config().cacheTTL).as("K6_JOSE_CACHE_TTL").toEqual(3600);
+    t.expect(jose.config().leeway).as("leeway").toEqual(null);
+    t.expect(jose.config().algorithms.length).as("algorithms").toEqual(0);
+    t.expect(jose.config().maxSegments).as("ext.jose option").toEqual(100);
+    t.expect(INIT_CONFIG.maxSegments).as("ext.jose option in init").toEqual(null);
+  });
+
+  describe("configure keySize", (t) => {
+    jose.configure({ keySize: 1024 });
+
+    t.expect(jose.config().keySize).as("config").toEqual(1024);
+    t.expect(jwk.toObject(jwk.generate("RS256")).n.length).as("generate").toEqual(171);
+    t.expect(jwk.toObject(jwk.generateMany("RS256", 1)[0]).n.length).as("generateMany").toEqual(171);
+    t.expect(jwk.toObject(jwk.generateMany("RS256", 1, { bits: 2048 })[0]).n.length).as("bits option").toEqual(342);
+
+    jose.configure({ keySize: 2048 });
+  });
+
+  describe("configure algorithms", (t) => {
+    const ec = jwk.generate("ES256");
+    const hmac = jwk.parse(HS256);
+    const token = jwt.sign(hmac, { sub: "hmac" });
+
+    jose.configure({ algorithms: ["ES256", "ECDH-ES"] });
+
+    t.expect(jose.config().algorithms.join(",")).as("config").toEqual("ES256,ECDH-ES");
+
+    t.expect(jwt.verify(jwt.sign(ec, { sub: "ec" }), ec.public()).sub).as("allowed").toEqual("ec");
+    t.expect(code(() => jwt.verify(token, hmac))).as("jwt").toEqual("ERR_JOSE_ALG_NOT_ALLOWED");
+    t.expect(code(() => jwt.verifyClaims(token, hmac))).as("verifyClaims").toEqual("ERR_JOSE_ALG_NOT_ALLOWED");
+    t.expect(code(() => jws.verify(jws.sign(hmac, "x"), hmac))).as("jws").toEqual("ERR_JOSE_ALG_NOT_ALLOWED");
+    t.expect(code(() => jwe.decrypt(ec, jwe.encrypt(ec.public(), "x", { alg: "ECDH-ES+A128KW" }))))
+      .as("jwe")
+      .toEqual("ERR_JOSE_ALG_NOT_ALLOWED");
+    t.expect(standalone.verify(token, hmac).sub).as("standalone module").toEqual("hmac");
+
+    jose.configure({ algorithms: [] });
+
+    t.expect(jwt.verify(token, hmac).sub).as("reset").toEqual("hmac");
+  });
+
+  describe("configure critical", (t) => {
+    const keys = [jwk.generate("ES256"), jwk.generate("PS256"), jwk.generate("ed25519"), jwk.parse(HS256)];
+    const header = { sigT: "2026-10-14T12:00:00Z" };
+
+    jose.configure({ critical: ["sigT"] });
+
+    t.expect(jose.config().critical.join(",")).as("config").toEqual("sigT");
+
+    keys.forEach((key) => {
+      const alg = JSON.parse(JSON.stringify(key)).alg;
+      const verifier = alg == "HS256" ? key : key.public();
+      const signed = jws.sign(key, "hello", header, { critical: ["sigT"] });
+
+      t.expect(String.fromCharCode(...new Uint8Array(jws.verify(signed, verifier)))).as(alg + " jws").toEqual("hello");
+      t.expect(jwt.verify(jwt.sign(key, { sub: alg }, header, { critical: ["sigT"] }), verifier).sub).as(alg + " jwt").toEqual(alg);
+      t.expect(code(() => jws.verify(signed.slice(0, -4) + "AAAA", verifier))).as(alg + " tampered").toEqual("ERR_JOSE_SIGNATURE_INVALID");
+    });
+
+    const unencoded = jws.sign(keys[0], "hello", header, { b64: false, critical: ["sigT"], serialization: "flattened" });
+    t.expect(String.fromCharCode(...new Uint8Array(jws.verify(unencoded, keys[0].public())))).as("unencoded").toEqual("hello");
+
+    const signer = jwt.createSigner(keys[0], { header, critical: ["sigT"] });
+    t.expect(jwt.verify(signer.sign({ sub: "signer" }), keys[0].public()).sub).as("signer").toEqual("signer");
+
+    const token = jwt.sign(keys[0], { sub: "crit" }, header, { critical: ["sigT"] });
+    t.expect(code(() => standalone.verify(token, keys[0].public()))).as("standalone module").toEqual("ERR_JOSE_CRIT_UNSUPPORTED");
+
+    const encrypted = jwe.encrypt(jwk.generate("ES256").public(), "hello", { header, critical: ["sigT"] });
+    const decryptors = [
+      [jwk.generate("ES256"), "ECDH-ES"],
+      [jwk.generate("ES256"), "ECDH-ES+A128KW"],
+      [jwk.generate("RS256"), "RSA-OAEP-256"],
+      [jwk.generate("RS256"), "RSA-OAEP"],
+      [jwk.generate("RS256"), "RSA1_5"],
+      [jwk.parse({ kty: "oct", k: b64encode("0123456789abcdef0123456789abcdef", "rawurl") }), "A256KW"],
+      [jwk.parse({ kty: "oct", k: b64encode("0123456789abcdef0123456789abcdef", "rawurl") }), "A256GCMKW"],
+      [jwk.parse({ kty: "oct", k: b64encode("0123456789abcdef0123456789abcdef", "rawurl") }), "dir"],
+      ["passphrase", "PBES2-HS256+A128KW"],
+    ];
+
+    decryptors.forEach(([key, alg]) => {
+      const recipient = typeof key === "string" || alg.startsWith("A") || alg == "dir" ? key : key.public();
+      const token = jwe.encrypt(recipient, "hello", { alg, header, critical: ["sigT"], zip: "DEF" });
+
+      t.expect(String.fromCharCode(...new Uint8Array(jwe.decrypt(key, token)))).as(alg + " jwe").toEqual("hello");
+    });
+
+    const general = jwe.encrypt([decryptors[1][0].public(), decryptors[3][0].public()], "hello", { header, critical: ["sigT"] });
+    t.expect(String.fromCharCode(...new Uint8Array(jwe.decrypt(decryptors[3][0], general)))).as("general jwe").toEqual("hello");
+    t.expect(code(() => jwe.decrypt(jwk.generate("ES256"), encrypted))).as("wrong key jwe").toEqual("ERR_JWE_DECRYPTION_FAILED");
+
+    jose.configure({ critical: [] });
+
+    t.expect(code(() => jwt.verify(token, keys[0].public()))).as("reset").toEqual("ERR_JOSE_CRIT_UNSUPPORTED");
+    t.expect(code(() => jwe.decrypt(decryptors[2][0], jwe.encrypt(decryptors[2][0].public(), "hello", { header, critical: ["sigT"] }))))
+      .as("reset jwe")
+      .toEqual("ERR_JOSE_CRIT_UNSUPPORTED");
+  });
+
+  describe("configure limits", (t) => {
+    const key = jwk.parse(HS256);
+    const big = jwt.sign(key, { data: "x".repeat(2 << 20) });
+    const deep = jwt.sign(key, { data: JSON.parse("[".repeat(40) + "]".repeat(40)) });
+
+    t.expect(code(() => jwt.verify(big, key))).as("size").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => jwt.decode(big))).as("decode").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => jws.verify(jws.sign(key, "x".repeat(2 << 20)), key))).as("jws").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => jwe.decrypt(key, "a".repeat(2 << 20)))).as("jwe").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => jwt.verify(deep, key))).as("depth").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => jwt.decode("a." + "b~".repeat(100) + "c"))).as("segments").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => oidc.verifySignedJWKS(big, key))).as("oidc").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => oauth.verifyIntrospectionResponse(big, key, "issuer", "audience")))
+      .as("oauth")
+      .toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+
+    jose.configure({ maxTokenSize: 4 << 20, maxDepth: 64 });
+
+    t.expect(jose.config().maxTokenSize).as("config").toEqual(4 << 20);
+    t.expect(jwt.verify(big, key).data.length).as("raised size").toEqual(2 << 20);
+    t.expect(jwt.verify(deep, key).data.length).as("raised depth").toEqual(1);
+
+    jose.configure({ maxTokenSize: 1 << 20, maxDepth: 32 });
+  });
+
+  describe("configure leeway", (t) => {
+    const key = jwk.generate("ES256");
+    const expired = jwt.sign(key, { sub: "late" }, {}, { expiredBy: "30s" });
+    const pub = key.public();
+
+    t.expect(jwt.verifyClaims(expired, pub).sub).as("default leeway").toEqual("late");
+
+    jose.configure({ leeway: 0 });
+
+    t.expect(code(() => jwt.verifyClaims(expired, pub))).as("no leeway").toEqual("ERR_JWT_EXPIRED");
+    t.expect(jwt.verifyClaims(expired, pub, { leeway: "1m" }).sub).as("option overrides").toEqual("late");
+    t.expect(standalone.verifyClaims(expired, pub).sub).as("standalone module").toEqual("late");
+
+    jose.configure({ leeway: "1m" });
+  });
+
+  describe("configure strictBase64", (t) => {
+    const key = jwk.parse(HS256);
+    const token = jwt.sign(key, { sub: "strict" });
+    const serialized = jws.sign(key, "hello", null, { serialization: "flattened" });
+    const unsecured = (payload) => "eyJhbGciOiJub25lIn0." + payload + ".";
+
+    t.expect(jose.config().strictBase64).as("default").toEqual(false);
+    t.expect(jwt.decode(unsecured("eyJzIjogImEifR")).s).as("tolerant").toEqual("a");
+
+    jose.configure({ strictBase64: true });
+
+    t.expect(jose.config().strictBase64).as("config").toEqual(true);
+    t.expect(jwt.verify(token, key).sub).as("canonical").toEqual("strict");
+    t.expect(jwt.decode(unsecured("eyJzIjogImEifQ")).s).as("canonical unsecured").toEqual("a");
+    t.expect(code(() => jwt.decode(unsecured("eyJzIjogImEifR")))).as("trailing bits").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwt.decode(unsecured("eyJzIjogImEifQ==")))).as("padding").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwt.decode(unsecured("eyJ4IjoiPz8+In0")))).as("alphabet").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwt.decode(unsecured("eyJzIjogImEifQ\n")))).as("whitespace").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwt.verify(token + "\n", key))).as("trailing garbage").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jws.verify(serialized.replace('"signature":"', '"signature":"+'), key))).as("json").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(String.fromCharCode(...new Uint8Array(jws.verify(serialized, key)))).as("json canonical").toEqual("hello");
+
+    jose.configure({ strictBase64: false });
+  });
+
+  describe("configure strictJSON", (t) => {
+    const key = jwk.parse(HS256);
+    const header = (src) => b64encode(src, "rawurl");
+    const duplicate = jws.sign(key, '{"sub":"alice","sub":"admin"}', { typ: "JWT" });
+    const nested = jws.sign(key, '{"cnf":{"jkt":"a","jkt":"b"}}');
+    const siblings = jws.sign(key, '{"list":[{"x":1},{"x":2}],"x":{"x":3}}');
+    const unsecured = header('{"alg":"none","alg":"HS256"}') + "." + header('{"sub":"a"}') + ".";
+    const flattened = JSON.stringify({ payload: header("x"), protected: header('{"alg":"HS256","alg":"none"}'), signature: "" });
+
+    t.expect(jose.config().strictJSON).as("default").toEqual(false);
+
+    jose.configure({ strictJSON: true });
+
+    t.expect(jose.config().strictJSON).as("config").toEqual(true);
+    t.expect(code(() => jwt.verify(duplicate, key))).as("claims").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jwt.verify(nested, key))).as("nested").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(jwt.verify(siblings, key).x.x).as("siblings").toEqual(3);
+    t.expect(code(() => jwt.decode(unsecured))).as("header").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jws.parse(flattened))).as("json protected").toEqual("ERR_JOSE_MALFORMED");
+    t.expect(code(() => jws.parse(flattened.replace("{", '{"header":{"kid":"a","kid":"b"},')))).as("json").toEqual("ERR_JOSE_MALFORMED");
+
+    jose.configure({ strictJSON: false });
+  });
+
+  describe("configure debug", (t) => {
+    const key = jwk.generate("ES256");
+    const other = jwk.generate("ES256");
+    const hmac = jwk.parse(HS256);
+    const token = jwt.sign(key, { sub: "alice", secret: "s3cr3t" });
+    const sealed = jwe.encrypt(key.public(), "hello", { alg: "ECDH-ES" });
+
+    t.expect(jose.config().debug).as("default").toEqual(false);
+
+    jose.configure({ debug: true });
+
+    t.expect(jose.config().debug).as("config").toEqual(true);
+    t.expect(code(() => jwt.verify(token, other.public()))).as("jwt").toEqual("ERR_JOSE_KID_UNKNOWN");
+    t.expect(code(() => jwt.verify("e30.".repeat(200), key))).as("limits").toEqual("ERR_JOSE_LIMIT_EXCEEDED");
+    t.expect(code(() => jwt.verifyClaims(jwt.sign(hmac, { exp: 1 }), hmac))).as("claims").toEqual("ERR_JWT_EXPIRED");
+    t.expect(code(() => jws.verify(jws.sign(hmac, "payload"), jwk.parse({ kty: "oct", k: b64encode("0123456789abcdef0123456789abcdef", "rawurl") })))).as("jws").toBeTruthy();
+    t.expect(code(() => jwe.decrypt(other, sealed))).as("jwe").toBeTruthy();
+    t.expect(code(() => jwt.sign(jwk.generate("ES256").public(), { sub: "alice" }))).as("sign").toBeTruthy();
+    t.expect(jwt.verify(token, key.public()).sub).as("valid").toEqual("alice");
+
+    jose.configure({ debug: false });
+  });
+
+  describe("configure clock", (t) => {
+    const key = jwk.generate("ES256");
+    const token = jwt.sign(key, { sub: "past", nbf: 1700000000, exp: 1700000060 });
+
+    let now = 1700000030;
+
+    jose.configure({ clock: () => now });
+
+    t.expect(jose.config().clock).as("config").toEqual(true);
+    t.expect(jwt.verifyClaims(token, key.public()).sub).as("valid").toEqual("past");
+    t.expect(jwt.decode(jwt.sign(key, {}, null, { expiredBy: 0 })).exp).as("sign").toEqual(1700000030);
+
+    now = 1700000200;
+
+    t.expect(code(() => jwt.verifyClaims(token, key.public()))).as("advanced").toEqual("ERR_JWT_EXPIRED");
+    t.expect(jwt.verifyClaims(token, key.public(), { now: 1700000001 }).sub).as("option overrides").toEqual("past");
+
+    jose.configure({ clock: "system" });
+
+    t.expect(jose.config().clock).as("reset").toEqual(false);
+    t.expect(code(() => jose.configure({ clock: "tomorrow" }))).as("invalid").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("configure cacheTTL", (t) => {
+    jose.configure({ cacheTTL: "1ms" });
+
+    t.expect(jwk.toObject(jwk.parse(HS256)).kid).as("parse").toEqual("oct-1");
+    sleep(0.01);
+    t.expect(jwk.toObject(jwk.parse(HS256)).kid).as("parse expired").toEqual("oct-1");
+    t.expect(code(() => jose.configure({ cacheTTL: "soon" }))).as("invalid").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+
+    jose.configure({ cacheTTL: 0 });
+  });
+
+  describe("configure fetchTimeout", (t) => {
+    jose.configure({ fetchTimeout: "2s" });
+
+    t.expect(jose.config().fetchTimeout).as("config").toEqual(2);
+    t.expect(code(() => jose.configure({ fetchTimeout: "soon" }))).as("invalid").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jwk.parse("https://127.0.0.1:1/key.json"))).as("unreachable").toEqual("ERR_JOSE_FETCH_FAILED");
+
+    jose.configure({ fetchTimeout: 0 });
+  });
+
+  describe("discover", (t) => {
+    t.expect(code(() => jose.discover("http://127.0.0.1:1", { timeout: 1 })))
+      .as("unreachable")
+      .toEqual("ERR_JOSE_DISCOVERY_FAILED");
+    t.expect(code(() => jose.discover("::invalid"))).as("invalid url").toEqual("ERR_JOSE_DISCOVERY_FAILED");
+    t.expect(code(() => jose.discover("http://127.0.0.1:1", { timeout: "soon" })))
+      .as("invalid timeout")
+      .toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+
+  describe("keys", (t) => {
+    const key = jose.keys.get("root-init");
+    const token = jwt.sign(key, { sub: "shared" });
+
+    t.expect(jwt.verify(token, key.public()).sub).as("init").toEqual("shared");
+
+    const set = [jwk.generate("ES384"), jwk.generate("RS256")];
+    jose.keys.put("root-set", set);
+
+    t.expect(jose.keys.get("root-set").length).as("set").toEqual(2);
+    t.expect(jose.keys.get("root-set")[1].kid).as("set kid").toEqual(set[1].kid);
+    t.expect(jose.keys.names().indexOf("root-set") >= 0).as("names").toEqual(true);
+    t.expect(jose.keys.delete("root-set")).as("delete").toEqual(true);
+    t.expect(jose.keys.delete("root-set")).as("delete again").toEqual(false);
+    t.expect(jose.keys.get("root-set")).as("deleted").toEqual(null);
+    t.expect(jose.keys.has("root-set")).as("has").toEqual(false);
+    t.expect(code(() => jose.keys.put("", key))).as("empty name").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jose.keys.put("root-invalid", 42))).as("invalid key").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+
+    let calls = 0;
+    const lazy = () => {
+      calls++;
+      return jwk.generate("ES256");
+    };
+    const first = jose.keys.getOrPut("root-lazy", lazy);
+
+    t.expect(jose.keys.getOrPut("root-lazy", lazy).kid).as("getOrPut same").toEqual(first.kid);
+    t.expect(calls).as("getOrPut calls").toBeLessThan(2);
+    t.expect(code(() => jose.keys.getOrPut("root-lazy-invalid", 42))).as("not function").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jose.keys.getOrPut("root-lazy-fail", () => 42))).as("invalid value").toEqual("ERR_JOSE_KEY_UNSUPPORTED");
+    t.expect(jose.keys.has("root-lazy-fail")).as("failed not stored").toEqual(false);
+  });
+
+  describe("issuers", (t) => {
+    const token = jose.issuers.sign("root-partner", { sub: "alice" });
+    const claims = jose.issuers.verify("root-partner", token);
+    const header = JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+
+    t.expect(claims.iss).as("iss").toEqual("https://idp.partner.test");
+    t.expect(claims.aud).as("aud").toEqual("orders-api");
+    t.expect(claims.sub).as("sub").toEqual("alice");
+    t.expect(claims.scope).as("default claim").toEqual("orders:read");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(900);
+    t.expect(claims.jti.length > 0).as("jti").toEqual(true);
+    t.expect(header.typ).as("default header").toEqual("at+jwt");
+    t.expect(jose.issuers.keys("root-partner").length).as("keys").toEqual(1);
+
+    const custom = jose.issuers.sign("root-partner", { scope: null, aud: "billing" }, { typ: "JWT" }, { lifetime: 60 });
+    const customClaims = jose.issuers.verify("root-partner", custom, { audience: "billing" });
+
+    t.expect("scope" in customClaims).as("removed claim").toEqual(false);
+    t.expect(customClaims.exp - customClaims.iat).as("custom lifetime").toEqual(60);
+    t.expect(code(() => jose.issuers.verify("root-partner", custom))).as("audience").toEqual("ERR_JWT_CLAIM_INVALID");
+    t.expect(code(() => jose.issuers.verify("root-partner", jose.issuers.sign("root-partner", {}, {}, { expiredBy: 3600 }))))
+      .as("expired")
+      .toEqual("ERR_JWT_EXPIRED");
+
+    const signer = jwk.generate("ES256");
+    const jwks = JSON.stringify({ keys: [jwk.toObject(signer.public())] });
+
+    jose.issuers.register("root-remote", {
+      issuer: "https://login.test",
+      jwks: "data:application/json;base64," + b64encode(jwks),
+      cacheTTL: "1m",
+    });
+
+    const remote = jwt.sign(signer, { iss: "https://login.test", sub: "bob" });
+
+    t.expect(jose.issuers.verify("root-remote", remote).sub).as("fetched jwks").toEqual("bob");
+    t.expect(jose.issuers.keys("root-remote").length).as("fetched keys").toEqual(1);
+    t.expect(code(() => jose.issuers.verify("root-partner", remote))).as("other issuer").toEqual("ERR_JOSE_KID_UNKNOWN");
+    t.expect(code(() => jose.issuers.sign("root-remote", {}))).as("no signing key").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(jose.issuers.names().indexOf("root-remote") >= 0).as("names").toEqual(true);
+    t.expect(jose.issuers.delete("root-remote")).as("delete").toEqual(true);
+    t.expect(jose.issuers.has("root-remote")).as("has").toEqual(false);
+    t.expect(code(() => jose.issuers.verify("root-remote", remote))).as("unknown").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jose.issuers.register("root-empty", {}))).as("no keys").toEqual("ERR_JOSE_KID_UNKNOWN");
+    t.expect(code(() => jose.issuers.register("", { keys: signer }))).as("empty name").toEqual("ERR_JOSE_INVALID_ARGUMENT");
+    t.expect(code(() => jose.issuers.register("root-both", { jwks: "https://login.test/jwks", discover: true })))
+      .as("jwks and discover")
+      .toEqual("ERR_JOSE_INVALID_ARGUMENT");
+  });
+}
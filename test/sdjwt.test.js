@@ -0,0 +1,181 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import sdjwt from "k6/x/jose/sdjwt";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { sha256 } from "k6/crypto";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256, EC_P256_2 } from "./keys.js";
+
+const CLAIMS = {
+  iss: "https://issuer.example.com",
+  sub: "user-1",
+  given_name: "Erika",
+  family_name: "Mustermann",
+  address: { street: "Heidestrasse 17", locality: "Köln" },
+  nationalities: ["DE", "FR"],
+};
+
+function decodeDisclosure(disclosure) {
+  return JSON.parse(b64decode(disclosure, "rawurl", "s"));
+}
+
+export default function () {
+  describe("issue", (t) => {
+    const key = jwk.parse(EC_P256);
+    const holder = jwk.parse(EC_P256_2);
+    const issued = sdjwt.issue(key, CLAIMS, {
+      disclose: ["given_name", "address", "address.street", "nationalities.1"],
+      decoys: 2,
+      holder: holder,
+    });
+    const payload = jwt.verify(issued.jwt, key.public());
+    const parts = issued.token.split("~");
+
+    t.expect(parts.length).as("number of parts").toEqual(6);
+    t.expect(parts[0]).as("jwt part").toEqual(issued.jwt);
+    t.expect(parts[5]).as("last part").toEqual("");
+    t.expect(issued.disclosures.length).as("number of disclosures").toEqual(4);
+
+    t.expect(payload._sd_alg).as("_sd_alg").toEqual("sha-256");
+    t.expect(payload._sd.length).as("number of digests").toEqual(4);
+    t.expect(payload.family_name).as("family_name").toEqual("Mustermann");
+    t.expect("given_name" in payload).as("given_name present").toEqual(false);
+    t.expect("address" in payload).as("address present").toEqual(false);
+    t.expect(payload.nationalities[0]).as("nationalities[0]").toEqual("DE");
+    t.expect(typeof payload.nationalities[1]["..."]).as("nationalities[1] digest").toEqual("string");
+    t.expect(payload.cnf.jwk.kid).as("cnf kid").toEqual("ec-2");
+
+    const byName = {};
+    issued.disclosures.forEach((d) => {
+      const content = decodeDisclosure(d);
+      byName[content.length === 3 ? content[1] : "element"] = { content: content, digest: sha256(d, "base64rawurl") };
+    });
+
+    t.expect(byName.given_name.content[2]).as("given_name value").toEqual("Erika");
+    t.expect(payload._sd.indexOf(byName.given_name.digest) >= 0).as("given_name digest").toBeTruthy();
+    t.expect(payload._sd.indexOf(byName.address.digest) >= 0).as("address digest").toBeTruthy();
+    t.expect(byName.address.content[2]._sd.indexOf(byName.street.digest) >= 0).as("street digest").toBeTruthy();
+    t.expect(byName.address.content[2]._sd.length).as("address digests").toEqual(3);
+    t.expect(byName.element.content[1]).as("element value").toEqual("FR");
+    t.expect(payload.nationalities[1]["..."]).as("element digest").toEqual(byName.element.digest);
+  });
+
+  describe("issue options", (t) => {
+    const key = jwk.parse(EC_P256);
+    const issued = sdjwt.issue(key, CLAIMS, { disclose: ["sub"], alg: "sha-512", header: { typ: "dc+sd-jwt" } });
+    const header = JSON.parse(b64decode(issued.jwt.split(".")[0], "rawurl", "s"));
+
+    t.expect(header.typ).as("typ").toEqual("dc+sd-jwt");
+    t.expect(jwt.decode(issued.jwt)._sd_alg).as("_sd_alg").toEqual("sha-512");
+    t.expect(jwt.decode(issued.jwt)._sd[0].length).as("digest length").toEqual(86);
+
+    let err = null;
+    try {
+      sdjwt.issue(key, CLAIMS, { disclose: ["address.country"] });
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("invalid path error").toBeTruthy();
+  });
+
+  describe("present and verify", (t) => {
+    const key = jwk.parse(EC_P256);
+    const holder = jwk.parse(EC_P256_2);
+    const issued = sdjwt.issue(key, CLAIMS, {
+      disclose: ["given_name", "family_name", "address", "address.street", "nationalities.1"],
+      holder: holder,
+    });
+
+    const presentation = sdjwt.present(issued.token, holder, {
+      disclose: ["given_name", "address", "street"],
+      nonce: "n-0S6_WzA2Mj",
+      aud: "https://verifier.example.com",
+    });
+    const result = sdjwt.verify(presentation, key.public(), {
+      nonce: "n-0S6_WzA2Mj",
+      aud: "https://verifier.example.com",
+      keyBinding: true,
+    });
+
+    t.expect(presentation.split("~").length).as("number of parts").toEqual(5);
+    t.expect(result.payload.given_name).as("given_name").toEqual("Erika");
+    t.expect("family_name" in result.payload).as("family_name present").toEqual(false);
+    t.expect(result.payload.address.street).as("address.street").toEqual("Heidestrasse 17");
+    t.expect("_sd" in result.payload).as("_sd present").toEqual(false);
+    t.expect("_sd_alg" in result.payload).as("_sd_alg present").toEqual(false);
+    t.expect(result.payload.nationalities.length).as("nationalities").toEqual(1);
+    t.expect(result.keyBinding.nonce).as("kb nonce").toEqual("n-0S6_WzA2Mj");
+
+    const element = sdjwt.verify(sdjwt.present(issued.token, null, { disclose: ["FR"] }), key.public());
+
+    t.expect(element.payload.nationalities.join()).as("element disclosed").toEqual("DE,FR");
+    t.expect("given_name" in element.payload).as("element only").toEqual(false);
+
+    const all = sdjwt.verify(sdjwt.present(issued.token), [key.public()]);
+
+    t.expect(all.payload.family_name).as("all family_name").toEqual("Mustermann");
+    t.expect(all.payload.nationalities[1]).as("all nationalities[1]").toEqual("FR");
+    t.expect(all.keyBinding).as("no key binding").toEqual(null);
+  });
+
+  describe("verify failures", (t) => {
+    const key = jwk.parse(EC_P256);
+    const holder = jwk.parse(EC_P256_2);
+    const issued = sdjwt.issue(key, CLAIMS, { disclose: ["given_name", "family_name"], holder: holder });
+    const presentation = sdjwt.present(issued.token, holder, { nonce: "abc", aud: "verifier" });
+
+    const fails = (token, options) => {
+      try {
+        sdjwt.verify(token, key.public(), options);
+      } catch (e) {
+        return true;
+      }
+      return false;
+    };
+
+    const parts = presentation.split("~");
+    const tampered = [parts[0], sdjwt.issue(key, CLAIMS, { disclose: ["sub"] }).disclosures[0]].join("~") + "~";
+
+    t.expect(fails(presentation, { nonce: "abc", aud: "verifier" })).as("valid").toEqual(false);
+    t.expect(fails(presentation, { nonce: "xyz" })).as("wrong nonce").toEqual(true);
+    t.expect(fails(presentation, { aud: "other" })).as("wrong aud").toEqual(true);
+    t.expect(fails(tampered)).as("foreign disclosure").toEqual(true);
+    t.expect(fails([parts[0], parts[2], parts[3]].join("~"))).as("sd_hash mismatch").toEqual(true);
+    t.expect(fails(sdjwt.present(issued.token), { keyBinding: true })).as("missing key binding").toEqual(true);
+
+    const now = Math.floor(Date.now() / 1000);
+    const issuedAt = (iat) => sdjwt.present(issued.token, holder, { claims: { iat: iat } });
+
+    t.expect(fails(presentation + "\n")).as("trailing newline").toEqual(false);
+    t.expect(fails(issuedAt(now - 3600))).as("old iat").toEqual(true);
+    t.expect(fails(issuedAt(now + 3600))).as("future iat").toEqual(true);
+    t.expect(fails(issuedAt(now - 120), { maxAge: 60 })).as("maxAge").toEqual(true);
+    t.expect(fails(issuedAt(now - 120))).as("default maxAge").toEqual(false);
+  });
+}
@@ -0,0 +1,242 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import jose from "k6/x/jose";
+import tamper from "k6/x/jose/tamper";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { b64decode, b64encode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256, RSA_2048 } from "./keys.js";
+
+function header(token) {
+  return JSON.parse(b64decode(token.split(".")[0], "rawurl", "s"));
+}
+
+function signature(token) {
+  return new Uint8Array(b64decode(token.split(".")[2], "rawurl"));
+}
+
+function hmacKey(secret, alg) {
+  return jwk.parse(JSON.stringify({ kty: "oct", alg: alg, k: b64encode(secret, "rawurl") }));
+}
+
+function fails(fn) {
+  try {
+    fn();
+  } catch (e) {
+    return true;
+  }
+  return false;
+}
+
+export default function () {
+  const key = jwk.parse(EC_P256);
+  const token = jwt.sign(key, { sub: "alice", admin: false, exp: 2000000000 });
+  const parts = token.split(".");
+
+  describe("claims", (t) => {
+    const tampered = tamper.claims(token, { admin: true, exp: null });
+    const claims = jwt.decode(tampered);
+
+    t.expect(claims.admin).as("admin").toEqual(true);
+    t.expect(claims.sub).as("sub").toEqual("alice");
+    t.expect("exp" in claims).as("exp present").toEqual(false);
+    t.expect(tampered.split(".")[0]).as("header").toEqual(parts[0]);
+    t.expect(tampered.split(".")[2]).as("signature").toEqual(parts[2]);
+    t.expect(fails(() => jwt.verify(tampered, key.public()))).as("verify").toEqual(true);
+    t.expect(fails(() => tamper.claims("not-a-token", {}))).as("invalid token").toEqual(true);
+  });
+
+  describe("header", (t) => {
+    const tampered = tamper.header(token, { kid: "../../etc/passwd" });
+
+    t.expect(header(tampered).kid).as("kid").toEqual("../../etc/passwd");
+    t.expect(header(tampered).alg).as("alg").toEqual("ES256");
+    t.expect(tampered.split(".")[1]).as("payload").toEqual(parts[1]);
+
+    const none = tamper.alg(token, "none");
+
+    t.expect(header(none).alg).as("swapped alg").toEqual("none");
+    t.expect(jwt.decode(none).sub).as("payload kept").toEqual("alice");
+    t.expect(fails(() => jwt.verify(none, key.public()))).as("verify").toEqual(true);
+  });
+
+  describe("signature", (t) => {
+    const original = signature(token);
+    const truncated = signature(tamper.truncate(token));
+
+    t.expect(truncated.length).as("truncate default").toEqual(original.length - 1);
+    t.expect(signature(tamper.truncate(token, 10)).length).as("truncate").toEqual(original.length - 10);
+    t.expect(signature(tamper.truncate(token, 1000)).length).as("truncate all").toEqual(0);
+
+    const flipped = signature(tamper.flipBit(token, 0));
+
+    t.expect(flipped[0] ^ original[0]).as("first bit").toEqual(0x80);
+    t.expect(flipped.slice(1).every((v, i) => v === original[i + 1])).as("rest kept").toEqual(true);
+    t.expect(signature(tamper.flipBit(token, -1))[original.length - 1] ^ original[original.length - 1])
+      .as("last bit")
+      .toEqual(1);
+    t.expect(tamper.flipBit(token, 13)).as("reproducible").toEqual(tamper.flipBit(token, 13));
+    t.expect(fails(() => jwt.verify(tamper.flipBit(token, 100), key.public()))).as("verify").toEqual(true);
+  });
+
+  describe("reorder", (t) => {
+    t.expect(tamper.reorder(token, [1, 0, 2])).as("swap").toEqual([parts[1], parts[0], parts[2]].join("."));
+    t.expect(tamper.reorder(token, [0, 1])).as("omit").toEqual(parts[0] + "." + parts[1]);
+    t.expect(tamper.reorder(token, [0, 0, 1, 2]).split(".").length).as("repeat").toEqual(4);
+    t.expect(fails(() => tamper.reorder(token, [0, 3]))).as("invalid index").toEqual(true);
+  });
+
+  describe("confuse", (t) => {
+    const rsa = jwk.parse(RSA_2048);
+    const rs256 = jwt.sign(rsa, { sub: "alice" });
+    const pem = tamper.confuse(rs256, rsa.public());
+
+    t.expect(header(pem).alg).as("alg").toEqual("HS256");
+    t.expect(header(pem).typ).as("typ").toEqual("JWT");
+    t.expect(pem.split(".")[1]).as("payload").toEqual(rs256.split(".")[1]);
+    t.expect(fails(() => jwt.verify(pem, rsa.public()))).as("rsa verify").toEqual(true);
+
+    const der = tamper.confuse(rs256, rsa.public(), { encoding: "der" });
+    const pkcs1 = tamper.confuse(rs256, rsa.public(), { encoding: "pkcs1", alg: "HS512" });
+
+    t.expect(header(pkcs1).alg).as("custom alg").toEqual("HS512");
+    t.expect(der !== pem && pkcs1 !== pem).as("distinct encodings").toEqual(true);
+
+    const ec = jwk.parse(EC_P256);
+    const raw = tamper.confuse(jwt.sign(ec, { sub: "bob" }), ec.public(), { encoding: "raw" });
+
+    const pub = JSON.parse(EC_P256);
+    const point = new Uint8Array(65);
+
+    point[0] = 4;
+    point.set(new Uint8Array(b64decode(pub.x, "rawurl")), 1);
+    point.set(new Uint8Array(b64decode(pub.y, "rawurl")), 33);
+
+    // a vulnerable verifier would accept it with the public key as HMAC secret
+    t.expect(header(raw).alg).as("ec alg").toEqual("HS256");
+    t.expect(jwt.verify(raw, hmacKey(point.buffer, "HS256")).sub).as("hmac verify").toEqual("bob");
+    t.expect(fails(() => tamper.confuse(rs256, rsa.public(), { encoding: "raw" }))).as("rsa raw").toEqual(true);
+    t.expect(fails(() => tamper.confuse(rs256, rsa.public(), { encoding: "xml" }))).as("encoding").toEqual(true);
+    t.expect(fails(() => tamper.confuse(rs256, rsa.public(), { alg: "RS256" }))).as("non hmac alg").toEqual(true);
+  });
+
+  describe("fuzz", (t) => {
+    const kinds = tamper.fuzzKinds();
+    const fuzzed = tamper.fuzz(token, { seed: 42, count: 200 });
+
+    t.expect(kinds.length).as("kinds").toEqual(10);
+    t.expect(fuzzed.length).as("count").toEqual(200);
+    t.expect(tamper.fuzz(token).length).as("default count").toEqual(16);
+    t.expect(JSON.stringify(tamper.fuzz(token, { seed: 42, count: 200 })))
+      .as("reproducible")
+      .toEqual(JSON.stringify(fuzzed));
+    t.expect(JSON.stringify(tamper.fuzz(token, { seed: 7 })) !== JSON.stringify(tamper.fuzz(token, { seed: 8 })))
+      .as("seed")
+      .toEqual(true);
+    t.expect(kinds.every((kind) => fuzzed.some((f) => f.kind === kind))).as("all kinds").toEqual(true);
+    t.expect(fuzzed.every((f) => f.token !== token)).as("modified").toEqual(true);
+    // go-jose strips whitespace before parsing
+    const strict = fuzzed.filter((f) => f.kind !== "whitespace");
+
+    t.expect(strict.every((f) => fails(() => jwt.verify(f.token, key.public())))).as("rejected").toEqual(true);
+
+    const nul = tamper.fuzz(token, { kinds: ["nul_byte"], count: 5 });
+
+    t.expect(nul.every((f) => f.kind === "nul_byte")).as("kinds option").toEqual(true);
+    t.expect(fails(() => tamper.fuzz(token, { kinds: ["unknown"] }))).as("unknown kind").toEqual(true);
+  });
+
+  describe("oversize", (t) => {
+    jose.configure({ maxTokenSize: 4 * 1024 * 1024, maxDepth: 128 });
+
+    const big = tamper.oversize(key, { sub: "alice", aud: "api" }, { size: 2 * 1024 * 1024, audience: 5000 });
+    const claims = jose.jwt.verify(big, key.public());
+
+    t.expect(fails(() => jwt.verify(big, key.public()))).as("default limits").toEqual(true);
+
+    t.expect(big.length).as("token length").toBeGreaterThan(2 * 1024 * 1024);
+    t.expect(claims.pad.length).as("pad length").toEqual(2 * 1024 * 1024);
+    t.expect(claims.aud.length).as("audience count").toEqual(5000);
+    t.expect(claims.aud[0]).as("original audience").toEqual("api");
+    t.expect(claims.sub).as("sub").toEqual("alice");
+
+    const deep = jose.jwt.decode(tamper.oversize(key, {}, { depth: 100, nestedClaim: "deep", padClaim: "x", size: 3 }));
+    let depth = 0;
+
+    for (let obj = deep.deep; obj; obj = obj.n) {
+      depth++;
+    }
+
+    t.expect(depth).as("depth").toEqual(100);
+    t.expect(deep.x).as("pad claim").toEqual("AAA");
+    t.expect(fails(() => tamper.oversize(key, {}, { size: -1 }))).as("negative size").toEqual(true);
+
+    jose.configure({ maxTokenSize: 1024 * 1024, maxDepth: 32 });
+  });
+
+  describe("embedJWK", (t) => {
+    const attacker = jwk.generate("ed25519");
+    const injected = tamper.embedJWK(attacker, { sub: "admin" }, { header: { kid: "ec-1" } });
+    const hdr = header(injected);
+
+    t.expect(hdr.jwk.kty).as("jwk kty").toEqual("OKP");
+    t.expect("d" in hdr.jwk).as("jwk private").toEqual(false);
+    t.expect(hdr.kid).as("kid").toEqual("ec-1");
+    t.expect(jwt.verify(injected, jwk.parse(JSON.stringify(hdr.jwk))).sub).as("self-signed").toEqual("admin");
+    t.expect(fails(() => jwt.verify(injected, key.public()))).as("trusted key").toEqual(true);
+  });
+
+  describe("jku", (t) => {
+    const attacker = jwk.generate("ed25519");
+    const url = "https://attacker.example.com/jwks.json";
+    const injected = tamper.jku(attacker, { sub: "admin" }, url);
+    const hdr = header(injected);
+
+    t.expect(hdr.jku).as("jku").toEqual(url);
+    t.expect(hdr.kid).as("kid").toEqual(JSON.parse(JSON.stringify(attacker)).kid);
+    t.expect(jwt.verify(injected, attacker.public()).sub).as("attacker signed").toEqual("admin");
+    t.expect(fails(() => jwt.verify(injected, key.public()))).as("trusted key").toEqual(true);
+  });
+
+  describe("kidInjection", (t) => {
+    const tokens = tamper.kidInjection(key, { sub: "alice" });
+    const kinds = ["command", "ldap", "path", "sql"];
+
+    t.expect(kinds.every((kind) => tokens.some((k) => k.kind === kind))).as("all kinds").toEqual(true);
+    t.expect(tokens.every((k) => header(k.token).kid === k.kid)).as("kid header").toEqual(true);
+    t.expect(tokens.every((k) => jwt.verify(k.token, key.public()).sub === "alice")).as("signed").toEqual(true);
+    t.expect(tokens.some((k) => k.kid.indexOf("../") >= 0)).as("path traversal").toEqual(true);
+
+    const custom = tamper.kidInjection(key, {}, { kinds: ["sql"], payloads: ["key%00.pem"] });
+
+    t.expect(custom.every((k) => k.kind === "sql" || k.kind === "custom")).as("kinds option").toEqual(true);
+    t.expect(custom[custom.length - 1].kid).as("custom").toEqual("key%00.pem");
+    t.expect(tamper.kidInjection(key, {}, { payloads: ["a", "b"] }).length).as("payloads only").toEqual(2);
+    t.expect(fails(() => tamper.kidInjection(key, {}, { kinds: ["xss"] }))).as("unknown kind").toEqual(true);
+  });
+}
@@ -0,0 +1,109 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import vc from "k6/x/jose/vc";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { describe } from "./expect.js";
+import { EC_P256, EC_P256_2 } from "./keys.js";
+
+const ISSUER = "did:web:issuer.example.com";
+const HOLDER = "did:example:holder";
+
+export default function () {
+  describe("issue", (t) => {
+    const key = jwk.parse(EC_P256);
+    const token = vc.issue(
+      key,
+      ISSUER,
+      { id: HOLDER, degree: { type: "BachelorDegree" } },
+      {
+        types: ["UniversityDegreeCredential"],
+        validUntil: 2000000000,
+        status: { url: "https://issuer.example.com/status/1", index: 94567 },
+      }
+    );
+    const claims = jwt.verify(token, key.public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    expect("iss").toEqual(ISSUER);
+    expect("sub").toEqual(HOLDER);
+    expect("exp").toEqual(2000000000);
+    t.expect(claims.nbf > 0).as("nbf").toBeTruthy();
+    t.expect(/^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$/.test(claims.jti))
+      .as("jti")
+      .toBeTruthy();
+
+    t.expect(claims.vc["@context"][0]).as("@context").toEqual("https://www.w3.org/2018/credentials/v1");
+    t.expect(claims.vc.type.join(",")).as("type").toEqual("VerifiableCredential,UniversityDegreeCredential");
+    t.expect(claims.vc.credentialSubject.degree.type).as("credentialSubject").toEqual("BachelorDegree");
+    t.expect("id" in claims.vc.credentialSubject).as("credentialSubject id present").toEqual(false);
+
+    const status = claims.vc.credentialStatus;
+
+    t.expect(status.type).as("status type").toEqual("StatusList2021Entry");
+    t.expect(status.id).as("status id").toEqual("https://issuer.example.com/status/1#94567");
+    t.expect(status.statusListIndex).as("status index").toEqual("94567");
+    t.expect(status.statusPurpose).as("status purpose").toEqual("revocation");
+
+    const other = jwt.decode(vc.issue(key, ISSUER, { name: "Alice" }, { id: "urn:example:1", validFrom: 1600000000 }));
+
+    t.expect(other.jti).as("custom id").toEqual("urn:example:1");
+    t.expect(other.nbf).as("custom nbf").toEqual(1600000000);
+    t.expect("sub" in other).as("sub present").toEqual(false);
+    t.expect("exp" in other).as("exp present").toEqual(false);
+    t.expect(vc.issue(key, ISSUER, {}) !== vc.issue(key, ISSUER, {})).as("distinct tokens").toBeTruthy();
+  });
+
+  describe("present", (t) => {
+    const issuerKey = jwk.parse(EC_P256);
+    const holderKey = jwk.parse(EC_P256_2);
+    const credentials = [vc.issue(issuerKey, ISSUER, { id: HOLDER, a: 1 }), vc.issue(issuerKey, ISSUER, { b: 2 })];
+
+    const token = vc.present(holderKey, HOLDER, credentials, { nonce: "n-1", aud: "https://verifier.example.com" });
+    const claims = jwt.verify(token, holderKey.public());
+    const expect = (prop) => t.expect(claims[prop]).as(prop);
+
+    expect("iss").toEqual(HOLDER);
+    expect("nonce").toEqual("n-1");
+    expect("aud").toEqual("https://verifier.example.com");
+    t.expect(claims.exp - claims.iat).as("lifetime").toEqual(300);
+    t.expect(claims.jti.indexOf("urn:uuid:")).as("jti").toEqual(0);
+    t.expect(claims.vp.type[0]).as("type").toEqual("VerifiablePresentation");
+    t.expect(claims.vp.verifiableCredential.length).as("number of credentials").toEqual(2);
+    t.expect(jwt.verify(claims.vp.verifiableCredential[0], issuerKey.public()).vc.credentialSubject.a)
+      .as("wrapped credential")
+      .toEqual(1);
+
+    let err = null;
+    try {
+      vc.present(holderKey, "did:example:other", credentials);
+    } catch (e) {
+      err = e;
+    }
+    t.expect(err !== null).as("holder binding error").toBeTruthy();
+  });
+}
@@ -0,0 +1,65 @@
+/**
+ * MIT License
+ *
+ * Copyright (c) 2021 Iván Szkiba
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+export { options } from "./expect.js";
+
+import webpush from "k6/x/jose/webpush";
+import jwt from "k6/x/jose/jwt";
+import jwk from "k6/x/jose/jwk";
+import { b64decode } from "k6/encoding";
+import { describe } from "./expect.js";
+import { EC_P256 } from "./keys.js";
+
+const ENDPOINT = "https://push.example.net/push/JzLQ3raZJfFBR0aqvOMsLrt54w4rJUsV?ttl=60";
+
+function fails(fn) {
+  try {
+    fn();
+  } catch (e) {
+    return true;
+  }
+  return false;
+}
+
+export default function () {
+  describe("vapid", (t) => {
+    const key = jwk.parse(EC_P256);
+    const vapid = webpush.vapid(key, ENDPOINT, { sub: "mailto:push@example.com" });
+    const claims = jwt.verify(vapid.token, key.public());
+    const now = Math.floor(Date.now() / 1000);
+    const pub = new Uint8Array(b64decode(vapid.publicKey, "rawurl"));
+
+    t.expect(vapid.authorization).as("authorization").toEqual(`vapid t=${vapid.token}, k=${vapid.publicKey}`);
+    t.expect(claims.aud).as("aud").toEqual("https://push.example.net");
+    t.expect(claims.sub).as("sub").toEqual("mailto:push@example.com");
+    t.expect(claims.exp - now).as("lifetime").toBeGreaterThan(43190);
+    t.expect(pub.length).as("public key length").toEqual(65);
+    t.expect(pub[0]).as("public key form").toEqual(4);
+    t.expect(webpush.vapid(key, ENDPOINT).token !== vapid.token).as("distinct").toEqual(true);
+
+    t.expect(fails(() => webpush.vapid(key, ENDPOINT, { lifetime: 90000 }))).as("too long").toEqual(true);
+    t.expect(fails(() => webpush.vapid(jwk.generate("ed25519"), ENDPOINT))).as("ed25519").toEqual(true);
+    t.expect(fails(() => webpush.vapid(key, "push"))).as("invalid endpoint").toEqual(true);
+  });
+}
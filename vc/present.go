@@ -0,0 +1,110 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/uuid"
+	"github.com/szkiba/xk6-jose/jwt"
+)
+
+const (
+	presentationType           = "VerifiablePresentation"
+	defaultPresentationSeconds = 300
+)
+
+type PresentOptions struct {
+	ID       string                 `js:"id"`
+	Nonce    string                 `js:"nonce"`
+	Audience string                 `js:"aud"`
+	Lifetime int                    `js:"lifetime"`
+	Types    []string               `js:"types"`
+	Claims   map[string]interface{} `js:"claims"`
+	Header   map[string]interface{} `js:"header"`
+}
+
+// Present creates verifiable presentation JWT signed by the holder, the credentials must be issued to the holder.
+func (m *Module) Present(
+	key *jose.JSONWebKey,
+	holder string,
+	credentials []string,
+	options *PresentOptions,
+) (string, error) {
+	if options == nil {
+		options = &PresentOptions{}
+	}
+
+	for i, credential := range credentials {
+		claims, err := jwt.Decode(credential)
+		if err != nil {
+			return "", fmt.Errorf("%w: credential %d: %s", ErrInvalidCredential, i, err.Error())
+		}
+
+		// holder binding: the subject of the credential is the holder
+		if sub, ok := claims["sub"]; ok && sub != holder {
+			return "", fmt.Errorf("%w: credential %d subject %v is not the holder", ErrInvalidCredential, i, sub)
+		}
+	}
+
+	id := options.ID
+	if id == "" {
+		var err error
+
+		if id, err = uuid.URN(); err != nil {
+			return "", err
+		}
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultPresentationSeconds
+	}
+
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss": holder,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(time.Duration(lifetime) * time.Second).Unix(),
+		"jti": id,
+		"vp": map[string]interface{}{
+			"@context":             []string{credentialsContext},
+			"type":                 append([]string{presentationType}, options.Types...),
+			"verifiableCredential": credentials,
+		},
+	}
+
+	if options.Nonce != "" {
+		claims["nonce"] = options.Nonce
+	}
+
+	if options.Audience != "" {
+		claims["aud"] = options.Audience
+	}
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), options.Header)
+}
@@ -0,0 +1,152 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/internal/uuid"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct{}
+
+func New() modules.Module {
+	return jsmodule.New(func(modules.VU) interface{} { return &Module{} })
+}
+
+var ErrInvalidCredential = errors.New("invalid credential")
+
+const (
+	credentialsContext   = "https://www.w3.org/2018/credentials/v1"
+	credentialType       = "VerifiableCredential"
+	statusListEntryType  = "StatusList2021Entry"
+	defaultStatusPurpose = "revocation"
+)
+
+type StatusOptions struct {
+	URL     string `js:"url"`
+	Index   int    `js:"index"`
+	Purpose string `js:"purpose"`
+}
+
+type IssueOptions struct {
+	ID         string                 `js:"id"`
+	Types      []string               `js:"types"`
+	Context    []string               `js:"context"`
+	ValidFrom  int64                  `js:"validFrom"`
+	ValidUntil int64                  `js:"validUntil"`
+	Status     *StatusOptions         `js:"status"`
+	Claims     map[string]interface{} `js:"claims"`
+	Header     map[string]interface{} `js:"header"`
+}
+
+// Issue creates verifiable credential JWT (W3C VC data model JWT encoding).
+func (m *Module) Issue(
+	key *jose.JSONWebKey,
+	issuer string,
+	credentialSubject map[string]interface{},
+	options *IssueOptions,
+) (string, error) {
+	if options == nil {
+		options = &IssueOptions{}
+	}
+
+	subject := jwt.Merge(credentialSubject)
+
+	id := options.ID
+	if id == "" {
+		var err error
+
+		if id, err = uuid.URN(); err != nil {
+			return "", err
+		}
+	}
+
+	nbf := options.ValidFrom
+	if nbf == 0 {
+		nbf = time.Now().Unix()
+	}
+
+	credential := map[string]interface{}{
+		"@context":          append([]string{credentialsContext}, options.Context...),
+		"type":              append([]string{credentialType}, options.Types...),
+		"credentialSubject": subject,
+	}
+
+	if options.Status != nil {
+		status, err := statusEntry(options.Status)
+		if err != nil {
+			return "", err
+		}
+
+		credential["credentialStatus"] = status
+	}
+
+	claims := map[string]interface{}{
+		"iss": issuer,
+		"nbf": nbf,
+		"jti": id,
+		"vc":  credential,
+	}
+
+	// the credential subject id is represented by the sub claim
+	if sub, ok := subject["id"].(string); ok {
+		claims["sub"] = sub
+
+		delete(subject, "id")
+	}
+
+	if options.ValidUntil != 0 {
+		claims["exp"] = options.ValidUntil
+	}
+
+	return jwt.Sign(key, jwt.Merge(claims, options.Claims), options.Header)
+}
+
+func statusEntry(options *StatusOptions) (map[string]interface{}, error) {
+	if options.URL == "" {
+		return nil, fmt.Errorf("%w: missing status list url", ErrInvalidCredential)
+	}
+
+	purpose := options.Purpose
+	if purpose == "" {
+		purpose = defaultStatusPurpose
+	}
+
+	index := strconv.Itoa(options.Index)
+
+	return map[string]interface{}{
+		"id":                   options.URL + "#" + index,
+		"type":                 statusListEntryType,
+		"statusPurpose":        purpose,
+		"statusListIndex":      index,
+		"statusListCredential": options.URL,
+	}, nil
+}
@@ -0,0 +1,128 @@
+// MIT License
+//
+// Copyright (c) 2021 Iván Szkiba
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/szkiba/xk6-jose/internal/jsmodule"
+	"github.com/szkiba/xk6-jose/jwk"
+	"github.com/szkiba/xk6-jose/jwt"
+	"go.k6.io/k6/js/modules"
+)
+
+type Module struct{}
+
+func New() modules.Module {
+	return jsmodule.New(func(modules.VU) interface{} { return &Module{} })
+}
+
+var (
+	ErrInvalidURL      = errors.New("invalid url")
+	ErrInvalidLifetime = errors.New("invalid lifetime")
+)
+
+const (
+	defaultVAPIDSeconds = 12 * 60 * 60
+	maxVAPIDSeconds     = 24 * 60 * 60
+)
+
+type VAPIDOptions struct {
+	Subject  string                 `js:"sub"`
+	Lifetime int                    `js:"lifetime"`
+	Claims   map[string]interface{} `js:"claims"`
+}
+
+type VAPID struct {
+	Authorization string `js:"authorization"`
+	Token         string `js:"token"`
+	PublicKey     string `js:"publicKey"`
+}
+
+// Vapid creates voluntary application server identification (RFC 8292) for the push service of the endpoint.
+func (m *Module) Vapid(key *jose.JSONWebKey, endpoint string, options *VAPIDOptions) (*VAPID, error) {
+	if options == nil {
+		options = &VAPIDOptions{}
+	}
+
+	priv, ok := key.Key.(*ecdsa.PrivateKey)
+	if !ok || priv.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: VAPID requires P-256 private key", jwk.ErrUnsupportedKey)
+	}
+
+	aud, err := origin(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultVAPIDSeconds
+	}
+
+	if lifetime > maxVAPIDSeconds {
+		return nil, fmt.Errorf("%w: %d seconds, maximum is %d", ErrInvalidLifetime, lifetime, maxVAPIDSeconds)
+	}
+
+	claims := map[string]interface{}{
+		"aud": aud,
+		"exp": time.Now().Add(time.Duration(lifetime) * time.Second).Unix(),
+	}
+
+	if options.Subject != "" {
+		claims["sub"] = options.Subject
+	}
+
+	signing := *key
+	signing.Algorithm = string(jose.ES256)
+
+	token, err := jwt.Sign(&signing, jwt.Merge(claims, options.Claims), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := base64.RawURLEncoding.EncodeToString(elliptic.Marshal(priv.Curve, priv.X, priv.Y))
+
+	return &VAPID{
+		Authorization: fmt.Sprintf("vapid t=%s, k=%s", token, pub),
+		Token:         token,
+		PublicKey:     pub,
+	}, nil
+}
+
+// origin returns the ASCII serialization of the origin of the push resource URL.
+func origin(src string) (string, error) {
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidURL, src)
+	}
+
+	return u.Scheme + "://" + u.Host, nil
+}